@@ -0,0 +1,254 @@
+// Package attribute checks the custom attributes of contacts, addresses and companies against
+// a registry of definitions kept per entity and record type.
+package attribute
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Entities that carry attributes.
+const (
+	EntityContact = "contact"
+	EntityAddress = "address"
+	EntityCompany = "company"
+)
+
+// Data types of attribute values.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// AnyType is the record type whose definitions apply to records of every type of an entity.
+const AnyType = "*"
+
+// maxSafeInteger is the largest integer a JSON number holds without losing precision.
+const maxSafeInteger = 1<<53 - 1
+
+var ErrInvalidRegistry = errors.New("invalid attribute registry")
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidName reports whether name can name an attribute.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Definition describes one attribute.
+type Definition struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Enum lists the values a string attribute may take; any value when empty.
+	Enum []string `json:"enum"`
+	// Pattern is a regular expression a string attribute must match.
+	Pattern string `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// Registry holds the attribute definitions of each entity, by record type. A nil Registry
+// defines no attribute.
+type Registry struct {
+	definitions map[string]map[string][]*Definition
+}
+
+// Parse reads a registry from JSON of the form
+//
+//	{"address": {"*": [{"name": "floor_code", "type": "string", "pattern": "^[0-9A-Z]{1,4}$"}]}}
+//
+// which maps each entity to the definitions of each record type, or of every type under "*".
+func Parse(data []byte) (*Registry, error) {
+	var raw map[string]map[string][]*Definition
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRegistry, err)
+	}
+
+	for entity, byType := range raw {
+		if entity != EntityContact && entity != EntityAddress && entity != EntityCompany {
+			return nil, fmt.Errorf("%w: unknown entity %q", ErrInvalidRegistry, entity)
+		}
+		for recordType, definitions := range byType {
+			seen := make(map[string]bool, len(definitions))
+			for _, definition := range definitions {
+				if err := definition.compile(); err != nil {
+					return nil, fmt.Errorf("%w: %s type %q: %v", ErrInvalidRegistry, entity, recordType, err)
+				}
+				if seen[definition.Name] {
+					return nil, fmt.Errorf("%w: %s type %q defines %s twice", ErrInvalidRegistry, entity, recordType, definition.Name)
+				}
+				seen[definition.Name] = true
+			}
+		}
+	}
+
+	return &Registry{definitions: raw}, nil
+}
+
+// Load reads the registry from the JSON file at path.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+func (d *Definition) compile() error {
+	if d == nil || !ValidName(d.Name) {
+		return errors.New("attribute names must be 1-64 lower case letters, digits or '_', starting with a letter")
+	}
+	switch d.Type {
+	case TypeString:
+	case TypeInteger, TypeNumber, TypeBoolean:
+		if len(d.Enum) > 0 || d.Pattern != "" {
+			return fmt.Errorf("%s: enum and pattern only apply to strings", d.Name)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %q", d.Name, d.Type)
+	}
+	if d.Pattern != "" {
+		pattern, err := regexp.Compile(d.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Name, err)
+		}
+		d.pattern = pattern
+	}
+
+	return nil
+}
+
+// Definitions returns the definitions that apply to records of entity with type recordType,
+// by name. A definition for the type replaces the one of the same name under AnyType.
+func (r *Registry) Definitions(entity, recordType string) map[string]*Definition {
+	definitions := make(map[string]*Definition)
+	if r == nil {
+		return definitions
+	}
+	for _, definition := range r.definitions[entity][AnyType] {
+		definitions[definition.Name] = definition
+	}
+	if recordType != AnyType {
+		for _, definition := range r.definitions[entity][recordType] {
+			definitions[definition.Name] = definition
+		}
+	}
+
+	return definitions
+}
+
+// Validate checks the attributes of a record of entity with type recordType: every attribute
+// must be defined for it and match its definition, and every required one must be set. The
+// first failure is reported as a field error on attributes.<name>.
+func (r *Registry) Validate(entity, recordType string, values map[string]any) error {
+	definitions := r.Definitions(entity, recordType)
+
+	names := make([]string, 0, len(values)+len(definitions))
+	for name := range values {
+		names = append(names, name)
+	}
+	for name, definition := range definitions {
+		if _, ok := values[name]; !ok && definition.Required {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		definition, ok := definitions[name]
+		if !ok {
+			return fieldError(name, fmt.Sprintf("attribute %s is not defined for %s type %q", name, entity, recordType))
+		}
+		value, ok := values[name]
+		if !ok {
+			return fieldError(name, fmt.Sprintf("attribute %s is required", name))
+		}
+		if err := definition.check(value); err != nil {
+			return fieldError(name, fmt.Sprintf("attribute %s %s", name, err))
+		}
+	}
+
+	return nil
+}
+
+func (d *Definition) check(value any) error {
+	switch d.Type {
+	case TypeString:
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if len(d.Enum) > 0 && !contains(d.Enum, s) {
+			return fmt.Errorf("must be one of %v", d.Enum)
+		}
+		if d.pattern != nil && !d.pattern.MatchString(s) {
+			return fmt.Errorf("must match %s", d.Pattern)
+		}
+	case TypeInteger:
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || math.Abs(n) > maxSafeInteger {
+			return errors.New("must be an integer")
+		}
+	case TypeNumber:
+		if _, ok := value.(float64); !ok {
+			return errors.New("must be a number")
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldError(name, message string) error {
+	return &country.FieldError{Field: "attributes." + name, Message: message}
+}
+
+// Struct returns attribute values as a google.protobuf.Struct, or nil when there are none.
+// Values decoded from JSON always convert.
+func Struct(values map[string]any) *structpb.Struct {
+	if len(values) == 0 {
+		return nil
+	}
+	s, err := structpb.NewStruct(values)
+	if err != nil {
+		return nil
+	}
+
+	return s
+}
+
+// Values returns the attributes held by s, or nil when it holds none.
+func Values(s *structpb.Struct) map[string]any {
+	if len(s.GetFields()) == 0 {
+		return nil
+	}
+
+	return s.AsMap()
+}
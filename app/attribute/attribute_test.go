@@ -0,0 +1,140 @@
+package attribute
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+)
+
+const testRegistry = `{
+	"address": {
+		"*": [{"name": "floor_code", "type": "string", "pattern": "^[0-9A-Z]{1,4}$"}],
+		"warehouse": [
+			{"name": "dock", "type": "string", "required": true, "enum": ["A", "B"]},
+			{"name": "floor_code", "type": "integer"}
+		]
+	},
+	"company": {
+		"*": [
+			{"name": "caen_code", "type": "string", "required": true},
+			{"name": "employees", "type": "integer"},
+			{"name": "revenue", "type": "number"},
+			{"name": "listed", "type": "boolean"}
+		]
+	}
+}`
+
+func decode(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var values map[string]any
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		t.Fatalf("invalid test values %s: %v", raw, err)
+	}
+	return values
+}
+
+func TestValidate(t *testing.T) {
+	registry, err := Parse([]byte(testRegistry))
+	if err != nil {
+		t.Fatalf("expected registry to parse, got %v", err)
+	}
+
+	valid := []struct {
+		entity, recordType, values string
+	}{
+		{EntityAddress, "home", `{"floor_code":"2B"}`},
+		{EntityAddress, "home", `{}`},
+		{EntityAddress, "warehouse", `{"dock":"A","floor_code":3}`},
+		{EntityCompany, "", `{"caen_code":"6201","employees":12,"revenue":1250.5,"listed":false}`},
+		{EntityContact, "", `{}`},
+	}
+	for _, tc := range valid {
+		if err = registry.Validate(tc.entity, tc.recordType, decode(t, tc.values)); err != nil {
+			t.Fatalf("expected %s %q %s to be valid, got %v", tc.entity, tc.recordType, tc.values, err)
+		}
+	}
+
+	cases := []struct {
+		entity, recordType, values, field string
+	}{
+		{EntityAddress, "home", `{"floor_code":"2b"}`, "attributes.floor_code"},
+		{EntityAddress, "home", `{"floor_code":2}`, "attributes.floor_code"},
+		{EntityAddress, "warehouse", `{"floor_code":3}`, "attributes.dock"},
+		{EntityAddress, "warehouse", `{"dock":"C"}`, "attributes.dock"},
+		{EntityAddress, "warehouse", `{"dock":"A","floor_code":"2B"}`, "attributes.floor_code"},
+		{EntityCompany, "", `{"caen_code":"6201","employees":1.5}`, "attributes.employees"},
+		{EntityCompany, "", `{"caen_code":"6201","revenue":"high"}`, "attributes.revenue"},
+		{EntityCompany, "", `{"caen_code":"6201","listed":"yes"}`, "attributes.listed"},
+		{EntityCompany, "", `{}`, "attributes.caen_code"},
+		{EntityContact, "", `{"job_title":"CTO"}`, "attributes.job_title"},
+	}
+	for _, tc := range cases {
+		var fieldErr *country.FieldError
+		err = registry.Validate(tc.entity, tc.recordType, decode(t, tc.values))
+		if !errors.As(err, &fieldErr) || fieldErr.Field != tc.field {
+			t.Fatalf("expected %s %q %s to fail on %s, got %v", tc.entity, tc.recordType, tc.values, tc.field, err)
+		}
+	}
+}
+
+func TestNilRegistryDefinesNothing(t *testing.T) {
+	var registry *Registry
+	if err := registry.Validate(EntityContact, "personal", nil); err != nil {
+		t.Fatalf("expected no attributes to be valid, got %v", err)
+	}
+	if err := registry.Validate(EntityContact, "personal", map[string]any{"job_title": "CTO"}); err == nil {
+		t.Fatal("expected an undefined attribute to be rejected")
+	}
+}
+
+func TestParseRejectsInvalidRegistries(t *testing.T) {
+	cases := []string{
+		`{"profile": {"*": []}}`,
+		`{"contact": {"*": [{"name": "Job Title", "type": "string"}]}}`,
+		`{"contact": {"*": [{"name": "job_title", "type": "date"}]}}`,
+		`{"contact": {"*": [{"name": "age", "type": "integer", "enum": ["1"]}]}}`,
+		`{"contact": {"*": [{"name": "job_title", "type": "string", "pattern": "("}]}}`,
+		`{"contact": {"*": [{"name": "job_title", "type": "string"}, {"name": "job_title", "type": "string"}]}}`,
+		`{"contact": {"*": [{"name": "job_title", "type": "string", "min": 1}]}}`,
+		`[]`,
+	}
+	for _, raw := range cases {
+		if _, err := Parse([]byte(raw)); !errors.Is(err, ErrInvalidRegistry) {
+			t.Fatalf("expected %s to be rejected, got %v", raw, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attributes.json")
+	if err := os.WriteFile(path, []byte(testRegistry), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected registry to load, got %v", err)
+	}
+	if definitions := registry.Definitions(EntityAddress, "warehouse"); len(definitions) != 2 || definitions["floor_code"].Type != TypeInteger {
+		t.Fatalf("expected the warehouse floor_code to replace the shared one, got %+v", definitions)
+	}
+
+	if _, err = Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	if Struct(nil) != nil || Values(nil) != nil {
+		t.Fatal("expected no attributes to stay empty")
+	}
+
+	values := Values(Struct(map[string]any{"dock": "B", "floor": float64(2)}))
+	if values["dock"] != "B" || values["floor"] != float64(2) {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
@@ -0,0 +1,41 @@
+// Package blobstore keeps the content of attachments by key, on the local file system or in
+// an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps blobs under slash-separated keys such as attachments/<uuid>.
+type Store interface {
+	// Put stores the size bytes read from content under key, replacing any blob already there.
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key, or fails with ErrNotFound. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key; a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey rejects keys that could leave the store's root: empty or absolute ones and those
+// holding empty, "." or ".." segments or backslashes.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+
+	return nil
+}
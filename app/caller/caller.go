@@ -0,0 +1,58 @@
+package caller
+
+import (
+	"context"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	authmiddleware "github.com/vibast-solutions/lib-go-auth/middleware"
+)
+
+// Identity describes the service behind a request, as authenticated by the internal auth middleware.
+type Identity struct {
+	Service string
+	Access  []string
+	// Admin is set when Access holds the configured admin grant.
+	Admin bool
+	// RequestID is the id assigned to the request by the transport's request id middleware.
+	RequestID string
+}
+
+type contextKey struct{}
+
+// NewIdentity builds an Identity, granting admin when access contains adminAccess.
+func NewIdentity(service string, access []string, adminAccess string) Identity {
+	return Identity{
+		Service: service,
+		Access:  access,
+		Admin:   adminAccess != "" && slices.Contains(access, adminAccess),
+	}
+}
+
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the caller stored in ctx, or a zero Identity for unauthenticated requests.
+func FromContext(ctx context.Context) Identity {
+	identity, _ := ctx.Value(contextKey{}).(Identity)
+	return identity
+}
+
+// EchoMiddleware copies the caller set by the internal auth middleware, and the request id,
+// into the request context. It must run after the RequestID and RequireInternalAccess middlewares.
+func EchoMiddleware(adminAccess string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			service, _ := authmiddleware.CallerServiceFromContext(c)
+			access, _ := authmiddleware.CallerAllowedAccessFromContext(c)
+			identity := NewIdentity(service, access, adminAccess)
+			identity.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(NewContext(req.Context(), identity)))
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package caller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	authmiddleware "github.com/vibast-solutions/lib-go-auth/middleware"
+)
+
+func TestNewIdentityGrantsAdminFromAccess(t *testing.T) {
+	identity := NewIdentity("backoffice", []string{"profile-service", "profile-service:admin"}, "profile-service:admin")
+	if !identity.Admin {
+		t.Fatalf("expected admin identity, got %+v", identity)
+	}
+
+	identity = NewIdentity("billing", []string{"profile-service"}, "profile-service:admin")
+	if identity.Admin {
+		t.Fatalf("expected non-admin identity, got %+v", identity)
+	}
+
+	identity = NewIdentity("billing", []string{""}, "")
+	if identity.Admin {
+		t.Fatal("an empty admin grant must not match")
+	}
+}
+
+func TestFromContextWithoutIdentity(t *testing.T) {
+	if identity := FromContext(context.Background()); identity.Admin || identity.Service != "" {
+		t.Fatalf("expected zero identity, got %+v", identity)
+	}
+}
+
+func TestEchoMiddlewareCopiesCallerIntoRequestContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	rec.Header().Set(echo.HeaderXRequestID, "rest-42")
+	ctx := e.NewContext(req, rec)
+	ctx.Set(authmiddleware.ContextKeyCallerService, "backoffice")
+	ctx.Set(authmiddleware.ContextKeyCallerAllowedAccess, []string{"profile-service", "profile-service:admin"})
+
+	var got Identity
+	handler := EchoMiddleware("profile-service:admin")(func(c echo.Context) error {
+		got = FromContext(c.Request().Context())
+		return nil
+	})
+	if err := handler(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.Service != "backoffice" || !got.Admin || got.RequestID != "rest-42" {
+		t.Fatalf("unexpected identity: %+v", got)
+	}
+}
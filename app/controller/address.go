@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/factory"
@@ -35,19 +37,33 @@ func (c *AddressController) Create(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request body"})
 	}
 	if err = req.Validate(); err != nil {
-		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		return validationError(ctx, err)
 	}
 
-	l = factory.LoggerWithContext(l, ctx).WithField("profile_id", req.GetProfileId())
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id": req.GetProfileId(),
+		"company_id": req.GetCompanyId(),
+	})
 	l.Info("Create address request received")
 
 	address, err := c.addressService.Create(ctx.Request().Context(), req)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Create address failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.WithField("address_id", address.ID).Info("Address created")
+	setETag(ctx, address.Version)
 	return ctx.JSON(http.StatusCreated, toAddressResponse(address))
 }
 
@@ -61,11 +77,19 @@ func (c *AddressController) GetByID(ctx echo.Context) error {
 	if err = req.Validate(); err != nil {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
+	format, err := types.AddressFormatFromContext(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
 
 	l = factory.LoggerWithContext(l, ctx).WithField("address_id", req.GetId())
 	l.Info("Get address request received")
 
-	address, err := c.addressService.GetByID(ctx.Request().Context(), req.GetId())
+	address, err := c.addressService.GetByID(ctx.Request().Context(), req.GetId(), req.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrAddressNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "address not found"})
@@ -74,6 +98,10 @@ func (c *AddressController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
+	setETag(ctx, address.Version)
+	if format == types.AddressFormatLabel {
+		return ctx.String(http.StatusOK, service.FormatAddress(address).MultiLine()+"\n")
+	}
 	return ctx.JSON(http.StatusOK, toAddressResponse(address))
 }
 
@@ -85,7 +113,7 @@ func (c *AddressController) Update(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
 	}
 	if err = req.Validate(); err != nil {
-		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		return validationError(ctx, err)
 	}
 
 	l = factory.LoggerWithContext(l, ctx).WithField("address_id", req.GetId())
@@ -96,11 +124,75 @@ func (c *AddressController) Update(ctx echo.Context) error {
 		if errors.Is(err, service.ErrAddressNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "address not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target profile does not exist"})
+		}
+		if errors.Is(err, service.ErrTargetCompanyNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target company does not exist"})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Update address failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.Info("Address updated")
+	setETag(ctx, address.Version)
+	return ctx.JSON(http.StatusOK, toAddressResponse(address))
+}
+
+func (c *AddressController) Patch(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewPatchAddressRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create patch address request from context")
+		if errors.Is(err, types.ErrUnsupportedPatchContentType) {
+			return ctx.JSON(http.StatusUnsupportedMediaType, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"address_id":  req.GetId(),
+		"update_mask": req.GetUpdateMask().GetPaths(),
+	})
+	l.Info("Patch address request received")
+
+	address, err := c.addressService.Patch(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "address not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target profile does not exist"})
+		}
+		if errors.Is(err, service.ErrTargetCompanyNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target company does not exist"})
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
+		l.WithError(err).Error("Patch address failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Address patched")
+	setETag(ctx, address.Version)
 	return ctx.JSON(http.StatusOK, toAddressResponse(address))
 }
 
@@ -118,10 +210,13 @@ func (c *AddressController) Delete(ctx echo.Context) error {
 	l = factory.LoggerWithContext(l, ctx).WithField("address_id", req.GetId())
 	l.Info("Delete address request received")
 
-	if err = c.addressService.Delete(ctx.Request().Context(), req.GetId()); err != nil {
+	if err = c.addressService.Delete(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrAddressNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "address not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
 		l.WithError(err).Error("Delete address failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
@@ -141,11 +236,17 @@ func (c *AddressController) List(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
 
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
 	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
-		"profile_id": req.GetProfileId(),
-		"page":       req.GetPage(),
-		"page_size":  req.GetPageSize(),
-		"type":       req.GetType(),
+		"profile_id":      req.GetProfileId(),
+		"company_id":      req.GetCompanyId(),
+		"page":            req.GetPage(),
+		"page_size":       req.GetPageSize(),
+		"type":            req.GetType(),
+		"include_deleted": req.GetIncludeDeleted(),
 	})
 	l.Info("List addresses request received")
 
@@ -168,6 +269,122 @@ func (c *AddressController) List(ctx echo.Context) error {
 	})
 }
 
+func (c *AddressController) SearchNear(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewSearchAddressesNearRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create search addresses near request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	if req.GetProfileId() == 0 && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id":    req.GetProfileId(),
+		"type":          req.GetType(),
+		"radius_meters": req.GetRadiusMeters(),
+		"limit":         req.GetLimit(),
+	})
+	l.Info("Search addresses near request received")
+
+	found, err := c.addressService.SearchNear(ctx.Request().Context(), req)
+	if err != nil {
+		l.WithError(err).Error("Search addresses near failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	addresses := make([]*types.NearbyAddress, 0, len(found))
+	for _, nearby := range found {
+		addresses = append(addresses, &types.NearbyAddress{Address: toAddressResponse(nearby.Address), DistanceMeters: nearby.DistanceMeters})
+	}
+
+	return ctx.JSON(http.StatusOK, &types.SearchAddressesNearResponse{Addresses: addresses})
+}
+
+func (c *AddressController) Restore(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewRestoreAddressRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create restore address request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+	if !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("address_id", req.GetId())
+	l.Info("Restore address request received")
+
+	address, err := c.addressService.Restore(ctx.Request().Context(), req.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "address not found"})
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: "address is not deleted"})
+		}
+		if errors.Is(err, service.ErrProfileDeleted) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "profile is deleted; restore it first"})
+		}
+		if errors.Is(err, service.ErrCompanyDeleted) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "company is deleted; restore it first"})
+		}
+		l.WithError(err).Error("Restore address failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Address restored")
+	setETag(ctx, address.Version)
+	return ctx.JSON(http.StatusOK, toAddressResponse(address))
+}
+
+func (c *AddressController) SetPrimary(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewSetPrimaryAddressRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create set primary address request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("address_id", req.GetId())
+	l.Info("Set primary address request received")
+
+	address, err := c.addressService.SetPrimary(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion())
+	if err != nil {
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "address not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrPrimaryConflict) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		l.WithError(err).Error("Set primary address failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Address set as primary")
+	setETag(ctx, address.Version)
+	return ctx.JSON(http.StatusOK, toAddressResponse(address))
+}
+
+func toFormattedAddress(address *entity.Address) *types.FormattedAddress {
+	label := service.FormatAddress(address)
+	return &types.FormattedAddress{SingleLine: label.SingleLine(), MultiLine: label.MultiLine()}
+}
+
 func toAddressResponse(a *entity.Address) *types.AddressResponse {
 	return &types.AddressResponse{
 		Id:             a.ID,
@@ -177,12 +394,20 @@ func toAddressResponse(a *entity.Address) *types.AddressResponse {
 		County:         a.County,
 		Country:        a.Country,
 		ProfileId:      a.ProfileID,
+		CompanyId:      a.CompanyID,
 		PostalCode:     a.PostalCode,
 		Building:       a.Building,
 		Apartment:      a.Apartment,
 		AdditionalData: a.AdditionalData,
 		Type:           a.Type,
+		IsPrimary:      a.IsPrimary,
+		Latitude:       a.Latitude,
+		Longitude:      a.Longitude,
+		Formatted:      toFormattedAddress(a),
 		CreatedAt:      a.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:      a.UpdatedAt.Format(time.RFC3339),
+		Version:        a.Version,
+		DeletedAt:      formatDeletedAt(a.DeletedAt),
+		Attributes:     attribute.Struct(a.Attributes),
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,10 +18,16 @@ import (
 
 type addressRepoStub struct {
 	createFn   func(ctx context.Context, address *entity.Address) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Address, error)
-	updateFn   func(ctx context.Context, address *entity.Address) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error)
+
+	findPrimaryFn     func(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error)
+	updateFn          func(ctx context.Context, address *entity.Address) error
+	deleteFn          func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn         func(ctx context.Context, id uint64) error
+	listFn            func(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64) ([]*entity.Address, error)
+	listByCompanyIDFn func(ctx context.Context, companyID uint64) ([]*entity.Address, error)
+	searchNearFn      func(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error)
 }
 
 func (s *addressRepoStub) Create(ctx context.Context, address *entity.Address) error {
@@ -30,9 +37,16 @@ func (s *addressRepoStub) Create(ctx context.Context, address *entity.Address) e
 	return nil
 }
 
-func (s *addressRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Address, error) {
+func (s *addressRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *addressRepoStub) FindPrimary(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error) {
+	if s.findPrimaryFn != nil {
+		return s.findPrimaryFn(ctx, profileID, companyID, addressType)
 	}
 	return nil, nil
 }
@@ -44,22 +58,59 @@ func (s *addressRepoStub) Update(ctx context.Context, address *entity.Address) e
 	return nil
 }
 
-func (s *addressRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *addressRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *addressRepoStub) DeleteByProfileID(context.Context, uint64) error { return nil }
+
+func (s *addressRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
 	}
 	return nil
 }
 
-func (s *addressRepoStub) List(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+func (s *addressRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error { return nil }
+
+func (s *addressRepoStub) List(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 	if s.listFn != nil {
-		return s.listFn(ctx, profileID, addressType, limit, offset)
+		return s.listFn(ctx, profileID, companyID, addressType, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (s *addressRepoStub) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Address, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (s *addressRepoStub) DeleteByCompanyID(context.Context, uint64) error { return nil }
+
+func (s *addressRepoStub) RestoreByCompanyID(context.Context, uint64, time.Time) error { return nil }
+
+func (s *addressRepoStub) ListByCompanyID(ctx context.Context, companyID uint64) ([]*entity.Address, error) {
+	if s.listByCompanyIDFn != nil {
+		return s.listByCompanyIDFn(ctx, companyID)
+	}
+	return nil, nil
+}
+
+func (s *addressRepoStub) SearchNear(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+	if s.searchNearFn != nil {
+		return s.searchNearFn(ctx, search)
+	}
+	return nil, nil
+}
+
 func newAddressControllerWithRepo(repo *addressRepoStub) *AddressController {
-	svc := service.NewAddressService(repo)
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{Addresses: repo, Audit: &auditRepoStub{}, Outbox: &outboxRepoStub{}}}
+	svc := service.NewAddressService(repo, uow, nil, nil)
 	return NewAddressController(svc)
 }
 
@@ -87,7 +138,7 @@ func TestAddressCreateSuccess(t *testing.T) {
 		},
 	})
 	e := echo.New()
-	req := httptest.NewRequest(http.MethodPost, "/addresses", bytes.NewBufferString(`{"street_name":"Street","streen_no":"10","city":"City","county":"County","country":"Country","profile_id":7}`))
+	req := httptest.NewRequest(http.MethodPost, "/addresses", bytes.NewBufferString(`{"street_name":"Street","streen_no":"10","city":"City","county":"Cluj","country":"RO","profile_id":7}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
@@ -100,6 +151,58 @@ func TestAddressCreateSuccess(t *testing.T) {
 	}
 }
 
+func TestAddressCreateInvalidPostalCodeNamesField(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/addresses", bytes.NewBufferString(`{"street_name":"Street","streen_no":"10","city":"City","county":"Cluj","country":"Romania","postal_code":"4000","profile_id":7}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["field"] != "postal_code" {
+		t.Fatalf("expected postal_code field error, got: %s", rec.Body.String())
+	}
+}
+
+func TestAddressPatchCountyOfStoredCountryNamesField(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, County: "Cluj", Country: "RO"}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/addresses/3", bytes.NewBufferString(`{"county":"Bayern"}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Patch(ctx); err != nil {
+		t.Fatalf("Patch() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["field"] != "county" {
+		t.Fatalf("expected county field error, got: %s", rec.Body.String())
+	}
+}
+
 func TestAddressGetByIDNotFound(t *testing.T) {
 	ctrl := newAddressControllerWithRepo(&addressRepoStub{})
 	e := echo.New()
@@ -117,9 +220,54 @@ func TestAddressGetByIDNotFound(t *testing.T) {
 	}
 }
 
+func TestAddressGetByIDFormats(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, StreetName: "Strada Memorandumului", StreenNo: "28", City: "Cluj-Napoca", County: "Cluj", PostalCode: "400114", Country: "RO", Version: 2}, nil
+		},
+	})
+	e := echo.New()
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(httptest.NewRequest(http.MethodGet, url, nil), rec)
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("9")
+		if err := ctrl.GetByID(ctx); err != nil {
+			t.Fatalf("GetByID() returned unexpected error: %v", err)
+		}
+		return rec
+	}
+
+	rec := get("/addresses/9")
+	var body struct {
+		Formatted struct {
+			SingleLine string `json:"single_line"`
+			MultiLine  string `json:"multi_line"`
+		} `json:"formatted"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Formatted.SingleLine != "Strada Memorandumului nr. 28, 400114 Cluj-Napoca, jud. Cluj, ROMANIA" {
+		t.Fatalf("unexpected formatted address: %s", rec.Body.String())
+	}
+
+	rec = get("/addresses/9?format=label")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMETextPlain) {
+		t.Fatalf("expected a plain text label, got %d %q", rec.Code, rec.Header().Get(echo.HeaderContentType))
+	}
+	if rec.Body.String() != "Strada Memorandumului nr. 28\n400114 Cluj-Napoca, jud. Cluj\nROMANIA\n" || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("unexpected label: %q", rec.Body.String())
+	}
+
+	if rec = get("/addresses/9?format=pdf"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", rec.Code)
+	}
+}
+
 func TestAddressDeleteNotFound(t *testing.T) {
 	ctrl := newAddressControllerWithRepo(&addressRepoStub{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrAddressNotFound
 		},
 	})
@@ -156,7 +304,7 @@ func TestAddressListInvalidQuery(t *testing.T) {
 func TestAddressListSuccess(t *testing.T) {
 	now := time.Now()
 	ctrl := newAddressControllerWithRepo(&addressRepoStub{
-		listFn: func(_ context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+		listFn: func(_ context.Context, profileID, _ uint64, addressType string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 			if profileID != 7 || addressType != "billing" || limit != 5 || offset != 5 {
 				t.Fatalf("unexpected list args profileID=%d addressType=%q limit=%d offset=%d", profileID, addressType, limit, offset)
 			}
@@ -166,8 +314,8 @@ func TestAddressListSuccess(t *testing.T) {
 					StreetName: "Street",
 					StreenNo:   "10",
 					City:       "City",
-					County:     "County",
-					Country:    "Country",
+					County:     "Cluj",
+					Country:    "RO",
 					ProfileID:  7,
 					CreatedAt:  now,
 					UpdatedAt:  now,
@@ -195,3 +343,200 @@ func TestAddressListSuccess(t *testing.T) {
 		t.Fatalf("expected paginated payload, got: %s", rec.Body.String())
 	}
 }
+
+func TestAddressCreateMissingProfile(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		createFn: func(_ context.Context, _ *entity.Address) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/addresses", bytes.NewBufferString(`{"street_name":"Main","streen_no":"1","city":"City","county":"Cluj","country":"RO","profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestAddressUpdateMissingTargetProfile(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Address) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/addresses/3", bytes.NewBufferString(`{"street_name":"Main","streen_no":"1","city":"City","county":"Cluj","country":"RO","profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Update(ctx); err != nil {
+		t.Fatalf("Update() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAddressPatchCannotClearMandatoryField(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/addresses/3", bytes.NewBufferString(`{"city":null}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Patch(ctx); err != nil {
+		t.Fatalf("Patch() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestAddressRestoreNotFound(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		restoreFn: func(context.Context, uint64) error {
+			return repository.ErrAddressNotFound
+		},
+	})
+	e := echo.New()
+	req := asAdmin(httptest.NewRequest(http.MethodPost, "/addresses/6/restore", nil))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("6")
+
+	if err := ctrl.Restore(ctx); err != nil {
+		t.Fatalf("Restore() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestAddressSetPrimarySuccess(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 2, Type: "billing", Version: 3}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			address.Version++
+			return nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/addresses/8/primary", nil)
+	req.Header.Set("If-Match", `"3"`)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("8")
+
+	if err := ctrl.SetPrimary(ctx); err != nil {
+		t.Fatalf("SetPrimary() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"4"` {
+		t.Fatalf("expected ETag \"4\", got %q", etag)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["is_primary"] != true {
+		t.Fatalf("expected is_primary in response, got %s", rec.Body.String())
+	}
+}
+
+func TestAddressSetPrimaryVersionMismatch(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 2, Type: "billing", Version: 3}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/addresses/8/primary", nil)
+	req.Header.Set("If-Match", `"2"`)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("8")
+
+	if err := ctrl.SetPrimary(ctx); err != nil {
+		t.Fatalf("SetPrimary() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d", rec.Code)
+	}
+}
+
+func TestAddressSearchNear(t *testing.T) {
+	latitude, longitude := 46.7694, 23.5899
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{
+		searchNearFn: func(_ context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+			if search.Center == nil || search.RadiusMeters != 5000 || search.ProfileID != 2 {
+				t.Fatalf("unexpected search: %+v", search)
+			}
+			return []*entity.NearbyAddress{{Address: &entity.Address{ID: 8, ProfileID: 2, Latitude: &latitude, Longitude: &longitude}, DistanceMeters: 812.5}}, nil
+		},
+	})
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/addresses/near?lat=46.7712&lng=23.6236&radius_m=5000&profile_id=2", nil), rec)
+
+	if err := ctrl.SearchNear(ctx); err != nil {
+		t.Fatalf("SearchNear() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Addresses []struct {
+			Address        map[string]any `json:"address"`
+			DistanceMeters float64        `json:"distance_meters"`
+		} `json:"addresses"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Addresses) != 1 || body.Addresses[0].DistanceMeters != 812.5 || body.Addresses[0].Address["latitude"] != latitude {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+}
+
+func TestAddressSearchNearAcrossProfilesIsAdminOnly(t *testing.T) {
+	ctrl := newAddressControllerWithRepo(&addressRepoStub{})
+	e := echo.New()
+	for _, tc := range []struct {
+		req  *http.Request
+		code int
+	}{
+		{httptest.NewRequest(http.MethodGet, "/addresses/near?bbox=23.5,46.7,23.7,46.8", nil), http.StatusForbidden},
+		{asAdmin(httptest.NewRequest(http.MethodGet, "/addresses/near?bbox=23.5,46.7,23.7,46.8", nil)), http.StatusOK},
+		{asAdmin(httptest.NewRequest(http.MethodGet, "/addresses/near?lat=46.7712&lng=23.6236", nil)), http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		if err := ctrl.SearchNear(e.NewContext(tc.req, rec)); err != nil {
+			t.Fatalf("SearchNear() returned unexpected error: %v", err)
+		}
+		if rec.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.req.URL, tc.code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+type AttachmentController struct {
+	attachmentService *service.AttachmentService
+	logger            logrus.FieldLogger
+}
+
+func NewAttachmentController(attachmentService *service.AttachmentService) *AttachmentController {
+	return &AttachmentController{
+		attachmentService: attachmentService,
+		logger:            factory.NewModuleLogger("attachment-controller"),
+	}
+}
+
+// Upload stores the "file" part of a multipart/form-data body as an attachment of the record
+// named by its entity_type and entity_id fields.
+func (c *AttachmentController) Upload(ctx echo.Context) error {
+	l := c.logger
+	req, content, err := types.NewAttachmentUploadFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create upload attachment request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid multipart body"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"entity_type": req.GetEntityType(),
+		"entity_id":   req.GetEntityId(),
+		"kind":        req.GetKind(),
+	})
+	l.Info("Upload attachment request received")
+
+	attachment, err := c.attachmentService.Upload(ctx.Request().Context(), req, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAttachmentTooLarge):
+			return ctx.JSON(http.StatusRequestEntityTooLarge, httpdto.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, service.ErrUnsupportedContentType):
+			return ctx.JSON(http.StatusUnsupportedMediaType, httpdto.ErrorResponse{Error: err.Error(), Field: "content_type"})
+		case errors.Is(err, service.ErrEmptyAttachment):
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if notFound := attachmentOwnerNotFound(err); notFound != "" {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: notFound})
+		}
+		l.WithError(err).Error("Upload attachment failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.WithField("attachment_id", attachment.ID).Info("Attachment uploaded")
+	return ctx.JSON(http.StatusCreated, toAttachmentResponse(attachment))
+}
+
+func (c *AttachmentController) GetByID(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewGetAttachmentRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create get attachment request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("attachment_id", req.GetId())
+	l.Info("Get attachment request received")
+
+	attachment, err := c.attachmentService.Get(ctx.Request().Context(), req.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "attachment not found"})
+		}
+		l.WithError(err).Error("Get attachment failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	return ctx.JSON(http.StatusOK, toAttachmentResponse(attachment))
+}
+
+// Download streams the content of an attachment with the content type it was stored with.
+func (c *AttachmentController) Download(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewDownloadAttachmentRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create download attachment request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("attachment_id", req.GetId())
+	l.Info("Download attachment request received")
+
+	attachment, content, err := c.attachmentService.Open(ctx.Request().Context(), req.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "attachment not found"})
+		}
+		l.WithError(err).Error("Download attachment failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+	defer content.Close()
+
+	writeAttachmentHeaders(ctx, attachment.Filename, attachment.ContentType, attachment.Size)
+	return ctx.Stream(http.StatusOK, attachment.ContentType, content)
+}
+
+func (c *AttachmentController) List(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewListAttachmentsRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create list attachments request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"entity_type": req.GetEntityType(),
+		"entity_id":   req.GetEntityId(),
+		"kind":        req.GetKind(),
+		"page":        req.GetPage(),
+		"page_size":   req.GetPageSize(),
+	})
+	l.Info("List attachments request received")
+
+	result, err := c.attachmentService.List(ctx.Request().Context(), req)
+	if err != nil {
+		if notFound := attachmentOwnerNotFound(err); notFound != "" {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: notFound})
+		}
+		l.WithError(err).Error("List attachments failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	attachments := make([]*types.AttachmentResponse, 0, len(result.Attachments))
+	for _, attachment := range result.Attachments {
+		attachments = append(attachments, toAttachmentResponse(attachment))
+	}
+
+	return ctx.JSON(http.StatusOK, &types.ListAttachmentsResponse{
+		Attachments: attachments,
+		Page:        result.Page,
+		PageSize:    result.PageSize,
+		Total:       result.Total,
+	})
+}
+
+func (c *AttachmentController) Delete(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewDeleteAttachmentRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create delete attachment request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("attachment_id", req.GetId())
+	l.Info("Delete attachment request received")
+
+	if err = c.attachmentService.Delete(ctx.Request().Context(), req.GetId()); err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "attachment not found"})
+		}
+		l.WithError(err).Error("Delete attachment failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Attachment deleted")
+	return ctx.JSON(http.StatusOK, httpdto.DeleteResponse{Message: "attachment deleted successfully"})
+}
+
+// writeAttachmentHeaders describes a download so that browsers save it rather than render it.
+func writeAttachmentHeaders(ctx echo.Context, filename, contentType string, size int64) {
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(size, 10))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	disposition := "attachment"
+	if filename != "" {
+		disposition = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+	header.Set(echo.HeaderContentDisposition, disposition)
+}
+
+// attachmentOwnerNotFound returns the message for an upload or list naming a record that does
+// not exist or is deleted, and "" for other errors.
+func attachmentOwnerNotFound(err error) string {
+	switch {
+	case errors.Is(err, service.ErrProfileNotFound):
+		return "profile not found"
+	case errors.Is(err, service.ErrContactNotFound):
+		return "contact not found"
+	case errors.Is(err, service.ErrAddressNotFound):
+		return "address not found"
+	case errors.Is(err, service.ErrCompanyNotFound):
+		return "company not found"
+	}
+
+	return ""
+}
+
+func toAttachmentResponse(attachment *entity.Attachment) *types.AttachmentResponse {
+	return &types.AttachmentResponse{
+		Id:          attachment.ID,
+		EntityType:  attachment.EntityType,
+		EntityId:    attachment.EntityID,
+		Kind:        attachment.Kind,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Sha256:      attachment.SHA256,
+		CreatedAt:   attachment.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/blobstore"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+)
+
+// pngHeader is the start of a PNG image, enough for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type attachmentRepoStub struct {
+	attachments map[uint64]*entity.Attachment
+}
+
+func (s *attachmentRepoStub) Create(_ context.Context, attachment *entity.Attachment) error {
+	attachment.ID = uint64(len(s.attachments) + 1)
+	s.attachments[attachment.ID] = attachment
+	return nil
+}
+
+func (s *attachmentRepoStub) FindByID(_ context.Context, id uint64) (*entity.Attachment, error) {
+	return s.attachments[id], nil
+}
+
+func (s *attachmentRepoStub) Delete(_ context.Context, id uint64) error {
+	delete(s.attachments, id)
+	return nil
+}
+
+func (s *attachmentRepoStub) List(context.Context, string, uint64, string, uint32, uint32) ([]*entity.Attachment, uint64, error) {
+	return nil, 0, nil
+}
+
+// newAttachmentController stores attachments of profile 7 only, accepting PNG images of up to
+// 64 bytes.
+func newAttachmentController(t *testing.T) *AttachmentController {
+	t.Helper()
+	profiles := &controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			if id != 7 {
+				return nil, nil
+			}
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{
+		Profiles:    profiles,
+		Attachments: &attachmentRepoStub{attachments: map[uint64]*entity.Attachment{}},
+		Audit:       &auditRepoStub{},
+		Outbox:      &outboxRepoStub{},
+	}}
+	return NewAttachmentController(service.NewAttachmentService(uow, blobstore.NewLocal(t.TempDir()), service.AttachmentLimits{
+		MaxSize:      64,
+		ContentTypes: []string{"image/png"},
+	}))
+}
+
+// newUploadRequest builds a multipart upload for profile entityID, with a file part holding
+// content when content is not nil.
+func newUploadRequest(t *testing.T, target, entityID string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("entity_type", "profile")
+	_ = writer.WriteField("entity_id", entityID)
+	_ = writer.WriteField("kind", "avatar")
+	if content != nil {
+		part, err := writer.CreateFormFile("file", `C:\photos\me.png`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = part.Write(content)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func TestAttachmentUploadAndDownload(t *testing.T) {
+	ctrl := newAttachmentController(t)
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	if err := ctrl.Upload(e.NewContext(newUploadRequest(t, "/attachments", "7", pngHeader), rec)); err != nil {
+		t.Fatalf("Upload() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var uploaded types.AttachmentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &uploaded); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Filename != "me.png" || uploaded.ContentType != "image/png" || uploaded.Kind != "avatar" || len(uploaded.Sha256) != 64 {
+		t.Fatalf("unexpected attachment: %+v", &uploaded)
+	}
+
+	rec = httptest.NewRecorder()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/attachments/1/content", nil), rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("1")
+	if err := ctrl.Download(ctx); err != nil {
+		t.Fatalf("Download() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), pngHeader) {
+		t.Fatalf("unexpected download: %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); got != "image/png" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	if got := rec.Header().Get(echo.HeaderContentDisposition); got != `attachment; filename=me.png` {
+		t.Fatalf("unexpected content disposition %q", got)
+	}
+	if got := rec.Header().Get(echo.HeaderXContentTypeOptions); got != "nosniff" {
+		t.Fatalf("unexpected X-Content-Type-Options %q", got)
+	}
+}
+
+func TestAttachmentUploadStatuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		entityID string
+		content  []byte
+		status   int
+	}{
+		{"no file part", "7", nil, http.StatusBadRequest},
+		{"empty file", "7", []byte{}, http.StatusBadRequest},
+		{"too large", "7", make([]byte, 65), http.StatusRequestEntityTooLarge},
+		{"unsupported type", "7", []byte("%PDF-1.7"), http.StatusUnsupportedMediaType},
+		{"unknown profile", "8", pngHeader, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx := echo.New().NewContext(newUploadRequest(t, "/attachments", tt.entityID, tt.content), rec)
+			if err := newAttachmentController(t).Upload(ctx); err != nil {
+				t.Fatalf("Upload() returned unexpected error: %v", err)
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
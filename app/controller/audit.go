@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditController struct {
+	auditService *service.AuditService
+	logger       logrus.FieldLogger
+}
+
+func NewAuditController(auditService *service.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+		logger:       factory.NewModuleLogger("audit-controller"),
+	}
+}
+
+// List returns the change history of one record. Admin callers only.
+func (c *AuditController) List(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewListAuditEventsRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create list audit events request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	if !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"entity":    req.GetEntity(),
+		"entity_id": req.GetEntityId(),
+		"page":      req.GetPage(),
+		"page_size": req.GetPageSize(),
+	})
+	l.Info("List audit events request received")
+
+	result, err := c.auditService.List(ctx.Request().Context(), req)
+	if err != nil {
+		l.WithError(err).Error("List audit events failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	events := make([]*types.AuditEventResponse, 0, len(result.Events))
+	for _, event := range result.Events {
+		events = append(events, toAuditEventResponse(event))
+	}
+
+	return ctx.JSON(http.StatusOK, &types.ListAuditEventsResponse{
+		Events:   events,
+		Page:     result.Page,
+		PageSize: result.PageSize,
+		Total:    result.Total,
+	})
+}
+
+func toAuditEventResponse(event *entity.AuditEvent) *types.AuditEventResponse {
+	return &types.AuditEventResponse{
+		Id:            event.ID,
+		EntityType:    event.EntityType,
+		EntityId:      event.EntityID,
+		Action:        event.Action,
+		OldValues:     string(event.OldValues),
+		NewValues:     string(event.NewValues),
+		CallerService: event.CallerService,
+		RequestId:     event.RequestID,
+		CreatedAt:     event.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+)
+
+type auditRepoStub struct {
+	listFn func(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error)
+}
+
+func (s *auditRepoStub) Create(context.Context, *entity.AuditEvent) error { return nil }
+
+func (s *auditRepoStub) List(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error) {
+	if s.listFn != nil {
+		return s.listFn(ctx, entityType, entityID, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+type outboxRepoStub struct{}
+
+func (s *outboxRepoStub) Create(context.Context, *entity.OutboxEvent) error { return nil }
+
+func newAuditControllerWithRepo(repo *auditRepoStub) *AuditController {
+	return NewAuditController(service.NewAuditService(repo))
+}
+
+func TestAuditListRequiresAdmin(t *testing.T) {
+	ctrl := newAuditControllerWithRepo(&auditRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/audit?entity=profile&id=5", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.List(ctx); err != nil {
+		t.Fatalf("List() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestAuditListInvalidEntity(t *testing.T) {
+	ctrl := newAuditControllerWithRepo(&auditRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/audit?entity=user&id=5", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(asAdmin(req), rec)
+
+	if err := ctrl.List(ctx); err != nil {
+		t.Fatalf("List() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestAuditListSuccess(t *testing.T) {
+	ctrl := newAuditControllerWithRepo(&auditRepoStub{
+		listFn: func(_ context.Context, entityType string, entityID uint64, _, _ uint32) ([]*entity.AuditEvent, uint64, error) {
+			if entityType != "contact" || entityID != 9 {
+				t.Fatalf("unexpected list args entityType=%q entityID=%d", entityType, entityID)
+			}
+			return []*entity.AuditEvent{{
+				ID:            1,
+				EntityType:    entityType,
+				EntityID:      entityID,
+				Action:        "update",
+				OldValues:     []byte(`{"phone":"111"}`),
+				NewValues:     []byte(`{"phone":"222"}`),
+				CallerService: "billing-service",
+				RequestID:     "rest-1",
+				CreatedAt:     time.Now(),
+			}}, 1, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/audit?entity=contact&id=9", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(asAdmin(req), rec)
+
+	if err := ctrl.List(ctx); err != nil {
+		t.Fatalf("List() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp types.ListAuditEventsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Events) != 1 || resp.Events[0].NewValues != `{"phone":"222"}` || resp.Events[0].CallerService != "billing-service" {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 	"github.com/vibast-solutions/ms-go-profile/app/types"
 
@@ -43,12 +46,23 @@ func (c *CompanyController) Create(ctx echo.Context) error {
 
 	company, err := c.companyService.Create(ctx.Request().Context(), req)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error(), Field: "fiscal_code"})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Create company failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.WithField("company_id", company.ID).Info("Company created")
-	return ctx.JSON(http.StatusCreated, toCompanyResponse(company))
+	setETag(ctx, company.Version)
+	return ctx.JSON(http.StatusCreated, toCompanyResponse(ctx, company))
 }
 
 func (c *CompanyController) GetByID(ctx echo.Context) error {
@@ -62,10 +76,14 @@ func (c *CompanyController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
 
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
 	l = factory.LoggerWithContext(l, ctx).WithField("company_id", req.GetId())
 	l.Info("Get company request received")
 
-	company, err := c.companyService.GetByID(ctx.Request().Context(), req.GetId())
+	company, err := c.companyService.GetByID(ctx.Request().Context(), req.GetId(), req.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrCompanyNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
@@ -74,7 +92,20 @@ func (c *CompanyController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
-	return ctx.JSON(http.StatusOK, toCompanyResponse(company))
+	response := toCompanyResponse(ctx, company)
+	if req.GetIncludeAddresses() {
+		addresses, err := c.companyService.ListAddresses(ctx.Request().Context(), company.ID)
+		if err != nil {
+			l.WithError(err).Error("List company addresses failed")
+			return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+		}
+		for _, address := range addresses {
+			response.Addresses = append(response.Addresses, toAddressResponse(address))
+		}
+	}
+
+	setETag(ctx, company.Version)
+	return ctx.JSON(http.StatusOK, response)
 }
 
 func (c *CompanyController) Update(ctx echo.Context) error {
@@ -96,12 +127,76 @@ func (c *CompanyController) Update(ctx echo.Context) error {
 		if errors.Is(err, service.ErrCompanyNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target profile does not exist"})
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error(), Field: "fiscal_code"})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Update company failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.Info("Company updated")
-	return ctx.JSON(http.StatusOK, toCompanyResponse(company))
+	setETag(ctx, company.Version)
+	return ctx.JSON(http.StatusOK, toCompanyResponse(ctx, company))
+}
+
+func (c *CompanyController) Patch(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewPatchCompanyRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create patch company request from context")
+		if errors.Is(err, types.ErrUnsupportedPatchContentType) {
+			return ctx.JSON(http.StatusUnsupportedMediaType, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"company_id":  req.GetId(),
+		"update_mask": req.GetUpdateMask().GetPaths(),
+	})
+	l.Info("Patch company request received")
+
+	company, err := c.companyService.Patch(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target profile does not exist"})
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error(), Field: "fiscal_code"})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
+		l.WithError(err).Error("Patch company failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Company patched")
+	setETag(ctx, company.Version)
+	return ctx.JSON(http.StatusOK, toCompanyResponse(ctx, company))
 }
 
 func (c *CompanyController) Delete(ctx echo.Context) error {
@@ -118,10 +213,13 @@ func (c *CompanyController) Delete(ctx echo.Context) error {
 	l = factory.LoggerWithContext(l, ctx).WithField("company_id", req.GetId())
 	l.Info("Delete company request received")
 
-	if err = c.companyService.Delete(ctx.Request().Context(), req.GetId()); err != nil {
+	if err = c.companyService.Delete(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrCompanyNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
 		l.WithError(err).Error("Delete company failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
@@ -141,11 +239,16 @@ func (c *CompanyController) List(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
 
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
 	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
-		"profile_id": req.GetProfileId(),
-		"page":       req.GetPage(),
-		"page_size":  req.GetPageSize(),
-		"type":       req.GetType(),
+		"profile_id":      req.GetProfileId(),
+		"page":            req.GetPage(),
+		"page_size":       req.GetPageSize(),
+		"type":            req.GetType(),
+		"include_deleted": req.GetIncludeDeleted(),
 	})
 	l.Info("List companies request received")
 
@@ -157,7 +260,7 @@ func (c *CompanyController) List(ctx echo.Context) error {
 
 	companies := make([]*types.CompanyResponse, 0, len(result.Companies))
 	for _, company := range result.Companies {
-		companies = append(companies, toCompanyResponse(company))
+		companies = append(companies, toCompanyResponse(ctx, company))
 	}
 
 	return ctx.JSON(http.StatusOK, &types.ListCompaniesResponse{
@@ -168,15 +271,96 @@ func (c *CompanyController) List(ctx echo.Context) error {
 	})
 }
 
-func toCompanyResponse(company *entity.Company) *types.CompanyResponse {
+func (c *CompanyController) Restore(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewRestoreCompanyRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create restore company request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+	if !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("company_id", req.GetId())
+	l.Info("Restore company request received")
+
+	company, err := c.companyService.Restore(ctx.Request().Context(), req.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: "company is not deleted"})
+		}
+		if errors.Is(err, service.ErrProfileDeleted) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "profile is deleted; restore it first"})
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error(), Field: "fiscal_code"})
+		}
+		l.WithError(err).Error("Restore company failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Company restored")
+	setETag(ctx, company.Version)
+	return ctx.JSON(http.StatusOK, toCompanyResponse(ctx, company))
+}
+
+func (c *CompanyController) SetPrimary(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewSetPrimaryCompanyRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create set primary company request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("company_id", req.GetId())
+	l.Info("Set primary company request received")
+
+	company, err := c.companyService.SetPrimary(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion())
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrPrimaryConflict) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		l.WithError(err).Error("Set primary company failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Company set as primary")
+	setETag(ctx, company.Version)
+	return ctx.JSON(http.StatusOK, toCompanyResponse(ctx, company))
+}
+
+// toCompanyResponse maps a company, applying the caller's field rules.
+func toCompanyResponse(ctx echo.Context, company *entity.Company) *types.CompanyResponse {
 	return &types.CompanyResponse{
-		Id:             company.ID,
-		Name:           company.Name,
-		RegistrationNo: company.RegistrationNo,
-		FiscalCode:     company.FiscalCode,
-		ProfileId:      company.ProfileID,
-		Type:           company.Type,
-		CreatedAt:      company.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:      company.UpdatedAt.Format(time.RFC3339),
+		Id:              company.ID,
+		Name:            company.Name,
+		RegistrationNo:  company.RegistrationNo,
+		FiscalCode:      fieldpolicy.FromContext(ctx.Request().Context()).Apply(fieldpolicy.CompanyFiscalCode, company.FiscalCode),
+		ProfileId:       company.ProfileID,
+		Type:            company.Type,
+		IsPrimary:       company.IsPrimary,
+		CreatedAt:       company.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       company.UpdatedAt.Format(time.RFC3339),
+		Version:         company.Version,
+		DeletedAt:       formatDeletedAt(company.DeletedAt),
+		FiscalCodeValid: company.FiscalCodeValid,
+		VatPayerPrefix:  company.VATPayerPrefix,
+		Attributes:      attribute.Struct(company.Attributes),
 	}
 }
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+func (c *CompanyController) AddMember(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewAddCompanyMemberRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create add company member request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"company_id": req.GetCompanyId(),
+		"profile_id": req.GetProfileId(),
+		"role":       req.GetRole(),
+	})
+	l.Info("Add company member request received")
+
+	member, err := c.companyService.AddMember(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
+		}
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrLastCompanyOwner) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		l.WithError(err).Error("Add company member failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Company member added")
+	return ctx.JSON(http.StatusOK, toCompanyMemberResponse(member))
+}
+
+func (c *CompanyController) RemoveMember(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewRemoveCompanyMemberRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create remove company member request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"company_id": req.GetCompanyId(),
+		"profile_id": req.GetProfileId(),
+	})
+	l.Info("Remove company member request received")
+
+	if err = c.companyService.RemoveMember(ctx.Request().Context(), req.GetCompanyId(), req.GetProfileId()); err != nil {
+		if errors.Is(err, service.ErrCompanyMemberNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if errors.Is(err, service.ErrLastCompanyOwner) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		l.WithError(err).Error("Remove company member failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Company member removed")
+	return ctx.JSON(http.StatusOK, httpdto.DeleteResponse{Message: "company member removed successfully"})
+}
+
+func (c *CompanyController) ListMembers(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewListCompanyMembersRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create list company members request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"company_id": req.GetCompanyId(),
+		"page":       req.GetPage(),
+		"page_size":  req.GetPageSize(),
+	})
+	l.Info("List company members request received")
+
+	result, err := c.companyService.ListMembers(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "company not found"})
+		}
+		l.WithError(err).Error("List company members failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	members := make([]*types.CompanyMemberResponse, 0, len(result.Members))
+	for _, member := range result.Members {
+		members = append(members, toCompanyMemberResponse(member))
+	}
+
+	return ctx.JSON(http.StatusOK, &types.ListCompanyMembersResponse{
+		Members:  members,
+		Page:     result.Page,
+		PageSize: result.PageSize,
+		Total:    result.Total,
+	})
+}
+
+// ListProfileCompanies lists the companies the profile is a member of, with its role in each.
+func (c *CompanyController) ListProfileCompanies(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewListProfileCompaniesRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create list profile companies request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id": req.GetProfileId(),
+		"page":       req.GetPage(),
+		"page_size":  req.GetPageSize(),
+	})
+	l.Info("List profile companies request received")
+
+	result, err := c.companyService.ListProfileCompanies(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		l.WithError(err).Error("List profile companies failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	companies := make([]*types.ProfileCompanyResponse, 0, len(result.Memberships))
+	for _, membership := range result.Memberships {
+		companies = append(companies, &types.ProfileCompanyResponse{
+			Company: toCompanyResponse(ctx, membership.Company),
+			Role:    membership.Role,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, &types.ListProfileCompaniesResponse{
+		Companies: companies,
+		Page:      result.Page,
+		PageSize:  result.PageSize,
+		Total:     result.Total,
+	})
+}
+
+func toCompanyMemberResponse(member *entity.CompanyMember) *types.CompanyMemberResponse {
+	return &types.CompanyMemberResponse{
+		CompanyId: member.CompanyID,
+		ProfileId: member.ProfileID,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: member.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+)
+
+type companyMemberRepoStub struct {
+	saveFn            func(ctx context.Context, member *entity.CompanyMember) error
+	findFn            func(ctx context.Context, companyID, profileID uint64) (*entity.CompanyMember, error)
+	countByRoleFn     func(ctx context.Context, companyID uint64, role string) (uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64, limit, offset uint32) ([]*entity.CompanyMembership, uint64, error)
+}
+
+func (s *companyMemberRepoStub) Save(ctx context.Context, member *entity.CompanyMember) error {
+	if s.saveFn != nil {
+		return s.saveFn(ctx, member)
+	}
+	return nil
+}
+
+func (s *companyMemberRepoStub) Find(ctx context.Context, companyID, profileID uint64) (*entity.CompanyMember, error) {
+	if s.findFn != nil {
+		return s.findFn(ctx, companyID, profileID)
+	}
+	return nil, nil
+}
+
+func (s *companyMemberRepoStub) CountByRole(ctx context.Context, companyID uint64, role string) (uint64, error) {
+	if s.countByRoleFn != nil {
+		return s.countByRoleFn(ctx, companyID, role)
+	}
+	return 0, nil
+}
+
+func (s *companyMemberRepoStub) Delete(context.Context, uint64, uint64) error { return nil }
+
+func (s *companyMemberRepoStub) ListByCompanyID(context.Context, uint64, uint32, uint32) ([]*entity.CompanyMember, uint64, error) {
+	return nil, 0, nil
+}
+
+func (s *companyMemberRepoStub) ListByProfileID(ctx context.Context, profileID uint64, limit, offset uint32) ([]*entity.CompanyMembership, uint64, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+func newCompanyMemberController(members *companyMemberRepoStub) *CompanyController {
+	companies := &companyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 7}, nil
+		},
+	}
+	profiles := &controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{
+		Profiles:       profiles,
+		Companies:      companies,
+		CompanyMembers: members,
+		Audit:          &auditRepoStub{},
+		Outbox:         &outboxRepoStub{},
+	}}
+	return NewCompanyController(service.NewCompanyService(companies, uow, fiscal.NewValidators(""), nil))
+}
+
+func TestCompanyAddMemberSuccess(t *testing.T) {
+	var saved *entity.CompanyMember
+	ctrl := newCompanyMemberController(&companyMemberRepoStub{
+		saveFn: func(_ context.Context, member *entity.CompanyMember) error {
+			saved = member
+			return nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/companies/5/members/8", bytes.NewBufferString(`{"role":"accountant"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id", "profile_id")
+	ctx.SetParamValues("5", "8")
+
+	if err := ctrl.AddMember(ctx); err != nil {
+		t.Fatalf("AddMember() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if saved == nil || saved.CompanyID != 5 || saved.ProfileID != 8 || saved.Role != entity.CompanyRoleAccountant {
+		t.Fatalf("unexpected saved member: %+v", saved)
+	}
+}
+
+func TestCompanyAddMemberStatuses(t *testing.T) {
+	cases := []struct {
+		name  string
+		body  string
+		owner bool
+		want  int
+	}{
+		{"unknown role", `{"role":"auditor"}`, false, http.StatusBadRequest},
+		{"demotes last owner", `{"role":"viewer"}`, true, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		ctrl := newCompanyMemberController(&companyMemberRepoStub{
+			findFn: func(_ context.Context, companyID, profileID uint64) (*entity.CompanyMember, error) {
+				if !tc.owner {
+					return nil, nil
+				}
+				return &entity.CompanyMember{CompanyID: companyID, ProfileID: profileID, Role: entity.CompanyRoleOwner}, nil
+			},
+			countByRoleFn: func(context.Context, uint64, string) (uint64, error) {
+				return 1, nil
+			},
+		})
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/companies/5/members/7", bytes.NewBufferString(tc.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("id", "profile_id")
+		ctx.SetParamValues("5", "7")
+
+		if err := ctrl.AddMember(ctx); err != nil {
+			t.Fatalf("%s: AddMember() returned unexpected error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCompanyListProfileCompanies(t *testing.T) {
+	now := time.Now()
+	ctrl := newCompanyMemberController(&companyMemberRepoStub{
+		listByProfileIDFn: func(_ context.Context, profileID uint64, limit, offset uint32) ([]*entity.CompanyMembership, uint64, error) {
+			if profileID != 8 || limit != 5 || offset != 5 {
+				t.Fatalf("unexpected list args profileID=%d limit=%d offset=%d", profileID, limit, offset)
+			}
+			return []*entity.CompanyMembership{{
+				Company: &entity.Company{ID: 5, Name: "ACME", ProfileID: 7, CreatedAt: now, UpdatedAt: now},
+				Role:    entity.CompanyRoleViewer,
+			}}, 6, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/profiles/8/companies?page=2&page_size=5", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("8")
+
+	if err := ctrl.ListProfileCompanies(ctx); err != nil {
+		t.Fatalf("ListProfileCompanies() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var payload struct {
+		Companies []struct {
+			Company struct {
+				ID        uint64 `json:"id"`
+				ProfileID uint64 `json:"profile_id"`
+			} `json:"company"`
+			Role string `json:"role"`
+		} `json:"companies"`
+		Total uint64 `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload.Total != 6 || len(payload.Companies) != 1 || payload.Companies[0].Company.ID != 5 || payload.Companies[0].Role != "viewer" {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+}
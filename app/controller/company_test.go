@@ -11,16 +11,22 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 )
 
 type companyRepoStub struct {
 	createFn   func(ctx context.Context, company *entity.Company) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Company, error)
-	updateFn   func(ctx context.Context, company *entity.Company) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error)
+
+	findPrimaryFn     func(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error)
+	updateFn          func(ctx context.Context, company *entity.Company) error
+	deleteFn          func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn         func(ctx context.Context, id uint64) error
+	listFn            func(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64) ([]*entity.Company, error)
+	listForProfileFn  func(ctx context.Context, profileID uint64) ([]*entity.Company, error)
 }
 
 func (s *companyRepoStub) Create(ctx context.Context, company *entity.Company) error {
@@ -30,13 +36,24 @@ func (s *companyRepoStub) Create(ctx context.Context, company *entity.Company) e
 	return nil
 }
 
-func (s *companyRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Company, error) {
+func (s *companyRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *companyRepoStub) FindPrimary(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error) {
+	if s.findPrimaryFn != nil {
+		return s.findPrimaryFn(ctx, profileID, companyType)
 	}
 	return nil, nil
 }
 
+func (s *companyRepoStub) FindByFiscalCode(context.Context, string) (*entity.Company, error) {
+	return nil, nil
+}
+
 func (s *companyRepoStub) Update(ctx context.Context, company *entity.Company) error {
 	if s.updateFn != nil {
 		return s.updateFn(ctx, company)
@@ -44,22 +61,48 @@ func (s *companyRepoStub) Update(ctx context.Context, company *entity.Company) e
 	return nil
 }
 
-func (s *companyRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *companyRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *companyRepoStub) DeleteByProfileID(context.Context, uint64) error { return nil }
+
+func (s *companyRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
 	}
 	return nil
 }
 
-func (s *companyRepoStub) List(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+func (s *companyRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error { return nil }
+
+func (s *companyRepoStub) List(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
 	if s.listFn != nil {
-		return s.listFn(ctx, profileID, companyType, limit, offset)
+		return s.listFn(ctx, profileID, companyType, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (s *companyRepoStub) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (s *companyRepoStub) ListForProfile(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	if s.listForProfileFn != nil {
+		return s.listForProfileFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
 func newCompanyControllerWithRepo(repo *companyRepoStub) *CompanyController {
-	svc := service.NewCompanyService(repo)
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{Companies: repo, Addresses: &addressRepoStub{}, CompanyMembers: &companyMemberRepoStub{}, Audit: &auditRepoStub{}, Outbox: &outboxRepoStub{}}}
+	svc := service.NewCompanyService(repo, uow, fiscal.NewValidators(""), nil)
 	return NewCompanyController(svc)
 }
 
@@ -87,7 +130,7 @@ func TestCompanyCreateSuccess(t *testing.T) {
 		},
 	})
 	e := echo.New()
-	req := httptest.NewRequest(http.MethodPost, "/companies", bytes.NewBufferString(`{"name":"ACME","registration_no":"REG-1","fiscal_code":"FISC-1","profile_id":4}`))
+	req := httptest.NewRequest(http.MethodPost, "/companies", bytes.NewBufferString(`{"name":"ACME","registration_no":"J40/123/2020","fiscal_code":"RO18547290","profile_id":4}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
@@ -98,6 +141,36 @@ func TestCompanyCreateSuccess(t *testing.T) {
 	if rec.Code != http.StatusCreated {
 		t.Fatalf("expected 201, got %d", rec.Code)
 	}
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["fiscal_code_valid"] != true || payload["vat_payer_prefix"] != "RO" {
+		t.Fatalf("expected a valid RO fiscal code, got: %s", rec.Body.String())
+	}
+}
+
+func TestCompanyCreateInvalidRegistrationNoNamesField(t *testing.T) {
+	ctrl := newCompanyControllerWithRepo(&companyRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/companies", bytes.NewBufferString(`{"name":"ACME","registration_no":"J40/123/1899","fiscal_code":"RO18547290","profile_id":4}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["field"] != "registration_no" {
+		t.Fatalf("expected registration_no field error, got: %s", rec.Body.String())
+	}
 }
 
 func TestCompanyGetByIDNotFound(t *testing.T) {
@@ -117,9 +190,48 @@ func TestCompanyGetByIDNotFound(t *testing.T) {
 	}
 }
 
+func TestCompanyGetByIDIncludesAddresses(t *testing.T) {
+	repo := &companyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", ProfileID: 4}, nil
+		},
+	}
+	addresses := &addressRepoStub{
+		listByCompanyIDFn: func(_ context.Context, companyID uint64) ([]*entity.Address, error) {
+			return []*entity.Address{{ID: 31, CompanyID: companyID, Type: "registered_office", Country: "RO"}}, nil
+		},
+	}
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{Companies: repo, Addresses: addresses, Audit: &auditRepoStub{}, Outbox: &outboxRepoStub{}}}
+	ctrl := NewCompanyController(service.NewCompanyService(repo, uow, fiscal.NewValidators(""), nil))
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/companies/9?include_addresses=true", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("9")
+
+	if err := ctrl.GetByID(ctx); err != nil {
+		t.Fatalf("GetByID() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var payload struct {
+		Addresses []struct {
+			CompanyID uint64 `json:"company_id"`
+		} `json:"addresses"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(payload.Addresses) != 1 || payload.Addresses[0].CompanyID != 9 {
+		t.Fatalf("expected the company address to be embedded, got: %s", rec.Body.String())
+	}
+}
+
 func TestCompanyDeleteNotFound(t *testing.T) {
 	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrCompanyNotFound
 		},
 	})
@@ -156,7 +268,7 @@ func TestCompanyListInvalidQuery(t *testing.T) {
 func TestCompanyListSuccess(t *testing.T) {
 	now := time.Now()
 	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
-		listFn: func(_ context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+		listFn: func(_ context.Context, profileID uint64, companyType string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
 			if profileID != 7 || companyType != "vendor" || limit != 5 || offset != 5 {
 				t.Fatalf("unexpected list args profileID=%d companyType=%q limit=%d offset=%d", profileID, companyType, limit, offset)
 			}
@@ -164,8 +276,8 @@ func TestCompanyListSuccess(t *testing.T) {
 				{
 					ID:             1,
 					Name:           "ACME",
-					RegistrationNo: "REG-1",
-					FiscalCode:     "FISC-1",
+					RegistrationNo: "J40/123/2020",
+					FiscalCode:     "RO18547290",
 					ProfileID:      7,
 					CreatedAt:      now,
 					UpdatedAt:      now,
@@ -193,3 +305,130 @@ func TestCompanyListSuccess(t *testing.T) {
 		t.Fatalf("expected paginated payload, got: %s", rec.Body.String())
 	}
 }
+
+func TestCompanyCreateMissingProfile(t *testing.T) {
+	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
+		createFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/companies", bytes.NewBufferString(`{"name":"ACME","registration_no":"J40/1/2020","fiscal_code":"RO18547290","profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestCompanyUpdateMissingTargetProfile(t *testing.T) {
+	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/companies/3", bytes.NewBufferString(`{"name":"ACME","registration_no":"J40/1/2020","fiscal_code":"RO18547290","profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Update(ctx); err != nil {
+		t.Fatalf("Update() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCompanyPatchMissingTargetProfile(t *testing.T) {
+	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/companies/3", bytes.NewBufferString(`{"profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Patch(ctx); err != nil {
+		t.Fatalf("Patch() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCompanyRestoreSuccess(t *testing.T) {
+	restored := false
+	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, includeDeleted bool) (*entity.Company, error) {
+			if includeDeleted {
+				if restored {
+					t.Fatal("restored company must be re-read as a live row")
+				}
+				restored = true
+				deletedAt := time.Now()
+				return &entity.Company{ID: id, ProfileID: 2, Version: 4, DeletedAt: &deletedAt}, nil
+			}
+			return &entity.Company{ID: id, ProfileID: 2, Version: 5}, nil
+		},
+	})
+	e := echo.New()
+	req := asAdmin(httptest.NewRequest(http.MethodPost, "/companies/7/restore", nil))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("7")
+
+	if err := ctrl.Restore(ctx); err != nil {
+		t.Fatalf("Restore() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"5"` {
+		t.Fatalf("expected ETag \"5\", got %q", etag)
+	}
+}
+
+func TestCompanySetPrimaryConflict(t *testing.T) {
+	ctrl := newCompanyControllerWithRepo(&companyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 2, Type: "employer", Version: 1}, nil
+		},
+		updateFn: func(context.Context, *entity.Company) error {
+			return repository.ErrPrimaryConflict
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/companies/7/primary", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("7")
+
+	if err := ctrl.SetPrimary(ctx); err != nil {
+		t.Fatalf("SetPrimary() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 	"github.com/vibast-solutions/ms-go-profile/app/types"
 
@@ -37,7 +40,7 @@ func (c *ContactController) Create(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request body"})
 	}
 	if err = req.Validate(); err != nil {
-		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		return validationError(ctx, err)
 	}
 
 	l = factory.LoggerWithContext(l, ctx).WithField("profile_id", req.GetProfileId())
@@ -45,12 +48,23 @@ func (c *ContactController) Create(ctx echo.Context) error {
 
 	contact, err := c.contactService.Create(ctx.Request().Context(), req)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Create contact failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.WithField("contact_id", contact.ID).Info("Contact created")
-	return ctx.JSON(http.StatusCreated, toContactResponse(contact))
+	setETag(ctx, contact.Version)
+	return ctx.JSON(http.StatusCreated, toContactResponse(ctx, contact))
 }
 
 func (c *ContactController) GetByID(ctx echo.Context) error {
@@ -64,10 +78,14 @@ func (c *ContactController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
 
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
 	l = factory.LoggerWithContext(l, ctx).WithField("contact_id", req.GetId())
 	l.Info("Get contact request received")
 
-	contact, err := c.contactService.GetByID(ctx.Request().Context(), req.GetId())
+	contact, err := c.contactService.GetByID(ctx.Request().Context(), req.GetId(), req.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrContactNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "contact not found"})
@@ -76,7 +94,8 @@ func (c *ContactController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
-	return ctx.JSON(http.StatusOK, toContactResponse(contact))
+	setETag(ctx, contact.Version)
+	return ctx.JSON(http.StatusOK, toContactResponse(ctx, contact))
 }
 
 func (c *ContactController) Update(ctx echo.Context) error {
@@ -87,7 +106,7 @@ func (c *ContactController) Update(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
 	}
 	if err = req.Validate(); err != nil {
-		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		return validationError(ctx, err)
 	}
 
 	l = factory.LoggerWithContext(l, ctx).WithField("contact_id", req.GetId())
@@ -98,12 +117,76 @@ func (c *ContactController) Update(ctx echo.Context) error {
 		if errors.Is(err, service.ErrContactNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "contact not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target profile does not exist"})
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Update contact failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.Info("Contact updated")
-	return ctx.JSON(http.StatusOK, toContactResponse(contact))
+	setETag(ctx, contact.Version)
+	return ctx.JSON(http.StatusOK, toContactResponse(ctx, contact))
+}
+
+func (c *ContactController) Patch(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewPatchContactRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create patch contact request from context")
+		if errors.Is(err, types.ErrUnsupportedPatchContentType) {
+			return ctx.JSON(http.StatusUnsupportedMediaType, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"contact_id":  req.GetId(),
+		"update_mask": req.GetUpdateMask().GetPaths(),
+	})
+	l.Info("Patch contact request received")
+
+	contact, err := c.contactService.Patch(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrContactNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "contact not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "target profile does not exist"})
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
+		l.WithError(err).Error("Patch contact failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Contact patched")
+	setETag(ctx, contact.Version)
+	return ctx.JSON(http.StatusOK, toContactResponse(ctx, contact))
 }
 
 func (c *ContactController) Delete(ctx echo.Context) error {
@@ -120,10 +203,13 @@ func (c *ContactController) Delete(ctx echo.Context) error {
 	l = factory.LoggerWithContext(l, ctx).WithField("contact_id", req.GetId())
 	l.Info("Delete contact request received")
 
-	if err = c.contactService.Delete(ctx.Request().Context(), req.GetId()); err != nil {
+	if err = c.contactService.Delete(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrContactNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "contact not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
 		l.WithError(err).Error("Delete contact failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
@@ -143,11 +229,16 @@ func (c *ContactController) List(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
 
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
 	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
-		"profile_id": req.GetProfileId(),
-		"page":       req.GetPage(),
-		"page_size":  req.GetPageSize(),
-		"type":       req.GetType(),
+		"profile_id":      req.GetProfileId(),
+		"page":            req.GetPage(),
+		"page_size":       req.GetPageSize(),
+		"type":            req.GetType(),
+		"include_deleted": req.GetIncludeDeleted(),
 	})
 	l.Info("List contacts request received")
 
@@ -159,7 +250,7 @@ func (c *ContactController) List(ctx echo.Context) error {
 
 	contacts := make([]*types.ContactResponse, 0, len(result.Contacts))
 	for _, contact := range result.Contacts {
-		contacts = append(contacts, toContactResponse(contact))
+		contacts = append(contacts, toContactResponse(ctx, contact))
 	}
 
 	return ctx.JSON(http.StatusOK, &types.ListContactsResponse{
@@ -170,22 +261,102 @@ func (c *ContactController) List(ctx echo.Context) error {
 	})
 }
 
-func toContactResponse(c *entity.Contact) *types.ContactResponse {
+func (c *ContactController) Restore(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewRestoreContactRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create restore contact request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+	if !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("contact_id", req.GetId())
+	l.Info("Restore contact request received")
+
+	contact, err := c.contactService.Restore(ctx.Request().Context(), req.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrContactNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "contact not found"})
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: "contact is not deleted"})
+		}
+		if errors.Is(err, service.ErrProfileDeleted) {
+			return ctx.JSON(http.StatusUnprocessableEntity, httpdto.ErrorResponse{Error: "profile is deleted; restore it first"})
+		}
+		l.WithError(err).Error("Restore contact failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Contact restored")
+	setETag(ctx, contact.Version)
+	return ctx.JSON(http.StatusOK, toContactResponse(ctx, contact))
+}
+
+func (c *ContactController) SetPrimary(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewSetPrimaryContactRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create set primary contact request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("contact_id", req.GetId())
+	l.Info("Set primary contact request received")
+
+	contact, err := c.contactService.SetPrimary(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion())
+	if err != nil {
+		if errors.Is(err, service.ErrContactNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "contact not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrPrimaryConflict) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		l.WithError(err).Error("Set primary contact failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Contact set as primary")
+	setETag(ctx, contact.Version)
+	return ctx.JSON(http.StatusOK, toContactResponse(ctx, contact))
+}
+
+// toContactResponse maps a contact, applying the caller's field rules.
+func toContactResponse(ctx echo.Context, c *entity.Contact) *types.ContactResponse {
 	dob := ""
 	if c.DOB != nil {
 		dob = c.DOB.Format(contactDOBLayout)
 	}
 
+	rules := fieldpolicy.FromContext(ctx.Request().Context())
 	return &types.ContactResponse{
-		Id:        c.ID,
-		FirstName: c.FirstName,
-		LastName:  c.LastName,
-		Nin:       c.NIN,
-		Dob:       dob,
-		Phone:     c.Phone,
-		CreatedAt: c.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: c.UpdatedAt.Format(time.RFC3339),
-		ProfileId: c.ProfileID,
-		Type:      c.Type,
+		Id:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		Nin:        rules.Apply(fieldpolicy.ContactNIN, c.NIN),
+		Dob:        rules.Apply(fieldpolicy.ContactDOB, dob),
+		Phone:      rules.Apply(fieldpolicy.ContactPhone, c.Phone),
+		PhoneE164:  rules.Apply(fieldpolicy.ContactPhone, c.PhoneE164),
+		PhoneType:  c.PhoneType,
+		NinCountry: c.NINCountry,
+		CreatedAt:  c.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  c.UpdatedAt.Format(time.RFC3339),
+		Version:    c.Version,
+		DeletedAt:  formatDeletedAt(c.DeletedAt),
+		ProfileId:  c.ProfileID,
+		Type:       c.Type,
+		IsPrimary:  c.IsPrimary,
+		Attributes: attribute.Struct(c.Attributes),
 	}
 }
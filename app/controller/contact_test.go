@@ -11,16 +11,21 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 )
 
 type contactRepoStub struct {
 	createFn   func(ctx context.Context, contact *entity.Contact) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Contact, error)
-	updateFn   func(ctx context.Context, contact *entity.Contact) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error)
+
+	findPrimaryFn     func(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error)
+	updateFn          func(ctx context.Context, contact *entity.Contact) error
+	deleteFn          func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn         func(ctx context.Context, id uint64) error
+	listFn            func(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64) ([]*entity.Contact, error)
 }
 
 func (s *contactRepoStub) Create(ctx context.Context, contact *entity.Contact) error {
@@ -30,9 +35,16 @@ func (s *contactRepoStub) Create(ctx context.Context, contact *entity.Contact) e
 	return nil
 }
 
-func (s *contactRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Contact, error) {
+func (s *contactRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *contactRepoStub) FindPrimary(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error) {
+	if s.findPrimaryFn != nil {
+		return s.findPrimaryFn(ctx, profileID, contactType)
 	}
 	return nil, nil
 }
@@ -44,22 +56,45 @@ func (s *contactRepoStub) Update(ctx context.Context, contact *entity.Contact) e
 	return nil
 }
 
-func (s *contactRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *contactRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
 	}
 	return nil
 }
 
-func (s *contactRepoStub) List(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+func (s *contactRepoStub) DeleteByProfileID(context.Context, uint64) error { return nil }
+
+func (s *contactRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
+	}
+	return nil
+}
+
+func (s *contactRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error { return nil }
+
+func (s *contactRepoStub) List(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 	if s.listFn != nil {
-		return s.listFn(ctx, profileID, contactType, limit, offset)
+		return s.listFn(ctx, profileID, contactType, nin, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (s *contactRepoStub) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Contact, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (s *contactRepoStub) Encrypts(string) bool {
+	return false
+}
+
 func newContactControllerWithRepo(repo *contactRepoStub) *ContactController {
-	svc := service.NewContactService(repo)
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{Contacts: repo, Addresses: &addressRepoStub{}, Audit: &auditRepoStub{}, Outbox: &outboxRepoStub{}}}
+	svc := service.NewContactService(repo, uow, "", nil)
 	return NewContactController(svc)
 }
 
@@ -100,6 +135,100 @@ func TestContactCreateSuccess(t *testing.T) {
 	}
 }
 
+func TestContactCreateInvalidPhone(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/contacts", bytes.NewBufferString(`{"profile_id":4,"phone":"0722 123 456"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a national number without a region, got %d", rec.Code)
+	}
+}
+
+func TestContactCreateFillsDOBFromNIN(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/contacts", bytes.NewBufferString(`{"profile_id":4,"nin":"2901231040024","nin_country":"ro"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["dob"] != "1990-12-31" || payload["nin_country"] != "RO" {
+		t.Fatalf("expected dob and nin_country from the CNP, got: %s", rec.Body.String())
+	}
+}
+
+func TestContactCreateInvalidNINNamesField(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/contacts", bytes.NewBufferString(`{"profile_id":4,"nin":"2901231040025","nin_country":"RO"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["field"] != "nin" {
+		t.Fatalf("expected nin field error, got: %s", rec.Body.String())
+	}
+}
+
+func TestContactGetByIDMasksPhoneE164WithPhone(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, Phone: "0722123456", PhoneE164: "+40722123456", PhoneType: "mobile"}, nil
+		},
+	})
+	policy, err := fieldpolicy.Parse("contact.phone:masked", "")
+	if err != nil {
+		t.Fatalf("fieldpolicy.Parse() returned error: %v", err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/contacts/9", nil)
+	req = req.WithContext(fieldpolicy.NewContext(req.Context(), policy.For("billing-service")))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("9")
+
+	if err = ctrl.GetByID(ctx); err != nil {
+		t.Fatalf("GetByID() returned unexpected error: %v", err)
+	}
+
+	var payload map[string]any
+	if err = json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["phone_e164"] != "********3456" || payload["phone_type"] != "mobile" {
+		t.Fatalf("expected masked phone_e164 and phone_type, got: %s", rec.Body.String())
+	}
+}
+
 func TestContactGetByIDNotFound(t *testing.T) {
 	ctrl := newContactControllerWithRepo(&contactRepoStub{})
 	e := echo.New()
@@ -119,7 +248,7 @@ func TestContactGetByIDNotFound(t *testing.T) {
 
 func TestContactDeleteNotFound(t *testing.T) {
 	ctrl := newContactControllerWithRepo(&contactRepoStub{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrContactNotFound
 		},
 	})
@@ -153,11 +282,47 @@ func TestContactListInvalidQuery(t *testing.T) {
 	}
 }
 
+func TestContactGetByIDAppliesFieldPolicy(t *testing.T) {
+	dob := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, NIN: "1900102223344", DOB: &dob, Phone: "+40700000000", ProfileID: 4}, nil
+		},
+	})
+	policy, err := fieldpolicy.Parse("contact.nin:masked,contact.dob:hidden", "")
+	if err != nil {
+		t.Fatalf("fieldpolicy.Parse() returned error: %v", err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/contacts/9", nil)
+	req = req.WithContext(fieldpolicy.NewContext(req.Context(), policy.For("billing-service")))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("9")
+
+	if err = ctrl.GetByID(ctx); err != nil {
+		t.Fatalf("GetByID() returned unexpected error: %v", err)
+	}
+
+	var payload map[string]any
+	if err = json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["nin"] != "*********3344" || payload["phone"] != "+40700000000" {
+		t.Fatalf("expected masked nin and full phone, got: %s", rec.Body.String())
+	}
+	if _, ok := payload["dob"]; ok {
+		t.Fatalf("expected dob to be hidden, got: %s", rec.Body.String())
+	}
+}
+
 func TestContactListSuccess(t *testing.T) {
 	now := time.Now()
 	dob := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
 	ctrl := newContactControllerWithRepo(&contactRepoStub{
-		listFn: func(_ context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+		listFn: func(_ context.Context, profileID uint64, contactType, _ string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 			if profileID != 4 || contactType != "emergency" || limit != 5 || offset != 5 {
 				t.Fatalf("unexpected list args profileID=%d contactType=%q limit=%d offset=%d", profileID, contactType, limit, offset)
 			}
@@ -196,3 +361,198 @@ func TestContactListSuccess(t *testing.T) {
 		t.Fatalf("expected paginated payload, got: %s", rec.Body.String())
 	}
 }
+
+func TestContactCreateMissingProfile(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		createFn: func(_ context.Context, _ *entity.Contact) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/contacts", bytes.NewBufferString(`{"profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestContactUpdateMissingTargetProfile(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Contact) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/contacts/3", bytes.NewBufferString(`{"profile_id":404}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Update(ctx); err != nil {
+		t.Fatalf("Update() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestContactPatchKeepsOmittedFields(t *testing.T) {
+	dob := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, FirstName: "John", Phone: "111", DOB: &dob, ProfileID: 7}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/contacts/3", bytes.NewBufferString(`{"phone":"+40722123456"}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Patch(ctx); err != nil {
+		t.Fatalf("Patch() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload["phone"] != "+40722123456" || payload["phone_type"] != "mobile" || payload["first_name"] != "John" || payload["dob"] != "1990-01-02" {
+		t.Fatalf("unexpected patched contact: %v", payload)
+	}
+}
+
+func TestContactPatchUnsupportedContentType(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/contacts/3", bytes.NewBufferString(`phone=222`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Patch(ctx); err != nil {
+		t.Fatalf("Patch() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415, got %d", rec.Code)
+	}
+}
+
+func TestContactGetByIDSetsETag(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 7, Version: 6}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/contacts/3", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.GetByID(ctx); err != nil {
+		t.Fatalf("GetByID() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"6"` {
+		t.Fatalf("expected ETag \"6\", got %q", etag)
+	}
+}
+
+func TestContactUpdateStaleIfMatch(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 7, Version: 6}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/contacts/3", bytes.NewBufferString(`{"profile_id":7}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("If-Match", `"5"`)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Update(ctx); err != nil {
+		t.Fatalf("Update() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestContactDeleteInvalidIfMatch(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/contacts/3", nil)
+	req.Header.Set("If-Match", "five")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Delete(ctx); err != nil {
+		t.Fatalf("Delete() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestContactRestoreWhileProfileDeleted(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{
+		restoreFn: func(context.Context, uint64) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	e := echo.New()
+	req := asAdmin(httptest.NewRequest(http.MethodPost, "/contacts/3/restore", nil))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.Restore(ctx); err != nil {
+		t.Fatalf("Restore() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rec.Code)
+	}
+}
+
+func TestContactListIncludeDeletedRequiresAdmin(t *testing.T) {
+	ctrl := newContactControllerWithRepo(&contactRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/contacts?profile_id=4&include_deleted=1", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.List(ctx); err != nil {
+		t.Fatalf("List() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
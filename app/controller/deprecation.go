@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+)
+
+// v2Prefix is where every v1 route has its v2 counterpart.
+const v2Prefix = "/v2"
+
+// DeprecatedV1 marks the responses of the v1 routes as deprecated and links the /v2 route
+// replacing each one.
+func DeprecatedV1() echo.MiddlewareFunc {
+	deprecation := types.V1Deprecation()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(types.HeaderDeprecation, deprecation)
+			header.Set(types.HeaderLink, types.SuccessorLink(v2Prefix+c.Request().URL.Path))
+			return next(c)
+		}
+	}
+}
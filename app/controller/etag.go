@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+)
+
+const headerETag = "ETag"
+
+// setETag exposes the record version so clients can send it back in If-Match.
+func setETag(ctx echo.Context, version uint64) {
+	ctx.Response().Header().Set(headerETag, types.FormatETag(version))
+}
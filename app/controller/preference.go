@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+type PreferenceController struct {
+	preferenceService *service.PreferenceService
+	logger            logrus.FieldLogger
+}
+
+func NewPreferenceController(preferenceService *service.PreferenceService) *PreferenceController {
+	return &PreferenceController{
+		preferenceService: preferenceService,
+		logger:            factory.NewModuleLogger("preference-controller"),
+	}
+}
+
+func (c *PreferenceController) Get(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewGetPreferencesRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create get preferences request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id": req.GetProfileId(),
+		"namespace":  req.GetNamespace(),
+	})
+	l.Info("Get preferences request received")
+
+	namespaces, err := c.preferenceService.Get(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		l.WithError(err).Error("Get preferences failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	return ctx.JSON(http.StatusOK, toPreferencesResponse(req.GetProfileId(), namespaces...))
+}
+
+func (c *PreferenceController) Set(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewSetPreferencesRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create set preferences request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request body"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id": req.GetProfileId(),
+		"namespace":  req.GetNamespace(),
+	})
+	l.Info("Set preferences request received")
+
+	namespace, err := c.preferenceService.Set(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
+		l.WithError(err).Error("Set preferences failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Preferences set")
+	return ctx.JSON(http.StatusOK, toPreferencesResponse(req.GetProfileId(), namespace))
+}
+
+func (c *PreferenceController) Delete(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewDeletePreferenceRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create delete preference request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id": req.GetProfileId(),
+		"namespace":  req.GetNamespace(),
+		"key":        req.GetKey(),
+	})
+	l.Info("Delete preference request received")
+
+	if err = c.preferenceService.Delete(ctx.Request().Context(), req); err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrPreferenceNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
+		l.WithError(err).Error("Delete preference failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Preference deleted")
+	return ctx.JSON(http.StatusOK, httpdto.DeleteResponse{Message: "preference deleted successfully"})
+}
+
+// toPreferencesResponse renders namespaces with plain JSON values; the proto response would
+// wrap each of them in a google.protobuf.Value.
+func toPreferencesResponse(profileID uint64, namespaces ...*service.PreferenceNamespace) *httpdto.PreferencesResponse {
+	response := &httpdto.PreferencesResponse{
+		ProfileID:  profileID,
+		Namespaces: make([]httpdto.PreferenceNamespace, 0, len(namespaces)),
+	}
+	for _, namespace := range namespaces {
+		item := httpdto.PreferenceNamespace{Namespace: namespace.Namespace, Values: namespace.Values}
+		if !namespace.UpdatedAt.IsZero() {
+			item.UpdatedAt = namespace.UpdatedAt.Format(time.RFC3339)
+		}
+		response.Namespaces = append(response.Namespaces, item)
+	}
+
+	return response
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/jsonschema"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+)
+
+// preferenceRepoStub stores every saved preference and lists them back.
+type preferenceRepoStub struct {
+	saved []*entity.Preference
+}
+
+func (s *preferenceRepoStub) Save(_ context.Context, preference *entity.Preference) error {
+	s.saved = append(s.saved, preference)
+	return nil
+}
+
+func (s *preferenceRepoStub) Delete(context.Context, uint64, string, string) error {
+	return repository.ErrPreferenceNotFound
+}
+
+func (s *preferenceRepoStub) List(context.Context, uint64, string) ([]*entity.Preference, error) {
+	return s.saved, nil
+}
+
+func (s *preferenceRepoStub) ListForUpdate(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	return s.List(ctx, profileID, namespace)
+}
+
+// newPreferenceController serves profile 7 only, with a schema on the "locale" namespace.
+func newPreferenceController(t *testing.T, preferences *preferenceRepoStub) *PreferenceController {
+	t.Helper()
+	schema, err := jsonschema.Compile([]byte(`{"type":"object","properties":{"language":{"type":"string","maxLength":2}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := &controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			if id != 7 {
+				return nil, nil
+			}
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{
+		Profiles:    profiles,
+		Preferences: preferences,
+		Audit:       &auditRepoStub{},
+		Outbox:      &outboxRepoStub{},
+	}}
+	return NewPreferenceController(service.NewPreferenceService(uow, map[string]*jsonschema.Schema{"locale": schema}))
+}
+
+func TestPreferenceSetReturnsPlainValues(t *testing.T) {
+	preferences := &preferenceRepoStub{}
+	ctrl := newPreferenceController(t, preferences)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/profiles/7/preferences/locale", bytes.NewBufferString(`{"values":{"language":"ro"}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id", "namespace")
+	ctx.SetParamValues("7", "locale")
+
+	if err := ctrl.Set(ctx); err != nil {
+		t.Fatalf("Set() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp httpdto.PreferencesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ProfileID != 7 || len(resp.Namespaces) != 1 || resp.Namespaces[0].Values["language"] != "ro" {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+	if len(preferences.saved) != 1 || string(preferences.saved[0].Value) != `"ro"` {
+		t.Fatalf("unexpected saved preferences: %+v", preferences.saved)
+	}
+}
+
+func TestPreferenceStatuses(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		namespace string
+		body      string
+		want      int
+		field     string
+	}{
+		{"schema violation", "7", "locale", `{"values":{"language":"romanian"}}`, http.StatusBadRequest, "values.language"},
+		{"invalid namespace", "7", "Locale", `{"values":{"language":"ro"}}`, http.StatusBadRequest, ""},
+		{"unknown profile", "8", "locale", `{"values":{"language":"ro"}}`, http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		ctrl := newPreferenceController(t, &preferenceRepoStub{})
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/profiles/"+tc.id+"/preferences/"+tc.namespace, bytes.NewBufferString(tc.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("id", "namespace")
+		ctx.SetParamValues(tc.id, tc.namespace)
+
+		if err := ctrl.Set(ctx); err != nil {
+			t.Fatalf("%s: Set() returned unexpected error: %v", tc.name, err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+		var resp httpdto.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Field != tc.field {
+			t.Fatalf("%s: unexpected error response: %s", tc.name, rec.Body.String())
+		}
+	}
+}
+
+func TestPreferenceDeleteMissingKey(t *testing.T) {
+	ctrl := newPreferenceController(t, &preferenceRepoStub{})
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodDelete, "/profiles/7/preferences/locale/language", nil), rec)
+	ctx.SetParamNames("id", "namespace", "key")
+	ctx.SetParamValues("7", "locale", "language")
+
+	if err := ctrl.Delete(ctx); err != nil {
+		t.Fatalf("Delete() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/factory"
@@ -38,17 +39,26 @@ func (c *ProfileController) Create(ctx echo.Context) error {
 	}
 
 	if err = req.Validate(); err != nil {
-		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		return validationError(ctx, err)
 	}
 	l = factory.LoggerWithContext(l, ctx).WithField("user_id", req.GetUserId())
 	l.Info("Create profile request received")
 
-	profile, err := c.profileService.Create(ctx.Request().Context(), req)
+	profile, err := c.profileService.CreateWithChildren(ctx.Request().Context(), req, profileChildren(req))
 	if err != nil {
 		if errors.Is(err, service.ErrProfileAlreadyExists) {
 			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: "profile already exists for this user"})
 		}
-
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return validationError(ctx, err)
+		}
 		l.WithError(err).Error("Create profile failed")
 
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
@@ -56,9 +66,23 @@ func (c *ProfileController) Create(ctx echo.Context) error {
 
 	l.Info("Profile created")
 
+	setETag(ctx, profile.Version)
 	return ctx.JSON(http.StatusCreated, toProfileResponse(profile))
 }
 
+// profileChildren avoids handing typed-nil request pointers to the service.
+func profileChildren(req *types.CreateProfileRequest) service.ProfileChildren {
+	children := service.ProfileChildren{}
+	if req.GetContact() != nil {
+		children.Contact = req.GetContact()
+	}
+	if req.GetAddress() != nil {
+		children.Address = req.GetAddress()
+	}
+
+	return children
+}
+
 func (c *ProfileController) GetByID(ctx echo.Context) error {
 	l := c.logger
 	req, err := types.NewGetProfileRequestFromContext(ctx)
@@ -72,10 +96,14 @@ func (c *ProfileController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
 
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
 	l = factory.LoggerWithContext(l, ctx).WithField("profile_id", req.GetId())
 	l.Info("Get profile request received")
 
-	profile, err := c.profileService.GetByID(ctx.Request().Context(), req.GetId())
+	profile, err := c.profileService.GetByID(ctx.Request().Context(), req.GetId(), req.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
@@ -85,9 +113,39 @@ func (c *ProfileController) GetByID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
+	setETag(ctx, profile.Version)
 	return ctx.JSON(http.StatusOK, toProfileResponse(profile))
 }
 
+func (c *ProfileController) GetBundle(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewGetProfileBundleRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create profile bundle request from context")
+
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("profile_id", req.GetId())
+	l.Info("Get profile bundle request received")
+
+	bundle, err := c.profileService.GetBundle(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		l.WithError(err).Error("Get profile bundle failed")
+
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	return ctx.JSON(http.StatusOK, toProfileBundleResponse(ctx, bundle))
+}
+
 func (c *ProfileController) GetByUserID(ctx echo.Context) error {
 	l := c.logger
 	req, err := types.NewGetProfileByUserIDRequestFromContext(ctx)
@@ -98,10 +156,13 @@ func (c *ProfileController) GetByUserID(ctx echo.Context) error {
 	if err = req.Validate(); err != nil {
 		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
 	}
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
 	l = factory.LoggerWithContext(l, ctx).WithField("user_id", req.GetUserId())
 	l.Info("Get profile by user ID request received")
 
-	profile, err := c.profileService.GetByUserID(ctx.Request().Context(), req.GetUserId())
+	profile, err := c.profileService.GetByUserID(ctx.Request().Context(), req.GetUserId(), req.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			l.WithField("user_id", req.GetUserId()).Warn("Get profile by user ID failed: not found")
@@ -114,6 +175,38 @@ func (c *ProfileController) GetByUserID(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
+	setETag(ctx, profile.Version)
+	return ctx.JSON(http.StatusOK, toProfileResponse(profile))
+}
+
+func (c *ProfileController) GetByEmail(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewGetProfileByEmailRequestFromContext(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+	if req.GetIncludeDeleted() && !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+	l = factory.LoggerWithContext(l, ctx)
+	l.Info("Get profile by email request received")
+
+	profile, err := c.profileService.GetByEmail(ctx.Request().Context(), req.GetEmail(), req.GetIncludeDeleted())
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+
+		l.WithError(err).Error("Get profile by email failed")
+
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	setETag(ctx, profile.Version)
 	return ctx.JSON(http.StatusOK, toProfileResponse(profile))
 }
 
@@ -137,12 +230,62 @@ func (c *ProfileController) Update(ctx echo.Context) error {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
 		l.WithError(err).Error("Update profile failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
 
 	l.Info("Profile updated")
 
+	setETag(ctx, profile.Version)
+	return ctx.JSON(http.StatusOK, toProfileResponse(profile))
+}
+
+func (c *ProfileController) Patch(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewPatchProfileRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create patch profile request from context")
+		if errors.Is(err, types.ErrUnsupportedPatchContentType) {
+			return ctx.JSON(http.StatusUnsupportedMediaType, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithFields(logrus.Fields{
+		"profile_id":  req.GetId(),
+		"update_mask": req.GetUpdateMask().GetPaths(),
+	})
+	l.Info("Patch profile request received")
+
+	profile, err := c.profileService.Patch(ctx.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		l.WithError(err).Error("Patch profile failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Profile patched")
+	setETag(ctx, profile.Version)
 	return ctx.JSON(http.StatusOK, toProfileResponse(profile))
 }
 
@@ -159,10 +302,13 @@ func (c *ProfileController) Delete(ctx echo.Context) error {
 	l = factory.LoggerWithContext(l, ctx).WithField("profile_id", req.GetId())
 
 	l.Info("Delete profile request received")
-	if err := c.profileService.Delete(ctx.Request().Context(), req.GetId()); err != nil {
+	if err := c.profileService.Delete(ctx.Request().Context(), req.GetId(), req.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return ctx.JSON(http.StatusPreconditionFailed, httpdto.ErrorResponse{Error: "version mismatch"})
+		}
 		l.WithError(err).Error("Delete profile failed")
 		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
 	}
@@ -171,6 +317,46 @@ func (c *ProfileController) Delete(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, httpdto.DeleteResponse{Message: "profile deleted successfully"})
 }
 
+func (c *ProfileController) Restore(ctx echo.Context) error {
+	l := c.logger
+	req, err := types.NewRestoreProfileRequestFromContext(ctx)
+	if err != nil {
+		l.WithError(err).Debug("Failed to create restore profile request from context")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid request"})
+	}
+	if err = req.Validate(); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+	if !isAdmin(ctx) {
+		return ctx.JSON(http.StatusForbidden, httpdto.ErrorResponse{Error: errAdminOnly})
+	}
+
+	l = factory.LoggerWithContext(l, ctx).WithField("profile_id", req.GetId())
+	l.Info("Restore profile request received")
+
+	profile, err := c.profileService.Restore(ctx.Request().Context(), req.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return ctx.JSON(http.StatusNotFound, httpdto.ErrorResponse{Error: "profile not found"})
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: "profile is not deleted"})
+		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return ctx.JSON(http.StatusConflict, httpdto.ErrorResponse{Error: err.Error(), Field: "fiscal_code"})
+		}
+		l.WithError(err).Error("Restore profile failed")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	l.Info("Profile restored")
+	setETag(ctx, profile.Version)
+	return ctx.JSON(http.StatusOK, toProfileResponse(profile))
+}
+
 func toProfileResponse(p *entity.Profile) *types.ProfileResponse {
 	return &types.ProfileResponse{
 		Id:        p.ID,
@@ -178,5 +364,22 @@ func toProfileResponse(p *entity.Profile) *types.ProfileResponse {
 		Email:     p.Email,
 		CreatedAt: p.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+		Version:   p.Version,
+		DeletedAt: formatDeletedAt(p.DeletedAt),
+	}
+}
+
+func toProfileBundleResponse(ctx echo.Context, bundle *service.ProfileBundle) *types.ProfileBundleResponse {
+	resp := &types.ProfileBundleResponse{Profile: toProfileResponse(bundle.Profile)}
+	for _, contact := range bundle.Contacts {
+		resp.Contacts = append(resp.Contacts, toContactResponse(ctx, contact))
 	}
+	for _, address := range bundle.Addresses {
+		resp.Addresses = append(resp.Addresses, toAddressResponse(address))
+	}
+	for _, company := range bundle.Companies {
+		resp.Companies = append(resp.Companies, toCompanyResponse(ctx, company))
+	}
+
+	return resp
 }
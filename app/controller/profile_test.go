@@ -3,24 +3,30 @@ package controller
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 )
 
 type controllerRepoStub struct {
-	createFn       func(ctx context.Context, profile *entity.Profile) error
-	findByIDFn     func(ctx context.Context, id uint64) (*entity.Profile, error)
-	findByUserIDFn func(ctx context.Context, userID uint64) (*entity.Profile, error)
-	updateFn       func(ctx context.Context, profile *entity.Profile) error
-	deleteFn       func(ctx context.Context, id uint64) error
+	createFn               func(ctx context.Context, profile *entity.Profile) error
+	findByIDFn             func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error)
+	findByUserIDFn         func(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error)
+	findByEmailFn          func(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error)
+	findByEmailForUpdateFn func(ctx context.Context, email string) (*entity.Profile, error)
+	updateFn               func(ctx context.Context, profile *entity.Profile) error
+	deleteFn               func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn              func(ctx context.Context, id uint64) error
 }
 
 func (s *controllerRepoStub) Create(ctx context.Context, profile *entity.Profile) error {
@@ -30,16 +36,35 @@ func (s *controllerRepoStub) Create(ctx context.Context, profile *entity.Profile
 	return nil
 }
 
-func (s *controllerRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Profile, error) {
+func (s *controllerRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
 	}
 	return nil, nil
 }
 
-func (s *controllerRepoStub) FindByUserID(ctx context.Context, userID uint64) (*entity.Profile, error) {
+// FindByIDForUpdate answers like FindByID; the stub takes no lock.
+func (s *controllerRepoStub) FindByIDForUpdate(ctx context.Context, id uint64) (*entity.Profile, error) {
+	return s.FindByID(ctx, id, false)
+}
+
+func (s *controllerRepoStub) FindByUserID(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error) {
 	if s.findByUserIDFn != nil {
-		return s.findByUserIDFn(ctx, userID)
+		return s.findByUserIDFn(ctx, userID, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *controllerRepoStub) FindByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error) {
+	if s.findByEmailFn != nil {
+		return s.findByEmailFn(ctx, email, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *controllerRepoStub) FindByEmailForUpdate(ctx context.Context, email string) (*entity.Profile, error) {
+	if s.findByEmailForUpdateFn != nil {
+		return s.findByEmailForUpdateFn(ctx, email)
 	}
 	return nil, nil
 }
@@ -51,15 +76,42 @@ func (s *controllerRepoStub) Update(ctx context.Context, profile *entity.Profile
 	return nil
 }
 
-func (s *controllerRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *controllerRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *controllerRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
 	}
 	return nil
 }
 
+type controllerUnitOfWorkStub struct {
+	repos service.Repositories
+}
+
+func (u *controllerUnitOfWorkStub) Do(ctx context.Context, _ *sql.TxOptions, fn func(ctx context.Context, repos service.Repositories) error) error {
+	return fn(ctx, u.repos)
+}
+
 func newControllerWithRepo(repo *controllerRepoStub) *ProfileController {
-	svc := service.NewProfileService(repo)
+	return newControllerWithRepos(repo, &contactRepoStub{}, &addressRepoStub{})
+}
+
+func newControllerWithRepos(repo *controllerRepoStub, contactRepo *contactRepoStub, addressRepo *addressRepoStub) *ProfileController {
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{
+		Profiles:  repo,
+		Contacts:  contactRepo,
+		Addresses: addressRepo,
+		Companies: &companyRepoStub{},
+		Audit:     &auditRepoStub{},
+		Outbox:    &outboxRepoStub{},
+	}}
+	svc := service.NewProfileService(repo, uow, false, "", nil)
 	return NewProfileController(svc)
 }
 
@@ -81,7 +133,7 @@ func TestCreateBadRequest(t *testing.T) {
 
 func TestCreateConflict(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{
-		findByUserIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: 1, UserID: 7}, nil
 		},
 	})
@@ -128,6 +180,46 @@ func TestCreateSuccess(t *testing.T) {
 	}
 }
 
+func TestCreateWithChildrenSuccess(t *testing.T) {
+	var contactProfileID, addressProfileID uint64
+	ctrl := newControllerWithRepos(
+		&controllerRepoStub{
+			createFn: func(_ context.Context, profile *entity.Profile) error {
+				profile.ID = 101
+				return nil
+			},
+		},
+		&contactRepoStub{
+			createFn: func(_ context.Context, contact *entity.Contact) error {
+				contactProfileID = contact.ProfileID
+				return nil
+			},
+		},
+		&addressRepoStub{
+			createFn: func(_ context.Context, address *entity.Address) error {
+				addressProfileID = address.ProfileID
+				return nil
+			},
+		},
+	)
+	e := echo.New()
+	body := `{"user_id":7,"email":"john@example.com","contact":{"first_name":"John"},"address":{"street_name":"Main","streen_no":"1","city":"City","county":"Cluj","country":"RO"}}`
+	req := httptest.NewRequest(http.MethodPost, "/profiles", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	if err := ctrl.Create(ctx); err != nil {
+		t.Fatalf("Create() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	if contactProfileID != 101 || addressProfileID != 101 {
+		t.Fatalf("expected children bound to profile 101, got contact=%d address=%d", contactProfileID, addressProfileID)
+	}
+}
+
 func TestGetByIDInvalidID(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{})
 	e := echo.New()
@@ -162,9 +254,88 @@ func TestGetByIDNotFound(t *testing.T) {
 	}
 }
 
+func TestGetBundleSuccess(t *testing.T) {
+	ctrl := newControllerWithRepos(
+		&controllerRepoStub{
+			findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+				return &entity.Profile{ID: id, UserID: 7, Email: "john@example.com"}, nil
+			},
+		},
+		&contactRepoStub{
+			listByProfileIDFn: func(_ context.Context, profileID uint64) ([]*entity.Contact, error) {
+				return []*entity.Contact{{ID: 5, ProfileID: profileID, FirstName: "John"}}, nil
+			},
+		},
+		&addressRepoStub{
+			listByProfileIDFn: func(context.Context, uint64) ([]*entity.Address, error) {
+				t.Fatal("addresses were not requested")
+				return nil, nil
+			},
+		},
+	)
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/profiles/12/full?include=contacts", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("12")
+
+	if err := ctrl.GetBundle(ctx); err != nil {
+		t.Fatalf("GetBundle() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	var payload struct {
+		Profile  map[string]any   `json:"profile"`
+		Contacts []map[string]any `json:"contacts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if payload.Profile["id"] != float64(12) || len(payload.Contacts) != 1 {
+		t.Fatalf("unexpected bundle payload: %s", rec.Body.String())
+	}
+}
+
+func TestGetBundleInvalidInclude(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/profiles/12/full?include=orders", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("12")
+
+	if err := ctrl.GetBundle(ctx); err != nil {
+		t.Fatalf("GetBundle() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestGetBundleNotFound(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/profiles/12/full", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("12")
+
+	if err := ctrl.GetBundle(ctx); err != nil {
+		t.Fatalf("GetBundle() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
 func TestDeleteNotFound(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrProfileNotFound
 		},
 	})
@@ -202,7 +373,7 @@ func TestGetByUserIDInvalidID(t *testing.T) {
 
 func TestGetByUserIDSuccess(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{
-		findByUserIDFn: func(_ context.Context, userID uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, userID uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: 11, UserID: userID, Email: "john@example.com"}, nil
 		},
 	})
@@ -221,9 +392,92 @@ func TestGetByUserIDSuccess(t *testing.T) {
 	}
 }
 
+func TestGetByEmailSuccess(t *testing.T) {
+	var lookedUp string
+	ctrl := newControllerWithRepo(&controllerRepoStub{
+		findByEmailFn: func(_ context.Context, email string, _ bool) (*entity.Profile, error) {
+			lookedUp = email
+			return &entity.Profile{ID: 11, UserID: 42, Email: email, Version: 3}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/profiles/email/John@Example.COM", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("email")
+	ctx.SetParamValues("John@Example.COM")
+
+	if err := ctrl.GetByEmail(ctx); err != nil {
+		t.Fatalf("GetByEmail() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if lookedUp != "John@example.com" {
+		t.Fatalf("expected lookup by normalized email, got %q", lookedUp)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"3"` {
+		t.Fatalf("expected ETag \"3\", got %q", etag)
+	}
+}
+
+func TestGetByEmailErrors(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{})
+	e := echo.New()
+	cases := []struct {
+		target string
+		email  string
+		want   int
+	}{
+		{"/profiles/email/not-an-email", "not-an-email", http.StatusBadRequest},
+		{"/profiles/email/john@example.com?include_deleted=true", "john@example.com", http.StatusForbidden},
+		{"/profiles/email/john@example.com", "john@example.com", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(httptest.NewRequest(http.MethodGet, tc.target, nil), rec)
+		ctx.SetParamNames("email")
+		ctx.SetParamValues(tc.email)
+
+		if err := ctrl.GetByEmail(ctx); err != nil {
+			t.Fatalf("GetByEmail() returned unexpected error: %v", err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.target, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestUpdateEmailTakenConflict(t *testing.T) {
+	repo := &controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, UserID: 7, Email: "old@example.com"}, nil
+		},
+		findByEmailForUpdateFn: func(_ context.Context, email string) (*entity.Profile, error) {
+			return &entity.Profile{ID: 1, Email: email}, nil
+		},
+	}
+	uow := &controllerUnitOfWorkStub{repos: service.Repositories{Profiles: repo, Audit: &auditRepoStub{}, Outbox: &outboxRepoStub{}}}
+	ctrl := NewProfileController(service.NewProfileService(repo, uow, true, "", nil))
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/profiles/5", bytes.NewBufferString(`{"email":"taken@example.com"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	if err := ctrl.Update(ctx); err != nil {
+		t.Fatalf("Update() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+}
+
 func TestGetByUserIDInternal(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{
-		findByUserIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return nil, errors.New("db unavailable")
 		},
 	})
@@ -280,7 +534,7 @@ func TestUpdateNotFound(t *testing.T) {
 
 func TestUpdateInternal(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{
-		findByIDFn: func(_ context.Context, id uint64) (*entity.Profile, error) {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: id, UserID: 7, Email: "old@example.com"}, nil
 		},
 		updateFn: func(_ context.Context, _ *entity.Profile) error {
@@ -305,7 +559,7 @@ func TestUpdateInternal(t *testing.T) {
 
 func TestUpdateSuccess(t *testing.T) {
 	ctrl := newControllerWithRepo(&controllerRepoStub{
-		findByIDFn: func(_ context.Context, id uint64) (*entity.Profile, error) {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: id, UserID: 7, Email: "old@example.com"}, nil
 		},
 	})
@@ -324,3 +578,152 @@ func TestUpdateSuccess(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rec.Code)
 	}
 }
+
+func TestPatchNotFound(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/profiles/10", bytes.NewBufferString(`{"email":"new@example.com"}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("10")
+
+	if err := ctrl.Patch(ctx); err != nil {
+		t.Fatalf("Patch() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+// asAdmin marks the request as coming from a caller holding the admin grant.
+func asAdmin(req *http.Request) *http.Request {
+	return req.WithContext(caller.NewContext(req.Context(), caller.Identity{Service: "admin-service", Admin: true}))
+}
+
+func TestGetByIDIncludeDeletedRequiresAdmin(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/profiles/5?include_deleted=true", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	if err := ctrl.GetByID(ctx); err != nil {
+		t.Fatalf("GetByID() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestGetByIDIncludeDeletedReturnsDeletedAt(t *testing.T) {
+	deletedAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	ctrl := newControllerWithRepo(&controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
+			if !includeDeleted {
+				return nil, nil
+			}
+			return &entity.Profile{ID: id, Version: 2, DeletedAt: &deletedAt}, nil
+		},
+	})
+	e := echo.New()
+	req := asAdmin(httptest.NewRequest(http.MethodGet, "/profiles/5?include_deleted=true", nil))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	if err := ctrl.GetByID(ctx); err != nil {
+		t.Fatalf("GetByID() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["deleted_at"] != "2026-03-01T10:00:00Z" {
+		t.Fatalf("expected deleted_at in response, got %v", resp["deleted_at"])
+	}
+}
+
+func TestRestoreRequiresAdmin(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{
+		restoreFn: func(context.Context, uint64) error {
+			t.Fatal("restore must not reach the repository")
+			return nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/profiles/5/restore", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	if err := ctrl.Restore(ctx); err != nil {
+		t.Fatalf("Restore() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestRestoreSuccess(t *testing.T) {
+	deletedAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	restored := false
+	ctrl := newControllerWithRepo(&controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			if restored {
+				return &entity.Profile{ID: id, Version: 4}, nil
+			}
+			return &entity.Profile{ID: id, Version: 3, DeletedAt: &deletedAt}, nil
+		},
+		restoreFn: func(context.Context, uint64) error {
+			restored = true
+			return nil
+		},
+	})
+	e := echo.New()
+	req := asAdmin(httptest.NewRequest(http.MethodPost, "/profiles/5/restore", nil))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	if err := ctrl.Restore(ctx); err != nil {
+		t.Fatalf("Restore() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if etag := rec.Header().Get("ETag"); etag != `"4"` {
+		t.Fatalf("expected ETag \"4\", got %q", etag)
+	}
+}
+
+func TestRestoreNotDeleted(t *testing.T) {
+	ctrl := newControllerWithRepo(&controllerRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, Version: 1}, nil
+		},
+	})
+	e := echo.New()
+	req := asAdmin(httptest.NewRequest(http.MethodPost, "/profiles/5/restore", nil))
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	if err := ctrl.Restore(ctx); err != nil {
+		t.Fatalf("Restore() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rec.Code)
+	}
+}
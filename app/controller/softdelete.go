@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+)
+
+const errAdminOnly = "admin access required"
+
+// isAdmin reports whether the caller may see soft-deleted records and restore them.
+func isAdmin(ctx echo.Context) bool {
+	return caller.FromContext(ctx.Request().Context()).Admin
+}
+
+func formatDeletedAt(deletedAt *time.Time) string {
+	if deletedAt == nil {
+		return ""
+	}
+
+	return deletedAt.Format(time.RFC3339)
+}
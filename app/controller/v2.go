@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/factory"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// V2Controller serves the /v2 routes by binding each request onto its profile.v2 message and
+// calling the v2 service in process, so HTTP and gRPC clients of v2 get the same behavior.
+type V2Controller struct {
+	server typesv2.ProfileServiceServer
+	logger logrus.FieldLogger
+}
+
+func NewV2Controller(server typesv2.ProfileServiceServer) *V2Controller {
+	return &V2Controller{
+		server: server,
+		logger: factory.NewModuleLogger("v2-controller"),
+	}
+}
+
+var v2JSON = protojson.MarshalOptions{UseProtoNames: true}
+
+func (c *V2Controller) CreateProfile(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.CreateProfile, http.StatusCreated)
+}
+
+func (c *V2Controller) GetProfile(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetProfile, http.StatusOK)
+}
+
+func (c *V2Controller) GetProfileByUserID(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetProfileByUserID, http.StatusOK)
+}
+
+func (c *V2Controller) GetProfileByEmail(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetProfileByEmail, http.StatusOK)
+}
+
+func (c *V2Controller) UpdateProfile(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.UpdateProfile, http.StatusOK)
+}
+
+func (c *V2Controller) PatchProfile(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.PatchProfile, http.StatusOK)
+}
+
+func (c *V2Controller) DeleteProfile(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.DeleteProfile, http.StatusNoContent)
+}
+
+func (c *V2Controller) RestoreProfile(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.RestoreProfile, http.StatusOK)
+}
+
+func (c *V2Controller) GetProfileBundle(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetProfileBundle, http.StatusOK)
+}
+
+func (c *V2Controller) CreateContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.CreateContact, http.StatusCreated)
+}
+
+func (c *V2Controller) GetContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetContact, http.StatusOK)
+}
+
+func (c *V2Controller) UpdateContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.UpdateContact, http.StatusOK)
+}
+
+func (c *V2Controller) PatchContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.PatchContact, http.StatusOK)
+}
+
+func (c *V2Controller) DeleteContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.DeleteContact, http.StatusNoContent)
+}
+
+func (c *V2Controller) RestoreContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.RestoreContact, http.StatusOK)
+}
+
+func (c *V2Controller) SetPrimaryContact(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.SetPrimaryContact, http.StatusOK)
+}
+
+func (c *V2Controller) ListContacts(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListContacts, http.StatusOK)
+}
+
+func (c *V2Controller) CreateAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.CreateAddress, http.StatusCreated)
+}
+
+func (c *V2Controller) GetAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetAddress, http.StatusOK)
+}
+
+func (c *V2Controller) UpdateAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.UpdateAddress, http.StatusOK)
+}
+
+func (c *V2Controller) PatchAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.PatchAddress, http.StatusOK)
+}
+
+func (c *V2Controller) DeleteAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.DeleteAddress, http.StatusNoContent)
+}
+
+func (c *V2Controller) RestoreAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.RestoreAddress, http.StatusOK)
+}
+
+func (c *V2Controller) SetPrimaryAddress(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.SetPrimaryAddress, http.StatusOK)
+}
+
+func (c *V2Controller) ListAddresses(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListAddresses, http.StatusOK)
+}
+
+func (c *V2Controller) SearchAddressesNear(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.SearchAddressesNear, http.StatusOK)
+}
+
+func (c *V2Controller) CreateCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.CreateCompany, http.StatusCreated)
+}
+
+func (c *V2Controller) GetCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetCompany, http.StatusOK)
+}
+
+func (c *V2Controller) UpdateCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.UpdateCompany, http.StatusOK)
+}
+
+func (c *V2Controller) PatchCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.PatchCompany, http.StatusOK)
+}
+
+func (c *V2Controller) DeleteCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.DeleteCompany, http.StatusNoContent)
+}
+
+func (c *V2Controller) RestoreCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.RestoreCompany, http.StatusOK)
+}
+
+func (c *V2Controller) SetPrimaryCompany(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.SetPrimaryCompany, http.StatusOK)
+}
+
+func (c *V2Controller) ListCompanies(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListCompanies, http.StatusOK)
+}
+
+func (c *V2Controller) AddCompanyMember(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.AddCompanyMember, http.StatusOK)
+}
+
+func (c *V2Controller) RemoveCompanyMember(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.RemoveCompanyMember, http.StatusNoContent)
+}
+
+func (c *V2Controller) ListCompanyMembers(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListCompanyMembers, http.StatusOK)
+}
+
+func (c *V2Controller) ListProfileCompanies(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListProfileCompanies, http.StatusOK)
+}
+
+func (c *V2Controller) GetPreferences(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetPreferences, http.StatusOK)
+}
+
+func (c *V2Controller) SetPreferences(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.SetPreferences, http.StatusOK)
+}
+
+func (c *V2Controller) DeletePreference(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.DeletePreference, http.StatusNoContent)
+}
+
+func (c *V2Controller) GetAttachment(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.GetAttachment, http.StatusOK)
+}
+
+func (c *V2Controller) ListAttachments(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListAttachments, http.StatusOK)
+}
+
+func (c *V2Controller) DeleteAttachment(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.DeleteAttachment, http.StatusNoContent)
+}
+
+func (c *V2Controller) ListAuditEvents(ctx echo.Context) error {
+	return serveV2(ctx, c.logger, c.server.ListAuditEvents, http.StatusOK)
+}
+
+// serveV2 binds the request of call, invokes it and writes its response, with the version of
+// the returned record as the ETag.
+func serveV2[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message](ctx echo.Context, l logrus.FieldLogger, call func(context.Context, PReq) (Resp, error), successStatus int) error {
+	req := PReq(new(Req))
+	if err := typesv2.BindRequest(ctx, req); err != nil {
+		if errors.Is(err, types.ErrUnsupportedPatchContentType) {
+			return ctx.JSON(http.StatusUnsupportedMediaType, httpdto.ErrorResponse{Error: err.Error()})
+		}
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	resp, err := call(ctx.Request().Context(), req)
+	if err != nil {
+		return v2Error(ctx, err)
+	}
+	if _, ok := any(resp).(*emptypb.Empty); ok {
+		return ctx.NoContent(successStatus)
+	}
+
+	if fd := resp.ProtoReflect().Descriptor().Fields().ByName("version"); fd != nil && fd.Kind() == protoreflect.Uint64Kind {
+		setETag(ctx, resp.ProtoReflect().Get(fd).Uint())
+	}
+	body, err := v2JSON.Marshal(resp)
+	if err != nil {
+		l.WithError(err).Error("Failed to marshal v2 response")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	return ctx.JSONBlob(successStatus, body)
+}
+
+// v2Error answers with the HTTP status matching the gRPC status of err. Version conflicts keep
+// the 412 the v1 routes answer with; other ABORTED conflicts are 409.
+func v2Error(ctx echo.Context, err error) error {
+	st := status.Convert(err)
+	response := httpdto.ErrorResponse{Error: st.Message()}
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok && len(badRequest.GetFieldViolations()) > 0 {
+			response.Field = badRequest.GetFieldViolations()[0].GetField()
+		}
+	}
+
+	code := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.AlreadyExists:
+		code = http.StatusConflict
+	case codes.Aborted:
+		code = http.StatusConflict
+		if st.Message() == "version mismatch" {
+			code = http.StatusPreconditionFailed
+		}
+	case codes.FailedPrecondition:
+		code = http.StatusUnprocessableEntity
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		code = http.StatusRequestEntityTooLarge
+	}
+
+	return ctx.JSON(code, response)
+}
@@ -0,0 +1,296 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type v2ServerStub struct {
+	typesv2.UnimplementedProfileServiceServer
+	patchAddressFn        func(ctx context.Context, req *typesv2.PatchAddressRequest) (*typesv2.AddressResponse, error)
+	deleteAddressFn       func(ctx context.Context, req *typesv2.DeleteAddressRequest) (*emptypb.Empty, error)
+	searchAddressesNearFn func(ctx context.Context, req *typesv2.SearchAddressesNearRequest) (*typesv2.SearchAddressesNearResponse, error)
+	listAddressesFn       func(ctx context.Context, req *typesv2.ListAddressesRequest) (*typesv2.ListAddressesResponse, error)
+	uploadAttachmentFn    func(stream typesv2.ProfileService_UploadAttachmentServer) error
+	downloadAttachmentFn  func(req *typesv2.DownloadAttachmentRequest, stream typesv2.ProfileService_DownloadAttachmentServer) error
+}
+
+func (s *v2ServerStub) PatchAddress(ctx context.Context, req *typesv2.PatchAddressRequest) (*typesv2.AddressResponse, error) {
+	return s.patchAddressFn(ctx, req)
+}
+
+func (s *v2ServerStub) DeleteAddress(ctx context.Context, req *typesv2.DeleteAddressRequest) (*emptypb.Empty, error) {
+	return s.deleteAddressFn(ctx, req)
+}
+
+func (s *v2ServerStub) SearchAddressesNear(ctx context.Context, req *typesv2.SearchAddressesNearRequest) (*typesv2.SearchAddressesNearResponse, error) {
+	return s.searchAddressesNearFn(ctx, req)
+}
+
+func (s *v2ServerStub) ListAddresses(ctx context.Context, req *typesv2.ListAddressesRequest) (*typesv2.ListAddressesResponse, error) {
+	return s.listAddressesFn(ctx, req)
+}
+
+func (s *v2ServerStub) UploadAttachment(stream typesv2.ProfileService_UploadAttachmentServer) error {
+	return s.uploadAttachmentFn(stream)
+}
+
+func (s *v2ServerStub) DownloadAttachment(req *typesv2.DownloadAttachmentRequest, stream typesv2.ProfileService_DownloadAttachmentServer) error {
+	return s.downloadAttachmentFn(req, stream)
+}
+
+func TestV2PatchAddressBindsMergePatch(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		patchAddressFn: func(_ context.Context, req *typesv2.PatchAddressRequest) (*typesv2.AddressResponse, error) {
+			if req.GetId() != 3 || req.GetStreetNo() != "2A" || req.GetExpectedVersion() != 4 {
+				t.Fatalf("unexpected request: %+v", req)
+			}
+			if paths := req.GetUpdateMask().GetPaths(); strings.Join(paths, ",") != "city,street_no" {
+				t.Fatalf("unexpected update mask: %v", paths)
+			}
+			return &typesv2.AddressResponse{
+				Id:        3,
+				StreetNo:  "2A",
+				Version:   5,
+				UpdatedAt: timestamppb.New(time.Date(2026, time.March, 1, 10, 30, 0, 0, time.UTC)),
+			}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/v2/addresses/3", strings.NewReader(`{"street_no":"2A","city":null}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	req.Header.Set("If-Match", `"4"`)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.PatchAddress(ctx); err != nil {
+		t.Fatalf("PatchAddress() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(headerETag); got != `"5"` {
+		t.Fatalf("expected ETag \"5\", got %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"street_no":"2A"`) || !strings.Contains(body, `"updated_at":"2026-03-01T10:30:00Z"`) {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestV2PatchAddressMasksEachAttribute(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		patchAddressFn: func(_ context.Context, req *typesv2.PatchAddressRequest) (*typesv2.AddressResponse, error) {
+			if paths := req.GetUpdateMask().GetPaths(); strings.Join(paths, ",") != "attributes.dock,attributes.gate" {
+				t.Fatalf("unexpected update mask: %v", paths)
+			}
+			if req.GetAttributes().GetFields()["dock"].GetStringValue() != "B" {
+				t.Fatalf("unexpected attributes: %v", req.GetAttributes())
+			}
+			return &typesv2.AddressResponse{Id: 3, Version: 2}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/v2/addresses/3", strings.NewReader(`{"attributes":{"dock":"B","gate":null}}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("3")
+
+	if err := ctrl.PatchAddress(ctx); err != nil {
+		t.Fatalf("PatchAddress() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestV2ListAddressesBindsAttributeFilters(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		listAddressesFn: func(_ context.Context, req *typesv2.ListAddressesRequest) (*typesv2.ListAddressesResponse, error) {
+			if req.GetProfileId() != 9 || req.GetAttributes()["dock"] != "B" || len(req.GetAttributes()) != 1 {
+				t.Fatalf("unexpected request: %+v", req)
+			}
+			return &typesv2.ListAddressesResponse{}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/addresses?profile_id=9&attributes.dock=B", nil)
+	rec := httptest.NewRecorder()
+
+	if err := ctrl.ListAddresses(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ListAddresses() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestV2SearchAddressesNearBindsQuery(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		searchAddressesNearFn: func(_ context.Context, req *typesv2.SearchAddressesNearRequest) (*typesv2.SearchAddressesNearResponse, error) {
+			if req.GetCenter().GetLatitude() != 46.77 || req.GetCenter().GetLongitude() != 23.59 || req.GetRadiusMeters() != 500 || req.GetProfileId() != 9 {
+				t.Fatalf("unexpected request: %+v", req)
+			}
+			return &typesv2.SearchAddressesNearResponse{}, nil
+		},
+	})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v2/addresses/near?center.latitude=46.77&center.longitude=23.59&radius_meters=500&profile_id=9", nil)
+	rec := httptest.NewRecorder()
+
+	if err := ctrl.SearchAddressesNear(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("SearchAddressesNear() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/addresses/near?radius=500", nil)
+	rec = httptest.NewRecorder()
+	if err := ctrl.SearchAddressesNear(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("SearchAddressesNear() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "unknown parameter") {
+		t.Fatalf("expected 400 for an unknown parameter, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestV2DeleteAddressStatuses(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusNoContent},
+		{status.Error(codes.NotFound, "address not found"), http.StatusNotFound},
+		{status.Error(codes.Aborted, "version mismatch"), http.StatusPreconditionFailed},
+		{status.Error(codes.Aborted, "another address became primary"), http.StatusConflict},
+		{status.Error(codes.FailedPrecondition, "profile is deleted; restore it first"), http.StatusUnprocessableEntity},
+		{status.Error(codes.Internal, "internal server error"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		ctrl := NewV2Controller(&v2ServerStub{
+			deleteAddressFn: func(context.Context, *typesv2.DeleteAddressRequest) (*emptypb.Empty, error) {
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return &emptypb.Empty{}, nil
+			},
+		})
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/v2/addresses/3", nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("3")
+
+		if err := ctrl.DeleteAddress(ctx); err != nil {
+			t.Fatalf("DeleteAddress() returned unexpected error: %v", err)
+		}
+		if rec.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.err, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestV2UploadAttachmentStreamsMetadataThenContent(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		uploadAttachmentFn: func(stream typesv2.ProfileService_UploadAttachmentServer) error {
+			first, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if upload := first.GetMetadata(); upload.GetEntityId() != 7 || upload.GetFilename() != "me.png" {
+				t.Fatalf("unexpected metadata: %+v", first)
+			}
+			var content []byte
+			for {
+				msg, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				content = append(content, msg.GetChunk()...)
+			}
+			if !bytes.Equal(content, pngHeader) {
+				t.Fatalf("unexpected content %q", content)
+			}
+			return stream.SendAndClose(&typesv2.AttachmentResponse{Id: 1, Size: int64(len(content))})
+		},
+	})
+	rec := httptest.NewRecorder()
+	if err := ctrl.UploadAttachment(echo.New().NewContext(newUploadRequest(t, "/v2/attachments", "7", pngHeader), rec)); err != nil {
+		t.Fatalf("UploadAttachment() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"size":"16"`) {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestV2UploadAttachmentTooLargeIs413(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		uploadAttachmentFn: func(typesv2.ProfileService_UploadAttachmentServer) error {
+			return status.Error(codes.ResourceExhausted, "attachment is too large")
+		},
+	})
+	rec := httptest.NewRecorder()
+	if err := ctrl.UploadAttachment(echo.New().NewContext(newUploadRequest(t, "/v2/attachments", "7", pngHeader), rec)); err != nil {
+		t.Fatalf("UploadAttachment() returned unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rec.Code)
+	}
+}
+
+func TestV2DownloadAttachmentWritesChunks(t *testing.T) {
+	ctrl := NewV2Controller(&v2ServerStub{
+		downloadAttachmentFn: func(req *typesv2.DownloadAttachmentRequest, stream typesv2.ProfileService_DownloadAttachmentServer) error {
+			if req.GetId() != 1 {
+				return status.Error(codes.NotFound, "attachment not found")
+			}
+			metadata := &typesv2.AttachmentResponse{Id: 1, Filename: "me.png", ContentType: "image/png", Size: int64(len(pngHeader))}
+			if err := stream.Send(&typesv2.DownloadAttachmentResponse{Data: &typesv2.DownloadAttachmentResponse_Metadata{Metadata: metadata}}); err != nil {
+				return err
+			}
+			for _, chunk := range [][]byte{pngHeader[:4], pngHeader[4:]} {
+				if err := stream.Send(&typesv2.DownloadAttachmentResponse{Data: &typesv2.DownloadAttachmentResponse_Chunk{Chunk: chunk}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	for id, want := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/v2/attachments/"+id+"/content", nil), rec)
+		ctx.SetParamNames("id")
+		ctx.SetParamValues(id)
+		if err := ctrl.DownloadAttachment(ctx); err != nil {
+			t.Fatalf("DownloadAttachment() returned unexpected error: %v", err)
+		}
+		if rec.Code != want {
+			t.Fatalf("id %s: expected %d, got %d", id, want, rec.Code)
+		}
+		if want == http.StatusOK && (!bytes.Equal(rec.Body.Bytes(), pngHeader) || rec.Header().Get(echo.HeaderContentType) != "image/png") {
+			t.Fatalf("unexpected download: %q %v", rec.Body.String(), rec.Header())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// v2UploadChunkSize is the size of the chunks an HTTP upload is streamed to the service in.
+const v2UploadChunkSize = 64 << 10
+
+// UploadAttachment reads a multipart/form-data upload the way the v1 route does and streams it
+// to the v2 service in chunks, as a gRPC client would.
+func (c *V2Controller) UploadAttachment(ctx echo.Context) error {
+	req, content, err := types.NewAttachmentUploadFromContext(ctx)
+	if err != nil {
+		c.logger.WithError(err).Debug("Failed to read v2 attachment upload")
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: "invalid multipart body"})
+	}
+
+	stream := &v2UploadStream{
+		v2HTTPStream: v2HTTPStream{ctx: ctx.Request().Context()},
+		metadata: &typesv2.AttachmentUpload{
+			EntityType:  req.GetEntityType(),
+			EntityId:    req.GetEntityId(),
+			Kind:        req.GetKind(),
+			Filename:    req.GetFilename(),
+			ContentType: req.GetContentType(),
+		},
+		content: content,
+	}
+	if err = c.server.UploadAttachment(stream); err != nil {
+		return v2Error(ctx, err)
+	}
+
+	body, err := v2JSON.Marshal(stream.resp)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to marshal v2 response")
+		return ctx.JSON(http.StatusInternalServerError, httpdto.ErrorResponse{Error: "internal server error"})
+	}
+
+	return ctx.JSONBlob(http.StatusCreated, body)
+}
+
+// DownloadAttachment writes the content the v2 service streams as the response body, under
+// the headers its leading metadata message describes.
+func (c *V2Controller) DownloadAttachment(ctx echo.Context) error {
+	req := &typesv2.DownloadAttachmentRequest{}
+	if err := typesv2.BindRequest(ctx, req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, httpdto.ErrorResponse{Error: err.Error()})
+	}
+
+	stream := &v2DownloadStream{v2HTTPStream: v2HTTPStream{ctx: ctx.Request().Context()}, echo: ctx}
+	if err := c.server.DownloadAttachment(req, stream); err != nil {
+		if ctx.Response().Committed {
+			c.logger.WithError(err).Error("Download attachment interrupted")
+			return nil
+		}
+		return v2Error(ctx, err)
+	}
+
+	return nil
+}
+
+// v2HTTPStream is the server stream of a call made in process for an HTTP request. Headers
+// and trailers have nowhere to go and are dropped.
+type v2HTTPStream struct {
+	ctx context.Context
+}
+
+func (s *v2HTTPStream) SetHeader(metadata.MD) error  { return nil }
+func (s *v2HTTPStream) SendHeader(metadata.MD) error { return nil }
+func (s *v2HTTPStream) SetTrailer(metadata.MD)       {}
+func (s *v2HTTPStream) Context() context.Context     { return s.ctx }
+
+func (s *v2HTTPStream) SendMsg(any) error {
+	return errors.New("v2 http stream: untyped messages are not supported")
+}
+
+func (s *v2HTTPStream) RecvMsg(any) error {
+	return errors.New("v2 http stream: untyped messages are not supported")
+}
+
+// v2UploadStream sends the metadata of an upload and then its content in chunks.
+type v2UploadStream struct {
+	v2HTTPStream
+	metadata *typesv2.AttachmentUpload
+	content  io.Reader
+	resp     *typesv2.AttachmentResponse
+}
+
+func (s *v2UploadStream) Recv() (*typesv2.UploadAttachmentRequest, error) {
+	if s.metadata != nil {
+		msg := &typesv2.UploadAttachmentRequest{Data: &typesv2.UploadAttachmentRequest_Metadata{Metadata: s.metadata}}
+		s.metadata = nil
+		return msg, nil
+	}
+
+	chunk := make([]byte, v2UploadChunkSize)
+	n, err := io.ReadFull(s.content, chunk)
+	if n > 0 {
+		return &typesv2.UploadAttachmentRequest{Data: &typesv2.UploadAttachmentRequest_Chunk{Chunk: chunk[:n]}}, nil
+	}
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	return nil, status.Error(codes.InvalidArgument, "invalid multipart body")
+}
+
+func (s *v2UploadStream) SendAndClose(resp *typesv2.AttachmentResponse) error {
+	s.resp = resp
+	return nil
+}
+
+// v2DownloadStream writes the headers on the metadata message and the chunks to the body.
+type v2DownloadStream struct {
+	v2HTTPStream
+	echo echo.Context
+}
+
+func (s *v2DownloadStream) Send(resp *typesv2.DownloadAttachmentResponse) error {
+	if attachment := resp.GetMetadata(); attachment != nil {
+		writeAttachmentHeaders(s.echo, attachment.GetFilename(), attachment.GetContentType(), attachment.GetSize())
+		s.echo.Response().WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	_, err := s.echo.Response().Write(resp.GetChunk())
+	return err
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	httpdto "github.com/vibast-solutions/ms-go-profile/app/dto"
+)
+
+// validationError answers 400 with err, naming the offending field when err carries one.
+func validationError(ctx echo.Context, err error) error {
+	response := httpdto.ErrorResponse{Error: err.Error()}
+	var fieldErr *country.FieldError
+	if errors.As(err, &fieldErr) {
+		response.Field = fieldErr.Field
+	}
+
+	return ctx.JSON(http.StatusBadRequest, response)
+}
@@ -0,0 +1,58 @@
+package country
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError reports a request field that does not fit the rules of its country, such as an
+// address county or a company fiscal code.
+type FieldError struct {
+	// Field is the request field, e.g. postal_code.
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Address holds the address fields that depend on the country.
+type Address struct {
+	Country    string
+	County     string
+	PostalCode string
+}
+
+// NormalizeAddress returns a with the country as its alpha-2 code, the county trimmed and the
+// postal code in the form PostalCode returns. The county must be a subdivision of the country
+// when its subdivisions are known; an empty county or postal code is left to the caller.
+func NormalizeAddress(a Address) (Address, error) {
+	c, ok := Lookup(a.Country)
+	if !ok {
+		return a, &FieldError{
+			Field:   "country",
+			Message: fmt.Sprintf("country %q is not an ISO 3166-1 country code or name", strings.TrimSpace(a.Country)),
+		}
+	}
+
+	normalized := Address{Country: c.Alpha2, County: strings.TrimSpace(a.County)}
+	if normalized.County != "" && c.HasSubdivisions() {
+		if _, ok = c.Subdivision(normalized.County); !ok {
+			return a, &FieldError{
+				Field:   "county",
+				Message: fmt.Sprintf("county %q is not a subdivision of %s", normalized.County, c.Alpha2),
+			}
+		}
+	}
+	if strings.TrimSpace(a.PostalCode) != "" {
+		if normalized.PostalCode, ok = c.PostalCode(a.PostalCode); !ok {
+			return a, &FieldError{
+				Field:   "postal_code",
+				Message: fmt.Sprintf("postal_code %q is not a valid %s postal code", normalized.PostalCode, c.Alpha2),
+			}
+		}
+	}
+
+	return normalized, nil
+}
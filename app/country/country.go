@@ -0,0 +1,160 @@
+// Package country resolves ISO 3166-1 countries and ISO 3166-2 subdivisions and checks postal
+// codes against the format of their country.
+//
+// countries.json is built from the country and subdivision data of github.com/pariz/gountries
+// and the postal code patterns of github.com/go-playground/validator, both MIT licensed. The
+// regions of FR, IT and ES were added next to their departments and provinces, since addresses
+// name either. The subdivisions of GB are left out: ISO 3166-2:GB lists council areas, not the
+// counties written in British addresses.
+package country
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed countries.json
+var countriesJSON []byte
+
+// Country is an ISO 3166-1 country.
+type Country struct {
+	Alpha2 string
+	Alpha3 string
+	Name   string
+
+	postalCode   *regexp.Regexp
+	subdivisions map[string]Subdivision
+}
+
+// Subdivision is an ISO 3166-2 subdivision, such as a county, state or region.
+type Subdivision struct {
+	// Code is the full ISO 3166-2 code, e.g. RO-CJ.
+	Code string
+	Name string
+}
+
+type countryRecord struct {
+	Alpha2       string              `json:"alpha2"`
+	Alpha3       string              `json:"alpha3"`
+	Name         string              `json:"name"`
+	Names        []string            `json:"names"`
+	PostalCode   string              `json:"postal_code"`
+	Subdivisions []subdivisionRecord `json:"subdivisions"`
+}
+
+type subdivisionRecord struct {
+	Code  string   `json:"code"`
+	Name  string   `json:"name"`
+	Names []string `json:"names"`
+}
+
+// index maps the folded codes and names of every country to it.
+var index = sync.OnceValue(func() map[string]*Country {
+	var records []countryRecord
+	if err := json.Unmarshal(countriesJSON, &records); err != nil {
+		panic(fmt.Sprintf("country: invalid embedded data: %v", err))
+	}
+
+	byKey := make(map[string]*Country, len(records)*4)
+	// Codes are added first so that a name can never shadow a code.
+	countries := make([]*Country, len(records))
+	for i, record := range records {
+		c := &Country{Alpha2: record.Alpha2, Alpha3: record.Alpha3, Name: record.Name}
+		if record.PostalCode != "" {
+			c.postalCode = regexp.MustCompile(`^(?:` + record.PostalCode + `)$`)
+		}
+		if len(record.Subdivisions) > 0 {
+			c.subdivisions = make(map[string]Subdivision, len(record.Subdivisions)*2)
+			for _, s := range record.Subdivisions {
+				subdivision := Subdivision{Code: record.Alpha2 + "-" + s.Code, Name: s.Name}
+				c.subdivisions[fold(s.Code)] = subdivision
+				c.subdivisions[fold(subdivision.Code)] = subdivision
+			}
+			for _, s := range record.Subdivisions {
+				for _, name := range append([]string{s.Name}, s.Names...) {
+					addOnce(c.subdivisions, fold(name), Subdivision{Code: record.Alpha2 + "-" + s.Code, Name: s.Name})
+				}
+			}
+		}
+		countries[i] = c
+		byKey[fold(c.Alpha2)] = c
+		byKey[fold(c.Alpha3)] = c
+	}
+	for i, record := range records {
+		for _, name := range append([]string{record.Name}, record.Names...) {
+			addOnce(byKey, fold(name), countries[i])
+		}
+	}
+
+	return byKey
+})
+
+func addOnce[V any](m map[string]V, key string, value V) {
+	if _, ok := m[key]; !ok && key != "" {
+		m[key] = value
+	}
+}
+
+// Lookup finds a country by its alpha-2 or alpha-3 code or by its English or native name,
+// ignoring case, accents and punctuation.
+func Lookup(raw string) (*Country, bool) {
+	c, ok := index()[fold(raw)]
+	return c, ok
+}
+
+// HasSubdivisions reports whether the subdivisions of c are known.
+func (c *Country) HasSubdivisions() bool {
+	return len(c.subdivisions) > 0
+}
+
+// Subdivision finds a subdivision of c by its code, with or without the country prefix, or
+// by one of its names.
+func (c *Country) Subdivision(raw string) (Subdivision, bool) {
+	s, ok := c.subdivisions[fold(raw)]
+	return s, ok
+}
+
+// PostalCode returns raw in upper case with its spaces collapsed, and whether it has the
+// postal code format of c. Codes of countries without a known format are not checked.
+func (c *Country) PostalCode(raw string) (string, bool) {
+	code := strings.ToUpper(strings.Join(strings.Fields(raw), " "))
+	if c.postalCode == nil {
+		return code, true
+	}
+
+	return code, c.postalCode.MatchString(code)
+}
+
+// fold reduces s to lower-case letters and digits without accents, separated by single
+// spaces, so that "Bistrița-Năsăud " and "bistrita nasaud" compare equal.
+func fold(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), s)
+	if err != nil {
+		stripped = s
+	}
+
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(stripped) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		space = true
+	}
+
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package country
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	cases := map[string]string{
+		"RO":                       "RO",
+		"ro":                       "RO",
+		"ROU":                      "RO",
+		"Romania":                  "RO",
+		"romania ":                 "RO",
+		"România":                  "RO",
+		"Germany":                  "DE",
+		"Deutschland":              "DE",
+		"United States of America": "US",
+		"usa":                      "US",
+		"UK":                       "GB",
+		"cote d ivoire":            "CI",
+	}
+	for raw, want := range cases {
+		c, ok := Lookup(raw)
+		if !ok {
+			t.Fatalf("Lookup(%q) found nothing, want %s", raw, want)
+		}
+		if c.Alpha2 != want {
+			t.Fatalf("Lookup(%q) = %s, want %s", raw, c.Alpha2, want)
+		}
+	}
+
+	for _, raw := range []string{"", "XX", "Atlantis", "R O"} {
+		if c, ok := Lookup(raw); ok {
+			t.Fatalf("Lookup(%q) = %s, want no match", raw, c.Alpha2)
+		}
+	}
+}
+
+func TestSubdivision(t *testing.T) {
+	ro, _ := Lookup("RO")
+	if !ro.HasSubdivisions() {
+		t.Fatal("expected Romanian counties to be known")
+	}
+	for _, raw := range []string{"CJ", "ro-cj", "Cluj", "Bistrița-Năsăud", "bistrita nasaud", "Bucharest", "B"} {
+		if _, ok := ro.Subdivision(raw); !ok {
+			t.Fatalf("expected %q to be a Romanian county", raw)
+		}
+	}
+	if s, _ := ro.Subdivision("cluj"); s.Code != "RO-CJ" || s.Name != "Cluj" {
+		t.Fatalf("unexpected subdivision: %+v", s)
+	}
+	if _, ok := ro.Subdivision("Bavaria"); ok {
+		t.Fatal("expected Bavaria not to be a Romanian county")
+	}
+
+	for country, names := range map[string][]string{"FR": {"Ile-de-France", "Paris", "75"}, "IT": {"Lombardia", "Milano"}} {
+		c, _ := Lookup(country)
+		for _, name := range names {
+			if _, ok := c.Subdivision(name); !ok {
+				t.Fatalf("expected %q to be a subdivision of %s", name, country)
+			}
+		}
+	}
+
+	gb, _ := Lookup("GB")
+	if gb.HasSubdivisions() {
+		t.Fatal("expected GB subdivisions not to be checked")
+	}
+}
+
+func TestPostalCode(t *testing.T) {
+	cases := []struct {
+		country, raw, want string
+		ok                 bool
+	}{
+		{"RO", "400001", "400001", true},
+		{"RO", "40001", "40001", false},
+		{"NL", " 1012  ab ", "1012 AB", true},
+		{"GB", "sw1a 1aa", "SW1A 1AA", true},
+		{"GB", "XXSW1A 1AAYY", "XXSW1A 1AAYY", false},
+		{"US", "12345-6789", "12345-6789", true},
+		{"AE", "anything", "ANYTHING", true},
+	}
+	for _, tc := range cases {
+		c, _ := Lookup(tc.country)
+		got, ok := c.PostalCode(tc.raw)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("%s PostalCode(%q) = %q, %v, want %q, %v", tc.country, tc.raw, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	got, err := NormalizeAddress(Address{Country: "romania ", County: " Cluj ", PostalCode: "400001"})
+	if err != nil {
+		t.Fatalf("expected valid address, got %v", err)
+	}
+	if got != (Address{Country: "RO", County: "Cluj", PostalCode: "400001"}) {
+		t.Fatalf("unexpected normalized address: %+v", got)
+	}
+
+	// Countries without subdivision data accept any county.
+	if _, err = NormalizeAddress(Address{Country: "AQ", County: "Ross Dependency"}); err != nil {
+		t.Fatalf("expected county to be accepted, got %v", err)
+	}
+
+	cases := []struct {
+		address Address
+		field   string
+	}{
+		{Address{Country: "Romaniaa", County: "Cluj"}, "country"},
+		{Address{Country: "RO", County: "Bavaria"}, "county"},
+		{Address{Country: "RO", County: "Cluj", PostalCode: "4000"}, "postal_code"},
+	}
+	for _, tc := range cases {
+		_, err = NormalizeAddress(tc.address)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != tc.field {
+			t.Fatalf("NormalizeAddress(%+v) = %v, want an error for %s", tc.address, err, tc.field)
+		}
+	}
+}
@@ -2,8 +2,23 @@ package dto
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Field names the request field that failed validation, when the error is about one.
+	Field string `json:"field,omitempty"`
 }
 
 type DeleteResponse struct {
 	Message string `json:"message"`
 }
+
+// PreferencesResponse lists the preference namespaces of a profile. Values keep the JSON they
+// were written with.
+type PreferencesResponse struct {
+	ProfileID  uint64                `json:"profile_id"`
+	Namespaces []PreferenceNamespace `json:"namespaces"`
+}
+
+type PreferenceNamespace struct {
+	Namespace string         `json:"namespace"`
+	Values    map[string]any `json:"values"`
+	UpdatedAt string         `json:"updated_at,omitempty"`
+}
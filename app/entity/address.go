@@ -3,18 +3,52 @@ package entity
 import "time"
 
 type Address struct {
-	ID             uint64
-	StreetName     string
-	StreenNo       string
-	City           string
-	County         string
-	Country        string
+	ID         uint64
+	StreetName string
+	StreenNo   string
+	City       string
+	County     string
+	Country    string
+	// ProfileID and CompanyID name the owner of the address; exactly one of them is non-zero.
 	ProfileID      uint64
+	CompanyID      uint64
 	PostalCode     string
 	Building       string
 	Apartment      string
 	AdditionalData string
 	Type           string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// Attributes holds the custom attributes of the address by name.
+	Attributes map[string]any
+	// IsPrimary marks the default address of its Type on its owner.
+	IsPrimary bool
+	// Latitude and Longitude are both set or both nil.
+	Latitude  *float64
+	Longitude *float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   uint64
+	DeletedAt *time.Time
+}
+
+// NearbyAddress is an address found by a location search.
+type NearbyAddress struct {
+	Address *Address
+	// DistanceMeters is the distance from the search center, or 0 for a bounding-box search.
+	DistanceMeters float64
+}
+
+// Types of the addresses of a company.
+const (
+	AddressTypeRegisteredOffice = "registered_office"
+	AddressTypeBilling          = "billing"
+	AddressTypeWarehouse        = "warehouse"
+)
+
+// IsCompanyAddressType reports whether addressType is one a company's address can have.
+func IsCompanyAddressType(addressType string) bool {
+	switch addressType {
+	case AddressTypeRegisteredOffice, AddressTypeBilling, AddressTypeWarehouse:
+		return true
+	}
+	return false
 }
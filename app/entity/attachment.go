@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Attachment is a file attached to a profile, contact, address or company, such as an avatar
+// or an ID card scan. Its content is kept by the blob store under StorageKey.
+type Attachment struct {
+	ID uint64
+	// EntityType is the kind of record owning the attachment: profile, contact, address or company.
+	EntityType string
+	EntityID   uint64
+	// Kind tells attachments of the same owner apart, such as avatar or id_card.
+	Kind        string
+	Filename    string
+	ContentType string
+	Size        int64
+	// SHA256 is the hex-encoded digest of the content.
+	SHA256     string
+	StorageKey string
+	CreatedAt  time.Time
+}
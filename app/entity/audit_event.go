@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent records one mutation of a profile, contact, address or company.
+type AuditEvent struct {
+	ID            uint64
+	EntityType    string
+	EntityID      uint64
+	Action        string
+	OldValues     json.RawMessage
+	NewValues     json.RawMessage
+	CallerService string
+	RequestID     string
+	CreatedAt     time.Time
+}
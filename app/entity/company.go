@@ -7,8 +7,18 @@ type Company struct {
 	Name           string
 	RegistrationNo string
 	FiscalCode     string
+	// FiscalCodeValid is set when a country validator accepted FiscalCode and RegistrationNo.
+	FiscalCodeValid bool
+	// VATPayerPrefix is the EU VAT prefix FiscalCode was given with, e.g. RO.
+	VATPayerPrefix string
 	ProfileID      uint64
 	Type           string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// Attributes holds the custom attributes of the company by name.
+	Attributes map[string]any
+	// IsPrimary marks the default company of its Type on the profile.
+	IsPrimary bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   uint64
+	DeletedAt *time.Time
 }
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	CompanyRoleOwner      = "owner"
+	CompanyRoleAdmin      = "admin"
+	CompanyRoleAccountant = "accountant"
+	CompanyRoleViewer     = "viewer"
+)
+
+// CompanyMember links a profile to a company it has access to under Role.
+type CompanyMember struct {
+	CompanyID uint64
+	ProfileID uint64
+	Role      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CompanyMembership is a company a profile is a member of.
+type CompanyMembership struct {
+	Company *Company
+	Role    string
+}
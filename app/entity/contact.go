@@ -7,10 +7,21 @@ type Contact struct {
 	FirstName string
 	LastName  string
 	NIN       string
-	DOB       *time.Time
-	Phone     string
+	// NINCountry is the ISO 3166-1 alpha-2 code of the country that issued NIN.
+	NINCountry string
+	DOB        *time.Time
+	Phone      string
+	// PhoneE164 and PhoneType are derived from Phone; both are empty when Phone is.
+	PhoneE164 string
+	PhoneType string
 	Type      string
+	// Attributes holds the custom attributes of the contact by name, with JSON-decoded values.
+	Attributes map[string]any
+	// IsPrimary marks the default contact of its Type on the profile.
+	IsPrimary bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	ProfileID uint64
+	Version   uint64
+	DeletedAt *time.Time
 }
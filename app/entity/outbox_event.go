@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event stored with the change that caused it and relayed to the
+// event publisher afterwards.
+type OutboxEvent struct {
+	ID            uint64
+	AggregateType string
+	AggregateID   uint64
+	EventType     string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+	Attempts      uint32
+	NextAttemptAt time.Time
+	LastError     string
+	PublishedAt   *time.Time
+}
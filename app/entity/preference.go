@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Preference is one setting of a profile, such as its language, stored under a namespace that
+// usually names the service owning it.
+type Preference struct {
+	ProfileID uint64
+	Namespace string
+	Key       string
+	// Value is the setting as a JSON document.
+	Value     json.RawMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -8,4 +8,6 @@ type Profile struct {
 	Email     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   uint64
+	DeletedAt *time.Time
 }
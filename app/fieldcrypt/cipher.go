@@ -0,0 +1,217 @@
+// Package fieldcrypt encrypts individual database columns with envelope encryption.
+//
+// Every value gets its own random data key. The value is sealed with the data key using
+// AES-256-GCM, and the data key is sealed with a key-encryption key from the keyring. The
+// stored form names the key-encryption key, so keys can be rotated without losing access
+// to older rows:
+//
+//	enc:v1:<key id>:<sealed data key>:<sealed value>
+//
+// A separate HMAC key derives a deterministic blind index, which allows exact-match lookups
+// without decrypting every row.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	FieldNIN   = "nin"
+	FieldPhone = "phone"
+	FieldDOB   = "dob"
+)
+
+// KeySize is the length of key-encryption, data and blind index keys.
+const KeySize = 32
+
+const prefix = "enc:v1:"
+
+var (
+	ErrUnknownKey       = errors.New("unknown encryption key id")
+	ErrMalformedValue   = errors.New("malformed encrypted value")
+	ErrInvalidKey       = errors.New("invalid encryption key")
+	ErrMissingIndexKey  = errors.New("blind index key is required")
+	ErrUnsupportedField = errors.New("field cannot be encrypted")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Cipher seals and opens column values. A nil *Cipher stores everything in plaintext, which
+// keeps local setups without keys working.
+type Cipher struct {
+	keys        map[string][]byte
+	activeKeyID string
+	indexKey    []byte
+	fields      map[string]bool
+}
+
+// New builds a cipher that encrypts fields with the active key and can decrypt values
+// sealed with any key in keys.
+func New(keys map[string][]byte, activeKeyID string, indexKey []byte, fields []string) (*Cipher, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q is not in the keyring", ErrUnknownKey, activeKeyID)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: key id %q", ErrInvalidKey, id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%w: key %q must be %d bytes", ErrInvalidKey, id, KeySize)
+		}
+	}
+	if len(indexKey) != KeySize {
+		return nil, ErrMissingIndexKey
+	}
+
+	enabled := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		switch field {
+		case FieldNIN, FieldPhone, FieldDOB:
+			enabled[field] = true
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedField, field)
+		}
+	}
+
+	return &Cipher{keys: keys, activeKeyID: activeKeyID, indexKey: indexKey, fields: enabled}, nil
+}
+
+// Encrypts reports whether values of field are stored encrypted.
+func (c *Cipher) Encrypts(field string) bool {
+	return c != nil && c.fields[field]
+}
+
+// Seal returns the stored form of a field value: encrypted with the active key when the field
+// is encrypted, the value itself otherwise. Empty values stay empty.
+func (c *Cipher) Seal(field, plaintext string) (string, error) {
+	if !c.Encrypts(field) || plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	sealedKey, err := seal(c.keys[c.activeKeyID], dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + c.activeKeyID + ":" + encoding.EncodeToString(sealedKey) + ":" + encoding.EncodeToString(sealedValue), nil
+}
+
+// Open returns the plaintext of a stored value. Values that were never encrypted are
+// returned unchanged.
+func (c *Cipher) Open(stored string) (string, error) {
+	if !strings.HasPrefix(stored, prefix) {
+		return stored, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(stored, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformedValue
+	}
+	if c == nil {
+		return "", fmt.Errorf("%w: %q (no keys configured)", ErrUnknownKey, parts[0])
+	}
+	key, ok := c.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, parts[0])
+	}
+
+	sealedKey, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+	sealedValue, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedValue
+	}
+	dataKey, err := open(key, sealedKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealedValue)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// IsCurrent reports whether a stored value already has the form Seal would give it now:
+// encrypted with the active key, or plaintext for a field that is not encrypted.
+func (c *Cipher) IsCurrent(field, stored string) bool {
+	if stored == "" {
+		return true
+	}
+	if !c.Encrypts(field) {
+		return !strings.HasPrefix(stored, prefix)
+	}
+
+	return strings.HasPrefix(stored, prefix+c.activeKeyID+":")
+}
+
+// BlindIndex returns the HMAC-SHA256 of value as hex, or "" when no cipher is configured or
+// the value is empty.
+func (c *Cipher) BlindIndex(value string) string {
+	value = strings.TrimSpace(value)
+	if c == nil || value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts plaintext with AES-256-GCM, prepending the random nonce.
+func seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformedValue
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrMalformedValue
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
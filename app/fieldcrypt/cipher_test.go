@@ -0,0 +1,189 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func newTestCipher(t *testing.T, keys map[string][]byte, active string, fields ...string) *Cipher {
+	t.Helper()
+
+	c, err := New(keys, active, testKey(9), fields)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	return c
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	c := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldNIN)
+
+	sealed, err := c.Seal(FieldNIN, "1960101223344")
+	if err != nil {
+		t.Fatalf("Seal() returned error: %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:k1:") || strings.Contains(sealed, "1960101223344") {
+		t.Fatalf("unexpected sealed value %q", sealed)
+	}
+
+	again, _ := c.Seal(FieldNIN, "1960101223344")
+	if again == sealed {
+		t.Fatal("expected a fresh data key and nonce per value")
+	}
+
+	opened, err := c.Open(sealed)
+	if err != nil || opened != "1960101223344" {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+}
+
+func TestSealLeavesOtherFieldsAndEmptyValues(t *testing.T) {
+	c := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldNIN)
+
+	if v, _ := c.Seal(FieldPhone, "+40700000000"); v != "+40700000000" {
+		t.Fatalf("expected phone in plaintext, got %q", v)
+	}
+	if v, _ := c.Seal(FieldNIN, ""); v != "" {
+		t.Fatalf("expected empty value to stay empty, got %q", v)
+	}
+
+	var disabled *Cipher
+	if v, _ := disabled.Seal(FieldNIN, "123"); v != "123" {
+		t.Fatalf("expected nil cipher to pass through, got %q", v)
+	}
+	if v, _ := disabled.Open("123"); v != "123" {
+		t.Fatalf("expected nil cipher to open plaintext, got %q", v)
+	}
+}
+
+func TestOpenWithRotatedKeyring(t *testing.T) {
+	old := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldNIN)
+	sealed, _ := old.Seal(FieldNIN, "secret")
+
+	rotated := newTestCipher(t, map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2", FieldNIN)
+	if rotated.IsCurrent(FieldNIN, sealed) {
+		t.Fatal("expected value sealed with k1 to need rotation")
+	}
+	if opened, err := rotated.Open(sealed); err != nil || opened != "secret" {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+
+	resealed, _ := rotated.Seal(FieldNIN, "secret")
+	if !rotated.IsCurrent(FieldNIN, resealed) {
+		t.Fatalf("expected %q to be current", resealed)
+	}
+
+	retired := newTestCipher(t, map[string][]byte{"k2": testKey(2)}, "k2", FieldNIN)
+	if _, err := retired.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestOpenRejectsTamperedValue(t *testing.T) {
+	c := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldNIN)
+	sealed, _ := c.Seal(FieldNIN, "secret")
+
+	i := len(sealed) - 10
+	flipped := byte('A')
+	if sealed[i] == 'A' {
+		flipped = 'B'
+	}
+	tampered := sealed[:i] + string(flipped) + sealed[i+1:]
+	if _, err := c.Open(tampered); !errors.Is(err, ErrMalformedValue) {
+		t.Fatalf("expected ErrMalformedValue, got %v", err)
+	}
+	if _, err := c.Open("enc:v1:k1:only-two"); !errors.Is(err, ErrMalformedValue) {
+		t.Fatalf("expected ErrMalformedValue, got %v", err)
+	}
+}
+
+func TestIsCurrentForPlaintextFields(t *testing.T) {
+	c := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldNIN)
+
+	if c.IsCurrent(FieldNIN, "1960101223344") {
+		t.Fatal("expected plaintext NIN to need encryption")
+	}
+	if !c.IsCurrent(FieldPhone, "+40700000000") {
+		t.Fatal("expected plaintext phone to be current")
+	}
+
+	sealed, _ := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldPhone).Seal(FieldPhone, "+40700000000")
+	if c.IsCurrent(FieldPhone, sealed) {
+		t.Fatal("expected encrypted phone to need decryption once phone is no longer encrypted")
+	}
+}
+
+func TestBlindIndexIsDeterministic(t *testing.T) {
+	c := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1", FieldNIN)
+	other, _ := New(map[string][]byte{"k1": testKey(1)}, "k1", testKey(8), []string{FieldNIN})
+
+	index := c.BlindIndex("1960101223344")
+	if len(index) != 64 || index != c.BlindIndex(" 1960101223344 ") {
+		t.Fatalf("unexpected blind index %q", index)
+	}
+	if index == other.BlindIndex("1960101223344") {
+		t.Fatal("expected blind index to depend on the index key")
+	}
+	if c.BlindIndex("") != "" {
+		t.Fatal("expected empty value to have no blind index")
+	}
+
+	var disabled *Cipher
+	if disabled.BlindIndex("1960101223344") != "" {
+		t.Fatal("expected nil cipher to have no blind index")
+	}
+}
+
+func TestNewValidatesConfiguration(t *testing.T) {
+	keys := map[string][]byte{"k1": testKey(1)}
+
+	if _, err := New(keys, "k2", testKey(9), nil); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if _, err := New(map[string][]byte{"k1": []byte("short")}, "k1", testKey(9), nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+	if _, err := New(keys, "k1", nil, nil); !errors.Is(err, ErrMissingIndexKey) {
+		t.Fatalf("expected ErrMissingIndexKey, got %v", err)
+	}
+	if _, err := New(keys, "k1", testKey(9), []string{"email"}); !errors.Is(err, ErrUnsupportedField) {
+		t.Fatalf("expected ErrUnsupportedField, got %v", err)
+	}
+}
+
+func TestParseKeysAndLoadKeyDir(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testKey(1))
+
+	keys, err := ParseKeys("k1:" + encoded + ", k2:" + base64.StdEncoding.EncodeToString(testKey(2)))
+	if err != nil || len(keys) != 2 || !bytes.Equal(keys["k1"], testKey(1)) {
+		t.Fatalf("ParseKeys() = %v, %v", keys, err)
+	}
+	if _, err = ParseKeys("k1"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+	if _, err = ParseKeys("k1:c2hvcnQ="); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "2026-01"), []byte(encoded+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, ".keep"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err = LoadKeyDir(dir)
+	if err != nil || len(keys) != 1 || !bytes.Equal(keys["2026-01"], testKey(1)) {
+		t.Fatalf("LoadKeyDir() = %v, %v", keys, err)
+	}
+}
@@ -0,0 +1,207 @@
+// Package fieldpolicy decides, per calling service, whether a sensitive response field is
+// returned in full, masked or not at all.
+package fieldpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+)
+
+type Access string
+
+const (
+	Full   Access = "full"
+	Masked Access = "masked"
+	Hidden Access = "hidden"
+)
+
+// Fields covered by policies.
+const (
+	ContactNIN        = "contact.nin"
+	ContactDOB        = "contact.dob"
+	ContactPhone      = "contact.phone"
+	CompanyFiscalCode = "company.fiscal_code"
+)
+
+// allFields matches every field in a rule.
+const allFields = "*"
+
+// visibleSuffix is how many trailing characters a masked value keeps.
+const visibleSuffix = 4
+
+var ErrInvalidPolicy = errors.New("invalid field policy")
+
+var knownFields = map[string]bool{
+	ContactNIN:        true,
+	ContactDOB:        true,
+	ContactPhone:      true,
+	CompanyFiscalCode: true,
+	allFields:         true,
+}
+
+// Rules is the access one caller has to each field. The zero value grants full access.
+type Rules struct {
+	fallback Access
+	fields   map[string]Access
+}
+
+// Access returns the caller's access to field.
+func (r Rules) Access(field string) Access {
+	if access, ok := r.fields[field]; ok {
+		return access
+	}
+	if r.fallback == "" {
+		return Full
+	}
+	return r.fallback
+}
+
+// Apply returns value as the caller may see it: unchanged, masked, or empty when hidden.
+func (r Rules) Apply(field, value string) string {
+	switch r.Access(field) {
+	case Hidden:
+		return ""
+	case Masked:
+		return Mask(value)
+	default:
+		return value
+	}
+}
+
+// Mask replaces every character but the last four with '*'. Values of four characters or
+// fewer are masked entirely.
+func Mask(value string) string {
+	runes := []rune(value)
+	keep := 0
+	if len(runes) > visibleSuffix {
+		keep = visibleSuffix
+	}
+
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+// Policy holds the default rules and the rules configured for individual services.
+type Policy struct {
+	defaults Rules
+	services map[string]Rules
+}
+
+// Parse builds a policy from two specs. defaultSpec is a comma-separated list of
+// field:access rules, where the field "*" stands for every field; servicesSpec is a
+// semicolon-separated list of service=rules entries. A service's rules override the default
+// ones field by field, and a "*" rule in them replaces the defaults altogether:
+//
+//	defaultSpec:  *:masked
+//	servicesSpec: billing-service=contact.nin:full;crm=*:full,contact.nin:hidden
+func Parse(defaultSpec, servicesSpec string) (*Policy, error) {
+	defaults, err := parseRules(defaultSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{defaults: defaults, services: make(map[string]Rules)}
+	for _, entry := range strings.Split(servicesSpec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		service, spec, ok := strings.Cut(entry, "=")
+		service = strings.TrimSpace(service)
+		if !ok || service == "" {
+			return nil, fmt.Errorf("%w: expected service=rules, got %q", ErrInvalidPolicy, entry)
+		}
+		rules, err := parseRules(spec)
+		if err != nil {
+			return nil, err
+		}
+		policy.services[service] = merge(defaults, rules)
+	}
+
+	return policy, nil
+}
+
+// For returns the rules of a calling service. A nil policy grants full access.
+func (p *Policy) For(service string) Rules {
+	if p == nil {
+		return Rules{}
+	}
+	if rules, ok := p.services[service]; ok {
+		return rules
+	}
+	return p.defaults
+}
+
+func parseRules(spec string) (Rules, error) {
+	rules := Rules{fields: make(map[string]Access)}
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		field, rawAccess, ok := strings.Cut(rule, ":")
+		field = strings.TrimSpace(field)
+		if !ok || !knownFields[field] {
+			return Rules{}, fmt.Errorf("%w: unknown field in rule %q", ErrInvalidPolicy, rule)
+		}
+		access := Access(strings.TrimSpace(rawAccess))
+		if access != Full && access != Masked && access != Hidden {
+			return Rules{}, fmt.Errorf("%w: unknown access in rule %q", ErrInvalidPolicy, rule)
+		}
+
+		if field == allFields {
+			rules.fallback = access
+		} else {
+			rules.fields[field] = access
+		}
+	}
+
+	return rules, nil
+}
+
+func merge(defaults, overrides Rules) Rules {
+	if overrides.fallback != "" {
+		return overrides
+	}
+
+	merged := Rules{fallback: defaults.fallback, fields: make(map[string]Access, len(defaults.fields)+len(overrides.fields))}
+	for field, access := range defaults.fields {
+		merged.fields[field] = access
+	}
+	for field, access := range overrides.fields {
+		merged.fields[field] = access
+	}
+	return merged
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, rules Rules) context.Context {
+	return context.WithValue(ctx, contextKey{}, rules)
+}
+
+// FromContext returns the rules stored in ctx, or full access when there are none.
+func FromContext(ctx context.Context) Rules {
+	rules, _ := ctx.Value(contextKey{}).(Rules)
+	return rules
+}
+
+// EchoMiddleware stores the rules of the calling service in the request context. It must run
+// after caller.EchoMiddleware.
+func EchoMiddleware(policy *Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			rules := policy.For(caller.FromContext(req.Context()).Service)
+			c.SetRequest(req.WithContext(NewContext(req.Context(), rules)))
+
+			return next(c)
+		}
+	}
+}
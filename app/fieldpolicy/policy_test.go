@@ -0,0 +1,115 @@
+package fieldpolicy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+)
+
+func TestMask(t *testing.T) {
+	cases := map[string]string{
+		"1900517223344": "*********3344",
+		"+40700000000":  "********0000",
+		"1234":          "****",
+		"":              "",
+		"ăâîșț12345":    "******2345",
+	}
+	for value, want := range cases {
+		if got := Mask(value); got != want {
+			t.Fatalf("Mask(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestParseMergesServiceRulesOverDefaults(t *testing.T) {
+	policy, err := Parse(
+		"*:masked, company.fiscal_code:full",
+		"billing-service=contact.nin:full,contact.dob:hidden; crm=*:full,contact.nin:hidden",
+	)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	billing := policy.For("billing-service")
+	if billing.Access(ContactNIN) != Full || billing.Access(ContactDOB) != Hidden ||
+		billing.Access(ContactPhone) != Masked || billing.Access(CompanyFiscalCode) != Full {
+		t.Fatalf("unexpected billing rules: %+v", billing)
+	}
+
+	crm := policy.For("crm")
+	if crm.Access(ContactNIN) != Hidden || crm.Access(ContactPhone) != Full || crm.Access(CompanyFiscalCode) != Full {
+		t.Fatalf("unexpected crm rules: %+v", crm)
+	}
+
+	other := policy.For("unknown")
+	if other.Access(ContactNIN) != Masked || other.Access(CompanyFiscalCode) != Full {
+		t.Fatalf("unexpected default rules: %+v", other)
+	}
+}
+
+func TestApply(t *testing.T) {
+	policy, _ := Parse("contact.nin:masked,contact.dob:hidden", "")
+	rules := policy.For("any")
+
+	if got := rules.Apply(ContactNIN, "1900517223344"); got != "*********3344" {
+		t.Fatalf("expected masked nin, got %q", got)
+	}
+	if got := rules.Apply(ContactDOB, "1990-05-17"); got != "" {
+		t.Fatalf("expected hidden dob, got %q", got)
+	}
+	if got := rules.Apply(ContactPhone, "+40700000000"); got != "+40700000000" {
+		t.Fatalf("expected full phone, got %q", got)
+	}
+}
+
+func TestParseRejectsInvalidSpecs(t *testing.T) {
+	cases := [][2]string{
+		{"contact.email:masked", ""},
+		{"contact.nin:partial", ""},
+		{"contact.nin", ""},
+		{"", "billing-service"},
+		{"", "=contact.nin:full"},
+		{"", "billing-service=contact.nin:secret"},
+	}
+	for _, tc := range cases {
+		if _, err := Parse(tc[0], tc[1]); !errors.Is(err, ErrInvalidPolicy) {
+			t.Fatalf("Parse(%q, %q): expected ErrInvalidPolicy, got %v", tc[0], tc[1], err)
+		}
+	}
+}
+
+func TestNilPolicyAndEmptyContextGrantFullAccess(t *testing.T) {
+	var policy *Policy
+	if policy.For("billing-service").Access(ContactNIN) != Full {
+		t.Fatal("expected a nil policy to grant full access")
+	}
+	if FromContext(context.Background()).Access(CompanyFiscalCode) != Full {
+		t.Fatal("expected a context without rules to grant full access")
+	}
+}
+
+func TestEchoMiddlewareStoresCallerRules(t *testing.T) {
+	policy, _ := Parse("*:full", "billing-service=contact.nin:masked")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(caller.NewContext(req.Context(), caller.Identity{Service: "billing-service"}))
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	var got Rules
+	handler := EchoMiddleware(policy)(func(c echo.Context) error {
+		got = FromContext(c.Request().Context())
+		return nil
+	})
+	if err := handler(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.Access(ContactNIN) != Masked || got.Access(ContactPhone) != Full {
+		t.Fatalf("unexpected rules: %+v", got)
+	}
+}
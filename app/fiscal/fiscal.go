@@ -0,0 +1,97 @@
+// Package fiscal checks company fiscal codes and trade register numbers against the rules of
+// the country that issued them.
+package fiscal
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrInvalidFiscalCode     = errors.New("invalid fiscal code")
+	ErrInvalidRegistrationNo = errors.New("invalid registration number")
+)
+
+// Validator checks the identifiers issued by one country.
+type Validator interface {
+	// FiscalCode checks a fiscal code written without its VAT prefix.
+	FiscalCode(code string) error
+	// RegistrationNo checks a trade register number.
+	RegistrationNo(no string) error
+}
+
+// Result describes a checked fiscal code.
+type Result struct {
+	// FiscalCode is the code in upper case without spaces.
+	FiscalCode string
+	// Valid reports whether a validator accepted the code; codes no validator applies to are
+	// not valid, but not rejected either.
+	Valid bool
+	// VATPrefix is the EU VAT prefix the code was written with, e.g. RO for RO18547290.
+	VATPrefix string
+}
+
+// Validators picks the validator of a company by the VAT prefix of its fiscal code, or by a
+// default country for codes written without one.
+type Validators struct {
+	byPrefix       map[string]Validator
+	defaultCountry string
+}
+
+// NewValidators returns the validators of every EU VAT prefix, with the Romanian rules for RO.
+// defaultCountry is the VAT prefix of the country of codes without one; when empty, such
+// codes are not checked.
+func NewValidators(defaultCountry string) *Validators {
+	v := &Validators{byPrefix: make(map[string]Validator, len(vatFormats)+1), defaultCountry: strings.ToUpper(defaultCountry)}
+	for prefix, format := range vatFormats {
+		v.byPrefix[prefix] = format
+	}
+	v.byPrefix["RO"] = Romania{}
+
+	return v
+}
+
+// Register sets the validator of prefix, replacing the built-in one.
+func (v *Validators) Register(prefix string, validator Validator) {
+	v.byPrefix[strings.ToUpper(prefix)] = validator
+}
+
+// Has reports whether prefix has a validator.
+func (v *Validators) Has(prefix string) bool {
+	_, ok := v.byPrefix[strings.ToUpper(prefix)]
+	return ok
+}
+
+// Check checks fiscalCode, and registrationNo against the same country. Codes starting with
+// two letters that are not a known VAT prefix are accepted unchecked.
+func (v *Validators) Check(fiscalCode, registrationNo string) (Result, error) {
+	result := Result{FiscalCode: strings.ToUpper(strings.Join(strings.Fields(fiscalCode), ""))}
+
+	code, prefix := result.FiscalCode, v.defaultCountry
+	if hasLetterPrefix(code) {
+		if _, ok := v.byPrefix[code[:2]]; !ok {
+			return result, nil
+		}
+		code, prefix = code[2:], code[:2]
+		result.VATPrefix = prefix
+	}
+	validator, ok := v.byPrefix[prefix]
+	if !ok {
+		return result, nil
+	}
+
+	if err := validator.FiscalCode(code); err != nil {
+		return result, err
+	}
+	if err := validator.RegistrationNo(strings.ToUpper(strings.TrimSpace(registrationNo))); err != nil {
+		return result, err
+	}
+	result.Valid = true
+
+	return result, nil
+}
+
+func hasLetterPrefix(code string) bool {
+	return len(code) > 2 && unicode.IsLetter(rune(code[0])) && unicode.IsLetter(rune(code[1]))
+}
@@ -0,0 +1,91 @@
+package fiscal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRomaniaFiscalCode(t *testing.T) {
+	for _, code := range []string{"18547290", "14399840", "1590082"} {
+		if err := (Romania{}).FiscalCode(code); err != nil {
+			t.Fatalf("expected CUI %s to be valid, got %v", code, err)
+		}
+	}
+	for _, code := range []string{"18547291", "1", "12345678901", "1854729O"} {
+		if err := (Romania{}).FiscalCode(code); !errors.Is(err, ErrInvalidFiscalCode) {
+			t.Fatalf("expected CUI %s to be invalid, got %v", code, err)
+		}
+	}
+}
+
+func TestRomaniaRegistrationNo(t *testing.T) {
+	for _, no := range []string{"J40/123/2020", "J05/1/1991", "F12/4567/2015", "C52/12/2010", "J2024000123040"} {
+		if err := (Romania{}).RegistrationNo(no); err != nil {
+			t.Fatalf("expected %s to be valid, got %v", no, err)
+		}
+	}
+	for _, no := range []string{"J41/123/2020", "J00/123/2020", "J40/123/1989", "J40/123/3020", "X40/123/2020", "J40-123-2020", "J2024000123041"} {
+		if err := (Romania{}).RegistrationNo(no); !errors.Is(err, ErrInvalidRegistrationNo) {
+			t.Fatalf("expected %s to be invalid, got %v", no, err)
+		}
+	}
+}
+
+func TestValidatorsCheck(t *testing.T) {
+	v := NewValidators("RO")
+	cases := []struct {
+		fiscalCode, registrationNo string
+		want                       Result
+	}{
+		{"RO18547290", "J40/123/2020", Result{FiscalCode: "RO18547290", Valid: true, VATPrefix: "RO"}},
+		{"ro 185 472 90", "J40/123/2020", Result{FiscalCode: "RO18547290", Valid: true, VATPrefix: "RO"}},
+		{"18547290", "J40/123/2020", Result{FiscalCode: "18547290", Valid: true}},
+		{"DE123456789", "HRB 1234", Result{FiscalCode: "DE123456789", Valid: true, VATPrefix: "DE"}},
+		{"NL123456789B01", "", Result{FiscalCode: "NL123456789B01", Valid: true, VATPrefix: "NL"}},
+		// Not an EU VAT prefix, so not checked.
+		{"GB123456789", "01234567", Result{FiscalCode: "GB123456789"}},
+	}
+	for _, tc := range cases {
+		got, err := v.Check(tc.fiscalCode, tc.registrationNo)
+		if err != nil {
+			t.Fatalf("Check(%q, %q) returned error: %v", tc.fiscalCode, tc.registrationNo, err)
+		}
+		if got != tc.want {
+			t.Fatalf("Check(%q, %q) = %+v, want %+v", tc.fiscalCode, tc.registrationNo, got, tc.want)
+		}
+	}
+
+	if _, err := v.Check("RO18547291", "J40/123/2020"); !errors.Is(err, ErrInvalidFiscalCode) {
+		t.Fatalf("expected bad control digit to be rejected, got %v", err)
+	}
+	if _, err := v.Check("18547290", "40/123/2020"); !errors.Is(err, ErrInvalidRegistrationNo) {
+		t.Fatalf("expected bad registration number to be rejected, got %v", err)
+	}
+	if _, err := v.Check("DE12345678", ""); !errors.Is(err, ErrInvalidFiscalCode) {
+		t.Fatalf("expected short German VAT number to be rejected, got %v", err)
+	}
+}
+
+func TestValidatorsWithoutDefaultCountry(t *testing.T) {
+	got, err := NewValidators("").Check("12345", "anything")
+	if err != nil {
+		t.Fatalf("expected code without prefix to be accepted, got %v", err)
+	}
+	if got.Valid || got.VATPrefix != "" {
+		t.Fatalf("expected an unchecked result, got %+v", got)
+	}
+}
+
+type stubValidator struct{ err error }
+
+func (s stubValidator) FiscalCode(string) error     { return s.err }
+func (s stubValidator) RegistrationNo(string) error { return nil }
+
+func TestValidatorsRegister(t *testing.T) {
+	v := NewValidators("")
+	v.Register("de", stubValidator{err: ErrInvalidFiscalCode})
+
+	if _, err := v.Check("DE123456789", ""); !errors.Is(err, ErrInvalidFiscalCode) {
+		t.Fatalf("expected the registered validator to be used, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package fiscal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// cuiKey weighs the digits of a CUI, right-aligned, to compute its control digit.
+const cuiKey = "753217532"
+
+var (
+	// J40/123/2020: register (J companies, F sole traders, C cooperatives), county, order number, year.
+	registrationNoSlashed = regexp.MustCompile(`^([JFC])(\d{1,2})/(\d{1,7})/(\d{4})$`)
+	// J2024000123040: the single-string form used since 2024, with year, order number and county.
+	registrationNoCompact = regexp.MustCompile(`^(J)(\d{4})(\d{6})(\d{3})$`)
+)
+
+// Romania checks CUI/CIF control digits and Trade Register (ONRC) numbers.
+type Romania struct{}
+
+func (Romania) FiscalCode(code string) error {
+	if len(code) < 2 || len(code) > 10 {
+		return fmt.Errorf("%w: a CUI has 2 to 10 digits", ErrInvalidFiscalCode)
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("%w: a CUI has only digits", ErrInvalidFiscalCode)
+		}
+	}
+
+	body := fmt.Sprintf("%09s", code[:len(code)-1])
+	sum := 0
+	for i := range cuiKey {
+		sum += int(body[i]-'0') * int(cuiKey[i]-'0')
+	}
+	control := sum * 10 % 11
+	if control == 10 {
+		control = 0
+	}
+	if int(code[len(code)-1]-'0') != control {
+		return fmt.Errorf("%w: control digit does not match", ErrInvalidFiscalCode)
+	}
+
+	return nil
+}
+
+func (Romania) RegistrationNo(no string) error {
+	var county, year string
+	if m := registrationNoSlashed.FindStringSubmatch(no); m != nil {
+		county, year = m[2], m[4]
+	} else if m = registrationNoCompact.FindStringSubmatch(no); m != nil {
+		county, year = m[4], m[2]
+	} else {
+		return fmt.Errorf("%w: expected a number such as J40/123/2020", ErrInvalidRegistrationNo)
+	}
+
+	// Counties are numbered alphabetically from 1 to 40 (Bucharest), with 51 and 52 added later.
+	if n, _ := strconv.Atoi(county); n < 1 || (n > 40 && n != 51 && n != 52) {
+		return fmt.Errorf("%w: unknown county code %s", ErrInvalidRegistrationNo, county)
+	}
+	if n, _ := strconv.Atoi(year); n < 1990 || n > time.Now().Year() {
+		return fmt.Errorf("%w: year %s is out of range", ErrInvalidRegistrationNo, year)
+	}
+
+	return nil
+}
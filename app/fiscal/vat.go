@@ -0,0 +1,60 @@
+package fiscal
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// vatFormat checks the format of the VAT numbers of a country whose identifiers are not
+// checked further. It does not check trade register numbers.
+type vatFormat struct {
+	pattern *regexp.Regexp
+}
+
+func (f vatFormat) FiscalCode(code string) error {
+	if !f.pattern.MatchString(code) {
+		return fmt.Errorf("%w: does not match the VAT number format of its country", ErrInvalidFiscalCode)
+	}
+
+	return nil
+}
+
+func (vatFormat) RegistrationNo(string) error {
+	return nil
+}
+
+func newVATFormat(pattern string) vatFormat {
+	return vatFormat{pattern: regexp.MustCompile(`^(?:` + pattern + `)$`)}
+}
+
+// vatFormats are the VIES formats of EU VAT numbers, by VAT prefix: EL is Greece and XI
+// Northern Ireland.
+var vatFormats = map[string]vatFormat{
+	"AT": newVATFormat(`U\d{8}`),
+	"BE": newVATFormat(`[01]\d{9}`),
+	"BG": newVATFormat(`\d{9,10}`),
+	"CY": newVATFormat(`\d{8}[A-Z]`),
+	"CZ": newVATFormat(`\d{8,10}`),
+	"DE": newVATFormat(`\d{9}`),
+	"DK": newVATFormat(`\d{8}`),
+	"EE": newVATFormat(`\d{9}`),
+	"EL": newVATFormat(`\d{9}`),
+	"ES": newVATFormat(`[A-Z0-9]\d{7}[A-Z0-9]`),
+	"FI": newVATFormat(`\d{8}`),
+	"FR": newVATFormat(`[A-HJ-NP-Z0-9]{2}\d{9}`),
+	"HR": newVATFormat(`\d{11}`),
+	"HU": newVATFormat(`\d{8}`),
+	"IE": newVATFormat(`\d{7}[A-W][A-I]?|\d[A-Z+*]\d{5}[A-W]`),
+	"IT": newVATFormat(`\d{11}`),
+	"LT": newVATFormat(`\d{9}|\d{12}`),
+	"LU": newVATFormat(`\d{8}`),
+	"LV": newVATFormat(`\d{11}`),
+	"MT": newVATFormat(`\d{8}`),
+	"NL": newVATFormat(`\d{9}B\d{2}`),
+	"PL": newVATFormat(`\d{10}`),
+	"PT": newVATFormat(`\d{9}`),
+	"SE": newVATFormat(`\d{10}01`),
+	"SI": newVATFormat(`\d{8}`),
+	"SK": newVATFormat(`\d{10}`),
+	"XI": newVATFormat(`\d{9}|\d{12}|GD\d{3}|HA\d{3}`),
+}
@@ -0,0 +1,86 @@
+// Package geo holds the coordinates of addresses, the areas they are searched in and the
+// geocoders that look coordinates up.
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// EarthRadiusMeters is the mean Earth radius MySQL's ST_Distance_Sphere uses by default.
+const EarthRadiusMeters = 6370986
+
+var (
+	ErrInvalidPoint = errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	// ErrNoMatch is returned by a Geocoder that knows no coordinates for an address.
+	ErrNoMatch = errors.New("no coordinates found for address")
+)
+
+// Point is a WGS 84 position in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Valid reports whether the point lies within the latitude and longitude ranges.
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// Box is an area bounded by two parallels and two meridians. It does not cross the
+// antimeridian, so MinLongitude is never greater than MaxLongitude.
+type Box struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// Valid reports whether both corners are valid points and the minimums do not exceed the
+// maximums.
+func (b Box) Valid() bool {
+	return Point{b.MinLatitude, b.MinLongitude}.Valid() && Point{b.MaxLatitude, b.MaxLongitude}.Valid() &&
+		b.MinLatitude <= b.MaxLatitude && b.MinLongitude <= b.MaxLongitude
+}
+
+// Around returns a box holding every point within radiusMeters of center. Near a pole, or
+// when the circle crosses the antimeridian, the box spans every longitude.
+func Around(center Point, radiusMeters float64) Box {
+	deltaLat := radiusMeters / EarthRadiusMeters * 180 / math.Pi
+	box := Box{
+		MinLatitude:  center.Latitude - deltaLat,
+		MinLongitude: -180,
+		MaxLatitude:  center.Latitude + deltaLat,
+		MaxLongitude: 180,
+	}
+	if box.MinLatitude <= -90 || box.MaxLatitude >= 90 {
+		box.MinLatitude, box.MaxLatitude = math.Max(box.MinLatitude, -90), math.Min(box.MaxLatitude, 90)
+		return box
+	}
+
+	// The widest point of the circle is on the parallel furthest from the equator.
+	widest := math.Max(math.Abs(box.MinLatitude), math.Abs(box.MaxLatitude))
+	deltaLng := deltaLat / math.Cos(widest*math.Pi/180)
+	if center.Longitude-deltaLng >= -180 && center.Longitude+deltaLng <= 180 {
+		box.MinLongitude, box.MaxLongitude = center.Longitude-deltaLng, center.Longitude+deltaLng
+	}
+
+	return box
+}
+
+// Address is the part of a postal address a Geocoder looks at.
+type Address struct {
+	StreetName string
+	StreetNo   string
+	City       string
+	County     string
+	Country    string
+	PostalCode string
+}
+
+// Geocoder looks up the coordinates of postal addresses.
+type Geocoder interface {
+	// Geocode returns the coordinates of address, or ErrNoMatch when it has none.
+	Geocode(ctx context.Context, address Address) (Point, error)
+}
@@ -0,0 +1,74 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAround(t *testing.T) {
+	cluj := Point{Latitude: 46.7712, Longitude: 23.6236}
+	box := Around(cluj, 10000)
+	if math.Abs(box.MaxLatitude-cluj.Latitude-0.0899) > 0.001 || math.Abs(box.MinLatitude-cluj.Latitude+0.0899) > 0.001 {
+		t.Fatalf("unexpected latitude bounds: %+v", box)
+	}
+	// A degree of longitude is shorter than one of latitude away from the equator.
+	if width := box.MaxLongitude - box.MinLongitude; width <= box.MaxLatitude-box.MinLatitude || width > 0.3 {
+		t.Fatalf("unexpected longitude bounds: %+v", box)
+	}
+	if !box.Valid() {
+		t.Fatalf("expected a valid box, got %+v", box)
+	}
+
+	if box = Around(Point{Latitude: 89.99, Longitude: 10}, 5000); box.MaxLatitude != 90 || box.MinLongitude != -180 || box.MaxLongitude != 180 {
+		t.Fatalf("expected the box around a pole to span every longitude, got %+v", box)
+	}
+	if box = Around(Point{Latitude: 0, Longitude: 179.99}, 5000); box.MinLongitude != -180 || box.MaxLongitude != 180 {
+		t.Fatalf("expected the box across the antimeridian to span every longitude, got %+v", box)
+	}
+}
+
+func TestBoxValid(t *testing.T) {
+	if !(Box{MinLatitude: 46, MinLongitude: 23, MaxLatitude: 47, MaxLongitude: 24}).Valid() {
+		t.Fatal("expected a valid box")
+	}
+	for _, box := range []Box{
+		{MinLatitude: 47, MinLongitude: 23, MaxLatitude: 46, MaxLongitude: 24},
+		{MinLatitude: 46, MinLongitude: 179, MaxLatitude: 47, MaxLongitude: -179},
+		{MinLatitude: -91, MinLongitude: 23, MaxLatitude: 47, MaxLongitude: 24},
+	} {
+		if box.Valid() {
+			t.Fatalf("expected %+v to be invalid", box)
+		}
+	}
+}
+
+func TestStaticGeocode(t *testing.T) {
+	exact := Point{Latitude: 46.7694, Longitude: 23.5899}
+	street := Point{Latitude: 46.77, Longitude: 23.59}
+	city := Point{Latitude: 46.7712, Longitude: 23.6236}
+	geocoder := NewStatic().
+		Add(Address{Country: "RO", City: "Cluj-Napoca", StreetName: "Strada Memorandumului", StreetNo: "28"}, exact).
+		Add(Address{Country: "RO", City: "Cluj-Napoca", StreetName: "Strada Memorandumului"}, street).
+		Add(Address{Country: "RO", City: "Cluj-Napoca"}, city)
+
+	cases := []struct {
+		address Address
+		want    Point
+	}{
+		{Address{Country: "RO", City: "cluj-napoca ", StreetName: "Strada  Memorandumului", StreetNo: "28", PostalCode: "400114"}, exact},
+		{Address{Country: "RO", City: "Cluj-Napoca", StreetName: "Strada Memorandumului", StreetNo: "3"}, street},
+		{Address{Country: "RO", City: "Cluj-Napoca", StreetName: "Strada Horea", StreetNo: "1"}, city},
+	}
+	for _, tc := range cases {
+		got, err := geocoder.Geocode(context.Background(), tc.address)
+		if err != nil || got != tc.want {
+			t.Fatalf("Geocode(%+v) = %+v, %v, want %+v", tc.address, got, err, tc.want)
+		}
+	}
+
+	if _, err := geocoder.Geocode(context.Background(), Address{Country: "RO", City: "Iasi"}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+}
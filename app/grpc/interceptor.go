@@ -4,10 +4,16 @@ import (
 	"context"
 	"fmt"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	authmiddleware "github.com/vibast-solutions/lib-go-auth/middleware"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -77,6 +83,68 @@ func RecoveryInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// CallerInterceptor stores the authenticated caller and the request id in the context. It must
+// be chained after RequestIDInterceptor and the internal auth interceptor.
+func CallerInterceptor(adminAccess string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		service, _ := authmiddleware.CallerServiceFromGRPCContext(ctx)
+		access, _ := authmiddleware.CallerAllowedAccessFromGRPCContext(ctx)
+
+		identity := caller.NewIdentity(service, access, adminAccess)
+		identity.RequestID = RequestIDFromContext(ctx)
+
+		return handler(caller.NewContext(ctx, identity), req)
+	}
+}
+
+// FieldPolicyInterceptor stores the field rules of the calling service in the context. It must
+// be chained after CallerInterceptor.
+func FieldPolicyInterceptor(policy *fieldpolicy.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rules := policy.For(caller.FromContext(ctx).Service)
+		return handler(fieldpolicy.NewContext(ctx, rules), req)
+	}
+}
+
+// DeprecationInterceptor adds deprecation and link headers to the responses of the v1 service,
+// naming profile.v2.ProfileService as its successor.
+func DeprecationInterceptor() grpc.UnaryServerInterceptor {
+	v1Prefix := "/" + types.ProfileService_ServiceDesc.ServiceName + "/"
+	headers := metadata.Pairs(
+		strings.ToLower(types.HeaderDeprecation), types.V1Deprecation(),
+		strings.ToLower(types.HeaderLink), types.SuccessorLink(typesv2.ProfileService_ServiceDesc.ServiceName),
+	)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, v1Prefix) {
+			_ = grpc.SetHeader(ctx, headers)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor runs a unary interceptor around a streaming call, so that streams get the
+// same request id, logging, recovery and caller context as unary calls. The interceptor sees
+// a nil request.
+func StreamInterceptor(interceptor grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		unaryInfo := &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}
+		_, err := interceptor(ss.Context(), nil, unaryInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return nil, handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+		})
+		return err
+	}
+}
+
+// contextServerStream is a server stream whose context was replaced by an interceptor.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
 func RequestIDFromContext(ctx context.Context) string {
 	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
 	return requestID
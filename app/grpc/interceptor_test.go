@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
 	grpcpkg "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -85,3 +87,106 @@ func TestLoggerWithContextIncludesRequestID(t *testing.T) {
 		t.Fatalf("expected request_id=req-42 in logger entry, got %#v", entry.Data)
 	}
 }
+
+func TestCallerInterceptorWithoutAuthenticatedCaller(t *testing.T) {
+	info := &grpcpkg.UnaryServerInfo{FullMethod: "/profile.ProfileService/RestoreProfile"}
+
+	_, err := CallerInterceptor("profile-service:admin")(context.Background(), nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		if identity := caller.FromContext(ctx); identity.Admin || identity.Service != "" {
+			t.Fatalf("expected anonymous caller, got %+v", identity)
+		}
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestCallerInterceptorCarriesRequestID(t *testing.T) {
+	info := &grpcpkg.UnaryServerInfo{FullMethod: "/profile.ProfileService/UpdateProfile"}
+	ctx := context.WithValue(context.Background(), requestIDContextKey{}, "grpc-7")
+
+	_, err := CallerInterceptor("profile-service:admin")(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		if got := caller.FromContext(ctx).RequestID; got != "grpc-7" {
+			t.Fatalf("expected request id grpc-7, got %q", got)
+		}
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestFieldPolicyInterceptorResolvesCallerRules(t *testing.T) {
+	info := &grpcpkg.UnaryServerInfo{FullMethod: "/profile.ProfileService/GetContact"}
+	policy, err := fieldpolicy.Parse("*:masked", "backoffice=*:full")
+	if err != nil {
+		t.Fatalf("fieldpolicy.Parse() returned error: %v", err)
+	}
+
+	for service, want := range map[string]fieldpolicy.Access{"backoffice": fieldpolicy.Full, "billing-service": fieldpolicy.Masked} {
+		ctx := caller.NewContext(context.Background(), caller.Identity{Service: service})
+		_, err = FieldPolicyInterceptor(policy)(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			if got := fieldpolicy.FromContext(ctx).Access(fieldpolicy.ContactNIN); got != want {
+				t.Fatalf("%s: expected %s access, got %s", service, want, got)
+			}
+			return "ok", nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+}
+
+type headerStreamStub struct {
+	grpcpkg.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStreamStub) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestDeprecationInterceptorMarksV1Calls(t *testing.T) {
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	for method, deprecated := range map[string]bool{
+		"/profile.ProfileService/GetProfile":    true,
+		"/profile.v2.ProfileService/GetProfile": false,
+	} {
+		stream := &headerStreamStub{}
+		ctx := grpcpkg.NewContextWithServerTransportStream(context.Background(), stream)
+		if _, err := DeprecationInterceptor()(ctx, nil, &grpcpkg.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		got := stream.header.Get("deprecation")
+		if deprecated != (len(got) == 1) {
+			t.Fatalf("%s: unexpected deprecation header %q", method, got)
+		}
+		if deprecated && !strings.Contains(stream.header.Get("link")[0], "profile.v2.ProfileService") {
+			t.Fatalf("%s: unexpected link header %q", method, stream.header.Get("link"))
+		}
+	}
+}
+
+type contextStreamStub struct {
+	grpcpkg.ServerStream
+}
+
+func (s *contextStreamStub) Context() context.Context { return context.Background() }
+
+func TestStreamInterceptorPassesContext(t *testing.T) {
+	var got string
+	handler := func(_ interface{}, stream grpcpkg.ServerStream) error {
+		got = RequestIDFromContext(stream.Context())
+		return nil
+	}
+	info := &grpcpkg.StreamServerInfo{FullMethod: "/profile.ProfileService/UploadAttachment", IsClientStream: true}
+	if err := StreamInterceptor(RequestIDInterceptor())(nil, &contextStreamStub{}, info, handler); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got == "" {
+		t.Fatal("expected the handler's stream to carry the request id")
+	}
+}
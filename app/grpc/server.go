@@ -3,32 +3,51 @@ package grpc
 import (
 	"context"
 	"errors"
+	"io"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 	"github.com/vibast-solutions/ms-go-profile/app/types"
 
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type ProfileServer struct {
 	types.UnimplementedProfileServiceServer
-	profileService *service.ProfileService
-	contactService *service.ContactService
-	addressService *service.AddressService
-	companyService *service.CompanyService
+	profileService    *service.ProfileService
+	contactService    *service.ContactService
+	addressService    *service.AddressService
+	companyService    *service.CompanyService
+	preferenceService *service.PreferenceService
+	attachmentService *service.AttachmentService
+	auditService      *service.AuditService
 }
 
-const grpcContactDOBLayout = "2006-01-02"
+const (
+	grpcContactDOBLayout = "2006-01-02"
+	errAdminOnly         = "admin access required"
+	// attachmentChunkSize is the largest chunk of content a download message carries.
+	attachmentChunkSize = 64 << 10
+)
 
-func NewProfileServer(profileService *service.ProfileService, contactService *service.ContactService, addressService *service.AddressService, companyService *service.CompanyService) *ProfileServer {
+func NewProfileServer(profileService *service.ProfileService, contactService *service.ContactService, addressService *service.AddressService, companyService *service.CompanyService, preferenceService *service.PreferenceService, attachmentService *service.AttachmentService, auditService *service.AuditService) *ProfileServer {
 	return &ProfileServer{
-		profileService: profileService,
-		contactService: contactService,
-		addressService: addressService,
-		companyService: companyService,
+		profileService:    profileService,
+		contactService:    contactService,
+		addressService:    addressService,
+		companyService:    companyService,
+		preferenceService: preferenceService,
+		attachmentService: attachmentService,
+		auditService:      auditService,
 	}
 }
 
@@ -37,28 +56,45 @@ func (s *ProfileServer) CreateProfile(ctx context.Context, pbReq *types.CreatePr
 	if err := pbReq.Validate(); err != nil {
 		l.WithField("user_id", pbReq.UserId).Debug("Create profile validation failed (grpc)")
 
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
 	l.WithField("user_id", pbReq.GetUserId()).Info("Create profile request received (grpc)")
-	profile, err := s.profileService.Create(ctx, pbReq)
+	profile, err := s.profileService.CreateWithChildren(ctx, pbReq, profileChildren(pbReq))
 	if err != nil {
 		if errors.Is(err, service.ErrProfileAlreadyExists) {
 			return nil, status.Error(codes.AlreadyExists, "profile already exists for this user")
 		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("user_id", pbReq.GetUserId()).Error("Create profile failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
 	l.WithField("profile_id", profile.ID).WithField("user_id", profile.UserID).Info("Profile created (grpc)")
 
-	return &types.ProfileResponse{
-		Id:        profile.ID,
-		UserId:    profile.UserID,
-		Email:     profile.Email,
-		CreatedAt: profile.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: profile.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return toProfileResponse(profile), nil
+}
+
+// profileChildren avoids handing typed-nil request pointers to the service.
+func profileChildren(pbReq *types.CreateProfileRequest) service.ProfileChildren {
+	children := service.ProfileChildren{}
+	if pbReq.GetContact() != nil {
+		children.Contact = pbReq.GetContact()
+	}
+	if pbReq.GetAddress() != nil {
+		children.Address = pbReq.GetAddress()
+	}
+
+	return children
 }
 
 func (s *ProfileServer) GetProfile(ctx context.Context, pbReq *types.GetProfileRequest) (*types.ProfileResponse, error) {
@@ -69,8 +105,12 @@ func (s *ProfileServer) GetProfile(ctx context.Context, pbReq *types.GetProfileR
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithField("profile_id", pbReq.GetId()).Info("Get profile request received (grpc)")
-	profile, err := s.profileService.GetByID(ctx, pbReq.GetId())
+	profile, err := s.profileService.GetByID(ctx, pbReq.GetId(), pbReq.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return nil, status.Error(codes.NotFound, "profile not found")
@@ -79,13 +119,7 @@ func (s *ProfileServer) GetProfile(ctx context.Context, pbReq *types.GetProfileR
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
-	return &types.ProfileResponse{
-		Id:        profile.ID,
-		UserId:    profile.UserID,
-		Email:     profile.Email,
-		CreatedAt: profile.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: profile.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return toProfileResponse(profile), nil
 }
 
 func (s *ProfileServer) GetProfileByUserID(ctx context.Context, pbReq *types.GetProfileByUserIDRequest) (*types.ProfileResponse, error) {
@@ -96,8 +130,12 @@ func (s *ProfileServer) GetProfileByUserID(ctx context.Context, pbReq *types.Get
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithField("user_id", pbReq.GetUserId()).Info("Get profile by user ID request received (grpc)")
-	profile, err := s.profileService.GetByUserID(ctx, pbReq.GetUserId())
+	profile, err := s.profileService.GetByUserID(ctx, pbReq.GetUserId(), pbReq.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return nil, status.Error(codes.NotFound, "profile not found")
@@ -106,13 +144,32 @@ func (s *ProfileServer) GetProfileByUserID(ctx context.Context, pbReq *types.Get
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
-	return &types.ProfileResponse{
-		Id:        profile.ID,
-		UserId:    profile.UserID,
-		Email:     profile.Email,
-		CreatedAt: profile.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: profile.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return toProfileResponse(profile), nil
+}
+
+func (s *ProfileServer) GetProfileByEmail(ctx context.Context, pbReq *types.GetProfileByEmailRequest) (*types.ProfileResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Get profile by email validation failed (grpc)")
+
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.Info("Get profile by email request received (grpc)")
+	profile, err := s.profileService.GetByEmail(ctx, pbReq.GetEmail(), pbReq.GetIncludeDeleted())
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		l.WithError(err).Error("Get profile by email failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return toProfileResponse(profile), nil
 }
 
 func (s *ProfileServer) UpdateProfile(ctx context.Context, pbReq *types.UpdateProfileRequest) (*types.ProfileResponse, error) {
@@ -129,18 +186,51 @@ func (s *ProfileServer) UpdateProfile(ctx context.Context, pbReq *types.UpdatePr
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return nil, status.Error(codes.NotFound, "profile not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
 		l.WithError(err).WithField("profile_id", pbReq.GetId()).Error("Update profile failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
 	l.WithField("profile_id", pbReq.GetId()).Info("Profile updated (grpc)")
-	return &types.ProfileResponse{
-		Id:        profile.ID,
-		UserId:    profile.UserID,
-		Email:     profile.Email,
-		CreatedAt: profile.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: profile.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return toProfileResponse(profile), nil
+}
+
+func (s *ProfileServer) PatchProfile(ctx context.Context, pbReq *types.PatchProfileRequest) (*types.ProfileResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Patch profile validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithFields(logrus.Fields{
+		"profile_id":  pbReq.GetId(),
+		"update_mask": pbReq.GetUpdateMask().GetPaths(),
+	}).Info("Patch profile request received (grpc)")
+	profile, err := s.profileService.Patch(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		l.WithError(err).WithField("profile_id", pbReq.GetId()).Error("Patch profile failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("profile_id", pbReq.GetId()).Info("Profile patched (grpc)")
+	return toProfileResponse(profile), nil
 }
 
 func (s *ProfileServer) DeleteProfile(ctx context.Context, pbReq *types.DeleteProfileRequest) (*types.DeleteProfileResponse, error) {
@@ -152,10 +242,13 @@ func (s *ProfileServer) DeleteProfile(ctx context.Context, pbReq *types.DeletePr
 	}
 
 	l.WithField("profile_id", pbReq.GetId()).Info("Delete profile request received (grpc)")
-	if err := s.profileService.Delete(ctx, pbReq.GetId()); err != nil {
+	if err := s.profileService.Delete(ctx, pbReq.GetId(), pbReq.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrProfileNotFound) {
 			return nil, status.Error(codes.NotFound, "profile not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
 		l.WithError(err).WithField("profile_id", pbReq.GetId()).Error("Delete profile failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -166,33 +259,97 @@ func (s *ProfileServer) DeleteProfile(ctx context.Context, pbReq *types.DeletePr
 	}, nil
 }
 
+func (s *ProfileServer) RestoreProfile(ctx context.Context, pbReq *types.RestoreProfileRequest) (*types.ProfileResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Restore profile validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.WithField("profile_id", pbReq.GetId()).Info("Restore profile request received (grpc)")
+	profile, err := s.profileService.Restore(ctx, pbReq.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "profile is not deleted")
+		}
+		if errors.Is(err, service.ErrProfileEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		l.WithError(err).WithField("profile_id", pbReq.GetId()).Error("Restore profile failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("profile_id", pbReq.GetId()).Info("Profile restored (grpc)")
+	return toProfileResponse(profile), nil
+}
+
+func (s *ProfileServer) GetProfileBundle(ctx context.Context, pbReq *types.GetProfileBundleRequest) (*types.ProfileBundleResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Get profile bundle validation failed (grpc)")
+
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithField("profile_id", pbReq.GetId()).Info("Get profile bundle request received (grpc)")
+	bundle, err := s.profileService.GetBundle(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		l.WithError(err).WithField("profile_id", pbReq.GetId()).Error("Get profile bundle failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp := &types.ProfileBundleResponse{Profile: toProfileResponse(bundle.Profile)}
+	for _, contact := range bundle.Contacts {
+		resp.Contacts = append(resp.Contacts, toContactResponse(ctx, contact))
+	}
+	for _, address := range bundle.Addresses {
+		resp.Addresses = append(resp.Addresses, toAddressResponse(address))
+	}
+	for _, company := range bundle.Companies {
+		resp.Companies = append(resp.Companies, toCompanyResponse(ctx, company))
+	}
+
+	return resp, nil
+}
+
 func (s *ProfileServer) CreateContact(ctx context.Context, pbReq *types.CreateContactRequest) (*types.ContactResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
 		l.WithField("profile_id", pbReq.GetProfileId()).Debug("Create contact validation failed (grpc)")
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
 	l.WithField("profile_id", pbReq.GetProfileId()).Info("Create contact request received (grpc)")
 	contact, err := s.contactService.Create(ctx, pbReq)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("profile_id", pbReq.GetProfileId()).Error("Create contact failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
 	l.WithField("contact_id", contact.ID).Info("Contact created (grpc)")
-	return &types.ContactResponse{
-		Id:        contact.ID,
-		FirstName: contact.FirstName,
-		LastName:  contact.LastName,
-		Nin:       contact.NIN,
-		Dob:       contactDOBString(contact.DOB),
-		Phone:     contact.Phone,
-		CreatedAt: contact.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: contact.UpdatedAt.Format(time.RFC3339),
-		ProfileId: contact.ProfileID,
-		Type:      contact.Type,
-	}, nil
+	return toContactResponse(ctx, contact), nil
 }
 
 func (s *ProfileServer) GetContact(ctx context.Context, pbReq *types.GetContactRequest) (*types.ContactResponse, error) {
@@ -202,8 +359,12 @@ func (s *ProfileServer) GetContact(ctx context.Context, pbReq *types.GetContactR
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithField("contact_id", pbReq.GetId()).Info("Get contact request received (grpc)")
-	contact, err := s.contactService.GetByID(ctx, pbReq.GetId())
+	contact, err := s.contactService.GetByID(ctx, pbReq.GetId(), pbReq.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrContactNotFound) {
 			return nil, status.Error(codes.NotFound, "contact not found")
@@ -212,25 +373,14 @@ func (s *ProfileServer) GetContact(ctx context.Context, pbReq *types.GetContactR
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
-	return &types.ContactResponse{
-		Id:        contact.ID,
-		FirstName: contact.FirstName,
-		LastName:  contact.LastName,
-		Nin:       contact.NIN,
-		Dob:       contactDOBString(contact.DOB),
-		Phone:     contact.Phone,
-		CreatedAt: contact.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: contact.UpdatedAt.Format(time.RFC3339),
-		ProfileId: contact.ProfileID,
-		Type:      contact.Type,
-	}, nil
+	return toContactResponse(ctx, contact), nil
 }
 
 func (s *ProfileServer) UpdateContact(ctx context.Context, pbReq *types.UpdateContactRequest) (*types.ContactResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
 		l.Debug("Update contact validation failed (grpc)")
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
 	l.WithField("contact_id", pbReq.GetId()).Info("Update contact request received (grpc)")
@@ -239,23 +389,65 @@ func (s *ProfileServer) UpdateContact(ctx context.Context, pbReq *types.UpdateCo
 		if errors.Is(err, service.ErrContactNotFound) {
 			return nil, status.Error(codes.NotFound, "contact not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target profile does not exist")
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("contact_id", pbReq.GetId()).Error("Update contact failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
 	l.WithField("contact_id", pbReq.GetId()).Info("Contact updated (grpc)")
-	return &types.ContactResponse{
-		Id:        contact.ID,
-		FirstName: contact.FirstName,
-		LastName:  contact.LastName,
-		Nin:       contact.NIN,
-		Dob:       contactDOBString(contact.DOB),
-		Phone:     contact.Phone,
-		CreatedAt: contact.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: contact.UpdatedAt.Format(time.RFC3339),
-		ProfileId: contact.ProfileID,
-		Type:      contact.Type,
-	}, nil
+	return toContactResponse(ctx, contact), nil
+}
+
+func (s *ProfileServer) PatchContact(ctx context.Context, pbReq *types.PatchContactRequest) (*types.ContactResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Patch contact validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithFields(logrus.Fields{
+		"contact_id":  pbReq.GetId(),
+		"update_mask": pbReq.GetUpdateMask().GetPaths(),
+	}).Info("Patch contact request received (grpc)")
+	contact, err := s.contactService.Patch(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrContactNotFound) {
+			return nil, status.Error(codes.NotFound, "contact not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target profile does not exist")
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
+		l.WithError(err).WithField("contact_id", pbReq.GetId()).Error("Patch contact failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("contact_id", pbReq.GetId()).Info("Contact patched (grpc)")
+	return toContactResponse(ctx, contact), nil
 }
 
 func (s *ProfileServer) DeleteContact(ctx context.Context, pbReq *types.DeleteContactRequest) (*types.DeleteContactResponse, error) {
@@ -266,10 +458,13 @@ func (s *ProfileServer) DeleteContact(ctx context.Context, pbReq *types.DeleteCo
 	}
 
 	l.WithField("contact_id", pbReq.GetId()).Info("Delete contact request received (grpc)")
-	if err := s.contactService.Delete(ctx, pbReq.GetId()); err != nil {
+	if err := s.contactService.Delete(ctx, pbReq.GetId(), pbReq.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrContactNotFound) {
 			return nil, status.Error(codes.NotFound, "contact not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
 		l.WithError(err).WithField("contact_id", pbReq.GetId()).Error("Delete contact failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -280,6 +475,63 @@ func (s *ProfileServer) DeleteContact(ctx context.Context, pbReq *types.DeleteCo
 	}, nil
 }
 
+func (s *ProfileServer) RestoreContact(ctx context.Context, pbReq *types.RestoreContactRequest) (*types.ContactResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Restore contact validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.WithField("contact_id", pbReq.GetId()).Info("Restore contact request received (grpc)")
+	contact, err := s.contactService.Restore(ctx, pbReq.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrContactNotFound) {
+			return nil, status.Error(codes.NotFound, "contact not found")
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "contact is not deleted")
+		}
+		if errors.Is(err, service.ErrProfileDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "profile is deleted; restore it first")
+		}
+		l.WithError(err).WithField("contact_id", pbReq.GetId()).Error("Restore contact failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("contact_id", pbReq.GetId()).Info("Contact restored (grpc)")
+	return toContactResponse(ctx, contact), nil
+}
+
+func (s *ProfileServer) SetPrimaryContact(ctx context.Context, pbReq *types.SetPrimaryContactRequest) (*types.ContactResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Set primary contact validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithField("contact_id", pbReq.GetId()).Info("Set primary contact request received (grpc)")
+	contact, err := s.contactService.SetPrimary(ctx, pbReq.GetId(), pbReq.GetExpectedVersion())
+	if err != nil {
+		if errors.Is(err, service.ErrContactNotFound) {
+			return nil, status.Error(codes.NotFound, "contact not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrPrimaryConflict) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		l.WithError(err).WithField("contact_id", pbReq.GetId()).Error("Set primary contact failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("contact_id", pbReq.GetId()).Info("Contact set as primary (grpc)")
+	return toContactResponse(ctx, contact), nil
+}
+
 func (s *ProfileServer) ListContacts(ctx context.Context, pbReq *types.ListContactsRequest) (*types.ListContactsResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
@@ -287,11 +539,16 @@ func (s *ProfileServer) ListContacts(ctx context.Context, pbReq *types.ListConta
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithFields(map[string]interface{}{
-		"profile_id": pbReq.GetProfileId(),
-		"page":       pbReq.GetPage(),
-		"page_size":  pbReq.GetPageSize(),
-		"type":       pbReq.GetType(),
+		"profile_id":      pbReq.GetProfileId(),
+		"page":            pbReq.GetPage(),
+		"page_size":       pbReq.GetPageSize(),
+		"type":            pbReq.GetType(),
+		"include_deleted": pbReq.GetIncludeDeleted(),
 	}).Info("List contacts request received (grpc)")
 
 	result, err := s.contactService.List(ctx, pbReq)
@@ -302,18 +559,7 @@ func (s *ProfileServer) ListContacts(ctx context.Context, pbReq *types.ListConta
 
 	contacts := make([]*types.ContactResponse, 0, len(result.Contacts))
 	for _, contact := range result.Contacts {
-		contacts = append(contacts, &types.ContactResponse{
-			Id:        contact.ID,
-			FirstName: contact.FirstName,
-			LastName:  contact.LastName,
-			Nin:       contact.NIN,
-			Dob:       contactDOBString(contact.DOB),
-			Phone:     contact.Phone,
-			CreatedAt: contact.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: contact.UpdatedAt.Format(time.RFC3339),
-			ProfileId: contact.ProfileID,
-			Type:      contact.Type,
-		})
+		contacts = append(contacts, toContactResponse(ctx, contact))
 	}
 
 	return &types.ListContactsResponse{
@@ -328,12 +574,25 @@ func (s *ProfileServer) CreateAddress(ctx context.Context, pbReq *types.CreateAd
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
 		l.WithField("profile_id", pbReq.GetProfileId()).Debug("Create address validation failed (grpc)")
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
-	l.WithField("profile_id", pbReq.GetProfileId()).Info("Create address request received (grpc)")
+	l.WithFields(logrus.Fields{
+		"profile_id": pbReq.GetProfileId(),
+		"company_id": pbReq.GetCompanyId(),
+	}).Info("Create address request received (grpc)")
 	address, err := s.addressService.Create(ctx, pbReq)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return nil, status.Error(codes.NotFound, "company not found")
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("profile_id", pbReq.GetProfileId()).Error("Create address failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -349,8 +608,12 @@ func (s *ProfileServer) GetAddress(ctx context.Context, pbReq *types.GetAddressR
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithField("address_id", pbReq.GetId()).Info("Get address request received (grpc)")
-	address, err := s.addressService.GetByID(ctx, pbReq.GetId())
+	address, err := s.addressService.GetByID(ctx, pbReq.GetId(), pbReq.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrAddressNotFound) {
 			return nil, status.Error(codes.NotFound, "address not found")
@@ -366,7 +629,7 @@ func (s *ProfileServer) UpdateAddress(ctx context.Context, pbReq *types.UpdateAd
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
 		l.Debug("Update address validation failed (grpc)")
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
 	l.WithField("address_id", pbReq.GetId()).Info("Update address request received (grpc)")
@@ -375,6 +638,19 @@ func (s *ProfileServer) UpdateAddress(ctx context.Context, pbReq *types.UpdateAd
 		if errors.Is(err, service.ErrAddressNotFound) {
 			return nil, status.Error(codes.NotFound, "address not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target profile does not exist")
+		}
+		if errors.Is(err, service.ErrTargetCompanyNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target company does not exist")
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("address_id", pbReq.GetId()).Error("Update address failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -383,6 +659,46 @@ func (s *ProfileServer) UpdateAddress(ctx context.Context, pbReq *types.UpdateAd
 	return toAddressResponse(address), nil
 }
 
+func (s *ProfileServer) PatchAddress(ctx context.Context, pbReq *types.PatchAddressRequest) (*types.AddressResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Patch address validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithFields(logrus.Fields{
+		"address_id":  pbReq.GetId(),
+		"update_mask": pbReq.GetUpdateMask().GetPaths(),
+	}).Info("Patch address request received (grpc)")
+	address, err := s.addressService.Patch(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return nil, status.Error(codes.NotFound, "address not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target profile does not exist")
+		}
+		if errors.Is(err, service.ErrTargetCompanyNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target company does not exist")
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
+		l.WithError(err).WithField("address_id", pbReq.GetId()).Error("Patch address failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("address_id", pbReq.GetId()).Info("Address patched (grpc)")
+	return toAddressResponse(address), nil
+}
+
 func (s *ProfileServer) DeleteAddress(ctx context.Context, pbReq *types.DeleteAddressRequest) (*types.DeleteAddressResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
@@ -391,10 +707,13 @@ func (s *ProfileServer) DeleteAddress(ctx context.Context, pbReq *types.DeleteAd
 	}
 
 	l.WithField("address_id", pbReq.GetId()).Info("Delete address request received (grpc)")
-	if err := s.addressService.Delete(ctx, pbReq.GetId()); err != nil {
+	if err := s.addressService.Delete(ctx, pbReq.GetId(), pbReq.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrAddressNotFound) {
 			return nil, status.Error(codes.NotFound, "address not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
 		l.WithError(err).WithField("address_id", pbReq.GetId()).Error("Delete address failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -403,39 +722,132 @@ func (s *ProfileServer) DeleteAddress(ctx context.Context, pbReq *types.DeleteAd
 	return &types.DeleteAddressResponse{Message: "address deleted successfully"}, nil
 }
 
-func (s *ProfileServer) ListAddresses(ctx context.Context, pbReq *types.ListAddressesRequest) (*types.ListAddressesResponse, error) {
+func (s *ProfileServer) RestoreAddress(ctx context.Context, pbReq *types.RestoreAddressRequest) (*types.AddressResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
-		l.Debug("List addresses validation failed (grpc)")
+		l.Debug("Restore address validation failed (grpc)")
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
 
-	l.WithFields(map[string]interface{}{
-		"profile_id": pbReq.GetProfileId(),
-		"page":       pbReq.GetPage(),
-		"page_size":  pbReq.GetPageSize(),
-		"type":       pbReq.GetType(),
-	}).Info("List addresses request received (grpc)")
-
-	result, err := s.addressService.List(ctx, pbReq)
+	l.WithField("address_id", pbReq.GetId()).Info("Restore address request received (grpc)")
+	address, err := s.addressService.Restore(ctx, pbReq.GetId())
 	if err != nil {
-		l.WithError(err).Error("List addresses failed (grpc)")
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return nil, status.Error(codes.NotFound, "address not found")
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "address is not deleted")
+		}
+		if errors.Is(err, service.ErrProfileDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "profile is deleted; restore it first")
+		}
+		if errors.Is(err, service.ErrCompanyDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "company is deleted; restore it first")
+		}
+		l.WithError(err).WithField("address_id", pbReq.GetId()).Error("Restore address failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
-	addresses := make([]*types.AddressResponse, 0, len(result.Addresses))
-	for _, address := range result.Addresses {
-		addresses = append(addresses, toAddressResponse(address))
-	}
+	l.WithField("address_id", pbReq.GetId()).Info("Address restored (grpc)")
+	return toAddressResponse(address), nil
+}
 
-	return &types.ListAddressesResponse{
-		Addresses: addresses,
+func (s *ProfileServer) SetPrimaryAddress(ctx context.Context, pbReq *types.SetPrimaryAddressRequest) (*types.AddressResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Set primary address validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithField("address_id", pbReq.GetId()).Info("Set primary address request received (grpc)")
+	address, err := s.addressService.SetPrimary(ctx, pbReq.GetId(), pbReq.GetExpectedVersion())
+	if err != nil {
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return nil, status.Error(codes.NotFound, "address not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrPrimaryConflict) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		l.WithError(err).WithField("address_id", pbReq.GetId()).Error("Set primary address failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("address_id", pbReq.GetId()).Info("Address set as primary (grpc)")
+	return toAddressResponse(address), nil
+}
+
+func (s *ProfileServer) ListAddresses(ctx context.Context, pbReq *types.ListAddressesRequest) (*types.ListAddressesResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("List addresses validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.WithFields(map[string]interface{}{
+		"profile_id":      pbReq.GetProfileId(),
+		"company_id":      pbReq.GetCompanyId(),
+		"page":            pbReq.GetPage(),
+		"page_size":       pbReq.GetPageSize(),
+		"type":            pbReq.GetType(),
+		"include_deleted": pbReq.GetIncludeDeleted(),
+	}).Info("List addresses request received (grpc)")
+
+	result, err := s.addressService.List(ctx, pbReq)
+	if err != nil {
+		l.WithError(err).Error("List addresses failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	addresses := make([]*types.AddressResponse, 0, len(result.Addresses))
+	for _, address := range result.Addresses {
+		addresses = append(addresses, toAddressResponse(address))
+	}
+
+	return &types.ListAddressesResponse{
+		Addresses: addresses,
 		Page:      result.Page,
 		PageSize:  result.PageSize,
 		Total:     result.Total,
 	}, nil
 }
 
+func (s *ProfileServer) SearchAddressesNear(ctx context.Context, pbReq *types.SearchAddressesNearRequest) (*types.SearchAddressesNearResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Search addresses near validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if pbReq.GetProfileId() == 0 && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.WithFields(map[string]interface{}{
+		"profile_id":    pbReq.GetProfileId(),
+		"type":          pbReq.GetType(),
+		"radius_meters": pbReq.GetRadiusMeters(),
+		"limit":         pbReq.GetLimit(),
+	}).Info("Search addresses near request received (grpc)")
+
+	found, err := s.addressService.SearchNear(ctx, pbReq)
+	if err != nil {
+		l.WithError(err).Error("Search addresses near failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return toSearchAddressesNearResponse(found), nil
+}
+
 func (s *ProfileServer) CreateCompany(ctx context.Context, pbReq *types.CreateCompanyRequest) (*types.CompanyResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
@@ -446,12 +858,22 @@ func (s *ProfileServer) CreateCompany(ctx context.Context, pbReq *types.CreateCo
 	l.WithField("profile_id", pbReq.GetProfileId()).Info("Create company request received (grpc)")
 	company, err := s.companyService.Create(ctx, pbReq)
 	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("profile_id", pbReq.GetProfileId()).Error("Create company failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
 	l.WithField("company_id", company.ID).Info("Company created (grpc)")
-	return toCompanyResponse(company), nil
+	return toCompanyResponse(ctx, company), nil
 }
 
 func (s *ProfileServer) GetCompany(ctx context.Context, pbReq *types.GetCompanyRequest) (*types.CompanyResponse, error) {
@@ -461,8 +883,12 @@ func (s *ProfileServer) GetCompany(ctx context.Context, pbReq *types.GetCompanyR
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithField("company_id", pbReq.GetId()).Info("Get company request received (grpc)")
-	company, err := s.companyService.GetByID(ctx, pbReq.GetId())
+	company, err := s.companyService.GetByID(ctx, pbReq.GetId(), pbReq.GetIncludeDeleted())
 	if err != nil {
 		if errors.Is(err, service.ErrCompanyNotFound) {
 			return nil, status.Error(codes.NotFound, "company not found")
@@ -471,7 +897,19 @@ func (s *ProfileServer) GetCompany(ctx context.Context, pbReq *types.GetCompanyR
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
-	return toCompanyResponse(company), nil
+	response := toCompanyResponse(ctx, company)
+	if pbReq.GetIncludeAddresses() {
+		addresses, err := s.companyService.ListAddresses(ctx, company.ID)
+		if err != nil {
+			l.WithError(err).WithField("company_id", pbReq.GetId()).Error("List company addresses failed (grpc)")
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		for _, address := range addresses {
+			response.Addresses = append(response.Addresses, toAddressResponse(address))
+		}
+	}
+
+	return response, nil
 }
 
 func (s *ProfileServer) UpdateCompany(ctx context.Context, pbReq *types.UpdateCompanyRequest) (*types.CompanyResponse, error) {
@@ -487,12 +925,65 @@ func (s *ProfileServer) UpdateCompany(ctx context.Context, pbReq *types.UpdateCo
 		if errors.Is(err, service.ErrCompanyNotFound) {
 			return nil, status.Error(codes.NotFound, "company not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target profile does not exist")
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
 		l.WithError(err).WithField("company_id", pbReq.GetId()).Error("Update company failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
 
 	l.WithField("company_id", pbReq.GetId()).Info("Company updated (grpc)")
-	return toCompanyResponse(company), nil
+	return toCompanyResponse(ctx, company), nil
+}
+
+func (s *ProfileServer) PatchCompany(ctx context.Context, pbReq *types.PatchCompanyRequest) (*types.CompanyResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Patch company validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithFields(logrus.Fields{
+		"company_id":  pbReq.GetId(),
+		"update_mask": pbReq.GetUpdateMask().GetPaths(),
+	}).Info("Patch company request received (grpc)")
+	company, err := s.companyService.Patch(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return nil, status.Error(codes.NotFound, "company not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrTargetProfileNotFound) {
+			return nil, status.Error(codes.FailedPrecondition, "target profile does not exist")
+		}
+		if errors.Is(err, service.ErrInvalidUpdateMask) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
+		l.WithError(err).WithField("company_id", pbReq.GetId()).Error("Patch company failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("company_id", pbReq.GetId()).Info("Company patched (grpc)")
+	return toCompanyResponse(ctx, company), nil
 }
 
 func (s *ProfileServer) DeleteCompany(ctx context.Context, pbReq *types.DeleteCompanyRequest) (*types.DeleteCompanyResponse, error) {
@@ -503,10 +994,13 @@ func (s *ProfileServer) DeleteCompany(ctx context.Context, pbReq *types.DeleteCo
 	}
 
 	l.WithField("company_id", pbReq.GetId()).Info("Delete company request received (grpc)")
-	if err := s.companyService.Delete(ctx, pbReq.GetId()); err != nil {
+	if err := s.companyService.Delete(ctx, pbReq.GetId(), pbReq.GetExpectedVersion()); err != nil {
 		if errors.Is(err, service.ErrCompanyNotFound) {
 			return nil, status.Error(codes.NotFound, "company not found")
 		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
 		l.WithError(err).WithField("company_id", pbReq.GetId()).Error("Delete company failed (grpc)")
 		return nil, status.Error(codes.Internal, "internal server error")
 	}
@@ -515,6 +1009,66 @@ func (s *ProfileServer) DeleteCompany(ctx context.Context, pbReq *types.DeleteCo
 	return &types.DeleteCompanyResponse{Message: "company deleted successfully"}, nil
 }
 
+func (s *ProfileServer) RestoreCompany(ctx context.Context, pbReq *types.RestoreCompanyRequest) (*types.CompanyResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Restore company validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.WithField("company_id", pbReq.GetId()).Info("Restore company request received (grpc)")
+	company, err := s.companyService.Restore(ctx, pbReq.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return nil, status.Error(codes.NotFound, "company not found")
+		}
+		if errors.Is(err, service.ErrNotDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "company is not deleted")
+		}
+		if errors.Is(err, service.ErrProfileDeleted) {
+			return nil, status.Error(codes.FailedPrecondition, "profile is deleted; restore it first")
+		}
+		if errors.Is(err, service.ErrCompanyFiscalCodeTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		l.WithError(err).WithField("company_id", pbReq.GetId()).Error("Restore company failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("company_id", pbReq.GetId()).Info("Company restored (grpc)")
+	return toCompanyResponse(ctx, company), nil
+}
+
+func (s *ProfileServer) SetPrimaryCompany(ctx context.Context, pbReq *types.SetPrimaryCompanyRequest) (*types.CompanyResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Set primary company validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithField("company_id", pbReq.GetId()).Info("Set primary company request received (grpc)")
+	company, err := s.companyService.SetPrimary(ctx, pbReq.GetId(), pbReq.GetExpectedVersion())
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return nil, status.Error(codes.NotFound, "company not found")
+		}
+		if errors.Is(err, service.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "version mismatch")
+		}
+		if errors.Is(err, service.ErrPrimaryConflict) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		l.WithError(err).WithField("company_id", pbReq.GetId()).Error("Set primary company failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("company_id", pbReq.GetId()).Info("Company set as primary (grpc)")
+	return toCompanyResponse(ctx, company), nil
+}
+
 func (s *ProfileServer) ListCompanies(ctx context.Context, pbReq *types.ListCompaniesRequest) (*types.ListCompaniesResponse, error) {
 	l := loggerWithContext(ctx)
 	if err := pbReq.Validate(); err != nil {
@@ -522,11 +1076,16 @@ func (s *ProfileServer) ListCompanies(ctx context.Context, pbReq *types.ListComp
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	if pbReq.GetIncludeDeleted() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
 	l.WithFields(map[string]interface{}{
-		"profile_id": pbReq.GetProfileId(),
-		"page":       pbReq.GetPage(),
-		"page_size":  pbReq.GetPageSize(),
-		"type":       pbReq.GetType(),
+		"profile_id":      pbReq.GetProfileId(),
+		"page":            pbReq.GetPage(),
+		"page_size":       pbReq.GetPageSize(),
+		"type":            pbReq.GetType(),
+		"include_deleted": pbReq.GetIncludeDeleted(),
 	}).Info("List companies request received (grpc)")
 
 	result, err := s.companyService.List(ctx, pbReq)
@@ -537,7 +1096,7 @@ func (s *ProfileServer) ListCompanies(ctx context.Context, pbReq *types.ListComp
 
 	companies := make([]*types.CompanyResponse, 0, len(result.Companies))
 	for _, company := range result.Companies {
-		companies = append(companies, toCompanyResponse(company))
+		companies = append(companies, toCompanyResponse(ctx, company))
 	}
 
 	return &types.ListCompaniesResponse{
@@ -548,6 +1107,477 @@ func (s *ProfileServer) ListCompanies(ctx context.Context, pbReq *types.ListComp
 	}, nil
 }
 
+func (s *ProfileServer) AddCompanyMember(ctx context.Context, pbReq *types.AddCompanyMemberRequest) (*types.CompanyMemberResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Add company member validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithFields(logrus.Fields{
+		"company_id": pbReq.GetCompanyId(),
+		"profile_id": pbReq.GetProfileId(),
+		"role":       pbReq.GetRole(),
+	})
+	l.Info("Add company member request received (grpc)")
+	member, err := s.companyService.AddMember(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return nil, status.Error(codes.NotFound, "company not found")
+		}
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrLastCompanyOwner) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		l.WithError(err).Error("Add company member failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.Info("Company member added (grpc)")
+	return toCompanyMemberResponse(member), nil
+}
+
+func (s *ProfileServer) RemoveCompanyMember(ctx context.Context, pbReq *types.RemoveCompanyMemberRequest) (*types.RemoveCompanyMemberResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Remove company member validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithFields(logrus.Fields{
+		"company_id": pbReq.GetCompanyId(),
+		"profile_id": pbReq.GetProfileId(),
+	})
+	l.Info("Remove company member request received (grpc)")
+	if err := s.companyService.RemoveMember(ctx, pbReq.GetCompanyId(), pbReq.GetProfileId()); err != nil {
+		if errors.Is(err, service.ErrCompanyMemberNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		if errors.Is(err, service.ErrLastCompanyOwner) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		l.WithError(err).Error("Remove company member failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.Info("Company member removed (grpc)")
+	return &types.RemoveCompanyMemberResponse{Message: "company member removed successfully"}, nil
+}
+
+func (s *ProfileServer) ListCompanyMembers(ctx context.Context, pbReq *types.ListCompanyMembersRequest) (*types.ListCompanyMembersResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("List company members validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithFields(map[string]interface{}{
+		"company_id": pbReq.GetCompanyId(),
+		"page":       pbReq.GetPage(),
+		"page_size":  pbReq.GetPageSize(),
+	}).Info("List company members request received (grpc)")
+
+	result, err := s.companyService.ListMembers(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrCompanyNotFound) {
+			return nil, status.Error(codes.NotFound, "company not found")
+		}
+		l.WithError(err).Error("List company members failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	members := make([]*types.CompanyMemberResponse, 0, len(result.Members))
+	for _, member := range result.Members {
+		members = append(members, toCompanyMemberResponse(member))
+	}
+
+	return &types.ListCompanyMembersResponse{
+		Members:  members,
+		Page:     result.Page,
+		PageSize: result.PageSize,
+		Total:    result.Total,
+	}, nil
+}
+
+func (s *ProfileServer) ListProfileCompanies(ctx context.Context, pbReq *types.ListProfileCompaniesRequest) (*types.ListProfileCompaniesResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("List profile companies validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l.WithFields(map[string]interface{}{
+		"profile_id": pbReq.GetProfileId(),
+		"page":       pbReq.GetPage(),
+		"page_size":  pbReq.GetPageSize(),
+	}).Info("List profile companies request received (grpc)")
+
+	result, err := s.companyService.ListProfileCompanies(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		l.WithError(err).Error("List profile companies failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	companies := make([]*types.ProfileCompanyResponse, 0, len(result.Memberships))
+	for _, membership := range result.Memberships {
+		companies = append(companies, &types.ProfileCompanyResponse{
+			Company: toCompanyResponse(ctx, membership.Company),
+			Role:    membership.Role,
+		})
+	}
+
+	return &types.ListProfileCompaniesResponse{
+		Companies: companies,
+		Page:      result.Page,
+		PageSize:  result.PageSize,
+		Total:     result.Total,
+	}, nil
+}
+
+func (s *ProfileServer) GetPreferences(ctx context.Context, pbReq *types.GetPreferencesRequest) (*types.PreferencesResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Get preferences validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithFields(logrus.Fields{
+		"profile_id": pbReq.GetProfileId(),
+		"namespace":  pbReq.GetNamespace(),
+	})
+	l.Info("Get preferences request received (grpc)")
+	namespaces, err := s.preferenceService.Get(ctx, pbReq)
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		l.WithError(err).Error("Get preferences failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	response, err := toPreferencesResponse(pbReq.GetProfileId(), namespaces...)
+	if err != nil {
+		l.WithError(err).Error("Get preferences failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return response, nil
+}
+
+// SetPreferences writes several keys of one namespace atomically.
+func (s *ProfileServer) SetPreferences(ctx context.Context, pbReq *types.SetPreferencesRequest) (*types.PreferencesResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Set preferences validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithFields(logrus.Fields{
+		"profile_id": pbReq.GetProfileId(),
+		"namespace":  pbReq.GetNamespace(),
+	})
+	l.Info("Set preferences request received (grpc)")
+	namespace, err := s.preferenceService.Set(ctx, pbReq)
+	if err != nil {
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		l.WithError(err).Error("Set preferences failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	response, err := toPreferencesResponse(pbReq.GetProfileId(), namespace)
+	if err != nil {
+		l.WithError(err).Error("Set preferences failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	l.Info("Preferences set (grpc)")
+	return response, nil
+}
+
+func (s *ProfileServer) DeletePreference(ctx context.Context, pbReq *types.DeletePreferenceRequest) (*types.DeletePreferenceResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Delete preference validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithFields(logrus.Fields{
+		"profile_id": pbReq.GetProfileId(),
+		"namespace":  pbReq.GetNamespace(),
+		"key":        pbReq.GetKey(),
+	})
+	l.Info("Delete preference request received (grpc)")
+	if err := s.preferenceService.Delete(ctx, pbReq); err != nil {
+		var fieldErr *country.FieldError
+		if errors.As(err, &fieldErr) {
+			return nil, invalidArgument(err)
+		}
+		if errors.Is(err, service.ErrProfileNotFound) {
+			return nil, status.Error(codes.NotFound, "profile not found")
+		}
+		if errors.Is(err, service.ErrPreferenceNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		l.WithError(err).Error("Delete preference failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.Info("Preference deleted (grpc)")
+	return &types.DeletePreferenceResponse{Message: "preference deleted successfully"}, nil
+}
+
+// UploadAttachment reads the metadata of an upload from the first message of the stream and
+// its content from the chunks that follow.
+func (s *ProfileServer) UploadAttachment(stream types.ProfileService_UploadAttachmentServer) error {
+	ctx := stream.Context()
+	l := loggerWithContext(ctx)
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, errUploadMetadataFirst)
+	}
+	if err != nil {
+		return err
+	}
+	pbReq := first.GetMetadata()
+	if pbReq == nil {
+		return status.Error(codes.InvalidArgument, errUploadMetadataFirst)
+	}
+	if err = pbReq.Validate(); err != nil {
+		l.Debug("Upload attachment validation failed (grpc)")
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithFields(logrus.Fields{
+		"entity_type": pbReq.GetEntityType(),
+		"entity_id":   pbReq.GetEntityId(),
+		"kind":        pbReq.GetKind(),
+	})
+	l.Info("Upload attachment request received (grpc)")
+	attachment, err := s.attachmentService.Upload(ctx, pbReq, &uploadChunkReader{stream: stream})
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return st.Err()
+		}
+		if errors.Is(err, service.ErrAttachmentTooLarge) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		if errors.Is(err, service.ErrUnsupportedContentType) {
+			return invalidArgument(&country.FieldError{Field: "content_type", Message: err.Error()})
+		}
+		if errors.Is(err, service.ErrEmptyAttachment) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if owner := attachmentOwnerNotFound(err); owner != nil {
+			return owner
+		}
+		l.WithError(err).Error("Upload attachment failed (grpc)")
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	l.WithField("attachment_id", attachment.ID).Info("Attachment uploaded (grpc)")
+	return stream.SendAndClose(toAttachmentResponse(attachment))
+}
+
+// DownloadAttachment sends the metadata of the attachment and then its content in chunks.
+func (s *ProfileServer) DownloadAttachment(pbReq *types.DownloadAttachmentRequest, stream types.ProfileService_DownloadAttachmentServer) error {
+	ctx := stream.Context()
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Download attachment validation failed (grpc)")
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithField("attachment_id", pbReq.GetId())
+	l.Info("Download attachment request received (grpc)")
+	attachment, content, err := s.attachmentService.Open(ctx, pbReq.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		l.WithError(err).Error("Download attachment failed (grpc)")
+		return status.Error(codes.Internal, "internal server error")
+	}
+	defer content.Close()
+
+	err = stream.Send(&types.DownloadAttachmentResponse{Data: &types.DownloadAttachmentResponse_Metadata{Metadata: toAttachmentResponse(attachment)}})
+	if err != nil {
+		return err
+	}
+	chunk := make([]byte, attachmentChunkSize)
+	for {
+		n, err := content.Read(chunk)
+		if n > 0 {
+			if sendErr := stream.Send(&types.DownloadAttachmentResponse{Data: &types.DownloadAttachmentResponse_Chunk{Chunk: chunk[:n]}}); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			l.WithError(err).Error("Download attachment failed (grpc)")
+			return status.Error(codes.Internal, "internal server error")
+		}
+	}
+}
+
+func (s *ProfileServer) GetAttachment(ctx context.Context, pbReq *types.GetAttachmentRequest) (*types.AttachmentResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Get attachment validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	attachment, err := s.attachmentService.Get(ctx, pbReq.GetId())
+	if err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		l.WithError(err).WithField("attachment_id", pbReq.GetId()).Error("Get attachment failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return toAttachmentResponse(attachment), nil
+}
+
+func (s *ProfileServer) ListAttachments(ctx context.Context, pbReq *types.ListAttachmentsRequest) (*types.ListAttachmentsResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("List attachments validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := s.attachmentService.List(ctx, pbReq)
+	if err != nil {
+		if owner := attachmentOwnerNotFound(err); owner != nil {
+			return nil, owner
+		}
+		l.WithError(err).Error("List attachments failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	attachments := make([]*types.AttachmentResponse, 0, len(result.Attachments))
+	for _, attachment := range result.Attachments {
+		attachments = append(attachments, toAttachmentResponse(attachment))
+	}
+
+	return &types.ListAttachmentsResponse{
+		Attachments: attachments,
+		Page:        result.Page,
+		PageSize:    result.PageSize,
+		Total:       result.Total,
+	}, nil
+}
+
+func (s *ProfileServer) DeleteAttachment(ctx context.Context, pbReq *types.DeleteAttachmentRequest) (*types.DeleteAttachmentResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("Delete attachment validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	l = l.WithField("attachment_id", pbReq.GetId())
+	l.Info("Delete attachment request received (grpc)")
+	if err := s.attachmentService.Delete(ctx, pbReq.GetId()); err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		l.WithError(err).Error("Delete attachment failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	l.Info("Attachment deleted (grpc)")
+	return &types.DeleteAttachmentResponse{Message: "attachment deleted successfully"}, nil
+}
+
+// ListAuditEvents returns the change history of one record. Admin callers only.
+func (s *ProfileServer) ListAuditEvents(ctx context.Context, pbReq *types.ListAuditEventsRequest) (*types.ListAuditEventsResponse, error) {
+	l := loggerWithContext(ctx)
+	if err := pbReq.Validate(); err != nil {
+		l.Debug("List audit events validation failed (grpc)")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, errAdminOnly)
+	}
+
+	l.WithFields(map[string]interface{}{
+		"entity":    pbReq.GetEntity(),
+		"entity_id": pbReq.GetEntityId(),
+		"page":      pbReq.GetPage(),
+		"page_size": pbReq.GetPageSize(),
+	}).Info("List audit events request received (grpc)")
+
+	result, err := s.auditService.List(ctx, pbReq)
+	if err != nil {
+		l.WithError(err).Error("List audit events failed (grpc)")
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	events := make([]*types.AuditEventResponse, 0, len(result.Events))
+	for _, event := range result.Events {
+		events = append(events, toAuditEventResponse(event))
+	}
+
+	return &types.ListAuditEventsResponse{
+		Events:   events,
+		Page:     result.Page,
+		PageSize: result.PageSize,
+		Total:    result.Total,
+	}, nil
+}
+
+func toProfileResponse(profile *entity.Profile) *types.ProfileResponse {
+	return &types.ProfileResponse{
+		Id:        profile.ID,
+		UserId:    profile.UserID,
+		Email:     profile.Email,
+		CreatedAt: profile.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: profile.UpdatedAt.Format(time.RFC3339),
+		Version:   profile.Version,
+		DeletedAt: formatDeletedAt(profile.DeletedAt),
+	}
+}
+
+// toContactResponse maps a contact, applying the caller's field rules from ctx.
+func toContactResponse(ctx context.Context, contact *entity.Contact) *types.ContactResponse {
+	rules := fieldpolicy.FromContext(ctx)
+	return &types.ContactResponse{
+		Id:         contact.ID,
+		FirstName:  contact.FirstName,
+		LastName:   contact.LastName,
+		Nin:        rules.Apply(fieldpolicy.ContactNIN, contact.NIN),
+		Dob:        rules.Apply(fieldpolicy.ContactDOB, contactDOBString(contact.DOB)),
+		Phone:      rules.Apply(fieldpolicy.ContactPhone, contact.Phone),
+		PhoneE164:  rules.Apply(fieldpolicy.ContactPhone, contact.PhoneE164),
+		PhoneType:  contact.PhoneType,
+		NinCountry: contact.NINCountry,
+		CreatedAt:  contact.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  contact.UpdatedAt.Format(time.RFC3339),
+		Version:    contact.Version,
+		DeletedAt:  formatDeletedAt(contact.DeletedAt),
+		ProfileId:  contact.ProfileID,
+		Type:       contact.Type,
+		IsPrimary:  contact.IsPrimary,
+		Attributes: attribute.Struct(contact.Attributes),
+	}
+}
+
+func toFormattedAddress(address *entity.Address) *types.FormattedAddress {
+	label := service.FormatAddress(address)
+	return &types.FormattedAddress{SingleLine: label.SingleLine(), MultiLine: label.MultiLine()}
+}
+
 func toAddressResponse(address *entity.Address) *types.AddressResponse {
 	return &types.AddressResponse{
 		Id:             address.ID,
@@ -557,32 +1587,183 @@ func toAddressResponse(address *entity.Address) *types.AddressResponse {
 		County:         address.County,
 		Country:        address.Country,
 		ProfileId:      address.ProfileID,
+		CompanyId:      address.CompanyID,
 		PostalCode:     address.PostalCode,
 		Building:       address.Building,
 		Apartment:      address.Apartment,
 		AdditionalData: address.AdditionalData,
 		Type:           address.Type,
+		IsPrimary:      address.IsPrimary,
+		Latitude:       address.Latitude,
+		Longitude:      address.Longitude,
+		Formatted:      toFormattedAddress(address),
 		CreatedAt:      address.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:      address.UpdatedAt.Format(time.RFC3339),
+		Version:        address.Version,
+		DeletedAt:      formatDeletedAt(address.DeletedAt),
+		Attributes:     attribute.Struct(address.Attributes),
 	}
 }
 
-func toCompanyResponse(company *entity.Company) *types.CompanyResponse {
+func toSearchAddressesNearResponse(found []*entity.NearbyAddress) *types.SearchAddressesNearResponse {
+	addresses := make([]*types.NearbyAddress, 0, len(found))
+	for _, nearby := range found {
+		addresses = append(addresses, &types.NearbyAddress{Address: toAddressResponse(nearby.Address), DistanceMeters: nearby.DistanceMeters})
+	}
+
+	return &types.SearchAddressesNearResponse{Addresses: addresses}
+}
+
+// toCompanyResponse maps a company, applying the caller's field rules from ctx.
+func toCompanyResponse(ctx context.Context, company *entity.Company) *types.CompanyResponse {
 	return &types.CompanyResponse{
-		Id:             company.ID,
-		Name:           company.Name,
-		RegistrationNo: company.RegistrationNo,
-		FiscalCode:     company.FiscalCode,
-		ProfileId:      company.ProfileID,
-		Type:           company.Type,
-		CreatedAt:      company.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:      company.UpdatedAt.Format(time.RFC3339),
+		Id:              company.ID,
+		Name:            company.Name,
+		RegistrationNo:  company.RegistrationNo,
+		FiscalCode:      fieldpolicy.FromContext(ctx).Apply(fieldpolicy.CompanyFiscalCode, company.FiscalCode),
+		ProfileId:       company.ProfileID,
+		Type:            company.Type,
+		IsPrimary:       company.IsPrimary,
+		CreatedAt:       company.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       company.UpdatedAt.Format(time.RFC3339),
+		Version:         company.Version,
+		DeletedAt:       formatDeletedAt(company.DeletedAt),
+		FiscalCodeValid: company.FiscalCodeValid,
+		VatPayerPrefix:  company.VATPayerPrefix,
+		Attributes:      attribute.Struct(company.Attributes),
+	}
+}
+
+func toCompanyMemberResponse(member *entity.CompanyMember) *types.CompanyMemberResponse {
+	return &types.CompanyMemberResponse{
+		CompanyId: member.CompanyID,
+		ProfileId: member.ProfileID,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: member.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toPreferencesResponse(profileID uint64, namespaces ...*service.PreferenceNamespace) (*types.PreferencesResponse, error) {
+	response := &types.PreferencesResponse{ProfileId: profileID, Namespaces: make([]*types.PreferenceNamespace, 0, len(namespaces))}
+	for _, namespace := range namespaces {
+		values, err := structpb.NewStruct(namespace.Values)
+		if err != nil {
+			return nil, err
+		}
+		updatedAt := ""
+		if !namespace.UpdatedAt.IsZero() {
+			updatedAt = namespace.UpdatedAt.Format(time.RFC3339)
+		}
+		response.Namespaces = append(response.Namespaces, &types.PreferenceNamespace{
+			Namespace: namespace.Namespace,
+			Values:    values,
+			UpdatedAt: updatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+func toAuditEventResponse(event *entity.AuditEvent) *types.AuditEventResponse {
+	return &types.AuditEventResponse{
+		Id:            event.ID,
+		EntityType:    event.EntityType,
+		EntityId:      event.EntityID,
+		Action:        event.Action,
+		OldValues:     string(event.OldValues),
+		NewValues:     string(event.NewValues),
+		CallerService: event.CallerService,
+		RequestId:     event.RequestID,
+		CreatedAt:     event.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toAttachmentResponse(attachment *entity.Attachment) *types.AttachmentResponse {
+	return &types.AttachmentResponse{
+		Id:          attachment.ID,
+		EntityType:  attachment.EntityType,
+		EntityId:    attachment.EntityID,
+		Kind:        attachment.Kind,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Sha256:      attachment.SHA256,
+		CreatedAt:   attachment.CreatedAt.Format(time.RFC3339),
 	}
 }
 
+func formatDeletedAt(deletedAt *time.Time) string {
+	if deletedAt == nil {
+		return ""
+	}
+	return deletedAt.Format(time.RFC3339)
+}
+
+// invalidArgument reports a validation failure, with the offending field as a BadRequest detail
+// when err names one.
+func invalidArgument(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) {
+		return st.Err()
+	}
+
+	detailed, detailErr := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: fieldErr.Field, Description: fieldErr.Message}},
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// isAdmin reports whether the caller may see soft-deleted records and restore them.
+func isAdmin(ctx context.Context) bool {
+	return caller.FromContext(ctx).Admin
+}
+
 func contactDOBString(dob *time.Time) string {
 	if dob == nil {
 		return ""
 	}
 	return dob.Format(grpcContactDOBLayout)
 }
+
+const errUploadMetadataFirst = "the first message of an upload must carry its metadata"
+
+// attachmentOwnerNotFound returns the NotFound status of an upload or list naming a record that
+// does not exist or is deleted, and nil for other errors.
+func attachmentOwnerNotFound(err error) error {
+	for _, notFound := range []error{service.ErrProfileNotFound, service.ErrContactNotFound, service.ErrAddressNotFound, service.ErrCompanyNotFound} {
+		if errors.Is(err, notFound) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+	}
+	return nil
+}
+
+// uploadChunkReader reads the content of an upload from the chunk messages of its stream.
+type uploadChunkReader struct {
+	stream interface {
+		Recv() (*types.UploadAttachmentRequest, error)
+	}
+	pending []byte
+}
+
+func (r *uploadChunkReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		msg, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if msg.GetMetadata() != nil {
+			return 0, status.Error(codes.InvalidArgument, "only the first message of an upload may carry metadata")
+		}
+		r.pending = msg.GetChunk()
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -2,47 +2,132 @@ package grpc
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"io"
 	"testing"
+	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/blobstore"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpcpkg "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type grpcRepoStub struct {
-	createFn       func(ctx context.Context, profile *entity.Profile) error
-	findByIDFn     func(ctx context.Context, id uint64) (*entity.Profile, error)
-	findByUserIDFn func(ctx context.Context, userID uint64) (*entity.Profile, error)
-	updateFn       func(ctx context.Context, profile *entity.Profile) error
-	deleteFn       func(ctx context.Context, id uint64) error
+	createFn               func(ctx context.Context, profile *entity.Profile) error
+	findByIDFn             func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error)
+	findByUserIDFn         func(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error)
+	findByEmailFn          func(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error)
+	findByEmailForUpdateFn func(ctx context.Context, email string) (*entity.Profile, error)
+	updateFn               func(ctx context.Context, profile *entity.Profile) error
+	deleteFn               func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn              func(ctx context.Context, id uint64) error
 }
 
 type grpcContactRepoStub struct {
 	createFn   func(ctx context.Context, contact *entity.Contact) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Contact, error)
-	updateFn   func(ctx context.Context, contact *entity.Contact) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error)
+
+	findPrimaryFn     func(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error)
+	updateFn          func(ctx context.Context, contact *entity.Contact) error
+	deleteFn          func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn         func(ctx context.Context, id uint64) error
+	listFn            func(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64) ([]*entity.Contact, error)
 }
 
 type grpcAddressRepoStub struct {
 	createFn   func(ctx context.Context, address *entity.Address) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Address, error)
-	updateFn   func(ctx context.Context, address *entity.Address) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error)
+
+	findPrimaryFn     func(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error)
+	updateFn          func(ctx context.Context, address *entity.Address) error
+	deleteFn          func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn         func(ctx context.Context, id uint64) error
+	listFn            func(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64) ([]*entity.Address, error)
+	searchNearFn      func(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error)
+}
+
+type grpcCompanyMemberRepoStub struct {
+	members []*entity.CompanyMember
+	listFn  func(ctx context.Context, companyID uint64, limit, offset uint32) ([]*entity.CompanyMember, uint64, error)
+}
+
+func (s *grpcCompanyMemberRepoStub) Save(_ context.Context, member *entity.CompanyMember) error {
+	s.members = append(s.members, member)
+	return nil
+}
+
+func (s *grpcCompanyMemberRepoStub) Find(context.Context, uint64, uint64) (*entity.CompanyMember, error) {
+	return nil, nil
+}
+
+func (s *grpcCompanyMemberRepoStub) CountByRole(context.Context, uint64, string) (uint64, error) {
+	return 0, nil
+}
+
+func (s *grpcCompanyMemberRepoStub) Delete(context.Context, uint64, uint64) error {
+	return repository.ErrCompanyMemberNotFound
+}
+
+func (s *grpcCompanyMemberRepoStub) ListByCompanyID(ctx context.Context, companyID uint64, limit, offset uint32) ([]*entity.CompanyMember, uint64, error) {
+	if s.listFn != nil {
+		return s.listFn(ctx, companyID, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+func (s *grpcCompanyMemberRepoStub) ListByProfileID(context.Context, uint64, uint32, uint32) ([]*entity.CompanyMembership, uint64, error) {
+	return nil, 0, nil
+}
+
+type grpcPreferenceRepoStub struct {
+	saved  []*entity.Preference
+	listFn func(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error)
+}
+
+func (s *grpcPreferenceRepoStub) Save(_ context.Context, preference *entity.Preference) error {
+	s.saved = append(s.saved, preference)
+	return nil
+}
+
+func (s *grpcPreferenceRepoStub) Delete(context.Context, uint64, string, string) error {
+	return repository.ErrPreferenceNotFound
+}
+
+func (s *grpcPreferenceRepoStub) List(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	if s.listFn != nil {
+		return s.listFn(ctx, profileID, namespace)
+	}
+	return nil, nil
+}
+
+func (s *grpcPreferenceRepoStub) ListForUpdate(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	return s.List(ctx, profileID, namespace)
 }
 
 type grpcCompanyRepoStub struct {
 	createFn   func(ctx context.Context, company *entity.Company) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Company, error)
-	updateFn   func(ctx context.Context, company *entity.Company) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error)
+
+	findPrimaryFn     func(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error)
+	updateFn          func(ctx context.Context, company *entity.Company) error
+	deleteFn          func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn         func(ctx context.Context, id uint64) error
+	listFn            func(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error)
+	listByProfileIDFn func(ctx context.Context, profileID uint64) ([]*entity.Company, error)
+	listForProfileFn  func(ctx context.Context, profileID uint64) ([]*entity.Company, error)
 }
 
 func (s *grpcRepoStub) Create(ctx context.Context, profile *entity.Profile) error {
@@ -52,16 +137,35 @@ func (s *grpcRepoStub) Create(ctx context.Context, profile *entity.Profile) erro
 	return nil
 }
 
-func (s *grpcRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Profile, error) {
+func (s *grpcRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
 	}
 	return nil, nil
 }
 
-func (s *grpcRepoStub) FindByUserID(ctx context.Context, userID uint64) (*entity.Profile, error) {
+// FindByIDForUpdate answers like FindByID; the stub takes no lock.
+func (s *grpcRepoStub) FindByIDForUpdate(ctx context.Context, id uint64) (*entity.Profile, error) {
+	return s.FindByID(ctx, id, false)
+}
+
+func (s *grpcRepoStub) FindByUserID(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error) {
 	if s.findByUserIDFn != nil {
-		return s.findByUserIDFn(ctx, userID)
+		return s.findByUserIDFn(ctx, userID, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *grpcRepoStub) FindByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error) {
+	if s.findByEmailFn != nil {
+		return s.findByEmailFn(ctx, email, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *grpcRepoStub) FindByEmailForUpdate(ctx context.Context, email string) (*entity.Profile, error) {
+	if s.findByEmailForUpdateFn != nil {
+		return s.findByEmailForUpdateFn(ctx, email)
 	}
 	return nil, nil
 }
@@ -73,9 +177,16 @@ func (s *grpcRepoStub) Update(ctx context.Context, profile *entity.Profile) erro
 	return nil
 }
 
-func (s *grpcRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *grpcRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *grpcRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
 	}
 	return nil
 }
@@ -87,9 +198,16 @@ func (s *grpcContactRepoStub) Create(ctx context.Context, contact *entity.Contac
 	return nil
 }
 
-func (s *grpcContactRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Contact, error) {
+func (s *grpcContactRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *grpcContactRepoStub) FindPrimary(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error) {
+	if s.findPrimaryFn != nil {
+		return s.findPrimaryFn(ctx, profileID, contactType)
 	}
 	return nil, nil
 }
@@ -101,20 +219,44 @@ func (s *grpcContactRepoStub) Update(ctx context.Context, contact *entity.Contac
 	return nil
 }
 
-func (s *grpcContactRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *grpcContactRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *grpcContactRepoStub) DeleteByProfileID(context.Context, uint64) error { return nil }
+
+func (s *grpcContactRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
 	}
 	return nil
 }
 
-func (s *grpcContactRepoStub) List(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+func (s *grpcContactRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error {
+	return nil
+}
+
+func (s *grpcContactRepoStub) List(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 	if s.listFn != nil {
-		return s.listFn(ctx, profileID, contactType, limit, offset)
+		return s.listFn(ctx, profileID, contactType, nin, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (s *grpcContactRepoStub) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Contact, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (s *grpcContactRepoStub) Encrypts(string) bool {
+	return false
+}
+
 func (s *grpcAddressRepoStub) Create(ctx context.Context, address *entity.Address) error {
 	if s.createFn != nil {
 		return s.createFn(ctx, address)
@@ -122,9 +264,16 @@ func (s *grpcAddressRepoStub) Create(ctx context.Context, address *entity.Addres
 	return nil
 }
 
-func (s *grpcAddressRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Address, error) {
+func (s *grpcAddressRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *grpcAddressRepoStub) FindPrimary(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error) {
+	if s.findPrimaryFn != nil {
+		return s.findPrimaryFn(ctx, profileID, companyID, addressType)
 	}
 	return nil, nil
 }
@@ -136,20 +285,57 @@ func (s *grpcAddressRepoStub) Update(ctx context.Context, address *entity.Addres
 	return nil
 }
 
-func (s *grpcAddressRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *grpcAddressRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *grpcAddressRepoStub) DeleteByProfileID(context.Context, uint64) error { return nil }
+
+func (s *grpcAddressRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
 	}
 	return nil
 }
 
-func (s *grpcAddressRepoStub) List(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+func (s *grpcAddressRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error {
+	return nil
+}
+
+func (s *grpcAddressRepoStub) List(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 	if s.listFn != nil {
-		return s.listFn(ctx, profileID, addressType, limit, offset)
+		return s.listFn(ctx, profileID, companyID, addressType, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (s *grpcAddressRepoStub) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Address, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (s *grpcAddressRepoStub) DeleteByCompanyID(context.Context, uint64) error { return nil }
+
+func (s *grpcAddressRepoStub) RestoreByCompanyID(context.Context, uint64, time.Time) error {
+	return nil
+}
+
+func (s *grpcAddressRepoStub) ListByCompanyID(context.Context, uint64) ([]*entity.Address, error) {
+	return nil, nil
+}
+
+func (s *grpcAddressRepoStub) SearchNear(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+	if s.searchNearFn != nil {
+		return s.searchNearFn(ctx, search)
+	}
+	return nil, nil
+}
+
 func (s *grpcCompanyRepoStub) Create(ctx context.Context, company *entity.Company) error {
 	if s.createFn != nil {
 		return s.createFn(ctx, company)
@@ -157,13 +343,24 @@ func (s *grpcCompanyRepoStub) Create(ctx context.Context, company *entity.Compan
 	return nil
 }
 
-func (s *grpcCompanyRepoStub) FindByID(ctx context.Context, id uint64) (*entity.Company, error) {
+func (s *grpcCompanyRepoStub) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error) {
 	if s.findByIDFn != nil {
-		return s.findByIDFn(ctx, id)
+		return s.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (s *grpcCompanyRepoStub) FindPrimary(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error) {
+	if s.findPrimaryFn != nil {
+		return s.findPrimaryFn(ctx, profileID, companyType)
 	}
 	return nil, nil
 }
 
+func (s *grpcCompanyRepoStub) FindByFiscalCode(context.Context, string) (*entity.Company, error) {
+	return nil, nil
+}
+
 func (s *grpcCompanyRepoStub) Update(ctx context.Context, company *entity.Company) error {
 	if s.updateFn != nil {
 		return s.updateFn(ctx, company)
@@ -171,50 +368,217 @@ func (s *grpcCompanyRepoStub) Update(ctx context.Context, company *entity.Compan
 	return nil
 }
 
-func (s *grpcCompanyRepoStub) Delete(ctx context.Context, id uint64) error {
+func (s *grpcCompanyRepoStub) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if s.deleteFn != nil {
-		return s.deleteFn(ctx, id)
+		return s.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (s *grpcCompanyRepoStub) DeleteByProfileID(context.Context, uint64) error { return nil }
+
+func (s *grpcCompanyRepoStub) Restore(ctx context.Context, id uint64) error {
+	if s.restoreFn != nil {
+		return s.restoreFn(ctx, id)
+	}
+	return nil
+}
+
+func (s *grpcCompanyRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error {
+	return nil
+}
+
+func (s *grpcCompanyRepoStub) List(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
+	if s.listFn != nil {
+		return s.listFn(ctx, profileID, companyType, attributes, includeDeleted, primaryOnly, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+func (s *grpcCompanyRepoStub) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	if s.listByProfileIDFn != nil {
+		return s.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (s *grpcCompanyRepoStub) ListForProfile(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	if s.listForProfileFn != nil {
+		return s.listForProfileFn(ctx, profileID)
 	}
+	return nil, nil
+}
+
+type grpcAuditRepoStub struct {
+	events []*entity.AuditEvent
+	listFn func(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error)
+}
+
+func (s *grpcAuditRepoStub) Create(_ context.Context, event *entity.AuditEvent) error {
+	s.events = append(s.events, event)
 	return nil
 }
 
-func (s *grpcCompanyRepoStub) List(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+func (s *grpcAuditRepoStub) List(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error) {
 	if s.listFn != nil {
-		return s.listFn(ctx, profileID, companyType, limit, offset)
+		return s.listFn(ctx, entityType, entityID, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+type grpcOutboxRepoStub struct{}
+
+func (s *grpcOutboxRepoStub) Create(context.Context, *entity.OutboxEvent) error { return nil }
+
+type grpcUnitOfWorkStub struct {
+	repos service.Repositories
+}
+
+func (u *grpcUnitOfWorkStub) Do(ctx context.Context, _ *sql.TxOptions, fn func(ctx context.Context, repos service.Repositories) error) error {
+	return fn(ctx, u.repos)
+}
+
+func newGRPCServer(profileRepo *grpcRepoStub, contactRepo *grpcContactRepoStub, addressRepo *grpcAddressRepoStub, companyRepo *grpcCompanyRepoStub) *ProfileServer {
+	return newGRPCServerWithAudit(profileRepo, contactRepo, addressRepo, companyRepo, &grpcAuditRepoStub{})
+}
+
+func newGRPCServerWithAudit(profileRepo *grpcRepoStub, contactRepo *grpcContactRepoStub, addressRepo *grpcAddressRepoStub, companyRepo *grpcCompanyRepoStub, auditRepo *grpcAuditRepoStub) *ProfileServer {
+	uow := &grpcUnitOfWorkStub{repos: service.Repositories{
+		Profiles:       profileRepo,
+		Contacts:       contactRepo,
+		Addresses:      addressRepo,
+		Companies:      companyRepo,
+		CompanyMembers: &grpcCompanyMemberRepoStub{},
+		Audit:          auditRepo,
+		Outbox:         &grpcOutboxRepoStub{},
+	}}
+	profileSvc := service.NewProfileService(profileRepo, uow, false, "", nil)
+	contactSvc := service.NewContactService(contactRepo, uow, "", nil)
+	addressSvc := service.NewAddressService(addressRepo, uow, nil, nil)
+	companySvc := service.NewCompanyService(companyRepo, uow, fiscal.NewValidators(""), nil)
+	preferenceSvc := service.NewPreferenceService(uow, nil)
+	auditSvc := service.NewAuditService(auditRepo)
+	return NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc, preferenceSvc, nil, auditSvc)
+}
+
 func newGRPCServerWithRepo(repo *grpcRepoStub) *ProfileServer {
-	profileSvc := service.NewProfileService(repo)
-	contactSvc := service.NewContactService(&grpcContactRepoStub{})
-	addressSvc := service.NewAddressService(&grpcAddressRepoStub{})
-	companySvc := service.NewCompanyService(&grpcCompanyRepoStub{})
-	return NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc)
+	return newGRPCServer(repo, &grpcContactRepoStub{}, &grpcAddressRepoStub{}, &grpcCompanyRepoStub{})
 }
 
 func newGRPCServerWithContactRepo(repo *grpcContactRepoStub) *ProfileServer {
-	profileSvc := service.NewProfileService(&grpcRepoStub{})
-	contactSvc := service.NewContactService(repo)
-	addressSvc := service.NewAddressService(&grpcAddressRepoStub{})
-	companySvc := service.NewCompanyService(&grpcCompanyRepoStub{})
-	return NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc)
+	return newGRPCServer(&grpcRepoStub{}, repo, &grpcAddressRepoStub{}, &grpcCompanyRepoStub{})
 }
 
 func newGRPCServerWithAddressRepo(repo *grpcAddressRepoStub) *ProfileServer {
-	profileSvc := service.NewProfileService(&grpcRepoStub{})
-	contactSvc := service.NewContactService(&grpcContactRepoStub{})
-	addressSvc := service.NewAddressService(repo)
-	companySvc := service.NewCompanyService(&grpcCompanyRepoStub{})
-	return NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc)
+	return newGRPCServer(&grpcRepoStub{}, &grpcContactRepoStub{}, repo, &grpcCompanyRepoStub{})
 }
 
 func newGRPCServerWithCompanyRepo(repo *grpcCompanyRepoStub) *ProfileServer {
-	profileSvc := service.NewProfileService(&grpcRepoStub{})
-	contactSvc := service.NewContactService(&grpcContactRepoStub{})
-	addressSvc := service.NewAddressService(&grpcAddressRepoStub{})
-	companySvc := service.NewCompanyService(repo)
-	return NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc)
+	return newGRPCServer(&grpcRepoStub{}, &grpcContactRepoStub{}, &grpcAddressRepoStub{}, repo)
+}
+
+func newGRPCServerWithCompanyMemberRepo(companyRepo *grpcCompanyRepoStub, memberRepo *grpcCompanyMemberRepoStub) *ProfileServer {
+	uow := &grpcUnitOfWorkStub{repos: service.Repositories{
+		Profiles:       &grpcRepoStub{},
+		Companies:      companyRepo,
+		CompanyMembers: memberRepo,
+		Audit:          &grpcAuditRepoStub{},
+		Outbox:         &grpcOutboxRepoStub{},
+	}}
+	companySvc := service.NewCompanyService(companyRepo, uow, fiscal.NewValidators(""), nil)
+	return NewProfileServer(nil, nil, nil, companySvc, nil, nil, nil)
+}
+
+type grpcAttachmentRepoStub struct {
+	attachments map[uint64]*entity.Attachment
+}
+
+func (s *grpcAttachmentRepoStub) Create(_ context.Context, attachment *entity.Attachment) error {
+	attachment.ID = uint64(len(s.attachments) + 1)
+	s.attachments[attachment.ID] = attachment
+	return nil
+}
+
+func (s *grpcAttachmentRepoStub) FindByID(_ context.Context, id uint64) (*entity.Attachment, error) {
+	return s.attachments[id], nil
+}
+
+func (s *grpcAttachmentRepoStub) Delete(_ context.Context, id uint64) error {
+	delete(s.attachments, id)
+	return nil
+}
+
+func (s *grpcAttachmentRepoStub) List(context.Context, string, uint64, string, uint32, uint32) ([]*entity.Attachment, uint64, error) {
+	return nil, 0, nil
+}
+
+type grpcUploadStreamStub struct {
+	grpcpkg.ServerStream
+	messages []*types.UploadAttachmentRequest
+	resp     *types.AttachmentResponse
+}
+
+func (s *grpcUploadStreamStub) Context() context.Context { return context.Background() }
+
+func (s *grpcUploadStreamStub) Recv() (*types.UploadAttachmentRequest, error) {
+	if len(s.messages) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.messages[0]
+	s.messages = s.messages[1:]
+	return msg, nil
+}
+
+func (s *grpcUploadStreamStub) SendAndClose(resp *types.AttachmentResponse) error {
+	s.resp = resp
+	return nil
+}
+
+type grpcDownloadStreamStub struct {
+	grpcpkg.ServerStream
+	sent []*types.DownloadAttachmentResponse
+}
+
+func (s *grpcDownloadStreamStub) Context() context.Context { return context.Background() }
+
+func (s *grpcDownloadStreamStub) Send(resp *types.DownloadAttachmentResponse) error {
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+// newGRPCServerWithAttachments stores attachments of live profiles under a temporary
+// directory, accepting PNG images of up to 64 bytes.
+func newGRPCServerWithAttachments(t *testing.T) *ProfileServer {
+	uow := &grpcUnitOfWorkStub{repos: service.Repositories{
+		Profiles: &grpcRepoStub{
+			findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+				return &entity.Profile{ID: id}, nil
+			},
+		},
+		Attachments: &grpcAttachmentRepoStub{attachments: map[uint64]*entity.Attachment{}},
+		Audit:       &grpcAuditRepoStub{},
+		Outbox:      &grpcOutboxRepoStub{},
+	}}
+	attachmentSvc := service.NewAttachmentService(uow, blobstore.NewLocal(t.TempDir()), service.AttachmentLimits{
+		MaxSize:      64,
+		ContentTypes: []string{"image/png"},
+	})
+	return NewProfileServer(nil, nil, nil, nil, nil, attachmentSvc, nil)
+}
+
+// newGRPCServerWithPreferenceRepo serves preferences of any live profile, without schemas.
+func newGRPCServerWithPreferenceRepo(repo *grpcPreferenceRepoStub) *ProfileServer {
+	uow := &grpcUnitOfWorkStub{repos: service.Repositories{
+		Profiles: &grpcRepoStub{
+			findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+				return &entity.Profile{ID: id}, nil
+			},
+		},
+		Preferences: repo,
+		Audit:       &grpcAuditRepoStub{},
+		Outbox:      &grpcOutboxRepoStub{},
+	}}
+	return NewProfileServer(nil, nil, nil, nil, service.NewPreferenceService(uow, nil), nil, nil)
 }
 
 func TestCreateProfileInvalidArgument(t *testing.T) {
@@ -227,7 +591,7 @@ func TestCreateProfileInvalidArgument(t *testing.T) {
 
 func TestCreateProfileAlreadyExists(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		findByUserIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: 9, UserID: 77}, nil
 		},
 	})
@@ -249,9 +613,50 @@ func TestGetProfileNotFound(t *testing.T) {
 	}
 }
 
+func TestGetProfileBundleSuccess(t *testing.T) {
+	server := newGRPCServer(
+		&grpcRepoStub{
+			findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+				return &entity.Profile{ID: id, UserID: 44}, nil
+			},
+		},
+		&grpcContactRepoStub{},
+		&grpcAddressRepoStub{},
+		&grpcCompanyRepoStub{
+			listForProfileFn: func(_ context.Context, profileID uint64) ([]*entity.Company, error) {
+				return []*entity.Company{{ID: 3, ProfileID: profileID, Name: "ACME"}}, nil
+			},
+		},
+	)
+
+	resp, err := server.GetProfileBundle(context.Background(), &types.GetProfileBundleRequest{Id: 11, Include: []string{"companies"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if resp.GetProfile().GetId() != 11 || len(resp.GetCompanies()) != 1 || resp.GetCompanies()[0].GetName() != "ACME" {
+		t.Fatalf("unexpected bundle response: %+v", resp)
+	}
+}
+
+func TestGetProfileBundleNotFound(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{})
+	_, err := server.GetProfileBundle(context.Background(), &types.GetProfileBundleRequest{Id: 100})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestGetProfileBundleInvalidArgument(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{})
+	_, err := server.GetProfileBundle(context.Background(), &types.GetProfileBundleRequest{Id: 1, Include: []string{"orders"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+}
+
 func TestUpdateProfileSuccess(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		findByIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{
 				ID:     11,
 				UserID: 44,
@@ -274,7 +679,7 @@ func TestUpdateProfileSuccess(t *testing.T) {
 
 func TestDeleteProfileNotFound(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrProfileNotFound
 		},
 	})
@@ -287,7 +692,10 @@ func TestDeleteProfileNotFound(t *testing.T) {
 
 func TestDeleteProfileInternal(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return errors.New("db down")
 		},
 	})
@@ -344,7 +752,7 @@ func TestGetProfileInvalidArgument(t *testing.T) {
 
 func TestGetProfileInternal(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		findByIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return nil, errors.New("db down")
 		},
 	})
@@ -373,7 +781,7 @@ func TestGetProfileByUserIDNotFound(t *testing.T) {
 
 func TestGetProfileByUserIDSuccess(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		findByUserIDFn: func(_ context.Context, userID uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, userID uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: 2, UserID: userID, Email: "john@example.com"}, nil
 		},
 	})
@@ -389,7 +797,7 @@ func TestGetProfileByUserIDSuccess(t *testing.T) {
 
 func TestGetProfileByUserIDInternal(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		findByUserIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return nil, errors.New("db down")
 		},
 	})
@@ -400,6 +808,38 @@ func TestGetProfileByUserIDInternal(t *testing.T) {
 	}
 }
 
+func TestGetProfileByEmail(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{
+		findByEmailFn: func(_ context.Context, email string, _ bool) (*entity.Profile, error) {
+			if email != "john@müller.de" {
+				return nil, nil
+			}
+			return &entity.Profile{ID: 2, UserID: 42, Email: email}, nil
+		},
+	})
+
+	resp, err := server.GetProfileByEmail(context.Background(), &types.GetProfileByEmailRequest{Email: "john@xn--mller-kva.DE"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if resp.GetId() != 2 || resp.GetEmail() != "john@müller.de" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	_, err = server.GetProfileByEmail(context.Background(), &types.GetProfileByEmailRequest{Email: "jane@example.com"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+	_, err = server.GetProfileByEmail(context.Background(), &types.GetProfileByEmailRequest{Email: "john"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+	_, err = server.GetProfileByEmail(context.Background(), &types.GetProfileByEmailRequest{Email: "john@example.com", IncludeDeleted: true})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected codes.PermissionDenied, got %s", status.Code(err))
+	}
+}
+
 func TestUpdateProfileInvalidArgument(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{})
 	_, err := server.UpdateProfile(context.Background(), &types.UpdateProfileRequest{})
@@ -421,7 +861,7 @@ func TestUpdateProfileNotFound(t *testing.T) {
 
 func TestUpdateProfileInternal(t *testing.T) {
 	server := newGRPCServerWithRepo(&grpcRepoStub{
-		findByIDFn: func(_ context.Context, id uint64) (*entity.Profile, error) {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: id, UserID: 1, Email: "old@example.com"}, nil
 		},
 		updateFn: func(_ context.Context, _ *entity.Profile) error {
@@ -446,7 +886,11 @@ func TestDeleteProfileInvalidArgument(t *testing.T) {
 }
 
 func TestDeleteProfileSuccess(t *testing.T) {
-	server := newGRPCServerWithRepo(&grpcRepoStub{})
+	server := newGRPCServerWithRepo(&grpcRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+	})
 	resp, err := server.DeleteProfile(context.Background(), &types.DeleteProfileRequest{Id: 15})
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -465,19 +909,25 @@ func TestCreateContactInvalidArgument(t *testing.T) {
 }
 
 func TestCreateContactSuccess(t *testing.T) {
-	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+	contactRepo := &grpcContactRepoStub{
 		createFn: func(_ context.Context, contact *entity.Contact) error {
 			contact.ID = 77
 			return nil
 		},
-	})
+	}
+	addressRepo := &grpcAddressRepoStub{
+		listByProfileIDFn: func(_ context.Context, _ uint64) ([]*entity.Address, error) {
+			return []*entity.Address{{Country: "RO"}}, nil
+		},
+	}
+	server := newGRPCServer(&grpcRepoStub{}, contactRepo, addressRepo, &grpcCompanyRepoStub{})
 
 	resp, err := server.CreateContact(context.Background(), &types.CreateContactRequest{
 		FirstName: "John",
 		LastName:  "Doe",
 		Nin:       "1234",
 		Dob:       "1990-01-02",
-		Phone:     "123456",
+		Phone:     "0722 123 456",
 		ProfileId: 9,
 	})
 	if err != nil {
@@ -486,6 +936,46 @@ func TestCreateContactSuccess(t *testing.T) {
 	if resp.GetId() != 77 {
 		t.Fatalf("expected id 77, got %d", resp.GetId())
 	}
+	if resp.GetPhone() != "0722 123 456" || resp.GetPhoneE164() != "+40722123456" || resp.GetPhoneType() != "mobile" {
+		t.Fatalf("unexpected phone fields: %+v", resp)
+	}
+}
+
+func TestCreateContactInvalidPhone(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+		createFn: func(_ context.Context, _ *entity.Contact) error {
+			t.Fatal("create must not be called")
+			return nil
+		},
+	})
+
+	_, err := server.CreateContact(context.Background(), &types.CreateContactRequest{ProfileId: 9, Phone: "0722 123 456"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+}
+
+func TestCreateContactDOBMismatchHasFieldViolation(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{})
+
+	_, err := server.CreateContact(context.Background(), &types.CreateContactRequest{
+		ProfileId:  9,
+		Nin:        "1800101120017",
+		NinCountry: "RO",
+		Dob:        "1990-01-02",
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", st.Code())
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("expected one detail, got %v", details)
+	}
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	if !ok || len(badRequest.GetFieldViolations()) != 1 || badRequest.GetFieldViolations()[0].GetField() != "dob" {
+		t.Fatalf("expected a dob field violation, got %v", details)
+	}
 }
 
 func TestGetContactNotFound(t *testing.T) {
@@ -498,7 +988,7 @@ func TestGetContactNotFound(t *testing.T) {
 
 func TestListContactsSuccess(t *testing.T) {
 	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
-		listFn: func(_ context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+		listFn: func(_ context.Context, profileID uint64, contactType, _ string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 			if profileID != 9 || contactType != "emergency" || limit != 10 || offset != 0 {
 				t.Fatalf("unexpected list args profileID=%d contactType=%q limit=%d offset=%d", profileID, contactType, limit, offset)
 			}
@@ -549,8 +1039,8 @@ func TestCreateAddressSuccess(t *testing.T) {
 		StreetName: "Street",
 		StreenNo:   "10",
 		City:       "City",
-		County:     "County",
-		Country:    "Country",
+		County:     "Cluj",
+		Country:    "RO",
 		ProfileId:  9,
 	})
 	if err != nil {
@@ -561,6 +1051,31 @@ func TestCreateAddressSuccess(t *testing.T) {
 	}
 }
 
+func TestCreateAddressInvalidCountyHasFieldViolation(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{})
+
+	_, err := server.CreateAddress(context.Background(), &types.CreateAddressRequest{
+		StreetName: "Street",
+		StreenNo:   "10",
+		City:       "City",
+		County:     "Bavaria",
+		Country:    "RO",
+		ProfileId:  9,
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", st.Code())
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("expected one detail, got %v", details)
+	}
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	if !ok || len(badRequest.GetFieldViolations()) != 1 || badRequest.GetFieldViolations()[0].GetField() != "county" {
+		t.Fatalf("expected a county field violation, got %v", details)
+	}
+}
+
 func TestGetAddressNotFound(t *testing.T) {
 	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{})
 	_, err := server.GetAddress(context.Background(), &types.GetAddressRequest{Id: 5})
@@ -569,9 +1084,24 @@ func TestGetAddressNotFound(t *testing.T) {
 	}
 }
 
+func TestGetAddressFormatted(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, StreetName: "Main St", StreenNo: "123", City: "Sacramento", County: "CA", PostalCode: "95814", Country: "US"}, nil
+		},
+	})
+	resp, err := server.GetAddress(context.Background(), &types.GetAddressRequest{Id: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := resp.GetFormatted().GetMultiLine(); got != "123 Main St\nSacramento, CA 95814\nUNITED STATES" {
+		t.Fatalf("unexpected formatted address: %q", got)
+	}
+}
+
 func TestListAddressesSuccess(t *testing.T) {
 	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
-		listFn: func(_ context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+		listFn: func(_ context.Context, profileID, _ uint64, addressType string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 			if profileID != 9 || addressType != "billing" || limit != 10 || offset != 0 {
 				t.Fatalf("unexpected list args profileID=%d addressType=%q limit=%d offset=%d", profileID, addressType, limit, offset)
 			}
@@ -581,8 +1111,8 @@ func TestListAddressesSuccess(t *testing.T) {
 					StreetName: "Street",
 					StreenNo:   "10",
 					City:       "City",
-					County:     "County",
-					Country:    "Country",
+					County:     "Cluj",
+					Country:    "RO",
 					ProfileID:  9,
 				},
 			}, 1, nil
@@ -621,8 +1151,8 @@ func TestCreateCompanySuccess(t *testing.T) {
 
 	resp, err := server.CreateCompany(context.Background(), &types.CreateCompanyRequest{
 		Name:           "ACME",
-		RegistrationNo: "REG-1",
-		FiscalCode:     "FISC-1",
+		RegistrationNo: "J40/123/2020",
+		FiscalCode:     "RO18547290",
 		ProfileId:      9,
 	})
 	if err != nil {
@@ -631,6 +1161,32 @@ func TestCreateCompanySuccess(t *testing.T) {
 	if resp.GetId() != 33 {
 		t.Fatalf("expected id 33, got %d", resp.GetId())
 	}
+	if !resp.GetFiscalCodeValid() || resp.GetVatPayerPrefix() != "RO" {
+		t.Fatalf("expected a valid RO fiscal code, got %+v", resp)
+	}
+}
+
+func TestCreateCompanyInvalidFiscalCodeHasFieldViolation(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{})
+
+	_, err := server.CreateCompany(context.Background(), &types.CreateCompanyRequest{
+		Name:           "ACME",
+		RegistrationNo: "J40/123/2020",
+		FiscalCode:     "RO18547291",
+		ProfileId:      9,
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", st.Code())
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("expected one detail, got %v", details)
+	}
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	if !ok || len(badRequest.GetFieldViolations()) != 1 || badRequest.GetFieldViolations()[0].GetField() != "fiscal_code" {
+		t.Fatalf("expected a fiscal_code field violation, got %v", details)
+	}
 }
 
 func TestGetCompanyNotFound(t *testing.T) {
@@ -643,7 +1199,7 @@ func TestGetCompanyNotFound(t *testing.T) {
 
 func TestListCompaniesSuccess(t *testing.T) {
 	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
-		listFn: func(_ context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+		listFn: func(_ context.Context, profileID uint64, companyType string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
 			if profileID != 9 || companyType != "vendor" || limit != 10 || offset != 0 {
 				t.Fatalf("unexpected list args profileID=%d companyType=%q limit=%d offset=%d", profileID, companyType, limit, offset)
 			}
@@ -651,8 +1207,8 @@ func TestListCompaniesSuccess(t *testing.T) {
 				{
 					ID:             1,
 					Name:           "ACME",
-					RegistrationNo: "REG-1",
-					FiscalCode:     "FISC-1",
+					RegistrationNo: "J40/123/2020",
+					FiscalCode:     "RO18547290",
 					ProfileID:      9,
 				},
 			}, 1, nil
@@ -672,3 +1228,606 @@ func TestListCompaniesSuccess(t *testing.T) {
 		t.Fatalf("unexpected list response: %+v", resp)
 	}
 }
+
+func TestCreateContactProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+		createFn: func(_ context.Context, _ *entity.Contact) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.CreateContact(context.Background(), &types.CreateContactRequest{ProfileId: 404})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestUpdateContactTargetProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Contact) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.UpdateContact(context.Background(), &types.UpdateContactRequest{Id: 3, ProfileId: 404})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected codes.FailedPrecondition, got %s", status.Code(err))
+	}
+}
+
+func TestCreateAddressProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		createFn: func(_ context.Context, _ *entity.Address) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.CreateAddress(context.Background(), &types.CreateAddressRequest{StreetName: "Main", StreenNo: "1", City: "City", County: "Cluj", Country: "RO", ProfileId: 404})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestUpdateAddressTargetProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Address) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.UpdateAddress(context.Background(), &types.UpdateAddressRequest{Id: 3, StreetName: "Main", StreenNo: "1", City: "City", County: "Cluj", Country: "RO", ProfileId: 404})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected codes.FailedPrecondition, got %s", status.Code(err))
+	}
+}
+
+func TestCreateCompanyProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		createFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.CreateCompany(context.Background(), &types.CreateCompanyRequest{Name: "ACME", RegistrationNo: "J40/1/2020", FiscalCode: "RO18547290", ProfileId: 404})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestUpdateCompanyTargetProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.UpdateCompany(context.Background(), &types.UpdateCompanyRequest{Id: 3, Name: "ACME", RegistrationNo: "J40/1/2020", FiscalCode: "RO18547290", ProfileId: 404})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected codes.FailedPrecondition, got %s", status.Code(err))
+	}
+}
+
+func TestPatchProfileSuccess(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, UserID: 44, Email: "old@example.com"}, nil
+		},
+	})
+
+	resp, err := server.PatchProfile(context.Background(), &types.PatchProfileRequest{
+		Id:         11,
+		Email:      "new@example.com",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if resp.GetEmail() != "new@example.com" || resp.GetUserId() != 44 {
+		t.Fatalf("unexpected patched profile: %+v", resp)
+	}
+}
+
+func TestPatchContactKeepsUnmaskedFields(t *testing.T) {
+	var saved *entity.Contact
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, FirstName: "John", Phone: "111", ProfileID: 7}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	})
+
+	resp, err := server.PatchContact(context.Background(), &types.PatchContactRequest{
+		Id:         3,
+		FirstName:  "ignored",
+		Phone:      "+40722123456",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"phone"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if resp.GetPhone() != "+40722123456" || resp.GetPhoneE164() != "+40722123456" || saved.FirstName != "John" || saved.ProfileID != 7 {
+		t.Fatalf("unexpected patched contact: %+v", saved)
+	}
+}
+
+func TestPatchContactInvalidMask(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{})
+
+	for _, mask := range []*fieldmaskpb.FieldMask{nil, {Paths: []string{"created_at"}}} {
+		_, err := server.PatchContact(context.Background(), &types.PatchContactRequest{Id: 3, UpdateMask: mask})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected codes.InvalidArgument for mask %v, got %s", mask, status.Code(err))
+		}
+	}
+}
+
+func TestPatchAddressNotFound(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{})
+
+	_, err := server.PatchAddress(context.Background(), &types.PatchAddressRequest{
+		Id:         3,
+		City:       "Cluj",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"city"}},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestPatchCompanyTargetProfileNotFound(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := server.PatchCompany(context.Background(), &types.PatchCompanyRequest{
+		Id:         3,
+		ProfileId:  404,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"profile_id"}},
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected codes.FailedPrecondition, got %s", status.Code(err))
+	}
+}
+
+func TestDeleteProfileVersionConflict(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+		deleteFn: func(_ context.Context, _, expectedVersion uint64) error {
+			if expectedVersion != 2 {
+				t.Fatalf("expected version 2, got %d", expectedVersion)
+			}
+			return repository.ErrVersionConflict
+		},
+	})
+
+	_, err := server.DeleteProfile(context.Background(), &types.DeleteProfileRequest{Id: 15, ExpectedVersion: 2})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected codes.Aborted, got %s", status.Code(err))
+	}
+}
+
+func TestPatchCompanyStaleExpectedVersion(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", ProfileID: 1, Version: 4}, nil
+		},
+	})
+
+	_, err := server.PatchCompany(context.Background(), &types.PatchCompanyRequest{
+		Id:              3,
+		Name:            "ACME SRL",
+		ExpectedVersion: 3,
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Fatalf("expected codes.Aborted, got %s", status.Code(err))
+	}
+}
+
+func TestGetCompanyReturnsVersion(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", ProfileID: 1, Version: 4}, nil
+		},
+	})
+
+	resp, err := server.GetCompany(context.Background(), &types.GetCompanyRequest{Id: 3})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if resp.GetVersion() != 4 {
+		t.Fatalf("expected version 4, got %d", resp.GetVersion())
+	}
+}
+
+func TestGetCompanyAppliesFieldPolicy(t *testing.T) {
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", FiscalCode: "RO12345678", ProfileID: 1}, nil
+		},
+	})
+	policy, err := fieldpolicy.Parse("", "billing-service=company.fiscal_code:masked;crm=company.fiscal_code:hidden")
+	if err != nil {
+		t.Fatalf("fieldpolicy.Parse() returned error: %v", err)
+	}
+
+	cases := map[string]string{"billing-service": "******5678", "crm": "", "backoffice": "RO12345678"}
+	for service, want := range cases {
+		ctx := fieldpolicy.NewContext(context.Background(), policy.For(service))
+		resp, err := server.GetCompany(ctx, &types.GetCompanyRequest{Id: 3})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if resp.GetFiscalCode() != want {
+			t.Fatalf("%s: expected fiscal code %q, got %q", service, want, resp.GetFiscalCode())
+		}
+	}
+}
+
+func adminContext() context.Context {
+	return caller.NewContext(context.Background(), caller.Identity{Service: "admin-service", Admin: true})
+}
+
+func TestGetProfileIncludeDeletedPermissionDenied(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{})
+	_, err := server.GetProfile(context.Background(), &types.GetProfileRequest{Id: 3, IncludeDeleted: true})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected codes.PermissionDenied, got %s", status.Code(err))
+	}
+}
+
+func TestRestoreProfilePermissionDenied(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{})
+	_, err := server.RestoreProfile(context.Background(), &types.RestoreProfileRequest{Id: 3})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected codes.PermissionDenied, got %s", status.Code(err))
+	}
+}
+
+func TestRestoreProfileNotDeleted(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, Version: 1}, nil
+		},
+	})
+	_, err := server.RestoreProfile(adminContext(), &types.RestoreProfileRequest{Id: 3})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected codes.FailedPrecondition, got %s", status.Code(err))
+	}
+}
+
+func TestRestoreAddressWhileProfileDeleted(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		restoreFn: func(context.Context, uint64) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+	_, err := server.RestoreAddress(adminContext(), &types.RestoreAddressRequest{Id: 4})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected codes.FailedPrecondition, got %s", status.Code(err))
+	}
+}
+
+func TestRestoreContactSuccess(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 1, Version: 3}, nil
+		},
+	})
+	resp, err := server.RestoreContact(adminContext(), &types.RestoreContactRequest{Id: 8})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.GetVersion() != 3 || resp.GetDeletedAt() != "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSetPrimaryContactSuccess(t *testing.T) {
+	server := newGRPCServerWithContactRepo(&grpcContactRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 1, Type: "emergency", Version: 3}, nil
+		},
+		findPrimaryFn: func(_ context.Context, profileID uint64, contactType string) (*entity.Contact, error) {
+			return &entity.Contact{ID: 9, ProfileID: profileID, Type: contactType, IsPrimary: true, Version: 1}, nil
+		},
+	})
+	resp, err := server.SetPrimaryContact(context.Background(), &types.SetPrimaryContactRequest{Id: 8, ExpectedVersion: 3})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.GetId() != 8 || !resp.GetIsPrimary() {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSetPrimaryAddressErrors(t *testing.T) {
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			if id == 404 {
+				return nil, nil
+			}
+			return &entity.Address{ID: id, ProfileID: 1, Type: "billing", Version: 2}, nil
+		},
+		updateFn: func(context.Context, *entity.Address) error {
+			return repository.ErrPrimaryConflict
+		},
+	})
+
+	cases := []struct {
+		req  *types.SetPrimaryAddressRequest
+		code codes.Code
+	}{
+		{&types.SetPrimaryAddressRequest{}, codes.InvalidArgument},
+		{&types.SetPrimaryAddressRequest{Id: 404}, codes.NotFound},
+		{&types.SetPrimaryAddressRequest{Id: 4, ExpectedVersion: 1}, codes.Aborted},
+		{&types.SetPrimaryAddressRequest{Id: 4}, codes.Aborted},
+	}
+	for _, tc := range cases {
+		if _, err := server.SetPrimaryAddress(context.Background(), tc.req); status.Code(err) != tc.code {
+			t.Fatalf("%+v: expected %s, got %s", tc.req, tc.code, status.Code(err))
+		}
+	}
+}
+
+func TestListCompaniesIncludeDeletedForAdmin(t *testing.T) {
+	deletedAt := time.Date(2026, 2, 1, 8, 30, 0, 0, time.UTC)
+	server := newGRPCServerWithCompanyRepo(&grpcCompanyRepoStub{
+		listFn: func(_ context.Context, _ uint64, _ string, _ map[string]string, includeDeleted, _ bool, _, _ uint32) ([]*entity.Company, uint64, error) {
+			if !includeDeleted {
+				t.Fatal("expected include_deleted to reach the repository")
+			}
+			return []*entity.Company{{ID: 2, DeletedAt: &deletedAt}}, 1, nil
+		},
+	})
+	resp, err := server.ListCompanies(adminContext(), &types.ListCompaniesRequest{ProfileId: 1, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := resp.GetCompanies()[0].GetDeletedAt(); got != "2026-02-01T08:30:00Z" {
+		t.Fatalf("expected deleted_at, got %q", got)
+	}
+}
+
+func TestListAuditEventsPermissionDenied(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{})
+
+	_, err := server.ListAuditEvents(context.Background(), &types.ListAuditEventsRequest{Entity: "profile", EntityId: 5})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestListAuditEventsInvalidArgument(t *testing.T) {
+	server := newGRPCServerWithRepo(&grpcRepoStub{})
+
+	_, err := server.ListAuditEvents(adminContext(), &types.ListAuditEventsRequest{Entity: "user", EntityId: 5})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestCreateCompanyRecordsAuditEvent(t *testing.T) {
+	auditRepo := &grpcAuditRepoStub{}
+	companyRepo := &grpcCompanyRepoStub{
+		createFn: func(_ context.Context, company *entity.Company) error {
+			company.ID = 14
+			return nil
+		},
+	}
+	server := newGRPCServerWithAudit(&grpcRepoStub{}, &grpcContactRepoStub{}, &grpcAddressRepoStub{}, companyRepo, auditRepo)
+	ctx := caller.NewContext(context.Background(), caller.Identity{Service: "billing-service", RequestID: "grpc-1"})
+
+	_, err := server.CreateCompany(ctx, &types.CreateCompanyRequest{
+		Name:           "ACME",
+		RegistrationNo: "J40/123/2020",
+		FiscalCode:     "RO18547290",
+		ProfileId:      3,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(auditRepo.events) != 2 {
+		t.Fatalf("expected two audit events, got %d", len(auditRepo.events))
+	}
+	event := auditRepo.events[0]
+	if event.EntityType != "company" || event.EntityID != 14 || event.Action != "create" || event.CallerService != "billing-service" || event.RequestID != "grpc-1" {
+		t.Fatalf("unexpected audit event: %+v", event)
+	}
+	if owner := auditRepo.events[1]; owner.EntityType != "company_member" || owner.EntityID != 14 || owner.Action != "create" {
+		t.Fatalf("unexpected owner audit event: %+v", owner)
+	}
+}
+
+func TestListAuditEventsSuccess(t *testing.T) {
+	server := newGRPCServerWithAudit(&grpcRepoStub{}, &grpcContactRepoStub{}, &grpcAddressRepoStub{}, &grpcCompanyRepoStub{}, &grpcAuditRepoStub{
+		listFn: func(_ context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error) {
+			if entityType != "address" || entityID != 4 || limit != 10 || offset != 10 {
+				t.Fatalf("unexpected list args entityType=%q entityID=%d limit=%d offset=%d", entityType, entityID, limit, offset)
+			}
+			return []*entity.AuditEvent{{ID: 7, EntityType: entityType, EntityID: entityID, Action: "delete", OldValues: []byte(`{"city":"Cluj"}`)}}, 11, nil
+		},
+	})
+
+	resp, err := server.ListAuditEvents(adminContext(), &types.ListAuditEventsRequest{Entity: "address", EntityId: 4, Page: 2, PageSize: 10})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.GetTotal() != 11 || len(resp.GetEvents()) != 1 || resp.GetEvents()[0].GetOldValues() != `{"city":"Cluj"}` || resp.GetEvents()[0].GetNewValues() != "" {
+		t.Fatalf("unexpected list response: %+v", resp)
+	}
+}
+
+func TestSearchAddressesNear(t *testing.T) {
+	latitude, longitude := 46.7694, 23.5899
+	server := newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		searchNearFn: func(_ context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+			if search.Box == nil || search.Center != nil || search.Type != "billing" {
+				t.Fatalf("unexpected search: %+v", search)
+			}
+			return []*entity.NearbyAddress{{Address: &entity.Address{ID: 8, Latitude: &latitude, Longitude: &longitude}}}, nil
+		},
+	})
+	req := &types.SearchAddressesNearRequest{
+		Bbox: &types.BoundingBox{MinLatitude: 46.7, MinLongitude: 23.5, MaxLatitude: 46.8, MaxLongitude: 23.7},
+		Type: "billing",
+	}
+
+	if _, err := server.SearchAddressesNear(context.Background(), req); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied across profiles, got %v", err)
+	}
+	resp, err := server.SearchAddressesNear(adminContext(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(resp.GetAddresses()) != 1 || resp.GetAddresses()[0].GetAddress().GetLatitude() != latitude {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if _, err = server.SearchAddressesNear(adminContext(), &types.SearchAddressesNearRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestListCompanyMembersSuccess(t *testing.T) {
+	created := time.Date(2026, time.March, 1, 10, 30, 0, 0, time.UTC)
+	memberRepo := &grpcCompanyMemberRepoStub{
+		listFn: func(_ context.Context, companyID uint64, limit, offset uint32) ([]*entity.CompanyMember, uint64, error) {
+			if companyID != 5 || limit != 20 || offset != 0 {
+				t.Fatalf("unexpected list args companyID=%d limit=%d offset=%d", companyID, limit, offset)
+			}
+			return []*entity.CompanyMember{{CompanyID: 5, ProfileID: 8, Role: entity.CompanyRoleAdmin, CreatedAt: created, UpdatedAt: created}}, 1, nil
+		},
+	}
+	server := newGRPCServerWithCompanyMemberRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id}, nil
+		},
+	}, memberRepo)
+
+	resp, err := server.ListCompanyMembers(context.Background(), &types.ListCompanyMembersRequest{CompanyId: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.GetTotal() != 1 || len(resp.GetMembers()) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if member := resp.GetMembers()[0]; member.GetProfileId() != 8 || member.GetRole() != "admin" || member.GetCreatedAt() != "2026-03-01T10:30:00Z" {
+		t.Fatalf("unexpected member: %+v", member)
+	}
+
+	_, err = server.ListCompanyMembers(context.Background(), &types.ListCompanyMembersRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+}
+
+func TestAddCompanyMemberRejectsUnknownRole(t *testing.T) {
+	server := newGRPCServerWithCompanyMemberRepo(&grpcCompanyRepoStub{}, &grpcCompanyMemberRepoStub{})
+
+	_, err := server.AddCompanyMember(context.Background(), &types.AddCompanyMemberRequest{CompanyId: 5, ProfileId: 8, Role: "auditor"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+}
+
+func TestAddCompanyMemberCompanyNotFound(t *testing.T) {
+	server := newGRPCServerWithCompanyMemberRepo(&grpcCompanyRepoStub{}, &grpcCompanyMemberRepoStub{})
+
+	_, err := server.AddCompanyMember(context.Background(), &types.AddCompanyMemberRequest{CompanyId: 5, ProfileId: 8, Role: "viewer"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestRemoveCompanyMemberNotFound(t *testing.T) {
+	server := newGRPCServerWithCompanyMemberRepo(&grpcCompanyRepoStub{}, &grpcCompanyMemberRepoStub{})
+
+	_, err := server.RemoveCompanyMember(context.Background(), &types.RemoveCompanyMemberRequest{CompanyId: 5, ProfileId: 8})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+// pngHeader is the start of a PNG image, enough for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestUploadAndDownloadAttachment(t *testing.T) {
+	server := newGRPCServerWithAttachments(t)
+	upload := &grpcUploadStreamStub{messages: []*types.UploadAttachmentRequest{
+		{Data: &types.UploadAttachmentRequest_Metadata{Metadata: &types.AttachmentUpload{
+			EntityType: "profile", EntityId: 7, Kind: "avatar", Filename: "me.png", ContentType: "application/octet-stream",
+		}}},
+		{Data: &types.UploadAttachmentRequest_Chunk{Chunk: pngHeader[:8]}},
+		{Data: &types.UploadAttachmentRequest_Chunk{Chunk: pngHeader[8:]}},
+	}}
+	if err := server.UploadAttachment(upload); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if upload.resp.GetContentType() != "image/png" || upload.resp.GetSize() != int64(len(pngHeader)) || upload.resp.GetKind() != "avatar" {
+		t.Fatalf("unexpected upload response: %+v", upload.resp)
+	}
+
+	download := &grpcDownloadStreamStub{}
+	if err := server.DownloadAttachment(&types.DownloadAttachmentRequest{Id: upload.resp.GetId()}, download); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if len(download.sent) != 2 || download.sent[0].GetMetadata().GetSha256() != upload.resp.GetSha256() {
+		t.Fatalf("unexpected download messages: %+v", download.sent)
+	}
+	if string(download.sent[1].GetChunk()) != string(pngHeader) {
+		t.Fatalf("unexpected content: %q", download.sent[1].GetChunk())
+	}
+}
+
+func TestUploadAttachmentRejections(t *testing.T) {
+	metadata := &types.UploadAttachmentRequest{Data: &types.UploadAttachmentRequest_Metadata{Metadata: &types.AttachmentUpload{
+		EntityType: "profile", EntityId: 7,
+	}}}
+	tests := []struct {
+		name     string
+		messages []*types.UploadAttachmentRequest
+		code     codes.Code
+	}{
+		{"no metadata", []*types.UploadAttachmentRequest{{Data: &types.UploadAttachmentRequest_Chunk{Chunk: pngHeader}}}, codes.InvalidArgument},
+		{"metadata twice", []*types.UploadAttachmentRequest{metadata, metadata}, codes.InvalidArgument},
+		{"too large", []*types.UploadAttachmentRequest{metadata, {Data: &types.UploadAttachmentRequest_Chunk{Chunk: make([]byte, 65)}}}, codes.ResourceExhausted},
+		{"unsupported type", []*types.UploadAttachmentRequest{metadata, {Data: &types.UploadAttachmentRequest_Chunk{Chunk: []byte("%PDF-1.7")}}}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newGRPCServerWithAttachments(t).UploadAttachment(&grpcUploadStreamStub{messages: tt.messages})
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestGetAttachmentNotFound(t *testing.T) {
+	_, err := newGRPCServerWithAttachments(t).GetAttachment(context.Background(), &types.GetAttachmentRequest{Id: 3})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ProfileServerV2 serves profile.v2.ProfileService. Each call is converted to its v1
+// counterpart and back, so both versions share validation, status codes and field policies.
+type ProfileServerV2 struct {
+	typesv2.UnimplementedProfileServiceServer
+	v1 *ProfileServer
+}
+
+func NewProfileServerV2(v1 *ProfileServer) *ProfileServerV2 {
+	return &ProfileServerV2{v1: v1}
+}
+
+func (s *ProfileServerV2) CreateProfile(ctx context.Context, pbReq *typesv2.CreateProfileRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.CreateProfile, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) GetProfile(ctx context.Context, pbReq *typesv2.GetProfileRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetProfile, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) GetProfileByUserID(ctx context.Context, pbReq *typesv2.GetProfileByUserIDRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetProfileByUserID, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) GetProfileByEmail(ctx context.Context, pbReq *typesv2.GetProfileByEmailRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetProfileByEmail, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) UpdateProfile(ctx context.Context, pbReq *typesv2.UpdateProfileRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.UpdateProfile, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) PatchProfile(ctx context.Context, pbReq *typesv2.PatchProfileRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.PatchProfile, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) DeleteProfile(ctx context.Context, pbReq *typesv2.DeleteProfileRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.DeleteProfile)
+}
+
+func (s *ProfileServerV2) RestoreProfile(ctx context.Context, pbReq *typesv2.RestoreProfileRequest) (*typesv2.ProfileResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.RestoreProfile, &typesv2.ProfileResponse{})
+}
+
+func (s *ProfileServerV2) GetProfileBundle(ctx context.Context, pbReq *typesv2.GetProfileBundleRequest) (*typesv2.ProfileBundleResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetProfileBundle, &typesv2.ProfileBundleResponse{})
+}
+
+func (s *ProfileServerV2) CreateContact(ctx context.Context, pbReq *typesv2.CreateContactRequest) (*typesv2.ContactResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.CreateContact, &typesv2.ContactResponse{})
+}
+
+func (s *ProfileServerV2) GetContact(ctx context.Context, pbReq *typesv2.GetContactRequest) (*typesv2.ContactResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetContact, &typesv2.ContactResponse{})
+}
+
+func (s *ProfileServerV2) UpdateContact(ctx context.Context, pbReq *typesv2.UpdateContactRequest) (*typesv2.ContactResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.UpdateContact, &typesv2.ContactResponse{})
+}
+
+func (s *ProfileServerV2) PatchContact(ctx context.Context, pbReq *typesv2.PatchContactRequest) (*typesv2.ContactResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.PatchContact, &typesv2.ContactResponse{})
+}
+
+func (s *ProfileServerV2) DeleteContact(ctx context.Context, pbReq *typesv2.DeleteContactRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.DeleteContact)
+}
+
+func (s *ProfileServerV2) RestoreContact(ctx context.Context, pbReq *typesv2.RestoreContactRequest) (*typesv2.ContactResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.RestoreContact, &typesv2.ContactResponse{})
+}
+
+func (s *ProfileServerV2) SetPrimaryContact(ctx context.Context, pbReq *typesv2.SetPrimaryContactRequest) (*typesv2.ContactResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.SetPrimaryContact, &typesv2.ContactResponse{})
+}
+
+func (s *ProfileServerV2) ListContacts(ctx context.Context, pbReq *typesv2.ListContactsRequest) (*typesv2.ListContactsResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListContacts, &typesv2.ListContactsResponse{})
+}
+
+func (s *ProfileServerV2) CreateAddress(ctx context.Context, pbReq *typesv2.CreateAddressRequest) (*typesv2.AddressResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.CreateAddress, &typesv2.AddressResponse{})
+}
+
+func (s *ProfileServerV2) GetAddress(ctx context.Context, pbReq *typesv2.GetAddressRequest) (*typesv2.AddressResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetAddress, &typesv2.AddressResponse{})
+}
+
+func (s *ProfileServerV2) UpdateAddress(ctx context.Context, pbReq *typesv2.UpdateAddressRequest) (*typesv2.AddressResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.UpdateAddress, &typesv2.AddressResponse{})
+}
+
+func (s *ProfileServerV2) PatchAddress(ctx context.Context, pbReq *typesv2.PatchAddressRequest) (*typesv2.AddressResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.PatchAddress, &typesv2.AddressResponse{})
+}
+
+func (s *ProfileServerV2) DeleteAddress(ctx context.Context, pbReq *typesv2.DeleteAddressRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.DeleteAddress)
+}
+
+func (s *ProfileServerV2) RestoreAddress(ctx context.Context, pbReq *typesv2.RestoreAddressRequest) (*typesv2.AddressResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.RestoreAddress, &typesv2.AddressResponse{})
+}
+
+func (s *ProfileServerV2) SetPrimaryAddress(ctx context.Context, pbReq *typesv2.SetPrimaryAddressRequest) (*typesv2.AddressResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.SetPrimaryAddress, &typesv2.AddressResponse{})
+}
+
+func (s *ProfileServerV2) ListAddresses(ctx context.Context, pbReq *typesv2.ListAddressesRequest) (*typesv2.ListAddressesResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListAddresses, &typesv2.ListAddressesResponse{})
+}
+
+func (s *ProfileServerV2) SearchAddressesNear(ctx context.Context, pbReq *typesv2.SearchAddressesNearRequest) (*typesv2.SearchAddressesNearResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.SearchAddressesNear, &typesv2.SearchAddressesNearResponse{})
+}
+
+func (s *ProfileServerV2) CreateCompany(ctx context.Context, pbReq *typesv2.CreateCompanyRequest) (*typesv2.CompanyResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.CreateCompany, &typesv2.CompanyResponse{})
+}
+
+func (s *ProfileServerV2) GetCompany(ctx context.Context, pbReq *typesv2.GetCompanyRequest) (*typesv2.CompanyResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetCompany, &typesv2.CompanyResponse{})
+}
+
+func (s *ProfileServerV2) UpdateCompany(ctx context.Context, pbReq *typesv2.UpdateCompanyRequest) (*typesv2.CompanyResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.UpdateCompany, &typesv2.CompanyResponse{})
+}
+
+func (s *ProfileServerV2) PatchCompany(ctx context.Context, pbReq *typesv2.PatchCompanyRequest) (*typesv2.CompanyResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.PatchCompany, &typesv2.CompanyResponse{})
+}
+
+func (s *ProfileServerV2) DeleteCompany(ctx context.Context, pbReq *typesv2.DeleteCompanyRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.DeleteCompany)
+}
+
+func (s *ProfileServerV2) RestoreCompany(ctx context.Context, pbReq *typesv2.RestoreCompanyRequest) (*typesv2.CompanyResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.RestoreCompany, &typesv2.CompanyResponse{})
+}
+
+func (s *ProfileServerV2) SetPrimaryCompany(ctx context.Context, pbReq *typesv2.SetPrimaryCompanyRequest) (*typesv2.CompanyResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.SetPrimaryCompany, &typesv2.CompanyResponse{})
+}
+
+func (s *ProfileServerV2) ListCompanies(ctx context.Context, pbReq *typesv2.ListCompaniesRequest) (*typesv2.ListCompaniesResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListCompanies, &typesv2.ListCompaniesResponse{})
+}
+
+func (s *ProfileServerV2) AddCompanyMember(ctx context.Context, pbReq *typesv2.AddCompanyMemberRequest) (*typesv2.CompanyMemberResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.AddCompanyMember, &typesv2.CompanyMemberResponse{})
+}
+
+func (s *ProfileServerV2) RemoveCompanyMember(ctx context.Context, pbReq *typesv2.RemoveCompanyMemberRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.RemoveCompanyMember)
+}
+
+func (s *ProfileServerV2) ListCompanyMembers(ctx context.Context, pbReq *typesv2.ListCompanyMembersRequest) (*typesv2.ListCompanyMembersResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListCompanyMembers, &typesv2.ListCompanyMembersResponse{})
+}
+
+func (s *ProfileServerV2) ListProfileCompanies(ctx context.Context, pbReq *typesv2.ListProfileCompaniesRequest) (*typesv2.ListProfileCompaniesResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListProfileCompanies, &typesv2.ListProfileCompaniesResponse{})
+}
+
+func (s *ProfileServerV2) GetPreferences(ctx context.Context, pbReq *typesv2.GetPreferencesRequest) (*typesv2.PreferencesResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetPreferences, &typesv2.PreferencesResponse{})
+}
+
+func (s *ProfileServerV2) SetPreferences(ctx context.Context, pbReq *typesv2.SetPreferencesRequest) (*typesv2.PreferencesResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.SetPreferences, &typesv2.PreferencesResponse{})
+}
+
+func (s *ProfileServerV2) DeletePreference(ctx context.Context, pbReq *typesv2.DeletePreferenceRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.DeletePreference)
+}
+
+func (s *ProfileServerV2) UploadAttachment(stream typesv2.ProfileService_UploadAttachmentServer) error {
+	if err := s.v1.UploadAttachment(&uploadAttachmentStreamV2{ProfileService_UploadAttachmentServer: stream}); err != nil {
+		return v2Status(err)
+	}
+
+	return nil
+}
+
+func (s *ProfileServerV2) DownloadAttachment(pbReq *typesv2.DownloadAttachmentRequest, stream typesv2.ProfileService_DownloadAttachmentServer) error {
+	v1Req := &types.DownloadAttachmentRequest{}
+	if err := toV1(pbReq, v1Req); err != nil {
+		loggerWithContext(stream.Context()).WithError(err).Error("Failed to convert v2 request (grpc)")
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	if err := s.v1.DownloadAttachment(v1Req, &downloadAttachmentStreamV2{ProfileService_DownloadAttachmentServer: stream}); err != nil {
+		return v2Status(err)
+	}
+
+	return nil
+}
+
+func (s *ProfileServerV2) GetAttachment(ctx context.Context, pbReq *typesv2.GetAttachmentRequest) (*typesv2.AttachmentResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.GetAttachment, &typesv2.AttachmentResponse{})
+}
+
+func (s *ProfileServerV2) ListAttachments(ctx context.Context, pbReq *typesv2.ListAttachmentsRequest) (*typesv2.ListAttachmentsResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListAttachments, &typesv2.ListAttachmentsResponse{})
+}
+
+func (s *ProfileServerV2) DeleteAttachment(ctx context.Context, pbReq *typesv2.DeleteAttachmentRequest) (*emptypb.Empty, error) {
+	return emptyViaV1(ctx, pbReq, s.v1.DeleteAttachment)
+}
+
+func (s *ProfileServerV2) ListAuditEvents(ctx context.Context, pbReq *typesv2.ListAuditEventsRequest) (*typesv2.ListAuditEventsResponse, error) {
+	return viaV1(ctx, pbReq, s.v1.ListAuditEvents, &typesv2.ListAuditEventsResponse{})
+}
+
+// viaV1 converts pbReq to the request of call, invokes it, and converts its response into resp.
+func viaV1[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message, V2Resp proto.Message](ctx context.Context, pbReq proto.Message, call func(context.Context, PReq) (Resp, error), resp V2Resp) (V2Resp, error) {
+	var zero V2Resp
+	v1Resp, err := callV1(ctx, pbReq, call)
+	if err != nil {
+		return zero, err
+	}
+	if err := fromV1(v1Resp, resp); err != nil {
+		loggerWithContext(ctx).WithError(err).Error("Failed to convert v1 response (grpc)")
+		return zero, status.Error(codes.Internal, "internal server error")
+	}
+
+	return resp, nil
+}
+
+// emptyViaV1 is viaV1 for the deletes and removals, whose v1 confirmation message v2 drops.
+func emptyViaV1[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp any](ctx context.Context, pbReq proto.Message, call func(context.Context, PReq) (Resp, error)) (*emptypb.Empty, error) {
+	if _, err := callV1(ctx, pbReq, call); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func callV1[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp any](ctx context.Context, pbReq proto.Message, call func(context.Context, PReq) (Resp, error)) (Resp, error) {
+	v1Req := PReq(new(Req))
+	if err := toV1(pbReq, v1Req); err != nil {
+		loggerWithContext(ctx).WithError(err).Error("Failed to convert v2 request (grpc)")
+		var zero Resp
+		return zero, status.Error(codes.Internal, "internal server error")
+	}
+
+	resp, err := call(ctx, v1Req)
+	if err != nil {
+		return resp, v2Status(err)
+	}
+
+	return resp, nil
+}
+
+// v2Status rewrites the v1 field names the message of a status error mentions, such as in
+// "streen_no is required", to their v2 names.
+func v2Status(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	pb := st.Proto()
+	for v1, v2 := range v2FieldNames {
+		pb.Message = strings.ReplaceAll(pb.Message, string(v1), string(v2))
+	}
+
+	return status.FromProto(pb).Err()
+}
+
+// uploadAttachmentStreamV2 presents a v2 upload stream to the v1 handler.
+type uploadAttachmentStreamV2 struct {
+	typesv2.ProfileService_UploadAttachmentServer
+}
+
+func (s *uploadAttachmentStreamV2) Recv() (*types.UploadAttachmentRequest, error) {
+	msg, err := s.ProfileService_UploadAttachmentServer.Recv()
+	if err != nil {
+		return nil, err
+	}
+	v1Msg := &types.UploadAttachmentRequest{}
+	if err = toV1(msg, v1Msg); err != nil {
+		return nil, err
+	}
+
+	return v1Msg, nil
+}
+
+func (s *uploadAttachmentStreamV2) SendAndClose(resp *types.AttachmentResponse) error {
+	v2Resp := &typesv2.AttachmentResponse{}
+	if err := fromV1(resp, v2Resp); err != nil {
+		return err
+	}
+
+	return s.ProfileService_UploadAttachmentServer.SendAndClose(v2Resp)
+}
+
+// downloadAttachmentStreamV2 presents a v2 download stream to the v1 handler.
+type downloadAttachmentStreamV2 struct {
+	typesv2.ProfileService_DownloadAttachmentServer
+}
+
+func (s *downloadAttachmentStreamV2) Send(resp *types.DownloadAttachmentResponse) error {
+	v2Resp := &typesv2.DownloadAttachmentResponse{}
+	if err := fromV1(resp, v2Resp); err != nil {
+		return err
+	}
+
+	return s.ProfileService_DownloadAttachmentServer.Send(v2Resp)
+}
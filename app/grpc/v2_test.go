@@ -0,0 +1,284 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+	"google.golang.org/genproto/googleapis/type/date"
+	grpcpkg "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestV2CreateAddressUsesStreetNoAndTimestamps(t *testing.T) {
+	created := time.Date(2026, time.March, 1, 10, 30, 0, 0, time.UTC)
+	var stored *entity.Address
+	server := NewProfileServerV2(newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		createFn: func(_ context.Context, address *entity.Address) error {
+			address.ID = 55
+			address.CreatedAt, address.UpdatedAt = created, created
+			stored = address
+			return nil
+		},
+	}))
+
+	resp, err := server.CreateAddress(context.Background(), &typesv2.CreateAddressRequest{
+		StreetName: "Strada Memorandumului",
+		StreetNo:   "28",
+		City:       "Cluj-Napoca",
+		County:     "Cluj",
+		Country:    "RO",
+		ProfileId:  9,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stored.StreenNo != "28" {
+		t.Fatalf("expected street_no to be stored, got %q", stored.StreenNo)
+	}
+	if resp.GetId() != 55 || resp.GetStreetNo() != "28" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if !resp.GetCreatedAt().AsTime().Equal(created) || resp.GetDeletedAt() != nil {
+		t.Fatalf("unexpected timestamps: created %v, deleted %v", resp.GetCreatedAt(), resp.GetDeletedAt())
+	}
+	if resp.GetFormatted().GetSingleLine() == "" {
+		t.Fatal("expected the formatted label to be converted")
+	}
+}
+
+func TestV2CreateAddressErrorNamesV2Field(t *testing.T) {
+	server := NewProfileServerV2(newGRPCServerWithAddressRepo(&grpcAddressRepoStub{}))
+
+	_, err := server.CreateAddress(context.Background(), &typesv2.CreateAddressRequest{StreetName: "Street", City: "City", Country: "RO", ProfileId: 9})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+	if got := status.Convert(err).Message(); got != "street_no is required" {
+		t.Fatalf("unexpected message: %q", got)
+	}
+}
+
+func TestV2PatchAddressRenamesMaskPaths(t *testing.T) {
+	var updated *entity.Address
+	server := NewProfileServerV2(newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, StreetName: "Street", StreenNo: "1", City: "City", Country: "RO", ProfileID: 9}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			updated = address
+			return nil
+		},
+	}))
+
+	_, err := server.PatchAddress(context.Background(), &typesv2.PatchAddressRequest{
+		Id:         3,
+		StreetNo:   "2A",
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"street_no"}},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if updated == nil || updated.StreenNo != "2A" {
+		t.Fatalf("expected street_no to be patched, got %+v", updated)
+	}
+}
+
+func TestV2ListAddressesCopiesAttributes(t *testing.T) {
+	server := NewProfileServerV2(newGRPCServerWithAddressRepo(&grpcAddressRepoStub{
+		listFn: func(_ context.Context, profileID, _ uint64, _ string, attributes map[string]string, _, _ bool, _, _ uint32) ([]*entity.Address, uint64, error) {
+			if profileID != 9 || attributes["dock"] != "B" {
+				t.Fatalf("unexpected filters: profile %d, attributes %v", profileID, attributes)
+			}
+			return []*entity.Address{{ID: 3, StreetName: "Street", StreenNo: "1", City: "City", Country: "RO", ProfileID: 9, Attributes: map[string]any{"dock": "B"}}}, 1, nil
+		},
+	}))
+
+	resp, err := server.ListAddresses(context.Background(), &typesv2.ListAddressesRequest{ProfileId: 9, Attributes: map[string]string{"dock": "B"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(resp.GetAddresses()) != 1 || resp.GetAddresses()[0].GetAttributes().GetFields()["dock"].GetStringValue() != "B" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestV2ContactDOBIsADate(t *testing.T) {
+	var stored *entity.Contact
+	repo := &grpcContactRepoStub{
+		createFn: func(_ context.Context, contact *entity.Contact) error {
+			contact.ID = 7
+			stored = contact
+			return nil
+		},
+	}
+	server := NewProfileServerV2(newGRPCServerWithContactRepo(repo))
+
+	resp, err := server.CreateContact(context.Background(), &typesv2.CreateContactRequest{
+		FirstName: "Ana",
+		LastName:  "Pop",
+		Dob:       &date.Date{Year: 1990, Month: 5, Day: 15},
+		ProfileId: 9,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stored.DOB == nil || stored.DOB.Format("2006-01-02") != "1990-05-15" {
+		t.Fatalf("unexpected stored dob: %v", stored.DOB)
+	}
+	if dob := resp.GetDob(); dob.GetYear() != 1990 || dob.GetMonth() != 5 || dob.GetDay() != 15 {
+		t.Fatalf("unexpected dob: %+v", dob)
+	}
+
+	// A masked birth date has no Date form and is returned in dob_masked instead.
+	policy, err := fieldpolicy.Parse("contact.dob:masked", "")
+	if err != nil {
+		t.Fatalf("fieldpolicy.Parse() returned error: %v", err)
+	}
+	repo.findByIDFn = func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+		return stored, nil
+	}
+	resp, err = server.GetContact(fieldpolicy.NewContext(context.Background(), policy.For("crm")), &typesv2.GetContactRequest{Id: 7})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if resp.GetDob() != nil {
+		t.Fatalf("expected a masked dob to be left out of dob, got %+v", resp.GetDob())
+	}
+	if resp.GetDobMasked() != "******5-15" {
+		t.Fatalf("expected dob_masked %q, got %q", "******5-15", resp.GetDobMasked())
+	}
+}
+
+func TestV2DeleteProfileReturnsEmpty(t *testing.T) {
+	server := NewProfileServerV2(newGRPCServerWithRepo(&grpcRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+		deleteFn: func(context.Context, uint64, uint64) error {
+			return nil
+		},
+	}))
+
+	resp, err := server.DeleteProfile(context.Background(), &typesv2.DeleteProfileRequest{Id: 1})
+	if err != nil || resp == nil {
+		t.Fatalf("expected an empty response, got %v, %v", resp, err)
+	}
+
+	_, err = server.DeleteProfile(context.Background(), &typesv2.DeleteProfileRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected codes.InvalidArgument, got %s", status.Code(err))
+	}
+}
+
+func TestV2ListCompanyMembersUsesTimestamps(t *testing.T) {
+	created := time.Date(2026, time.March, 1, 10, 30, 0, 0, time.UTC)
+	server := NewProfileServerV2(newGRPCServerWithCompanyMemberRepo(&grpcCompanyRepoStub{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id}, nil
+		},
+	}, &grpcCompanyMemberRepoStub{
+		listFn: func(context.Context, uint64, uint32, uint32) ([]*entity.CompanyMember, uint64, error) {
+			return []*entity.CompanyMember{{CompanyID: 5, ProfileID: 8, Role: entity.CompanyRoleOwner, CreatedAt: created, UpdatedAt: created}}, 1, nil
+		},
+	}))
+
+	resp, err := server.ListCompanyMembers(context.Background(), &typesv2.ListCompanyMembersRequest{CompanyId: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(resp.GetMembers()) != 1 || !resp.GetMembers()[0].GetCreatedAt().AsTime().Equal(created) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	_, err = server.RemoveCompanyMember(context.Background(), &typesv2.RemoveCompanyMemberRequest{CompanyId: 5, ProfileId: 8})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+func TestV2SetPreferencesKeepsStructValues(t *testing.T) {
+	updated := time.Date(2026, time.April, 2, 8, 0, 0, 0, time.UTC)
+	repo := &grpcPreferenceRepoStub{}
+	repo.listFn = func(context.Context, uint64, string) ([]*entity.Preference, error) {
+		for _, preference := range repo.saved {
+			preference.UpdatedAt = updated
+		}
+		return repo.saved, nil
+	}
+	server := NewProfileServerV2(newGRPCServerWithPreferenceRepo(repo))
+
+	values, err := structpb.NewStruct(map[string]any{"channels": []any{"email", "sms"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.SetPreferences(context.Background(), &typesv2.SetPreferencesRequest{ProfileId: 7, Namespace: "notifications", Values: values})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(resp.GetNamespaces()) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	namespace := resp.GetNamespaces()[0]
+	channels := namespace.GetValues().GetFields()["channels"].GetListValue().GetValues()
+	if len(channels) != 2 || channels[1].GetStringValue() != "sms" || !namespace.GetUpdatedAt().AsTime().Equal(updated) {
+		t.Fatalf("unexpected namespace: %+v", namespace)
+	}
+
+	_, err = server.DeletePreference(context.Background(), &typesv2.DeletePreferenceRequest{ProfileId: 7, Namespace: "notifications", Key: "digest"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound, got %s", status.Code(err))
+	}
+}
+
+type v2UploadStreamStub struct {
+	grpcpkg.ServerStream
+	messages []*typesv2.UploadAttachmentRequest
+	resp     *typesv2.AttachmentResponse
+}
+
+func (s *v2UploadStreamStub) Context() context.Context { return context.Background() }
+
+func (s *v2UploadStreamStub) Recv() (*typesv2.UploadAttachmentRequest, error) {
+	if len(s.messages) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.messages[0]
+	s.messages = s.messages[1:]
+	return msg, nil
+}
+
+func (s *v2UploadStreamStub) SendAndClose(resp *typesv2.AttachmentResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestV2UploadAttachmentUsesTimestamps(t *testing.T) {
+	server := NewProfileServerV2(newGRPCServerWithAttachments(t))
+	stream := &v2UploadStreamStub{messages: []*typesv2.UploadAttachmentRequest{
+		{Data: &typesv2.UploadAttachmentRequest_Metadata{Metadata: &typesv2.AttachmentUpload{EntityType: "profile", EntityId: 7}}},
+		{Data: &typesv2.UploadAttachmentRequest_Chunk{Chunk: pngHeader}},
+	}}
+	if err := server.UploadAttachment(stream); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if stream.resp.GetContentType() != "image/png" || stream.resp.GetCreatedAt().AsTime().IsZero() {
+		t.Fatalf("unexpected response: %+v", stream.resp)
+	}
+
+	_, err := server.DeleteAttachment(context.Background(), &typesv2.DeleteAttachmentRequest{Id: stream.resp.GetId()})
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	_, err = server.GetAttachment(context.Background(), &typesv2.GetAttachmentRequest{Id: stream.resp.GetId()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected codes.NotFound after delete, got %v", err)
+	}
+}
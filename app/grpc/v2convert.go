@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/date"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// v1 and v2 messages match field by field name. v2 renames the fields below, carries the v1
+// RFC 3339 strings as Timestamps and the v1 YYYY-MM-DD birth dates as Dates, or in dob_masked
+// when masked.
+var v2FieldNames = map[protoreflect.Name]protoreflect.Name{
+	"streen_no": "street_no",
+}
+
+var v1FieldNames = func() map[protoreflect.Name]protoreflect.Name {
+	names := make(map[protoreflect.Name]protoreflect.Name, len(v2FieldNames))
+	for v1, v2 := range v2FieldNames {
+		names[v2] = v1
+	}
+	return names
+}()
+
+var (
+	timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+	dateName      = (&date.Date{}).ProtoReflect().Descriptor().FullName()
+	fieldMaskName = (&fieldmaskpb.FieldMask{}).ProtoReflect().Descriptor().FullName()
+)
+
+// toV1 copies the v2 message src into the v1 message dst.
+func toV1(src, dst proto.Message) error {
+	return convertMessage(src.ProtoReflect(), dst.ProtoReflect(), v1FieldNames)
+}
+
+// fromV1 copies the v1 message src into the v2 message dst.
+func fromV1(src, dst proto.Message) error {
+	return convertMessage(src.ProtoReflect(), dst.ProtoReflect(), v2FieldNames)
+}
+
+func convertMessage(src, dst protoreflect.Message, names map[protoreflect.Name]protoreflect.Name) error {
+	fields := dst.Descriptor().Fields()
+	var err error
+	src.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		target := fields.ByName(renamed(fd.Name(), names))
+		if target == nil || target.IsList() != fd.IsList() || target.IsMap() != fd.IsMap() {
+			err = fmt.Errorf("%s has no counterpart in %s", fd.FullName(), dst.Descriptor().FullName())
+			return false
+		}
+
+		if fd.IsMap() {
+			entries := dst.Mutable(target).Map()
+			value.Map().Range(func(key protoreflect.MapKey, item protoreflect.Value) bool {
+				var converted protoreflect.Value
+				converted, err = convertValue(fd.MapValue(), item, target.MapValue(), entries.NewValue(), names)
+				if err == nil && converted.IsValid() {
+					entries.Set(key, converted)
+				}
+				return err == nil
+			})
+			return err == nil
+		}
+
+		if !fd.IsList() {
+			var converted protoreflect.Value
+			converted, err = convertValue(fd, value, target, dst.NewField(target), names)
+			switch {
+			case err != nil:
+			case converted.IsValid():
+				dst.Set(target, converted)
+			case fd.Kind() == protoreflect.StringKind:
+				// A string without a form in target, such as a masked birth date, goes to the
+				// string field <target>_masked when dst has one.
+				if masked := fields.ByName(target.Name() + "_masked"); masked != nil && masked.Kind() == protoreflect.StringKind {
+					dst.Set(masked, value)
+				}
+			}
+			return err == nil
+		}
+
+		list := dst.Mutable(target).List()
+		for i := 0; i < value.List().Len() && err == nil; i++ {
+			var converted protoreflect.Value
+			converted, err = convertValue(fd, value.List().Get(i), target, list.NewElement(), names)
+			if err == nil && converted.IsValid() {
+				list.Append(converted)
+			}
+		}
+		return err == nil
+	})
+
+	return err
+}
+
+// convertValue converts value of field fd for the field target. empty is a new value of
+// target. An invalid value with a nil error means the field is left unset.
+func convertValue(fd protoreflect.FieldDescriptor, value protoreflect.Value, target protoreflect.FieldDescriptor, empty protoreflect.Value, names map[protoreflect.Name]protoreflect.Name) (protoreflect.Value, error) {
+	switch {
+	case fd.Kind() == target.Kind() && fd.Kind() != protoreflect.MessageKind:
+		return value, nil
+
+	case fd.Kind() == protoreflect.StringKind && target.Kind() == protoreflect.MessageKind:
+		switch target.Message().FullName() {
+		case timestampName:
+			t, err := time.Parse(time.RFC3339, value.String())
+			if err != nil {
+				return protoreflect.Value{}, fmt.Errorf("%s: %w", fd.FullName(), err)
+			}
+			return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), nil
+		case dateName:
+			t, err := time.Parse(grpcContactDOBLayout, value.String())
+			if err != nil {
+				// A birth date masked by the caller's field policy has no Date form;
+				// convertMessage keeps it in dob_masked.
+				if strings.Contains(value.String(), "*") {
+					return protoreflect.Value{}, nil
+				}
+				return protoreflect.Value{}, fmt.Errorf("%s: %w", fd.FullName(), err)
+			}
+			return protoreflect.ValueOfMessage((&date.Date{Year: int32(t.Year()), Month: int32(t.Month()), Day: int32(t.Day())}).ProtoReflect()), nil
+		}
+
+	case fd.Kind() == protoreflect.MessageKind && target.Kind() == protoreflect.StringKind:
+		switch msg := value.Message().Interface().(type) {
+		case *timestamppb.Timestamp:
+			return protoreflect.ValueOfString(msg.AsTime().Format(time.RFC3339)), nil
+		case *date.Date:
+			if msg.GetYear() == 0 && msg.GetMonth() == 0 && msg.GetDay() == 0 {
+				return protoreflect.Value{}, nil
+			}
+			return protoreflect.ValueOfString(fmt.Sprintf("%04d-%02d-%02d", msg.GetYear(), msg.GetMonth(), msg.GetDay())), nil
+		}
+
+	case fd.Kind() == protoreflect.MessageKind && target.Kind() == protoreflect.MessageKind:
+		if fd.Message().FullName() == fieldMaskName && target.Message().FullName() == fieldMaskName {
+			mask := value.Message().Interface().(*fieldmaskpb.FieldMask)
+			paths := make([]string, len(mask.GetPaths()))
+			for i, path := range mask.GetPaths() {
+				paths[i] = string(renamed(protoreflect.Name(path), names))
+			}
+			return protoreflect.ValueOfMessage((&fieldmaskpb.FieldMask{Paths: paths}).ProtoReflect()), nil
+		}
+		// Well-known types such as Struct are shared by both versions and copied as they are.
+		if fd.Message().FullName() == target.Message().FullName() {
+			return protoreflect.ValueOfMessage(proto.Clone(value.Message().Interface()).ProtoReflect()), nil
+		}
+		if err := convertMessage(value.Message(), empty.Message(), names); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return empty, nil
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("cannot convert %s to %s", fd.FullName(), target.FullName())
+}
+
+func renamed(name protoreflect.Name, names map[protoreflect.Name]protoreflect.Name) protoreflect.Name {
+	if to, ok := names[name]; ok {
+		return to
+	}
+	return name
+}
@@ -0,0 +1,456 @@
+// Package jsonschema validates JSON values against the subset of JSON Schema used for profile
+// preferences: type, enum, const, the object, array, string and number bounds, and pattern.
+// Annotations such as title and description are ignored; any other keyword is rejected when
+// the schema is compiled, so that a schema is never enforced only in part.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidSchema = errors.New("invalid JSON schema")
+
+// ValidationError reports the first part of a value that does not match its schema.
+type ValidationError struct {
+	// Path is the dotted path of the offending member within the value, empty for the value
+	// itself.
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+var typeNames = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"number":  true,
+	"integer": true,
+	"string":  true,
+}
+
+// Schema is a compiled schema. The zero value accepts every value.
+type Schema struct {
+	types    []string
+	enum     []any
+	constant *any
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+	minProperties        *int
+	maxProperties        *int
+
+	items    *Schema
+	minItems *int
+	maxItems *int
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+}
+
+// Compile parses a schema document.
+func Compile(data []byte) (*Schema, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	return compile(raw, "")
+}
+
+// LoadDir compiles every *.json file of dir, keyed by its file name without the extension.
+func LoadDir(dir string) (map[string]*Schema, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*Schema, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := Compile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		schemas[strings.TrimSuffix(filepath.Base(path), ".json")] = schema
+	}
+
+	return schemas, nil
+}
+
+func compile(raw any, at string) (*Schema, error) {
+	if accept, ok := raw.(bool); ok {
+		if accept {
+			return &Schema{}, nil
+		}
+		return nil, invalid(at, "boolean schemas other than true are not supported")
+	}
+	doc, ok := raw.(map[string]any)
+	if !ok {
+		return nil, invalid(at, "a schema must be an object")
+	}
+
+	s := &Schema{}
+	var err error
+	for _, keyword := range sortedKeys(doc) {
+		value := doc[keyword]
+		where := join(at, keyword)
+		switch keyword {
+		case "type":
+			s.types, err = compileTypes(value, where)
+		case "enum":
+			values, ok := value.([]any)
+			if !ok || len(values) == 0 {
+				return nil, invalid(where, "must be a non-empty array")
+			}
+			s.enum = values
+		case "const":
+			constant := value
+			s.constant = &constant
+		case "properties":
+			s.properties, err = compileProperties(value, where)
+		case "required":
+			s.required, err = compileStrings(value, where)
+		case "additionalProperties":
+			if accept, ok := value.(bool); ok {
+				s.noAdditional = !accept
+				continue
+			}
+			s.additionalProperties, err = compile(value, where)
+		case "minProperties":
+			s.minProperties, err = compileCount(value, where)
+		case "maxProperties":
+			s.maxProperties, err = compileCount(value, where)
+		case "items":
+			s.items, err = compile(value, where)
+		case "minItems":
+			s.minItems, err = compileCount(value, where)
+		case "maxItems":
+			s.maxItems, err = compileCount(value, where)
+		case "minLength":
+			s.minLength, err = compileCount(value, where)
+		case "maxLength":
+			s.maxLength, err = compileCount(value, where)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, invalid(where, "must be a string")
+			}
+			if s.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, invalid(where, err.Error())
+			}
+		case "minimum":
+			s.minimum, err = compileNumber(value, where)
+		case "maximum":
+			s.maximum, err = compileNumber(value, where)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = compileNumber(value, where)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = compileNumber(value, where)
+		default:
+			if !annotations[keyword] {
+				return nil, invalid(where, "keyword is not supported")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func compileTypes(value any, at string) ([]string, error) {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = []string{v}
+	case []any:
+		var err error
+		if names, err = compileStrings(v, at); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 {
+		return nil, invalid(at, "must be a type name or an array of them")
+	}
+	for _, name := range names {
+		if !typeNames[name] {
+			return nil, invalid(at, fmt.Sprintf("unknown type %q", name))
+		}
+	}
+
+	return names, nil
+}
+
+func compileProperties(value any, at string) (map[string]*Schema, error) {
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return nil, invalid(at, "must be an object")
+	}
+
+	properties := make(map[string]*Schema, len(doc))
+	for _, name := range sortedKeys(doc) {
+		property, err := compile(doc[name], join(at, name))
+		if err != nil {
+			return nil, err
+		}
+		properties[name] = property
+	}
+
+	return properties, nil
+}
+
+func compileStrings(value any, at string) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, invalid(at, "must be an array of strings")
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, invalid(at, "must be an array of strings")
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func compileCount(value any, at string) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, invalid(at, "must be a non-negative integer")
+	}
+	count := int(n)
+
+	return &count, nil
+}
+
+func compileNumber(value any, at string) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, invalid(at, "must be a number")
+	}
+
+	return &n, nil
+}
+
+// Validate checks value, a JSON value as decoded by encoding/json into an any.
+func (s *Schema) Validate(value any) error {
+	return s.validate(value, "")
+}
+
+func (s *Schema) validate(value any, at string) error {
+	if len(s.types) > 0 && !s.hasType(value) {
+		return &ValidationError{Path: at, Message: "must be of type " + strings.Join(s.types, " or ")}
+	}
+	if s.enum != nil && !containsValue(s.enum, value) {
+		return &ValidationError{Path: at, Message: "must be one of the allowed values"}
+	}
+	if s.constant != nil && !reflect.DeepEqual(*s.constant, value) {
+		return &ValidationError{Path: at, Message: "must equal the constant value"}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return s.validateObject(v, at)
+	case []any:
+		return s.validateArray(v, at)
+	case string:
+		return s.validateString(v, at)
+	case float64:
+		return s.validateNumber(v, at)
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(value map[string]any, at string) error {
+	for _, name := range s.required {
+		if _, ok := value[name]; !ok {
+			return &ValidationError{Path: join(at, name), Message: "is required"}
+		}
+	}
+	if s.minProperties != nil && len(value) < *s.minProperties {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must have at least %d members", *s.minProperties)}
+	}
+	if s.maxProperties != nil && len(value) > *s.maxProperties {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must have at most %d members", *s.maxProperties)}
+	}
+
+	for _, name := range sortedKeys(value) {
+		property, ok := s.properties[name]
+		switch {
+		case ok:
+		case s.noAdditional:
+			return &ValidationError{Path: join(at, name), Message: "is not allowed"}
+		case s.additionalProperties != nil:
+			property = s.additionalProperties
+		default:
+			continue
+		}
+		if err := property.validate(value[name], join(at, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateArray(value []any, at string) error {
+	if s.minItems != nil && len(value) < *s.minItems {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must have at least %d items", *s.minItems)}
+	}
+	if s.maxItems != nil && len(value) > *s.maxItems {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must have at most %d items", *s.maxItems)}
+	}
+	if s.items == nil {
+		return nil
+	}
+	for i, item := range value {
+		if err := s.items.validate(item, join(at, fmt.Sprint(i))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateString(value string, at string) error {
+	length := utf8.RuneCountInString(value)
+	if s.minLength != nil && length < *s.minLength {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must be at least %d characters long", *s.minLength)}
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must be at most %d characters long", *s.maxLength)}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must match %q", s.pattern.String())}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateNumber(value float64, at string) error {
+	if s.minimum != nil && value < *s.minimum {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must be at least %v", *s.minimum)}
+	}
+	if s.maximum != nil && value > *s.maximum {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must be at most %v", *s.maximum)}
+	}
+	if s.exclusiveMinimum != nil && value <= *s.exclusiveMinimum {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must be greater than %v", *s.exclusiveMinimum)}
+	}
+	if s.exclusiveMaximum != nil && value >= *s.exclusiveMaximum {
+		return &ValidationError{Path: at, Message: fmt.Sprintf("must be less than %v", *s.exclusiveMaximum)}
+	}
+
+	return nil
+}
+
+func (s *Schema) hasType(value any) bool {
+	for _, name := range s.types {
+		if typeOf(value) == name || (name == "number" && typeOf(value) == "integer") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeOf names the JSON type of value; whole numbers are integers.
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	}
+
+	return ""
+}
+
+func containsValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+func invalid(at, message string) error {
+	if at == "" {
+		return fmt.Errorf("%w: %s", ErrInvalidSchema, message)
+	}
+	return fmt.Errorf("%w: %s %s", ErrInvalidSchema, at, message)
+}
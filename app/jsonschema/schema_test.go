@@ -0,0 +1,104 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const localeSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Locale preferences",
+	"type": "object",
+	"required": ["language"],
+	"additionalProperties": false,
+	"properties": {
+		"language": {"type": "string", "pattern": "^[a-z]{2}(-[A-Z]{2})?$"},
+		"timezone": {"type": "string", "minLength": 1, "maxLength": 64},
+		"currency": {"enum": ["RON", "EUR", "USD"]},
+		"digest_hour": {"type": "integer", "minimum": 0, "maximum": 23},
+		"channels": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+	}
+}`
+
+func decode(t *testing.T, raw string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("invalid test value %s: %v", raw, err)
+	}
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(localeSchema))
+	if err != nil {
+		t.Fatalf("expected schema to compile, got %v", err)
+	}
+
+	if err = schema.Validate(decode(t, `{"language":"ro-RO","currency":"RON","digest_hour":7,"channels":["email"]}`)); err != nil {
+		t.Fatalf("expected valid value, got %v", err)
+	}
+
+	cases := []struct {
+		value, path string
+	}{
+		{`{"timezone":"Europe/Bucharest"}`, "language"},
+		{`{"language":"romanian"}`, "language"},
+		{`{"language":"ro","currency":"GBP"}`, "currency"},
+		{`{"language":"ro","digest_hour":7.5}`, "digest_hour"},
+		{`{"language":"ro","digest_hour":24}`, "digest_hour"},
+		{`{"language":"ro","channels":["email",3]}`, "channels.1"},
+		{`{"language":"ro","channels":["email","sms","push"]}`, "channels"},
+		{`{"language":"ro","theme":"dark"}`, "theme"},
+		{`["ro"]`, ""},
+	}
+	for _, tc := range cases {
+		var validationErr *ValidationError
+		if err = schema.Validate(decode(t, tc.value)); !errors.As(err, &validationErr) || validationErr.Path != tc.path {
+			t.Fatalf("expected an error at %q for %s, got %v", tc.path, tc.value, err)
+		}
+	}
+}
+
+func TestCompileRejectsUnsupportedSchemas(t *testing.T) {
+	for _, raw := range []string{
+		`{"type": "object", "oneOf": [{"type": "string"}]}`,
+		`{"type": "text"}`,
+		`{"properties": {"language": {"maxLength": -1}}}`,
+		`{"pattern": "("}`,
+		`false`,
+		`[]`,
+	} {
+		if _, err := Compile([]byte(raw)); !errors.Is(err, ErrInvalidSchema) {
+			t.Fatalf("expected ErrInvalidSchema for %s, got %v", raw, err)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "locale.json"), []byte(localeSchema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a schema"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("expected schemas to load, got %v", err)
+	}
+	if len(schemas) != 1 || schemas["locale"] == nil {
+		t.Fatalf("expected the locale schema only, got %v", schemas)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"type": 1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadDir(dir); !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("expected ErrInvalidSchema, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/vibast-solutions/ms-go-profile/migrations"
+)
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON t (c);")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX idx ON t;")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE t (c INT);")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE t;")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+
+	loaded, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(loaded))
+	}
+	if loaded[0].Version != 1 || loaded[0].Name != "init" || loaded[1].Version != 2 {
+		t.Fatalf("unexpected migration order: %+v", loaded)
+	}
+	if loaded[1].Down != "DROP INDEX idx ON t;" {
+		t.Fatalf("unexpected down script: %q", loaded[1].Down)
+	}
+}
+
+func TestLoadRejectsMissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql": {Data: []byte("CREATE TABLE t (c INT);")},
+	}
+
+	if _, err := Load(fsys); err == nil {
+		t.Fatal("expected error for migration without down script")
+	}
+}
+
+func TestLoadRejectsInvalidFileName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"init.sql": {Data: []byte("CREATE TABLE t (c INT);")},
+	}
+
+	if _, err := Load(fsys); err == nil {
+		t.Fatal("expected error for invalid migration file name")
+	}
+}
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("embedded migrations failed to load: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("expected at least one embedded migration")
+	}
+	for i, m := range loaded {
+		if m.Version != uint64(i+1) {
+			t.Fatalf("expected contiguous versions, got %d at position %d", m.Version, i)
+		}
+	}
+
+	migrator := NewMigrator(nil, loaded)
+	if migrator.LatestVersion() != loaded[len(loaded)-1].Version {
+		t.Fatalf("unexpected latest version %d", migrator.LatestVersion())
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+-- leading comment
+CREATE TABLE a (
+    id INT
+);
+
+DROP TABLE b;
+ALTER TABLE c ADD COLUMN d INT`
+
+	statements := splitStatements(script)
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %q", len(statements), statements)
+	}
+	if statements[0] != "CREATE TABLE a (\n    id INT\n)" {
+		t.Fatalf("unexpected first statement: %q", statements[0])
+	}
+	if statements[1] != "DROP TABLE b" {
+		t.Fatalf("unexpected second statement: %q", statements[1])
+	}
+	if statements[2] != "ALTER TABLE c ADD COLUMN d INT" {
+		t.Fatalf("unexpected third statement: %q", statements[2])
+	}
+}
@@ -0,0 +1,103 @@
+// Package nin checks national identification numbers against the rules of the country that
+// issued them and decodes what they encode about their holder.
+package nin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+)
+
+var ErrInvalidNIN = errors.New("invalid nin")
+
+// Validator checks the identification numbers of one country.
+type Validator interface {
+	// Check validates nin and returns what it encodes.
+	Check(nin string) (Info, error)
+}
+
+// Info is what a NIN encodes about its holder; fields it does not encode are zero.
+type Info struct {
+	BirthDate time.Time
+	// Sex is M or F.
+	Sex string
+}
+
+var (
+	mu         sync.RWMutex
+	validators = map[string]Validator{"RO": CNP{}}
+)
+
+// Register sets the validator of the country with ISO 3166-1 alpha-2 code country, replacing
+// any built-in one.
+func Register(country string, v Validator) {
+	mu.Lock()
+	defer mu.Unlock()
+	validators[strings.ToUpper(country)] = v
+}
+
+func validatorFor(country string) (Validator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	v, ok := validators[country]
+	return v, ok
+}
+
+// Contact holds the contact fields that depend on the NIN country.
+type Contact struct {
+	Country string
+	NIN     string
+	DOB     *time.Time
+}
+
+// NormalizeContact returns c with the country as its alpha-2 code. When the country has a
+// validator, the NIN must pass it, the date of birth must match the one the NIN encodes, and
+// a missing date of birth is taken from the NIN. Contacts without a country are not checked.
+func NormalizeContact(c Contact) (Contact, error) {
+	if strings.TrimSpace(c.Country) == "" {
+		c.Country = ""
+		return c, nil
+	}
+	found, ok := country.Lookup(c.Country)
+	if !ok {
+		return c, &country.FieldError{
+			Field:   "nin_country",
+			Message: fmt.Sprintf("nin_country %q is not an ISO 3166-1 country code or name", strings.TrimSpace(c.Country)),
+		}
+	}
+
+	normalized := Contact{Country: found.Alpha2, NIN: c.NIN, DOB: c.DOB}
+	v, ok := validatorFor(found.Alpha2)
+	if !ok || strings.TrimSpace(c.NIN) == "" {
+		return normalized, nil
+	}
+
+	normalized.NIN = strings.TrimSpace(c.NIN)
+	info, err := v.Check(normalized.NIN)
+	if err != nil {
+		return c, &country.FieldError{Field: "nin", Message: err.Error()}
+	}
+	if info.BirthDate.IsZero() {
+		return normalized, nil
+	}
+	if normalized.DOB == nil {
+		normalized.DOB = &info.BirthDate
+	} else if !sameDay(*normalized.DOB, info.BirthDate) {
+		return c, &country.FieldError{
+			Field:   "dob",
+			Message: fmt.Sprintf("dob does not match the date of birth in the nin (%s)", info.BirthDate.Format(time.DateOnly)),
+		}
+	}
+
+	return normalized, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,102 @@
+package nin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+)
+
+func TestCNPCheck(t *testing.T) {
+	cases := map[string]Info{
+		"1800101120017": {BirthDate: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), Sex: "M"},
+		"2901231040024": {BirthDate: time.Date(1990, 12, 31, 0, 0, 0, 0, time.UTC), Sex: "F"},
+		"5000229400131": {BirthDate: time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), Sex: "M"},
+		"7600101400049": {BirthDate: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), Sex: "M"},
+		"1800101520015": {BirthDate: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), Sex: "M"},
+		"9800101120011": {},
+	}
+	for cnp, want := range cases {
+		got, err := (CNP{}).Check(cnp)
+		if err != nil {
+			t.Fatalf("expected CNP %s to be valid, got %v", cnp, err)
+		}
+		if got != want {
+			t.Fatalf("CNP %s = %+v, want %+v", cnp, got, want)
+		}
+	}
+
+	for _, cnp := range []string{
+		"1800101120018", // control digit
+		"180010112001",  // length
+		"18001011200A7", // digits
+		"1800101120009", // serial 000
+		"1800101990014", // county 99
+		"5020229400138", // 2002-02-29
+		"1999901120014", // month 99
+		"5990101120014", // 2099
+		"0800101120015", // sex digit
+	} {
+		if _, err := (CNP{}).Check(cnp); !errors.Is(err, ErrInvalidNIN) {
+			t.Fatalf("expected CNP %s to be invalid, got %v", cnp, err)
+		}
+	}
+}
+
+func TestNormalizeContact(t *testing.T) {
+	got, err := NormalizeContact(Contact{Country: "romania", NIN: " 1800101120017 "})
+	if err != nil {
+		t.Fatalf("expected valid contact, got %v", err)
+	}
+	if got.Country != "RO" || got.NIN != "1800101120017" || got.DOB == nil || got.DOB.Format(time.DateOnly) != "1980-01-01" {
+		t.Fatalf("unexpected normalized contact: %+v", got)
+	}
+
+	dob := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err = NormalizeContact(Contact{Country: "RO", NIN: "1800101120017", DOB: &dob}); err != nil {
+		t.Fatalf("expected matching dob to be accepted, got %v", err)
+	}
+
+	// Without a country, or for a country without a validator, the NIN is not checked.
+	for _, c := range []Contact{{NIN: "123"}, {Country: "DE", NIN: "123"}} {
+		if _, err = NormalizeContact(c); err != nil {
+			t.Fatalf("expected %+v to be accepted, got %v", c, err)
+		}
+	}
+
+	other := time.Date(1981, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		contact Contact
+		field   string
+	}{
+		{Contact{Country: "Atlantis", NIN: "1800101120017"}, "nin_country"},
+		{Contact{Country: "RO", NIN: "1800101120018"}, "nin"},
+		{Contact{Country: "RO", NIN: "1800101120017", DOB: &other}, "dob"},
+	}
+	for _, tc := range cases {
+		_, err = NormalizeContact(tc.contact)
+		var fieldErr *country.FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != tc.field {
+			t.Fatalf("NormalizeContact(%+v) = %v, want an error for %s", tc.contact, err, tc.field)
+		}
+	}
+}
+
+type stubValidator struct{}
+
+func (stubValidator) Check(string) (Info, error) { return Info{}, ErrInvalidNIN }
+
+func TestRegister(t *testing.T) {
+	Register("de", stubValidator{})
+	defer func() {
+		mu.Lock()
+		delete(validators, "DE")
+		mu.Unlock()
+	}()
+
+	var fieldErr *country.FieldError
+	if _, err := NormalizeContact(Contact{Country: "DE", NIN: "123"}); !errors.As(err, &fieldErr) || fieldErr.Field != "nin" {
+		t.Fatalf("expected the registered validator to reject the nin, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package nin
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// cnpKey weighs the first 12 digits of a CNP to compute its control digit.
+const cnpKey = "279146358279"
+
+// CNP checks Romanian personal numeric codes (Cod Numeric Personal): SYYMMDDJJNNNC, where S
+// encodes sex and century, JJ the county of registration, NNN a serial number and C the
+// control digit.
+type CNP struct{}
+
+func (CNP) Check(nin string) (Info, error) {
+	if len(nin) != 13 {
+		return Info{}, fmt.Errorf("%w: a CNP has 13 digits", ErrInvalidNIN)
+	}
+	for _, r := range nin {
+		if r < '0' || r > '9' {
+			return Info{}, fmt.Errorf("%w: a CNP has only digits", ErrInvalidNIN)
+		}
+	}
+
+	sum := 0
+	for i := range cnpKey {
+		sum += int(nin[i]-'0') * int(cnpKey[i]-'0')
+	}
+	control := sum % 11
+	if control == 10 {
+		control = 1
+	}
+	if int(nin[12]-'0') != control {
+		return Info{}, fmt.Errorf("%w: control digit does not match", ErrInvalidNIN)
+	}
+
+	// 01-40 are the counties, 41-48 the sectors of Bucharest, 51 and 52 the counties added
+	// later and 70 any county, for numbers issued since 2024.
+	county, _ := strconv.Atoi(nin[7:9])
+	if county < 1 || (county > 48 && county != 51 && county != 52 && county != 70) {
+		return Info{}, fmt.Errorf("%w: unknown county code %s", ErrInvalidNIN, nin[7:9])
+	}
+	if nin[9:12] == "000" {
+		return Info{}, fmt.Errorf("%w: serial number 000", ErrInvalidNIN)
+	}
+
+	s := int(nin[0] - '0')
+	if s == 0 {
+		return Info{}, fmt.Errorf("%w: unknown sex digit 0", ErrInvalidNIN)
+	}
+	if s == 9 {
+		// Foreign nationals: neither sex nor the century of birth is encoded.
+		return Info{}, nil
+	}
+	info := Info{Sex: "F"}
+	if s%2 == 1 {
+		info.Sex = "M"
+	}
+
+	yy, _ := strconv.Atoi(nin[1:3])
+	mm, _ := strconv.Atoi(nin[3:5])
+	dd, _ := strconv.Atoi(nin[5:7])
+	now := time.Now().UTC()
+	var year int
+	switch s {
+	case 1, 2:
+		year = 1900 + yy
+	case 3, 4:
+		year = 1800 + yy
+	case 5, 6:
+		year = 2000 + yy
+	default:
+		// Foreign residents: the century is the latest one that puts the birth in the past.
+		if year = 2000 + yy; year > now.Year() {
+			year -= 100
+		}
+	}
+	birth := time.Date(year, time.Month(mm), dd, 0, 0, 0, 0, time.UTC)
+	if birth.Year() != year || int(birth.Month()) != mm || birth.Day() != dd {
+		return Info{}, fmt.Errorf("%w: no such date of birth", ErrInvalidNIN)
+	}
+	if birth.After(now) {
+		return Info{}, fmt.Errorf("%w: date of birth is in the future", ErrInvalidNIN)
+	}
+	info.BirthDate = birth
+
+	return info, nil
+}
@@ -0,0 +1,90 @@
+// Package phone parses free-text phone numbers into E.164 form and classifies them.
+package phone
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+type Type string
+
+const (
+	Mobile   Type = "mobile"
+	Landline Type = "landline"
+	// Other covers toll-free, premium, VoIP and similar numbers, and numbers whose plan does
+	// not tell mobile and landline apart, such as North American ones.
+	Other Type = "other"
+)
+
+// minDigits and maxDigits bound the digits of a plausible number, including an international
+// "00" prefix in front of the 15 digits E.164 allows.
+const (
+	minDigits = 3
+	maxDigits = 17
+)
+
+var ErrInvalid = errors.New("invalid phone number")
+
+// Number is a parsed phone number.
+type Number struct {
+	E164 string
+	Type Type
+}
+
+// Parse reads raw as a number of region, an ISO 3166-1 alpha-2 code used when raw has no
+// country calling code. Numbers written with "+" parse without a region.
+func Parse(raw, region string) (Number, error) {
+	parsed, err := phonenumbers.Parse(strings.TrimSpace(raw), strings.ToUpper(region))
+	if err != nil || !phonenumbers.IsValidNumber(parsed) {
+		return Number{}, ErrInvalid
+	}
+
+	return Number{
+		E164: phonenumbers.Format(parsed, phonenumbers.E164),
+		Type: classify(phonenumbers.GetNumberType(parsed)),
+	}, nil
+}
+
+// CheckSyntax rejects input that cannot be a phone number whatever its region. Numbers
+// written with "+" are parsed in full, since they do not depend on a region.
+func CheckSyntax(raw string) error {
+	raw = strings.TrimSpace(raw)
+	digits := 0
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '+' && i == 0:
+		case strings.ContainsRune(" -.()/", r):
+		default:
+			return ErrInvalid
+		}
+	}
+	if digits < minDigits || digits > maxDigits {
+		return ErrInvalid
+	}
+	if strings.HasPrefix(raw, "+") {
+		_, err := Parse(raw, "")
+		return err
+	}
+
+	return nil
+}
+
+// IsRegion reports whether code is a region with a known numbering plan.
+func IsRegion(code string) bool {
+	return len(code) == 2 && phonenumbers.GetCountryCodeForRegion(strings.ToUpper(code)) != 0
+}
+
+func classify(numberType phonenumbers.PhoneNumberType) Type {
+	switch numberType {
+	case phonenumbers.MOBILE:
+		return Mobile
+	case phonenumbers.FIXED_LINE:
+		return Landline
+	default:
+		return Other
+	}
+}
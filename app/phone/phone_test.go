@@ -0,0 +1,66 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		raw, region string
+		want        Number
+	}{
+		{"0722 123 456", "RO", Number{E164: "+40722123456", Type: Mobile}},
+		{"+40 722-123-456", "", Number{E164: "+40722123456", Type: Mobile}},
+		{"0040722123456", "ro", Number{E164: "+40722123456", Type: Mobile}},
+		{"021 312 3456", "RO", Number{E164: "+40213123456", Type: Landline}},
+		{"+49 30 123456", "RO", Number{E164: "+4930123456", Type: Landline}},
+		{"(202) 555-0143", "US", Number{E164: "+12025550143", Type: Other}},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.raw, tc.region)
+		if err != nil {
+			t.Fatalf("Parse(%q, %q) returned error: %v", tc.raw, tc.region, err)
+		}
+		if got != tc.want {
+			t.Fatalf("Parse(%q, %q) = %+v, want %+v", tc.raw, tc.region, got, tc.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	cases := [][2]string{
+		{"0722123456", ""},
+		{"0722", "RO"},
+		{"+40 123", ""},
+		{"phone", "RO"},
+		{"", "RO"},
+	}
+	for _, tc := range cases {
+		if _, err := Parse(tc[0], tc[1]); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("Parse(%q, %q): expected ErrInvalid, got %v", tc[0], tc[1], err)
+		}
+	}
+}
+
+func TestCheckSyntax(t *testing.T) {
+	for _, raw := range []string{"0722 123 456", "(021) 312.3456", "+40 722 123 456", "0040722123456"} {
+		if err := CheckSyntax(raw); err != nil {
+			t.Fatalf("CheckSyntax(%q) returned error: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"", "12", "0722 ABC", "07+22", "+40 123", "123456789012345678"} {
+		if err := CheckSyntax(raw); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("CheckSyntax(%q): expected ErrInvalid, got %v", raw, err)
+		}
+	}
+}
+
+func TestIsRegion(t *testing.T) {
+	if !IsRegion("RO") || !IsRegion("de") {
+		t.Fatal("expected RO and de to be regions")
+	}
+	if IsRegion("") || IsRegion("Romania") || IsRegion("XX") {
+		t.Fatal("expected empty, Romania and XX not to be regions")
+	}
+}
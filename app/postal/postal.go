@@ -0,0 +1,144 @@
+// Package postal formats addresses as postal labels laid out the way the country of the
+// address writes them.
+package postal
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+)
+
+// Address is an address to format. County may be a subdivision code or name, and Country any
+// form country.Lookup accepts.
+type Address struct {
+	StreetName string
+	StreetNo   string
+	Building   string
+	Apartment  string
+	City       string
+	County     string
+	PostalCode string
+	Country    string
+}
+
+// Label is a formatted address.
+type Label struct {
+	Lines []string
+}
+
+// SingleLine returns the lines joined with commas.
+func (l Label) SingleLine() string {
+	return strings.Join(l.Lines, ", ")
+}
+
+// MultiLine returns one line per row of the printed label.
+func (l Label) MultiLine() string {
+	return strings.Join(l.Lines, "\n")
+}
+
+// Templates lay out one label line per template line. {name} is replaced by a field and
+// {NAME} by the field in upper case. A [bracketed] part is left out when every field in it
+// is empty, and lines left empty are dropped. The fields are street, number, building,
+// apartment, city, county, county_code (the subdivision code without the country prefix),
+// postal_code and country (the English country name).
+const defaultTemplate = "{street}[ {number}]\n[{building}][, {apartment}]\n{postal_code} {city}\n{COUNTRY}"
+
+var templates = map[string]string{
+	"RO": "{street}[ nr. {number}]\n[bl. {building}][, ap. {apartment}]\n{postal_code} {city}[, jud. {county}]\n{COUNTRY}",
+	"FR": "{number} {street}\n[Bât. {building}][, Appt {apartment}]\n{postal_code} {CITY}\n{COUNTRY}",
+	"LU": "[{number}, ]{street}\n[{building}][, {apartment}]\n[L-{postal_code} ]{city}\n{COUNTRY}",
+	"IT": "{street}[ {number}]\n[{building}][, int. {apartment}]\n{postal_code} {city}[ {county_code}]\n{COUNTRY}",
+	"ES": "{street}[, {number}]\n[{building}][, {apartment}]\n{postal_code} {city}\n{county}\n{COUNTRY}",
+	"IE": "{number} {street}\n[{building}][, {apartment}]\n{city}\n[Co. {county}]\n{postal_code}\n{COUNTRY}",
+	"HU": "{city}\n{street}[ {number}]\n[{building}][, {apartment}]\n{postal_code}\n{COUNTRY}",
+	"MT": "{number} {street}\n[{building}][, {apartment}]\n{city} {postal_code}\n{COUNTRY}",
+	"LV": "{street}[ {number}]\n[{building}][, {apartment}]\n{city}[, LV-{postal_code}]\n{COUNTRY}",
+	"LT": "{street}[ {number}]\n[{building}][, {apartment}]\n[LT-{postal_code} ]{city}\n{COUNTRY}",
+	"US": "{number} {street}[ Apt {apartment}]\n[Bldg {building}]\n{city}[, {county_code}] {postal_code}\n{COUNTRY}",
+}
+
+var (
+	placeholder = regexp.MustCompile(`\{(\w+)\}`)
+	// separators are trimmed from the ends of a line once empty fields are left out.
+	separators = " ,"
+)
+
+// Format lays out the address with the template of its country. Countries without one of
+// their own, including most of the EU, put the street and number first and the postal code
+// before the city.
+func Format(a Address) Label {
+	values := map[string]string{
+		"street":      a.StreetName,
+		"number":      a.StreetNo,
+		"building":    a.Building,
+		"apartment":   a.Apartment,
+		"city":        a.City,
+		"county":      a.County,
+		"county_code": a.County,
+		"postal_code": a.PostalCode,
+		"country":     a.Country,
+	}
+
+	template := defaultTemplate
+	if c, ok := country.Lookup(a.Country); ok {
+		values["country"] = c.Name
+		if t, ok := templates[c.Alpha2]; ok {
+			template = t
+		}
+		if s, ok := c.Subdivision(a.County); ok {
+			values["county_code"] = strings.TrimPrefix(s.Code, c.Alpha2+"-")
+		}
+	}
+
+	label := Label{Lines: make([]string, 0, strings.Count(template, "\n")+1)}
+	for _, line := range strings.Split(template, "\n") {
+		if rendered := renderLine(line, values); rendered != "" {
+			label.Lines = append(label.Lines, rendered)
+		}
+	}
+
+	return label
+}
+
+func renderLine(line string, values map[string]string) string {
+	var out strings.Builder
+	for line != "" {
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if part, filled := expand(line[1:end], values); filled {
+				out.WriteString(part)
+			}
+			line = line[end+1:]
+			continue
+		}
+
+		end := strings.IndexByte(line, '[')
+		if end < 0 {
+			end = len(line)
+		}
+		part, _ := expand(line[:end], values)
+		out.WriteString(part)
+		line = line[end:]
+	}
+
+	return strings.Trim(strings.Join(strings.Fields(out.String()), " "), separators)
+}
+
+// expand replaces the placeholders of s and reports whether any of them had a value.
+func expand(s string, values map[string]string) (string, bool) {
+	filled := false
+	expanded := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[1 : len(match)-1]
+		value := strings.TrimSpace(values[strings.ToLower(name)])
+		if value != "" {
+			filled = true
+		}
+		if name == strings.ToUpper(name) {
+			return strings.ToUpper(value)
+		}
+		return value
+	})
+
+	return expanded, filled
+}
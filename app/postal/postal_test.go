@@ -0,0 +1,79 @@
+package postal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		address Address
+		want    []string
+	}{
+		{
+			Address{StreetName: "Strada Memorandumului", StreetNo: "28", Building: "A1", Apartment: "12", City: "Cluj-Napoca", County: "Cluj", PostalCode: "400114", Country: "RO"},
+			[]string{"Strada Memorandumului nr. 28", "bl. A1, ap. 12", "400114 Cluj-Napoca, jud. Cluj", "ROMANIA"},
+		},
+		{
+			Address{StreetName: "Unter den Linden", StreetNo: "77", City: "Berlin", County: "Berlin", PostalCode: "10117", Country: "DE"},
+			[]string{"Unter den Linden 77", "10117 Berlin", "GERMANY"},
+		},
+		{
+			Address{StreetName: "rue de Rivoli", StreetNo: "99", Apartment: "4", City: "Paris", PostalCode: "75001", Country: "France"},
+			[]string{"99 rue de Rivoli", "Appt 4", "75001 PARIS", "FRANCE"},
+		},
+		{
+			Address{StreetName: "Via del Corso", StreetNo: "1", City: "Roma", County: "Roma", PostalCode: "00186", Country: "IT"},
+			[]string{"Via del Corso 1", "00186 Roma RM", "ITALY"},
+		},
+		{
+			Address{StreetName: "Main St", StreetNo: "123", Apartment: "4B", City: "Sacramento", County: "California", PostalCode: "95814", Country: "US"},
+			[]string{"123 Main St Apt 4B", "Sacramento, CA 95814", "UNITED STATES"},
+		},
+		{
+			Address{StreetName: "Kossuth Lajos tér", StreetNo: "1-3", City: "Budapest", PostalCode: "1055", Country: "HU"},
+			[]string{"Budapest", "Kossuth Lajos tér 1-3", "1055", "HUNGARY"},
+		},
+		// Unknown countries keep their stored name and the common layout.
+		{
+			Address{StreetName: "High Street", City: "Nowhere", Country: "Atlantis"},
+			[]string{"High Street", "Nowhere", "ATLANTIS"},
+		},
+	}
+	for _, tc := range cases {
+		got := Format(tc.address)
+		if strings.Join(got.Lines, "|") != strings.Join(tc.want, "|") {
+			t.Fatalf("Format(%+v) = %q, want %q", tc.address, got.Lines, tc.want)
+		}
+	}
+}
+
+func TestLabelJoins(t *testing.T) {
+	label := Format(Address{StreetName: "Main St", StreetNo: "123", City: "Sacramento", County: "CA", PostalCode: "95814", Country: "US"})
+	if got := label.SingleLine(); got != "123 Main St, Sacramento, CA 95814, UNITED STATES" {
+		t.Fatalf("unexpected single line: %q", got)
+	}
+	if got := label.MultiLine(); got != "123 Main St\nSacramento, CA 95814\nUNITED STATES" {
+		t.Fatalf("unexpected multi line: %q", got)
+	}
+}
+
+func TestTemplatesAreBalanced(t *testing.T) {
+	for code, template := range templates {
+		depth := 0
+		for _, r := range template {
+			switch r {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+			if depth < 0 || depth > 1 {
+				t.Fatalf("template of %s has unbalanced brackets", code)
+			}
+		}
+		if depth != 0 {
+			t.Fatalf("template of %s has an unclosed bracket", code)
+		}
+	}
+}
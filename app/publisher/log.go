@@ -0,0 +1,32 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/factory"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogPublisher writes every domain event to the service log. It is meant for local runs,
+// where no broker is available.
+type LogPublisher struct {
+	logger logrus.FieldLogger
+}
+
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{logger: factory.NewModuleLogger("event-publisher")}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event *entity.OutboxEvent) error {
+	p.logger.WithFields(logrus.Fields{
+		"event_id":       event.ID,
+		"event_type":     event.EventType,
+		"aggregate_type": event.AggregateType,
+		"aggregate_id":   event.AggregateID,
+		"payload":        string(event.Payload),
+	}).Info("Domain event published")
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+func TestLogPublisherLogsEvent(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	p := &LogPublisher{logger: logger}
+
+	err := p.Publish(context.Background(), &entity.OutboxEvent{
+		ID:            4,
+		AggregateType: "contact",
+		AggregateID:   9,
+		EventType:     "contact.updated",
+		Payload:       []byte(`{"id":9}`),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.InfoLevel {
+		t.Fatalf("expected an info entry, got %+v", entry)
+	}
+	if entry.Data["event_type"] != "contact.updated" || entry.Data["payload"] != `{"id":9}` {
+		t.Fatalf("unexpected log fields: %v", entry.Data)
+	}
+}
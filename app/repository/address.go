@@ -8,12 +8,18 @@ import (
 	"time"
 
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/geo"
 )
 
 var (
 	ErrAddressNotFound = errors.New("address not found")
+	// ErrCompanyReferenceNotFound is returned when an address names a company that does not exist.
+	ErrCompanyReferenceNotFound = errors.New("referenced company does not exist")
 )
 
+// liveAddressOwner limits a restore to addresses whose profile or company is live.
+const liveAddressOwner = `(` + liveProfileOwner + ` OR company_id IN (SELECT id FROM companies WHERE deleted_at IS NULL))`
+
 type AddressDBTX interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
@@ -29,13 +35,18 @@ func NewAddressRepository(db AddressDBTX) *AddressRepository {
 }
 
 func (r *AddressRepository) Create(ctx context.Context, address *entity.Address) error {
+	attributes, err := attributesValue(address.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO addresses (
-			street_name, streen_no, city, county, country, profile_id,
+			street_name, streen_no, city, county, country, profile_id, company_id,
 			postal_code, building, apartment, additional_data, type,
-			created_at, updated_at
+			latitude, longitude, created_at, updated_at, attributes
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		address.StreetName,
@@ -43,17 +54,21 @@ func (r *AddressRepository) Create(ctx context.Context, address *entity.Address)
 		address.City,
 		address.County,
 		address.Country,
-		address.ProfileID,
+		nullOwnerID(address.ProfileID),
+		nullOwnerID(address.CompanyID),
 		address.PostalCode,
 		address.Building,
 		address.Apartment,
 		address.AdditionalData,
 		address.Type,
+		address.Latitude,
+		address.Longitude,
 		address.CreatedAt,
 		address.UpdatedAt,
+		attributes,
 	)
 	if err != nil {
-		return err
+		return addressWriteError(err)
 	}
 
 	id, err := result.LastInsertId()
@@ -61,36 +76,24 @@ func (r *AddressRepository) Create(ctx context.Context, address *entity.Address)
 		return err
 	}
 	address.ID = uint64(id)
+	address.Version = 1
 
 	return nil
 }
 
-func (r *AddressRepository) FindByID(ctx context.Context, id uint64) (*entity.Address, error) {
+func (r *AddressRepository) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error) {
 	query := `
 		SELECT
-			id, street_name, streen_no, city, county, country, profile_id,
-			postal_code, building, apartment, additional_data, type,
-			created_at, updated_at
+			id, street_name, streen_no, city, county, country, profile_id, company_id,
+			postal_code, building, apartment, additional_data, type, is_primary,
+			latitude, longitude, created_at, updated_at, version, deleted_at, attributes
 		FROM addresses
 		WHERE id = ?
 	`
-	address := &entity.Address{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&address.ID,
-		&address.StreetName,
-		&address.StreenNo,
-		&address.City,
-		&address.County,
-		&address.Country,
-		&address.ProfileID,
-		&address.PostalCode,
-		&address.Building,
-		&address.Apartment,
-		&address.AdditionalData,
-		&address.Type,
-		&address.CreatedAt,
-		&address.UpdatedAt,
-	)
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+	address, err := scanAddress(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -101,7 +104,28 @@ func (r *AddressRepository) FindByID(ctx context.Context, id uint64) (*entity.Ad
 	return address, nil
 }
 
+// FindPrimary returns the live primary address of the given type of the profile, or of the
+// company when companyID is set, or nil when there is none, and locks it until the
+// transaction ends.
+func (r *AddressRepository) FindPrimary(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error) {
+	ownerColumn, ownerID := "profile_id", profileID
+	if companyID > 0 {
+		ownerColumn, ownerID = "company_id", companyID
+	}
+	id, err := primaryID(ctx, r.db, "addresses", ownerColumn, ownerID, addressType)
+	if err != nil || id == 0 {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id, false)
+}
+
 func (r *AddressRepository) Update(ctx context.Context, address *entity.Address) error {
+	attributes, err := attributesValue(address.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE addresses SET
 			street_name = ?,
@@ -110,13 +134,19 @@ func (r *AddressRepository) Update(ctx context.Context, address *entity.Address)
 			county = ?,
 			country = ?,
 			profile_id = ?,
+			company_id = ?,
 			postal_code = ?,
 			building = ?,
 			apartment = ?,
 			additional_data = ?,
 			type = ?,
-			updated_at = ?
-		WHERE id = ?
+			attributes = ?,
+			is_primary = ?,
+			latitude = ?,
+			longitude = ?,
+			updated_at = ?,
+			version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
 	`
 	address.UpdatedAt = time.Now()
 	result, err := r.db.ExecContext(ctx, query,
@@ -125,17 +155,26 @@ func (r *AddressRepository) Update(ctx context.Context, address *entity.Address)
 		address.City,
 		address.County,
 		address.Country,
-		address.ProfileID,
+		nullOwnerID(address.ProfileID),
+		nullOwnerID(address.CompanyID),
 		address.PostalCode,
 		address.Building,
 		address.Apartment,
 		address.AdditionalData,
 		address.Type,
+		attributes,
+		address.IsPrimary,
+		address.Latitude,
+		address.Longitude,
 		address.UpdatedAt,
 		address.ID,
+		address.Version,
 	)
 	if err != nil {
-		return err
+		if isDuplicateEntryError(err) {
+			return ErrPrimaryConflict
+		}
+		return addressWriteError(err)
 	}
 
 	affected, err := result.RowsAffected()
@@ -143,46 +182,80 @@ func (r *AddressRepository) Update(ctx context.Context, address *entity.Address)
 		return err
 	}
 	if affected == 0 {
-		return nil
+		return staleWriteError(ctx, r.db, "addresses", address.ID, ErrAddressNotFound)
 	}
+	address.Version++
 
 	return nil
 }
 
-func (r *AddressRepository) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM addresses WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+// Delete soft-deletes the address; a non-zero expectedVersion only deletes that version.
+func (r *AddressRepository) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return softDelete(ctx, r.db, "addresses", id, expectedVersion, true, ErrAddressNotFound)
+}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return ErrAddressNotFound
-	}
+// DeleteByProfileID soft-deletes every live address of the profile.
+func (r *AddressRepository) DeleteByProfileID(ctx context.Context, profileID uint64) error {
+	return softDeleteByProfileID(ctx, r.db, "addresses", profileID)
+}
 
-	return nil
+// Restore undeletes the address. It fails with ErrProfileReferenceNotFound while its profile or
+// company is deleted.
+func (r *AddressRepository) Restore(ctx context.Context, id uint64) error {
+	return restore(ctx, r.db, "addresses", id, liveAddressOwner, ErrAddressNotFound)
 }
 
-func (r *AddressRepository) List(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+// RestoreByProfileID undeletes the addresses of the profile that were deleted at or after deletedSince.
+func (r *AddressRepository) RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error {
+	return restoreByProfileID(ctx, r.db, "addresses", profileID, deletedSince)
+}
+
+// DeleteByCompanyID soft-deletes every live address of the company.
+func (r *AddressRepository) DeleteByCompanyID(ctx context.Context, companyID uint64) error {
+	return softDeleteByOwner(ctx, r.db, "addresses", "company_id", companyID)
+}
+
+// RestoreByCompanyID undeletes the addresses of the company that were deleted at or after deletedSince.
+func (r *AddressRepository) RestoreByCompanyID(ctx context.Context, companyID uint64, deletedSince time.Time) error {
+	return restoreByOwner(ctx, r.db, "addresses", "company_id", companyID, deletedSince)
+}
+
+// Purge hard-deletes at most limit addresses soft-deleted before deletedBefore.
+func (r *AddressRepository) Purge(ctx context.Context, deletedBefore time.Time, limit uint32) (int64, error) {
+	return purgeDeleted(ctx, r.db, "addresses", deletedBefore, limit)
+}
+
+// List returns a page of addresses, newest first. A non-zero profileID or companyID keeps only
+// the addresses of that owner, and attributes only those holding each of its values.
+func (r *AddressRepository) List(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 	if limit == 0 {
 		limit = 20
 	}
 
 	addressType = strings.TrimSpace(addressType)
-	whereClauses := make([]string, 0, 2)
-	countArgs := make([]interface{}, 0, 2)
+	whereClauses := make([]string, 0, 5)
+	countArgs := make([]interface{}, 0, 3)
+	if !includeDeleted {
+		whereClauses = append(whereClauses, "deleted_at IS NULL")
+	}
+	if primaryOnly {
+		whereClauses = append(whereClauses, "is_primary = 1")
+	}
 	if profileID > 0 {
 		whereClauses = append(whereClauses, "profile_id = ?")
 		countArgs = append(countArgs, profileID)
 	}
+	if companyID > 0 {
+		whereClauses = append(whereClauses, "company_id = ?")
+		countArgs = append(countArgs, companyID)
+	}
 	if addressType != "" {
 		whereClauses = append(whereClauses, "`type` = ?")
 		countArgs = append(countArgs, addressType)
 	}
+	attributeClauses, attributeArgs := attributeFilters(attributes)
+	whereClauses = append(whereClauses, attributeClauses...)
+	countArgs = append(countArgs, attributeArgs...)
 
 	countQuery := strings.Builder{}
 	countQuery.WriteString(`SELECT COUNT(*) FROM addresses`)
@@ -199,12 +272,12 @@ func (r *AddressRepository) List(ctx context.Context, profileID uint64, addressT
 	query := strings.Builder{}
 	query.WriteString(`
 		SELECT
-			id, street_name, streen_no, city, county, country, profile_id,
-			postal_code, building, apartment, additional_data, type,
-			created_at, updated_at
+			id, street_name, streen_no, city, county, country, profile_id, company_id,
+			postal_code, building, apartment, additional_data, type, is_primary,
+			latitude, longitude, created_at, updated_at, version, deleted_at, attributes
 		FROM addresses
 	`)
-	args := make([]interface{}, 0, 4)
+	args := make([]interface{}, 0, 5)
 	if len(whereClauses) > 0 {
 		query.WriteString(` WHERE `)
 		query.WriteString(strings.Join(whereClauses, " AND "))
@@ -219,33 +292,196 @@ func (r *AddressRepository) List(ctx context.Context, profileID uint64, addressT
 	}
 	defer rows.Close()
 
+	addresses, err := scanAddresses(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return addresses, total, nil
+}
+
+// AddressSearch selects the addresses SearchNear returns: those within RadiusMeters of Center,
+// or inside Box when Center is nil.
+type AddressSearch struct {
+	Center       *geo.Point
+	RadiusMeters float64
+	Box          *geo.Box
+	ProfileID    uint64
+	Type         string
+	Limit        uint32
+}
+
+// SearchNear returns live addresses with coordinates matching search, nearest first for a
+// radius search and newest first for a box search. Coordinates are compared as planar
+// degrees by the spatial index and the radius by great-circle distance.
+func (r *AddressRepository) SearchNear(ctx context.Context, search AddressSearch) ([]*entity.NearbyAddress, error) {
+	if search.Limit == 0 {
+		search.Limit = 20
+	}
+
+	box := search.Box
+	distance := `0`
+	distanceArgs := make([]interface{}, 0, 2)
+	if search.Center != nil {
+		around := geo.Around(*search.Center, search.RadiusMeters)
+		box = &around
+		distance = `ST_Distance_Sphere(location, POINT(?, ?))`
+		distanceArgs = append(distanceArgs, search.Center.Longitude, search.Center.Latitude)
+	}
+
+	query := strings.Builder{}
+	query.WriteString(`
+		SELECT
+			id, street_name, streen_no, city, county, country, profile_id, company_id,
+			postal_code, building, apartment, additional_data, type, is_primary,
+			latitude, longitude, created_at, updated_at, version, deleted_at, attributes,
+			` + distance + ` AS distance
+		FROM addresses
+		WHERE deleted_at IS NULL AND latitude IS NOT NULL
+			AND MBRCovers(ST_MakeEnvelope(POINT(?, ?), POINT(?, ?)), location)
+	`)
+	args := append(distanceArgs, box.MinLongitude, box.MinLatitude, box.MaxLongitude, box.MaxLatitude)
+	if search.ProfileID > 0 {
+		query.WriteString(` AND profile_id = ?`)
+		args = append(args, search.ProfileID)
+	}
+	if addressType := strings.TrimSpace(search.Type); addressType != "" {
+		query.WriteString(" AND `type` = ?")
+		args = append(args, addressType)
+	}
+	if search.Center != nil {
+		query.WriteString(` HAVING distance <= ? ORDER BY distance ASC, id ASC`)
+		args = append(args, search.RadiusMeters)
+	} else {
+		query.WriteString(` ORDER BY id DESC`)
+	}
+	query.WriteString(` LIMIT ?`)
+	args = append(args, search.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make([]*entity.NearbyAddress, 0)
+	for rows.Next() {
+		nearby := &entity.NearbyAddress{}
+		if nearby.Address, err = scanAddress(rows, &nearby.DistanceMeters); err != nil {
+			return nil, err
+		}
+		found = append(found, nearby)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// ListByProfileID returns every live address of the profile, oldest first.
+func (r *AddressRepository) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Address, error) {
+	return r.listByOwner(ctx, "profile_id", profileID)
+}
+
+// ListByCompanyID returns every live address of the company, oldest first.
+func (r *AddressRepository) ListByCompanyID(ctx context.Context, companyID uint64) ([]*entity.Address, error) {
+	return r.listByOwner(ctx, "company_id", companyID)
+}
+
+func (r *AddressRepository) listByOwner(ctx context.Context, ownerColumn string, ownerID uint64) ([]*entity.Address, error) {
+	query := `
+		SELECT
+			id, street_name, streen_no, city, county, country, profile_id, company_id,
+			postal_code, building, apartment, additional_data, type, is_primary,
+			latitude, longitude, created_at, updated_at, version, deleted_at, attributes
+		FROM addresses
+		WHERE ` + ownerColumn + ` = ? AND deleted_at IS NULL
+		ORDER BY id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAddresses(rows)
+}
+
+func scanAddresses(rows *sql.Rows) ([]*entity.Address, error) {
 	addresses := make([]*entity.Address, 0)
 	for rows.Next() {
-		address := &entity.Address{}
-		if err = rows.Scan(
-			&address.ID,
-			&address.StreetName,
-			&address.StreenNo,
-			&address.City,
-			&address.County,
-			&address.Country,
-			&address.ProfileID,
-			&address.PostalCode,
-			&address.Building,
-			&address.Apartment,
-			&address.AdditionalData,
-			&address.Type,
-			&address.CreatedAt,
-			&address.UpdatedAt,
-		); err != nil {
-			return nil, 0, err
+		address, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
 		}
 		addresses = append(addresses, address)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, 0, err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return addresses, total, nil
+	return addresses, nil
+}
+
+// scanAddress reads the address columns in the order the queries above select them, followed
+// by any extra columns into extra.
+func scanAddress(row interface{ Scan(dest ...any) error }, extra ...any) (*entity.Address, error) {
+	address := &entity.Address{}
+	var profileID, companyID sql.NullInt64
+	var latitude, longitude sql.NullFloat64
+	var deletedAt sql.NullTime
+	dest := []any{
+		&address.ID,
+		&address.StreetName,
+		&address.StreenNo,
+		&address.City,
+		&address.County,
+		&address.Country,
+		&profileID,
+		&companyID,
+		&address.PostalCode,
+		&address.Building,
+		&address.Apartment,
+		&address.AdditionalData,
+		&address.Type,
+		&address.IsPrimary,
+		&latitude,
+		&longitude,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+		&address.Version,
+		&deletedAt,
+		attributesColumn{&address.Attributes},
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	address.ProfileID, address.CompanyID = uint64(profileID.Int64), uint64(companyID.Int64)
+	address.DeletedAt = nullTimePtr(deletedAt)
+	if latitude.Valid && longitude.Valid {
+		address.Latitude, address.Longitude = &latitude.Float64, &longitude.Float64
+	}
+
+	return address, nil
+}
+
+// nullOwnerID stores an unset owner id as NULL.
+func nullOwnerID(id uint64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// addressWriteError maps a failed foreign key to the missing profile or company it names.
+func addressWriteError(err error) error {
+	if !isForeignKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), "fk_addresses_company_id") {
+		return ErrCompanyReferenceNotFound
+	}
+	return ErrProfileReferenceNotFound
 }
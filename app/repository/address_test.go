@@ -3,15 +3,22 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/geo"
 )
 
 type fakeAddressDB struct {
 	execFn func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// rowDB answers QueryRowContext when set.
+	rowDB   *sql.DB
+	queryFn func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 }
 
 func (f *fakeAddressDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
@@ -21,11 +28,17 @@ func (f *fakeAddressDB) ExecContext(ctx context.Context, query string, args ...i
 	return fakeResult{lastInsertID: 1, rowsAffected: 1}, nil
 }
 
-func (f *fakeAddressDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+func (f *fakeAddressDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if f.rowDB != nil {
+		return f.rowDB.QueryRowContext(ctx, query, args...)
+	}
 	return nil
 }
 
-func (f *fakeAddressDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+func (f *fakeAddressDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if f.queryFn != nil {
+		return f.queryFn(ctx, query, args...)
+	}
 	return nil, nil
 }
 
@@ -67,15 +80,17 @@ func TestAddressCreateLastInsertIDError(t *testing.T) {
 	}
 }
 
-func TestAddressUpdateNoopWhenNoRowsAffected(t *testing.T) {
+func TestAddressUpdateVersionConflictWhenNoRowsAffected(t *testing.T) {
 	repo := NewAddressRepository(&fakeAddressDB{
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{rowsAffected: 0}, nil
 		},
+		rowDB: newQueryTestDB(t, queryCase{columns: []string{"1"}, row: []driver.Value{int64(1)}}),
 	})
 
-	if err := repo.Update(context.Background(), &entity.Address{ID: 1}); err != nil {
-		t.Fatalf("expected no error for no-op update, got %v", err)
+	err := repo.Update(context.Background(), &entity.Address{ID: 1, Version: 3})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
 	}
 }
 
@@ -86,8 +101,175 @@ func TestAddressDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
 		},
 	})
 
-	err := repo.Delete(context.Background(), 1)
+	err := repo.Delete(context.Background(), 1, 0)
 	if !errors.Is(err, ErrAddressNotFound) {
 		t.Fatalf("expected ErrAddressNotFound, got %v", err)
 	}
 }
+
+func TestAddressCreateAndUpdateMapForeignKeyError(t *testing.T) {
+	repo := NewAddressRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return nil, &mysqlDriver.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}
+		},
+	})
+
+	if err := repo.Create(context.Background(), &entity.Address{ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound on create, got: %v", err)
+	}
+	if err := repo.Update(context.Background(), &entity.Address{ID: 1, ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound on update, got: %v", err)
+	}
+}
+
+func TestAddressCreateMapsCompanyForeignKeyError(t *testing.T) {
+	var gotArgs []interface{}
+	repo := NewAddressRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			gotArgs = args
+			return nil, &mysqlDriver.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`profile`.`addresses`, CONSTRAINT `fk_addresses_company_id` FOREIGN KEY (`company_id`) REFERENCES `companies` (`id`))"}
+		},
+	})
+
+	if err := repo.Create(context.Background(), &entity.Address{CompanyID: 404}); !errors.Is(err, ErrCompanyReferenceNotFound) {
+		t.Fatalf("expected ErrCompanyReferenceNotFound, got: %v", err)
+	}
+	// The missing profile is written as NULL.
+	if gotArgs[5] != nil || gotArgs[6] != uint64(404) {
+		t.Fatalf("unexpected owner args: %v, %v", gotArgs[5], gotArgs[6])
+	}
+}
+
+func TestAddressListByCompanyID(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := newQueryTestDB(t, queryCase{
+		columns: addressColumns,
+		row: []driver.Value{
+			int64(5), "Calea Victoriei", "1", "Bucuresti", "Bucuresti", "RO", nil, int64(3),
+			"010061", "", "", "", "registered_office", true,
+			nil, nil, now, now, int64(1), nil, []byte(`{"dock":"B"}`),
+		},
+	})
+	var gotQuery string
+	repo := NewAddressRepository(&fakeAddressDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery = query
+			if len(args) != 1 || args[0] != uint64(3) {
+				t.Fatalf("unexpected args: %v", args)
+			}
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	addresses, err := repo.ListByCompanyID(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(addresses) != 1 || addresses[0].CompanyID != 3 || addresses[0].ProfileID != 0 || !addresses[0].IsPrimary || addresses[0].Attributes["dock"] != "B" {
+		t.Fatalf("unexpected addresses: %+v", addresses)
+	}
+	if !strings.Contains(gotQuery, "WHERE company_id = ? AND deleted_at IS NULL") {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+}
+
+var addressColumns = []string{
+	"id", "street_name", "streen_no", "city", "county", "country", "profile_id", "company_id",
+	"postal_code", "building", "apartment", "additional_data", "type", "is_primary",
+	"latitude", "longitude", "created_at", "updated_at", "version", "deleted_at", "attributes",
+}
+
+func TestAddressListFiltersByAttributes(t *testing.T) {
+	var gotQuery string
+	var gotArgs []interface{}
+	rows := newQueryTestDB(t, queryCase{columns: addressColumns})
+	repo := NewAddressRepository(&fakeAddressDB{
+		rowDB: newQueryTestDB(t, queryCase{columns: []string{"count"}, row: []driver.Value{int64(0)}}),
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	_, _, err := repo.List(context.Background(), 7, 0, "", map[string]string{"floor_code": "2B", "dock": "B"}, false, false, 20, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(gotQuery, "profile_id = ? AND JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) = ? AND JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) = ?") {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+	// Filters follow the attribute names in order.
+	if len(gotArgs) != 7 || gotArgs[1] != `$."dock"` || gotArgs[2] != "B" || gotArgs[3] != `$."floor_code"` || gotArgs[4] != "2B" {
+		t.Fatalf("unexpected args: %v", gotArgs)
+	}
+}
+
+func TestAddressSearchNearByRadius(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := newQueryTestDB(t, queryCase{
+		columns: append(addressColumns, "distance"),
+		row: []driver.Value{
+			int64(4), "Memorandumului", "28", "Cluj-Napoca", "Cluj", "RO", int64(7), nil,
+			"400114", "", "", "", "billing", false,
+			46.7694, 23.5899, now, now, int64(1), nil, nil,
+			812.5,
+		},
+	})
+	var gotQuery string
+	var gotArgs []interface{}
+	repo := NewAddressRepository(&fakeAddressDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	found, err := repo.SearchNear(context.Background(), AddressSearch{
+		Center:       &geo.Point{Latitude: 46.7712, Longitude: 23.6236},
+		RadiusMeters: 5000,
+		ProfileID:    7,
+		Type:         "billing",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(found) != 1 || found[0].DistanceMeters != 812.5 || found[0].Address.ID != 4 {
+		t.Fatalf("unexpected result: %+v", found)
+	}
+	if lat, lng := found[0].Address.Latitude, found[0].Address.Longitude; lat == nil || lng == nil || *lat != 46.7694 || *lng != 23.5899 {
+		t.Fatalf("unexpected coordinates: %v, %v", lat, lng)
+	}
+	if !strings.Contains(gotQuery, "ST_Distance_Sphere(location, POINT(?, ?)) AS distance") || !strings.Contains(gotQuery, "HAVING distance <= ?") {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+	// Points are written longitude first; the envelope follows the center, then the filters.
+	if len(gotArgs) != 10 || gotArgs[0] != 23.6236 || gotArgs[1] != 46.7712 || gotArgs[6] != uint64(7) || gotArgs[7] != "billing" || gotArgs[8] != 5000.0 || gotArgs[9] != uint32(20) {
+		t.Fatalf("unexpected args: %v", gotArgs)
+	}
+}
+
+func TestAddressSearchNearByBox(t *testing.T) {
+	rows := newQueryTestDB(t, queryCase{columns: append(addressColumns, "distance")})
+	var gotQuery string
+	var gotArgs []interface{}
+	repo := NewAddressRepository(&fakeAddressDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	found, err := repo.SearchNear(context.Background(), AddressSearch{
+		Box:   &geo.Box{MinLatitude: 46.7, MinLongitude: 23.5, MaxLatitude: 46.8, MaxLongitude: 23.7},
+		Limit: 5,
+	})
+	if err != nil || len(found) != 0 {
+		t.Fatalf("expected no addresses, got %+v, %v", found, err)
+	}
+	if strings.Contains(gotQuery, "ST_Distance_Sphere") || !strings.Contains(gotQuery, "ORDER BY id DESC") {
+		t.Fatalf("unexpected query: %s", gotQuery)
+	}
+	if len(gotArgs) != 5 || gotArgs[0] != 23.5 || gotArgs[1] != 46.7 || gotArgs[2] != 23.7 || gotArgs[3] != 46.8 || gotArgs[4] != uint32(5) {
+		t.Fatalf("unexpected args: %v", gotArgs)
+	}
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+const attachmentColumns = `a.id, a.entity_type, a.entity_id, a.kind, a.filename, a.content_type, a.size, a.sha256, a.storage_key, a.created_at`
+
+type AttachmentRepository struct {
+	db QueryDBTX
+}
+
+func NewAttachmentRepository(db QueryDBTX) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *entity.Attachment) error {
+	query := `
+		INSERT INTO attachments (entity_type, entity_id, kind, filename, content_type, size, sha256, storage_key, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		attachment.EntityType,
+		attachment.EntityID,
+		attachment.Kind,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.SHA256,
+		attachment.StorageKey,
+		attachment.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	attachment.ID = uint64(id)
+
+	return nil
+}
+
+// FindByID returns the attachment, or nil when there is none with that id.
+func (r *AttachmentRepository) FindByID(ctx context.Context, id uint64) (*entity.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments a WHERE a.id = ?`
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, id uint64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+// List returns a page of the attachments of one record, newest first. A non-empty kind keeps
+// only the attachments of that kind.
+func (r *AttachmentRepository) List(ctx context.Context, entityType string, entityID uint64, kind string, limit, offset uint32) ([]*entity.Attachment, uint64, error) {
+	if limit == 0 {
+		limit = 20
+	}
+
+	where := ` WHERE a.entity_type = ? AND a.entity_id = ?`
+	args := []interface{}{entityType, entityID}
+	if kind != "" {
+		where += ` AND a.kind = ?`
+		args = append(args, kind)
+	}
+
+	var total uint64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM attachments a`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + attachmentColumns + ` FROM attachments a` + where + ` ORDER BY a.id DESC LIMIT ? OFFSET ?`
+	attachments, err := r.query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attachments, total, nil
+}
+
+// ListPurgeable returns up to limit attachments whose owner was soft-deleted before
+// deletedBefore, or no longer exists, oldest first.
+func (r *AttachmentRepository) ListPurgeable(ctx context.Context, deletedBefore time.Time, limit uint32) ([]*entity.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments a
+		LEFT JOIN profile p ON a.entity_type = 'profile' AND p.id = a.entity_id
+		LEFT JOIN contacts c ON a.entity_type = 'contact' AND c.id = a.entity_id
+		LEFT JOIN addresses ad ON a.entity_type = 'address' AND ad.id = a.entity_id
+		LEFT JOIN companies co ON a.entity_type = 'company' AND co.id = a.entity_id
+		WHERE COALESCE(p.id, c.id, ad.id, co.id) IS NULL
+			OR COALESCE(p.deleted_at, c.deleted_at, ad.deleted_at, co.deleted_at) < ?
+		ORDER BY a.id LIMIT ?
+	`
+	return r.query(ctx, query, deletedBefore, limit)
+}
+
+func (r *AttachmentRepository) query(ctx context.Context, query string, args ...interface{}) ([]*entity.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make([]*entity.Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func scanAttachment(row interface{ Scan(dest ...any) error }) (*entity.Attachment, error) {
+	attachment := &entity.Attachment{}
+	if err := row.Scan(
+		&attachment.ID,
+		&attachment.EntityType,
+		&attachment.EntityID,
+		&attachment.Kind,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.StorageKey,
+		&attachment.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
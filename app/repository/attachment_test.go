@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+var attachmentTestColumns = []string{"id", "entity_type", "entity_id", "kind", "filename", "content_type", "size", "sha256", "storage_key", "created_at"}
+
+func TestAttachmentCreateSetsID(t *testing.T) {
+	repo := NewAttachmentRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return fakeResult{lastInsertID: 31}, nil
+		},
+	})
+
+	attachment := &entity.Attachment{EntityType: "contact", EntityID: 4, Kind: "id_card", ContentType: "image/png", Size: 10, StorageKey: "attachments/k"}
+	if err := repo.Create(context.Background(), attachment); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if attachment.ID != 31 {
+		t.Fatalf("expected id 31, got %d", attachment.ID)
+	}
+}
+
+func TestAttachmentDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
+	repo := NewAttachmentRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return fakeResult{rowsAffected: 0}, nil
+		},
+	})
+
+	if err := repo.Delete(context.Background(), 31); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected ErrAttachmentNotFound, got %v", err)
+	}
+}
+
+func TestAttachmentFindByIDReturnsNilWhenMissing(t *testing.T) {
+	repo := NewAttachmentRepository(&fakeAddressDB{
+		rowDB: newQueryTestDB(t, queryCase{queryErr: sql.ErrNoRows}),
+	})
+
+	attachment, err := repo.FindByID(context.Background(), 31)
+	if err != nil || attachment != nil {
+		t.Fatalf("expected nil attachment and no error, got %v, %v", attachment, err)
+	}
+}
+
+func TestAttachmentListPurgeableJoinsOwners(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := newQueryTestDB(t, queryCase{
+		columns: attachmentTestColumns,
+		row:     []driver.Value{int64(31), "contact", int64(4), "id_card", "id.png", "image/png", int64(10), strings.Repeat("a", 64), "attachments/k", now},
+	})
+	var gotQuery string
+	var gotArgs []interface{}
+	repo := NewAttachmentRepository(&fakeAddressDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	attachments, err := repo.ListPurgeable(context.Background(), now, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].StorageKey != "attachments/k" || attachments[0].Size != 10 {
+		t.Fatalf("unexpected attachments: %+v", attachments)
+	}
+	for _, join := range []string{"LEFT JOIN profile p", "LEFT JOIN contacts c", "LEFT JOIN addresses ad", "LEFT JOIN companies co"} {
+		if !strings.Contains(gotQuery, join) {
+			t.Fatalf("expected %q in query %s", join, gotQuery)
+		}
+	}
+	if len(gotArgs) != 2 || gotArgs[1] != uint32(50) {
+		t.Fatalf("unexpected args: %v", gotArgs)
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// attributesValue stores custom attributes as a JSON object, or NULL when there are none.
+func attributesValue(values map[string]any) (sql.NullString, error) {
+	if len(values) == 0 {
+		return sql.NullString{}, nil
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(raw), Valid: true}, nil
+}
+
+// attributesColumn scans the attributes column into the map it points to, leaving it nil for
+// NULL.
+type attributesColumn struct {
+	values *map[string]any
+}
+
+func (c attributesColumn) Scan(src any) error {
+	*c.values = nil
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("unsupported attributes value %T", src)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(raw, c.values)
+}
+
+// attributeFilters returns the where clauses and arguments keeping the rows whose attributes
+// hold every given value. Values are compared as text, so 5 matches "5" and true matches "true".
+func attributeFilters(filters map[string]string) ([]string, []interface{}) {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	clauses := make([]string, 0, len(names))
+	args := make([]interface{}, 0, 2*len(names))
+	for _, name := range names {
+		clauses = append(clauses, "JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) = ?")
+		args = append(args, `$."`+name+`"`, filters[name])
+	}
+
+	return clauses, args
+}
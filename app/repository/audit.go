@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+type AuditRepository struct {
+	db QueryDBTX
+}
+
+func NewAuditRepository(db QueryDBTX) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, event *entity.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (entity_type, entity_id, action, old_values, new_values, caller_service, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		event.EntityType,
+		event.EntityID,
+		event.Action,
+		nullJSON(event.OldValues),
+		nullJSON(event.NewValues),
+		event.CallerService,
+		event.RequestID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.ID = uint64(id)
+
+	return nil
+}
+
+// List returns the events of one entity, newest first.
+func (r *AuditRepository) List(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error) {
+	if limit == 0 {
+		limit = 20
+	}
+
+	var total uint64
+	countQuery := `SELECT COUNT(*) FROM audit_events WHERE entity_type = ? AND entity_id = ?`
+	if err := r.db.QueryRowContext(ctx, countQuery, entityType, entityID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, entity_type, entity_id, action, old_values, new_values, caller_service, request_id, created_at
+		FROM audit_events
+		WHERE entity_type = ? AND entity_id = ?
+		ORDER BY id DESC LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, entityType, entityID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := make([]*entity.AuditEvent, 0)
+	for rows.Next() {
+		event := &entity.AuditEvent{}
+		var oldValues, newValues []byte
+		if err = rows.Scan(
+			&event.ID,
+			&event.EntityType,
+			&event.EntityID,
+			&event.Action,
+			&oldValues,
+			&newValues,
+			&event.CallerService,
+			&event.RequestID,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		event.OldValues = oldValues
+		event.NewValues = newValues
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// nullJSON stores an absent snapshot as SQL NULL rather than an empty JSON document.
+func nullJSON(raw []byte) sql.NullString {
+	if len(raw) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(raw), Valid: true}
+}
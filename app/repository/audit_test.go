@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+func TestAuditCreateStoresMissingSnapshotAsNull(t *testing.T) {
+	repo := NewAuditRepository(&fakeContactDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.Contains(query, "INSERT INTO audit_events") {
+				t.Fatalf("unexpected query %q", query)
+			}
+			if oldValues := args[3].(sql.NullString); oldValues.Valid {
+				t.Fatalf("expected NULL old_values for a create, got %+v", oldValues)
+			}
+			if newValues := args[4].(sql.NullString); !newValues.Valid || newValues.String != `{"email":"a@b.c"}` {
+				t.Fatalf("unexpected new_values: %+v", newValues)
+			}
+			if args[5] != "billing" || args[6] != "req-1" {
+				t.Fatalf("expected caller and request id, got %v", args)
+			}
+			return fakeResult{lastInsertID: 12, rowsAffected: 1}, nil
+		},
+	})
+
+	event := &entity.AuditEvent{
+		EntityType:    "profile",
+		EntityID:      3,
+		Action:        "create",
+		NewValues:     json.RawMessage(`{"email":"a@b.c"}`),
+		CallerService: "billing",
+		RequestID:     "req-1",
+		CreatedAt:     time.Now(),
+	}
+	if err := repo.Create(context.Background(), event); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if event.ID != 12 {
+		t.Fatalf("expected id 12, got %d", event.ID)
+	}
+}
@@ -12,8 +12,13 @@ import (
 
 var (
 	ErrCompanyNotFound = errors.New("company not found")
+	// ErrCompanyFiscalCodeTaken is returned when another live company has the fiscal code.
+	ErrCompanyFiscalCodeTaken = errors.New("another live company has this fiscal code")
 )
 
+// companyFiscalCodeKey is the unique index over the fiscal codes of live companies.
+const companyFiscalCodeKey = "uq_companies_live_fiscal_code"
+
 type CompanyDBTX interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
@@ -29,20 +34,34 @@ func NewCompanyRepository(db CompanyDBTX) *CompanyRepository {
 }
 
 func (r *CompanyRepository) Create(ctx context.Context, company *entity.Company) error {
+	attributes, err := attributesValue(company.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO companies (name, registration_no, fiscal_code, profile_id, type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO companies (name, registration_no, fiscal_code, fiscal_code_valid, vat_payer_prefix, profile_id, type, attributes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		company.Name,
 		company.RegistrationNo,
 		company.FiscalCode,
+		company.FiscalCodeValid,
+		company.VATPayerPrefix,
 		company.ProfileID,
 		company.Type,
+		attributes,
 		company.CreatedAt,
 		company.UpdatedAt,
 	)
 	if err != nil {
+		if isForeignKeyError(err) {
+			return ErrProfileReferenceNotFound
+		}
+		if isDuplicateKeyError(err, companyFiscalCodeKey) {
+			return ErrCompanyFiscalCodeTaken
+		}
 		return err
 	}
 
@@ -51,25 +70,36 @@ func (r *CompanyRepository) Create(ctx context.Context, company *entity.Company)
 		return err
 	}
 	company.ID = uint64(id)
+	company.Version = 1
 
 	return nil
 }
 
-func (r *CompanyRepository) FindByID(ctx context.Context, id uint64) (*entity.Company, error) {
+func (r *CompanyRepository) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error) {
 	query := `
-		SELECT id, name, registration_no, fiscal_code, profile_id, type, created_at, updated_at
+		SELECT id, name, registration_no, fiscal_code, fiscal_code_valid, vat_payer_prefix, profile_id, type, is_primary, created_at, updated_at, version, deleted_at, attributes
 		FROM companies WHERE id = ?
 	`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 	company := &entity.Company{}
+	var deletedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&company.ID,
 		&company.Name,
 		&company.RegistrationNo,
 		&company.FiscalCode,
+		&company.FiscalCodeValid,
+		&company.VATPayerPrefix,
 		&company.ProfileID,
 		&company.Type,
+		&company.IsPrimary,
 		&company.CreatedAt,
 		&company.UpdatedAt,
+		&company.Version,
+		&deletedAt,
+		attributesColumn{&company.Attributes},
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -78,31 +108,83 @@ func (r *CompanyRepository) FindByID(ctx context.Context, id uint64) (*entity.Co
 		return nil, err
 	}
 
+	company.DeletedAt = nullTimePtr(deletedAt)
+
 	return company, nil
 }
 
+// FindPrimary returns the live primary company of the given type of the profile, or nil when
+// there is none, and locks it until the transaction ends.
+func (r *CompanyRepository) FindPrimary(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error) {
+	id, err := primaryID(ctx, r.db, "companies", "profile_id", profileID, companyType)
+	if err != nil || id == 0 {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id, false)
+}
+
+// FindByFiscalCode returns the live company with the fiscal code, or nil when there is none, and
+// locks the code until the transaction ends so that no other company can take it meanwhile.
+func (r *CompanyRepository) FindByFiscalCode(ctx context.Context, fiscalCode string) (*entity.Company, error) {
+	var id uint64
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM companies WHERE live_fiscal_code = ? FOR UPDATE`, fiscalCode).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id, false)
+}
+
 func (r *CompanyRepository) Update(ctx context.Context, company *entity.Company) error {
+	attributes, err := attributesValue(company.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE companies SET
 			name = ?,
 			registration_no = ?,
 			fiscal_code = ?,
+			fiscal_code_valid = ?,
+			vat_payer_prefix = ?,
 			profile_id = ?,
 			type = ?,
-			updated_at = ?
-		WHERE id = ?
+			attributes = ?,
+			is_primary = ?,
+			updated_at = ?,
+			version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
 	`
 	company.UpdatedAt = time.Now()
 	result, err := r.db.ExecContext(ctx, query,
 		company.Name,
 		company.RegistrationNo,
 		company.FiscalCode,
+		company.FiscalCodeValid,
+		company.VATPayerPrefix,
 		company.ProfileID,
 		company.Type,
+		attributes,
+		company.IsPrimary,
 		company.UpdatedAt,
 		company.ID,
+		company.Version,
 	)
 	if err != nil {
+		if isForeignKeyError(err) {
+			return ErrProfileReferenceNotFound
+		}
+		if isDuplicateKeyError(err, companyFiscalCodeKey) {
+			return ErrCompanyFiscalCodeTaken
+		}
+		if isDuplicateEntryError(err) {
+			return ErrPrimaryConflict
+		}
 		return err
 	}
 
@@ -111,46 +193,85 @@ func (r *CompanyRepository) Update(ctx context.Context, company *entity.Company)
 		return err
 	}
 	if affected == 0 {
-		return nil
+		return staleWriteError(ctx, r.db, "companies", company.ID, ErrCompanyNotFound)
 	}
+	company.Version++
 
 	return nil
 }
 
-func (r *CompanyRepository) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM companies WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+// Delete soft-deletes the company; a non-zero expectedVersion only deletes that version.
+func (r *CompanyRepository) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return softDelete(ctx, r.db, "companies", id, expectedVersion, true, ErrCompanyNotFound)
+}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
+// DeleteByProfileID soft-deletes every live company of the profile.
+func (r *CompanyRepository) DeleteByProfileID(ctx context.Context, profileID uint64) error {
+	return softDeleteByProfileID(ctx, r.db, "companies", profileID)
+}
+
+// Restore undeletes the company. It fails with ErrProfileReferenceNotFound while its profile is deleted
+// and with ErrCompanyFiscalCodeTaken while another live company has its fiscal code.
+func (r *CompanyRepository) Restore(ctx context.Context, id uint64) error {
+	err := restore(ctx, r.db, "companies", id, liveProfileOwner, ErrCompanyNotFound)
+	if isDuplicateKeyError(err, companyFiscalCodeKey) {
+		return ErrCompanyFiscalCodeTaken
 	}
-	if affected == 0 {
-		return ErrCompanyNotFound
+	return err
+}
+
+// RestoreByProfileID undeletes the companies of the profile that were deleted at or after deletedSince.
+// It fails with ErrCompanyFiscalCodeTaken while another live company has the fiscal code of one of them.
+func (r *CompanyRepository) RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error {
+	err := restoreByProfileID(ctx, r.db, "companies", profileID, deletedSince)
+	if isDuplicateKeyError(err, companyFiscalCodeKey) {
+		return ErrCompanyFiscalCodeTaken
 	}
+	return err
+}
 
-	return nil
+// Purge hard-deletes at most limit companies soft-deleted before deletedBefore.
+func (r *CompanyRepository) Purge(ctx context.Context, deletedBefore time.Time, limit uint32) (int64, error) {
+	return purgeDeleted(ctx, r.db, "companies", deletedBefore, limit)
 }
 
-func (r *CompanyRepository) List(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+// companyOfProfileClause keeps the companies filed under a profile or having it as a member. It
+// takes the profile id twice.
+const companyOfProfileClause = "(profile_id = ? OR id IN (SELECT m.company_id FROM company_members m WHERE m.profile_id = ?))"
+
+// List returns a page of companies, newest first. A non-zero profileID keeps the companies
+// filed under the profile or having it as a member, or with primaryOnly only the primary
+// companies filed under it. A non-empty attributes keeps the companies holding each of its
+// values.
+func (r *CompanyRepository) List(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
 	if limit == 0 {
 		limit = 20
 	}
 
 	companyType = strings.TrimSpace(companyType)
-	whereClauses := make([]string, 0, 2)
+	whereClauses := make([]string, 0, 4)
 	countArgs := make([]interface{}, 0, 2)
-	if profileID > 0 {
+	if !includeDeleted {
+		whereClauses = append(whereClauses, "deleted_at IS NULL")
+	}
+	if primaryOnly {
+		whereClauses = append(whereClauses, "is_primary = 1")
+	}
+	switch {
+	case profileID > 0 && primaryOnly:
 		whereClauses = append(whereClauses, "profile_id = ?")
 		countArgs = append(countArgs, profileID)
+	case profileID > 0:
+		whereClauses = append(whereClauses, companyOfProfileClause)
+		countArgs = append(countArgs, profileID, profileID)
 	}
 	if companyType != "" {
 		whereClauses = append(whereClauses, "`type` = ?")
 		countArgs = append(countArgs, companyType)
 	}
+	attributeClauses, attributeArgs := attributeFilters(attributes)
+	whereClauses = append(whereClauses, attributeClauses...)
+	countArgs = append(countArgs, attributeArgs...)
 
 	countQuery := strings.Builder{}
 	countQuery.WriteString(`SELECT COUNT(*) FROM companies`)
@@ -166,7 +287,7 @@ func (r *CompanyRepository) List(ctx context.Context, profileID uint64, companyT
 
 	query := strings.Builder{}
 	query.WriteString(`
-		SELECT id, name, registration_no, fiscal_code, profile_id, type, created_at, updated_at
+		SELECT id, name, registration_no, fiscal_code, fiscal_code_valid, vat_payer_prefix, profile_id, type, is_primary, created_at, updated_at, version, deleted_at, attributes
 		FROM companies
 	`)
 	args := make([]interface{}, 0, 4)
@@ -184,27 +305,79 @@ func (r *CompanyRepository) List(ctx context.Context, profileID uint64, companyT
 	}
 	defer rows.Close()
 
+	companies, err := scanCompanies(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return companies, total, nil
+}
+
+// ListByProfileID returns every live company filed under the profile, oldest first.
+func (r *CompanyRepository) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	query := `
+		SELECT id, name, registration_no, fiscal_code, fiscal_code_valid, vat_payer_prefix, profile_id, type, is_primary, created_at, updated_at, version, deleted_at, attributes
+		FROM companies
+		WHERE profile_id = ? AND deleted_at IS NULL
+		ORDER BY id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCompanies(rows)
+}
+
+// ListForProfile returns every live company filed under the profile or having it as a member,
+// oldest first.
+func (r *CompanyRepository) ListForProfile(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	query := `
+		SELECT id, name, registration_no, fiscal_code, fiscal_code_valid, vat_payer_prefix, profile_id, type, is_primary, created_at, updated_at, version, deleted_at, attributes
+		FROM companies
+		WHERE ` + companyOfProfileClause + ` AND deleted_at IS NULL
+		ORDER BY id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, profileID, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCompanies(rows)
+}
+
+func scanCompanies(rows *sql.Rows) ([]*entity.Company, error) {
 	companies := make([]*entity.Company, 0)
 	for rows.Next() {
 		company := &entity.Company{}
-		if err = rows.Scan(
+		var deletedAt sql.NullTime
+		if err := rows.Scan(
 			&company.ID,
 			&company.Name,
 			&company.RegistrationNo,
 			&company.FiscalCode,
+			&company.FiscalCodeValid,
+			&company.VATPayerPrefix,
 			&company.ProfileID,
 			&company.Type,
+			&company.IsPrimary,
 			&company.CreatedAt,
 			&company.UpdatedAt,
+			&company.Version,
+			&deletedAt,
+			attributesColumn{&company.Attributes},
 		); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
+		company.DeletedAt = nullTimePtr(deletedAt)
 		companies = append(companies, company)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, 0, err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return companies, total, nil
+	return companies, nil
 }
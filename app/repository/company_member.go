@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+var (
+	ErrCompanyMemberNotFound = errors.New("company member not found")
+)
+
+type CompanyMemberRepository struct {
+	db QueryDBTX
+}
+
+func NewCompanyMemberRepository(db QueryDBTX) *CompanyMemberRepository {
+	return &CompanyMemberRepository{db: db}
+}
+
+// Save adds the profile to the company, or changes its role when it already is a member.
+func (r *CompanyMemberRepository) Save(ctx context.Context, member *entity.CompanyMember) error {
+	query := `
+		INSERT INTO company_members (company_id, profile_id, role, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role), updated_at = VALUES(updated_at)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		member.CompanyID,
+		member.ProfileID,
+		member.Role,
+		member.CreatedAt,
+		member.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return ErrProfileReferenceNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Find returns the membership of the profile in the company, or nil when it is not a member,
+// and locks it until the transaction ends.
+func (r *CompanyMemberRepository) Find(ctx context.Context, companyID, profileID uint64) (*entity.CompanyMember, error) {
+	query := `
+		SELECT company_id, profile_id, role, created_at, updated_at
+		FROM company_members WHERE company_id = ? AND profile_id = ?
+		FOR UPDATE
+	`
+	member := &entity.CompanyMember{}
+	err := r.db.QueryRowContext(ctx, query, companyID, profileID).Scan(
+		&member.CompanyID,
+		&member.ProfileID,
+		&member.Role,
+		&member.CreatedAt,
+		&member.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// CountByRole counts the members of the company holding role and locks them until the
+// transaction ends, so that two writers cannot both take away the last one.
+func (r *CompanyMemberRepository) CountByRole(ctx context.Context, companyID uint64, role string) (uint64, error) {
+	query := `SELECT COUNT(*) FROM company_members WHERE company_id = ? AND role = ? FOR UPDATE`
+
+	var count uint64
+	if err := r.db.QueryRowContext(ctx, query, companyID, role).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *CompanyMemberRepository) Delete(ctx context.Context, companyID, profileID uint64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM company_members WHERE company_id = ? AND profile_id = ?`, companyID, profileID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCompanyMemberNotFound
+	}
+
+	return nil
+}
+
+// ListByCompanyID returns the members of the company whose profiles are not deleted, oldest first.
+func (r *CompanyMemberRepository) ListByCompanyID(ctx context.Context, companyID uint64, limit, offset uint32) ([]*entity.CompanyMember, uint64, error) {
+	if limit == 0 {
+		limit = 20
+	}
+
+	countQuery := `
+		SELECT COUNT(*) FROM company_members m
+		JOIN profile p ON p.id = m.profile_id AND p.deleted_at IS NULL
+		WHERE m.company_id = ?
+	`
+	var total uint64
+	if err := r.db.QueryRowContext(ctx, countQuery, companyID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT m.company_id, m.profile_id, m.role, m.created_at, m.updated_at
+		FROM company_members m
+		JOIN profile p ON p.id = m.profile_id AND p.deleted_at IS NULL
+		WHERE m.company_id = ?
+		ORDER BY m.created_at ASC, m.profile_id ASC LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, companyID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	members := make([]*entity.CompanyMember, 0)
+	for rows.Next() {
+		member := &entity.CompanyMember{}
+		if err = rows.Scan(&member.CompanyID, &member.ProfileID, &member.Role, &member.CreatedAt, &member.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		members = append(members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return members, total, nil
+}
+
+// ListByProfileID returns the live companies the profile is a member of with its role in each,
+// newest first.
+func (r *CompanyMemberRepository) ListByProfileID(ctx context.Context, profileID uint64, limit, offset uint32) ([]*entity.CompanyMembership, uint64, error) {
+	if limit == 0 {
+		limit = 20
+	}
+
+	countQuery := `
+		SELECT COUNT(*) FROM company_members m
+		JOIN companies c ON c.id = m.company_id AND c.deleted_at IS NULL
+		WHERE m.profile_id = ?
+	`
+	var total uint64
+	if err := r.db.QueryRowContext(ctx, countQuery, profileID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT c.id, c.name, c.registration_no, c.fiscal_code, c.fiscal_code_valid, c.vat_payer_prefix, c.profile_id, c.type, c.is_primary, c.created_at, c.updated_at, c.version, c.deleted_at, m.role
+		FROM company_members m
+		JOIN companies c ON c.id = m.company_id AND c.deleted_at IS NULL
+		WHERE m.profile_id = ?
+		ORDER BY c.id DESC LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, profileID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	memberships := make([]*entity.CompanyMembership, 0)
+	for rows.Next() {
+		company := &entity.Company{}
+		membership := &entity.CompanyMembership{Company: company}
+		var deletedAt sql.NullTime
+		if err = rows.Scan(
+			&company.ID,
+			&company.Name,
+			&company.RegistrationNo,
+			&company.FiscalCode,
+			&company.FiscalCodeValid,
+			&company.VATPayerPrefix,
+			&company.ProfileID,
+			&company.Type,
+			&company.IsPrimary,
+			&company.CreatedAt,
+			&company.UpdatedAt,
+			&company.Version,
+			&deletedAt,
+			&membership.Role,
+		); err != nil {
+			return nil, 0, err
+		}
+		company.DeletedAt = nullTimePtr(deletedAt)
+		memberships = append(memberships, membership)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return memberships, total, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+func TestCompanyMemberSaveUpsertsRole(t *testing.T) {
+	var query string
+	repo := NewCompanyMemberRepository(&fakeCompanyDB{
+		execFn: func(_ context.Context, q string, _ ...interface{}) (sql.Result, error) {
+			query = q
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	})
+
+	if err := repo.Save(context.Background(), &entity.CompanyMember{CompanyID: 5, ProfileID: 8, Role: entity.CompanyRoleViewer}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(query, "ON DUPLICATE KEY UPDATE role = VALUES(role)") {
+		t.Fatalf("expected an upsert, got %s", query)
+	}
+}
+
+func TestCompanyMemberSaveMapsForeignKeyError(t *testing.T) {
+	repo := NewCompanyMemberRepository(&fakeCompanyDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return nil, &mysqlDriver.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}
+		},
+	})
+
+	if err := repo.Save(context.Background(), &entity.CompanyMember{CompanyID: 5, ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound, got %v", err)
+	}
+}
+
+func TestCompanyMemberDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
+	repo := NewCompanyMemberRepository(&fakeCompanyDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return fakeResult{rowsAffected: 0}, nil
+		},
+	})
+
+	if err := repo.Delete(context.Background(), 5, 8); !errors.Is(err, ErrCompanyMemberNotFound) {
+		t.Fatalf("expected ErrCompanyMemberNotFound, got %v", err)
+	}
+}
+
+func TestCompanyMemberCountByRole(t *testing.T) {
+	repo := NewCompanyMemberRepository(&fakeCompanyDB{
+		rowDB: newQueryTestDB(t, queryCase{columns: []string{"count"}, row: []driver.Value{int64(2)}}),
+	})
+
+	count, err := repo.CountByRole(context.Background(), 5, entity.CompanyRoleOwner)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 owners, got %d", count)
+	}
+}
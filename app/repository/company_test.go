@@ -3,15 +3,21 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 )
 
 type fakeCompanyDB struct {
-	execFn func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	execFn  func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	queryFn func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	// rowDB answers QueryRowContext when set.
+	rowDB *sql.DB
 }
 
 func (f *fakeCompanyDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
@@ -21,11 +27,17 @@ func (f *fakeCompanyDB) ExecContext(ctx context.Context, query string, args ...i
 	return fakeResult{lastInsertID: 1, rowsAffected: 1}, nil
 }
 
-func (f *fakeCompanyDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+func (f *fakeCompanyDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if f.rowDB != nil {
+		return f.rowDB.QueryRowContext(ctx, query, args...)
+	}
 	return nil
 }
 
-func (f *fakeCompanyDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+func (f *fakeCompanyDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if f.queryFn != nil {
+		return f.queryFn(ctx, query, args...)
+	}
 	return nil, nil
 }
 
@@ -65,15 +77,17 @@ func TestCompanyCreateLastInsertIDError(t *testing.T) {
 	}
 }
 
-func TestCompanyUpdateNoopWhenNoRowsAffected(t *testing.T) {
+func TestCompanyUpdateVersionConflictWhenNoRowsAffected(t *testing.T) {
 	repo := NewCompanyRepository(&fakeCompanyDB{
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{rowsAffected: 0}, nil
 		},
+		rowDB: newQueryTestDB(t, queryCase{columns: []string{"1"}, row: []driver.Value{int64(1)}}),
 	})
 
-	if err := repo.Update(context.Background(), &entity.Company{ID: 1}); err != nil {
-		t.Fatalf("expected no error for no-op update, got %v", err)
+	err := repo.Update(context.Background(), &entity.Company{ID: 1, Version: 3})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
 	}
 }
 
@@ -84,8 +98,105 @@ func TestCompanyDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
 		},
 	})
 
-	err := repo.Delete(context.Background(), 1)
+	err := repo.Delete(context.Background(), 1, 0)
 	if !errors.Is(err, ErrCompanyNotFound) {
 		t.Fatalf("expected ErrCompanyNotFound, got %v", err)
 	}
 }
+
+func TestCompanyCreateAndUpdateMapForeignKeyError(t *testing.T) {
+	repo := NewCompanyRepository(&fakeCompanyDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return nil, &mysqlDriver.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}
+		},
+	})
+
+	if err := repo.Create(context.Background(), &entity.Company{ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound on create, got: %v", err)
+	}
+	if err := repo.Update(context.Background(), &entity.Company{ID: 1, ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound on update, got: %v", err)
+	}
+}
+
+func TestCompanyWritesMapFiscalCodeDuplicate(t *testing.T) {
+	dupErr := &mysqlDriver.MySQLError{Number: 1062, Message: "Duplicate entry 'RO18547290' for key 'companies.uq_companies_live_fiscal_code'"}
+	repo := NewCompanyRepository(&fakeCompanyDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return nil, dupErr
+		},
+	})
+
+	if err := repo.Create(context.Background(), &entity.Company{FiscalCode: "RO18547290"}); !errors.Is(err, ErrCompanyFiscalCodeTaken) {
+		t.Fatalf("expected ErrCompanyFiscalCodeTaken on create, got: %v", err)
+	}
+	if err := repo.Update(context.Background(), &entity.Company{ID: 1, FiscalCode: "RO18547290"}); !errors.Is(err, ErrCompanyFiscalCodeTaken) {
+		t.Fatalf("expected ErrCompanyFiscalCodeTaken on update, got: %v", err)
+	}
+	if err := repo.Restore(context.Background(), 1); !errors.Is(err, ErrCompanyFiscalCodeTaken) {
+		t.Fatalf("expected ErrCompanyFiscalCodeTaken on restore, got: %v", err)
+	}
+}
+
+var companyColumns = []string{
+	"id", "name", "registration_no", "fiscal_code", "fiscal_code_valid", "vat_payer_prefix", "profile_id",
+	"type", "is_primary", "created_at", "updated_at", "version", "deleted_at", "attributes",
+}
+
+func TestCompanyListForProfileIncludesMemberships(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := newQueryTestDB(t, queryCase{
+		columns: companyColumns,
+		row:     []driver.Value{int64(3), "ACME", "J40/123/2020", "RO123", true, "RO", int64(9), "", false, now, now, int64(1), nil, nil},
+	})
+	var gotQuery string
+	var gotArgs []interface{}
+	repo := NewCompanyRepository(&fakeCompanyDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	// The company is filed under profile 9 and reached by profile 7 through its membership.
+	companies, err := repo.ListForProfile(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(companies) != 1 || companies[0].ID != 3 || companies[0].ProfileID != 9 {
+		t.Fatalf("unexpected companies: %+v", companies)
+	}
+	if !strings.Contains(gotQuery, companyOfProfileClause) || len(gotArgs) != 2 || gotArgs[0] != uint64(7) || gotArgs[1] != uint64(7) {
+		t.Fatalf("unexpected query: %s %v", gotQuery, gotArgs)
+	}
+}
+
+func TestCompanyListByProfileIncludesMemberships(t *testing.T) {
+	for _, tc := range []struct {
+		primaryOnly bool
+		clause      string
+		args        int
+	}{
+		{primaryOnly: false, clause: companyOfProfileClause, args: 4},
+		// Only the profile a company is filed under has it as a primary company.
+		{primaryOnly: true, clause: "is_primary = 1 AND profile_id = ?", args: 3},
+	} {
+		var gotQuery string
+		var gotArgs []interface{}
+		rows := newQueryTestDB(t, queryCase{columns: companyColumns})
+		repo := NewCompanyRepository(&fakeCompanyDB{
+			rowDB: newQueryTestDB(t, queryCase{columns: []string{"count"}, row: []driver.Value{int64(0)}}),
+			queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+				gotQuery, gotArgs = query, args
+				return rows.QueryContext(ctx, "SELECT")
+			},
+		})
+
+		if _, _, err := repo.List(context.Background(), 7, "", nil, false, tc.primaryOnly, 20, 0); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !strings.Contains(gotQuery, tc.clause) || len(gotArgs) != tc.args {
+			t.Fatalf("primaryOnly=%v: unexpected query: %s %v", tc.primaryOnly, gotQuery, gotArgs)
+		}
+	}
+}
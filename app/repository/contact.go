@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldcrypt"
 )
 
 var (
@@ -20,31 +21,124 @@ type ContactDBTX interface {
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 }
 
+// contactDOBLayout is the text form of dob in the contacts table, encrypted or not.
+const contactDOBLayout = "2006-01-02"
+
+// ContactRepository stores contacts, encrypting nin (and optionally phone and dob) with the
+// cipher. A nil cipher stores them in plaintext.
 type ContactRepository struct {
-	db ContactDBTX
+	db     ContactDBTX
+	cipher *fieldcrypt.Cipher
+}
+
+func NewContactRepository(db ContactDBTX, cipher *fieldcrypt.Cipher) *ContactRepository {
+	return &ContactRepository{db: db, cipher: cipher}
+}
+
+// Encrypts reports whether the repository stores values of the fieldcrypt field encrypted.
+func (r *ContactRepository) Encrypts(field string) bool {
+	return r.cipher.Encrypts(field)
+}
+
+// sealedContact holds the stored form of the contact columns that may be encrypted.
+type sealedContact struct {
+	nin       string
+	dob       sql.NullString
+	phone     string
+	phoneE164 string
+	ninIndex  string
 }
 
-func NewContactRepository(db ContactDBTX) *ContactRepository {
-	return &ContactRepository{db: db}
+// seal encrypts the contact's sensitive columns. The E.164 form of the phone follows the
+// phone's setting.
+func (r *ContactRepository) seal(contact *entity.Contact) (sealedContact, error) {
+	var (
+		sealed sealedContact
+		err    error
+	)
+	if sealed.nin, err = r.cipher.Seal(fieldcrypt.FieldNIN, contact.NIN); err != nil {
+		return sealed, err
+	}
+	if contact.DOB != nil {
+		sealed.dob.Valid = true
+		if sealed.dob.String, err = r.cipher.Seal(fieldcrypt.FieldDOB, contact.DOB.Format(contactDOBLayout)); err != nil {
+			return sealed, err
+		}
+	}
+	if sealed.phone, err = r.cipher.Seal(fieldcrypt.FieldPhone, contact.Phone); err != nil {
+		return sealed, err
+	}
+	if sealed.phoneE164, err = r.cipher.Seal(fieldcrypt.FieldPhone, contact.PhoneE164); err != nil {
+		return sealed, err
+	}
+	sealed.ninIndex = r.cipher.BlindIndex(contact.NIN)
+
+	return sealed, nil
+}
+
+// open decrypts the columns scanned into contact and parses the stored dob.
+func (r *ContactRepository) open(contact *entity.Contact, dob sql.NullString) error {
+	var err error
+	if contact.NIN, err = r.cipher.Open(contact.NIN); err != nil {
+		return err
+	}
+	if contact.Phone, err = r.cipher.Open(contact.Phone); err != nil {
+		return err
+	}
+	if contact.PhoneE164, err = r.cipher.Open(contact.PhoneE164); err != nil {
+		return err
+	}
+	if !dob.Valid || dob.String == "" {
+		return nil
+	}
+
+	plain, err := r.cipher.Open(dob.String)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.Parse(contactDOBLayout, plain)
+	if err != nil {
+		return err
+	}
+	contact.DOB = &parsed
+
+	return nil
 }
 
 func (r *ContactRepository) Create(ctx context.Context, contact *entity.Contact) error {
+	sealed, err := r.seal(contact)
+	if err != nil {
+		return err
+	}
+	attributes, err := attributesValue(contact.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO contacts (first_name, last_name, nin, dob, phone, created_at, updated_at, profile_id, type)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO contacts (first_name, last_name, nin, nin_country, dob, phone, phone_e164, phone_type, nin_index, created_at, updated_at, profile_id, type, attributes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		contact.FirstName,
 		contact.LastName,
-		contact.NIN,
-		contact.DOB,
-		contact.Phone,
+		sealed.nin,
+		contact.NINCountry,
+		sealed.dob,
+		sealed.phone,
+		sealed.phoneE164,
+		contact.PhoneType,
+		sealed.ninIndex,
 		contact.CreatedAt,
 		contact.UpdatedAt,
 		contact.ProfileID,
 		contact.Type,
+		attributes,
 	)
 	if err != nil {
+		if isForeignKeyError(err) {
+			return ErrProfileReferenceNotFound
+		}
 		return err
 	}
 
@@ -53,28 +147,40 @@ func (r *ContactRepository) Create(ctx context.Context, contact *entity.Contact)
 		return err
 	}
 	contact.ID = uint64(id)
+	contact.Version = 1
 
 	return nil
 }
 
-func (r *ContactRepository) FindByID(ctx context.Context, id uint64) (*entity.Contact, error) {
+func (r *ContactRepository) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error) {
 	query := `
-		SELECT id, first_name, last_name, nin, dob, phone, created_at, updated_at, profile_id, type
+		SELECT id, first_name, last_name, nin, nin_country, dob, phone, phone_e164, phone_type, created_at, updated_at, profile_id, type, is_primary, version, deleted_at, attributes
 		FROM contacts WHERE id = ?
 	`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 	contact := &entity.Contact{}
-	var dob sql.NullTime
+	var dob sql.NullString
+	var deletedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&contact.ID,
 		&contact.FirstName,
 		&contact.LastName,
 		&contact.NIN,
+		&contact.NINCountry,
 		&dob,
 		&contact.Phone,
+		&contact.PhoneE164,
+		&contact.PhoneType,
 		&contact.CreatedAt,
 		&contact.UpdatedAt,
 		&contact.ProfileID,
 		&contact.Type,
+		&contact.IsPrimary,
+		&contact.Version,
+		&deletedAt,
+		attributesColumn{&contact.Attributes},
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -82,39 +188,80 @@ func (r *ContactRepository) FindByID(ctx context.Context, id uint64) (*entity.Co
 	if err != nil {
 		return nil, err
 	}
-	if dob.Valid {
-		contact.DOB = &dob.Time
+	if err = r.open(contact, dob); err != nil {
+		return nil, err
 	}
+	contact.DeletedAt = nullTimePtr(deletedAt)
 
 	return contact, nil
 }
 
+// FindPrimary returns the live primary contact of the given type of the profile, or nil when
+// there is none, and locks it until the transaction ends.
+func (r *ContactRepository) FindPrimary(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error) {
+	id, err := primaryID(ctx, r.db, "contacts", "profile_id", profileID, contactType)
+	if err != nil || id == 0 {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id, false)
+}
+
 func (r *ContactRepository) Update(ctx context.Context, contact *entity.Contact) error {
+	sealed, err := r.seal(contact)
+	if err != nil {
+		return err
+	}
+	attributes, err := attributesValue(contact.Attributes)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE contacts SET
 			first_name = ?,
 			last_name = ?,
 			nin = ?,
+			nin_country = ?,
 			dob = ?,
 			phone = ?,
+			phone_e164 = ?,
+			phone_type = ?,
+			nin_index = ?,
 			updated_at = ?,
 			profile_id = ?,
-			type = ?
-		WHERE id = ?
+			type = ?,
+			attributes = ?,
+			is_primary = ?,
+			version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
 	`
 	contact.UpdatedAt = time.Now()
 	result, err := r.db.ExecContext(ctx, query,
 		contact.FirstName,
 		contact.LastName,
-		contact.NIN,
-		contact.DOB,
-		contact.Phone,
+		sealed.nin,
+		contact.NINCountry,
+		sealed.dob,
+		sealed.phone,
+		sealed.phoneE164,
+		contact.PhoneType,
+		sealed.ninIndex,
 		contact.UpdatedAt,
 		contact.ProfileID,
 		contact.Type,
+		attributes,
+		contact.IsPrimary,
 		contact.ID,
+		contact.Version,
 	)
 	if err != nil {
+		if isForeignKeyError(err) {
+			return ErrProfileReferenceNotFound
+		}
+		if isDuplicateEntryError(err) {
+			return ErrPrimaryConflict
+		}
 		return err
 	}
 
@@ -123,40 +270,55 @@ func (r *ContactRepository) Update(ctx context.Context, contact *entity.Contact)
 		return err
 	}
 	if affected == 0 {
-		// MySQL reports 0 affected rows when values are unchanged.
-		// The service already checks existence before update, so treat this as success.
-		return nil
+		return staleWriteError(ctx, r.db, "contacts", contact.ID, ErrContactNotFound)
 	}
+	contact.Version++
 
 	return nil
 }
 
-func (r *ContactRepository) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM contacts WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+// Delete soft-deletes the contact; a non-zero expectedVersion only deletes that version.
+func (r *ContactRepository) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return softDelete(ctx, r.db, "contacts", id, expectedVersion, true, ErrContactNotFound)
+}
 
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return ErrContactNotFound
-	}
+// DeleteByProfileID soft-deletes every live contact of the profile.
+func (r *ContactRepository) DeleteByProfileID(ctx context.Context, profileID uint64) error {
+	return softDeleteByProfileID(ctx, r.db, "contacts", profileID)
+}
 
-	return nil
+// Restore undeletes the contact. It fails with ErrProfileReferenceNotFound while its profile is deleted.
+func (r *ContactRepository) Restore(ctx context.Context, id uint64) error {
+	return restore(ctx, r.db, "contacts", id, liveProfileOwner, ErrContactNotFound)
+}
+
+// RestoreByProfileID undeletes the contacts of the profile that were deleted at or after deletedSince.
+func (r *ContactRepository) RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error {
+	return restoreByProfileID(ctx, r.db, "contacts", profileID, deletedSince)
+}
+
+// Purge hard-deletes at most limit contacts soft-deleted before deletedBefore.
+func (r *ContactRepository) Purge(ctx context.Context, deletedBefore time.Time, limit uint32) (int64, error) {
+	return purgeDeleted(ctx, r.db, "contacts", deletedBefore, limit)
 }
 
-func (r *ContactRepository) List(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+// List pages through contacts. A non-empty nin matches exactly, through the blind index
+// when NINs are encrypted; attributes keeps the contacts holding each of its values.
+func (r *ContactRepository) List(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 	if limit == 0 {
 		limit = 20
 	}
 
 	contactType = strings.TrimSpace(contactType)
-	whereClauses := make([]string, 0, 2)
-	countArgs := make([]interface{}, 0, 2)
+	nin = strings.TrimSpace(nin)
+	whereClauses := make([]string, 0, 5)
+	countArgs := make([]interface{}, 0, 3)
+	if !includeDeleted {
+		whereClauses = append(whereClauses, "deleted_at IS NULL")
+	}
+	if primaryOnly {
+		whereClauses = append(whereClauses, "is_primary = 1")
+	}
 	if profileID > 0 {
 		whereClauses = append(whereClauses, "profile_id = ?")
 		countArgs = append(countArgs, profileID)
@@ -165,6 +327,18 @@ func (r *ContactRepository) List(ctx context.Context, profileID uint64, contactT
 		whereClauses = append(whereClauses, "`type` = ?")
 		countArgs = append(countArgs, contactType)
 	}
+	if nin != "" {
+		if r.cipher.Encrypts(fieldcrypt.FieldNIN) {
+			whereClauses = append(whereClauses, "nin_index = ?")
+			countArgs = append(countArgs, r.cipher.BlindIndex(nin))
+		} else {
+			whereClauses = append(whereClauses, "nin = ?")
+			countArgs = append(countArgs, nin)
+		}
+	}
+	attributeClauses, attributeArgs := attributeFilters(attributes)
+	whereClauses = append(whereClauses, attributeClauses...)
+	countArgs = append(countArgs, attributeArgs...)
 
 	countQuery := strings.Builder{}
 	countQuery.WriteString(`SELECT COUNT(*) FROM contacts`)
@@ -180,10 +354,10 @@ func (r *ContactRepository) List(ctx context.Context, profileID uint64, contactT
 
 	query := strings.Builder{}
 	query.WriteString(`
-		SELECT id, first_name, last_name, nin, dob, phone, created_at, updated_at, profile_id, type
+		SELECT id, first_name, last_name, nin, nin_country, dob, phone, phone_e164, phone_type, created_at, updated_at, profile_id, type, is_primary, version, deleted_at, attributes
 		FROM contacts
 	`)
-	args := make([]interface{}, 0, 4)
+	args := make([]interface{}, 0, 5)
 	if len(whereClauses) > 0 {
 		query.WriteString(` WHERE `)
 		query.WriteString(strings.Join(whereClauses, " AND "))
@@ -198,33 +372,150 @@ func (r *ContactRepository) List(ctx context.Context, profileID uint64, contactT
 	}
 	defer rows.Close()
 
+	contacts, err := r.scanContacts(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return contacts, total, nil
+}
+
+// ListByProfileID returns every contact of the profile, oldest first.
+func (r *ContactRepository) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Contact, error) {
+	query := `
+		SELECT id, first_name, last_name, nin, nin_country, dob, phone, phone_e164, phone_type, created_at, updated_at, profile_id, type, is_primary, version, deleted_at, attributes
+		FROM contacts
+		WHERE profile_id = ? AND deleted_at IS NULL
+		ORDER BY id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanContacts(rows)
+}
+
+func (r *ContactRepository) scanContacts(rows *sql.Rows) ([]*entity.Contact, error) {
 	contacts := make([]*entity.Contact, 0)
 	for rows.Next() {
 		contact := &entity.Contact{}
-		var dob sql.NullTime
-		if err = rows.Scan(
+		var dob sql.NullString
+		var deletedAt sql.NullTime
+		if err := rows.Scan(
 			&contact.ID,
 			&contact.FirstName,
 			&contact.LastName,
 			&contact.NIN,
+			&contact.NINCountry,
 			&dob,
 			&contact.Phone,
+			&contact.PhoneE164,
+			&contact.PhoneType,
 			&contact.CreatedAt,
 			&contact.UpdatedAt,
 			&contact.ProfileID,
 			&contact.Type,
+			&contact.IsPrimary,
+			&contact.Version,
+			&deletedAt,
+			attributesColumn{&contact.Attributes},
 		); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
-		if dob.Valid {
-			contact.DOB = &dob.Time
+		if err := r.open(contact, dob); err != nil {
+			return nil, err
 		}
+		contact.DeletedAt = nullTimePtr(deletedAt)
 		contacts = append(contacts, contact)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// RotateKeys re-encrypts up to limit contacts with id greater than afterID, deleted ones
+// included, so that every encrypted column uses the active key and every field that is no
+// longer encrypted goes back to plaintext. It returns the last id it looked at, or afterID
+// once there is nothing left, and how many rows it rewrote. Rows changed concurrently are
+// skipped; their update already stored them with the active key.
+func (r *ContactRepository) RotateKeys(ctx context.Context, afterID uint64, limit uint32) (uint64, int, error) {
+	query := `
+		SELECT id, nin, dob, phone, phone_e164, nin_index, version
+		FROM contacts
+		WHERE id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return afterID, 0, err
+	}
+
+	type storedContact struct {
+		id        uint64
+		nin       string
+		dob       sql.NullString
+		phone     string
+		phoneE164 string
+		ninIndex  string
+		version   uint64
+	}
+	stored := make([]storedContact, 0, limit)
+	for rows.Next() {
+		var row storedContact
+		if err = rows.Scan(&row.id, &row.nin, &row.dob, &row.phone, &row.phoneE164, &row.ninIndex, &row.version); err != nil {
+			rows.Close()
+			return afterID, 0, err
+		}
+		stored = append(stored, row)
+	}
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		rows.Close()
+		return afterID, 0, err
 	}
+	rows.Close()
 
-	return contacts, total, nil
+	lastID := afterID
+	rotated := 0
+	for _, row := range stored {
+		lastID = row.id
+
+		contact := &entity.Contact{NIN: row.nin, Phone: row.phone, PhoneE164: row.phoneE164}
+		if err = r.open(contact, row.dob); err != nil {
+			return lastID, rotated, err
+		}
+		if r.cipher.IsCurrent(fieldcrypt.FieldNIN, row.nin) &&
+			r.cipher.IsCurrent(fieldcrypt.FieldDOB, row.dob.String) &&
+			r.cipher.IsCurrent(fieldcrypt.FieldPhone, row.phone) &&
+			r.cipher.IsCurrent(fieldcrypt.FieldPhone, row.phoneE164) &&
+			row.ninIndex == r.cipher.BlindIndex(contact.NIN) {
+			continue
+		}
+
+		sealed, err := r.seal(contact)
+		if err != nil {
+			return lastID, rotated, err
+		}
+		result, err := r.db.ExecContext(ctx,
+			`UPDATE contacts SET nin = ?, dob = ?, phone = ?, phone_e164 = ?, nin_index = ? WHERE id = ? AND version = ?`,
+			sealed.nin, sealed.dob, sealed.phone, sealed.phoneE164, sealed.ninIndex, row.id, row.version,
+		)
+		if err != nil {
+			return lastID, rotated, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return lastID, rotated, err
+		}
+		if affected > 0 {
+			rotated++
+		}
+	}
+
+	return lastID, rotated, nil
 }
@@ -1,17 +1,26 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldcrypt"
 )
 
 type fakeContactDB struct {
 	execFn func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// rowDB answers QueryRowContext when set.
+	rowDB *sql.DB
+	// rowsDB answers QueryContext when set.
+	rowsDB *sql.DB
 }
 
 func (f *fakeContactDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
@@ -21,23 +30,41 @@ func (f *fakeContactDB) ExecContext(ctx context.Context, query string, args ...i
 	return fakeResult{lastInsertID: 1, rowsAffected: 1}, nil
 }
 
-func (f *fakeContactDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
-	// Not used in these tests.
+func (f *fakeContactDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if f.rowDB != nil {
+		return f.rowDB.QueryRowContext(ctx, query, args...)
+	}
 	return nil
 }
 
-func (f *fakeContactDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
-	// Not used in these tests.
+func (f *fakeContactDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if f.rowsDB != nil {
+		return f.rowsDB.QueryContext(ctx, query, args...)
+	}
 	return nil, nil
 }
 
+func newTestCipher(t *testing.T, activeKeyID string) *fieldcrypt.Cipher {
+	t.Helper()
+
+	keys := map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, fieldcrypt.KeySize),
+		"k2": bytes.Repeat([]byte{2}, fieldcrypt.KeySize),
+	}
+	cipher, err := fieldcrypt.New(keys, activeKeyID, bytes.Repeat([]byte{9}, fieldcrypt.KeySize), []string{fieldcrypt.FieldNIN})
+	if err != nil {
+		t.Fatalf("fieldcrypt.New() returned error: %v", err)
+	}
+	return cipher
+}
+
 func TestContactCreateSuccess(t *testing.T) {
 	dob := time.Now()
 	repo := NewContactRepository(&fakeContactDB{
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{lastInsertID: 9}, nil
 		},
-	})
+	}, nil)
 
 	contact := &entity.Contact{
 		FirstName: "John",
@@ -62,7 +89,7 @@ func TestContactCreateLastInsertIDError(t *testing.T) {
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{lastInsertErr: errors.New("no id")}, nil
 		},
-	})
+	}, nil)
 
 	err := repo.Create(context.Background(), &entity.Contact{})
 	if err == nil {
@@ -70,16 +97,17 @@ func TestContactCreateLastInsertIDError(t *testing.T) {
 	}
 }
 
-func TestContactUpdateNoopWhenNoRowsAffected(t *testing.T) {
+func TestContactUpdateVersionConflictWhenNoRowsAffected(t *testing.T) {
 	repo := NewContactRepository(&fakeContactDB{
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{rowsAffected: 0}, nil
 		},
-	})
+		rowDB: newQueryTestDB(t, queryCase{columns: []string{"1"}, row: []driver.Value{int64(1)}}),
+	}, nil)
 
-	err := repo.Update(context.Background(), &entity.Contact{ID: 1})
-	if err != nil {
-		t.Fatalf("expected no error for no-op update, got %v", err)
+	err := repo.Update(context.Background(), &entity.Contact{ID: 1, Version: 3})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
 	}
 }
 
@@ -88,10 +116,167 @@ func TestContactDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{rowsAffected: 0}, nil
 		},
-	})
+	}, nil)
 
-	err := repo.Delete(context.Background(), 1)
+	err := repo.Delete(context.Background(), 1, 0)
 	if !errors.Is(err, ErrContactNotFound) {
 		t.Fatalf("expected ErrContactNotFound, got %v", err)
 	}
 }
+
+func TestContactCreateAndUpdateMapForeignKeyError(t *testing.T) {
+	repo := NewContactRepository(&fakeContactDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return nil, &mysqlDriver.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}
+		},
+	}, nil)
+
+	if err := repo.Create(context.Background(), &entity.Contact{ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound on create, got: %v", err)
+	}
+	if err := repo.Update(context.Background(), &entity.Contact{ID: 1, ProfileID: 404}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound on update, got: %v", err)
+	}
+}
+
+func TestContactCreateEncryptsNIN(t *testing.T) {
+	cipher := newTestCipher(t, "k1")
+	dob := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	var got []interface{}
+	repo := NewContactRepository(&fakeContactDB{
+		execFn: func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			got = args
+			return fakeResult{lastInsertID: 9}, nil
+		},
+	}, cipher)
+
+	err := repo.Create(context.Background(), &entity.Contact{
+		NIN: "1900517223344", NINCountry: "RO", DOB: &dob, Phone: "0700 000 000", PhoneE164: "+40700000000", PhoneType: "mobile", ProfileID: 5,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	nin, _ := got[2].(string)
+	if !strings.HasPrefix(nin, "enc:v1:k1:") {
+		t.Fatalf("expected encrypted nin, got %q", nin)
+	}
+	if opened, _ := cipher.Open(nin); opened != "1900517223344" {
+		t.Fatalf("expected nin to decrypt, got %q", opened)
+	}
+	if got[3] != "RO" {
+		t.Fatalf("expected nin country in plaintext, got %v", got[3])
+	}
+	if got[4] != (sql.NullString{String: "1990-05-17", Valid: true}) || got[5] != "0700 000 000" || got[6] != "+40700000000" {
+		t.Fatalf("expected dob and phone in plaintext, got %v %v %v", got[4], got[5], got[6])
+	}
+	if got[7] != "mobile" {
+		t.Fatalf("expected phone type, got %v", got[7])
+	}
+	if got[8] != cipher.BlindIndex("1900517223344") {
+		t.Fatalf("expected blind index, got %v", got[8])
+	}
+}
+
+func TestContactCreateEncryptsPhoneE164WithPhone(t *testing.T) {
+	cipher, err := fieldcrypt.New(map[string][]byte{"k1": bytes.Repeat([]byte{1}, fieldcrypt.KeySize)}, "k1",
+		bytes.Repeat([]byte{9}, fieldcrypt.KeySize), []string{fieldcrypt.FieldPhone})
+	if err != nil {
+		t.Fatalf("fieldcrypt.New() returned error: %v", err)
+	}
+	var got []interface{}
+	repo := NewContactRepository(&fakeContactDB{
+		execFn: func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			got = args
+			return fakeResult{lastInsertID: 9}, nil
+		},
+	}, cipher)
+
+	if err = repo.Create(context.Background(), &entity.Contact{Phone: "0700000000", PhoneE164: "+40700000000", PhoneType: "mobile"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, i := range []int{5, 6} {
+		if value, _ := got[i].(string); !strings.HasPrefix(value, "enc:v1:k1:") {
+			t.Fatalf("expected encrypted column %d, got %q", i, value)
+		}
+	}
+	if opened, _ := cipher.Open(got[6].(string)); opened != "+40700000000" || got[7] != "mobile" {
+		t.Fatalf("unexpected phone_e164 %q and phone_type %v", opened, got[7])
+	}
+}
+
+func TestContactFindByIDDecryptsNIN(t *testing.T) {
+	cipher := newTestCipher(t, "k1")
+	sealed, _ := cipher.Seal(fieldcrypt.FieldNIN, "1900517223344")
+	now := time.Now().UTC().Truncate(time.Second)
+	repo := NewContactRepository(&fakeContactDB{
+		rowDB: newQueryTestDB(t, queryCase{
+			columns: []string{"id", "first_name", "last_name", "nin", "nin_country", "dob", "phone", "phone_e164", "phone_type", "created_at", "updated_at", "profile_id", "type", "is_primary", "version", "deleted_at", "attributes"},
+			row:     []driver.Value{int64(4), "Ana", "Pop", sealed, "RO", "1990-05-17", "0700000000", "+40700000000", "mobile", now, now, int64(5), "", false, int64(1), nil, nil},
+		}),
+	}, cipher)
+
+	contact, err := repo.FindByID(context.Background(), 4, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if contact.NIN != "1900517223344" || contact.NINCountry != "RO" || contact.DOB == nil || contact.DOB.Format("2006-01-02") != "1990-05-17" ||
+		contact.PhoneE164 != "+40700000000" || contact.PhoneType != "mobile" {
+		t.Fatalf("unexpected contact: %+v", contact)
+	}
+}
+
+func TestContactRotateKeysReencryptsStaleRows(t *testing.T) {
+	old := newTestCipher(t, "k1")
+	sealed, _ := old.Seal(fieldcrypt.FieldNIN, "1900517223344")
+	cipher := newTestCipher(t, "k2")
+
+	var got []interface{}
+	repo := NewContactRepository(&fakeContactDB{
+		rowsDB: newQueryTestDB(t, queryCase{
+			columns: []string{"id", "nin", "dob", "phone", "phone_e164", "nin_index", "version"},
+			row:     []driver.Value{int64(7), sealed, nil, "0700000000", "+40700000000", old.BlindIndex("1900517223344"), int64(3)},
+		}),
+		execFn: func(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+			got = args
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	}, cipher)
+
+	lastID, rotated, err := repo.RotateKeys(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if lastID != 7 || rotated != 1 {
+		t.Fatalf("expected last id 7 and 1 rotated row, got %d and %d", lastID, rotated)
+	}
+
+	nin, _ := got[0].(string)
+	if !strings.HasPrefix(nin, "enc:v1:k2:") {
+		t.Fatalf("expected nin encrypted with k2, got %q", nin)
+	}
+	if got[5] != uint64(7) || got[6] != uint64(3) {
+		t.Fatalf("expected update guarded by id and version, got %v", got[5:])
+	}
+}
+
+func TestContactRotateKeysSkipsCurrentRows(t *testing.T) {
+	cipher := newTestCipher(t, "k1")
+	sealed, _ := cipher.Seal(fieldcrypt.FieldNIN, "1900517223344")
+
+	repo := NewContactRepository(&fakeContactDB{
+		rowsDB: newQueryTestDB(t, queryCase{
+			columns: []string{"id", "nin", "dob", "phone", "phone_e164", "nin_index", "version"},
+			row:     []driver.Value{int64(7), sealed, nil, "", "", cipher.BlindIndex("1900517223344"), int64(3)},
+		}),
+		execFn: func(context.Context, string, ...interface{}) (sql.Result, error) {
+			t.Fatal("expected no update for a current row")
+			return nil, nil
+		},
+	}, cipher)
+
+	lastID, rotated, err := repo.RotateKeys(context.Background(), 0, 100)
+	if err != nil || lastID != 7 || rotated != 0 {
+		t.Fatalf("RotateKeys() = %d, %d, %v", lastID, rotated, err)
+	}
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+// maxOutboxErrorLength matches the size of outbox_events.last_error.
+const maxOutboxErrorLength = 1024
+
+type OutboxRepository struct {
+	db QueryDBTX
+}
+
+func NewOutboxRepository(db QueryDBTX) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Create stores the event as pending; it is due for delivery right away.
+func (r *OutboxRepository) Create(ctx context.Context, event *entity.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = event.CreatedAt
+	}
+
+	query := `
+		INSERT INTO outbox_events (aggregate_type, aggregate_id, event_type, payload, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		event.AggregateType,
+		event.AggregateID,
+		event.EventType,
+		string(event.Payload),
+		event.CreatedAt,
+		event.NextAttemptAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.ID = uint64(id)
+
+	return nil
+}
+
+// ListPending returns up to limit unpublished events due by now in the order they were written.
+// An event is left out while an older event of its aggregate waits for a later attempt, so that
+// every aggregate's events are published in order; older events that are due come first in the
+// same batch.
+func (r *OutboxRepository) ListPending(ctx context.Context, now time.Time, limit uint32) ([]*entity.OutboxEvent, error) {
+	query := `
+		SELECT e.id, e.aggregate_type, e.aggregate_id, e.event_type, e.payload, e.created_at, e.attempts, e.next_attempt_at, e.last_error
+		FROM outbox_events e
+		WHERE e.published_at IS NULL AND e.next_attempt_at <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox_events older
+				WHERE older.aggregate_type = e.aggregate_type AND older.aggregate_id = e.aggregate_id
+					AND older.published_at IS NULL AND older.id < e.id AND older.next_attempt_at > ?
+			)
+		ORDER BY e.id LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*entity.OutboxEvent, 0)
+	for rows.Next() {
+		event := &entity.OutboxEvent{}
+		var payload []byte
+		if err = rows.Scan(
+			&event.ID,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.EventType,
+			&payload,
+			&event.CreatedAt,
+			&event.Attempts,
+			&event.NextAttemptAt,
+			&event.LastError,
+		); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id uint64, publishedAt time.Time) error {
+	query := `UPDATE outbox_events SET published_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, publishedAt, id)
+	return err
+}
+
+// MarkFailed counts a failed delivery and postpones the event until nextAttemptAt.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint64, nextAttemptAt time.Time, lastError string) error {
+	if len(lastError) > maxOutboxErrorLength {
+		lastError = lastError[:maxOutboxErrorLength]
+	}
+
+	query := `UPDATE outbox_events SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, nextAttemptAt, lastError, id)
+	return err
+}
+
+// outboxRelayLockName is the MySQL named lock held by the relay that is delivering events.
+const outboxRelayLockName = "profile_outbox_relay"
+
+// ErrOutboxRelayNotLocked is returned by Unlock when the lock is not held.
+var ErrOutboxRelayNotLocked = errors.New("outbox relay lock is not held")
+
+// OutboxRelayLock lets a single instance relay events at a time, so that two instances never
+// publish the same event or the events of one aggregate out of order. It holds a MySQL named
+// lock on a dedicated connection, the way the migrator serializes migrations.
+type OutboxRelayLock struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+func NewOutboxRelayLock(db *sql.DB) *OutboxRelayLock {
+	return &OutboxRelayLock{db: db}
+}
+
+// TryLock takes the lock without waiting and reports whether it got it; false means another
+// instance is relaying.
+func (l *OutboxRelayLock) TryLock(ctx context.Context) (bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired sql.NullInt64
+	if err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 0)`, outboxRelayLockName).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return false, err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		_ = conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+
+	return true, nil
+}
+
+// Unlock releases the lock taken by TryLock.
+func (l *OutboxRelayLock) Unlock(ctx context.Context) error {
+	if l.conn == nil {
+		return ErrOutboxRelayNotLocked
+	}
+	conn := l.conn
+	l.conn = nil
+	defer conn.Close()
+
+	_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, outboxRelayLockName)
+	return err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutboxMarkFailedTruncatesError(t *testing.T) {
+	next := time.Now().Add(time.Minute)
+	repo := NewOutboxRepository(&fakeContactDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.Contains(query, "attempts = attempts + 1") {
+				t.Fatalf("unexpected query %q", query)
+			}
+			if args[0] != next || len(args[1].(string)) != maxOutboxErrorLength || args[2] != uint64(5) {
+				t.Fatalf("unexpected args: %v", args)
+			}
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	})
+
+	if err := repo.MarkFailed(context.Background(), 5, next, strings.Repeat("x", 2000)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOutboxListPendingSkipsEventsBehindAPostponedOne(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := newQueryTestDB(t, queryCase{
+		columns: []string{"id", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at", "attempts", "next_attempt_at", "last_error"},
+		row:     []driver.Value{int64(5), "contact", int64(7), "contact.updated", []byte(`{"id":7}`), now, int64(0), now, ""},
+	})
+	var gotQuery string
+	var gotArgs []interface{}
+	repo := NewOutboxRepository(&fakeAddressDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	events, err := repo.ListPending(context.Background(), now, 50)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(events) != 1 || events[0].ID != 5 || string(events[0].Payload) != `{"id":7}` {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for _, condition := range []string{"e.next_attempt_at <= ?", "NOT EXISTS", "older.id < e.id AND older.next_attempt_at > ?"} {
+		if !strings.Contains(gotQuery, condition) {
+			t.Fatalf("expected %q in query %s", condition, gotQuery)
+		}
+	}
+	if len(gotArgs) != 3 || gotArgs[0] != now || gotArgs[1] != now || gotArgs[2] != uint32(50) {
+		t.Fatalf("unexpected args: %v", gotArgs)
+	}
+}
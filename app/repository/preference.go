@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+var (
+	ErrPreferenceNotFound = errors.New("preference not found")
+)
+
+type PreferenceRepository struct {
+	db QueryDBTX
+}
+
+func NewPreferenceRepository(db QueryDBTX) *PreferenceRepository {
+	return &PreferenceRepository{db: db}
+}
+
+// Save stores the preference, replacing the value of a key the profile already has.
+func (r *PreferenceRepository) Save(ctx context.Context, preference *entity.Preference) error {
+	query := `
+		INSERT INTO profile_preferences (profile_id, namespace, ` + "`key`" + `, value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = VALUES(updated_at)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		preference.ProfileID,
+		preference.Namespace,
+		preference.Key,
+		string(preference.Value),
+		preference.CreatedAt,
+		preference.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyError(err) {
+			return ErrProfileReferenceNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PreferenceRepository) Delete(ctx context.Context, profileID uint64, namespace, key string) error {
+	query := "DELETE FROM profile_preferences WHERE profile_id = ? AND namespace = ? AND `key` = ?"
+	result, err := r.db.ExecContext(ctx, query, profileID, namespace, key)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrPreferenceNotFound
+	}
+
+	return nil
+}
+
+// List returns the preferences of the profile ordered by namespace and key, only those of
+// namespace when it is not empty.
+func (r *PreferenceRepository) List(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	return r.list(ctx, profileID, namespace, "")
+}
+
+// ListForUpdate returns the preferences of the profile in namespace and locks them until the
+// transaction ends. Keys not stored yet are only locked under repeatable-read isolation, so
+// writers that add keys must also lock the profile.
+func (r *PreferenceRepository) ListForUpdate(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	return r.list(ctx, profileID, namespace, " FOR UPDATE")
+}
+
+func (r *PreferenceRepository) list(ctx context.Context, profileID uint64, namespace, lock string) ([]*entity.Preference, error) {
+	query := "SELECT profile_id, namespace, `key`, value, created_at, updated_at FROM profile_preferences WHERE profile_id = ?"
+	args := []interface{}{profileID}
+	if namespace != "" {
+		query += " AND namespace = ?"
+		args = append(args, namespace)
+	}
+	query += " ORDER BY namespace ASC, `key` ASC" + lock
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := make([]*entity.Preference, 0)
+	for rows.Next() {
+		preference := &entity.Preference{}
+		var value []byte
+		if err = rows.Scan(
+			&preference.ProfileID,
+			&preference.Namespace,
+			&preference.Key,
+			&value,
+			&preference.CreatedAt,
+			&preference.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		preference.Value = value
+		preferences = append(preferences, preference)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+func TestPreferenceSaveUpsertsValue(t *testing.T) {
+	var query string
+	var args []interface{}
+	repo := NewPreferenceRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, q string, a ...interface{}) (sql.Result, error) {
+			query, args = q, a
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	})
+
+	err := repo.Save(context.Background(), &entity.Preference{ProfileID: 7, Namespace: "billing", Key: "currency", Value: []byte(`"RON"`)})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(query, "ON DUPLICATE KEY UPDATE value = VALUES(value)") {
+		t.Fatalf("expected an upsert, got %s", query)
+	}
+	if args[3] != `"RON"` {
+		t.Fatalf("expected the value as JSON text, got %v", args[3])
+	}
+}
+
+func TestPreferenceSaveMapsForeignKeyError(t *testing.T) {
+	repo := NewPreferenceRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return nil, &mysqlDriver.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}
+		},
+	})
+
+	if err := repo.Save(context.Background(), &entity.Preference{ProfileID: 404, Namespace: "billing", Key: "currency"}); !errors.Is(err, ErrProfileReferenceNotFound) {
+		t.Fatalf("expected ErrProfileReferenceNotFound, got %v", err)
+	}
+}
+
+func TestPreferenceDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
+	repo := NewPreferenceRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+			return fakeResult{rowsAffected: 0}, nil
+		},
+	})
+
+	if err := repo.Delete(context.Background(), 7, "billing", "currency"); !errors.Is(err, ErrPreferenceNotFound) {
+		t.Fatalf("expected ErrPreferenceNotFound, got %v", err)
+	}
+}
+
+func TestPreferenceListForUpdateLocksNamespace(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := newQueryTestDB(t, queryCase{
+		columns: []string{"profile_id", "namespace", "key", "value", "created_at", "updated_at"},
+		row:     []driver.Value{int64(7), "billing", "currency", []byte(`"RON"`), now, now},
+	})
+	var gotQuery string
+	repo := NewPreferenceRepository(&fakeAddressDB{
+		queryFn: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery = query
+			if len(args) != 2 || args[0] != uint64(7) || args[1] != "billing" {
+				t.Fatalf("unexpected args: %v", args)
+			}
+			return rows.QueryContext(ctx, "SELECT")
+		},
+	})
+
+	preferences, err := repo.ListForUpdate(context.Background(), 7, "billing")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(preferences) != 1 || preferences[0].Key != "currency" || string(preferences[0].Value) != `"RON"` {
+		t.Fatalf("unexpected preferences: %+v", preferences)
+	}
+	if !strings.HasSuffix(gotQuery, "FOR UPDATE") {
+		t.Fatalf("expected a locking read, got %s", gotQuery)
+	}
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrPrimaryConflict is returned when a write would leave two primary rows of the same
+	// type on one profile.
+	ErrPrimaryConflict = errors.New("profile already has a primary record of this type")
+)
+
+// primaryID returns the id of the live primary row of the given type of the owner, the row
+// whose ownerColumn is ownerID, or 0 when there is none. The row is locked until the
+// transaction ends.
+func primaryID(ctx context.Context, db DBTX, table string, ownerColumn string, ownerID uint64, kind string) (uint64, error) {
+	query := `SELECT id FROM ` + table + ` WHERE ` + ownerColumn + ` = ? AND ` + "`type`" + ` = ? AND is_primary = 1 AND deleted_at IS NULL FOR UPDATE`
+
+	var id uint64
+	err := db.QueryRowContext(ctx, query, ownerID, kind).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
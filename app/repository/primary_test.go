@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+func TestChildDeleteClearsPrimaryFlag(t *testing.T) {
+	var query string
+	execFn := func(_ context.Context, q string, _ ...interface{}) (sql.Result, error) {
+		query = q
+		return fakeResult{rowsAffected: 1}, nil
+	}
+
+	if err := NewAddressRepository(&fakeAddressDB{execFn: execFn}).Delete(context.Background(), 3, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(query, "is_primary = 0") {
+		t.Fatalf("expected the deleted address to stop being primary, got %q", query)
+	}
+
+	if err := NewProfileRepository(&fakeDB{execFn: execFn}).Delete(context.Background(), 3, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(query, "is_primary") {
+		t.Fatalf("expected the profile delete not to touch is_primary, got %q", query)
+	}
+}
+
+func TestUpdateMapsDuplicatePrimaryToConflict(t *testing.T) {
+	duplicate := func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+		return nil, &mysqlDriver.MySQLError{Number: 1062, Message: "Duplicate entry '7-billing' for key 'uq_addresses_primary'"}
+	}
+
+	if err := NewAddressRepository(&fakeAddressDB{execFn: duplicate}).Update(context.Background(), &entity.Address{ID: 1, IsPrimary: true}); !errors.Is(err, ErrPrimaryConflict) {
+		t.Fatalf("expected ErrPrimaryConflict for address, got %v", err)
+	}
+	if err := NewCompanyRepository(&fakeCompanyDB{execFn: duplicate}).Update(context.Background(), &entity.Company{ID: 1, IsPrimary: true}); !errors.Is(err, ErrPrimaryConflict) {
+		t.Fatalf("expected ErrPrimaryConflict for company, got %v", err)
+	}
+	contactRepo := NewContactRepository(&fakeContactDB{execFn: duplicate}, newTestCipher(t, "k1"))
+	if err := contactRepo.Update(context.Background(), &entity.Contact{ID: 1, IsPrimary: true}); !errors.Is(err, ErrPrimaryConflict) {
+		t.Fatalf("expected ErrPrimaryConflict for contact, got %v", err)
+	}
+}
+
+func TestFindPrimaryReturnsNilWithoutPrimary(t *testing.T) {
+	repo := NewCompanyRepository(&fakeCompanyDB{rowDB: newQueryTestDB(t, queryCase{columns: []string{"id"}, row: nil})})
+
+	company, err := repo.FindPrimary(context.Background(), 7, "billing")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if company != nil {
+		t.Fatalf("expected no primary company, got %+v", company)
+	}
+}
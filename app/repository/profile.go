@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	mysqlDriver "github.com/go-sql-driver/mysql"
@@ -13,6 +14,8 @@ import (
 var (
 	ErrProfileNotFound      = errors.New("profile not found")
 	ErrProfileAlreadyExists = errors.New("profile already exists")
+	// ErrProfileReferenceNotFound is returned when a child record points at a missing profile.
+	ErrProfileReferenceNotFound = errors.New("referenced profile does not exist")
 )
 
 type DBTX interface {
@@ -51,21 +54,28 @@ func (r *ProfileRepository) Create(ctx context.Context, profile *entity.Profile)
 		return err
 	}
 	profile.ID = uint64(id)
+	profile.Version = 1
 	return nil
 }
 
-func (r *ProfileRepository) FindByID(ctx context.Context, id uint64) (*entity.Profile, error) {
+func (r *ProfileRepository) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
 	query := `
-		SELECT id, user_id, email, created_at, updated_at
+		SELECT id, user_id, email, created_at, updated_at, version, deleted_at
 		FROM profile WHERE id = ?
 	`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 	profile := &entity.Profile{}
+	var deletedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&profile.ID,
 		&profile.UserID,
 		&profile.Email,
 		&profile.CreatedAt,
 		&profile.UpdatedAt,
+		&profile.Version,
+		&deletedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -73,21 +83,74 @@ func (r *ProfileRepository) FindByID(ctx context.Context, id uint64) (*entity.Pr
 	if err != nil {
 		return nil, err
 	}
+	profile.DeletedAt = nullTimePtr(deletedAt)
 	return profile, nil
 }
 
-func (r *ProfileRepository) FindByUserID(ctx context.Context, userID uint64) (*entity.Profile, error) {
+// FindByIDForUpdate returns the live profile, or nil when there is none, and locks its row until
+// the transaction ends.
+func (r *ProfileRepository) FindByIDForUpdate(ctx context.Context, id uint64) (*entity.Profile, error) {
+	var locked uint64
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM profile WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id, false)
+}
+
+func (r *ProfileRepository) FindByUserID(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error) {
 	query := `
-		SELECT id, user_id, email, created_at, updated_at
+		SELECT id, user_id, email, created_at, updated_at, version, deleted_at
 		FROM profile WHERE user_id = ?
 	`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
 	profile := &entity.Profile{}
+	var deletedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&profile.ID,
 		&profile.UserID,
 		&profile.Email,
 		&profile.CreatedAt,
 		&profile.UpdatedAt,
+		&profile.Version,
+		&deletedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile.DeletedAt = nullTimePtr(deletedAt)
+	return profile, nil
+}
+
+// FindByEmail returns the oldest profile with the given email, using idx_profile_email.
+func (r *ProfileRepository) FindByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error) {
+	query := `
+		SELECT id, user_id, email, created_at, updated_at, version, deleted_at
+		FROM profile WHERE email = ?
+	`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+	query += ` ORDER BY id ASC LIMIT 1`
+	profile := &entity.Profile{}
+	var deletedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
+		&profile.ID,
+		&profile.UserID,
+		&profile.Email,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+		&profile.Version,
+		&deletedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -95,21 +158,46 @@ func (r *ProfileRepository) FindByUserID(ctx context.Context, userID uint64) (*e
 	if err != nil {
 		return nil, err
 	}
+	profile.DeletedAt = nullTimePtr(deletedAt)
 	return profile, nil
 }
 
+// FindByEmailForUpdate returns the oldest live profile with the email, or nil when there is
+// none, and locks the email until the transaction ends so that no other profile can take it
+// meanwhile. The lock is the email's row in profile_email_locks rather than a gap lock on
+// idx_profile_email, so it also holds under read-committed isolation.
+func (r *ProfileRepository) FindByEmailForUpdate(ctx context.Context, email string) (*entity.Profile, error) {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO profile_email_locks (email) VALUES (?) ON DUPLICATE KEY UPDATE email = email`, email)
+	if err != nil {
+		return nil, err
+	}
+
+	var id uint64
+	err = r.db.QueryRowContext(ctx, `SELECT id FROM profile WHERE email = ? AND deleted_at IS NULL ORDER BY id ASC LIMIT 1 FOR UPDATE`, email).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByID(ctx, id, false)
+}
+
 func (r *ProfileRepository) Update(ctx context.Context, profile *entity.Profile) error {
 	query := `
 		UPDATE profile SET
 			email = ?,
-			updated_at = ?
-		WHERE id = ?
+			updated_at = ?,
+			version = version + 1
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
 	`
 	profile.UpdatedAt = time.Now()
 	result, err := r.db.ExecContext(ctx, query,
 		profile.Email,
 		profile.UpdatedAt,
 		profile.ID,
+		profile.Version,
 	)
 	if err != nil {
 		return err
@@ -120,31 +208,72 @@ func (r *ProfileRepository) Update(ctx context.Context, profile *entity.Profile)
 		return err
 	}
 	if affected == 0 {
-		return ErrProfileNotFound
+		return staleWriteError(ctx, r.db, "profile", profile.ID, ErrProfileNotFound)
 	}
+	profile.Version++
 
 	return nil
 }
 
-func (r *ProfileRepository) Delete(ctx context.Context, id uint64) error {
-	query := `DELETE FROM profile WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, query, id)
+// Delete soft-deletes the profile; a non-zero expectedVersion only deletes that version.
+// Child records are left to the caller, see DeleteByProfileID on their repositories.
+func (r *ProfileRepository) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return softDelete(ctx, r.db, "profile", id, expectedVersion, false, ErrProfileNotFound)
+}
+
+// Restore undeletes the profile.
+func (r *ProfileRepository) Restore(ctx context.Context, id uint64) error {
+	return restore(ctx, r.db, "profile", id, "", ErrProfileNotFound)
+}
+
+// Purge hard-deletes at most limit profiles soft-deleted before deletedBefore.
+// Their remaining child rows go with them through ON DELETE CASCADE, except companies: a profile
+// that still has companies filed under it is kept until they are purged.
+func (r *ProfileRepository) Purge(ctx context.Context, deletedBefore time.Time, limit uint32) (int64, error) {
+	query := `DELETE FROM profile WHERE deleted_at < ? AND NOT EXISTS (SELECT 1 FROM companies c WHERE c.profile_id = profile.id) ORDER BY id LIMIT ?`
+	result, err := r.db.ExecContext(ctx, query, deletedBefore, limit)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	affected, err := result.RowsAffected()
+	return result.RowsAffected()
+}
+
+// ProfileEmailLockRepository prunes the rows FindByEmailForUpdate adds to profile_email_locks.
+type ProfileEmailLockRepository struct {
+	db DBTX
+}
+
+func NewProfileEmailLockRepository(db DBTX) *ProfileEmailLockRepository {
+	return &ProfileEmailLockRepository{db: db}
+}
+
+// Purge deletes at most limit lock rows of emails no live profile has. The rows carry no deletion
+// time, so deletedBefore is not used. A row locked by a write in progress is deleted only once
+// that write ends, and the next write of the email adds it again.
+func (r *ProfileEmailLockRepository) Purge(ctx context.Context, _ time.Time, limit uint32) (int64, error) {
+	query := `DELETE FROM profile_email_locks WHERE NOT EXISTS (SELECT 1 FROM profile p WHERE p.email = profile_email_locks.email AND p.deleted_at IS NULL) ORDER BY email LIMIT ?`
+	result, err := r.db.ExecContext(ctx, query, limit)
 	if err != nil {
-		return err
-	}
-	if affected == 0 {
-		return ErrProfileNotFound
+		return 0, err
 	}
 
-	return nil
+	return result.RowsAffected()
 }
 
 func isDuplicateEntryError(err error) bool {
 	var mysqlErr *mysqlDriver.MySQLError
 	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
 }
+
+// isDuplicateKeyError reports whether err is a duplicate entry on the named unique index.
+func isDuplicateKeyError(err error, key string) bool {
+	var mysqlErr *mysqlDriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && strings.Contains(mysqlErr.Message, key)
+}
+
+// isForeignKeyError reports a child row whose parent does not exist (ER_NO_REFERENCED_ROW_2).
+func isForeignKeyError(err error) bool {
+	var mysqlErr *mysqlDriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1452
+}
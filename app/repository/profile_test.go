@@ -19,6 +19,8 @@ import (
 
 type fakeDB struct {
 	execFn func(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// rowDB answers QueryRowContext when set.
+	rowDB *sql.DB
 }
 
 func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
@@ -28,8 +30,10 @@ func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...interfac
 	return fakeResult{lastInsertID: 1, rowsAffected: 1}, nil
 }
 
-func (f *fakeDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
-	// Not used in these tests.
+func (f *fakeDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if f.rowDB != nil {
+		return f.rowDB.QueryRowContext(ctx, query, args...)
+	}
 	return nil
 }
 
@@ -98,11 +102,14 @@ func TestCreateLastInsertIDError(t *testing.T) {
 }
 
 func TestUpdateSuccess(t *testing.T) {
-	profile := &entity.Profile{ID: 11, Email: "before@example.com", UpdatedAt: time.Now().Add(-time.Hour)}
+	profile := &entity.Profile{ID: 11, Email: "before@example.com", UpdatedAt: time.Now().Add(-time.Hour), Version: 4}
 	oldUpdatedAt := profile.UpdatedAt
 
 	repo := NewProfileRepository(&fakeDB{
-		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.Contains(query, "version = version + 1") || args[len(args)-1] != uint64(4) {
+				t.Fatalf("expected a write guarded by the read version, got %q %v", query, args)
+			}
 			return fakeResult{rowsAffected: 1}, nil
 		},
 	})
@@ -113,6 +120,9 @@ func TestUpdateSuccess(t *testing.T) {
 	if !profile.UpdatedAt.After(oldUpdatedAt) {
 		t.Fatalf("expected UpdatedAt to move forward (old=%v new=%v)", oldUpdatedAt, profile.UpdatedAt)
 	}
+	if profile.Version != 5 {
+		t.Fatalf("expected version to be incremented, got %d", profile.Version)
+	}
 }
 
 func TestUpdateNotFoundWhenNoRowsAffected(t *testing.T) {
@@ -120,6 +130,7 @@ func TestUpdateNotFoundWhenNoRowsAffected(t *testing.T) {
 		execFn: func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
 			return fakeResult{rowsAffected: 0}, nil
 		},
+		rowDB: newQueryTestDB(t, queryCase{columns: []string{"1"}}),
 	})
 
 	err := repo.Update(context.Background(), &entity.Profile{ID: 11})
@@ -135,7 +146,7 @@ func TestDeleteSuccess(t *testing.T) {
 		},
 	})
 
-	if err := repo.Delete(context.Background(), 10); err != nil {
+	if err := repo.Delete(context.Background(), 10, 0); err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 }
@@ -147,7 +158,7 @@ func TestDeleteNotFoundWhenNoRowsAffected(t *testing.T) {
 		},
 	})
 
-	err := repo.Delete(context.Background(), 10)
+	err := repo.Delete(context.Background(), 10, 0)
 	if !errors.Is(err, ErrProfileNotFound) {
 		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
 	}
@@ -162,6 +173,15 @@ func TestIsDuplicateEntryError(t *testing.T) {
 	}
 }
 
+func TestIsForeignKeyError(t *testing.T) {
+	if !isForeignKeyError(&mysqlDriver.MySQLError{Number: 1452}) {
+		t.Fatal("expected foreign-key detection to be true for MySQL 1452")
+	}
+	if isForeignKeyError(&mysqlDriver.MySQLError{Number: 1062}) {
+		t.Fatal("expected foreign-key detection to be false for MySQL 1062")
+	}
+}
+
 var (
 	queryDriverOnce sync.Once
 	queryCaseID     uint64
@@ -170,7 +190,9 @@ var (
 
 type queryCase struct {
 	queryErr error
-	row      []driver.Value
+	// columns defaults to the profile columns.
+	columns []string
+	row     []driver.Value
 }
 
 type queryStubDriver struct{}
@@ -203,8 +225,13 @@ func (c *queryStubConn) QueryContext(_ context.Context, _ string, _ []driver.Nam
 		return nil, tc.queryErr
 	}
 
+	columns := tc.columns
+	if columns == nil {
+		columns = []string{"id", "user_id", "email", "created_at", "updated_at", "version", "deleted_at"}
+	}
+
 	return &queryStubRows{
-		columns:  []string{"id", "user_id", "email", "created_at", "updated_at"},
+		columns:  columns,
 		row:      tc.row,
 		returned: false,
 	}, nil
@@ -273,7 +300,7 @@ func TestFindByIDNoRows(t *testing.T) {
 	db := newQueryTestDB(t, queryCase{row: nil})
 	repo := NewProfileRepository(db)
 
-	profile, err := repo.FindByID(context.Background(), 10)
+	profile, err := repo.FindByID(context.Background(), 10, false)
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
@@ -285,18 +312,18 @@ func TestFindByIDNoRows(t *testing.T) {
 func TestFindByIDSuccess(t *testing.T) {
 	now := time.Now().UTC().Truncate(time.Second)
 	db := newQueryTestDB(t, queryCase{
-		row: []driver.Value{int64(3), int64(42), "john@example.com", now, now},
+		row: []driver.Value{int64(3), int64(42), "john@example.com", now, now, int64(2), nil},
 	})
 	repo := NewProfileRepository(db)
 
-	profile, err := repo.FindByID(context.Background(), 3)
+	profile, err := repo.FindByID(context.Background(), 3, false)
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 	if profile == nil {
 		t.Fatal("expected profile, got nil")
 	}
-	if profile.ID != 3 || profile.UserID != 42 || profile.Email != "john@example.com" {
+	if profile.ID != 3 || profile.UserID != 42 || profile.Email != "john@example.com" || profile.Version != 2 {
 		t.Fatalf("unexpected profile values: %+v", profile)
 	}
 }
@@ -307,7 +334,7 @@ func TestFindByIDQueryError(t *testing.T) {
 	})
 	repo := NewProfileRepository(db)
 
-	_, err := repo.FindByID(context.Background(), 3)
+	_, err := repo.FindByID(context.Background(), 3, false)
 	if err == nil || !strings.Contains(err.Error(), "query failed") {
 		t.Fatalf("expected query error, got: %v", err)
 	}
@@ -316,21 +343,33 @@ func TestFindByIDQueryError(t *testing.T) {
 func TestFindByIDScanError(t *testing.T) {
 	now := time.Now().UTC().Truncate(time.Second)
 	db := newQueryTestDB(t, queryCase{
-		row: []driver.Value{"bad-id", int64(42), "john@example.com", now, now},
+		row: []driver.Value{"bad-id", int64(42), "john@example.com", now, now, int64(2), nil},
 	})
 	repo := NewProfileRepository(db)
 
-	_, err := repo.FindByID(context.Background(), 3)
+	_, err := repo.FindByID(context.Background(), 3, false)
 	if err == nil {
 		t.Fatal("expected scan error, got nil")
 	}
 }
 
+func TestFindByIDForUpdateNoRows(t *testing.T) {
+	repo := NewProfileRepository(&fakeDB{rowDB: newQueryTestDB(t, queryCase{columns: []string{"id"}, row: nil})})
+
+	profile, err := repo.FindByIDForUpdate(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if profile != nil {
+		t.Fatalf("expected nil profile for no rows, got: %+v", profile)
+	}
+}
+
 func TestFindByUserIDNoRows(t *testing.T) {
 	db := newQueryTestDB(t, queryCase{row: nil})
 	repo := NewProfileRepository(db)
 
-	profile, err := repo.FindByUserID(context.Background(), 42)
+	profile, err := repo.FindByUserID(context.Background(), 42, false)
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
@@ -342,18 +381,83 @@ func TestFindByUserIDNoRows(t *testing.T) {
 func TestFindByUserIDSuccess(t *testing.T) {
 	now := time.Now().UTC().Truncate(time.Second)
 	db := newQueryTestDB(t, queryCase{
-		row: []driver.Value{int64(3), int64(42), "john@example.com", now, now},
+		row: []driver.Value{int64(3), int64(42), "john@example.com", now, now, int64(2), nil},
 	})
 	repo := NewProfileRepository(db)
 
-	profile, err := repo.FindByUserID(context.Background(), 42)
+	profile, err := repo.FindByUserID(context.Background(), 42, false)
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 	if profile == nil {
 		t.Fatal("expected profile, got nil")
 	}
-	if profile.ID != 3 || profile.UserID != 42 || profile.Email != "john@example.com" {
+	if profile.ID != 3 || profile.UserID != 42 || profile.Email != "john@example.com" || profile.Version != 2 {
+		t.Fatalf("unexpected profile values: %+v", profile)
+	}
+}
+
+func TestFindByEmailSuccess(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	db := newQueryTestDB(t, queryCase{
+		row: []driver.Value{int64(3), int64(42), "john@example.com", now, now, int64(2), nil},
+	})
+	repo := NewProfileRepository(db)
+
+	profile, err := repo.FindByEmail(context.Background(), "john@example.com", false)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if profile == nil || profile.ID != 3 || profile.Email != "john@example.com" {
 		t.Fatalf("unexpected profile values: %+v", profile)
 	}
 }
+
+func TestFindByEmailForUpdateLocksTheEmailFirst(t *testing.T) {
+	var lockQuery string
+	var lockArgs []interface{}
+	repo := NewProfileRepository(&fakeDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			lockQuery, lockArgs = query, args
+			return fakeResult{rowsAffected: 1}, nil
+		},
+		rowDB: newQueryTestDB(t, queryCase{row: nil}),
+	})
+
+	profile, err := repo.FindByEmailForUpdate(context.Background(), "john@example.com")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if profile != nil {
+		t.Fatalf("expected nil profile for no rows, got: %+v", profile)
+	}
+	if !strings.Contains(lockQuery, "profile_email_locks") || len(lockArgs) != 1 || lockArgs[0] != "john@example.com" {
+		t.Fatalf("expected the email lock row to be written, got %q %v", lockQuery, lockArgs)
+	}
+}
+
+func TestFindByEmailForUpdateLockError(t *testing.T) {
+	lockErr := errors.New("lock wait timeout")
+	repo := NewProfileRepository(&fakeDB{
+		execFn: func(context.Context, string, ...interface{}) (sql.Result, error) {
+			return nil, lockErr
+		},
+	})
+
+	if _, err := repo.FindByEmailForUpdate(context.Background(), "john@example.com"); !errors.Is(err, lockErr) {
+		t.Fatalf("expected the lock error, got: %v", err)
+	}
+}
+
+func TestFindByEmailNoRows(t *testing.T) {
+	db := newQueryTestDB(t, queryCase{row: nil})
+	repo := NewProfileRepository(db)
+
+	profile, err := repo.FindByEmail(context.Background(), "john@example.com", true)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if profile != nil {
+		t.Fatalf("expected nil profile for no rows, got: %+v", profile)
+	}
+}
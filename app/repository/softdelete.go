@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotDeleted is returned when restoring a record that is not soft-deleted.
+	ErrNotDeleted = errors.New("record is not deleted")
+)
+
+// softDelete marks the live row with the given id as deleted. A non-zero
+// expectedVersion restricts the delete to that version. With clearPrimary the row also
+// stops being primary, so that the profile can pick another one while it stays deleted.
+func softDelete(ctx context.Context, db DBTX, table string, id uint64, expectedVersion uint64, clearPrimary bool, notFound error) error {
+	set := `deleted_at = ?, version = version + 1`
+	if clearPrimary {
+		set += `, is_primary = 0`
+	}
+	query := `UPDATE ` + table + ` SET ` + set + ` WHERE id = ? AND deleted_at IS NULL`
+	args := []interface{}{time.Now(), id}
+	if expectedVersion != 0 {
+		query += ` AND version = ?`
+		args = append(args, expectedVersion)
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		if expectedVersion == 0 {
+			return notFound
+		}
+		return staleWriteError(ctx, db, table, id, notFound)
+	}
+
+	return nil
+}
+
+// softDeleteByProfileID marks every live row of the profile in table as deleted.
+func softDeleteByProfileID(ctx context.Context, db DBTX, table string, profileID uint64) error {
+	return softDeleteByOwner(ctx, db, table, "profile_id", profileID)
+}
+
+// softDeleteByOwner marks every live row whose ownerColumn is ownerID as deleted.
+func softDeleteByOwner(ctx context.Context, db DBTX, table string, ownerColumn string, ownerID uint64) error {
+	query := `UPDATE ` + table + ` SET deleted_at = ?, version = version + 1 WHERE ` + ownerColumn + ` = ? AND deleted_at IS NULL`
+	_, err := db.ExecContext(ctx, query, time.Now(), ownerID)
+	return err
+}
+
+// liveProfileOwner limits a restore to rows whose profile is live.
+const liveProfileOwner = `profile_id IN (SELECT id FROM profile WHERE deleted_at IS NULL)`
+
+// restore clears deleted_at on the row. A non-empty liveOwner is the condition the row must
+// also meet, such as liveProfileOwner; rows failing it give ErrProfileReferenceNotFound.
+func restore(ctx context.Context, db DBTX, table string, id uint64, liveOwner string, notFound error) error {
+	query := `UPDATE ` + table + ` SET deleted_at = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL`
+	if liveOwner != "" {
+		query += ` AND ` + liveOwner
+	}
+
+	result, err := db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var deleted bool
+	err = db.QueryRowContext(ctx, `SELECT deleted_at IS NOT NULL FROM `+table+` WHERE id = ?`, id).Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotDeleted
+	}
+
+	return ErrProfileReferenceNotFound
+}
+
+// restoreByProfileID clears deleted_at on the rows of the profile deleted at or after deletedSince,
+// which leaves alone the rows that had been deleted on their own before the profile.
+func restoreByProfileID(ctx context.Context, db DBTX, table string, profileID uint64, deletedSince time.Time) error {
+	return restoreByOwner(ctx, db, table, "profile_id", profileID, deletedSince)
+}
+
+// restoreByOwner clears deleted_at on the rows whose ownerColumn is ownerID that were deleted at
+// or after deletedSince.
+func restoreByOwner(ctx context.Context, db DBTX, table string, ownerColumn string, ownerID uint64, deletedSince time.Time) error {
+	query := `UPDATE ` + table + ` SET deleted_at = NULL, updated_at = ?, version = version + 1 WHERE ` + ownerColumn + ` = ? AND deleted_at >= ?`
+	_, err := db.ExecContext(ctx, query, time.Now(), ownerID, deletedSince)
+	return err
+}
+
+// purgeDeleted hard-deletes up to limit rows of table soft-deleted before deletedBefore
+// and returns how many were removed.
+func purgeDeleted(ctx context.Context, db DBTX, table string, deletedBefore time.Time, limit uint32) (int64, error) {
+	query := `DELETE FROM ` + table + ` WHERE deleted_at < ? ORDER BY id LIMIT ?`
+	result, err := db.ExecContext(ctx, query, deletedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
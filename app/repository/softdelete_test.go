@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSoftDeleteMarksRowInsteadOfDeleting(t *testing.T) {
+	repo := NewContactRepository(&fakeContactDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.HasPrefix(query, "UPDATE contacts SET deleted_at = ?") || !strings.Contains(query, "deleted_at IS NULL") {
+				t.Fatalf("expected a soft delete of a live row, got %q", query)
+			}
+			if len(args) != 2 || args[1] != uint64(5) {
+				t.Fatalf("unexpected args: %v", args)
+			}
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	}, nil)
+
+	if err := repo.Delete(context.Background(), 5, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRestoreExplainsMissedRow(t *testing.T) {
+	noRows := func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+		return fakeResult{rowsAffected: 0}, nil
+	}
+	cases := []struct {
+		name     string
+		row      []driver.Value
+		expected error
+	}{
+		{name: "missing", row: nil, expected: ErrAddressNotFound},
+		{name: "live", row: []driver.Value{int64(0)}, expected: ErrNotDeleted},
+		{name: "deleted profile", row: []driver.Value{int64(1)}, expected: ErrProfileReferenceNotFound},
+	}
+	for _, tc := range cases {
+		repo := NewAddressRepository(&fakeAddressDB{
+			execFn: noRows,
+			rowDB:  newQueryTestDB(t, queryCase{columns: []string{"deleted"}, row: tc.row}),
+		})
+		if err := repo.Restore(context.Background(), 4); !errors.Is(err, tc.expected) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestRestoreChildRequiresLiveProfile(t *testing.T) {
+	repo := NewCompanyRepository(&fakeCompanyDB{
+		execFn: func(_ context.Context, query string, _ ...interface{}) (sql.Result, error) {
+			if !strings.Contains(query, "profile_id IN (SELECT id FROM profile WHERE deleted_at IS NULL)") {
+				t.Fatalf("expected restore to be limited to live profiles, got %q", query)
+			}
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	})
+
+	if err := repo.Restore(context.Background(), 4); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestPurgeDeletesOldSoftDeletedRowsInBatches(t *testing.T) {
+	cutoff := time.Now().Add(-24 * time.Hour)
+	repo := NewProfileRepository(&fakeDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.HasPrefix(query, "DELETE FROM profile WHERE deleted_at < ?") || !strings.HasSuffix(query, "LIMIT ?") {
+				t.Fatalf("unexpected purge query %q", query)
+			}
+			if !strings.Contains(query, "NOT EXISTS (SELECT 1 FROM companies c WHERE c.profile_id = profile.id)") {
+				t.Fatalf("unexpected purge query %q", query)
+			}
+			if args[0] != cutoff || args[1] != uint32(100) {
+				t.Fatalf("unexpected purge args: %v", args)
+			}
+			return fakeResult{rowsAffected: 7}, nil
+		},
+	})
+
+	purged, err := repo.Purge(context.Background(), cutoff, 100)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if purged != 7 {
+		t.Fatalf("expected 7 purged rows, got %d", purged)
+	}
+}
+
+func TestPurgeEmailLocksKeepsEmailsOfLiveProfiles(t *testing.T) {
+	repo := NewProfileEmailLockRepository(&fakeDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.HasPrefix(query, "DELETE FROM profile_email_locks WHERE NOT EXISTS") || !strings.Contains(query, "p.deleted_at IS NULL") {
+				t.Fatalf("unexpected purge query %q", query)
+			}
+			if len(args) != 1 || args[0] != uint32(100) {
+				t.Fatalf("unexpected purge args: %v", args)
+			}
+			return fakeResult{rowsAffected: 3}, nil
+		},
+	})
+
+	purged, err := repo.Purge(context.Background(), time.Now(), 100)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if purged != 3 {
+		t.Fatalf("expected 3 purged rows, got %d", purged)
+	}
+}
+
+func TestFindByIDIncludingDeletedReturnsDeletedAt(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	repo := NewProfileRepository(newQueryTestDB(t, queryCase{
+		row: []driver.Value{int64(3), int64(42), "john@example.com", now, now, int64(2), now},
+	}))
+
+	profile, err := repo.FindByID(context.Background(), 3, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.DeletedAt == nil || !profile.DeletedAt.Equal(now) {
+		t.Fatalf("expected deleted_at %v, got %v", now, profile.DeletedAt)
+	}
+}
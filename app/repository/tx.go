@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vibast-solutions/ms-go-profile/app/fieldcrypt"
+)
+
+var (
+	ErrInvalidIsolationLevel = errors.New("invalid transaction isolation level")
+)
+
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// QueryDBTX is satisfied by both *sql.DB and *sql.Tx.
+type QueryDBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Repositories groups every repository bound to the same connection or transaction.
+type Repositories struct {
+	Profiles       *ProfileRepository
+	Contacts       *ContactRepository
+	Addresses      *AddressRepository
+	Companies      *CompanyRepository
+	CompanyMembers *CompanyMemberRepository
+	Preferences    *PreferenceRepository
+	Attachments    *AttachmentRepository
+	Audit          *AuditRepository
+	Outbox         *OutboxRepository
+}
+
+func NewRepositories(db QueryDBTX, cipher *fieldcrypt.Cipher) *Repositories {
+	return &Repositories{
+		Profiles:       NewProfileRepository(db),
+		Contacts:       NewContactRepository(db, cipher),
+		Addresses:      NewAddressRepository(db),
+		Companies:      NewCompanyRepository(db),
+		CompanyMembers: NewCompanyMemberRepository(db),
+		Preferences:    NewPreferenceRepository(db),
+		Attachments:    NewAttachmentRepository(db),
+		Audit:          NewAuditRepository(db),
+		Outbox:         NewOutboxRepository(db),
+	}
+}
+
+type TxManager struct {
+	db        TxBeginner
+	isolation sql.IsolationLevel
+	cipher    *fieldcrypt.Cipher
+}
+
+func NewTxManager(db TxBeginner, isolation sql.IsolationLevel, cipher *fieldcrypt.Cipher) *TxManager {
+	return &TxManager{db: db, isolation: isolation, cipher: cipher}
+}
+
+// WithinTx runs fn over repositories bound to a single transaction. The transaction
+// is committed when fn returns nil and rolled back when it returns an error or panics.
+// A nil opts uses the manager's default isolation level.
+func (m *TxManager) WithinTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos *Repositories) error) (err error) {
+	if opts == nil {
+		opts = &sql.TxOptions{Isolation: m.isolation}
+	}
+
+	tx, err := m.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				err = errors.Join(err, fmt.Errorf("rollback transaction: %w", rbErr))
+			}
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commit transaction: %w", err)
+		}
+	}()
+
+	return fn(ctx, NewRepositories(tx, m.cipher))
+}
+
+// ParseIsolationLevel maps a config value such as "read-committed" to a sql.IsolationLevel.
+// An empty value selects the driver default.
+func ParseIsolationLevel(raw string) (sql.IsolationLevel, error) {
+	normalized := strings.ToLower(strings.TrimSpace(raw))
+	normalized = strings.NewReplacer("_", "-", " ", "-").Replace(normalized)
+
+	switch normalized {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read-uncommitted":
+		return sql.LevelReadUncommitted, nil
+	case "read-committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable-read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("%w: %q", ErrInvalidIsolationLevel, raw)
+	}
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var (
+	txDriverOnce sync.Once
+	txCaseID     uint64
+	txCases      sync.Map
+)
+
+type txRecorder struct {
+	mu         sync.Mutex
+	began      int
+	committed  int
+	rolledBack int
+	isolation  driver.IsolationLevel
+}
+
+type txStubDriver struct{}
+
+func (d *txStubDriver) Open(name string) (driver.Conn, error) {
+	v, ok := txCases.Load(name)
+	if !ok {
+		return nil, errors.New("missing tx case")
+	}
+	return &txStubConn{rec: v.(*txRecorder)}, nil
+}
+
+type txStubConn struct {
+	rec *txRecorder
+}
+
+func (c *txStubConn) Prepare(_ string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported in tx stub")
+}
+
+func (c *txStubConn) Close() error { return nil }
+
+func (c *txStubConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txStubConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	c.rec.began++
+	c.rec.isolation = opts.Isolation
+	return &txStubTx{rec: c.rec}, nil
+}
+
+type txStubTx struct {
+	rec *txRecorder
+}
+
+func (t *txStubTx) Commit() error {
+	t.rec.mu.Lock()
+	defer t.rec.mu.Unlock()
+	t.rec.committed++
+	return nil
+}
+
+func (t *txStubTx) Rollback() error {
+	t.rec.mu.Lock()
+	defer t.rec.mu.Unlock()
+	t.rec.rolledBack++
+	return nil
+}
+
+func newTxTestDB(t *testing.T) (*sql.DB, *txRecorder) {
+	t.Helper()
+
+	txDriverOnce.Do(func() {
+		sql.Register("repo_tx_stub", &txStubDriver{})
+	})
+
+	rec := &txRecorder{}
+	dsn := fmt.Sprintf("tx-case-%d", atomic.AddUint64(&txCaseID, 1))
+	txCases.Store(dsn, rec)
+
+	db, err := sql.Open("repo_tx_stub", dsn)
+	if err != nil {
+		t.Fatalf("failed to open tx stub db: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		txCases.Delete(dsn)
+	})
+
+	return db, rec
+}
+
+func TestWithinTxCommitsOnSuccess(t *testing.T) {
+	db, rec := newTxTestDB(t)
+	manager := NewTxManager(db, sql.LevelReadCommitted, nil)
+
+	err := manager.WithinTx(context.Background(), nil, func(_ context.Context, repos *Repositories) error {
+		if repos.Profiles == nil || repos.Contacts == nil || repos.Addresses == nil || repos.Companies == nil {
+			t.Fatal("expected every repository to be bound to the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rec.began != 1 || rec.committed != 1 || rec.rolledBack != 0 {
+		t.Fatalf("expected a single committed transaction, got %+v", rec)
+	}
+	if rec.isolation != driver.IsolationLevel(sql.LevelReadCommitted) {
+		t.Fatalf("expected default isolation to be applied, got %v", rec.isolation)
+	}
+}
+
+func TestWithinTxUsesExplicitOptions(t *testing.T) {
+	db, rec := newTxTestDB(t)
+	manager := NewTxManager(db, sql.LevelReadCommitted, nil)
+
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	if err := manager.WithinTx(context.Background(), opts, func(context.Context, *Repositories) error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if rec.isolation != driver.IsolationLevel(sql.LevelSerializable) {
+		t.Fatalf("expected explicit isolation, got %v", rec.isolation)
+	}
+}
+
+func TestWithinTxRollsBackOnError(t *testing.T) {
+	db, rec := newTxTestDB(t)
+	manager := NewTxManager(db, sql.LevelDefault, nil)
+	boom := errors.New("boom")
+
+	err := manager.WithinTx(context.Background(), nil, func(context.Context, *Repositories) error {
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected callback error, got: %v", err)
+	}
+	if rec.committed != 0 || rec.rolledBack != 1 {
+		t.Fatalf("expected rollback only, got %+v", rec)
+	}
+}
+
+func TestWithinTxRollsBackOnPanic(t *testing.T) {
+	db, rec := newTxTestDB(t)
+	manager := NewTxManager(db, sql.LevelDefault, nil)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("expected panic to be re-raised, got: %v", p)
+		}
+		if rec.committed != 0 || rec.rolledBack != 1 {
+			t.Fatalf("expected rollback only, got %+v", rec)
+		}
+	}()
+
+	_ = manager.WithinTx(context.Background(), nil, func(context.Context, *Repositories) error {
+		panic("boom")
+	})
+}
+
+func TestParseIsolationLevel(t *testing.T) {
+	cases := map[string]sql.IsolationLevel{
+		"":                 sql.LevelDefault,
+		"default":          sql.LevelDefault,
+		"READ-UNCOMMITTED": sql.LevelReadUncommitted,
+		"read_committed":   sql.LevelReadCommitted,
+		"repeatable read":  sql.LevelRepeatableRead,
+		"serializable":     sql.LevelSerializable,
+	}
+	for raw, expected := range cases {
+		level, err := ParseIsolationLevel(raw)
+		if err != nil {
+			t.Fatalf("ParseIsolationLevel(%q) returned error: %v", raw, err)
+		}
+		if level != expected {
+			t.Fatalf("ParseIsolationLevel(%q) = %v, expected %v", raw, level, expected)
+		}
+	}
+
+	if _, err := ParseIsolationLevel("snapshot"); !errors.Is(err, ErrInvalidIsolationLevel) {
+		t.Fatalf("expected ErrInvalidIsolationLevel, got: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrVersionConflict is returned when a versioned write finds the row at a different version.
+	ErrVersionConflict = errors.New("record version conflict")
+)
+
+// staleWriteError explains why a versioned write matched no row: the row is
+// either gone or soft-deleted (notFound), or was changed since it was read (ErrVersionConflict).
+func staleWriteError(ctx context.Context, db DBTX, table string, id uint64, notFound error) error {
+	var exists int
+	err := db.QueryRowContext(ctx, `SELECT 1 FROM `+table+` WHERE id = ? AND deleted_at IS NULL`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return ErrVersionConflict
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDeleteGuardsOnExpectedVersion(t *testing.T) {
+	repo := NewAddressRepository(&fakeAddressDB{
+		execFn: func(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+			if !strings.Contains(query, "version = ?") || len(args) != 3 || args[2] != uint64(7) {
+				t.Fatalf("expected delete guarded by version 7, got %q %v", query, args)
+			}
+			return fakeResult{rowsAffected: 1}, nil
+		},
+	})
+
+	if err := repo.Delete(context.Background(), 1, 7); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestDeleteDistinguishesConflictFromMissingRow(t *testing.T) {
+	noRows := func(_ context.Context, _ string, _ ...interface{}) (sql.Result, error) {
+		return fakeResult{rowsAffected: 0}, nil
+	}
+
+	gone := NewCompanyRepository(&fakeCompanyDB{
+		execFn: noRows,
+		rowDB:  newQueryTestDB(t, queryCase{columns: []string{"1"}}),
+	})
+	if err := gone.Delete(context.Background(), 1, 2); !errors.Is(err, ErrCompanyNotFound) {
+		t.Fatalf("expected ErrCompanyNotFound, got %v", err)
+	}
+
+	changed := NewProfileRepository(&fakeDB{
+		execFn: noRows,
+		rowDB:  newQueryTestDB(t, queryCase{columns: []string{"1"}, row: []driver.Value{int64(1)}}),
+	})
+	if err := changed.Delete(context.Background(), 1, 2); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+}
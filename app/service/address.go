@@ -3,10 +3,17 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/geo"
+	"github.com/vibast-solutions/ms-go-profile/app/postal"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var (
@@ -20,11 +27,16 @@ type createAddressRequest interface {
 	GetCounty() string
 	GetCountry() string
 	GetProfileId() uint64
+	GetCompanyId() uint64
 	GetPostalCode() string
 	GetBuilding() string
 	GetApartment() string
 	GetAdditionalData() string
 	GetType() string
+	GetLatitude() float64
+	GetLongitude() float64
+	HasCoordinates() bool
+	GetAttributes() *structpb.Struct
 }
 
 type updateAddressRequest interface {
@@ -35,26 +47,59 @@ type updateAddressRequest interface {
 	GetCounty() string
 	GetCountry() string
 	GetProfileId() uint64
+	GetCompanyId() uint64
 	GetPostalCode() string
 	GetBuilding() string
 	GetApartment() string
 	GetAdditionalData() string
 	GetType() string
+	GetLatitude() float64
+	GetLongitude() float64
+	HasCoordinates() bool
+	GetExpectedVersion() uint64
+	GetAttributes() *structpb.Struct
+}
+
+type patchAddressRequest interface {
+	updateAddressRequest
+	GetUpdateMask() *fieldmaskpb.FieldMask
 }
 
 type listAddressesRequest interface {
 	GetProfileId() uint64
+	GetCompanyId() uint64
 	GetPage() uint32
 	GetPageSize() uint32
 	GetType() string
+	GetIncludeDeleted() bool
+	GetPrimaryOnly() bool
+	GetAttributes() map[string]string
+}
+
+type searchAddressesNearRequest interface {
+	CenterPoint() *geo.Point
+	GetRadiusMeters() float64
+	Box() *geo.Box
+	GetProfileId() uint64
+	GetType() string
+	GetLimit() uint32
 }
 
 type addressRepository interface {
 	Create(ctx context.Context, address *entity.Address) error
-	FindByID(ctx context.Context, id uint64) (*entity.Address, error)
+	FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error)
+	FindPrimary(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error)
 	Update(ctx context.Context, address *entity.Address) error
-	Delete(ctx context.Context, id uint64) error
-	List(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error)
+	Delete(ctx context.Context, id uint64, expectedVersion uint64) error
+	DeleteByProfileID(ctx context.Context, profileID uint64) error
+	Restore(ctx context.Context, id uint64) error
+	RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error
+	DeleteByCompanyID(ctx context.Context, companyID uint64) error
+	RestoreByCompanyID(ctx context.Context, companyID uint64, deletedSince time.Time) error
+	List(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error)
+	ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Address, error)
+	ListByCompanyID(ctx context.Context, companyID uint64) ([]*entity.Address, error)
+	SearchNear(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error)
 }
 
 type AddressList struct {
@@ -66,39 +111,51 @@ type AddressList struct {
 
 type AddressService struct {
 	addressRepo addressRepository
+	uow         UnitOfWork
+	geocoder    geo.Geocoder
+	attributes  *attribute.Registry
 }
 
-func NewAddressService(addressRepo addressRepository) *AddressService {
-	return &AddressService{addressRepo: addressRepo}
+// NewAddressService creates the service. geocoder fills in the coordinates of addresses
+// written without them; with a nil geocoder such addresses have none. attributes defines the
+// custom attributes addresses may carry.
+func NewAddressService(addressRepo addressRepository, uow UnitOfWork, geocoder geo.Geocoder, attributes *attribute.Registry) *AddressService {
+	return &AddressService{addressRepo: addressRepo, uow: uow, geocoder: geocoder, attributes: attributes}
 }
 
 func (s *AddressService) Create(ctx context.Context, req createAddressRequest) (*entity.Address, error) {
-	now := time.Now()
-	address := &entity.Address{
-		StreetName:     req.GetStreetName(),
-		StreenNo:       req.GetStreenNo(),
-		City:           req.GetCity(),
-		County:         req.GetCounty(),
-		Country:        req.GetCountry(),
-		ProfileID:      req.GetProfileId(),
-		PostalCode:     req.GetPostalCode(),
-		Building:       req.GetBuilding(),
-		Apartment:      req.GetApartment(),
-		AdditionalData: req.GetAdditionalData(),
-		Type:           req.GetType(),
-		CreatedAt:      now,
-		UpdatedAt:      now,
+	address := newAddressEntity(req, time.Now())
+	if err := s.attributes.Validate(attribute.EntityAddress, address.Type, address.Attributes); err != nil {
+		return nil, err
+	}
+	if !req.HasCoordinates() {
+		if err := s.locate(ctx, address); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.addressRepo.Create(ctx, address); err != nil {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := repos.Addresses.Create(ctx, address); err != nil {
+			if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+				return ErrProfileNotFound
+			}
+			if errors.Is(err, repository.ErrCompanyReferenceNotFound) {
+				return ErrCompanyNotFound
+			}
+			return err
+		}
+		return recordChange(ctx, repos, AuditEntityAddress, address.ID, AuditActionCreate, nil, addressAuditValues(address))
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return address, nil
 }
 
-func (s *AddressService) GetByID(ctx context.Context, id uint64) (*entity.Address, error) {
-	address, err := s.addressRepo.FindByID(ctx, id)
+// GetByID returns the address; soft-deleted addresses are only returned with includeDeleted.
+func (s *AddressService) GetByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error) {
+	address, err := s.addressRepo.FindByID(ctx, id, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -110,13 +167,19 @@ func (s *AddressService) GetByID(ctx context.Context, id uint64) (*entity.Addres
 }
 
 func (s *AddressService) Update(ctx context.Context, req updateAddressRequest) (*entity.Address, error) {
-	address, err := s.addressRepo.FindByID(ctx, req.GetId())
+	address, err := s.addressRepo.FindByID(ctx, req.GetId(), false)
 	if err != nil {
 		return nil, err
 	}
 	if address == nil {
 		return nil, ErrAddressNotFound
 	}
+	if expected := req.GetExpectedVersion(); expected != 0 && address.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := addressAuditValues(address)
+	profileID, companyID, addressType := address.ProfileID, address.CompanyID, address.Type
+	location := geoAddress(address)
 
 	address.StreetName = req.GetStreetName()
 	address.StreenNo = req.GetStreenNo()
@@ -124,31 +187,289 @@ func (s *AddressService) Update(ctx context.Context, req updateAddressRequest) (
 	address.County = req.GetCounty()
 	address.Country = req.GetCountry()
 	address.ProfileID = req.GetProfileId()
+	address.CompanyID = req.GetCompanyId()
 	address.PostalCode = req.GetPostalCode()
 	address.Building = req.GetBuilding()
 	address.Apartment = req.GetApartment()
 	address.AdditionalData = req.GetAdditionalData()
 	address.Type = req.GetType()
+	address.Attributes = attribute.Values(req.GetAttributes())
+	if err = s.attributes.Validate(attribute.EntityAddress, address.Type, address.Attributes); err != nil {
+		return nil, err
+	}
 
-	if err = s.addressRepo.Update(ctx, address); err != nil {
-		if errors.Is(err, repository.ErrAddressNotFound) {
-			return nil, ErrAddressNotFound
+	if address.ProfileID != profileID || address.CompanyID != companyID || address.Type != addressType {
+		address.IsPrimary = false
+	}
+	// Coordinates left out are kept while the address they were found for is unchanged.
+	if req.HasCoordinates() {
+		setCoordinates(address, req.GetLatitude(), req.GetLongitude())
+	} else if geoAddress(address) != location {
+		if err = s.locate(ctx, address); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.save(ctx, address, before)
+}
+
+// Patch changes only the fields named in the request's update mask.
+func (s *AddressService) Patch(ctx context.Context, req patchAddressRequest) (*entity.Address, error) {
+	address, err := s.addressRepo.FindByID(ctx, req.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, ErrAddressNotFound
+	}
+	if expected := req.GetExpectedVersion(); expected != 0 && address.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := addressAuditValues(address)
+	profileID, companyID, addressType := address.ProfileID, address.CompanyID, address.Type
+	location := geoAddress(address)
+	countryFieldsChanged, coordinatesPatched, attributesChanged := false, false, false
+
+	for _, path := range req.GetUpdateMask().GetPaths() {
+		switch path {
+		case "street_name":
+			address.StreetName = req.GetStreetName()
+		case "streen_no":
+			address.StreenNo = req.GetStreenNo()
+		case "city":
+			address.City = req.GetCity()
+		case "county":
+			address.County = req.GetCounty()
+			countryFieldsChanged = true
+		case "country":
+			address.Country = req.GetCountry()
+			countryFieldsChanged = true
+		case "profile_id":
+			if address.ProfileID = req.GetProfileId(); address.ProfileID != 0 {
+				address.CompanyID = 0
+			}
+		case "company_id":
+			if address.CompanyID = req.GetCompanyId(); address.CompanyID != 0 {
+				address.ProfileID = 0
+			}
+		case "postal_code":
+			address.PostalCode = req.GetPostalCode()
+			countryFieldsChanged = true
+		case "building":
+			address.Building = req.GetBuilding()
+		case "apartment":
+			address.Apartment = req.GetApartment()
+		case "additional_data":
+			address.AdditionalData = req.GetAdditionalData()
+		case "type":
+			address.Type = req.GetType()
+		case "latitude", "longitude":
+			coordinatesPatched = true
+		default:
+			attributes, ok := patchAttributes(address.Attributes, path, req.GetAttributes())
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidUpdateMask, path)
+			}
+			address.Attributes, attributesChanged = attributes, true
+		}
+	}
+	// The request cannot check a county or postal code against a country it does not carry.
+	if countryFieldsChanged {
+		normalized, err := country.NormalizeAddress(country.Address{Country: address.Country, County: address.County, PostalCode: address.PostalCode})
+		if err != nil {
+			return nil, err
+		}
+		address.Country, address.County, address.PostalCode = normalized.Country, normalized.County, normalized.PostalCode
+	}
+	// A patch of the type alone is checked against the owner the address already has.
+	if address.CompanyID != 0 && !entity.IsCompanyAddressType(address.Type) {
+		return nil, &country.FieldError{Field: "type", Message: "type of a company address must be one of registered_office, billing, warehouse"}
+	}
+	if attributesChanged || address.Type != addressType {
+		if err = s.attributes.Validate(attribute.EntityAddress, address.Type, address.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if address.ProfileID != profileID || address.CompanyID != companyID || address.Type != addressType {
+		address.IsPrimary = false
+	}
+	switch {
+	case coordinatesPatched && req.HasCoordinates():
+		setCoordinates(address, req.GetLatitude(), req.GetLongitude())
+	case coordinatesPatched:
+		address.Latitude, address.Longitude = nil, nil
+	case geoAddress(address) != location:
+		if err = s.locate(ctx, address); err != nil {
+			return nil, err
 		}
+	}
+
+	return s.save(ctx, address, before)
+}
+
+// locate sets the coordinates of the address from the geocoder, or clears them when there is
+// no geocoder or it has no match. Other geocoder errors fail the write.
+func (s *AddressService) locate(ctx context.Context, address *entity.Address) error {
+	address.Latitude, address.Longitude = nil, nil
+	if s.geocoder == nil {
+		return nil
+	}
+
+	point, err := s.geocoder.Geocode(ctx, geoAddress(address))
+	if errors.Is(err, geo.ErrNoMatch) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("geocode address: %w", err)
+	}
+	setCoordinates(address, point.Latitude, point.Longitude)
+
+	return nil
+}
+
+func (s *AddressService) save(ctx context.Context, address *entity.Address, before auditValues) (*entity.Address, error) {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		return updateAddress(ctx, repos, address, before)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return address, nil
 }
 
-func (s *AddressService) Delete(ctx context.Context, id uint64) error {
-	if err := s.addressRepo.Delete(ctx, id); err != nil {
+// updateAddress stores the changed address and records the change in the audit log.
+func updateAddress(ctx context.Context, repos Repositories, address *entity.Address, before auditValues) error {
+	if err := repos.Addresses.Update(ctx, address); err != nil {
 		if errors.Is(err, repository.ErrAddressNotFound) {
 			return ErrAddressNotFound
 		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return ErrVersionConflict
+		}
+		if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+			return ErrTargetProfileNotFound
+		}
+		if errors.Is(err, repository.ErrCompanyReferenceNotFound) {
+			return ErrTargetCompanyNotFound
+		}
+		if errors.Is(err, repository.ErrPrimaryConflict) {
+			return ErrPrimaryConflict
+		}
 		return err
 	}
+	return recordChange(ctx, repos, AuditEntityAddress, address.ID, AuditActionUpdate, before, addressAuditValues(address))
+}
 
-	return nil
+// Delete soft-deletes the address; a non-zero expectedVersion only deletes that version.
+func (s *AddressService) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		address, err := repos.Addresses.FindByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		if address == nil {
+			return ErrAddressNotFound
+		}
+
+		if err = repos.Addresses.Delete(ctx, id, expectedVersion); err != nil {
+			if errors.Is(err, repository.ErrAddressNotFound) {
+				return ErrAddressNotFound
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return ErrVersionConflict
+			}
+			return err
+		}
+
+		return recordChange(ctx, repos, AuditEntityAddress, id, AuditActionDelete, addressAuditValues(address), nil)
+	})
+}
+
+// Restore undeletes the address. An address cannot be restored while its profile or company
+// is deleted.
+func (s *AddressService) Restore(ctx context.Context, id uint64) (*entity.Address, error) {
+	var restored *entity.Address
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		err := repos.Addresses.Restore(ctx, id)
+		if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+			deleted, findErr := repos.Addresses.FindByID(ctx, id, true)
+			if findErr != nil {
+				return findErr
+			}
+			if deleted != nil && deleted.CompanyID != 0 {
+				return ErrCompanyDeleted
+			}
+			return ErrProfileDeleted
+		}
+		if err != nil {
+			if errors.Is(err, repository.ErrAddressNotFound) {
+				return ErrAddressNotFound
+			}
+			if errors.Is(err, repository.ErrNotDeleted) {
+				return ErrNotDeleted
+			}
+			return err
+		}
+
+		if restored, err = repos.Addresses.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if restored == nil {
+			return ErrAddressNotFound
+		}
+
+		return recordChange(ctx, repos, AuditEntityAddress, id, AuditActionRestore, nil, addressAuditValues(restored))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// SetPrimary makes the address the primary one of its type on its profile or company, taking the
+// flag off the previous primary in the same transaction; a non-zero expectedVersion only changes
+// that version. Moving an address to another owner or type takes its flag off.
+func (s *AddressService) SetPrimary(ctx context.Context, id uint64, expectedVersion uint64) (*entity.Address, error) {
+	var address *entity.Address
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		var err error
+		if address, err = repos.Addresses.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if address == nil {
+			return ErrAddressNotFound
+		}
+		if expectedVersion != 0 && address.Version != expectedVersion {
+			return ErrVersionConflict
+		}
+		if address.IsPrimary {
+			return nil
+		}
+
+		current, err := repos.Addresses.FindPrimary(ctx, address.ProfileID, address.CompanyID, address.Type)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			before := addressAuditValues(current)
+			current.IsPrimary = false
+			if err = updateAddress(ctx, repos, current, before); err != nil {
+				return err
+			}
+		}
+
+		before := addressAuditValues(address)
+		address.IsPrimary = true
+		return updateAddress(ctx, repos, address, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return address, nil
 }
 
 func (s *AddressService) List(ctx context.Context, req listAddressesRequest) (*AddressList, error) {
@@ -164,7 +485,7 @@ func (s *AddressService) List(ctx context.Context, req listAddressesRequest) (*A
 
 	offset := (page - 1) * pageSize
 
-	addresses, total, err := s.addressRepo.List(ctx, req.GetProfileId(), req.GetType(), pageSize, offset)
+	addresses, total, err := s.addressRepo.List(ctx, req.GetProfileId(), req.GetCompanyId(), req.GetType(), req.GetAttributes(), req.GetIncludeDeleted(), req.GetPrimaryOnly(), pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -176,3 +497,71 @@ func (s *AddressService) List(ctx context.Context, req listAddressesRequest) (*A
 		Total:     total,
 	}, nil
 }
+
+// SearchNear returns the live addresses within a radius of a point, nearest first, or inside a
+// bounding box, newest first.
+func (s *AddressService) SearchNear(ctx context.Context, req searchAddressesNearRequest) ([]*entity.NearbyAddress, error) {
+	return s.addressRepo.SearchNear(ctx, repository.AddressSearch{
+		Center:       req.CenterPoint(),
+		RadiusMeters: req.GetRadiusMeters(),
+		Box:          req.Box(),
+		ProfileID:    req.GetProfileId(),
+		Type:         req.GetType(),
+		Limit:        req.GetLimit(),
+	})
+}
+
+func newAddressEntity(req createAddressRequest, now time.Time) *entity.Address {
+	address := &entity.Address{
+		StreetName:     req.GetStreetName(),
+		StreenNo:       req.GetStreenNo(),
+		City:           req.GetCity(),
+		County:         req.GetCounty(),
+		Country:        req.GetCountry(),
+		ProfileID:      req.GetProfileId(),
+		CompanyID:      req.GetCompanyId(),
+		PostalCode:     req.GetPostalCode(),
+		Building:       req.GetBuilding(),
+		Apartment:      req.GetApartment(),
+		AdditionalData: req.GetAdditionalData(),
+		Type:           req.GetType(),
+		Attributes:     attribute.Values(req.GetAttributes()),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if req.HasCoordinates() {
+		setCoordinates(address, req.GetLatitude(), req.GetLongitude())
+	}
+
+	return address
+}
+
+func setCoordinates(address *entity.Address, latitude, longitude float64) {
+	address.Latitude, address.Longitude = &latitude, &longitude
+}
+
+// FormatAddress returns the postal label of the address.
+func FormatAddress(address *entity.Address) postal.Label {
+	return postal.Format(postal.Address{
+		StreetName: address.StreetName,
+		StreetNo:   address.StreenNo,
+		Building:   address.Building,
+		Apartment:  address.Apartment,
+		City:       address.City,
+		County:     address.County,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+	})
+}
+
+// geoAddress returns the fields of the address its coordinates depend on.
+func geoAddress(address *entity.Address) geo.Address {
+	return geo.Address{
+		StreetName: address.StreetName,
+		StreetNo:   address.StreenNo,
+		City:       address.City,
+		County:     address.County,
+		Country:    address.Country,
+		PostalCode: address.PostalCode,
+	}
+}
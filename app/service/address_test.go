@@ -6,8 +6,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/geo"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type mockCreateAddressReq struct {
@@ -17,11 +22,14 @@ type mockCreateAddressReq struct {
 	county     string
 	country    string
 	profileID  uint64
+	companyID  uint64
 	postalCode string
 	building   string
 	apartment  string
 	additional string
 	kind       string
+	location   *geo.Point
+	attributes *structpb.Struct
 }
 
 func (r mockCreateAddressReq) GetStreetName() string     { return r.streetName }
@@ -30,37 +38,85 @@ func (r mockCreateAddressReq) GetCity() string           { return r.city }
 func (r mockCreateAddressReq) GetCounty() string         { return r.county }
 func (r mockCreateAddressReq) GetCountry() string        { return r.country }
 func (r mockCreateAddressReq) GetProfileId() uint64      { return r.profileID }
+func (r mockCreateAddressReq) GetCompanyId() uint64      { return r.companyID }
 func (r mockCreateAddressReq) GetPostalCode() string     { return r.postalCode }
 func (r mockCreateAddressReq) GetBuilding() string       { return r.building }
 func (r mockCreateAddressReq) GetApartment() string      { return r.apartment }
 func (r mockCreateAddressReq) GetAdditionalData() string { return r.additional }
 func (r mockCreateAddressReq) GetType() string           { return r.kind }
+func (r mockCreateAddressReq) HasCoordinates() bool      { return r.location != nil }
+
+func (r mockCreateAddressReq) GetAttributes() *structpb.Struct { return r.attributes }
+
+func (r mockCreateAddressReq) GetLatitude() float64 {
+	if r.location == nil {
+		return 0
+	}
+	return r.location.Latitude
+}
+
+func (r mockCreateAddressReq) GetLongitude() float64 {
+	if r.location == nil {
+		return 0
+	}
+	return r.location.Longitude
+}
 
 type mockUpdateAddressReq struct {
-	id uint64
+	id              uint64
+	expectedVersion uint64
 	mockCreateAddressReq
 }
 
-func (r mockUpdateAddressReq) GetId() uint64 { return r.id }
+func (r mockUpdateAddressReq) GetId() uint64              { return r.id }
+func (r mockUpdateAddressReq) GetExpectedVersion() uint64 { return r.expectedVersion }
+
+type mockPatchAddressReq struct {
+	mockUpdateAddressReq
+	paths []string
+}
+
+func (r mockPatchAddressReq) GetUpdateMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{Paths: r.paths}
+}
 
 type mockListAddressesReq struct {
-	profileID uint64
-	page      uint32
-	pageSize  uint32
-	kind      string
+	profileID      uint64
+	companyID      uint64
+	page           uint32
+	pageSize       uint32
+	kind           string
+	includeDeleted bool
+	primaryOnly    bool
+	attributes     map[string]string
 }
 
-func (r mockListAddressesReq) GetProfileId() uint64 { return r.profileID }
-func (r mockListAddressesReq) GetPage() uint32      { return r.page }
-func (r mockListAddressesReq) GetPageSize() uint32  { return r.pageSize }
-func (r mockListAddressesReq) GetType() string      { return r.kind }
+func (r mockListAddressesReq) GetProfileId() uint64    { return r.profileID }
+func (r mockListAddressesReq) GetCompanyId() uint64    { return r.companyID }
+func (r mockListAddressesReq) GetPage() uint32         { return r.page }
+func (r mockListAddressesReq) GetPageSize() uint32     { return r.pageSize }
+func (r mockListAddressesReq) GetType() string         { return r.kind }
+func (r mockListAddressesReq) GetIncludeDeleted() bool { return r.includeDeleted }
+func (r mockListAddressesReq) GetPrimaryOnly() bool    { return r.primaryOnly }
+
+func (r mockListAddressesReq) GetAttributes() map[string]string { return r.attributes }
 
 type mockAddressRepo struct {
 	createFn   func(ctx context.Context, address *entity.Address) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Address, error)
-	updateFn   func(ctx context.Context, address *entity.Address) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error)
+
+	findPrimaryFn        func(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error)
+	updateFn             func(ctx context.Context, address *entity.Address) error
+	deleteFn             func(ctx context.Context, id, expectedVersion uint64) error
+	deleteByProfileIDFn  func(ctx context.Context, profileID uint64) error
+	restoreFn            func(ctx context.Context, id uint64) error
+	restoreByProfileIDFn func(ctx context.Context, profileID uint64, deletedSince time.Time) error
+	listFn               func(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error)
+	listByProfileIDFn    func(ctx context.Context, profileID uint64) ([]*entity.Address, error)
+	deleteByCompanyIDFn  func(ctx context.Context, companyID uint64) error
+	restoreByCompanyIDFn func(ctx context.Context, companyID uint64, deletedSince time.Time) error
+	listByCompanyIDFn    func(ctx context.Context, companyID uint64) ([]*entity.Address, error)
+	searchNearFn         func(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error)
 }
 
 func (m *mockAddressRepo) Create(ctx context.Context, address *entity.Address) error {
@@ -70,9 +126,16 @@ func (m *mockAddressRepo) Create(ctx context.Context, address *entity.Address) e
 	return nil
 }
 
-func (m *mockAddressRepo) FindByID(ctx context.Context, id uint64) (*entity.Address, error) {
+func (m *mockAddressRepo) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Address, error) {
 	if m.findByIDFn != nil {
-		return m.findByIDFn(ctx, id)
+		return m.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (m *mockAddressRepo) FindPrimary(ctx context.Context, profileID, companyID uint64, addressType string) (*entity.Address, error) {
+	if m.findPrimaryFn != nil {
+		return m.findPrimaryFn(ctx, profileID, companyID, addressType)
 	}
 	return nil, nil
 }
@@ -84,20 +147,83 @@ func (m *mockAddressRepo) Update(ctx context.Context, address *entity.Address) e
 	return nil
 }
 
-func (m *mockAddressRepo) Delete(ctx context.Context, id uint64) error {
+func (m *mockAddressRepo) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if m.deleteFn != nil {
-		return m.deleteFn(ctx, id)
+		return m.deleteFn(ctx, id, expectedVersion)
 	}
 	return nil
 }
 
-func (m *mockAddressRepo) List(ctx context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+func (m *mockAddressRepo) DeleteByProfileID(ctx context.Context, profileID uint64) error {
+	if m.deleteByProfileIDFn != nil {
+		return m.deleteByProfileIDFn(ctx, profileID)
+	}
+	return nil
+}
+
+func (m *mockAddressRepo) Restore(ctx context.Context, id uint64) error {
+	if m.restoreFn != nil {
+		return m.restoreFn(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockAddressRepo) RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error {
+	if m.restoreByProfileIDFn != nil {
+		return m.restoreByProfileIDFn(ctx, profileID, deletedSince)
+	}
+	return nil
+}
+
+func (m *mockAddressRepo) List(ctx context.Context, profileID, companyID uint64, addressType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 	if m.listFn != nil {
-		return m.listFn(ctx, profileID, addressType, limit, offset)
+		return m.listFn(ctx, profileID, companyID, addressType, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (m *mockAddressRepo) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Address, error) {
+	if m.listByProfileIDFn != nil {
+		return m.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (m *mockAddressRepo) DeleteByCompanyID(ctx context.Context, companyID uint64) error {
+	if m.deleteByCompanyIDFn != nil {
+		return m.deleteByCompanyIDFn(ctx, companyID)
+	}
+	return nil
+}
+
+func (m *mockAddressRepo) RestoreByCompanyID(ctx context.Context, companyID uint64, deletedSince time.Time) error {
+	if m.restoreByCompanyIDFn != nil {
+		return m.restoreByCompanyIDFn(ctx, companyID, deletedSince)
+	}
+	return nil
+}
+
+func (m *mockAddressRepo) ListByCompanyID(ctx context.Context, companyID uint64) ([]*entity.Address, error) {
+	if m.listByCompanyIDFn != nil {
+		return m.listByCompanyIDFn(ctx, companyID)
+	}
+	return nil, nil
+}
+
+func (m *mockAddressRepo) SearchNear(ctx context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+	if m.searchNearFn != nil {
+		return m.searchNearFn(ctx, search)
+	}
+	return nil, nil
+}
+
+// newAddressService wires the service to a unit of work that hands out the same repository.
+func newAddressService(repo addressRepository) *AddressService {
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = repo
+	return NewAddressService(repo, uow, nil, nil)
+}
+
 func TestAddressCreateSuccess(t *testing.T) {
 	repo := &mockAddressRepo{
 		createFn: func(_ context.Context, address *entity.Address) error {
@@ -105,7 +231,7 @@ func TestAddressCreateSuccess(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewAddressService(repo)
+	svc := newAddressService(repo)
 
 	address, err := svc.Create(context.Background(), mockCreateAddressReq{
 		streetName: "Street",
@@ -124,15 +250,15 @@ func TestAddressCreateSuccess(t *testing.T) {
 }
 
 func TestAddressGetByIDNotFound(t *testing.T) {
-	svc := NewAddressService(&mockAddressRepo{})
-	_, err := svc.GetByID(context.Background(), 3)
+	svc := newAddressService(&mockAddressRepo{})
+	_, err := svc.GetByID(context.Background(), 3, false)
 	if !errors.Is(err, ErrAddressNotFound) {
 		t.Fatalf("expected ErrAddressNotFound, got %v", err)
 	}
 }
 
 func TestAddressUpdateNotFound(t *testing.T) {
-	svc := NewAddressService(&mockAddressRepo{})
+	svc := newAddressService(&mockAddressRepo{})
 	_, err := svc.Update(context.Background(), mockUpdateAddressReq{
 		id: 1,
 		mockCreateAddressReq: mockCreateAddressReq{
@@ -151,13 +277,16 @@ func TestAddressUpdateNotFound(t *testing.T) {
 
 func TestAddressDeleteNotFoundMapped(t *testing.T) {
 	repo := &mockAddressRepo{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id}, nil
+		},
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrAddressNotFound
 		},
 	}
-	svc := NewAddressService(repo)
+	svc := newAddressService(repo)
 
-	err := svc.Delete(context.Background(), 3)
+	err := svc.Delete(context.Background(), 3, 0)
 	if !errors.Is(err, ErrAddressNotFound) {
 		t.Fatalf("expected ErrAddressNotFound, got %v", err)
 	}
@@ -166,14 +295,14 @@ func TestAddressDeleteNotFoundMapped(t *testing.T) {
 func TestAddressListDefaults(t *testing.T) {
 	now := time.Now()
 	repo := &mockAddressRepo{
-		listFn: func(_ context.Context, profileID uint64, addressType string, limit, offset uint32) ([]*entity.Address, uint64, error) {
+		listFn: func(_ context.Context, profileID, _ uint64, addressType string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Address, uint64, error) {
 			if profileID != 7 || addressType != "billing" || limit != 20 || offset != 0 {
 				t.Fatalf("unexpected list args profileID=%d addressType=%q limit=%d offset=%d", profileID, addressType, limit, offset)
 			}
 			return []*entity.Address{{ID: 1, StreetName: "Street", CreatedAt: now, UpdatedAt: now}}, 1, nil
 		},
 	}
-	svc := NewAddressService(repo)
+	svc := newAddressService(repo)
 
 	result, err := svc.List(context.Background(), mockListAddressesReq{profileID: 7, page: 0, pageSize: 0, kind: "billing"})
 	if err != nil {
@@ -183,3 +312,512 @@ func TestAddressListDefaults(t *testing.T) {
 		t.Fatalf("unexpected list result: %+v", result)
 	}
 }
+
+func TestAddressCreateMissingProfileMapped(t *testing.T) {
+	svc := newAddressService(&mockAddressRepo{
+		createFn: func(_ context.Context, _ *entity.Address) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Create(context.Background(), mockCreateAddressReq{profileID: 404})
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestAddressUpdateMissingTargetProfileMapped(t *testing.T) {
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Address) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Update(context.Background(), mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{profileID: 404}})
+	if !errors.Is(err, ErrTargetProfileNotFound) {
+		t.Fatalf("expected ErrTargetProfileNotFound, got %v", err)
+	}
+}
+
+func TestAddressPatchOnlyMaskedFields(t *testing.T) {
+	var saved *entity.Address
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, StreetName: "Street", City: "Old", Building: "B", ProfileID: 7}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			saved = address
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{city: "New"}},
+		paths:                []string{"city", "building"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.StreetName != "Street" || saved.ProfileID != 7 || saved.City != "New" || saved.Building != "" {
+		t.Fatalf("unexpected patched address: %+v", saved)
+	}
+}
+
+func TestAddressPatchChecksCountyAgainstStoredCountry(t *testing.T) {
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, County: "Cluj", Country: "RO"}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Address) error {
+			t.Fatal("update must not be called")
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{county: "Bayern"}},
+		paths:                []string{"county"},
+	})
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "county" {
+		t.Fatalf("expected county field error, got %v", err)
+	}
+}
+
+func TestAddressPatchNormalizesCountry(t *testing.T) {
+	var saved *entity.Address
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, County: "Bayern", Country: "Romania", PostalCode: "80331"}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			saved = address
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{country: "deutschland"}},
+		paths:                []string{"country"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.Country != "DE" || saved.County != "Bayern" || saved.PostalCode != "80331" {
+		t.Fatalf("unexpected patched address: %+v", saved)
+	}
+}
+
+func TestAddressPatchLeavesUncheckedStoredCountry(t *testing.T) {
+	var saved *entity.Address
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, County: "County", Country: "Country"}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			saved = address
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{city: "New"}},
+		paths:                []string{"city"},
+	})
+	if err != nil {
+		t.Fatalf("expected stored country to be left alone, got %v", err)
+	}
+	if saved.Country != "Country" {
+		t.Fatalf("unexpected patched address: %+v", saved)
+	}
+}
+
+func TestAddressSetPrimaryMovesFlagFromPreviousPrimary(t *testing.T) {
+	stored := map[uint64]*entity.Address{
+		3: {ID: 3, ProfileID: 7, Type: "billing", Version: 2},
+		5: {ID: 5, ProfileID: 7, Type: "billing", IsPrimary: true, Version: 4},
+	}
+	var updates []entity.Address
+	repo := &mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return stored[id], nil
+		},
+		findPrimaryFn: func(_ context.Context, profileID, _ uint64, addressType string) (*entity.Address, error) {
+			if profileID != 7 || addressType != "billing" {
+				t.Fatalf("unexpected primary lookup profileID=%d addressType=%q", profileID, addressType)
+			}
+			return stored[5], nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			updates = append(updates, *address)
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = repo
+	audit := uow.repos.Audit.(*mockAuditRepo)
+	svc := NewAddressService(repo, uow, nil, nil)
+
+	address, err := svc.SetPrimary(context.Background(), 3, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !address.IsPrimary || uow.calls != 1 {
+		t.Fatalf("expected the address to become primary in one unit of work, got %+v after %d calls", address, uow.calls)
+	}
+	if len(updates) != 2 || updates[0].ID != 5 || updates[0].IsPrimary || updates[1].ID != 3 || !updates[1].IsPrimary {
+		t.Fatalf("expected the previous primary to be cleared first, got %+v", updates)
+	}
+	if len(audit.events) != 2 || audit.events[0].EntityID != 5 || audit.events[1].EntityID != 3 {
+		t.Fatalf("expected both changes to be audited, got %+v", audit.events)
+	}
+}
+
+func TestAddressSetPrimaryChecks(t *testing.T) {
+	updated := false
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			if id == 404 {
+				return nil, nil
+			}
+			return &entity.Address{ID: id, ProfileID: 7, Type: "billing", IsPrimary: id == 5, Version: 2}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Address) error {
+			updated = true
+			return nil
+		},
+	})
+
+	if _, err := svc.SetPrimary(context.Background(), 404, 0); !errors.Is(err, ErrAddressNotFound) {
+		t.Fatalf("expected ErrAddressNotFound, got %v", err)
+	}
+	if _, err := svc.SetPrimary(context.Background(), 3, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if address, err := svc.SetPrimary(context.Background(), 5, 0); err != nil || !address.IsPrimary {
+		t.Fatalf("expected the primary address to be returned as is, got %+v, %v", address, err)
+	}
+	if updated {
+		t.Fatal("expected no update")
+	}
+}
+
+func TestAddressPatchTypeDropsPrimaryFlag(t *testing.T) {
+	var saved *entity.Address
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 7, Type: "billing", IsPrimary: true}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			saved = address
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{kind: "shipping"}},
+		paths:                []string{"type"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.Type != "shipping" || saved.IsPrimary {
+		t.Fatalf("expected the moved address not to stay primary, got %+v", saved)
+	}
+}
+
+func TestAddressListPassesPrimaryOnly(t *testing.T) {
+	svc := newAddressService(&mockAddressRepo{
+		listFn: func(_ context.Context, _, _ uint64, _ string, _ map[string]string, _, primaryOnly bool, _, _ uint32) ([]*entity.Address, uint64, error) {
+			if !primaryOnly {
+				t.Fatal("expected primary_only to reach the repository")
+			}
+			return nil, 0, nil
+		},
+	})
+
+	if _, err := svc.List(context.Background(), mockListAddressesReq{profileID: 7, primaryOnly: true}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+type failingGeocoder struct{ err error }
+
+func (g failingGeocoder) Geocode(context.Context, geo.Address) (geo.Point, error) {
+	return geo.Point{}, g.err
+}
+
+func TestAddressCreateGeocodesMissingCoordinates(t *testing.T) {
+	cluj := geo.Point{Latitude: 46.7712, Longitude: 23.6236}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = &mockAddressRepo{}
+	svc := NewAddressService(uow.repos.Addresses, uow, geo.NewStatic().Add(geo.Address{Country: "RO", City: "Cluj-Napoca"}, cluj), nil)
+
+	address, err := svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, streetName: "Horea", city: "Cluj-Napoca", country: "RO"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if address.Latitude == nil || *address.Latitude != cluj.Latitude || *address.Longitude != cluj.Longitude {
+		t.Fatalf("expected geocoded coordinates, got %v, %v", address.Latitude, address.Longitude)
+	}
+
+	// Given coordinates win over the geocoder, and an address it does not know gets none.
+	given := geo.Point{Latitude: 46.77, Longitude: 23.59}
+	if address, err = svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, city: "Cluj-Napoca", country: "RO", location: &given}); err != nil || *address.Latitude != given.Latitude {
+		t.Fatalf("expected the given coordinates, got %+v, %v", address, err)
+	}
+	if address, err = svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, city: "Iasi", country: "RO"}); err != nil || address.Latitude != nil {
+		t.Fatalf("expected no coordinates, got %+v, %v", address, err)
+	}
+}
+
+func TestAddressCreateFailsOnGeocoderError(t *testing.T) {
+	outage := errors.New("geocoder unavailable")
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = &mockAddressRepo{}
+	svc := NewAddressService(uow.repos.Addresses, uow, failingGeocoder{err: outage}, nil)
+
+	if _, err := svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, city: "Cluj-Napoca"}); !errors.Is(err, outage) {
+		t.Fatalf("expected the geocoder error, got %v", err)
+	}
+	if uow.calls != 0 {
+		t.Fatalf("expected nothing to be written, got %d units of work", uow.calls)
+	}
+}
+
+func TestAddressUpdateKeepsCoordinatesOfUnchangedAddress(t *testing.T) {
+	latitude, longitude := 46.7712, 23.6236
+	stored := func() *entity.Address {
+		return &entity.Address{ID: 3, ProfileID: 7, StreetName: "Horea", City: "Cluj-Napoca", Country: "RO", Latitude: &latitude, Longitude: &longitude}
+	}
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(context.Context, uint64, bool) (*entity.Address, error) { return stored(), nil },
+	})
+
+	req := mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{profileID: 7, streetName: "Horea", city: "Cluj-Napoca", country: "RO", building: "B"}}
+	address, err := svc.Update(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if address.Latitude == nil || *address.Latitude != latitude {
+		t.Fatalf("expected the coordinates to be kept, got %v", address.Latitude)
+	}
+
+	// Without a geocoder, coordinates of a moved address are cleared rather than left stale.
+	req.city = "Turda"
+	if address, err = svc.Update(context.Background(), req); err != nil || address.Latitude != nil || address.Longitude != nil {
+		t.Fatalf("expected the coordinates to be cleared, got %+v, %v", address, err)
+	}
+}
+
+func TestAddressPatchCoordinates(t *testing.T) {
+	latitude, longitude := 46.7712, 23.6236
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(context.Context, uint64, bool) (*entity.Address, error) {
+			return &entity.Address{ID: 3, City: "Cluj-Napoca", Country: "RO", Latitude: &latitude, Longitude: &longitude}, nil
+		},
+	})
+
+	moved := geo.Point{Latitude: 46.5, Longitude: 23.8}
+	req := mockPatchAddressReq{paths: []string{"latitude", "longitude"}}
+	req.id, req.location = 3, &moved
+	address, err := svc.Patch(context.Background(), req)
+	if err != nil || *address.Latitude != moved.Latitude || *address.Longitude != moved.Longitude {
+		t.Fatalf("expected the patched coordinates, got %+v, %v", address, err)
+	}
+
+	req.location = nil
+	if address, err = svc.Patch(context.Background(), req); err != nil || address.Latitude != nil || address.Longitude != nil {
+		t.Fatalf("expected the coordinates to be cleared, got %+v, %v", address, err)
+	}
+
+	req = mockPatchAddressReq{paths: []string{"building"}}
+	req.id, req.building = 3, "C"
+	if address, err = svc.Patch(context.Background(), req); err != nil || address.Latitude == nil {
+		t.Fatalf("expected the coordinates to be kept, got %+v, %v", address, err)
+	}
+}
+
+func TestAddressSearchNearPassesSearch(t *testing.T) {
+	center := &geo.Point{Latitude: 46.7712, Longitude: 23.6236}
+	svc := newAddressService(&mockAddressRepo{
+		searchNearFn: func(_ context.Context, search repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+			if search.Center != center || search.RadiusMeters != 2500 || search.Box != nil || search.ProfileID != 7 || search.Type != "billing" || search.Limit != 5 {
+				t.Fatalf("unexpected search: %+v", search)
+			}
+			return []*entity.NearbyAddress{{Address: &entity.Address{ID: 1}, DistanceMeters: 120}}, nil
+		},
+	})
+
+	found, err := svc.SearchNear(context.Background(), mockSearchAddressesNearReq{center: center, radiusMeters: 2500, profileID: 7, kind: "billing", limit: 5})
+	if err != nil || len(found) != 1 || found[0].DistanceMeters != 120 {
+		t.Fatalf("unexpected result: %+v, %v", found, err)
+	}
+}
+
+type mockSearchAddressesNearReq struct {
+	center       *geo.Point
+	radiusMeters float64
+	box          *geo.Box
+	profileID    uint64
+	kind         string
+	limit        uint32
+}
+
+func (r mockSearchAddressesNearReq) CenterPoint() *geo.Point  { return r.center }
+func (r mockSearchAddressesNearReq) GetRadiusMeters() float64 { return r.radiusMeters }
+func (r mockSearchAddressesNearReq) Box() *geo.Box            { return r.box }
+func (r mockSearchAddressesNearReq) GetProfileId() uint64     { return r.profileID }
+func (r mockSearchAddressesNearReq) GetType() string          { return r.kind }
+func (r mockSearchAddressesNearReq) GetLimit() uint32         { return r.limit }
+
+func TestAddressPatchCompanyMovesAddressOffProfile(t *testing.T) {
+	var saved *entity.Address
+	svc := newAddressService(&mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 7, Type: "billing"}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			saved = address
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{companyID: 11}},
+		paths:                []string{"company_id"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.CompanyID != 11 || saved.ProfileID != 0 {
+		t.Fatalf("expected the address to move to the company, got %+v", saved)
+	}
+
+	_, err = svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{companyID: 11, kind: "shipping"}},
+		paths:                []string{"company_id", "type"},
+	})
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "type" {
+		t.Fatalf("expected a type field error, got %v", err)
+	}
+}
+
+func TestAddressRestoreOfDeletedCompanyMapped(t *testing.T) {
+	svc := newAddressService(&mockAddressRepo{
+		restoreFn: func(context.Context, uint64) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, CompanyID: 11, Type: "billing"}, nil
+		},
+	})
+
+	_, err := svc.Restore(context.Background(), 3)
+	if !errors.Is(err, ErrCompanyDeleted) {
+		t.Fatalf("expected ErrCompanyDeleted, got %v", err)
+	}
+}
+
+func newAttributeRegistry(t *testing.T, raw string) *attribute.Registry {
+	t.Helper()
+	registry, err := attribute.Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("invalid test registry: %v", err)
+	}
+	return registry
+}
+
+func TestAddressCreateValidatesAttributes(t *testing.T) {
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = &mockAddressRepo{}
+	registry := newAttributeRegistry(t, `{"address": {"*": [{"name": "floor_code", "type": "string", "pattern": "^[0-9A-Z]{1,4}$"}]}}`)
+	svc := NewAddressService(uow.repos.Addresses, uow, nil, registry)
+
+	floor, _ := structpb.NewStruct(map[string]any{"floor_code": "2B"})
+	address, err := svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, city: "Cluj-Napoca", country: "RO", attributes: floor})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if address.Attributes["floor_code"] != "2B" {
+		t.Fatalf("expected floor_code to be stored, got %v", address.Attributes)
+	}
+
+	var fieldErr *country.FieldError
+	undefined, _ := structpb.NewStruct(map[string]any{"dock": "B"})
+	if _, err = svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, city: "Cluj-Napoca", country: "RO", attributes: undefined}); !errors.As(err, &fieldErr) || fieldErr.Field != "attributes.dock" {
+		t.Fatalf("expected attributes.dock field error, got %v", err)
+	}
+	badFloor, _ := structpb.NewStruct(map[string]any{"floor_code": "second"})
+	if _, err = svc.Create(context.Background(), mockCreateAddressReq{profileID: 7, city: "Cluj-Napoca", country: "RO", attributes: badFloor}); !errors.As(err, &fieldErr) || fieldErr.Field != "attributes.floor_code" {
+		t.Fatalf("expected attributes.floor_code field error, got %v", err)
+	}
+}
+
+func TestAddressPatchSingleAttribute(t *testing.T) {
+	var saved *entity.Address
+	registry := newAttributeRegistry(t, `{"address": {"*": [{"name": "floor_code", "type": "string"}, {"name": "dock", "type": "string"}]}}`)
+	repo := &mockAddressRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Address, error) {
+			return &entity.Address{ID: id, ProfileID: 7, Attributes: map[string]any{"floor_code": "2B", "dock": "A"}}, nil
+		},
+		updateFn: func(_ context.Context, address *entity.Address) error {
+			saved = address
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = repo
+	svc := NewAddressService(repo, uow, nil, registry)
+
+	dock, _ := structpb.NewStruct(map[string]any{"dock": "B"})
+	_, err := svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3, mockCreateAddressReq: mockCreateAddressReq{attributes: dock}},
+		paths:                []string{"attributes.dock"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.Attributes["dock"] != "B" || saved.Attributes["floor_code"] != "2B" {
+		t.Fatalf("expected only dock to change, got %v", saved.Attributes)
+	}
+
+	// A named attribute the request does not carry is removed.
+	if _, err = svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3},
+		paths:                []string{"attributes.floor_code"},
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := saved.Attributes["floor_code"]; ok || saved.Attributes["dock"] != "A" {
+		t.Fatalf("expected floor_code to be removed, got %v", saved.Attributes)
+	}
+
+	if _, err = svc.Patch(context.Background(), mockPatchAddressReq{
+		mockUpdateAddressReq: mockUpdateAddressReq{id: 3},
+		paths:                []string{"attributes.Floor Code"},
+	}); !errors.Is(err, ErrInvalidUpdateMask) {
+		t.Fatalf("expected ErrInvalidUpdateMask, got %v", err)
+	}
+}
+
+func TestAddressListPassesAttributeFilters(t *testing.T) {
+	var got map[string]string
+	svc := newAddressService(&mockAddressRepo{
+		listFn: func(_ context.Context, _, _ uint64, _ string, attributes map[string]string, _, _ bool, _, _ uint32) ([]*entity.Address, uint64, error) {
+			got = attributes
+			return nil, 0, nil
+		},
+	})
+
+	if _, err := svc.List(context.Background(), mockListAddressesReq{profileID: 7, attributes: map[string]string{"dock": "B"}}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got["dock"] != "B" {
+		t.Fatalf("expected the attribute filter to reach the repository, got %v", got)
+	}
+}
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vibast-solutions/ms-go-profile/app/blobstore"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrAttachmentTooLarge is returned when an upload goes past the configured maximum size.
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	// ErrEmptyAttachment is returned when an upload has no content.
+	ErrEmptyAttachment = errors.New("attachment is empty")
+	// ErrUnsupportedContentType is returned when the content of an upload is not of an allowed type.
+	ErrUnsupportedContentType = errors.New("attachment content type is not allowed")
+)
+
+const (
+	attachmentKeyPrefix     = "attachments/"
+	defaultAttachmentSize   = 10 << 20
+	contentTypeSniffLength  = 512
+	unknownAttachmentFormat = "application/octet-stream"
+)
+
+type attachmentRepository interface {
+	Create(ctx context.Context, attachment *entity.Attachment) error
+	FindByID(ctx context.Context, id uint64) (*entity.Attachment, error)
+	Delete(ctx context.Context, id uint64) error
+	List(ctx context.Context, entityType string, entityID uint64, kind string, limit, offset uint32) ([]*entity.Attachment, uint64, error)
+}
+
+type attachmentPurgeRepository interface {
+	ListPurgeable(ctx context.Context, deletedBefore time.Time, limit uint32) ([]*entity.Attachment, error)
+	Delete(ctx context.Context, id uint64) error
+}
+
+type uploadAttachmentRequest interface {
+	GetEntityType() string
+	GetEntityId() uint64
+	GetKind() string
+	GetFilename() string
+	GetContentType() string
+}
+
+type listAttachmentsRequest interface {
+	GetEntityType() string
+	GetEntityId() uint64
+	GetKind() string
+	GetPage() uint32
+	GetPageSize() uint32
+}
+
+// AttachmentLimits bounds what an upload may hold.
+type AttachmentLimits struct {
+	// MaxSize is the largest accepted content in bytes; zero selects 10 MiB.
+	MaxSize int64
+	// ContentTypes lists the accepted media types, such as image/png, or whole families such as image/*.
+	ContentTypes []string
+}
+
+type AttachmentList struct {
+	Attachments []*entity.Attachment
+	Page        uint32
+	PageSize    uint32
+	Total       uint64
+}
+
+// AttachmentService keeps files attached to profiles, contacts, addresses and companies: their
+// metadata through the unit of work and their content in the blob store. Attachments follow
+// their owner: they are hidden while it is soft-deleted and purged along with it.
+type AttachmentService struct {
+	uow    UnitOfWork
+	blobs  blobstore.Store
+	limits AttachmentLimits
+}
+
+func NewAttachmentService(uow UnitOfWork, blobs blobstore.Store, limits AttachmentLimits) *AttachmentService {
+	if limits.MaxSize <= 0 {
+		limits.MaxSize = defaultAttachmentSize
+	}
+
+	return &AttachmentService{uow: uow, blobs: blobs, limits: limits}
+}
+
+// Upload reads content to its end and stores it as a new attachment of the requested record.
+// The content type is sniffed from the content itself, falling back to the declared one only
+// when the content is not recognised.
+func (s *AttachmentService) Upload(ctx context.Context, req uploadAttachmentRequest, content io.Reader) (*entity.Attachment, error) {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		return checkAttachmentOwner(ctx, repos, req.GetEntityType(), req.GetEntityId())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spooled, err := spoolAttachment(content, s.limits.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.close()
+
+	contentType, err := s.contentType(spooled.head, req.GetContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &entity.Attachment{
+		EntityType:  req.GetEntityType(),
+		EntityID:    req.GetEntityId(),
+		Kind:        req.GetKind(),
+		Filename:    req.GetFilename(),
+		ContentType: contentType,
+		Size:        spooled.size,
+		SHA256:      spooled.sha256,
+		StorageKey:  attachmentKeyPrefix + uuid.NewString(),
+		CreatedAt:   time.Now(),
+	}
+	if err = s.blobs.Put(ctx, attachment.StorageKey, spooled.file, attachment.Size, attachment.ContentType); err != nil {
+		return nil, fmt.Errorf("store attachment content: %w", err)
+	}
+
+	err = s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := checkAttachmentOwner(ctx, repos, attachment.EntityType, attachment.EntityID); err != nil {
+			return err
+		}
+		if err := repos.Attachments.Create(ctx, attachment); err != nil {
+			return err
+		}
+
+		return recordChange(ctx, repos, AuditEntityAttachment, attachment.ID, AuditActionCreate, nil, attachmentAuditValues(attachment))
+	})
+	if err != nil {
+		// Without its row nothing can reach the blob any more.
+		_ = s.blobs.Delete(context.WithoutCancel(ctx), attachment.StorageKey)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// Get returns the metadata of an attachment whose owner is live.
+func (s *AttachmentService) Get(ctx context.Context, id uint64) (*entity.Attachment, error) {
+	var attachment *entity.Attachment
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		var err error
+		attachment, err = findAttachment(ctx, repos, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// Open returns the metadata of an attachment along with its content, which the caller closes.
+func (s *AttachmentService) Open(ctx context.Context, id uint64) (*entity.Attachment, io.ReadCloser, error) {
+	attachment, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open attachment content: %w", err)
+	}
+
+	return attachment, content, nil
+}
+
+// List returns the attachments of a live record, newest first.
+func (s *AttachmentService) List(ctx context.Context, req listAttachmentsRequest) (*AttachmentList, error) {
+	page := req.GetPage()
+	if page == 0 {
+		page = 1
+	}
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	result := &AttachmentList{Page: page, PageSize: pageSize}
+
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := checkAttachmentOwner(ctx, repos, req.GetEntityType(), req.GetEntityId()); err != nil {
+			return err
+		}
+
+		var err error
+		result.Attachments, result.Total, err = repos.Attachments.List(ctx, req.GetEntityType(), req.GetEntityId(), req.GetKind(), pageSize, (page-1)*pageSize)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete removes the attachment and then its content. A blob the store fails to remove is
+// left behind rather than failing a delete that is already committed.
+func (s *AttachmentService) Delete(ctx context.Context, id uint64) error {
+	var attachment *entity.Attachment
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		var err error
+		if attachment, err = findAttachment(ctx, repos, id); err != nil {
+			return err
+		}
+
+		if err = repos.Attachments.Delete(ctx, id); err != nil {
+			if errors.Is(err, repository.ErrAttachmentNotFound) {
+				return ErrAttachmentNotFound
+			}
+			return err
+		}
+
+		return recordChange(ctx, repos, AuditEntityAttachment, id, AuditActionDelete, attachmentAuditValues(attachment), nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	_ = s.blobs.Delete(context.WithoutCancel(ctx), attachment.StorageKey)
+	return nil
+}
+
+// contentType picks the media type of an upload from its first bytes, or from declared when
+// they are not recognised, and checks it against the allowed ones.
+func (s *AttachmentService) contentType(head []byte, declared string) (string, error) {
+	contentType := mediaType(http.DetectContentType(head))
+	if contentType == unknownAttachmentFormat && declared != "" {
+		contentType = mediaType(declared)
+	}
+
+	for _, allowed := range s.limits.ContentTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == contentType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*"))) {
+			return contentType, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+}
+
+// mediaType drops the parameters of a Content-Type value, such as its charset.
+func mediaType(value string) string {
+	parsed, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return unknownAttachmentFormat
+	}
+	return parsed
+}
+
+// AttachmentPurger removes, blob first, the attachments of records about to be purged. It runs
+// before the records themselves so that no attachment is left without an owner.
+type AttachmentPurger struct {
+	repo  attachmentPurgeRepository
+	blobs blobstore.Store
+}
+
+func NewAttachmentPurger(repo attachmentPurgeRepository, blobs blobstore.Store) *AttachmentPurger {
+	return &AttachmentPurger{repo: repo, blobs: blobs}
+}
+
+func (p *AttachmentPurger) Purge(ctx context.Context, deletedBefore time.Time, limit uint32) (int64, error) {
+	attachments, err := p.repo.ListPurgeable(ctx, deletedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, attachment := range attachments {
+		if err = p.blobs.Delete(ctx, attachment.StorageKey); err != nil {
+			return purged, fmt.Errorf("delete content of attachment %d: %w", attachment.ID, err)
+		}
+		if err = p.repo.Delete(ctx, attachment.ID); err != nil && !errors.Is(err, repository.ErrAttachmentNotFound) {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// findAttachment returns the attachment when its owner is live; one whose owner is
+// soft-deleted is reported missing.
+func findAttachment(ctx context.Context, repos Repositories, id uint64) (*entity.Attachment, error) {
+	attachment, err := repos.Attachments.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil {
+		return nil, ErrAttachmentNotFound
+	}
+
+	if err = checkAttachmentOwner(ctx, repos, attachment.EntityType, attachment.EntityID); err != nil {
+		if isOwnerNotFound(err) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// checkAttachmentOwner fails with the not-found error of entityType unless the record is live.
+func checkAttachmentOwner(ctx context.Context, repos Repositories, entityType string, entityID uint64) error {
+	var found bool
+	var notFound error
+	switch entityType {
+	case AuditEntityProfile:
+		return checkLiveProfile(ctx, repos, entityID)
+	case AuditEntityContact:
+		contact, err := repos.Contacts.FindByID(ctx, entityID, false)
+		if err != nil {
+			return err
+		}
+		found, notFound = contact != nil, ErrContactNotFound
+	case AuditEntityAddress:
+		address, err := repos.Addresses.FindByID(ctx, entityID, false)
+		if err != nil {
+			return err
+		}
+		found, notFound = address != nil, ErrAddressNotFound
+	case AuditEntityCompany:
+		company, err := repos.Companies.FindByID(ctx, entityID, false)
+		if err != nil {
+			return err
+		}
+		found, notFound = company != nil, ErrCompanyNotFound
+	default:
+		return fmt.Errorf("unsupported attachment owner %q", entityType)
+	}
+
+	if !found {
+		return notFound
+	}
+	return nil
+}
+
+func isOwnerNotFound(err error) bool {
+	return errors.Is(err, ErrProfileNotFound) || errors.Is(err, ErrContactNotFound) ||
+		errors.Is(err, ErrAddressNotFound) || errors.Is(err, ErrCompanyNotFound)
+}
+
+// spooledAttachment is an upload copied to a temporary file, so that its size and digest are
+// known before it reaches the blob store.
+type spooledAttachment struct {
+	file   *os.File
+	size   int64
+	sha256 string
+	// head holds the first bytes, from which the content type is sniffed.
+	head []byte
+}
+
+func spoolAttachment(content io.Reader, maxSize int64) (*spooledAttachment, error) {
+	file, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledAttachment{file: file}
+
+	digest := sha256.New()
+	head := &headWriter{limit: contentTypeSniffLength}
+	size, err := io.Copy(io.MultiWriter(file, digest, head), io.LimitReader(content, maxSize+1))
+	if err == nil && size > maxSize {
+		err = ErrAttachmentTooLarge
+	}
+	if err == nil && size == 0 {
+		err = ErrEmptyAttachment
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spooled.close()
+		return nil, err
+	}
+
+	spooled.size = size
+	spooled.sha256 = hex.EncodeToString(digest.Sum(nil))
+	spooled.head = head.buf
+	return spooled, nil
+}
+
+func (s *spooledAttachment) close() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// headWriter keeps the first limit bytes written to it.
+type headWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.buf); room > 0 {
+		w.buf = append(w.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
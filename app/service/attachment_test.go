@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/blobstore"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+)
+
+const testPNG = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+// mockAttachmentRepo keeps attachments in memory, keyed by id.
+type mockAttachmentRepo struct {
+	attachments map[uint64]*entity.Attachment
+	nextID      uint64
+	// purgeable is what ListPurgeable returns.
+	purgeable []*entity.Attachment
+}
+
+func newMockAttachmentRepo() *mockAttachmentRepo {
+	return &mockAttachmentRepo{attachments: map[uint64]*entity.Attachment{}}
+}
+
+func (m *mockAttachmentRepo) Create(_ context.Context, attachment *entity.Attachment) error {
+	m.nextID++
+	attachment.ID = m.nextID
+	stored := *attachment
+	m.attachments[attachment.ID] = &stored
+	return nil
+}
+
+func (m *mockAttachmentRepo) FindByID(_ context.Context, id uint64) (*entity.Attachment, error) {
+	return m.attachments[id], nil
+}
+
+func (m *mockAttachmentRepo) Delete(_ context.Context, id uint64) error {
+	if _, ok := m.attachments[id]; !ok {
+		return repository.ErrAttachmentNotFound
+	}
+	delete(m.attachments, id)
+	return nil
+}
+
+func (m *mockAttachmentRepo) List(_ context.Context, entityType string, entityID uint64, kind string, _, _ uint32) ([]*entity.Attachment, uint64, error) {
+	attachments := make([]*entity.Attachment, 0)
+	for _, attachment := range m.attachments {
+		if attachment.EntityType == entityType && attachment.EntityID == entityID && (kind == "" || attachment.Kind == kind) {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].ID > attachments[j].ID })
+	return attachments, uint64(len(attachments)), nil
+}
+
+func (m *mockAttachmentRepo) ListPurgeable(_ context.Context, _ time.Time, _ uint32) ([]*entity.Attachment, error) {
+	return m.purgeable, nil
+}
+
+type mockUploadAttachmentReq struct {
+	entityType  string
+	entityID    uint64
+	kind        string
+	filename    string
+	contentType string
+}
+
+func (r mockUploadAttachmentReq) GetEntityType() string  { return r.entityType }
+func (r mockUploadAttachmentReq) GetEntityId() uint64    { return r.entityID }
+func (r mockUploadAttachmentReq) GetKind() string        { return r.kind }
+func (r mockUploadAttachmentReq) GetFilename() string    { return r.filename }
+func (r mockUploadAttachmentReq) GetContentType() string { return r.contentType }
+
+type mockListAttachmentsReq struct {
+	entityType string
+	entityID   uint64
+	kind       string
+}
+
+func (r mockListAttachmentsReq) GetEntityType() string { return r.entityType }
+func (r mockListAttachmentsReq) GetEntityId() uint64   { return r.entityID }
+func (r mockListAttachmentsReq) GetKind() string       { return r.kind }
+func (r mockListAttachmentsReq) GetPage() uint32       { return 0 }
+func (r mockListAttachmentsReq) GetPageSize() uint32   { return 0 }
+
+// newAttachmentService serves the live contact 4 and stores blobs under dir. Uploads are
+// limited to 64 bytes of PNG or PDF.
+func newAttachmentService(t *testing.T, dir string) (*AttachmentService, *mockUnitOfWork, *bool) {
+	t.Helper()
+	contactDeleted := false
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Contacts = &mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			if id != 4 || contactDeleted {
+				return nil, nil
+			}
+			return &entity.Contact{ID: id}, nil
+		},
+	}
+
+	limits := AttachmentLimits{MaxSize: 64, ContentTypes: []string{"image/png", "application/pdf"}}
+	return NewAttachmentService(uow, blobstore.NewLocal(dir), limits), uow, &contactDeleted
+}
+
+// storedBlobs lists the files under dir, relative to it.
+func storedBlobs(t *testing.T, dir string) []string {
+	t.Helper()
+	var blobs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		blobs = append(blobs, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return blobs
+}
+
+func TestAttachmentUploadSniffsAndStoresContent(t *testing.T) {
+	dir := t.TempDir()
+	svc, uow, _ := newAttachmentService(t, dir)
+	ctx := context.Background()
+
+	req := mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4, kind: "id_card", filename: "front.jpg", contentType: "image/jpeg"}
+	attachment, err := svc.Upload(ctx, req, strings.NewReader(testPNG))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(testPNG))
+	if attachment.ID == 0 || attachment.ContentType != "image/png" || attachment.Size != int64(len(testPNG)) || attachment.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected attachment: %+v", attachment)
+	}
+	if blobs := storedBlobs(t, dir); len(blobs) != 1 || blobs[0] != attachment.StorageKey {
+		t.Fatalf("expected the blob under %s, got %v", attachment.StorageKey, blobs)
+	}
+	events := uow.repos.Audit.(*mockAuditRepo).events
+	if len(events) != 1 || events[0].EntityType != AuditEntityAttachment || events[0].Action != AuditActionCreate {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+
+	_, content, err := svc.Open(ctx, attachment.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	body, _ := io.ReadAll(content)
+	content.Close()
+	if string(body) != testPNG {
+		t.Fatalf("unexpected content %q", body)
+	}
+
+	// Unrecognised content keeps the declared type.
+	pdf, err := svc.Upload(ctx, mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4, contentType: "application/pdf; charset=binary"}, strings.NewReader("\x00\x01binary"))
+	if err != nil || pdf.ContentType != "application/pdf" {
+		t.Fatalf("expected the declared type, got %+v, %v", pdf, err)
+	}
+}
+
+func TestAttachmentUploadRejections(t *testing.T) {
+	dir := t.TempDir()
+	svc, _, _ := newAttachmentService(t, dir)
+
+	cases := []struct {
+		name    string
+		req     mockUploadAttachmentReq
+		content string
+		want    error
+	}{
+		{"missing owner", mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 5}, testPNG, ErrContactNotFound},
+		{"too large", mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4}, testPNG + strings.Repeat("x", 64), ErrAttachmentTooLarge},
+		{"empty", mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4}, "", ErrEmptyAttachment},
+		{"sniffed html", mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4, contentType: "image/png"}, "<html><script></script></html>", ErrUnsupportedContentType},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Upload(context.Background(), tc.req, strings.NewReader(tc.content)); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+	if blobs := storedBlobs(t, dir); len(blobs) != 0 {
+		t.Fatalf("expected no blobs, got %v", blobs)
+	}
+}
+
+func TestAttachmentsOfDeletedOwnerAreHidden(t *testing.T) {
+	svc, _, contactDeleted := newAttachmentService(t, t.TempDir())
+	ctx := context.Background()
+
+	attachment, err := svc.Upload(ctx, mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4, kind: "id_card"}, strings.NewReader(testPNG))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	list, err := svc.List(ctx, mockListAttachmentsReq{entityType: AuditEntityContact, entityID: 4, kind: "id_card"})
+	if err != nil || list.Total != 1 || list.Page != 1 || list.PageSize != 20 {
+		t.Fatalf("unexpected list %+v, %v", list, err)
+	}
+
+	*contactDeleted = true
+	if _, err = svc.Get(ctx, attachment.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected ErrAttachmentNotFound, got %v", err)
+	}
+	if _, err = svc.List(ctx, mockListAttachmentsReq{entityType: AuditEntityContact, entityID: 4}); !errors.Is(err, ErrContactNotFound) {
+		t.Fatalf("expected ErrContactNotFound, got %v", err)
+	}
+	if err = svc.Delete(ctx, attachment.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected ErrAttachmentNotFound, got %v", err)
+	}
+}
+
+func TestAttachmentDeleteRemovesBlob(t *testing.T) {
+	dir := t.TempDir()
+	svc, uow, _ := newAttachmentService(t, dir)
+	ctx := context.Background()
+
+	attachment, err := svc.Upload(ctx, mockUploadAttachmentReq{entityType: AuditEntityContact, entityID: 4}, strings.NewReader(testPNG))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = svc.Delete(ctx, attachment.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if blobs := storedBlobs(t, dir); len(blobs) != 0 {
+		t.Fatalf("expected no blobs, got %v", blobs)
+	}
+	if _, err = svc.Get(ctx, attachment.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected ErrAttachmentNotFound, got %v", err)
+	}
+	events := uow.repos.Audit.(*mockAuditRepo).events
+	if last := events[len(events)-1]; last.Action != AuditActionDelete || last.EntityID != attachment.ID {
+		t.Fatalf("unexpected audit event: %+v", last)
+	}
+}
+
+func TestAttachmentPurgerRemovesBlobsAndRows(t *testing.T) {
+	dir := t.TempDir()
+	blobs := blobstore.NewLocal(dir)
+	if err := blobs.Put(context.Background(), "attachments/a1", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	repo := newMockAttachmentRepo()
+	stored := &entity.Attachment{ID: 1, StorageKey: "attachments/a1"}
+	repo.attachments[1] = stored
+	repo.purgeable = []*entity.Attachment{stored, {ID: 2, StorageKey: "attachments/a2"}}
+
+	purged, err := NewAttachmentPurger(repo, blobs).Purge(context.Background(), time.Now(), 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if purged != 2 || len(repo.attachments) != 0 {
+		t.Fatalf("expected both attachments purged, got %d and %v", purged, repo.attachments)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "attachments", "a1")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the blob to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// attributesPath is the update mask path that replaces every attribute of a record;
+// attributesPath.<name> changes only that attribute.
+const attributesPath = "attributes"
+
+// patchAttributes applies the update mask path to the current attributes, taking values
+// from the request's attributes. A named attribute that is absent or null there is removed.
+// It reports false when path names no attribute. current is never modified.
+func patchAttributes(current map[string]any, path string, values *structpb.Struct) (map[string]any, bool) {
+	if path == attributesPath {
+		return attribute.Values(values), true
+	}
+	name, ok := strings.CutPrefix(path, attributesPath+".")
+	if !ok || !attribute.ValidName(name) {
+		return nil, false
+	}
+
+	patched := make(map[string]any, len(current)+1)
+	for key, value := range current {
+		patched[key] = value
+	}
+	value, ok := values.GetFields()[name]
+	if _, null := value.GetKind().(*structpb.Value_NullValue); !ok || null {
+		delete(patched, name)
+	} else {
+		patched[name] = value.AsInterface()
+	}
+	if len(patched) == 0 {
+		return nil, true
+	}
+
+	return patched, true
+}
+
+// childFieldError names the failed field after the child record created with a profile, the
+// way the request's own validation does.
+func childFieldError(child string, err error) error {
+	var fieldErr *country.FieldError
+	if errors.As(err, &fieldErr) {
+		return &country.FieldError{Field: child + "." + fieldErr.Field, Message: child + ": " + fieldErr.Message}
+	}
+	return err
+}
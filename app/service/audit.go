@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldcrypt"
+)
+
+const (
+	AuditEntityProfile = "profile"
+	AuditEntityContact = "contact"
+	AuditEntityAddress = "address"
+	AuditEntityCompany = "company"
+	// AuditEntityCompanyMember events are keyed by company id; their values name the profile.
+	AuditEntityCompanyMember = "company_member"
+	// AuditEntityPreference events are keyed by profile id; their values name the namespace.
+	AuditEntityPreference = "preference"
+	// AuditEntityAttachment events are keyed by attachment id; their values name the owner.
+	AuditEntityAttachment = "attachment"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+type auditRepository interface {
+	Create(ctx context.Context, event *entity.AuditEvent) error
+	List(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error)
+}
+
+type listAuditEventsRequest interface {
+	GetEntity() string
+	GetEntityId() uint64
+	GetPage() uint32
+	GetPageSize() uint32
+}
+
+type AuditEventList struct {
+	Events   []*entity.AuditEvent
+	Page     uint32
+	PageSize uint32
+	Total    uint64
+}
+
+// AuditService reads the change history written by the other services.
+type AuditService struct {
+	auditRepo auditRepository
+}
+
+func NewAuditService(auditRepo auditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// List returns the events of one record, newest first.
+func (s *AuditService) List(ctx context.Context, req listAuditEventsRequest) (*AuditEventList, error) {
+	page := req.GetPage()
+	if page == 0 {
+		page = 1
+	}
+
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	events, total, err := s.auditRepo.List(ctx, req.GetEntity(), req.GetEntityId(), pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditEventList{
+		Events:   events,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
+
+// auditValues is the JSON snapshot of a record stored with an audit event.
+type auditValues map[string]any
+
+// recordAudit stores one change, attributing it to the caller and request id found in ctx.
+// before is nil for creates and restores, after is nil for deletes.
+func recordAudit(ctx context.Context, repo auditRepository, entityType string, entityID uint64, action string, before, after auditValues) error {
+	identity := caller.FromContext(ctx)
+	event := &entity.AuditEvent{
+		EntityType:    entityType,
+		EntityID:      entityID,
+		Action:        action,
+		CallerService: identity.Service,
+		RequestID:     identity.RequestID,
+		CreatedAt:     time.Now(),
+	}
+
+	var err error
+	if before != nil {
+		if event.OldValues, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if event.NewValues, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+
+	return repo.Create(ctx, event)
+}
+
+func profileAuditValues(profile *entity.Profile) auditValues {
+	return auditValues{
+		"user_id": profile.UserID,
+		"email":   profile.Email,
+		"version": profile.Version,
+	}
+}
+
+// redactedValue replaces sensitive contact values in audit and event snapshots, which are not
+// encrypted at rest.
+const redactedValue = "[redacted]"
+
+// contactSensitiveFields maps the keys of a contact snapshot to the encrypted field they hold.
+var contactSensitiveFields = map[string]string{
+	"nin":   fieldcrypt.FieldNIN,
+	"dob":   fieldcrypt.FieldDOB,
+	"phone": fieldcrypt.FieldPhone,
+}
+
+// contactAuditValues snapshots the contact, redacting its national identification number and
+// every other field that encrypts reports as stored encrypted, so that audit events and domain
+// events never hold in plaintext what the contacts table keeps sealed.
+func contactAuditValues(contact *entity.Contact, encrypts func(field string) bool) auditValues {
+	dob := ""
+	if contact.DOB != nil {
+		dob = contact.DOB.Format(contactDOBLayout)
+	}
+
+	values := auditValues{
+		"first_name":  contact.FirstName,
+		"last_name":   contact.LastName,
+		"nin":         contact.NIN,
+		"nin_country": contact.NINCountry,
+		"dob":         dob,
+		"phone":       contact.Phone,
+		"type":        contact.Type,
+		"attributes":  contact.Attributes,
+		"is_primary":  contact.IsPrimary,
+		"profile_id":  contact.ProfileID,
+		"version":     contact.Version,
+	}
+	for key, field := range contactSensitiveFields {
+		if values[key] != "" && (field == fieldcrypt.FieldNIN || encrypts(field)) {
+			values[key] = redactedValue
+		}
+	}
+
+	return values
+}
+
+func addressAuditValues(address *entity.Address) auditValues {
+	return auditValues{
+		"street_name":     address.StreetName,
+		"streen_no":       address.StreenNo,
+		"city":            address.City,
+		"county":          address.County,
+		"country":         address.Country,
+		"profile_id":      address.ProfileID,
+		"company_id":      address.CompanyID,
+		"postal_code":     address.PostalCode,
+		"building":        address.Building,
+		"apartment":       address.Apartment,
+		"additional_data": address.AdditionalData,
+		"type":            address.Type,
+		"attributes":      address.Attributes,
+		"is_primary":      address.IsPrimary,
+		"latitude":        address.Latitude,
+		"longitude":       address.Longitude,
+		"version":         address.Version,
+	}
+}
+
+func companyAuditValues(company *entity.Company) auditValues {
+	return auditValues{
+		"name":            company.Name,
+		"registration_no": company.RegistrationNo,
+		"fiscal_code":     company.FiscalCode,
+		"profile_id":      company.ProfileID,
+		"type":            company.Type,
+		"attributes":      company.Attributes,
+		"is_primary":      company.IsPrimary,
+		"version":         company.Version,
+	}
+}
+
+func companyMemberAuditValues(member *entity.CompanyMember) auditValues {
+	return auditValues{
+		"profile_id": member.ProfileID,
+		"role":       member.Role,
+	}
+}
+
+func attachmentAuditValues(attachment *entity.Attachment) auditValues {
+	return auditValues{
+		"entity_type":  attachment.EntityType,
+		"entity_id":    attachment.EntityID,
+		"kind":         attachment.Kind,
+		"filename":     attachment.Filename,
+		"content_type": attachment.ContentType,
+		"size":         attachment.Size,
+		"sha256":       attachment.SHA256,
+	}
+}
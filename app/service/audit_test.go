@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldcrypt"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
+)
+
+type mockAuditRepo struct {
+	events []*entity.AuditEvent
+	listFn func(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error)
+}
+
+func (m *mockAuditRepo) Create(_ context.Context, event *entity.AuditEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *mockAuditRepo) List(ctx context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error) {
+	if m.listFn != nil {
+		return m.listFn(ctx, entityType, entityID, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+type mockListAuditEventsReq struct {
+	entity   string
+	entityID uint64
+	page     uint32
+	pageSize uint32
+}
+
+func (r mockListAuditEventsReq) GetEntity() string   { return r.entity }
+func (r mockListAuditEventsReq) GetEntityId() uint64 { return r.entityID }
+func (r mockListAuditEventsReq) GetPage() uint32     { return r.page }
+func (r mockListAuditEventsReq) GetPageSize() uint32 { return r.pageSize }
+
+func auditContext() context.Context {
+	return caller.NewContext(context.Background(), caller.Identity{Service: "billing-service", RequestID: "rest-1"})
+}
+
+func decodeAuditValues(t *testing.T, raw json.RawMessage) map[string]any {
+	t.Helper()
+	if raw == nil {
+		return nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal(raw, &values); err != nil {
+		t.Fatalf("invalid audit json %s: %v", raw, err)
+	}
+	return values
+}
+
+func TestAuditListDefaults(t *testing.T) {
+	repo := &mockAuditRepo{
+		listFn: func(_ context.Context, entityType string, entityID uint64, limit, offset uint32) ([]*entity.AuditEvent, uint64, error) {
+			if entityType != AuditEntityAddress || entityID != 4 || limit != 20 || offset != 0 {
+				t.Fatalf("unexpected list args entityType=%q entityID=%d limit=%d offset=%d", entityType, entityID, limit, offset)
+			}
+			return []*entity.AuditEvent{{ID: 1}}, 1, nil
+		},
+	}
+
+	result, err := NewAuditService(repo).List(context.Background(), mockListAuditEventsReq{entity: AuditEntityAddress, entityID: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Page != 1 || result.PageSize != 20 || result.Total != 1 || len(result.Events) != 1 {
+		t.Fatalf("unexpected list result: %+v", result)
+	}
+}
+
+func TestContactUpdateRecordsAuditEvent(t *testing.T) {
+	repo := &mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, FirstName: "John", NIN: "1900517223344", ProfileID: 7, Version: 2}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			contact.Version++
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Contacts = repo
+	svc := NewContactService(repo, uow, "", nil)
+
+	_, err := svc.Patch(auditContext(), mockPatchContactReq{
+		mockUpdateContactReq: mockUpdateContactReq{id: 3, firstName: "Jane"},
+		paths:                []string{"first_name"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events := uow.repos.Audit.(*mockAuditRepo).events
+	if len(events) != 1 {
+		t.Fatalf("expected one audit event, got %d", len(events))
+	}
+	event := events[0]
+	if event.EntityType != AuditEntityContact || event.EntityID != 3 || event.Action != AuditActionUpdate {
+		t.Fatalf("unexpected audit event: %+v", event)
+	}
+	if event.CallerService != "billing-service" || event.RequestID != "rest-1" {
+		t.Fatalf("expected caller and request id on the event, got %+v", event)
+	}
+	before, after := decodeAuditValues(t, event.OldValues), decodeAuditValues(t, event.NewValues)
+	if before["first_name"] != "John" || before["version"] != float64(2) {
+		t.Fatalf("unexpected old values: %v", before)
+	}
+	if after["first_name"] != "Jane" || after["version"] != float64(3) {
+		t.Fatalf("unexpected new values: %v", after)
+	}
+	if before["nin"] != "[redacted]" || after["nin"] != "[redacted]" {
+		t.Fatalf("expected nin to be redacted, got %v and %v", before["nin"], after["nin"])
+	}
+}
+
+func TestContactAuditRedactsEncryptedFields(t *testing.T) {
+	dob := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	repo := &mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, Phone: "+40722000111", DOB: &dob, ProfileID: 7}, nil
+		},
+		encrypted: map[string]bool{fieldcrypt.FieldPhone: true},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Contacts = repo
+
+	if err := NewContactService(repo, uow, "", nil).Delete(auditContext(), 3, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	before := decodeAuditValues(t, uow.repos.Audit.(*mockAuditRepo).events[0].OldValues)
+	if before["phone"] != "[redacted]" || before["dob"] != "1990-05-17" || before["nin"] != "" {
+		t.Fatalf("expected only the encrypted phone to be redacted, got %v", before)
+	}
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(uow.repos.Outbox.(*mockOutboxRepo).events[0].Payload, &payload); err != nil {
+		t.Fatalf("invalid event payload: %v", err)
+	}
+	if payload.Data["phone"] != "[redacted]" {
+		t.Fatalf("expected the event payload to redact the phone, got %v", payload.Data)
+	}
+}
+
+func TestCompanyCreateFailureRecordsNothing(t *testing.T) {
+	repo := &mockCompanyRepo{
+		createFn: func(context.Context, *entity.Company) error {
+			return context.DeadlineExceeded
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Companies = repo
+
+	if _, err := NewCompanyService(repo, uow, fiscal.NewValidators(""), nil).Create(auditContext(), mockCreateCompanyReq{profileID: 1}); err == nil {
+		t.Fatal("expected create error")
+	}
+	if events := uow.repos.Audit.(*mockAuditRepo).events; len(events) != 0 {
+		t.Fatalf("expected no audit event, got %d", len(events))
+	}
+}
+
+func TestProfileDeleteRecordsCascadedChildren(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, Email: "john@example.com"}, nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	uow.repos.Contacts = &mockContactRepo{
+		listByProfileIDFn: func(context.Context, uint64) ([]*entity.Contact, error) {
+			return []*entity.Contact{{ID: 11}, {ID: 12}}, nil
+		},
+	}
+	uow.repos.Companies = &mockCompanyRepo{
+		listByProfileIDFn: func(context.Context, uint64) ([]*entity.Company, error) {
+			return []*entity.Company{{ID: 21}}, nil
+		},
+	}
+
+	if err := NewProfileService(repo, uow, false, "", nil).Delete(auditContext(), 5, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var got []string
+	for _, event := range uow.repos.Audit.(*mockAuditRepo).events {
+		if event.Action != AuditActionDelete || event.OldValues == nil || event.NewValues != nil {
+			t.Fatalf("unexpected delete event: %+v", event)
+		}
+		got = append(got, event.EntityType)
+	}
+	want := []string{AuditEntityProfile, AuditEntityContact, AuditEntityContact, AuditEntityCompany}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+	}
+}
+
+func TestProfileRestoreRecordsOnlyRestoredChildren(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+	restored := false
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			if restored {
+				return &entity.Profile{ID: id}, nil
+			}
+			return &entity.Profile{ID: id, DeletedAt: &deletedAt}, nil
+		},
+		restoreFn: func(context.Context, uint64) error {
+			restored = true
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	childrenRestored := false
+	uow.repos.Addresses = &mockAddressRepo{
+		restoreByProfileIDFn: func(context.Context, uint64, time.Time) error {
+			childrenRestored = true
+			return nil
+		},
+		listByProfileIDFn: func(context.Context, uint64) ([]*entity.Address, error) {
+			// Address 31 was never deleted; 32 comes back with the profile.
+			if childrenRestored {
+				return []*entity.Address{{ID: 31}, {ID: 32}}, nil
+			}
+			return []*entity.Address{{ID: 31}}, nil
+		},
+	}
+
+	if _, err := NewProfileService(repo, uow, false, "", nil).Restore(auditContext(), 5); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events := uow.repos.Audit.(*mockAuditRepo).events
+	if len(events) != 2 {
+		t.Fatalf("expected profile and one address event, got %d", len(events))
+	}
+	if events[0].EntityType != AuditEntityProfile || events[1].EntityType != AuditEntityAddress || events[1].EntityID != 32 {
+		t.Fatalf("unexpected restore events: %+v %+v", events[0], events[1])
+	}
+	if events[1].Action != AuditActionRestore || events[1].OldValues != nil || events[1].NewValues == nil {
+		t.Fatalf("unexpected restore event: %+v", events[1])
+	}
+}
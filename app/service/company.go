@@ -3,14 +3,27 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var (
 	ErrCompanyNotFound = errors.New("company not found")
+	// ErrTargetCompanyNotFound is returned when an update moves an address to a missing company.
+	ErrTargetCompanyNotFound = errors.New("target company does not exist")
+	// ErrCompanyDeleted is returned when restoring an address of a soft-deleted company.
+	ErrCompanyDeleted = errors.New("company is deleted")
+	// ErrCompanyFiscalCodeTaken is returned when another live company has the fiscal code; the
+	// caller should become a member of that company instead of creating a copy.
+	ErrCompanyFiscalCodeTaken = errors.New("a company with this fiscal code already exists")
 )
 
 type createCompanyRequest interface {
@@ -19,6 +32,7 @@ type createCompanyRequest interface {
 	GetFiscalCode() string
 	GetProfileId() uint64
 	GetType() string
+	GetAttributes() *structpb.Struct
 }
 
 type updateCompanyRequest interface {
@@ -28,6 +42,13 @@ type updateCompanyRequest interface {
 	GetFiscalCode() string
 	GetProfileId() uint64
 	GetType() string
+	GetExpectedVersion() uint64
+	GetAttributes() *structpb.Struct
+}
+
+type patchCompanyRequest interface {
+	updateCompanyRequest
+	GetUpdateMask() *fieldmaskpb.FieldMask
 }
 
 type listCompaniesRequest interface {
@@ -35,14 +56,24 @@ type listCompaniesRequest interface {
 	GetPage() uint32
 	GetPageSize() uint32
 	GetType() string
+	GetIncludeDeleted() bool
+	GetPrimaryOnly() bool
+	GetAttributes() map[string]string
 }
 
 type companyRepository interface {
 	Create(ctx context.Context, company *entity.Company) error
-	FindByID(ctx context.Context, id uint64) (*entity.Company, error)
+	FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error)
+	FindPrimary(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error)
+	FindByFiscalCode(ctx context.Context, fiscalCode string) (*entity.Company, error)
 	Update(ctx context.Context, company *entity.Company) error
-	Delete(ctx context.Context, id uint64) error
-	List(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error)
+	Delete(ctx context.Context, id uint64, expectedVersion uint64) error
+	DeleteByProfileID(ctx context.Context, profileID uint64) error
+	Restore(ctx context.Context, id uint64) error
+	RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error
+	List(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error)
+	ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Company, error)
+	ListForProfile(ctx context.Context, profileID uint64) ([]*entity.Company, error)
 }
 
 type CompanyList struct {
@@ -54,10 +85,15 @@ type CompanyList struct {
 
 type CompanyService struct {
 	companyRepo companyRepository
+	uow         UnitOfWork
+	validators  *fiscal.Validators
+	attributes  *attribute.Registry
 }
 
-func NewCompanyService(companyRepo companyRepository) *CompanyService {
-	return &CompanyService{companyRepo: companyRepo}
+// NewCompanyService returns a service that checks fiscal codes and registration numbers with
+// validators and custom attributes with attributes.
+func NewCompanyService(companyRepo companyRepository, uow UnitOfWork, validators *fiscal.Validators, attributes *attribute.Registry) *CompanyService {
+	return &CompanyService{companyRepo: companyRepo, uow: uow, validators: validators, attributes: attributes}
 }
 
 func (s *CompanyService) Create(ctx context.Context, req createCompanyRequest) (*entity.Company, error) {
@@ -68,19 +104,45 @@ func (s *CompanyService) Create(ctx context.Context, req createCompanyRequest) (
 		FiscalCode:     req.GetFiscalCode(),
 		ProfileID:      req.GetProfileId(),
 		Type:           req.GetType(),
+		Attributes:     attribute.Values(req.GetAttributes()),
 		CreatedAt:      now,
 		UpdatedAt:      now,
 	}
+	if err := s.checkFiscalCode(company); err != nil {
+		return nil, err
+	}
+	if err := s.attributes.Validate(attribute.EntityCompany, company.Type, company.Attributes); err != nil {
+		return nil, err
+	}
 
-	if err := s.companyRepo.Create(ctx, company); err != nil {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := checkFiscalCodeAvailable(ctx, repos, company); err != nil {
+			return err
+		}
+		if err := repos.Companies.Create(ctx, company); err != nil {
+			if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+				return ErrProfileNotFound
+			}
+			if errors.Is(err, repository.ErrCompanyFiscalCodeTaken) {
+				return ErrCompanyFiscalCodeTaken
+			}
+			return err
+		}
+		if err := recordChange(ctx, repos, AuditEntityCompany, company.ID, AuditActionCreate, nil, companyAuditValues(company)); err != nil {
+			return err
+		}
+		return addCompanyOwner(ctx, repos, company.ID, company.ProfileID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return company, nil
 }
 
-func (s *CompanyService) GetByID(ctx context.Context, id uint64) (*entity.Company, error) {
-	company, err := s.companyRepo.FindByID(ctx, id)
+// GetByID returns the company; soft-deleted companies are only returned with includeDeleted.
+func (s *CompanyService) GetByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error) {
+	company, err := s.companyRepo.FindByID(ctx, id, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -92,39 +154,333 @@ func (s *CompanyService) GetByID(ctx context.Context, id uint64) (*entity.Compan
 }
 
 func (s *CompanyService) Update(ctx context.Context, req updateCompanyRequest) (*entity.Company, error) {
-	company, err := s.companyRepo.FindByID(ctx, req.GetId())
+	company, err := s.companyRepo.FindByID(ctx, req.GetId(), false)
 	if err != nil {
 		return nil, err
 	}
 	if company == nil {
 		return nil, ErrCompanyNotFound
 	}
+	if expected := req.GetExpectedVersion(); expected != 0 && company.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := companyAuditValues(company)
+	profileID, companyType := company.ProfileID, company.Type
 
 	company.Name = req.GetName()
 	company.RegistrationNo = req.GetRegistrationNo()
 	company.FiscalCode = req.GetFiscalCode()
 	company.ProfileID = req.GetProfileId()
 	company.Type = req.GetType()
+	company.Attributes = attribute.Values(req.GetAttributes())
+	if err = s.checkFiscalCode(company); err != nil {
+		return nil, err
+	}
+	if err = s.attributes.Validate(attribute.EntityCompany, company.Type, company.Attributes); err != nil {
+		return nil, err
+	}
 
-	if err = s.companyRepo.Update(ctx, company); err != nil {
-		if errors.Is(err, repository.ErrCompanyNotFound) {
-			return nil, ErrCompanyNotFound
+	if company.ProfileID != profileID || company.Type != companyType {
+		company.IsPrimary = false
+	}
+
+	return s.save(ctx, company, before, profileID)
+}
+
+// Patch changes only the fields named in the request's update mask.
+func (s *CompanyService) Patch(ctx context.Context, req patchCompanyRequest) (*entity.Company, error) {
+	company, err := s.companyRepo.FindByID(ctx, req.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+	if expected := req.GetExpectedVersion(); expected != 0 && company.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := companyAuditValues(company)
+	profileID, companyType := company.ProfileID, company.Type
+
+	fiscalFieldsChanged, attributesChanged := false, false
+	for _, path := range req.GetUpdateMask().GetPaths() {
+		switch path {
+		case "name":
+			company.Name = req.GetName()
+		case "registration_no":
+			company.RegistrationNo = req.GetRegistrationNo()
+			fiscalFieldsChanged = true
+		case "fiscal_code":
+			company.FiscalCode = req.GetFiscalCode()
+			fiscalFieldsChanged = true
+		case "profile_id":
+			company.ProfileID = req.GetProfileId()
+		case "type":
+			company.Type = req.GetType()
+		default:
+			attributes, ok := patchAttributes(company.Attributes, path, req.GetAttributes())
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidUpdateMask, path)
+			}
+			company.Attributes, attributesChanged = attributes, true
 		}
+	}
+	if fiscalFieldsChanged {
+		if err = s.checkFiscalCode(company); err != nil {
+			return nil, err
+		}
+	}
+	if attributesChanged || company.Type != companyType {
+		if err = s.attributes.Validate(attribute.EntityCompany, company.Type, company.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if company.ProfileID != profileID || company.Type != companyType {
+		company.IsPrimary = false
+	}
+
+	return s.save(ctx, company, before, profileID)
+}
+
+// checkFiscalCode validates the company's fiscal code and registration number against the
+// country of the code, stores the code normalized and records the outcome on the company.
+func (s *CompanyService) checkFiscalCode(company *entity.Company) error {
+	result, err := s.validators.Check(company.FiscalCode, company.RegistrationNo)
+	if errors.Is(err, fiscal.ErrInvalidFiscalCode) {
+		return &country.FieldError{Field: "fiscal_code", Message: err.Error()}
+	}
+	if errors.Is(err, fiscal.ErrInvalidRegistrationNo) {
+		return &country.FieldError{Field: "registration_no", Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+
+	company.FiscalCode = result.FiscalCode
+	company.FiscalCodeValid = result.Valid
+	company.VATPayerPrefix = result.VATPrefix
+
+	return nil
+}
+
+// checkFiscalCodeAvailable fails with ErrCompanyFiscalCodeTaken when a live company other than
+// company has its fiscal code. The code stays locked until the transaction ends.
+func checkFiscalCodeAvailable(ctx context.Context, repos Repositories, company *entity.Company) error {
+	if company.FiscalCode == "" {
+		return nil
+	}
+	existing, err := repos.Companies.FindByFiscalCode(ctx, company.FiscalCode)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != company.ID {
+		return ErrCompanyFiscalCodeTaken
+	}
+
+	return nil
+}
+
+// save stores the changed company; a company moved to another profile makes that profile one
+// of its owners.
+func (s *CompanyService) save(ctx context.Context, company *entity.Company, before auditValues, previousProfileID uint64) (*entity.Company, error) {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := checkFiscalCodeAvailable(ctx, repos, company); err != nil {
+			return err
+		}
+		if err := updateCompany(ctx, repos, company, before); err != nil {
+			return err
+		}
+		if company.ProfileID == previousProfileID {
+			return nil
+		}
+		return addCompanyOwner(ctx, repos, company.ID, company.ProfileID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return company, nil
 }
 
-func (s *CompanyService) Delete(ctx context.Context, id uint64) error {
-	if err := s.companyRepo.Delete(ctx, id); err != nil {
+// updateCompany stores the changed company and records the change in the audit log.
+func updateCompany(ctx context.Context, repos Repositories, company *entity.Company, before auditValues) error {
+	if err := repos.Companies.Update(ctx, company); err != nil {
 		if errors.Is(err, repository.ErrCompanyNotFound) {
 			return ErrCompanyNotFound
 		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return ErrVersionConflict
+		}
+		if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+			return ErrTargetProfileNotFound
+		}
+		if errors.Is(err, repository.ErrPrimaryConflict) {
+			return ErrPrimaryConflict
+		}
+		if errors.Is(err, repository.ErrCompanyFiscalCodeTaken) {
+			return ErrCompanyFiscalCodeTaken
+		}
 		return err
 	}
+	return recordChange(ctx, repos, AuditEntityCompany, company.ID, AuditActionUpdate, before, companyAuditValues(company))
+}
 
-	return nil
+// Delete soft-deletes the company together with its addresses; a non-zero expectedVersion only
+// deletes that version of the company.
+func (s *CompanyService) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		company, err := repos.Companies.FindByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		if company == nil {
+			return ErrCompanyNotFound
+		}
+		addresses, err := repos.Addresses.ListByCompanyID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err = repos.Companies.Delete(ctx, id, expectedVersion); err != nil {
+			if errors.Is(err, repository.ErrCompanyNotFound) {
+				return ErrCompanyNotFound
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return ErrVersionConflict
+			}
+			return err
+		}
+		if err = repos.Addresses.DeleteByCompanyID(ctx, id); err != nil {
+			return err
+		}
+
+		if err = recordChange(ctx, repos, AuditEntityCompany, id, AuditActionDelete, companyAuditValues(company), nil); err != nil {
+			return err
+		}
+		return (&profileChildren{addresses: addresses}).record(ctx, repos, AuditActionDelete, nil)
+	})
+}
+
+// Restore undeletes the company and the addresses that were deleted with it. A company cannot
+// be restored while its profile is deleted or another live company has its fiscal code.
+func (s *CompanyService) Restore(ctx context.Context, id uint64) (*entity.Company, error) {
+	var restored *entity.Company
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		company, err := repos.Companies.FindByID(ctx, id, true)
+		if err != nil {
+			return err
+		}
+		if company == nil {
+			return ErrCompanyNotFound
+		}
+		if company.DeletedAt == nil {
+			return ErrNotDeleted
+		}
+
+		if err = repos.Companies.Restore(ctx, id); err != nil {
+			if errors.Is(err, repository.ErrCompanyNotFound) {
+				return ErrCompanyNotFound
+			}
+			if errors.Is(err, repository.ErrNotDeleted) {
+				return ErrNotDeleted
+			}
+			if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+				return ErrProfileDeleted
+			}
+			if errors.Is(err, repository.ErrCompanyFiscalCodeTaken) {
+				return ErrCompanyFiscalCodeTaken
+			}
+			return err
+		}
+
+		alive, err := repos.Addresses.ListByCompanyID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = repos.Addresses.RestoreByCompanyID(ctx, id, *company.DeletedAt); err != nil {
+			return err
+		}
+
+		if restored, err = repos.Companies.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if restored == nil {
+			return ErrCompanyNotFound
+		}
+		if err = recordChange(ctx, repos, AuditEntityCompany, id, AuditActionRestore, nil, companyAuditValues(restored)); err != nil {
+			return err
+		}
+
+		addresses, err := repos.Addresses.ListByCompanyID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return (&profileChildren{addresses: addresses}).record(ctx, repos, AuditActionRestore, &profileChildren{addresses: alive})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// ListAddresses returns the live addresses of the company, oldest first.
+func (s *CompanyService) ListAddresses(ctx context.Context, companyID uint64) ([]*entity.Address, error) {
+	var addresses []*entity.Address
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		var err error
+		addresses, err = repos.Addresses.ListByCompanyID(ctx, companyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// SetPrimary makes the company the primary one of its type on its profile, taking the flag off the
+// previous primary in the same transaction; a non-zero expectedVersion only changes that
+// version. Moving a company to another profile or type takes its flag off.
+func (s *CompanyService) SetPrimary(ctx context.Context, id uint64, expectedVersion uint64) (*entity.Company, error) {
+	var company *entity.Company
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		var err error
+		if company, err = repos.Companies.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if company == nil {
+			return ErrCompanyNotFound
+		}
+		if expectedVersion != 0 && company.Version != expectedVersion {
+			return ErrVersionConflict
+		}
+		if company.IsPrimary {
+			return nil
+		}
+
+		current, err := repos.Companies.FindPrimary(ctx, company.ProfileID, company.Type)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			before := companyAuditValues(current)
+			current.IsPrimary = false
+			if err = updateCompany(ctx, repos, current, before); err != nil {
+				return err
+			}
+		}
+
+		before := companyAuditValues(company)
+		company.IsPrimary = true
+		return updateCompany(ctx, repos, company, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return company, nil
 }
 
 func (s *CompanyService) List(ctx context.Context, req listCompaniesRequest) (*CompanyList, error) {
@@ -140,7 +496,7 @@ func (s *CompanyService) List(ctx context.Context, req listCompaniesRequest) (*C
 
 	offset := (page - 1) * pageSize
 
-	companies, total, err := s.companyRepo.List(ctx, req.GetProfileId(), req.GetType(), pageSize, offset)
+	companies, total, err := s.companyRepo.List(ctx, req.GetProfileId(), req.GetType(), req.GetAttributes(), req.GetIncludeDeleted(), req.GetPrimaryOnly(), pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+)
+
+var (
+	ErrCompanyMemberNotFound = errors.New("profile is not a member of the company")
+	// ErrLastCompanyOwner is returned when a change would leave a company without an owner.
+	ErrLastCompanyOwner = errors.New("company must keep at least one owner")
+)
+
+// companyMemberBatchSize is how many memberships leaveCompanies reads at a time.
+const companyMemberBatchSize = 100
+
+type companyMemberRepository interface {
+	Save(ctx context.Context, member *entity.CompanyMember) error
+	Find(ctx context.Context, companyID, profileID uint64) (*entity.CompanyMember, error)
+	CountByRole(ctx context.Context, companyID uint64, role string) (uint64, error)
+	Delete(ctx context.Context, companyID, profileID uint64) error
+	ListByCompanyID(ctx context.Context, companyID uint64, limit, offset uint32) ([]*entity.CompanyMember, uint64, error)
+	ListByProfileID(ctx context.Context, profileID uint64, limit, offset uint32) ([]*entity.CompanyMembership, uint64, error)
+}
+
+type addCompanyMemberRequest interface {
+	GetCompanyId() uint64
+	GetProfileId() uint64
+	GetRole() string
+}
+
+type listCompanyMembersRequest interface {
+	GetCompanyId() uint64
+	GetPage() uint32
+	GetPageSize() uint32
+}
+
+type listProfileCompaniesRequest interface {
+	GetProfileId() uint64
+	GetPage() uint32
+	GetPageSize() uint32
+}
+
+type CompanyMemberList struct {
+	Members  []*entity.CompanyMember
+	Page     uint32
+	PageSize uint32
+	Total    uint64
+}
+
+type CompanyMembershipList struct {
+	Memberships []*entity.CompanyMembership
+	Page        uint32
+	PageSize    uint32
+	Total       uint64
+}
+
+// AddMember gives the profile access to the company under the requested role, or changes the
+// role of a profile that already is a member. The last owner cannot be given another role.
+func (s *CompanyService) AddMember(ctx context.Context, req addCompanyMemberRequest) (*entity.CompanyMember, error) {
+	now := time.Now()
+	member := &entity.CompanyMember{
+		CompanyID: req.GetCompanyId(),
+		ProfileID: req.GetProfileId(),
+		Role:      req.GetRole(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		company, err := repos.Companies.FindByID(ctx, member.CompanyID, false)
+		if err != nil {
+			return err
+		}
+		if company == nil {
+			return ErrCompanyNotFound
+		}
+		profile, err := repos.Profiles.FindByID(ctx, member.ProfileID, false)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return ErrProfileNotFound
+		}
+
+		current, err := repos.CompanyMembers.Find(ctx, member.CompanyID, member.ProfileID)
+		if err != nil {
+			return err
+		}
+		if current != nil && current.Role == member.Role {
+			*member = *current
+			return nil
+		}
+		if current != nil && current.Role == entity.CompanyRoleOwner {
+			if err = checkOtherOwner(ctx, repos, member.CompanyID); err != nil {
+				return err
+			}
+		}
+
+		return saveCompanyMember(ctx, repos, member, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember takes the profile's access to the company away. The last owner cannot be removed.
+func (s *CompanyService) RemoveMember(ctx context.Context, companyID, profileID uint64) error {
+	return s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		member, err := repos.CompanyMembers.Find(ctx, companyID, profileID)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return ErrCompanyMemberNotFound
+		}
+		if member.Role == entity.CompanyRoleOwner {
+			if err = checkOtherOwner(ctx, repos, companyID); err != nil {
+				return err
+			}
+		}
+
+		if err = repos.CompanyMembers.Delete(ctx, companyID, profileID); err != nil {
+			if errors.Is(err, repository.ErrCompanyMemberNotFound) {
+				return ErrCompanyMemberNotFound
+			}
+			return err
+		}
+
+		return recordChange(ctx, repos, AuditEntityCompanyMember, companyID, AuditActionDelete, companyMemberAuditValues(member), nil)
+	})
+}
+
+// ListMembers returns the members of a live company, leaving out deleted profiles.
+func (s *CompanyService) ListMembers(ctx context.Context, req listCompanyMembersRequest) (*CompanyMemberList, error) {
+	page, pageSize, offset := companyMemberPage(req.GetPage(), req.GetPageSize())
+	result := &CompanyMemberList{Page: page, PageSize: pageSize}
+
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		company, err := repos.Companies.FindByID(ctx, req.GetCompanyId(), false)
+		if err != nil {
+			return err
+		}
+		if company == nil {
+			return ErrCompanyNotFound
+		}
+
+		result.Members, result.Total, err = repos.CompanyMembers.ListByCompanyID(ctx, company.ID, pageSize, offset)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListProfileCompanies returns the live companies the profile is a member of, with its role
+// in each.
+func (s *CompanyService) ListProfileCompanies(ctx context.Context, req listProfileCompaniesRequest) (*CompanyMembershipList, error) {
+	page, pageSize, offset := companyMemberPage(req.GetPage(), req.GetPageSize())
+	result := &CompanyMembershipList{Page: page, PageSize: pageSize}
+
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		profile, err := repos.Profiles.FindByID(ctx, req.GetProfileId(), false)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return ErrProfileNotFound
+		}
+
+		result.Memberships, result.Total, err = repos.CompanyMembers.ListByProfileID(ctx, profile.ID, pageSize, offset)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkOtherOwner fails with ErrLastCompanyOwner unless the company has more than one owner.
+func checkOtherOwner(ctx context.Context, repos Repositories, companyID uint64) error {
+	owners, err := repos.CompanyMembers.CountByRole(ctx, companyID, entity.CompanyRoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return ErrLastCompanyOwner
+	}
+
+	return nil
+}
+
+// leaveCompanies prepares the companies of a profile that is being deleted. A company that has
+// another owner is kept: the profile's membership is removed and a company filed under the profile
+// is filed under that owner instead. A company the profile owns alone is filed under the profile,
+// keeping the membership, so that it is deleted and restored together with the profile.
+func leaveCompanies(ctx context.Context, repos Repositories, profileID uint64) error {
+	companyIDs, err := listProfileCompanyIDs(ctx, repos, profileID)
+	if err != nil {
+		return err
+	}
+
+	for _, companyID := range companyIDs {
+		company, err := repos.Companies.FindByID(ctx, companyID, false)
+		if err != nil {
+			return err
+		}
+		if company == nil {
+			continue
+		}
+		member, err := repos.CompanyMembers.Find(ctx, companyID, profileID)
+		if err != nil {
+			return err
+		}
+
+		owner := member != nil && member.Role == entity.CompanyRoleOwner
+		if company.ProfileID != profileID && !owner {
+			if err = leaveCompany(ctx, repos, member); err != nil {
+				return err
+			}
+			continue
+		}
+
+		successorID, err := findOtherOwner(ctx, repos, companyID, profileID)
+		if err != nil {
+			return err
+		}
+		if successorID == 0 {
+			if company.ProfileID == profileID {
+				continue
+			}
+			if err = fileCompanyUnder(ctx, repos, company, profileID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = leaveCompany(ctx, repos, member); err != nil {
+			return err
+		}
+		if company.ProfileID == profileID {
+			if err = fileCompanyUnder(ctx, repos, company, successorID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// leaveCompany removes the membership, if any, and records the removal.
+func leaveCompany(ctx context.Context, repos Repositories, member *entity.CompanyMember) error {
+	if member == nil {
+		return nil
+	}
+	if err := repos.CompanyMembers.Delete(ctx, member.CompanyID, member.ProfileID); err != nil {
+		return err
+	}
+
+	return recordChange(ctx, repos, AuditEntityCompanyMember, member.CompanyID, AuditActionDelete, companyMemberAuditValues(member), nil)
+}
+
+// fileCompanyUnder moves the company to the profile, which takes its primary flag off.
+func fileCompanyUnder(ctx context.Context, repos Repositories, company *entity.Company, profileID uint64) error {
+	before := companyAuditValues(company)
+	company.ProfileID = profileID
+	company.IsPrimary = false
+
+	return updateCompany(ctx, repos, company, before)
+}
+
+// listProfileCompanyIDs returns the ids of the live companies filed under the profile or having it
+// as a member, in ascending order so that concurrent deletes lock them in the same order.
+func listProfileCompanyIDs(ctx context.Context, repos Repositories, profileID uint64) ([]uint64, error) {
+	filed, err := repos.Companies.ListByProfileID(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(filed))
+	for _, company := range filed {
+		ids = append(ids, company.ID)
+	}
+
+	for offset := uint32(0); ; offset += companyMemberBatchSize {
+		memberships, total, err := repos.CompanyMembers.ListByProfileID(ctx, profileID, companyMemberBatchSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, membership := range memberships {
+			ids = append(ids, membership.Company.ID)
+		}
+		if len(memberships) == 0 || uint64(offset)+uint64(len(memberships)) >= total {
+			break
+		}
+	}
+
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// findOtherOwner returns the oldest owner of the company other than the profile whose own profile
+// is not deleted, or 0 when there is none. The owners stay locked until the transaction ends.
+func findOtherOwner(ctx context.Context, repos Repositories, companyID, profileID uint64) (uint64, error) {
+	owners, err := repos.CompanyMembers.CountByRole(ctx, companyID, entity.CompanyRoleOwner)
+	if err != nil || owners == 0 {
+		return 0, err
+	}
+
+	for offset := uint32(0); ; offset += companyMemberBatchSize {
+		members, total, err := repos.CompanyMembers.ListByCompanyID(ctx, companyID, companyMemberBatchSize, offset)
+		if err != nil {
+			return 0, err
+		}
+		for _, member := range members {
+			if member.Role == entity.CompanyRoleOwner && member.ProfileID != profileID {
+				return member.ProfileID, nil
+			}
+		}
+		if len(members) == 0 || uint64(offset)+uint64(len(members)) >= total {
+			return 0, nil
+		}
+	}
+}
+
+// saveCompanyMember stores the membership and records it as added, or as changed when current
+// is the membership it replaces.
+func saveCompanyMember(ctx context.Context, repos Repositories, member, current *entity.CompanyMember) error {
+	var before auditValues
+	action := AuditActionCreate
+	if current != nil {
+		before = companyMemberAuditValues(current)
+		action = AuditActionUpdate
+		member.CreatedAt = current.CreatedAt
+	}
+
+	if err := repos.CompanyMembers.Save(ctx, member); err != nil {
+		if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+			return ErrProfileNotFound
+		}
+		return err
+	}
+
+	return recordChange(ctx, repos, AuditEntityCompanyMember, member.CompanyID, action, before, companyMemberAuditValues(member))
+}
+
+// addCompanyOwner makes the profile an owner of the company unless it already is one.
+func addCompanyOwner(ctx context.Context, repos Repositories, companyID, profileID uint64) error {
+	current, err := repos.CompanyMembers.Find(ctx, companyID, profileID)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Role == entity.CompanyRoleOwner {
+		return nil
+	}
+
+	now := time.Now()
+	return saveCompanyMember(ctx, repos, &entity.CompanyMember{
+		CompanyID: companyID,
+		ProfileID: profileID,
+		Role:      entity.CompanyRoleOwner,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, current)
+}
+
+func companyMemberPage(page, pageSize uint32) (uint32, uint32, uint32) {
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = 20
+	}
+
+	return page, pageSize, (page - 1) * pageSize
+}
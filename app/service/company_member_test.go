@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+)
+
+// mockCompanyMemberRepo keeps memberships in memory, keyed by company and profile.
+type mockCompanyMemberRepo struct {
+	members map[[2]uint64]*entity.CompanyMember
+}
+
+func newMockCompanyMemberRepo() *mockCompanyMemberRepo {
+	return &mockCompanyMemberRepo{members: map[[2]uint64]*entity.CompanyMember{}}
+}
+
+func (m *mockCompanyMemberRepo) Save(_ context.Context, member *entity.CompanyMember) error {
+	stored := *member
+	m.members[[2]uint64{member.CompanyID, member.ProfileID}] = &stored
+	return nil
+}
+
+func (m *mockCompanyMemberRepo) Find(_ context.Context, companyID, profileID uint64) (*entity.CompanyMember, error) {
+	member, ok := m.members[[2]uint64{companyID, profileID}]
+	if !ok {
+		return nil, nil
+	}
+	found := *member
+	return &found, nil
+}
+
+func (m *mockCompanyMemberRepo) CountByRole(_ context.Context, companyID uint64, role string) (uint64, error) {
+	var count uint64
+	for _, member := range m.members {
+		if member.CompanyID == companyID && member.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockCompanyMemberRepo) Delete(_ context.Context, companyID, profileID uint64) error {
+	key := [2]uint64{companyID, profileID}
+	if _, ok := m.members[key]; !ok {
+		return repository.ErrCompanyMemberNotFound
+	}
+	delete(m.members, key)
+	return nil
+}
+
+func (m *mockCompanyMemberRepo) ListByCompanyID(_ context.Context, companyID uint64, _, _ uint32) ([]*entity.CompanyMember, uint64, error) {
+	members := make([]*entity.CompanyMember, 0)
+	for _, member := range m.members {
+		if member.CompanyID == companyID {
+			members = append(members, member)
+		}
+	}
+	return members, uint64(len(members)), nil
+}
+
+func (m *mockCompanyMemberRepo) ListByProfileID(_ context.Context, profileID uint64, _, _ uint32) ([]*entity.CompanyMembership, uint64, error) {
+	memberships := make([]*entity.CompanyMembership, 0)
+	for _, member := range m.members {
+		if member.ProfileID == profileID {
+			memberships = append(memberships, &entity.CompanyMembership{Company: &entity.Company{ID: member.CompanyID}, Role: member.Role})
+		}
+	}
+	return memberships, uint64(len(memberships)), nil
+}
+
+type mockAddCompanyMemberReq struct {
+	companyID uint64
+	profileID uint64
+	role      string
+}
+
+func (r mockAddCompanyMemberReq) GetCompanyId() uint64 { return r.companyID }
+func (r mockAddCompanyMemberReq) GetProfileId() uint64 { return r.profileID }
+func (r mockAddCompanyMemberReq) GetRole() string      { return r.role }
+
+// newCompanyMemberService serves company 5 and profiles 7 and 8, with profile 7 as the owner.
+func newCompanyMemberService(t *testing.T) (*CompanyService, *mockUnitOfWork) {
+	t.Helper()
+	companies := &mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			if id != 5 {
+				return nil, nil
+			}
+			return &entity.Company{ID: id, ProfileID: 7}, nil
+		},
+	}
+	profiles := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			if id != 7 && id != 8 {
+				return nil, nil
+			}
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := newMockUnitOfWork(profiles)
+	uow.repos.Companies = companies
+	members := uow.repos.CompanyMembers.(*mockCompanyMemberRepo)
+	if err := members.Save(context.Background(), &entity.CompanyMember{CompanyID: 5, ProfileID: 7, Role: entity.CompanyRoleOwner}); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	return NewCompanyService(companies, uow, fiscal.NewValidators(""), nil), uow
+}
+
+func TestCompanyCreateAddsOwner(t *testing.T) {
+	repo := &mockCompanyRepo{
+		createFn: func(_ context.Context, company *entity.Company) error {
+			company.ID = 23
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Companies = repo
+
+	if _, err := NewCompanyService(repo, uow, fiscal.NewValidators(""), nil).Create(context.Background(), mockCreateCompanyReq{name: "ACME", profileID: 9}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	member, _ := uow.repos.CompanyMembers.Find(context.Background(), 23, 9)
+	if member == nil || member.Role != entity.CompanyRoleOwner {
+		t.Fatalf("expected profile 9 to own company 23, got %+v", member)
+	}
+}
+
+func TestCompanyAddMemberAndChangeRole(t *testing.T) {
+	svc, uow := newCompanyMemberService(t)
+
+	member, err := svc.AddMember(context.Background(), mockAddCompanyMemberReq{companyID: 5, profileID: 8, role: entity.CompanyRoleAccountant})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if member.Role != entity.CompanyRoleAccountant {
+		t.Fatalf("unexpected member: %+v", member)
+	}
+
+	if _, err = svc.AddMember(context.Background(), mockAddCompanyMemberReq{companyID: 5, profileID: 8, role: entity.CompanyRoleAdmin}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	events := uow.repos.Audit.(*mockAuditRepo).events
+	if len(events) != 2 || events[0].Action != AuditActionCreate || events[1].Action != AuditActionUpdate {
+		t.Fatalf("expected a create and an update event, got %+v", events)
+	}
+	if events[1].EntityType != AuditEntityCompanyMember || events[1].EntityID != 5 {
+		t.Fatalf("unexpected event: %+v", events[1])
+	}
+}
+
+func TestCompanyAddMemberMissingRecords(t *testing.T) {
+	svc, _ := newCompanyMemberService(t)
+
+	if _, err := svc.AddMember(context.Background(), mockAddCompanyMemberReq{companyID: 6, profileID: 8, role: entity.CompanyRoleViewer}); !errors.Is(err, ErrCompanyNotFound) {
+		t.Fatalf("expected ErrCompanyNotFound, got %v", err)
+	}
+	if _, err := svc.AddMember(context.Background(), mockAddCompanyMemberReq{companyID: 5, profileID: 9, role: entity.CompanyRoleViewer}); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestCompanyKeepsLastOwner(t *testing.T) {
+	svc, _ := newCompanyMemberService(t)
+
+	if _, err := svc.AddMember(context.Background(), mockAddCompanyMemberReq{companyID: 5, profileID: 7, role: entity.CompanyRoleViewer}); !errors.Is(err, ErrLastCompanyOwner) {
+		t.Fatalf("expected ErrLastCompanyOwner when demoting the last owner, got %v", err)
+	}
+	if err := svc.RemoveMember(context.Background(), 5, 7); !errors.Is(err, ErrLastCompanyOwner) {
+		t.Fatalf("expected ErrLastCompanyOwner when removing the last owner, got %v", err)
+	}
+
+	if _, err := svc.AddMember(context.Background(), mockAddCompanyMemberReq{companyID: 5, profileID: 8, role: entity.CompanyRoleOwner}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.RemoveMember(context.Background(), 5, 7); err != nil {
+		t.Fatalf("expected the first owner to be removable once there is another, got %v", err)
+	}
+	if err := svc.RemoveMember(context.Background(), 5, 7); !errors.Is(err, ErrCompanyMemberNotFound) {
+		t.Fatalf("expected ErrCompanyMemberNotFound, got %v", err)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type mockCreateCompanyReq struct {
@@ -16,6 +20,7 @@ type mockCreateCompanyReq struct {
 	fiscalCode     string
 	profileID      uint64
 	kind           string
+	attributes     *structpb.Struct
 }
 
 func (r mockCreateCompanyReq) GetName() string           { return r.name }
@@ -24,31 +29,59 @@ func (r mockCreateCompanyReq) GetFiscalCode() string     { return r.fiscalCode }
 func (r mockCreateCompanyReq) GetProfileId() uint64      { return r.profileID }
 func (r mockCreateCompanyReq) GetType() string           { return r.kind }
 
+func (r mockCreateCompanyReq) GetAttributes() *structpb.Struct { return r.attributes }
+
 type mockUpdateCompanyReq struct {
-	id uint64
+	id              uint64
+	expectedVersion uint64
 	mockCreateCompanyReq
 }
 
-func (r mockUpdateCompanyReq) GetId() uint64 { return r.id }
+func (r mockUpdateCompanyReq) GetId() uint64              { return r.id }
+func (r mockUpdateCompanyReq) GetExpectedVersion() uint64 { return r.expectedVersion }
+
+type mockPatchCompanyReq struct {
+	mockUpdateCompanyReq
+	paths []string
+}
+
+func (r mockPatchCompanyReq) GetUpdateMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{Paths: r.paths}
+}
 
 type mockListCompaniesReq struct {
-	profileID uint64
-	page      uint32
-	pageSize  uint32
-	kind      string
+	profileID      uint64
+	page           uint32
+	pageSize       uint32
+	kind           string
+	includeDeleted bool
+	primaryOnly    bool
+	attributes     map[string]string
 }
 
-func (r mockListCompaniesReq) GetProfileId() uint64 { return r.profileID }
-func (r mockListCompaniesReq) GetPage() uint32      { return r.page }
-func (r mockListCompaniesReq) GetPageSize() uint32  { return r.pageSize }
-func (r mockListCompaniesReq) GetType() string      { return r.kind }
+func (r mockListCompaniesReq) GetProfileId() uint64    { return r.profileID }
+func (r mockListCompaniesReq) GetPage() uint32         { return r.page }
+func (r mockListCompaniesReq) GetPageSize() uint32     { return r.pageSize }
+func (r mockListCompaniesReq) GetType() string         { return r.kind }
+func (r mockListCompaniesReq) GetIncludeDeleted() bool { return r.includeDeleted }
+func (r mockListCompaniesReq) GetPrimaryOnly() bool    { return r.primaryOnly }
+
+func (r mockListCompaniesReq) GetAttributes() map[string]string { return r.attributes }
 
 type mockCompanyRepo struct {
 	createFn   func(ctx context.Context, company *entity.Company) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Company, error)
-	updateFn   func(ctx context.Context, company *entity.Company) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error)
+
+	findPrimaryFn        func(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error)
+	findByFiscalCodeFn   func(ctx context.Context, fiscalCode string) (*entity.Company, error)
+	updateFn             func(ctx context.Context, company *entity.Company) error
+	deleteFn             func(ctx context.Context, id, expectedVersion uint64) error
+	deleteByProfileIDFn  func(ctx context.Context, profileID uint64) error
+	restoreFn            func(ctx context.Context, id uint64) error
+	restoreByProfileIDFn func(ctx context.Context, profileID uint64, deletedSince time.Time) error
+	listFn               func(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error)
+	listByProfileIDFn    func(ctx context.Context, profileID uint64) ([]*entity.Company, error)
+	listForProfileFn     func(ctx context.Context, profileID uint64) ([]*entity.Company, error)
 }
 
 func (m *mockCompanyRepo) Create(ctx context.Context, company *entity.Company) error {
@@ -58,9 +91,23 @@ func (m *mockCompanyRepo) Create(ctx context.Context, company *entity.Company) e
 	return nil
 }
 
-func (m *mockCompanyRepo) FindByID(ctx context.Context, id uint64) (*entity.Company, error) {
+func (m *mockCompanyRepo) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Company, error) {
 	if m.findByIDFn != nil {
-		return m.findByIDFn(ctx, id)
+		return m.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (m *mockCompanyRepo) FindPrimary(ctx context.Context, profileID uint64, companyType string) (*entity.Company, error) {
+	if m.findPrimaryFn != nil {
+		return m.findPrimaryFn(ctx, profileID, companyType)
+	}
+	return nil, nil
+}
+
+func (m *mockCompanyRepo) FindByFiscalCode(ctx context.Context, fiscalCode string) (*entity.Company, error) {
+	if m.findByFiscalCodeFn != nil {
+		return m.findByFiscalCodeFn(ctx, fiscalCode)
 	}
 	return nil, nil
 }
@@ -72,20 +119,62 @@ func (m *mockCompanyRepo) Update(ctx context.Context, company *entity.Company) e
 	return nil
 }
 
-func (m *mockCompanyRepo) Delete(ctx context.Context, id uint64) error {
+func (m *mockCompanyRepo) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if m.deleteFn != nil {
-		return m.deleteFn(ctx, id)
+		return m.deleteFn(ctx, id, expectedVersion)
 	}
 	return nil
 }
 
-func (m *mockCompanyRepo) List(ctx context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+func (m *mockCompanyRepo) DeleteByProfileID(ctx context.Context, profileID uint64) error {
+	if m.deleteByProfileIDFn != nil {
+		return m.deleteByProfileIDFn(ctx, profileID)
+	}
+	return nil
+}
+
+func (m *mockCompanyRepo) Restore(ctx context.Context, id uint64) error {
+	if m.restoreFn != nil {
+		return m.restoreFn(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockCompanyRepo) RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error {
+	if m.restoreByProfileIDFn != nil {
+		return m.restoreByProfileIDFn(ctx, profileID, deletedSince)
+	}
+	return nil
+}
+
+func (m *mockCompanyRepo) List(ctx context.Context, profileID uint64, companyType string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
 	if m.listFn != nil {
-		return m.listFn(ctx, profileID, companyType, limit, offset)
+		return m.listFn(ctx, profileID, companyType, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (m *mockCompanyRepo) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	if m.listByProfileIDFn != nil {
+		return m.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (m *mockCompanyRepo) ListForProfile(ctx context.Context, profileID uint64) ([]*entity.Company, error) {
+	if m.listForProfileFn != nil {
+		return m.listForProfileFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+// newCompanyService wires the service to a unit of work that hands out the same repository.
+func newCompanyService(repo companyRepository) *CompanyService {
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Companies = repo
+	return NewCompanyService(repo, uow, fiscal.NewValidators(""), nil)
+}
+
 func TestCompanyCreateSuccess(t *testing.T) {
 	repo := &mockCompanyRepo{
 		createFn: func(_ context.Context, company *entity.Company) error {
@@ -93,12 +182,12 @@ func TestCompanyCreateSuccess(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewCompanyService(repo)
+	svc := newCompanyService(repo)
 
 	company, err := svc.Create(context.Background(), mockCreateCompanyReq{
 		name:           "ACME",
-		registrationNo: "REG-1",
-		fiscalCode:     "FISC-1",
+		registrationNo: "J40/123/2020",
+		fiscalCode:     "RO18547290",
 		profileID:      9,
 		kind:           "vendor",
 	})
@@ -110,22 +199,64 @@ func TestCompanyCreateSuccess(t *testing.T) {
 	}
 }
 
+func TestCompanyCreateRejectsTakenFiscalCode(t *testing.T) {
+	repo := &mockCompanyRepo{
+		findByFiscalCodeFn: func(_ context.Context, fiscalCode string) (*entity.Company, error) {
+			if fiscalCode != "RO18547290" {
+				t.Fatalf("unexpected fiscal code %q", fiscalCode)
+			}
+			return &entity.Company{ID: 4, FiscalCode: fiscalCode}, nil
+		},
+		createFn: func(context.Context, *entity.Company) error {
+			t.Fatal("expected no copy of the company to be created")
+			return nil
+		},
+	}
+	svc := newCompanyService(repo)
+
+	_, err := svc.Create(context.Background(), mockCreateCompanyReq{name: "ACME", registrationNo: "J40/123/2020", fiscalCode: "RO18547290", profileID: 9})
+	if !errors.Is(err, ErrCompanyFiscalCodeTaken) {
+		t.Fatalf("expected ErrCompanyFiscalCodeTaken, got %v", err)
+	}
+}
+
+func TestCompanyUpdateKeepsItsOwnFiscalCode(t *testing.T) {
+	repo := &mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, FiscalCode: "RO18547290", ProfileID: 9}, nil
+		},
+		findByFiscalCodeFn: func(_ context.Context, fiscalCode string) (*entity.Company, error) {
+			return &entity.Company{ID: 4, FiscalCode: fiscalCode}, nil
+		},
+	}
+	svc := newCompanyService(repo)
+
+	req := mockUpdateCompanyReq{id: 4, mockCreateCompanyReq: mockCreateCompanyReq{name: "ACME SRL", registrationNo: "J40/123/2020", fiscalCode: "RO18547290", profileID: 9}}
+	if _, err := svc.Update(context.Background(), req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	req.id = 5
+	if _, err := svc.Update(context.Background(), req); !errors.Is(err, ErrCompanyFiscalCodeTaken) {
+		t.Fatalf("expected ErrCompanyFiscalCodeTaken, got %v", err)
+	}
+}
+
 func TestCompanyGetByIDNotFound(t *testing.T) {
-	svc := NewCompanyService(&mockCompanyRepo{})
-	_, err := svc.GetByID(context.Background(), 3)
+	svc := newCompanyService(&mockCompanyRepo{})
+	_, err := svc.GetByID(context.Background(), 3, false)
 	if !errors.Is(err, ErrCompanyNotFound) {
 		t.Fatalf("expected ErrCompanyNotFound, got %v", err)
 	}
 }
 
 func TestCompanyUpdateNotFound(t *testing.T) {
-	svc := NewCompanyService(&mockCompanyRepo{})
+	svc := newCompanyService(&mockCompanyRepo{})
 	_, err := svc.Update(context.Background(), mockUpdateCompanyReq{
 		id: 4,
 		mockCreateCompanyReq: mockCreateCompanyReq{
 			name:           "ACME",
-			registrationNo: "REG-1",
-			fiscalCode:     "FISC-1",
+			registrationNo: "J40/123/2020",
+			fiscalCode:     "RO18547290",
 			profileID:      9,
 		},
 	})
@@ -136,13 +267,16 @@ func TestCompanyUpdateNotFound(t *testing.T) {
 
 func TestCompanyDeleteRepositoryNotFoundMapped(t *testing.T) {
 	repo := &mockCompanyRepo{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id}, nil
+		},
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrCompanyNotFound
 		},
 	}
-	svc := NewCompanyService(repo)
+	svc := newCompanyService(repo)
 
-	err := svc.Delete(context.Background(), 10)
+	err := svc.Delete(context.Background(), 10, 0)
 	if !errors.Is(err, ErrCompanyNotFound) {
 		t.Fatalf("expected ErrCompanyNotFound, got %v", err)
 	}
@@ -151,14 +285,14 @@ func TestCompanyDeleteRepositoryNotFoundMapped(t *testing.T) {
 func TestCompanyListDefaults(t *testing.T) {
 	now := time.Now()
 	repo := &mockCompanyRepo{
-		listFn: func(_ context.Context, profileID uint64, companyType string, limit, offset uint32) ([]*entity.Company, uint64, error) {
+		listFn: func(_ context.Context, profileID uint64, companyType string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Company, uint64, error) {
 			if profileID != 7 || companyType != "vendor" || limit != 20 || offset != 0 {
 				t.Fatalf("unexpected list args profileID=%d companyType=%q limit=%d offset=%d", profileID, companyType, limit, offset)
 			}
 			return []*entity.Company{{ID: 1, Name: "ACME", CreatedAt: now, UpdatedAt: now}}, 1, nil
 		},
 	}
-	svc := NewCompanyService(repo)
+	svc := newCompanyService(repo)
 
 	result, err := svc.List(context.Background(), mockListCompaniesReq{profileID: 7, page: 0, pageSize: 0, kind: "vendor"})
 	if err != nil {
@@ -168,3 +302,216 @@ func TestCompanyListDefaults(t *testing.T) {
 		t.Fatalf("unexpected list result: %+v", result)
 	}
 }
+
+func TestCompanyCreateMissingProfileMapped(t *testing.T) {
+	svc := newCompanyService(&mockCompanyRepo{
+		createFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Create(context.Background(), mockCreateCompanyReq{profileID: 404})
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestCompanyUpdateMissingTargetProfileMapped(t *testing.T) {
+	svc := newCompanyService(&mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Update(context.Background(), mockUpdateCompanyReq{id: 3, mockCreateCompanyReq: mockCreateCompanyReq{profileID: 404}})
+	if !errors.Is(err, ErrTargetProfileNotFound) {
+		t.Fatalf("expected ErrTargetProfileNotFound, got %v", err)
+	}
+}
+
+func TestCompanyPatchOnlyMaskedFields(t *testing.T) {
+	var saved *entity.Company
+	svc := newCompanyService(&mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", FiscalCode: "FISC-1", ProfileID: 7}, nil
+		},
+		updateFn: func(_ context.Context, company *entity.Company) error {
+			saved = company
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchCompanyReq{
+		mockUpdateCompanyReq: mockUpdateCompanyReq{id: 3, mockCreateCompanyReq: mockCreateCompanyReq{kind: "vendor"}},
+		paths:                []string{"type"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.Name != "ACME" || saved.FiscalCode != "FISC-1" || saved.ProfileID != 7 || saved.Type != "vendor" {
+		t.Fatalf("unexpected patched company: %+v", saved)
+	}
+}
+
+func TestCompanyCreateChecksFiscalCode(t *testing.T) {
+	svc := newCompanyService(&mockCompanyRepo{})
+
+	company, err := svc.Create(context.Background(), mockCreateCompanyReq{registrationNo: "j40/123/2020", fiscalCode: "ro 18547290", profileID: 9})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if company.FiscalCode != "RO18547290" || !company.FiscalCodeValid || company.VATPayerPrefix != "RO" {
+		t.Fatalf("unexpected fiscal fields: %+v", company)
+	}
+
+	// Without a prefix or a default country the code is stored unchecked.
+	company, err = svc.Create(context.Background(), mockCreateCompanyReq{registrationNo: "REG-1", fiscalCode: "18547291", profileID: 9})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if company.FiscalCodeValid || company.VATPayerPrefix != "" {
+		t.Fatalf("expected an unchecked fiscal code, got %+v", company)
+	}
+
+	svc.validators = fiscal.NewValidators("RO")
+	company, err = svc.Create(context.Background(), mockCreateCompanyReq{registrationNo: "J40/123/2020", fiscalCode: "18547290", profileID: 9})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !company.FiscalCodeValid || company.VATPayerPrefix != "" {
+		t.Fatalf("expected a valid code without prefix, got %+v", company)
+	}
+}
+
+func TestCompanyCreateRejectsInvalidFiscalFields(t *testing.T) {
+	svc := newCompanyService(&mockCompanyRepo{
+		createFn: func(context.Context, *entity.Company) error {
+			t.Fatal("expected the company not to be stored")
+			return nil
+		},
+	})
+
+	cases := []struct {
+		registrationNo, fiscalCode, field string
+	}{
+		{"J40/123/2020", "RO18547291", "fiscal_code"},
+		{"J40/123/2020", "DE12345", "fiscal_code"},
+		{"J99/123/2020", "RO18547290", "registration_no"},
+	}
+	for _, tc := range cases {
+		_, err := svc.Create(context.Background(), mockCreateCompanyReq{registrationNo: tc.registrationNo, fiscalCode: tc.fiscalCode, profileID: 9})
+		var fieldErr *country.FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != tc.field {
+			t.Fatalf("expected %s error for %+v, got %v", tc.field, tc, err)
+		}
+	}
+}
+
+func TestCompanyPatchChecksChangedFiscalFields(t *testing.T) {
+	svc := newCompanyService(&mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, RegistrationNo: "J40/123/2020", FiscalCode: "RO18547290", FiscalCodeValid: true, VATPayerPrefix: "RO"}, nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchCompanyReq{
+		mockUpdateCompanyReq: mockUpdateCompanyReq{id: 3, mockCreateCompanyReq: mockCreateCompanyReq{registrationNo: "J40/123/1980"}},
+		paths:                []string{"registration_no"},
+	})
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "registration_no" {
+		t.Fatalf("expected registration_no error, got %v", err)
+	}
+
+	company, err := svc.Patch(context.Background(), mockPatchCompanyReq{
+		mockUpdateCompanyReq: mockUpdateCompanyReq{id: 3, mockCreateCompanyReq: mockCreateCompanyReq{fiscalCode: "14399840"}},
+		paths:                []string{"fiscal_code"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if company.FiscalCode != "14399840" || company.FiscalCodeValid || company.VATPayerPrefix != "" {
+		t.Fatalf("expected the check to be recomputed, got %+v", company)
+	}
+}
+
+func TestCompanySetPrimaryConflictMapped(t *testing.T) {
+	svc := newCompanyService(&mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 7, Type: "employer", Version: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Company) error {
+			return repository.ErrPrimaryConflict
+		},
+	})
+
+	if _, err := svc.SetPrimary(context.Background(), 3, 0); !errors.Is(err, ErrPrimaryConflict) {
+		t.Fatalf("expected ErrPrimaryConflict, got %v", err)
+	}
+}
+
+func TestCompanyDeleteCascadesToAddresses(t *testing.T) {
+	repo := &mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, ProfileID: 9}, nil
+		},
+	}
+	var deletedFor uint64
+	addresses := &mockAddressRepo{
+		listByCompanyIDFn: func(_ context.Context, companyID uint64) ([]*entity.Address, error) {
+			return []*entity.Address{{ID: 31, CompanyID: companyID, Type: "billing"}}, nil
+		},
+		deleteByCompanyIDFn: func(_ context.Context, companyID uint64) error {
+			deletedFor = companyID
+			return nil
+		},
+	}
+	audit := &mockAuditRepo{}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Companies = repo
+	uow.repos.Addresses = addresses
+	uow.repos.Audit = audit
+	svc := NewCompanyService(repo, uow, fiscal.NewValidators(""), nil)
+
+	if err := svc.Delete(context.Background(), 10, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deletedFor != 10 {
+		t.Fatalf("expected the company addresses to be deleted, got company %d", deletedFor)
+	}
+	if len(audit.events) != 2 || audit.events[1].EntityType != AuditEntityAddress || audit.events[1].EntityID != 31 {
+		t.Fatalf("expected the company and its address to be audited, got %+v", audit.events)
+	}
+}
+
+func TestCompanyPatchTypeChecksItsRequiredAttributes(t *testing.T) {
+	repo := &mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", ProfileID: 9, Type: "client"}, nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Companies = repo
+	registry := newAttributeRegistry(t, `{"company": {"vendor": [{"name": "caen_code", "type": "string", "required": true}]}}`)
+	svc := NewCompanyService(repo, uow, fiscal.NewValidators(""), registry)
+
+	_, err := svc.Patch(context.Background(), mockPatchCompanyReq{
+		mockUpdateCompanyReq: mockUpdateCompanyReq{id: 3, mockCreateCompanyReq: mockCreateCompanyReq{kind: "vendor"}},
+		paths:                []string{"type"},
+	})
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "attributes.caen_code" {
+		t.Fatalf("expected attributes.caen_code field error, got %v", err)
+	}
+
+	caen, _ := structpb.NewStruct(map[string]any{"caen_code": "6201"})
+	if _, err = svc.Patch(context.Background(), mockPatchCompanyReq{
+		mockUpdateCompanyReq: mockUpdateCompanyReq{id: 3, mockCreateCompanyReq: mockCreateCompanyReq{kind: "vendor", attributes: caen}},
+		paths:                []string{"type", "attributes.caen_code"},
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
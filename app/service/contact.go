@@ -3,15 +3,23 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/nin"
+	"github.com/vibast-solutions/ms-go-profile/app/phone"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var (
 	ErrContactNotFound = errors.New("contact not found")
+	// ErrInvalidPhone is returned when a contact phone is not a valid number of its region.
+	ErrInvalidPhone = errors.New("invalid phone number")
 )
 
 const contactDOBLayout = "2006-01-02"
@@ -20,10 +28,12 @@ type createContactRequest interface {
 	GetFirstName() string
 	GetLastName() string
 	GetNin() string
+	GetNinCountry() string
 	GetDob() string
 	GetPhone() string
 	GetProfileId() uint64
 	GetType() string
+	GetAttributes() *structpb.Struct
 }
 
 type updateContactRequest interface {
@@ -31,10 +41,18 @@ type updateContactRequest interface {
 	GetFirstName() string
 	GetLastName() string
 	GetNin() string
+	GetNinCountry() string
 	GetDob() string
 	GetPhone() string
 	GetProfileId() uint64
 	GetType() string
+	GetExpectedVersion() uint64
+	GetAttributes() *structpb.Struct
+}
+
+type patchContactRequest interface {
+	updateContactRequest
+	GetUpdateMask() *fieldmaskpb.FieldMask
 }
 
 type listContactsRequest interface {
@@ -42,14 +60,24 @@ type listContactsRequest interface {
 	GetPage() uint32
 	GetPageSize() uint32
 	GetType() string
+	GetNin() string
+	GetIncludeDeleted() bool
+	GetPrimaryOnly() bool
+	GetAttributes() map[string]string
 }
 
 type contactRepository interface {
 	Create(ctx context.Context, contact *entity.Contact) error
-	FindByID(ctx context.Context, id uint64) (*entity.Contact, error)
+	FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error)
+	FindPrimary(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error)
 	Update(ctx context.Context, contact *entity.Contact) error
-	Delete(ctx context.Context, id uint64) error
-	List(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	Delete(ctx context.Context, id uint64, expectedVersion uint64) error
+	DeleteByProfileID(ctx context.Context, profileID uint64) error
+	Restore(ctx context.Context, id uint64) error
+	RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error
+	List(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Contact, error)
+	Encrypts(field string) bool
 }
 
 type ContactList struct {
@@ -61,40 +89,49 @@ type ContactList struct {
 
 type ContactService struct {
 	contactRepo contactRepository
+	uow         UnitOfWork
+	phoneRegion string
+	attributes  *attribute.Registry
 }
 
-func NewContactService(contactRepo contactRepository) *ContactService {
-	return &ContactService{contactRepo: contactRepo}
+// NewContactService creates the service. phoneRegion is the region of phone numbers written
+// without a country calling code when the profile has no address in a known region; when
+// empty, such numbers need that address. Contact attributes are checked against attributes.
+func NewContactService(contactRepo contactRepository, uow UnitOfWork, phoneRegion string, attributes *attribute.Registry) *ContactService {
+	return &ContactService{contactRepo: contactRepo, uow: uow, phoneRegion: phoneRegion, attributes: attributes}
 }
 
 func (s *ContactService) Create(ctx context.Context, req createContactRequest) (*entity.Contact, error) {
-	dob, err := parseOptionalContactDOB(req.GetDob())
+	contact, err := newContactEntity(req, time.Now())
 	if err != nil {
 		return nil, err
 	}
-
-	now := time.Now()
-	contact := &entity.Contact{
-		FirstName: req.GetFirstName(),
-		LastName:  req.GetLastName(),
-		NIN:       req.GetNin(),
-		DOB:       dob,
-		Phone:     req.GetPhone(),
-		Type:      req.GetType(),
-		CreatedAt: now,
-		UpdatedAt: now,
-		ProfileID: req.GetProfileId(),
+	if err = s.attributes.Validate(attribute.EntityContact, contact.Type, contact.Attributes); err != nil {
+		return nil, err
 	}
 
-	if err = s.contactRepo.Create(ctx, contact); err != nil {
+	err = s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := normalizePhone(ctx, repos, contact, s.phoneRegion); err != nil {
+			return err
+		}
+		if err := repos.Contacts.Create(ctx, contact); err != nil {
+			if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+				return ErrProfileNotFound
+			}
+			return err
+		}
+		return recordChange(ctx, repos, AuditEntityContact, contact.ID, AuditActionCreate, nil, contactAuditValues(contact, repos.Contacts.Encrypts))
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return contact, nil
 }
 
-func (s *ContactService) GetByID(ctx context.Context, id uint64) (*entity.Contact, error) {
-	contact, err := s.contactRepo.FindByID(ctx, id)
+// GetByID returns the contact; soft-deleted contacts are only returned with includeDeleted.
+func (s *ContactService) GetByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error) {
+	contact, err := s.contactRepo.FindByID(ctx, id, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -106,13 +143,19 @@ func (s *ContactService) GetByID(ctx context.Context, id uint64) (*entity.Contac
 }
 
 func (s *ContactService) Update(ctx context.Context, req updateContactRequest) (*entity.Contact, error) {
-	contact, err := s.contactRepo.FindByID(ctx, req.GetId())
+	contact, err := s.contactRepo.FindByID(ctx, req.GetId(), false)
 	if err != nil {
 		return nil, err
 	}
 	if contact == nil {
 		return nil, ErrContactNotFound
 	}
+	if expected := req.GetExpectedVersion(); expected != 0 && contact.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := contactAuditValues(contact, s.contactRepo.Encrypts)
+	profileID, contactType := contact.ProfileID, contact.Type
+	phoneChanged := contact.Phone != req.GetPhone()
 
 	dob, err := parseOptionalContactDOB(req.GetDob())
 	if err != nil {
@@ -122,30 +165,229 @@ func (s *ContactService) Update(ctx context.Context, req updateContactRequest) (
 	contact.FirstName = req.GetFirstName()
 	contact.LastName = req.GetLastName()
 	contact.NIN = req.GetNin()
+	contact.NINCountry = req.GetNinCountry()
 	contact.DOB = dob
 	contact.Phone = req.GetPhone()
 	contact.ProfileID = req.GetProfileId()
 	contact.Type = req.GetType()
+	contact.Attributes = attribute.Values(req.GetAttributes())
+	if err = s.attributes.Validate(attribute.EntityContact, contact.Type, contact.Attributes); err != nil {
+		return nil, err
+	}
 
-	if err = s.contactRepo.Update(ctx, contact); err != nil {
-		if errors.Is(err, repository.ErrContactNotFound) {
-			return nil, ErrContactNotFound
+	if contact.ProfileID != profileID || contact.Type != contactType {
+		contact.IsPrimary = false
+	}
+
+	return s.save(ctx, contact, before, phoneChanged)
+}
+
+// Patch changes only the fields named in the request's update mask.
+func (s *ContactService) Patch(ctx context.Context, req patchContactRequest) (*entity.Contact, error) {
+	contact, err := s.contactRepo.FindByID(ctx, req.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil {
+		return nil, ErrContactNotFound
+	}
+	if expected := req.GetExpectedVersion(); expected != 0 && contact.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := contactAuditValues(contact, s.contactRepo.Encrypts)
+	profileID, contactType := contact.ProfileID, contact.Type
+	phoneChanged, ninFieldsChanged, attributesChanged := false, false, false
+
+	for _, path := range req.GetUpdateMask().GetPaths() {
+		switch path {
+		case "first_name":
+			contact.FirstName = req.GetFirstName()
+		case "last_name":
+			contact.LastName = req.GetLastName()
+		case "nin":
+			contact.NIN = req.GetNin()
+			ninFieldsChanged = true
+		case "nin_country":
+			contact.NINCountry = req.GetNinCountry()
+			ninFieldsChanged = true
+		case "dob":
+			if contact.DOB, err = parseOptionalContactDOB(req.GetDob()); err != nil {
+				return nil, err
+			}
+			ninFieldsChanged = true
+		case "phone":
+			phoneChanged = contact.Phone != req.GetPhone()
+			contact.Phone = req.GetPhone()
+		case "profile_id":
+			contact.ProfileID = req.GetProfileId()
+		case "type":
+			contact.Type = req.GetType()
+		default:
+			attributes, ok := patchAttributes(contact.Attributes, path, req.GetAttributes())
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidUpdateMask, path)
+			}
+			contact.Attributes, attributesChanged = attributes, true
 		}
+	}
+	if ninFieldsChanged {
+		normalized, err := nin.NormalizeContact(nin.Contact{Country: contact.NINCountry, NIN: contact.NIN, DOB: contact.DOB})
+		if err != nil {
+			return nil, err
+		}
+		contact.NINCountry, contact.NIN, contact.DOB = normalized.Country, normalized.NIN, normalized.DOB
+	}
+	if attributesChanged || contact.Type != contactType {
+		if err = s.attributes.Validate(attribute.EntityContact, contact.Type, contact.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if contact.ProfileID != profileID || contact.Type != contactType {
+		contact.IsPrimary = false
+	}
+
+	return s.save(ctx, contact, before, phoneChanged)
+}
+
+// save stores the contact, parsing its phone again when it changed. Stored phones that no
+// longer parse are kept as they are.
+func (s *ContactService) save(ctx context.Context, contact *entity.Contact, before auditValues, phoneChanged bool) (*entity.Contact, error) {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if phoneChanged {
+			if err := normalizePhone(ctx, repos, contact, s.phoneRegion); err != nil {
+				return err
+			}
+		}
+		return updateContact(ctx, repos, contact, before)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return contact, nil
 }
 
-func (s *ContactService) Delete(ctx context.Context, id uint64) error {
-	if err := s.contactRepo.Delete(ctx, id); err != nil {
+// updateContact stores the changed contact and records the change in the audit log.
+func updateContact(ctx context.Context, repos Repositories, contact *entity.Contact, before auditValues) error {
+	if err := repos.Contacts.Update(ctx, contact); err != nil {
 		if errors.Is(err, repository.ErrContactNotFound) {
 			return ErrContactNotFound
 		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return ErrVersionConflict
+		}
+		if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+			return ErrTargetProfileNotFound
+		}
+		if errors.Is(err, repository.ErrPrimaryConflict) {
+			return ErrPrimaryConflict
+		}
 		return err
 	}
+	return recordChange(ctx, repos, AuditEntityContact, contact.ID, AuditActionUpdate, before, contactAuditValues(contact, repos.Contacts.Encrypts))
+}
 
-	return nil
+// Delete soft-deletes the contact; a non-zero expectedVersion only deletes that version.
+func (s *ContactService) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		contact, err := repos.Contacts.FindByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		if contact == nil {
+			return ErrContactNotFound
+		}
+
+		if err = repos.Contacts.Delete(ctx, id, expectedVersion); err != nil {
+			if errors.Is(err, repository.ErrContactNotFound) {
+				return ErrContactNotFound
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return ErrVersionConflict
+			}
+			return err
+		}
+
+		return recordChange(ctx, repos, AuditEntityContact, id, AuditActionDelete, contactAuditValues(contact, repos.Contacts.Encrypts), nil)
+	})
+}
+
+// Restore undeletes the contact. A contact cannot be restored while its profile is deleted.
+func (s *ContactService) Restore(ctx context.Context, id uint64) (*entity.Contact, error) {
+	var restored *entity.Contact
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := repos.Contacts.Restore(ctx, id); err != nil {
+			if errors.Is(err, repository.ErrContactNotFound) {
+				return ErrContactNotFound
+			}
+			if errors.Is(err, repository.ErrNotDeleted) {
+				return ErrNotDeleted
+			}
+			if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+				return ErrProfileDeleted
+			}
+			return err
+		}
+
+		var err error
+		if restored, err = repos.Contacts.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if restored == nil {
+			return ErrContactNotFound
+		}
+
+		return recordChange(ctx, repos, AuditEntityContact, id, AuditActionRestore, nil, contactAuditValues(restored, repos.Contacts.Encrypts))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// SetPrimary makes the contact the primary one of its type on its profile, taking the flag off the
+// previous primary in the same transaction; a non-zero expectedVersion only changes that
+// version. Moving a contact to another profile or type takes its flag off.
+func (s *ContactService) SetPrimary(ctx context.Context, id uint64, expectedVersion uint64) (*entity.Contact, error) {
+	var contact *entity.Contact
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		var err error
+		if contact, err = repos.Contacts.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if contact == nil {
+			return ErrContactNotFound
+		}
+		if expectedVersion != 0 && contact.Version != expectedVersion {
+			return ErrVersionConflict
+		}
+		if contact.IsPrimary {
+			return nil
+		}
+
+		current, err := repos.Contacts.FindPrimary(ctx, contact.ProfileID, contact.Type)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			before := contactAuditValues(current, repos.Contacts.Encrypts)
+			current.IsPrimary = false
+			if err = updateContact(ctx, repos, current, before); err != nil {
+				return err
+			}
+		}
+
+		before := contactAuditValues(contact, repos.Contacts.Encrypts)
+		contact.IsPrimary = true
+		return updateContact(ctx, repos, contact, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return contact, nil
 }
 
 func (s *ContactService) List(ctx context.Context, req listContactsRequest) (*ContactList, error) {
@@ -161,7 +403,7 @@ func (s *ContactService) List(ctx context.Context, req listContactsRequest) (*Co
 
 	offset := (page - 1) * pageSize
 
-	contacts, total, err := s.contactRepo.List(ctx, req.GetProfileId(), req.GetType(), pageSize, offset)
+	contacts, total, err := s.contactRepo.List(ctx, req.GetProfileId(), req.GetType(), req.GetNin(), req.GetAttributes(), req.GetIncludeDeleted(), req.GetPrimaryOnly(), pageSize, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +416,64 @@ func (s *ContactService) List(ctx context.Context, req listContactsRequest) (*Co
 	}, nil
 }
 
+func newContactEntity(req createContactRequest, now time.Time) (*entity.Contact, error) {
+	dob, err := parseOptionalContactDOB(req.GetDob())
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.Contact{
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		NIN:        req.GetNin(),
+		NINCountry: req.GetNinCountry(),
+		DOB:        dob,
+		Phone:      req.GetPhone(),
+		Type:       req.GetType(),
+		Attributes: attribute.Values(req.GetAttributes()),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ProfileID:  req.GetProfileId(),
+	}, nil
+}
+
+// normalizePhone sets the E.164 form and type of the contact's phone. A number without a
+// country calling code belongs to the region of the profile's oldest address in a known
+// region, or to defaultRegion when there is none.
+func normalizePhone(ctx context.Context, repos Repositories, contact *entity.Contact, defaultRegion string) error {
+	region := defaultRegion
+	if raw := strings.TrimSpace(contact.Phone); raw != "" && !strings.HasPrefix(raw, "+") {
+		addresses, err := repos.Addresses.ListByProfileID(ctx, contact.ProfileID)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			if phone.IsRegion(address.Country) {
+				region = address.Country
+				break
+			}
+		}
+	}
+
+	return setContactPhone(contact, region)
+}
+
+// setContactPhone parses the contact's phone as a number of region.
+func setContactPhone(contact *entity.Contact, region string) error {
+	contact.PhoneE164, contact.PhoneType = "", ""
+	if strings.TrimSpace(contact.Phone) == "" {
+		return nil
+	}
+
+	number, err := phone.Parse(contact.Phone, region)
+	if err != nil {
+		return ErrInvalidPhone
+	}
+	contact.PhoneE164, contact.PhoneType = number.E164, string(number.Type)
+
+	return nil
+}
+
 func parseOptionalContactDOB(raw string) (*time.Time, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -6,66 +6,107 @@ import (
 	"testing"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type mockCreateContactReq struct {
-	firstName string
-	lastName  string
-	nin       string
-	dob       string
-	phone     string
-	profileID uint64
-	kind      string
-}
-
-func (r mockCreateContactReq) GetFirstName() string { return r.firstName }
-func (r mockCreateContactReq) GetLastName() string  { return r.lastName }
-func (r mockCreateContactReq) GetNin() string       { return r.nin }
-func (r mockCreateContactReq) GetDob() string       { return r.dob }
-func (r mockCreateContactReq) GetPhone() string     { return r.phone }
-func (r mockCreateContactReq) GetProfileId() uint64 { return r.profileID }
-func (r mockCreateContactReq) GetType() string      { return r.kind }
+	firstName  string
+	lastName   string
+	nin        string
+	ninCountry string
+	dob        string
+	phone      string
+	profileID  uint64
+	kind       string
+	attributes *structpb.Struct
+}
+
+func (r mockCreateContactReq) GetFirstName() string  { return r.firstName }
+func (r mockCreateContactReq) GetLastName() string   { return r.lastName }
+func (r mockCreateContactReq) GetNin() string        { return r.nin }
+func (r mockCreateContactReq) GetNinCountry() string { return r.ninCountry }
+func (r mockCreateContactReq) GetDob() string        { return r.dob }
+func (r mockCreateContactReq) GetPhone() string      { return r.phone }
+func (r mockCreateContactReq) GetProfileId() uint64  { return r.profileID }
+func (r mockCreateContactReq) GetType() string       { return r.kind }
+
+func (r mockCreateContactReq) GetAttributes() *structpb.Struct { return r.attributes }
 
 type mockUpdateContactReq struct {
-	id        uint64
-	firstName string
-	lastName  string
-	nin       string
-	dob       string
-	phone     string
-	profileID uint64
-	kind      string
-}
-
-func (r mockUpdateContactReq) GetId() uint64        { return r.id }
-func (r mockUpdateContactReq) GetFirstName() string { return r.firstName }
-func (r mockUpdateContactReq) GetLastName() string  { return r.lastName }
-func (r mockUpdateContactReq) GetNin() string       { return r.nin }
-func (r mockUpdateContactReq) GetDob() string       { return r.dob }
-func (r mockUpdateContactReq) GetPhone() string     { return r.phone }
-func (r mockUpdateContactReq) GetProfileId() uint64 { return r.profileID }
-func (r mockUpdateContactReq) GetType() string      { return r.kind }
+	id         uint64
+	firstName  string
+	lastName   string
+	nin        string
+	ninCountry string
+	dob        string
+	phone      string
+	profileID  uint64
+	kind       string
+	attributes *structpb.Struct
+
+	expectedVersion uint64
+}
+
+func (r mockUpdateContactReq) GetId() uint64         { return r.id }
+func (r mockUpdateContactReq) GetFirstName() string  { return r.firstName }
+func (r mockUpdateContactReq) GetLastName() string   { return r.lastName }
+func (r mockUpdateContactReq) GetNin() string        { return r.nin }
+func (r mockUpdateContactReq) GetNinCountry() string { return r.ninCountry }
+func (r mockUpdateContactReq) GetDob() string        { return r.dob }
+func (r mockUpdateContactReq) GetPhone() string      { return r.phone }
+func (r mockUpdateContactReq) GetProfileId() uint64  { return r.profileID }
+func (r mockUpdateContactReq) GetType() string       { return r.kind }
+
+func (r mockUpdateContactReq) GetExpectedVersion() uint64      { return r.expectedVersion }
+func (r mockUpdateContactReq) GetAttributes() *structpb.Struct { return r.attributes }
+
+type mockPatchContactReq struct {
+	mockUpdateContactReq
+	paths []string
+}
+
+func (r mockPatchContactReq) GetUpdateMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{Paths: r.paths}
+}
 
 type mockListContactsReq struct {
-	profileID uint64
-	page      uint32
-	pageSize  uint32
-	kind      string
+	profileID      uint64
+	page           uint32
+	pageSize       uint32
+	kind           string
+	nin            string
+	includeDeleted bool
+	primaryOnly    bool
+	attributes     map[string]string
 }
 
-func (r mockListContactsReq) GetProfileId() uint64 { return r.profileID }
-func (r mockListContactsReq) GetPage() uint32      { return r.page }
-func (r mockListContactsReq) GetPageSize() uint32  { return r.pageSize }
-func (r mockListContactsReq) GetType() string      { return r.kind }
+func (r mockListContactsReq) GetProfileId() uint64    { return r.profileID }
+func (r mockListContactsReq) GetPage() uint32         { return r.page }
+func (r mockListContactsReq) GetPageSize() uint32     { return r.pageSize }
+func (r mockListContactsReq) GetType() string         { return r.kind }
+func (r mockListContactsReq) GetNin() string          { return r.nin }
+func (r mockListContactsReq) GetIncludeDeleted() bool { return r.includeDeleted }
+func (r mockListContactsReq) GetPrimaryOnly() bool    { return r.primaryOnly }
+
+func (r mockListContactsReq) GetAttributes() map[string]string { return r.attributes }
 
 type mockContactRepo struct {
 	createFn   func(ctx context.Context, contact *entity.Contact) error
-	findByIDFn func(ctx context.Context, id uint64) (*entity.Contact, error)
-	updateFn   func(ctx context.Context, contact *entity.Contact) error
-	deleteFn   func(ctx context.Context, id uint64) error
-	listFn     func(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	findByIDFn func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error)
+
+	findPrimaryFn        func(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error)
+	updateFn             func(ctx context.Context, contact *entity.Contact) error
+	deleteFn             func(ctx context.Context, id, expectedVersion uint64) error
+	deleteByProfileIDFn  func(ctx context.Context, profileID uint64) error
+	restoreFn            func(ctx context.Context, id uint64) error
+	restoreByProfileIDFn func(ctx context.Context, profileID uint64, deletedSince time.Time) error
+	listFn               func(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error)
+	listByProfileIDFn    func(ctx context.Context, profileID uint64) ([]*entity.Contact, error)
+	encrypted            map[string]bool
 }
 
 func (m *mockContactRepo) Create(ctx context.Context, contact *entity.Contact) error {
@@ -75,9 +116,16 @@ func (m *mockContactRepo) Create(ctx context.Context, contact *entity.Contact) e
 	return nil
 }
 
-func (m *mockContactRepo) FindByID(ctx context.Context, id uint64) (*entity.Contact, error) {
+func (m *mockContactRepo) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Contact, error) {
 	if m.findByIDFn != nil {
-		return m.findByIDFn(ctx, id)
+		return m.findByIDFn(ctx, id, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (m *mockContactRepo) FindPrimary(ctx context.Context, profileID uint64, contactType string) (*entity.Contact, error) {
+	if m.findPrimaryFn != nil {
+		return m.findPrimaryFn(ctx, profileID, contactType)
 	}
 	return nil, nil
 }
@@ -89,20 +137,59 @@ func (m *mockContactRepo) Update(ctx context.Context, contact *entity.Contact) e
 	return nil
 }
 
-func (m *mockContactRepo) Delete(ctx context.Context, id uint64) error {
+func (m *mockContactRepo) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if m.deleteFn != nil {
-		return m.deleteFn(ctx, id)
+		return m.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (m *mockContactRepo) DeleteByProfileID(ctx context.Context, profileID uint64) error {
+	if m.deleteByProfileIDFn != nil {
+		return m.deleteByProfileIDFn(ctx, profileID)
+	}
+	return nil
+}
+
+func (m *mockContactRepo) Restore(ctx context.Context, id uint64) error {
+	if m.restoreFn != nil {
+		return m.restoreFn(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockContactRepo) RestoreByProfileID(ctx context.Context, profileID uint64, deletedSince time.Time) error {
+	if m.restoreByProfileIDFn != nil {
+		return m.restoreByProfileIDFn(ctx, profileID, deletedSince)
 	}
 	return nil
 }
 
-func (m *mockContactRepo) List(ctx context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+func (m *mockContactRepo) List(ctx context.Context, profileID uint64, contactType, nin string, attributes map[string]string, includeDeleted, primaryOnly bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 	if m.listFn != nil {
-		return m.listFn(ctx, profileID, contactType, limit, offset)
+		return m.listFn(ctx, profileID, contactType, nin, attributes, includeDeleted, primaryOnly, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (m *mockContactRepo) ListByProfileID(ctx context.Context, profileID uint64) ([]*entity.Contact, error) {
+	if m.listByProfileIDFn != nil {
+		return m.listByProfileIDFn(ctx, profileID)
+	}
+	return nil, nil
+}
+
+func (m *mockContactRepo) Encrypts(field string) bool {
+	return m.encrypted[field]
+}
+
+// newContactService wires the service to a unit of work that hands out the same repository.
+func newContactService(repo contactRepository) *ContactService {
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Contacts = repo
+	return NewContactService(repo, uow, "", nil)
+}
+
 func TestContactCreateSuccess(t *testing.T) {
 	repo := &mockContactRepo{
 		createFn: func(_ context.Context, contact *entity.Contact) error {
@@ -110,14 +197,14 @@ func TestContactCreateSuccess(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewContactService(repo)
+	svc := newContactService(repo)
 
 	contact, err := svc.Create(context.Background(), mockCreateContactReq{
 		firstName: "John",
 		lastName:  "Doe",
 		nin:       "1234",
 		dob:       "1990-01-02",
-		phone:     "123456",
+		phone:     "+40 722 123 456",
 		profileID: 9,
 		kind:      "friend",
 	})
@@ -129,8 +216,98 @@ func TestContactCreateSuccess(t *testing.T) {
 	}
 }
 
+func TestContactCreateParsesPhoneInRegionOfProfileAddress(t *testing.T) {
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Addresses = &mockAddressRepo{listByProfileIDFn: func(_ context.Context, profileID uint64) ([]*entity.Address, error) {
+		if profileID != 9 {
+			t.Fatalf("unexpected profile id %d", profileID)
+		}
+		return []*entity.Address{{Country: "Romania"}, {Country: "RO"}, {Country: "DE"}}, nil
+	}}
+	svc := NewContactService(uow.repos.Contacts, uow, "DE", nil)
+
+	contact, err := svc.Create(context.Background(), mockCreateContactReq{phone: "0722 123 456", profileID: 9})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if contact.Phone != "0722 123 456" || contact.PhoneE164 != "+40722123456" || contact.PhoneType != "mobile" {
+		t.Fatalf("unexpected phone fields: %+v", contact)
+	}
+}
+
+func TestContactCreateFallsBackToDefaultPhoneRegion(t *testing.T) {
+	uow := newMockUnitOfWork(&mockRepo{})
+	svc := NewContactService(uow.repos.Contacts, uow, "RO", nil)
+
+	contact, err := svc.Create(context.Background(), mockCreateContactReq{phone: "021 312 3456", profileID: 9})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if contact.PhoneE164 != "+40213123456" || contact.PhoneType != "landline" {
+		t.Fatalf("unexpected phone fields: %+v", contact)
+	}
+}
+
+func TestContactCreateInvalidPhone(t *testing.T) {
+	svc := newContactService(&mockContactRepo{createFn: func(_ context.Context, _ *entity.Contact) error {
+		t.Fatal("create must not be called")
+		return nil
+	}})
+
+	// Without a region a national number cannot be parsed.
+	_, err := svc.Create(context.Background(), mockCreateContactReq{phone: "0722 123 456", profileID: 9})
+	if !errors.Is(err, ErrInvalidPhone) {
+		t.Fatalf("expected ErrInvalidPhone, got %v", err)
+	}
+}
+
+func TestContactUpdateKeepsUnchangedPhone(t *testing.T) {
+	var saved *entity.Contact
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, Phone: "111", PhoneE164: "+40111", PhoneType: "other", ProfileID: 7}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	})
+
+	_, err := svc.Update(context.Background(), mockUpdateContactReq{id: 3, firstName: "Jane", phone: "111", profileID: 7})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.PhoneE164 != "+40111" || saved.PhoneType != "other" {
+		t.Fatalf("expected stored phone fields to be kept, got %+v", saved)
+	}
+}
+
+func TestContactPatchClearingPhoneClearsDerivedFields(t *testing.T) {
+	var saved *entity.Contact
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, Phone: "+40722123456", PhoneE164: "+40722123456", PhoneType: "mobile"}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchContactReq{
+		mockUpdateContactReq: mockUpdateContactReq{id: 3},
+		paths:                []string{"phone"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.Phone != "" || saved.PhoneE164 != "" || saved.PhoneType != "" {
+		t.Fatalf("expected phone fields to be cleared, got %+v", saved)
+	}
+}
+
 func TestContactCreateInvalidDOB(t *testing.T) {
-	svc := NewContactService(&mockContactRepo{})
+	svc := newContactService(&mockContactRepo{})
 	_, err := svc.Create(context.Background(), mockCreateContactReq{dob: "1990/01/02"})
 	if err == nil {
 		t.Fatal("expected parse error for invalid dob")
@@ -138,15 +315,15 @@ func TestContactCreateInvalidDOB(t *testing.T) {
 }
 
 func TestContactGetByIDNotFound(t *testing.T) {
-	svc := NewContactService(&mockContactRepo{})
-	_, err := svc.GetByID(context.Background(), 3)
+	svc := newContactService(&mockContactRepo{})
+	_, err := svc.GetByID(context.Background(), 3, false)
 	if !errors.Is(err, ErrContactNotFound) {
 		t.Fatalf("expected ErrContactNotFound, got %v", err)
 	}
 }
 
 func TestContactUpdateNotFound(t *testing.T) {
-	svc := NewContactService(&mockContactRepo{})
+	svc := newContactService(&mockContactRepo{})
 	_, err := svc.Update(context.Background(), mockUpdateContactReq{
 		id:        4,
 		firstName: "John",
@@ -163,13 +340,16 @@ func TestContactUpdateNotFound(t *testing.T) {
 
 func TestContactDeleteRepositoryNotFoundMapped(t *testing.T) {
 	repo := &mockContactRepo{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id}, nil
+		},
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrContactNotFound
 		},
 	}
-	svc := NewContactService(repo)
+	svc := newContactService(repo)
 
-	err := svc.Delete(context.Background(), 10)
+	err := svc.Delete(context.Background(), 10, 0)
 	if !errors.Is(err, ErrContactNotFound) {
 		t.Fatalf("expected ErrContactNotFound, got %v", err)
 	}
@@ -178,14 +358,14 @@ func TestContactDeleteRepositoryNotFoundMapped(t *testing.T) {
 func TestContactListDefaults(t *testing.T) {
 	now := time.Now()
 	repo := &mockContactRepo{
-		listFn: func(_ context.Context, profileID uint64, contactType string, limit, offset uint32) ([]*entity.Contact, uint64, error) {
+		listFn: func(_ context.Context, profileID uint64, contactType, _ string, _ map[string]string, _, _ bool, limit, offset uint32) ([]*entity.Contact, uint64, error) {
 			if profileID != 5 || contactType != "emergency" || limit != 20 || offset != 0 {
 				t.Fatalf("unexpected list args profileID=%d contactType=%q limit=%d offset=%d", profileID, contactType, limit, offset)
 			}
 			return []*entity.Contact{{ID: 1, FirstName: "John", CreatedAt: now, UpdatedAt: now}}, 1, nil
 		},
 	}
-	svc := NewContactService(repo)
+	svc := newContactService(repo)
 
 	result, err := svc.List(context.Background(), mockListContactsReq{profileID: 5, page: 0, pageSize: 0, kind: "emergency"})
 	if err != nil {
@@ -195,3 +375,227 @@ func TestContactListDefaults(t *testing.T) {
 		t.Fatalf("unexpected list result: %+v", result)
 	}
 }
+
+func TestContactListPassesNINFilter(t *testing.T) {
+	repo := &mockContactRepo{
+		listFn: func(_ context.Context, _ uint64, _, nin string, _ map[string]string, _, _ bool, _, _ uint32) ([]*entity.Contact, uint64, error) {
+			if nin != "1900517223344" {
+				t.Fatalf("expected nin filter, got %q", nin)
+			}
+			return []*entity.Contact{}, 0, nil
+		},
+	}
+
+	if _, err := newContactService(repo).List(context.Background(), mockListContactsReq{nin: "1900517223344"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestContactCreateMissingProfileMapped(t *testing.T) {
+	svc := newContactService(&mockContactRepo{
+		createFn: func(_ context.Context, _ *entity.Contact) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Create(context.Background(), mockCreateContactReq{profileID: 404})
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestContactUpdateMissingTargetProfileMapped(t *testing.T) {
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 1}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Contact) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Update(context.Background(), mockUpdateContactReq{id: 3, profileID: 404})
+	if !errors.Is(err, ErrTargetProfileNotFound) {
+		t.Fatalf("expected ErrTargetProfileNotFound, got %v", err)
+	}
+}
+
+func TestContactPatchOnlyMaskedFields(t *testing.T) {
+	dob := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	var saved *entity.Contact
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, FirstName: "John", Phone: "111", DOB: &dob, ProfileID: 7}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchContactReq{
+		mockUpdateContactReq: mockUpdateContactReq{id: 3, phone: "+40722123456"},
+		paths:                []string{"phone", "dob"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.FirstName != "John" || saved.ProfileID != 7 || saved.Phone != "+40722123456" || saved.DOB != nil {
+		t.Fatalf("unexpected patched contact: %+v", saved)
+	}
+}
+
+func TestContactPatchChecksNINAgainstStoredFields(t *testing.T) {
+	dob := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	var saved *entity.Contact
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, NIN: "1800101120017", DOB: &dob}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchContactReq{
+		mockUpdateContactReq: mockUpdateContactReq{id: 3, ninCountry: "RO"},
+		paths:                []string{"nin_country"},
+	})
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "dob" {
+		t.Fatalf("expected dob error, got %v", err)
+	}
+
+	_, err = svc.Patch(context.Background(), mockPatchContactReq{
+		mockUpdateContactReq: mockUpdateContactReq{id: 3, ninCountry: "ro"},
+		paths:                []string{"nin_country", "dob"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.NINCountry != "RO" || saved.DOB == nil || saved.DOB.Format(contactDOBLayout) != "1980-01-01" {
+		t.Fatalf("expected country code and dob from the CNP, got %+v", saved)
+	}
+}
+
+func TestContactPatchUnknownPathRejected(t *testing.T) {
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Contact) error {
+			t.Fatal("update must not be called")
+			return nil
+		},
+	})
+
+	_, err := svc.Patch(context.Background(), mockPatchContactReq{
+		mockUpdateContactReq: mockUpdateContactReq{id: 3},
+		paths:                []string{"created_at"},
+	})
+	if !errors.Is(err, ErrInvalidUpdateMask) {
+		t.Fatalf("expected ErrInvalidUpdateMask, got %v", err)
+	}
+}
+
+func TestContactUpdateStaleExpectedVersion(t *testing.T) {
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, ProfileID: 7, Version: 3}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Contact) error {
+			t.Fatal("expected stale update to be rejected before saving")
+			return nil
+		},
+	})
+
+	_, err := svc.Update(context.Background(), mockUpdateContactReq{id: 5, profileID: 7, expectedVersion: 2})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+}
+
+func TestContactDeleteVersionConflictMapped(t *testing.T) {
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, Version: 5}, nil
+		},
+		deleteFn: func(_ context.Context, _, expectedVersion uint64) error {
+			if expectedVersion != 4 {
+				t.Fatalf("expected version 4 to reach the repository, got %d", expectedVersion)
+			}
+			return repository.ErrVersionConflict
+		},
+	})
+
+	err := svc.Delete(context.Background(), 10, 4)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+}
+
+func TestContactRestoreWhileProfileDeleted(t *testing.T) {
+	svc := newContactService(&mockContactRepo{
+		restoreFn: func(context.Context, uint64) error {
+			return repository.ErrProfileReferenceNotFound
+		},
+	})
+
+	_, err := svc.Restore(context.Background(), 4)
+	if !errors.Is(err, ErrProfileDeleted) {
+		t.Fatalf("expected ErrProfileDeleted, got: %v", err)
+	}
+}
+
+func TestContactUpdateToAnotherProfileDropsPrimaryFlag(t *testing.T) {
+	var saved *entity.Contact
+	svc := newContactService(&mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, FirstName: "Ana", ProfileID: 7, Type: "emergency", IsPrimary: true}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	})
+
+	_, err := svc.Update(context.Background(), mockUpdateContactReq{id: 3, firstName: "Ana", profileID: 8, kind: "emergency"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if saved.ProfileID != 8 || saved.IsPrimary {
+		t.Fatalf("expected the moved contact not to stay primary, got %+v", saved)
+	}
+}
+
+func TestContactUpdateReplacesAttributes(t *testing.T) {
+	var saved *entity.Contact
+	repo := &mockContactRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Contact, error) {
+			return &entity.Contact{ID: id, FirstName: "John", ProfileID: 7, Attributes: map[string]any{"job_title": "CTO", "department": "IT"}}, nil
+		},
+		updateFn: func(_ context.Context, contact *entity.Contact) error {
+			saved = contact
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Contacts = repo
+	registry := newAttributeRegistry(t, `{"contact": {"*": [{"name": "job_title", "type": "string"}, {"name": "department", "type": "string"}]}}`)
+	svc := NewContactService(repo, uow, "", registry)
+
+	title, _ := structpb.NewStruct(map[string]any{"job_title": "CEO"})
+	if _, err := svc.Update(context.Background(), mockUpdateContactReq{id: 3, firstName: "John", profileID: 7, attributes: title}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(saved.Attributes) != 1 || saved.Attributes["job_title"] != "CEO" {
+		t.Fatalf("expected the attributes to be replaced, got %v", saved.Attributes)
+	}
+
+	level, _ := structpb.NewStruct(map[string]any{"job_level": float64(3)})
+	var fieldErr *country.FieldError
+	if _, err := svc.Update(context.Background(), mockUpdateContactReq{id: 3, firstName: "John", profileID: 7, attributes: level}); !errors.As(err, &fieldErr) || fieldErr.Field != "attributes.job_level" {
+		t.Fatalf("expected attributes.job_level field error, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+)
+
+// EventPublisher delivers domain events to downstream systems. Delivery is at least once,
+// so a publisher may see the same event again after a crash or a failed acknowledgement.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *entity.OutboxEvent) error
+}
+
+type outboxRepository interface {
+	Create(ctx context.Context, event *entity.OutboxEvent) error
+}
+
+type outboxRelayRepository interface {
+	ListPending(ctx context.Context, now time.Time, limit uint32) ([]*entity.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id uint64, publishedAt time.Time) error
+	MarkFailed(ctx context.Context, id uint64, nextAttemptAt time.Time, lastError string) error
+}
+
+var outboxEventSuffixes = map[string]string{
+	AuditActionCreate:  "created",
+	AuditActionUpdate:  "updated",
+	AuditActionDelete:  "deleted",
+	AuditActionRestore: "restored",
+}
+
+// outboxPayload is the JSON body of a domain event: the record after the change, or before
+// it for deletes.
+type outboxPayload struct {
+	ID   uint64      `json:"id"`
+	Data auditValues `json:"data"`
+}
+
+// recordChange stores the audit event and the domain event for one change in the
+// transaction behind repos.
+func recordChange(ctx context.Context, repos Repositories, entityType string, entityID uint64, action string, before, after auditValues) error {
+	if err := recordAudit(ctx, repos.Audit, entityType, entityID, action, before, after); err != nil {
+		return err
+	}
+
+	data := after
+	if data == nil {
+		data = before
+	}
+	payload, err := json.Marshal(outboxPayload{ID: entityID, Data: data})
+	if err != nil {
+		return err
+	}
+
+	return repos.Outbox.Create(ctx, &entity.OutboxEvent{
+		AggregateType: entityType,
+		AggregateID:   entityID,
+		EventType:     entityType + "." + outboxEventSuffixes[action],
+		Payload:       payload,
+		CreatedAt:     time.Now(),
+	})
+}
+
+// outboxRelayLock keeps other instances from relaying while one instance holds it.
+type outboxRelayLock interface {
+	TryLock(ctx context.Context) (bool, error)
+	Unlock(ctx context.Context) error
+}
+
+// OutboxRelay hands pending outbox events to the publisher.
+type OutboxRelay struct {
+	repo       outboxRelayRepository
+	lock       outboxRelayLock
+	publisher  EventPublisher
+	batchSize  uint32
+	maxBackoff time.Duration
+}
+
+// NewOutboxRelay returns a relay that only runs a batch while it holds lock; a nil lock relays
+// without coordinating with other instances.
+func NewOutboxRelay(repo outboxRelayRepository, lock outboxRelayLock, publisher EventPublisher, batchSize uint32, maxBackoff time.Duration) *OutboxRelay {
+	if batchSize == 0 {
+		batchSize = 100
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Minute
+	}
+
+	return &OutboxRelay{
+		repo:       repo,
+		lock:       lock,
+		publisher:  publisher,
+		batchSize:  batchSize,
+		maxBackoff: maxBackoff,
+	}
+}
+
+// Relay publishes one batch of pending events in the order they were written and returns how
+// many were published. An event that fails is retried later with exponential backoff; until
+// it goes through, the later events of the same aggregate are held back so that every
+// aggregate's events are published in order. Nothing is published while another instance
+// holds the relay lock.
+func (r *OutboxRelay) Relay(ctx context.Context, now time.Time) (int, error) {
+	if r.lock == nil {
+		return r.relayBatch(ctx, now)
+	}
+
+	acquired, err := r.lock.TryLock(ctx)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	published, err := r.relayBatch(ctx, now)
+	if unlockErr := r.lock.Unlock(context.WithoutCancel(ctx)); err == nil {
+		err = unlockErr
+	}
+
+	return published, err
+}
+
+// BatchSize returns the most events one Relay call publishes; a call that publishes fewer has
+// drained every event that is due.
+func (r *OutboxRelay) BatchSize() int {
+	return int(r.batchSize)
+}
+
+func (r *OutboxRelay) relayBatch(ctx context.Context, now time.Time) (int, error) {
+	events, err := r.repo.ListPending(ctx, now, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blocked := make(map[string]bool)
+	for _, event := range events {
+		aggregate := event.AggregateType + ":" + strconv.FormatUint(event.AggregateID, 10)
+		if blocked[aggregate] {
+			continue
+		}
+		if event.NextAttemptAt.After(now) {
+			blocked[aggregate] = true
+			continue
+		}
+
+		if err = r.publisher.Publish(ctx, event); err != nil {
+			blocked[aggregate] = true
+			if err = r.repo.MarkFailed(ctx, event.ID, now.Add(r.backoff(event.Attempts+1)), err.Error()); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err = r.repo.MarkPublished(ctx, event.ID, now); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// backoff doubles the delay from one second with every failed attempt, up to maxBackoff.
+func (r *OutboxRelay) backoff(attempts uint32) time.Duration {
+	delay := time.Second
+	for i := uint32(1); i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, r.maxBackoff)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
+)
+
+type mockOutboxRepo struct {
+	events []*entity.OutboxEvent
+}
+
+func (m *mockOutboxRepo) Create(_ context.Context, event *entity.OutboxEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+type mockOutboxRelayRepo struct {
+	pending   []*entity.OutboxEvent
+	published []uint64
+	failed    map[uint64]time.Time
+}
+
+func (m *mockOutboxRelayRepo) ListPending(_ context.Context, _ time.Time, limit uint32) ([]*entity.OutboxEvent, error) {
+	if uint32(len(m.pending)) > limit {
+		return m.pending[:limit], nil
+	}
+	return m.pending, nil
+}
+
+func (m *mockOutboxRelayRepo) MarkPublished(_ context.Context, id uint64, _ time.Time) error {
+	m.published = append(m.published, id)
+	return nil
+}
+
+func (m *mockOutboxRelayRepo) MarkFailed(_ context.Context, id uint64, nextAttemptAt time.Time, _ string) error {
+	if m.failed == nil {
+		m.failed = make(map[uint64]time.Time)
+	}
+	m.failed[id] = nextAttemptAt
+	return nil
+}
+
+type publisherFunc func(ctx context.Context, event *entity.OutboxEvent) error
+
+func (f publisherFunc) Publish(ctx context.Context, event *entity.OutboxEvent) error {
+	return f(ctx, event)
+}
+
+func TestCompanyDeleteEnqueuesDomainEvent(t *testing.T) {
+	repo := &mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			return &entity.Company{ID: id, Name: "ACME", ProfileID: 2}, nil
+		},
+	}
+	uow := newMockUnitOfWork(&mockRepo{})
+	uow.repos.Companies = repo
+
+	if err := NewCompanyService(repo, uow, fiscal.NewValidators(""), nil).Delete(context.Background(), 8, 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events := uow.repos.Outbox.(*mockOutboxRepo).events
+	if len(events) != 1 {
+		t.Fatalf("expected one outbox event, got %d", len(events))
+	}
+	event := events[0]
+	if event.AggregateType != AuditEntityCompany || event.AggregateID != 8 || event.EventType != "company.deleted" {
+		t.Fatalf("unexpected outbox event: %+v", event)
+	}
+	var payload struct {
+		ID   uint64         `json:"id"`
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", event.Payload, err)
+	}
+	if payload.ID != 8 || payload.Data["name"] != "ACME" {
+		t.Fatalf("expected the deleted company in the payload, got %s", event.Payload)
+	}
+}
+
+func TestOutboxRelayKeepsAggregateOrderOnFailure(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &mockOutboxRelayRepo{pending: []*entity.OutboxEvent{
+		{ID: 1, AggregateType: "contact", AggregateID: 7, NextAttemptAt: now},
+		{ID: 2, AggregateType: "profile", AggregateID: 7, NextAttemptAt: now},
+		{ID: 3, AggregateType: "contact", AggregateID: 7, NextAttemptAt: now},
+		{ID: 4, AggregateType: "address", AggregateID: 1, NextAttemptAt: now.Add(time.Minute)},
+		{ID: 5, AggregateType: "address", AggregateID: 1, NextAttemptAt: now},
+	}}
+	relay := NewOutboxRelay(repo, nil, publisherFunc(func(_ context.Context, event *entity.OutboxEvent) error {
+		if event.ID == 1 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}), 10, time.Minute)
+
+	published, err := relay.Relay(context.Background(), now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if published != 1 || len(repo.published) != 1 || repo.published[0] != 2 {
+		t.Fatalf("expected only event 2 to be published, got %v", repo.published)
+	}
+	if next, ok := repo.failed[1]; !ok || !next.Equal(now.Add(time.Second)) {
+		t.Fatalf("expected event 1 to be retried after one second, got %v", repo.failed)
+	}
+}
+
+type mockOutboxRelayLock struct {
+	held     bool
+	unlocked int
+}
+
+func (m *mockOutboxRelayLock) TryLock(context.Context) (bool, error) {
+	return !m.held, nil
+}
+
+func (m *mockOutboxRelayLock) Unlock(context.Context) error {
+	m.unlocked++
+	return nil
+}
+
+func TestOutboxRelayWaitsForTheLock(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &mockOutboxRelayRepo{pending: []*entity.OutboxEvent{{ID: 1, AggregateType: "contact", AggregateID: 7, NextAttemptAt: now}}}
+	lock := &mockOutboxRelayLock{held: true}
+	relay := NewOutboxRelay(repo, lock, publisherFunc(func(context.Context, *entity.OutboxEvent) error { return nil }), 10, time.Minute)
+
+	if published, err := relay.Relay(context.Background(), now); err != nil || published != 0 || len(repo.published) != 0 {
+		t.Fatalf("expected nothing published while another relay holds the lock, got %d, %v", published, err)
+	}
+
+	lock.held = false
+	if published, err := relay.Relay(context.Background(), now); err != nil || published != 1 {
+		t.Fatalf("expected the event to be published, got %d, %v", published, err)
+	}
+	if lock.unlocked != 1 {
+		t.Fatalf("expected the lock to be released once, got %d", lock.unlocked)
+	}
+}
+
+func TestOutboxRelayBackoffIsCapped(t *testing.T) {
+	relay := NewOutboxRelay(&mockOutboxRelayRepo{}, nil, nil, 0, 30*time.Second)
+
+	cases := map[uint32]time.Duration{1: time.Second, 2: 2 * time.Second, 5: 16 * time.Second, 6: 30 * time.Second, 40: 30 * time.Second}
+	for attempts, want := range cases {
+		if got := relay.backoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/jsonschema"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var (
+	ErrPreferenceNotFound = errors.New("preference not found")
+)
+
+type preferenceRepository interface {
+	Save(ctx context.Context, preference *entity.Preference) error
+	Delete(ctx context.Context, profileID uint64, namespace, key string) error
+	List(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error)
+	ListForUpdate(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error)
+}
+
+type getPreferencesRequest interface {
+	GetProfileId() uint64
+	GetNamespace() string
+}
+
+type setPreferencesRequest interface {
+	GetProfileId() uint64
+	GetNamespace() string
+	GetValues() *structpb.Struct
+}
+
+type deletePreferenceRequest interface {
+	GetProfileId() uint64
+	GetNamespace() string
+	GetKey() string
+}
+
+// PreferenceNamespace holds the preferences a profile keeps under one namespace.
+type PreferenceNamespace struct {
+	Namespace string
+	// Values maps each key to its value as decoded from JSON.
+	Values map[string]any
+	// UpdatedAt is when a key of the namespace last changed; zero for an empty namespace.
+	UpdatedAt time.Time
+}
+
+// PreferenceService keeps the namespaced settings of profiles. A namespace with a schema only
+// ever holds values that match it, as one object of its keys.
+type PreferenceService struct {
+	uow     UnitOfWork
+	schemas map[string]*jsonschema.Schema
+}
+
+func NewPreferenceService(uow UnitOfWork, schemas map[string]*jsonschema.Schema) *PreferenceService {
+	return &PreferenceService{uow: uow, schemas: schemas}
+}
+
+// Get returns the namespaces of a live profile, or only the requested one.
+func (s *PreferenceService) Get(ctx context.Context, req getPreferencesRequest) ([]*PreferenceNamespace, error) {
+	var namespaces []*PreferenceNamespace
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := checkLiveProfile(ctx, repos, req.GetProfileId()); err != nil {
+			return err
+		}
+
+		preferences, err := repos.Preferences.List(ctx, req.GetProfileId(), req.GetNamespace())
+		if err != nil {
+			return err
+		}
+		namespaces, err = groupPreferences(preferences)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
+// Set writes every key of the request into its namespace at once; a null value removes the
+// key. The namespace as a whole must still match its schema afterwards.
+func (s *PreferenceService) Set(ctx context.Context, req setPreferencesRequest) (*PreferenceNamespace, error) {
+	profileID, namespace := req.GetProfileId(), req.GetNamespace()
+	var result *PreferenceNamespace
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := lockLiveProfile(ctx, repos, profileID); err != nil {
+			return err
+		}
+		current, err := repos.Preferences.ListForUpdate(ctx, profileID, namespace)
+		if err != nil {
+			return err
+		}
+		before, err := preferenceValues(current)
+		if err != nil {
+			return err
+		}
+
+		after := make(map[string]any, len(before))
+		for key, value := range before {
+			after[key] = value
+		}
+		now := time.Now()
+		var saved []*entity.Preference
+		var removed []string
+		for key, value := range req.GetValues().GetFields() {
+			if _, isNull := value.GetKind().(*structpb.Value_NullValue); isNull {
+				if _, ok := before[key]; ok {
+					removed = append(removed, key)
+				}
+				delete(after, key)
+				continue
+			}
+
+			raw, err := json.Marshal(value.AsInterface())
+			if err != nil {
+				return err
+			}
+			after[key] = value.AsInterface()
+			saved = append(saved, &entity.Preference{
+				ProfileID: profileID,
+				Namespace: namespace,
+				Key:       key,
+				Value:     raw,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+		}
+		if err = s.checkSchema(namespace, after); err != nil {
+			return err
+		}
+
+		for _, preference := range saved {
+			if err = repos.Preferences.Save(ctx, preference); err != nil {
+				if errors.Is(err, repository.ErrProfileReferenceNotFound) {
+					return ErrProfileNotFound
+				}
+				return err
+			}
+		}
+		for _, key := range removed {
+			if err = repos.Preferences.Delete(ctx, profileID, namespace, key); err != nil {
+				return err
+			}
+		}
+		if err = recordPreferenceChange(ctx, repos, profileID, namespace, before, after); err != nil {
+			return err
+		}
+
+		stored, err := repos.Preferences.List(ctx, profileID, namespace)
+		if err != nil {
+			return err
+		}
+		namespaces, err := groupPreferences(stored)
+		if err != nil {
+			return err
+		}
+		result = &PreferenceNamespace{Namespace: namespace, Values: map[string]any{}}
+		if len(namespaces) > 0 {
+			result = namespaces[0]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete removes one key. A key the namespace's schema requires cannot be removed.
+func (s *PreferenceService) Delete(ctx context.Context, req deletePreferenceRequest) error {
+	profileID, namespace, key := req.GetProfileId(), req.GetNamespace(), req.GetKey()
+	return s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := lockLiveProfile(ctx, repos, profileID); err != nil {
+			return err
+		}
+		current, err := repos.Preferences.ListForUpdate(ctx, profileID, namespace)
+		if err != nil {
+			return err
+		}
+		before, err := preferenceValues(current)
+		if err != nil {
+			return err
+		}
+		if _, ok := before[key]; !ok {
+			return ErrPreferenceNotFound
+		}
+
+		after := make(map[string]any, len(before))
+		for name, value := range before {
+			if name != key {
+				after[name] = value
+			}
+		}
+		if err = s.checkSchema(namespace, after); err != nil {
+			return err
+		}
+
+		if err = repos.Preferences.Delete(ctx, profileID, namespace, key); err != nil {
+			if errors.Is(err, repository.ErrPreferenceNotFound) {
+				return ErrPreferenceNotFound
+			}
+			return err
+		}
+
+		return recordPreferenceChange(ctx, repos, profileID, namespace, before, after)
+	})
+}
+
+// checkSchema reports values that do not match the namespace's schema as a field error on
+// the key at fault. Namespaces without a schema take any value.
+func (s *PreferenceService) checkSchema(namespace string, values map[string]any) error {
+	schema, ok := s.schemas[namespace]
+	if !ok {
+		return nil
+	}
+
+	err := schema.Validate(values)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	field := "values"
+	if validationErr.Path != "" {
+		field += "." + validationErr.Path
+	}
+
+	return &country.FieldError{Field: field, Message: validationErr.Error()}
+}
+
+func checkLiveProfile(ctx context.Context, repos Repositories, profileID uint64) error {
+	profile, err := repos.Profiles.FindByID(ctx, profileID, false)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return ErrProfileNotFound
+	}
+
+	return nil
+}
+
+// lockLiveProfile is checkLiveProfile for writes: the profile's row stays locked until the
+// transaction ends, so that two writes to its preferences never check a namespace's schema
+// against the same stale copy, whatever the isolation level.
+func lockLiveProfile(ctx context.Context, repos Repositories, profileID uint64) error {
+	profile, err := repos.Profiles.FindByIDForUpdate(ctx, profileID)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return ErrProfileNotFound
+	}
+
+	return nil
+}
+
+// recordPreferenceChange audits a write to one namespace under the profile's id.
+func recordPreferenceChange(ctx context.Context, repos Repositories, profileID uint64, namespace string, before, after map[string]any) error {
+	return recordChange(ctx, repos, AuditEntityPreference, profileID, AuditActionUpdate,
+		auditValues{"namespace": namespace, "values": before},
+		auditValues{"namespace": namespace, "values": after},
+	)
+}
+
+func preferenceValues(preferences []*entity.Preference) (map[string]any, error) {
+	values := make(map[string]any, len(preferences))
+	for _, preference := range preferences {
+		var value any
+		if err := json.Unmarshal(preference.Value, &value); err != nil {
+			return nil, err
+		}
+		values[preference.Key] = value
+	}
+
+	return values, nil
+}
+
+// groupPreferences folds preferences ordered by namespace into one entry per namespace.
+func groupPreferences(preferences []*entity.Preference) ([]*PreferenceNamespace, error) {
+	namespaces := make([]*PreferenceNamespace, 0)
+	var current *PreferenceNamespace
+	for _, preference := range preferences {
+		if current == nil || current.Namespace != preference.Namespace {
+			current = &PreferenceNamespace{Namespace: preference.Namespace, Values: map[string]any{}}
+			namespaces = append(namespaces, current)
+		}
+
+		var value any
+		if err := json.Unmarshal(preference.Value, &value); err != nil {
+			return nil, err
+		}
+		current.Values[preference.Key] = value
+		if preference.UpdatedAt.After(current.UpdatedAt) {
+			current.UpdatedAt = preference.UpdatedAt
+		}
+	}
+
+	return namespaces, nil
+}
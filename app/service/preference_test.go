@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/jsonschema"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// mockPreferenceRepo keeps preferences in memory, keyed by profile, namespace and key.
+type mockPreferenceRepo struct {
+	preferences map[string]*entity.Preference
+}
+
+func newMockPreferenceRepo() *mockPreferenceRepo {
+	return &mockPreferenceRepo{preferences: map[string]*entity.Preference{}}
+}
+
+func preferenceKey(profileID uint64, namespace, key string) string {
+	return fmt.Sprintf("%d/%s/%s", profileID, namespace, key)
+}
+
+func (m *mockPreferenceRepo) Save(_ context.Context, preference *entity.Preference) error {
+	stored := *preference
+	m.preferences[preferenceKey(preference.ProfileID, preference.Namespace, preference.Key)] = &stored
+	return nil
+}
+
+func (m *mockPreferenceRepo) Delete(_ context.Context, profileID uint64, namespace, key string) error {
+	id := preferenceKey(profileID, namespace, key)
+	if _, ok := m.preferences[id]; !ok {
+		return repository.ErrPreferenceNotFound
+	}
+	delete(m.preferences, id)
+	return nil
+}
+
+func (m *mockPreferenceRepo) List(_ context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	preferences := make([]*entity.Preference, 0)
+	for _, preference := range m.preferences {
+		if preference.ProfileID == profileID && (namespace == "" || preference.Namespace == namespace) {
+			preferences = append(preferences, preference)
+		}
+	}
+	sort.Slice(preferences, func(i, j int) bool {
+		if preferences[i].Namespace != preferences[j].Namespace {
+			return preferences[i].Namespace < preferences[j].Namespace
+		}
+		return preferences[i].Key < preferences[j].Key
+	})
+	return preferences, nil
+}
+
+func (m *mockPreferenceRepo) ListForUpdate(ctx context.Context, profileID uint64, namespace string) ([]*entity.Preference, error) {
+	return m.List(ctx, profileID, namespace)
+}
+
+type mockGetPreferencesReq struct {
+	profileID uint64
+	namespace string
+}
+
+func (r mockGetPreferencesReq) GetProfileId() uint64 { return r.profileID }
+func (r mockGetPreferencesReq) GetNamespace() string { return r.namespace }
+
+type mockSetPreferencesReq struct {
+	profileID uint64
+	namespace string
+	values    *structpb.Struct
+}
+
+func (r mockSetPreferencesReq) GetProfileId() uint64        { return r.profileID }
+func (r mockSetPreferencesReq) GetNamespace() string        { return r.namespace }
+func (r mockSetPreferencesReq) GetValues() *structpb.Struct { return r.values }
+
+type mockDeletePreferenceReq struct {
+	profileID uint64
+	namespace string
+	key       string
+}
+
+func (r mockDeletePreferenceReq) GetProfileId() uint64 { return r.profileID }
+func (r mockDeletePreferenceReq) GetNamespace() string { return r.namespace }
+func (r mockDeletePreferenceReq) GetKey() string       { return r.key }
+
+// newPreferenceService serves profile 7, with a schema on the "locale" namespace that requires
+// a two-letter language.
+func newPreferenceService(t *testing.T) (*PreferenceService, *mockUnitOfWork) {
+	t.Helper()
+	schema, err := jsonschema.Compile([]byte(`{
+		"type": "object",
+		"required": ["language"],
+		"properties": {"language": {"type": "string", "pattern": "^[a-z]{2}$"}}
+	}`))
+	if err != nil {
+		t.Fatalf("Compile() returned error: %v", err)
+	}
+	uow := newMockUnitOfWork(&mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			if id != 7 {
+				return nil, nil
+			}
+			return &entity.Profile{ID: id}, nil
+		},
+	})
+
+	return NewPreferenceService(uow, map[string]*jsonschema.Schema{"locale": schema}), uow
+}
+
+func preferenceStruct(t *testing.T, values map[string]any) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(values)
+	if err != nil {
+		t.Fatalf("NewStruct() returned error: %v", err)
+	}
+	return s
+}
+
+func TestPreferenceSetMergesAndRemovesNullKeys(t *testing.T) {
+	svc, uow := newPreferenceService(t)
+	ctx := context.Background()
+
+	if _, err := svc.Set(ctx, mockSetPreferencesReq{profileID: 7, namespace: "billing", values: preferenceStruct(t, map[string]any{"currency": "RON", "invoice_day": 5})}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	namespace, err := svc.Set(ctx, mockSetPreferencesReq{profileID: 7, namespace: "billing", values: preferenceStruct(t, map[string]any{"invoice_day": nil, "paper": true})})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(namespace.Values) != 2 || namespace.Values["currency"] != "RON" || namespace.Values["paper"] != true {
+		t.Fatalf("unexpected values: %v", namespace.Values)
+	}
+	if namespace.UpdatedAt.IsZero() {
+		t.Fatal("expected updated_at to be set")
+	}
+
+	events := uow.repos.Audit.(*mockAuditRepo).events
+	if len(events) != 2 || events[1].EntityType != AuditEntityPreference || events[1].EntityID != 7 {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+	var before map[string]any
+	if err = json.Unmarshal(events[1].OldValues, &before); err != nil {
+		t.Fatalf("unexpected old values: %v", err)
+	}
+	if before["namespace"] != "billing" || before["values"].(map[string]any)["invoice_day"] != float64(5) {
+		t.Fatalf("unexpected old values: %v", before)
+	}
+}
+
+func TestPreferenceSetRejectsSchemaViolation(t *testing.T) {
+	svc, uow := newPreferenceService(t)
+
+	_, err := svc.Set(context.Background(), mockSetPreferencesReq{profileID: 7, namespace: "locale", values: preferenceStruct(t, map[string]any{"language": "romanian"})})
+	var fieldErr *country.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "values.language" {
+		t.Fatalf("expected a field error on values.language, got %v", err)
+	}
+	if stored, _ := uow.repos.Preferences.List(context.Background(), 7, "locale"); len(stored) != 0 {
+		t.Fatalf("expected nothing stored, got %+v", stored)
+	}
+}
+
+func TestPreferenceWritesLockTheProfile(t *testing.T) {
+	svc, uow := newPreferenceService(t)
+	ctx := context.Background()
+	var locked []uint64
+	uow.repos.Profiles.(*mockRepo).findByIDForUpdateFn = func(_ context.Context, id uint64) (*entity.Profile, error) {
+		locked = append(locked, id)
+		return &entity.Profile{ID: id}, nil
+	}
+
+	if _, err := svc.Set(ctx, mockSetPreferencesReq{profileID: 7, namespace: "billing", values: preferenceStruct(t, map[string]any{"currency": "RON"})}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.Delete(ctx, mockDeletePreferenceReq{profileID: 7, namespace: "billing", key: "currency"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.Get(ctx, mockGetPreferencesReq{profileID: 7}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(locked) != 2 || locked[0] != 7 || locked[1] != 7 {
+		t.Fatalf("expected Set and Delete to lock profile 7, got %v", locked)
+	}
+}
+
+func TestPreferenceSetUnknownProfile(t *testing.T) {
+	svc, _ := newPreferenceService(t)
+
+	_, err := svc.Set(context.Background(), mockSetPreferencesReq{profileID: 404, namespace: "billing", values: preferenceStruct(t, map[string]any{"currency": "RON"})})
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestPreferenceDelete(t *testing.T) {
+	svc, _ := newPreferenceService(t)
+	ctx := context.Background()
+	if _, err := svc.Set(ctx, mockSetPreferencesReq{profileID: 7, namespace: "locale", values: preferenceStruct(t, map[string]any{"language": "ro", "timezone": "Europe/Bucharest"})}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, mockDeletePreferenceReq{profileID: 7, namespace: "locale", key: "currency"}); !errors.Is(err, ErrPreferenceNotFound) {
+		t.Fatalf("expected ErrPreferenceNotFound, got %v", err)
+	}
+	var fieldErr *country.FieldError
+	if err := svc.Delete(ctx, mockDeletePreferenceReq{profileID: 7, namespace: "locale", key: "language"}); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected removing a required key to fail, got %v", err)
+	}
+	if err := svc.Delete(ctx, mockDeletePreferenceReq{profileID: 7, namespace: "locale", key: "timezone"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	namespaces, err := svc.Get(ctx, mockGetPreferencesReq{profileID: 7})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(namespaces) != 1 || len(namespaces[0].Values) != 1 || namespaces[0].Values["language"] != "ro" {
+		t.Fatalf("unexpected namespaces: %+v", namespaces)
+	}
+}
@@ -2,16 +2,37 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/phone"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 var (
 	ErrProfileNotFound      = errors.New("profile not found")
 	ErrProfileAlreadyExists = errors.New("profile already exists for this user")
+	// ErrTargetProfileNotFound is returned when an update moves a record to a missing profile.
+	ErrTargetProfileNotFound = errors.New("target profile does not exist")
+	// ErrInvalidUpdateMask is returned when a patch names a field that cannot be changed.
+	ErrInvalidUpdateMask = errors.New("invalid update mask")
+	// ErrVersionConflict is returned when a write expects a version the record no longer has.
+	ErrVersionConflict = errors.New("record version does not match")
+	// ErrNotDeleted is returned when restoring a record that is not soft-deleted.
+	ErrNotDeleted = errors.New("record is not deleted")
+	// ErrProfileDeleted is returned when restoring a child record of a soft-deleted profile.
+	ErrProfileDeleted = errors.New("profile is deleted")
+	// ErrProfileEmailTaken is returned, when emails are unique, if another live profile
+	// already uses the email.
+	ErrProfileEmailTaken = errors.New("email is already used by another profile")
+	// ErrPrimaryConflict is returned when another record became primary for the same profile
+	// and type at the same time.
+	ErrPrimaryConflict = errors.New("another record of this type is already primary")
 )
 
 type createProfileRequest interface {
@@ -19,36 +40,80 @@ type createProfileRequest interface {
 	GetEmail() string
 }
 
+type getProfileBundleRequest interface {
+	GetId() uint64
+	GetInclude() []string
+}
+
 type updateProfileRequest interface {
 	GetId() uint64
 	GetEmail() string
+	GetExpectedVersion() uint64
+}
+
+type patchProfileRequest interface {
+	updateProfileRequest
+	GetUpdateMask() *fieldmaskpb.FieldMask
+}
+
+const (
+	bundleIncludeContacts  = "contacts"
+	bundleIncludeAddresses = "addresses"
+	bundleIncludeCompanies = "companies"
+)
+
+// ProfileBundle is a profile with the child collections requested by the caller.
+// Collections that were not requested are nil.
+type ProfileBundle struct {
+	Profile   *entity.Profile
+	Contacts  []*entity.Contact
+	Addresses []*entity.Address
+	Companies []*entity.Company
+}
+
+// ProfileChildren carries the optional records created together with a new profile.
+// Their profile_id is ignored and set to the id of the created profile.
+type ProfileChildren struct {
+	Contact createContactRequest
+	Address createAddressRequest
 }
 
 type ProfileService struct {
 	profileRepo profileRepository
+	uow         UnitOfWork
+	uniqueEmail bool
+	phoneRegion string
+	attributes  *attribute.Registry
 }
 
 type profileRepository interface {
 	Create(ctx context.Context, profile *entity.Profile) error
-	FindByID(ctx context.Context, id uint64) (*entity.Profile, error)
-	FindByUserID(ctx context.Context, userID uint64) (*entity.Profile, error)
+	FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error)
+	FindByIDForUpdate(ctx context.Context, id uint64) (*entity.Profile, error)
+	FindByUserID(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error)
+	FindByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error)
+	FindByEmailForUpdate(ctx context.Context, email string) (*entity.Profile, error)
 	Update(ctx context.Context, profile *entity.Profile) error
-	Delete(ctx context.Context, id uint64) error
+	Delete(ctx context.Context, id uint64, expectedVersion uint64) error
+	Restore(ctx context.Context, id uint64) error
 }
 
-func NewProfileService(profileRepo profileRepository) *ProfileService {
-	return &ProfileService{profileRepo: profileRepo}
+// NewProfileService creates the service. With uniqueEmail set, no two live profiles may
+// share an email: each write locks the email's row in profile_email_locks before checking it,
+// so of two concurrent writes of the same email only the first succeeds. phoneRegion is
+// used as in NewContactService for a contact created with the profile, and attributes checks
+// the custom attributes of the contact and address created with it.
+func NewProfileService(profileRepo profileRepository, uow UnitOfWork, uniqueEmail bool, phoneRegion string, attributes *attribute.Registry) *ProfileService {
+	return &ProfileService{profileRepo: profileRepo, uow: uow, uniqueEmail: uniqueEmail, phoneRegion: phoneRegion, attributes: attributes}
 }
 
 func (s *ProfileService) Create(ctx context.Context, req createProfileRequest) (*entity.Profile, error) {
-	existing, err := s.profileRepo.FindByUserID(ctx, req.GetUserId())
-	if err != nil {
-		return nil, err
-	}
-	if existing != nil {
-		return nil, ErrProfileAlreadyExists
-	}
+	return s.CreateWithChildren(ctx, req, ProfileChildren{})
+}
 
+// CreateWithChildren creates the profile and any initial contact/address in one
+// transaction, so either all of them are stored or none is.
+func (s *ProfileService) CreateWithChildren(ctx context.Context, req createProfileRequest, children ProfileChildren) (*entity.Profile, error) {
 	now := time.Now()
 	profile := &entity.Profile{
 		UserID:    req.GetUserId(),
@@ -57,18 +122,147 @@ func (s *ProfileService) Create(ctx context.Context, req createProfileRequest) (
 		UpdatedAt: now,
 	}
 
-	if err := s.profileRepo.Create(ctx, profile); err != nil {
-		if errors.Is(err, repository.ErrProfileAlreadyExists) {
-			return nil, ErrProfileAlreadyExists
+	var contact *entity.Contact
+	if children.Contact != nil {
+		var err error
+		if contact, err = newContactEntity(children.Contact, now); err != nil {
+			return nil, err
+		}
+		if err = s.attributes.Validate(attribute.EntityContact, contact.Type, contact.Attributes); err != nil {
+			return nil, childFieldError("contact", err)
 		}
+	}
+	var address *entity.Address
+	if children.Address != nil {
+		address = newAddressEntity(children.Address, now)
+		if err := s.attributes.Validate(attribute.EntityAddress, address.Type, address.Attributes); err != nil {
+			return nil, childFieldError("address", err)
+		}
+	}
+	if contact != nil {
+		// The profile is new, so the only address that can give the phone its region is the one
+		// created with it.
+		region := s.phoneRegion
+		if address != nil && phone.IsRegion(address.Country) {
+			region = address.Country
+		}
+		if err := setContactPhone(contact, region); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		// A soft-deleted profile still holds its user_id until it is purged.
+		existing, err := repos.Profiles.FindByUserID(ctx, req.GetUserId(), true)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return ErrProfileAlreadyExists
+		}
+		if err = s.ensureEmailAvailable(ctx, repos, profile); err != nil {
+			return err
+		}
+
+		if err = repos.Profiles.Create(ctx, profile); err != nil {
+			if errors.Is(err, repository.ErrProfileAlreadyExists) {
+				return ErrProfileAlreadyExists
+			}
+			return err
+		}
+		if err = recordChange(ctx, repos, AuditEntityProfile, profile.ID, AuditActionCreate, nil, profileAuditValues(profile)); err != nil {
+			return err
+		}
+
+		if contact != nil {
+			contact.ProfileID = profile.ID
+			if err = repos.Contacts.Create(ctx, contact); err != nil {
+				return err
+			}
+			if err = recordChange(ctx, repos, AuditEntityContact, contact.ID, AuditActionCreate, nil, contactAuditValues(contact, repos.Contacts.Encrypts)); err != nil {
+				return err
+			}
+		}
+		if address != nil {
+			address.ProfileID = profile.ID
+			if err = repos.Addresses.Create(ctx, address); err != nil {
+				return err
+			}
+			if err = recordChange(ctx, repos, AuditEntityAddress, address.ID, AuditActionCreate, nil, addressAuditValues(address)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return profile, nil
 }
 
-func (s *ProfileService) GetByID(ctx context.Context, id uint64) (*entity.Profile, error) {
-	profile, err := s.profileRepo.FindByID(ctx, id)
+// GetByID returns the profile; soft-deleted profiles are only returned with includeDeleted.
+func (s *ProfileService) GetByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
+	profile, err := s.profileRepo.FindByID(ctx, id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrProfileNotFound
+	}
+	return profile, nil
+}
+
+// GetBundle loads the profile and the requested child collections (all of them when
+// none is requested) inside one read-only repeatable-read transaction, so every
+// collection reflects the same snapshot.
+func (s *ProfileService) GetBundle(ctx context.Context, req getProfileBundleRequest) (*ProfileBundle, error) {
+	include := make(map[string]bool, 3)
+	for _, name := range req.GetInclude() {
+		include[name] = true
+	}
+	all := len(include) == 0
+
+	bundle := &ProfileBundle{}
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := s.uow.Do(ctx, opts, func(ctx context.Context, repos Repositories) error {
+		profile, err := repos.Profiles.FindByID(ctx, req.GetId(), false)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return ErrProfileNotFound
+		}
+		bundle.Profile = profile
+
+		if all || include[bundleIncludeContacts] {
+			if bundle.Contacts, err = repos.Contacts.ListByProfileID(ctx, profile.ID); err != nil {
+				return err
+			}
+		}
+		if all || include[bundleIncludeAddresses] {
+			if bundle.Addresses, err = repos.Addresses.ListByProfileID(ctx, profile.ID); err != nil {
+				return err
+			}
+		}
+		if all || include[bundleIncludeCompanies] {
+			if bundle.Companies, err = repos.Companies.ListForProfile(ctx, profile.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+func (s *ProfileService) GetByUserID(ctx context.Context, userId uint64, includeDeleted bool) (*entity.Profile, error) {
+	profile, err := s.profileRepo.FindByUserID(ctx, userId, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -78,8 +272,9 @@ func (s *ProfileService) GetByID(ctx context.Context, id uint64) (*entity.Profil
 	return profile, nil
 }
 
-func (s *ProfileService) GetByUserID(ctx context.Context, userId uint64) (*entity.Profile, error) {
-	profile, err := s.profileRepo.FindByUserID(ctx, userId)
+// GetByEmail returns the oldest profile with the given, already normalized, email.
+func (s *ProfileService) GetByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error) {
+	profile, err := s.profileRepo.FindByEmail(ctx, email, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -90,32 +285,300 @@ func (s *ProfileService) GetByUserID(ctx context.Context, userId uint64) (*entit
 }
 
 func (s *ProfileService) Update(ctx context.Context, req updateProfileRequest) (*entity.Profile, error) {
-	profile, err := s.profileRepo.FindByID(ctx, req.GetId())
+	profile, err := s.profileRepo.FindByID(ctx, req.GetId(), false)
 	if err != nil {
 		return nil, err
 	}
 	if profile == nil {
 		return nil, ErrProfileNotFound
 	}
+	if expected := req.GetExpectedVersion(); expected != 0 && profile.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := profileAuditValues(profile)
 
 	profile.Email = req.GetEmail()
-	if err := s.profileRepo.Update(ctx, profile); err != nil {
-		if errors.Is(err, repository.ErrProfileNotFound) {
-			return nil, ErrProfileNotFound
+
+	return s.save(ctx, profile, before)
+}
+
+// Patch changes only the fields named in the request's update mask.
+func (s *ProfileService) Patch(ctx context.Context, req patchProfileRequest) (*entity.Profile, error) {
+	profile, err := s.profileRepo.FindByID(ctx, req.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrProfileNotFound
+	}
+	if expected := req.GetExpectedVersion(); expected != 0 && profile.Version != expected {
+		return nil, ErrVersionConflict
+	}
+	before := profileAuditValues(profile)
+
+	for _, path := range req.GetUpdateMask().GetPaths() {
+		switch path {
+		case "email":
+			profile.Email = req.GetEmail()
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidUpdateMask, path)
+		}
+	}
+
+	return s.save(ctx, profile, before)
+}
+
+func (s *ProfileService) save(ctx context.Context, profile *entity.Profile, before auditValues) (*entity.Profile, error) {
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		if err := s.ensureEmailAvailable(ctx, repos, profile); err != nil {
+			return err
+		}
+		if err := repos.Profiles.Update(ctx, profile); err != nil {
+			if errors.Is(err, repository.ErrProfileNotFound) {
+				return ErrProfileNotFound
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return ErrVersionConflict
+			}
+			return err
 		}
+		return recordChange(ctx, repos, AuditEntityProfile, profile.ID, AuditActionUpdate, before, profileAuditValues(profile))
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return profile, nil
 }
 
-func (s *ProfileService) Delete(ctx context.Context, id uint64) error {
-	if err := s.profileRepo.Delete(ctx, id); err != nil {
-		if errors.Is(err, repository.ErrProfileNotFound) {
+// Delete soft-deletes the profile together with its contacts, addresses and the companies it
+// owns alone and the addresses of those companies; a non-zero expectedVersion only deletes that
+// version of the profile. Companies with another owner stay and only lose the profile as a member.
+// Every deleted child gets its own audit event.
+func (s *ProfileService) Delete(ctx context.Context, id uint64, expectedVersion uint64) error {
+	return s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		profile, err := repos.Profiles.FindByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return ErrProfileNotFound
+		}
+		if err = leaveCompanies(ctx, repos, id); err != nil {
+			return err
+		}
+		children, err := loadProfileChildren(ctx, repos, id)
+		if err != nil {
+			return err
+		}
+
+		if err = repos.Profiles.Delete(ctx, id, expectedVersion); err != nil {
+			if errors.Is(err, repository.ErrProfileNotFound) {
+				return ErrProfileNotFound
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return ErrVersionConflict
+			}
+			return err
+		}
+
+		if err = repos.Contacts.DeleteByProfileID(ctx, id); err != nil {
+			return err
+		}
+		if err = repos.Addresses.DeleteByProfileID(ctx, id); err != nil {
+			return err
+		}
+		if err = repos.Companies.DeleteByProfileID(ctx, id); err != nil {
+			return err
+		}
+		for _, company := range children.companies {
+			if err = repos.Addresses.DeleteByCompanyID(ctx, company.ID); err != nil {
+				return err
+			}
+		}
+
+		if err = recordChange(ctx, repos, AuditEntityProfile, id, AuditActionDelete, profileAuditValues(profile), nil); err != nil {
+			return err
+		}
+		return children.record(ctx, repos, AuditActionDelete, nil)
+	})
+}
+
+// Restore undeletes the profile and the child records that were deleted with it.
+// Children deleted on their own before the profile stay deleted.
+func (s *ProfileService) Restore(ctx context.Context, id uint64) (*entity.Profile, error) {
+	var restored *entity.Profile
+	err := s.uow.Do(ctx, nil, func(ctx context.Context, repos Repositories) error {
+		profile, err := repos.Profiles.FindByID(ctx, id, true)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
 			return ErrProfileNotFound
 		}
+		if profile.DeletedAt == nil {
+			return ErrNotDeleted
+		}
+		if err = s.ensureEmailAvailable(ctx, repos, profile); err != nil {
+			return err
+		}
+
+		if err = repos.Profiles.Restore(ctx, id); err != nil {
+			if errors.Is(err, repository.ErrProfileNotFound) {
+				return ErrProfileNotFound
+			}
+			if errors.Is(err, repository.ErrNotDeleted) {
+				return ErrNotDeleted
+			}
+			return err
+		}
+
+		alive, err := loadProfileChildren(ctx, repos, id)
+		if err != nil {
+			return err
+		}
+
+		deletedSince := *profile.DeletedAt
+		if err = repos.Contacts.RestoreByProfileID(ctx, id, deletedSince); err != nil {
+			return err
+		}
+		if err = repos.Addresses.RestoreByProfileID(ctx, id, deletedSince); err != nil {
+			return err
+		}
+		if err = repos.Companies.RestoreByProfileID(ctx, id, deletedSince); err != nil {
+			if errors.Is(err, repository.ErrCompanyFiscalCodeTaken) {
+				return ErrCompanyFiscalCodeTaken
+			}
+			return err
+		}
+		companies, err := repos.Companies.ListByProfileID(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, company := range companies {
+			if err = repos.Addresses.RestoreByCompanyID(ctx, company.ID, deletedSince); err != nil {
+				return err
+			}
+		}
+
+		if restored, err = repos.Profiles.FindByID(ctx, id, false); err != nil {
+			return err
+		}
+		if restored == nil {
+			return ErrProfileNotFound
+		}
+		if err = recordChange(ctx, repos, AuditEntityProfile, id, AuditActionRestore, nil, profileAuditValues(restored)); err != nil {
+			return err
+		}
+
+		children, err := loadProfileChildren(ctx, repos, id)
+		if err != nil {
+			return err
+		}
+		return children.record(ctx, repos, AuditActionRestore, alive)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// ensureEmailAvailable fails with ErrProfileEmailTaken when emails are unique and a live
+// profile other than profile already has its email. The email stays locked until the
+// transaction ends, so two concurrent writes of the same email cannot both pass.
+func (s *ProfileService) ensureEmailAvailable(ctx context.Context, repos Repositories, profile *entity.Profile) error {
+	if !s.uniqueEmail {
+		return nil
+	}
+	existing, err := repos.Profiles.FindByEmailForUpdate(ctx, profile.Email)
+	if err != nil {
 		return err
 	}
+	if existing != nil && existing.ID != profile.ID {
+		return ErrProfileEmailTaken
+	}
+	return nil
+}
+
+// profileChildren holds the live child records of one profile, counting the addresses of its
+// companies among its addresses.
+type profileChildren struct {
+	contacts  []*entity.Contact
+	addresses []*entity.Address
+	companies []*entity.Company
+}
+
+func loadProfileChildren(ctx context.Context, repos Repositories, profileID uint64) (*profileChildren, error) {
+	var (
+		children profileChildren
+		err      error
+	)
+	if children.contacts, err = repos.Contacts.ListByProfileID(ctx, profileID); err != nil {
+		return nil, err
+	}
+	if children.addresses, err = repos.Addresses.ListByProfileID(ctx, profileID); err != nil {
+		return nil, err
+	}
+	if children.companies, err = repos.Companies.ListByProfileID(ctx, profileID); err != nil {
+		return nil, err
+	}
+	for _, company := range children.companies {
+		addresses, err := repos.Addresses.ListByCompanyID(ctx, company.ID)
+		if err != nil {
+			return nil, err
+		}
+		children.addresses = append(children.addresses, addresses...)
+	}
+
+	return &children, nil
+}
+
+// record writes the audit and domain events of every child, skipping those already present in skip.
+// Deletes store the child as old values, restores as new values.
+func (c *profileChildren) record(ctx context.Context, repos Repositories, action string, skip *profileChildren) error {
+	seen := map[string]map[uint64]bool{
+		AuditEntityContact: {},
+		AuditEntityAddress: {},
+		AuditEntityCompany: {},
+	}
+	if skip != nil {
+		for _, contact := range skip.contacts {
+			seen[AuditEntityContact][contact.ID] = true
+		}
+		for _, address := range skip.addresses {
+			seen[AuditEntityAddress][address.ID] = true
+		}
+		for _, company := range skip.companies {
+			seen[AuditEntityCompany][company.ID] = true
+		}
+	}
+
+	write := func(entityType string, id uint64, values auditValues) error {
+		if seen[entityType][id] {
+			return nil
+		}
+		if action == AuditActionDelete {
+			return recordChange(ctx, repos, entityType, id, action, values, nil)
+		}
+		return recordChange(ctx, repos, entityType, id, action, nil, values)
+	}
+
+	for _, contact := range c.contacts {
+		if err := write(AuditEntityContact, contact.ID, contactAuditValues(contact, repos.Contacts.Encrypts)); err != nil {
+			return err
+		}
+	}
+	for _, address := range c.addresses {
+		if err := write(AuditEntityAddress, address.ID, addressAuditValues(address)); err != nil {
+			return err
+		}
+	}
+	for _, company := range c.companies {
+		if err := write(AuditEntityCompany, company.ID, companyAuditValues(company)); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"runtime"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type mockCreateReq struct {
@@ -17,20 +22,43 @@ type mockCreateReq struct {
 func (r mockCreateReq) GetUserId() uint64 { return r.userID }
 func (r mockCreateReq) GetEmail() string  { return r.email }
 
+type mockBundleReq struct {
+	id      uint64
+	include []string
+}
+
+func (r mockBundleReq) GetId() uint64        { return r.id }
+func (r mockBundleReq) GetInclude() []string { return r.include }
+
 type mockUpdateReq struct {
-	id    uint64
-	email string
+	id              uint64
+	email           string
+	expectedVersion uint64
 }
 
-func (r mockUpdateReq) GetId() uint64    { return r.id }
-func (r mockUpdateReq) GetEmail() string { return r.email }
+func (r mockUpdateReq) GetId() uint64              { return r.id }
+func (r mockUpdateReq) GetEmail() string           { return r.email }
+func (r mockUpdateReq) GetExpectedVersion() uint64 { return r.expectedVersion }
+
+type mockPatchReq struct {
+	mockUpdateReq
+	paths []string
+}
+
+func (r mockPatchReq) GetUpdateMask() *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{Paths: r.paths}
+}
 
 type mockRepo struct {
-	createFn       func(ctx context.Context, profile *entity.Profile) error
-	findByIDFn     func(ctx context.Context, id uint64) (*entity.Profile, error)
-	findByUserIDFn func(ctx context.Context, userID uint64) (*entity.Profile, error)
-	updateFn       func(ctx context.Context, profile *entity.Profile) error
-	deleteFn       func(ctx context.Context, id uint64) error
+	createFn               func(ctx context.Context, profile *entity.Profile) error
+	findByIDFn             func(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error)
+	findByIDForUpdateFn    func(ctx context.Context, id uint64) (*entity.Profile, error)
+	findByUserIDFn         func(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error)
+	findByEmailFn          func(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error)
+	findByEmailForUpdateFn func(ctx context.Context, email string) (*entity.Profile, error)
+	updateFn               func(ctx context.Context, profile *entity.Profile) error
+	deleteFn               func(ctx context.Context, id, expectedVersion uint64) error
+	restoreFn              func(ctx context.Context, id uint64) error
 }
 
 func (m *mockRepo) Create(ctx context.Context, profile *entity.Profile) error {
@@ -40,16 +68,38 @@ func (m *mockRepo) Create(ctx context.Context, profile *entity.Profile) error {
 	return nil
 }
 
-func (m *mockRepo) FindByID(ctx context.Context, id uint64) (*entity.Profile, error) {
+func (m *mockRepo) FindByID(ctx context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
 	if m.findByIDFn != nil {
-		return m.findByIDFn(ctx, id)
+		return m.findByIDFn(ctx, id, includeDeleted)
 	}
 	return nil, nil
 }
 
-func (m *mockRepo) FindByUserID(ctx context.Context, userID uint64) (*entity.Profile, error) {
+// FindByIDForUpdate answers like FindByID unless findByIDForUpdateFn is set.
+func (m *mockRepo) FindByIDForUpdate(ctx context.Context, id uint64) (*entity.Profile, error) {
+	if m.findByIDForUpdateFn != nil {
+		return m.findByIDForUpdateFn(ctx, id)
+	}
+	return m.FindByID(ctx, id, false)
+}
+
+func (m *mockRepo) FindByUserID(ctx context.Context, userID uint64, includeDeleted bool) (*entity.Profile, error) {
 	if m.findByUserIDFn != nil {
-		return m.findByUserIDFn(ctx, userID)
+		return m.findByUserIDFn(ctx, userID, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) FindByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.Profile, error) {
+	if m.findByEmailFn != nil {
+		return m.findByEmailFn(ctx, email, includeDeleted)
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) FindByEmailForUpdate(ctx context.Context, email string) (*entity.Profile, error) {
+	if m.findByEmailForUpdateFn != nil {
+		return m.findByEmailForUpdateFn(ctx, email)
 	}
 	return nil, nil
 }
@@ -61,13 +111,46 @@ func (m *mockRepo) Update(ctx context.Context, profile *entity.Profile) error {
 	return nil
 }
 
-func (m *mockRepo) Delete(ctx context.Context, id uint64) error {
+func (m *mockRepo) Delete(ctx context.Context, id, expectedVersion uint64) error {
 	if m.deleteFn != nil {
-		return m.deleteFn(ctx, id)
+		return m.deleteFn(ctx, id, expectedVersion)
+	}
+	return nil
+}
+
+func (m *mockRepo) Restore(ctx context.Context, id uint64) error {
+	if m.restoreFn != nil {
+		return m.restoreFn(ctx, id)
 	}
 	return nil
 }
 
+type mockUnitOfWork struct {
+	repos    Repositories
+	calls    int
+	lastOpts *sql.TxOptions
+}
+
+func newMockUnitOfWork(profileRepo profileRepository) *mockUnitOfWork {
+	return &mockUnitOfWork{repos: Repositories{
+		Profiles:       profileRepo,
+		Contacts:       &mockContactRepo{},
+		Addresses:      &mockAddressRepo{},
+		Companies:      &mockCompanyRepo{},
+		CompanyMembers: newMockCompanyMemberRepo(),
+		Preferences:    newMockPreferenceRepo(),
+		Attachments:    newMockAttachmentRepo(),
+		Audit:          &mockAuditRepo{},
+		Outbox:         &mockOutboxRepo{},
+	}}
+}
+
+func (u *mockUnitOfWork) Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error {
+	u.calls++
+	u.lastOpts = opts
+	return fn(ctx, u.repos)
+}
+
 func TestCreateSuccess(t *testing.T) {
 	repo := &mockRepo{
 		createFn: func(_ context.Context, profile *entity.Profile) error {
@@ -75,7 +158,7 @@ func TestCreateSuccess(t *testing.T) {
 			return nil
 		},
 	}
-	svc := NewProfileService(repo)
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
 
 	profile, err := svc.Create(context.Background(), mockCreateReq{
 		userID: 42,
@@ -91,11 +174,11 @@ func TestCreateSuccess(t *testing.T) {
 
 func TestCreateAlreadyExistsFromLookup(t *testing.T) {
 	repo := &mockRepo{
-		findByUserIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByUserIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: 1, UserID: 42}, nil
 		},
 	}
-	svc := NewProfileService(repo)
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
 
 	_, err := svc.Create(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"})
 	if !errors.Is(err, ErrProfileAlreadyExists) {
@@ -109,7 +192,7 @@ func TestCreateAlreadyExistsFromRepositoryError(t *testing.T) {
 			return repository.ErrProfileAlreadyExists
 		},
 	}
-	svc := NewProfileService(repo)
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
 
 	_, err := svc.Create(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"})
 	if !errors.Is(err, ErrProfileAlreadyExists) {
@@ -117,24 +200,405 @@ func TestCreateAlreadyExistsFromRepositoryError(t *testing.T) {
 	}
 }
 
+func TestCreateWithChildrenBindsChildrenToProfile(t *testing.T) {
+	repo := &mockRepo{
+		createFn: func(_ context.Context, profile *entity.Profile) error {
+			profile.ID = 99
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	var contact *entity.Contact
+	var address *entity.Address
+	uow.repos.Contacts = &mockContactRepo{createFn: func(_ context.Context, c *entity.Contact) error {
+		contact = c
+		return nil
+	}}
+	uow.repos.Addresses = &mockAddressRepo{createFn: func(_ context.Context, a *entity.Address) error {
+		address = a
+		return nil
+	}}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	_, err := svc.CreateWithChildren(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"}, ProfileChildren{
+		Contact: mockCreateContactReq{firstName: "John", dob: "1990-01-02", profileID: 5},
+		Address: mockCreateAddressReq{streetName: "Main", profileID: 5},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if uow.calls != 1 {
+		t.Fatalf("expected a single unit of work, got %d", uow.calls)
+	}
+	if contact == nil || contact.ProfileID != 99 || contact.FirstName != "John" || contact.DOB == nil {
+		t.Fatalf("unexpected contact: %+v", contact)
+	}
+	if address == nil || address.ProfileID != 99 || address.StreetName != "Main" {
+		t.Fatalf("unexpected address: %+v", address)
+	}
+}
+
+func TestCreateWithChildrenPropagatesChildError(t *testing.T) {
+	repo := &mockRepo{}
+	uow := newMockUnitOfWork(repo)
+	boom := errors.New("boom")
+	uow.repos.Addresses = &mockAddressRepo{createFn: func(_ context.Context, _ *entity.Address) error {
+		return boom
+	}}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	profile, err := svc.CreateWithChildren(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"}, ProfileChildren{
+		Address: mockCreateAddressReq{streetName: "Main"},
+	})
+	if !errors.Is(err, boom) || profile != nil {
+		t.Fatalf("expected address error to abort the unit of work, got profile=%+v err=%v", profile, err)
+	}
+}
+
+func TestCreateWithChildrenParsesPhoneInRegionOfAddress(t *testing.T) {
+	repo := &mockRepo{}
+	uow := newMockUnitOfWork(repo)
+	var contact *entity.Contact
+	uow.repos.Contacts = &mockContactRepo{createFn: func(_ context.Context, c *entity.Contact) error {
+		contact = c
+		return nil
+	}}
+	svc := NewProfileService(repo, uow, false, "DE", nil)
+
+	_, err := svc.CreateWithChildren(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"}, ProfileChildren{
+		Contact: mockCreateContactReq{phone: "0722 123 456"},
+		Address: mockCreateAddressReq{country: "RO"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if contact == nil || contact.PhoneE164 != "+40722123456" || contact.PhoneType != "mobile" {
+		t.Fatalf("unexpected contact: %+v", contact)
+	}
+}
+
+func TestCreateWithChildrenInvalidContactPhone(t *testing.T) {
+	repo := &mockRepo{}
+	uow := newMockUnitOfWork(repo)
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	_, err := svc.CreateWithChildren(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"}, ProfileChildren{
+		Contact: mockCreateContactReq{phone: "0722 123 456"},
+	})
+	if !errors.Is(err, ErrInvalidPhone) {
+		t.Fatalf("expected ErrInvalidPhone, got %v", err)
+	}
+	if uow.calls != 0 {
+		t.Fatal("expected no unit of work for invalid input")
+	}
+}
+
+func TestCreateWithChildrenInvalidContactDOB(t *testing.T) {
+	repo := &mockRepo{}
+	uow := newMockUnitOfWork(repo)
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	_, err := svc.CreateWithChildren(context.Background(), mockCreateReq{userID: 42, email: "john@example.com"}, ProfileChildren{
+		Contact: mockCreateContactReq{dob: "1990/01/02"},
+	})
+	if err == nil {
+		t.Fatal("expected DOB parse error")
+	}
+	if uow.calls != 0 {
+		t.Fatal("expected no unit of work for invalid input")
+	}
+}
+
 func TestGetByIDNotFound(t *testing.T) {
-	svc := NewProfileService(&mockRepo{})
-	_, err := svc.GetByID(context.Background(), 1)
+	svc := NewProfileService(&mockRepo{}, newMockUnitOfWork(&mockRepo{}), false, "", nil)
+	_, err := svc.GetByID(context.Background(), 1, false)
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
+	}
+}
+
+func TestGetBundleLoadsAllCollectionsInReadOnlySnapshot(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, UserID: 42}, nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	uow.repos.Contacts = &mockContactRepo{listByProfileIDFn: func(_ context.Context, profileID uint64) ([]*entity.Contact, error) {
+		return []*entity.Contact{{ID: 1, ProfileID: profileID}}, nil
+	}}
+	uow.repos.Addresses = &mockAddressRepo{listByProfileIDFn: func(_ context.Context, profileID uint64) ([]*entity.Address, error) {
+		return []*entity.Address{{ID: 2, ProfileID: profileID}}, nil
+	}}
+	uow.repos.Companies = &mockCompanyRepo{listForProfileFn: func(_ context.Context, profileID uint64) ([]*entity.Company, error) {
+		return []*entity.Company{{ID: 3, ProfileID: profileID}}, nil
+	}}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	bundle, err := svc.GetBundle(context.Background(), mockBundleReq{id: 7})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if bundle.Profile.ID != 7 || len(bundle.Contacts) != 1 || len(bundle.Addresses) != 1 || len(bundle.Companies) != 1 {
+		t.Fatalf("unexpected bundle: %+v", bundle)
+	}
+	if uow.lastOpts == nil || !uow.lastOpts.ReadOnly || uow.lastOpts.Isolation != sql.LevelRepeatableRead {
+		t.Fatalf("expected read-only repeatable-read transaction, got %+v", uow.lastOpts)
+	}
+}
+
+func TestGetBundleIncludesCompaniesOfAMember(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	uow.repos.Companies = &mockCompanyRepo{
+		listByProfileIDFn: func(context.Context, uint64) ([]*entity.Company, error) {
+			return []*entity.Company{}, nil
+		},
+		// Profile 7 is a member of company 3, which is filed under profile 9.
+		listForProfileFn: func(_ context.Context, profileID uint64) ([]*entity.Company, error) {
+			if profileID != 7 {
+				return []*entity.Company{}, nil
+			}
+			return []*entity.Company{{ID: 3, ProfileID: 9}}, nil
+		},
+	}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	bundle, err := svc.GetBundle(context.Background(), mockBundleReq{id: 7, include: []string{"companies"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(bundle.Companies) != 1 || bundle.Companies[0].ID != 3 {
+		t.Fatalf("expected the member's company in the bundle, got %+v", bundle.Companies)
+	}
+}
+
+func TestGetBundleOnlyRequestedCollections(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	uow.repos.Contacts = &mockContactRepo{listByProfileIDFn: func(context.Context, uint64) ([]*entity.Contact, error) {
+		t.Fatal("contacts were not requested")
+		return nil, nil
+	}}
+	uow.repos.Addresses = &mockAddressRepo{listByProfileIDFn: func(context.Context, uint64) ([]*entity.Address, error) {
+		return []*entity.Address{}, nil
+	}}
+	uow.repos.Companies = &mockCompanyRepo{listForProfileFn: func(context.Context, uint64) ([]*entity.Company, error) {
+		t.Fatal("companies were not requested")
+		return nil, nil
+	}}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	bundle, err := svc.GetBundle(context.Background(), mockBundleReq{id: 7, include: []string{"addresses"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if bundle.Contacts != nil || bundle.Addresses == nil || bundle.Companies != nil {
+		t.Fatalf("unexpected bundle collections: %+v", bundle)
+	}
+}
+
+func TestGetBundleNotFound(t *testing.T) {
+	svc := NewProfileService(&mockRepo{}, newMockUnitOfWork(&mockRepo{}), false, "", nil)
+	_, err := svc.GetBundle(context.Background(), mockBundleReq{id: 1})
 	if !errors.Is(err, ErrProfileNotFound) {
 		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
 	}
 }
 
 func TestGetByUserIDNotFound(t *testing.T) {
-	svc := NewProfileService(&mockRepo{})
-	_, err := svc.GetByUserID(context.Background(), 1)
+	svc := NewProfileService(&mockRepo{}, newMockUnitOfWork(&mockRepo{}), false, "", nil)
+	_, err := svc.GetByUserID(context.Background(), 1, false)
 	if !errors.Is(err, ErrProfileNotFound) {
 		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
 	}
 }
 
+func TestGetByEmail(t *testing.T) {
+	var gotEmail string
+	var gotIncludeDeleted bool
+	repo := &mockRepo{
+		findByEmailFn: func(_ context.Context, email string, includeDeleted bool) (*entity.Profile, error) {
+			gotEmail, gotIncludeDeleted = email, includeDeleted
+			if email == "john@example.com" {
+				return &entity.Profile{ID: 3, Email: email}, nil
+			}
+			return nil, nil
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
+
+	profile, err := svc.GetByEmail(context.Background(), "john@example.com", true)
+	if err != nil || profile.ID != 3 {
+		t.Fatalf("expected profile 3, got %+v (%v)", profile, err)
+	}
+	if gotEmail != "john@example.com" || !gotIncludeDeleted {
+		t.Fatalf("unexpected lookup: %q, %v", gotEmail, gotIncludeDeleted)
+	}
+
+	if _, err = svc.GetByEmail(context.Background(), "jane@example.com", false); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
+	}
+}
+
+func TestUniqueEmailRejectsEmailOfAnotherProfile(t *testing.T) {
+	var created, updated bool
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, UserID: 7, Email: "old@example.com"}, nil
+		},
+		findByEmailForUpdateFn: func(_ context.Context, email string) (*entity.Profile, error) {
+			if email == "taken@example.com" {
+				return &entity.Profile{ID: 1, Email: email}, nil
+			}
+			return nil, nil
+		},
+		createFn: func(_ context.Context, _ *entity.Profile) error {
+			created = true
+			return nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Profile) error {
+			updated = true
+			return nil
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), true, "", nil)
+
+	if _, err := svc.Create(context.Background(), mockCreateReq{userID: 42, email: "taken@example.com"}); !errors.Is(err, ErrProfileEmailTaken) {
+		t.Fatalf("expected ErrProfileEmailTaken on create, got: %v", err)
+	}
+	if _, err := svc.Update(context.Background(), mockUpdateReq{id: 22, email: "taken@example.com"}); !errors.Is(err, ErrProfileEmailTaken) {
+		t.Fatalf("expected ErrProfileEmailTaken on update, got: %v", err)
+	}
+	if created || updated {
+		t.Fatal("expected no write for a taken email")
+	}
+
+	// A profile keeping its own email is not a conflict.
+	if _, err := svc.Update(context.Background(), mockUpdateReq{id: 1, email: "taken@example.com"}); err != nil {
+		t.Fatalf("expected update of the owning profile to succeed, got: %v", err)
+	}
+	if _, err := svc.Create(context.Background(), mockCreateReq{userID: 43, email: "free@example.com"}); err != nil {
+		t.Fatalf("expected create with a free email to succeed, got: %v", err)
+	}
+}
+
+// emailLockStore is the state shared by emailLockingTx transactions: the live profiles and the
+// row lock that FindByEmailForUpdate takes, which is held until the transaction ends.
+type emailLockStore struct {
+	lock     chan struct{}
+	mu       sync.Mutex
+	profiles []*entity.Profile
+}
+
+type emailLockingTx struct {
+	mockRepo
+	t     *testing.T
+	store *emailLockStore
+	held  bool
+}
+
+func (tx *emailLockingTx) FindByEmailForUpdate(_ context.Context, email string) (*entity.Profile, error) {
+	tx.store.lock <- struct{}{}
+	tx.held = true
+
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	for _, profile := range tx.store.profiles {
+		if profile.Email == email {
+			return profile, nil
+		}
+	}
+	return nil, nil
+}
+
+func (tx *emailLockingTx) Create(_ context.Context, profile *entity.Profile) error {
+	if !tx.held {
+		tx.t.Error("expected the email to be locked before the profile is created")
+	}
+	runtime.Gosched()
+
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	profile.ID = uint64(len(tx.store.profiles) + 1)
+	tx.store.profiles = append(tx.store.profiles, profile)
+	return nil
+}
+
+type emailLockingUnitOfWork struct {
+	t     *testing.T
+	store *emailLockStore
+}
+
+func (u *emailLockingUnitOfWork) Do(ctx context.Context, _ *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error {
+	tx := &emailLockingTx{t: u.t, store: u.store}
+	defer func() {
+		if tx.held {
+			<-u.store.lock
+		}
+	}()
+	return fn(ctx, newMockUnitOfWork(tx).repos)
+}
+
+func TestUniqueEmailConcurrentCreates(t *testing.T) {
+	uow := &emailLockingUnitOfWork{t: t, store: &emailLockStore{lock: make(chan struct{}, 1)}}
+	svc := NewProfileService(&mockRepo{}, uow, true, "", nil)
+
+	start := make(chan struct{})
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for userID := uint64(42); userID < 44; userID++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := svc.Create(context.Background(), mockCreateReq{userID: userID, email: "john@example.com"})
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	var created, taken int
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrProfileEmailTaken):
+			taken++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if created != 1 || taken != 1 {
+		t.Fatalf("expected one create and one ErrProfileEmailTaken, got %d and %d", created, taken)
+	}
+}
+
+func TestUniqueEmailDisabledAllowsDuplicates(t *testing.T) {
+	repo := &mockRepo{
+		findByEmailForUpdateFn: func(_ context.Context, email string) (*entity.Profile, error) {
+			return &entity.Profile{ID: 1, Email: email}, nil
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
+
+	if _, err := svc.Create(context.Background(), mockCreateReq{userID: 42, email: "taken@example.com"}); err != nil {
+		t.Fatalf("expected duplicate email to be allowed, got: %v", err)
+	}
+}
+
 func TestUpdateNotFound(t *testing.T) {
-	svc := NewProfileService(&mockRepo{})
+	svc := NewProfileService(&mockRepo{}, newMockUnitOfWork(&mockRepo{}), false, "", nil)
 	_, err := svc.Update(context.Background(), mockUpdateReq{id: 22, email: "new@example.com"})
 	if !errors.Is(err, ErrProfileNotFound) {
 		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
@@ -143,14 +607,14 @@ func TestUpdateNotFound(t *testing.T) {
 
 func TestUpdateRepositoryNotFoundMapped(t *testing.T) {
 	repo := &mockRepo{
-		findByIDFn: func(_ context.Context, _ uint64) (*entity.Profile, error) {
+		findByIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
 			return &entity.Profile{ID: 22, UserID: 7, Email: "old@example.com"}, nil
 		},
 		updateFn: func(_ context.Context, _ *entity.Profile) error {
 			return repository.ErrProfileNotFound
 		},
 	}
-	svc := NewProfileService(repo)
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
 
 	_, err := svc.Update(context.Background(), mockUpdateReq{id: 22, email: "new@example.com"})
 	if !errors.Is(err, ErrProfileNotFound) {
@@ -158,16 +622,253 @@ func TestUpdateRepositoryNotFoundMapped(t *testing.T) {
 	}
 }
 
+func TestPatchUpdatesMaskedEmail(t *testing.T) {
+	var saved *entity.Profile
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: 22, UserID: 7, Email: "old@example.com"}, nil
+		},
+		updateFn: func(_ context.Context, profile *entity.Profile) error {
+			saved = profile
+			return nil
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
+
+	_, err := svc.Patch(context.Background(), mockPatchReq{mockUpdateReq: mockUpdateReq{id: 22, email: "new@example.com"}, paths: []string{"email"}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if saved.Email != "new@example.com" || saved.UserID != 7 {
+		t.Fatalf("unexpected patched profile: %+v", saved)
+	}
+
+	_, err = svc.Patch(context.Background(), mockPatchReq{mockUpdateReq: mockUpdateReq{id: 22}, paths: []string{"user_id"}})
+	if !errors.Is(err, ErrInvalidUpdateMask) {
+		t.Fatalf("expected ErrInvalidUpdateMask, got: %v", err)
+	}
+}
+
 func TestDeleteRepositoryNotFoundMapped(t *testing.T) {
 	repo := &mockRepo{
-		deleteFn: func(_ context.Context, _ uint64) error {
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+		deleteFn: func(_ context.Context, _, _ uint64) error {
 			return repository.ErrProfileNotFound
 		},
 	}
-	svc := NewProfileService(repo)
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
 
-	err := svc.Delete(context.Background(), 7)
+	err := svc.Delete(context.Background(), 7, 0)
 	if !errors.Is(err, ErrProfileNotFound) {
 		t.Fatalf("expected ErrProfileNotFound, got: %v", err)
 	}
 }
+
+func TestUpdateRepositoryVersionConflictMapped(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, _ uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: 22, UserID: 7, Email: "old@example.com", Version: 2}, nil
+		},
+		updateFn: func(_ context.Context, _ *entity.Profile) error {
+			return repository.ErrVersionConflict
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
+
+	_, err := svc.Update(context.Background(), mockUpdateReq{id: 22, email: "new@example.com", expectedVersion: 2})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got: %v", err)
+	}
+}
+
+func TestDeleteCascadesSoftDeleteToChildren(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	cascaded := make([]string, 0, 3)
+	uow.repos.Contacts = &mockContactRepo{deleteByProfileIDFn: func(_ context.Context, profileID uint64) error {
+		cascaded = append(cascaded, "contacts")
+		return nil
+	}}
+	uow.repos.Addresses = &mockAddressRepo{deleteByProfileIDFn: func(_ context.Context, profileID uint64) error {
+		cascaded = append(cascaded, "addresses")
+		return nil
+	}}
+	uow.repos.Companies = &mockCompanyRepo{deleteByProfileIDFn: func(_ context.Context, profileID uint64) error {
+		cascaded = append(cascaded, "companies")
+		return nil
+	}}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	if err := svc.Delete(context.Background(), 7, 0); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if uow.calls != 1 || len(cascaded) != 3 {
+		t.Fatalf("expected one unit of work cascading to all children, got calls=%d cascaded=%v", uow.calls, cascaded)
+	}
+}
+
+func TestDeleteKeepsCompanySharedWithAnotherOwner(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id}, nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	companies := map[uint64]*entity.Company{
+		5: {ID: 5, ProfileID: 7, IsPrimary: true},
+		6: {ID: 6, ProfileID: 7},
+	}
+	deletedFor := uint64(0)
+	uow.repos.Companies = &mockCompanyRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Company, error) {
+			company, ok := companies[id]
+			if !ok {
+				return nil, nil
+			}
+			found := *company
+			return &found, nil
+		},
+		updateFn: func(_ context.Context, company *entity.Company) error {
+			stored := *company
+			companies[company.ID] = &stored
+			return nil
+		},
+		listByProfileIDFn: func(_ context.Context, profileID uint64) ([]*entity.Company, error) {
+			filed := make([]*entity.Company, 0)
+			for _, id := range []uint64{5, 6} {
+				if companies[id].ProfileID == profileID {
+					filed = append(filed, companies[id])
+				}
+			}
+			return filed, nil
+		},
+		deleteByProfileIDFn: func(_ context.Context, profileID uint64) error {
+			deletedFor = profileID
+			return nil
+		},
+	}
+	members := uow.repos.CompanyMembers.(*mockCompanyMemberRepo)
+	for _, member := range []*entity.CompanyMember{
+		{CompanyID: 5, ProfileID: 7, Role: entity.CompanyRoleOwner},
+		{CompanyID: 5, ProfileID: 8, Role: entity.CompanyRoleOwner},
+		{CompanyID: 6, ProfileID: 7, Role: entity.CompanyRoleOwner},
+	} {
+		if err := members.Save(context.Background(), member); err != nil {
+			t.Fatalf("Save() returned error: %v", err)
+		}
+	}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	if err := svc.Delete(context.Background(), 7, 0); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if shared := companies[5]; shared.ProfileID != 8 || shared.IsPrimary {
+		t.Fatalf("expected the shared company to be filed under the other owner, got %+v", shared)
+	}
+	if member, _ := members.Find(context.Background(), 5, 7); member != nil {
+		t.Fatalf("expected the deleted profile to leave the shared company, got %+v", member)
+	}
+	if member, _ := members.Find(context.Background(), 5, 8); member == nil || member.Role != entity.CompanyRoleOwner {
+		t.Fatalf("expected the other owner to keep the shared company, got %+v", member)
+	}
+	if owned := companies[6]; owned.ProfileID != 7 || deletedFor != 7 {
+		t.Fatalf("expected the company owned alone to be deleted with the profile, got %+v deleted for %d", owned, deletedFor)
+	}
+	if member, _ := members.Find(context.Background(), 6, 7); member == nil {
+		t.Fatal("expected the membership of the company owned alone to be kept for a restore")
+	}
+
+	deleted := map[string][]uint64{}
+	for _, event := range uow.repos.Audit.(*mockAuditRepo).events {
+		if event.Action == AuditActionDelete {
+			deleted[event.EntityType] = append(deleted[event.EntityType], event.EntityID)
+		}
+	}
+	if got := deleted[AuditEntityCompany]; len(got) != 1 || got[0] != 6 {
+		t.Fatalf("expected only company 6 to be recorded as deleted, got %v", got)
+	}
+	if got := deleted[AuditEntityCompanyMember]; len(got) != 1 || got[0] != 5 {
+		t.Fatalf("expected the membership in company 5 to be recorded as removed, got %v", got)
+	}
+}
+
+func TestRestoreBringsBackChildrenDeletedWithProfile(t *testing.T) {
+	deletedAt := time.Date(2026, 4, 2, 9, 0, 0, 0, time.UTC)
+	restored := false
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, includeDeleted bool) (*entity.Profile, error) {
+			if !restored {
+				if !includeDeleted {
+					t.Fatal("expected the deleted profile to be looked up with includeDeleted")
+				}
+				return &entity.Profile{ID: id, Version: 3, DeletedAt: &deletedAt}, nil
+			}
+			return &entity.Profile{ID: id, Version: 4}, nil
+		},
+		restoreFn: func(context.Context, uint64) error {
+			restored = true
+			return nil
+		},
+	}
+	uow := newMockUnitOfWork(repo)
+	var since time.Time
+	uow.repos.Contacts = &mockContactRepo{restoreByProfileIDFn: func(_ context.Context, _ uint64, deletedSince time.Time) error {
+		since = deletedSince
+		return nil
+	}}
+	svc := NewProfileService(repo, uow, false, "", nil)
+
+	profile, err := svc.Restore(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if profile.Version != 4 || profile.DeletedAt != nil {
+		t.Fatalf("unexpected restored profile: %+v", profile)
+	}
+	if !since.Equal(deletedAt) {
+		t.Fatalf("expected children deleted since %v to be restored, got %v", deletedAt, since)
+	}
+}
+
+func TestRestoreLiveProfileReturnsNotDeleted(t *testing.T) {
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, Version: 1}, nil
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), false, "", nil)
+
+	_, err := svc.Restore(context.Background(), 5)
+	if !errors.Is(err, ErrNotDeleted) {
+		t.Fatalf("expected ErrNotDeleted, got: %v", err)
+	}
+}
+
+func TestRestoreWithUniqueEmailTakenByLiveProfile(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+	restored := false
+	repo := &mockRepo{
+		findByIDFn: func(_ context.Context, id uint64, _ bool) (*entity.Profile, error) {
+			return &entity.Profile{ID: id, Email: "john@example.com", DeletedAt: &deletedAt}, nil
+		},
+		findByEmailForUpdateFn: func(_ context.Context, email string) (*entity.Profile, error) {
+			return &entity.Profile{ID: 9, Email: email}, nil
+		},
+		restoreFn: func(_ context.Context, _ uint64) error {
+			restored = true
+			return nil
+		},
+	}
+	svc := NewProfileService(repo, newMockUnitOfWork(repo), true, "", nil)
+
+	_, err := svc.Restore(context.Background(), 5)
+	if !errors.Is(err, ErrProfileEmailTaken) || restored {
+		t.Fatalf("expected ErrProfileEmailTaken without restoring, got: %v (restored=%v)", err, restored)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+type purgeRepository interface {
+	Purge(ctx context.Context, deletedBefore time.Time, limit uint32) (int64, error)
+}
+
+type purgeTarget struct {
+	name string
+	repo purgeRepository
+}
+
+// PurgeResult counts the hard-deleted rows per entity.
+type PurgeResult map[string]int64
+
+// PurgeService hard-deletes records that have stayed soft-deleted longer than the retention window.
+type PurgeService struct {
+	targets   []purgeTarget
+	retention time.Duration
+	batchSize uint32
+}
+
+func NewPurgeService(
+	profileRepo purgeRepository,
+	contactRepo purgeRepository,
+	addressRepo purgeRepository,
+	companyRepo purgeRepository,
+	attachmentPurger purgeRepository,
+	emailLockRepo purgeRepository,
+	retention time.Duration,
+	batchSize uint32,
+) *PurgeService {
+	if batchSize == 0 {
+		batchSize = 500
+	}
+
+	return &PurgeService{
+		// Children go first; profiles last, taking along any child rows left through ON DELETE CASCADE.
+		// Attachments have no foreign key to cascade through, so they go before their owners.
+		targets: []purgeTarget{
+			{name: "attachments", repo: attachmentPurger},
+			{name: "contacts", repo: contactRepo},
+			{name: "addresses", repo: addressRepo},
+			{name: "companies", repo: companyRepo},
+			{name: "profiles", repo: profileRepo},
+			// Email locks outlive the profiles they were taken for, and are pruned once no live
+			// profile has their email.
+			{name: "email_locks", repo: emailLockRepo},
+		},
+		retention: retention,
+		batchSize: batchSize,
+	}
+}
+
+// Purge removes, in batches, every record soft-deleted before now minus the retention window.
+func (s *PurgeService) Purge(ctx context.Context, now time.Time) (PurgeResult, error) {
+	cutoff := now.Add(-s.retention)
+	result := make(PurgeResult, len(s.targets))
+	for _, target := range s.targets {
+		for {
+			purged, err := target.repo.Purge(ctx, cutoff, s.batchSize)
+			if err != nil {
+				return result, err
+			}
+			result[target.name] += purged
+			if purged < int64(s.batchSize) {
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
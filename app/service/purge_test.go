@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockPurgeRepo struct {
+	remaining int64
+	calls     int
+	cutoff    time.Time
+	err       error
+}
+
+func (m *mockPurgeRepo) Purge(_ context.Context, deletedBefore time.Time, limit uint32) (int64, error) {
+	m.calls++
+	m.cutoff = deletedBefore
+	if m.err != nil {
+		return 0, m.err
+	}
+	purged := min(m.remaining, int64(limit))
+	m.remaining -= purged
+	return purged, nil
+}
+
+func TestPurgeRunsBatchesUntilExhausted(t *testing.T) {
+	profiles := &mockPurgeRepo{remaining: 5}
+	contacts := &mockPurgeRepo{remaining: 2}
+	addresses := &mockPurgeRepo{}
+	companies := &mockPurgeRepo{remaining: 4}
+	attachments := &mockPurgeRepo{remaining: 1}
+	emailLocks := &mockPurgeRepo{remaining: 3}
+	svc := NewPurgeService(profiles, contacts, addresses, companies, attachments, emailLocks, 30*24*time.Hour, 2)
+	now := time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)
+
+	result, err := svc.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if result["profiles"] != 5 || result["contacts"] != 2 || result["addresses"] != 0 || result["companies"] != 4 || result["attachments"] != 1 || result["email_locks"] != 3 {
+		t.Fatalf("unexpected purge result: %v", result)
+	}
+	// Full batches trigger another round; the first short batch ends the loop.
+	if profiles.calls != 3 || contacts.calls != 2 || addresses.calls != 1 || companies.calls != 3 {
+		t.Fatalf("unexpected batch counts: profiles=%d contacts=%d addresses=%d companies=%d",
+			profiles.calls, contacts.calls, addresses.calls, companies.calls)
+	}
+	if want := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC); !profiles.cutoff.Equal(want) {
+		t.Fatalf("expected cutoff %v, got %v", want, profiles.cutoff)
+	}
+}
+
+func TestPurgeStopsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	profiles := &mockPurgeRepo{remaining: 1}
+	contacts := &mockPurgeRepo{err: boom}
+	svc := NewPurgeService(profiles, contacts, &mockPurgeRepo{}, &mockPurgeRepo{}, &mockPurgeRepo{}, &mockPurgeRepo{}, time.Hour, 10)
+
+	_, err := svc.Purge(context.Background(), time.Now())
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got: %v", err)
+	}
+	if profiles.calls != 0 {
+		t.Fatal("profiles must not be purged after a child purge failed")
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+)
+
+// Repositories exposes the repositories bound to a single unit of work.
+type Repositories struct {
+	Profiles       profileRepository
+	Contacts       contactRepository
+	Addresses      addressRepository
+	Companies      companyRepository
+	CompanyMembers companyMemberRepository
+	Preferences    preferenceRepository
+	Attachments    attachmentRepository
+	Audit          auditRepository
+	Outbox         outboxRepository
+}
+
+// UnitOfWork runs fn over repositories sharing one transaction: everything fn does is
+// committed when it returns nil and rolled back when it returns an error or panics.
+// A nil opts uses the configured default isolation level.
+type UnitOfWork interface {
+	Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+type txUnitOfWork struct {
+	txManager *repository.TxManager
+}
+
+func NewUnitOfWork(txManager *repository.TxManager) UnitOfWork {
+	return &txUnitOfWork{txManager: txManager}
+}
+
+func (u *txUnitOfWork) Do(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, repos Repositories) error) error {
+	return u.txManager.WithinTx(ctx, opts, func(ctx context.Context, repos *repository.Repositories) error {
+		return fn(ctx, Repositories{
+			Profiles:       repos.Profiles,
+			Contacts:       repos.Contacts,
+			Addresses:      repos.Addresses,
+			Companies:      repos.Companies,
+			CompanyMembers: repos.CompanyMembers,
+			Preferences:    repos.Preferences,
+			Attachments:    repos.Attachments,
+			Audit:          repos.Audit,
+			Outbox:         repos.Outbox,
+		})
+	})
+}
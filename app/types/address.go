@@ -2,9 +2,13 @@ package types
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"github.com/vibast-solutions/ms-go-profile/app/entity"
 )
 
 const (
@@ -14,43 +18,59 @@ const (
 )
 
 type createAddressBody struct {
-	StreetName     string `json:"street_name"`
-	StreenNo       string `json:"streen_no"`
-	City           string `json:"city"`
-	County         string `json:"county"`
-	Country        string `json:"country"`
-	ProfileID      uint64 `json:"profile_id"`
-	PostalCode     string `json:"postal_code"`
-	Building       string `json:"building"`
-	Apartment      string `json:"apartment"`
-	AdditionalData string `json:"additional_data"`
-	Type           string `json:"type"`
+	StreetName     string         `json:"street_name"`
+	StreenNo       string         `json:"streen_no"`
+	City           string         `json:"city"`
+	County         string         `json:"county"`
+	Country        string         `json:"country"`
+	ProfileID      uint64         `json:"profile_id"`
+	CompanyID      uint64         `json:"company_id"`
+	PostalCode     string         `json:"postal_code"`
+	Building       string         `json:"building"`
+	Apartment      string         `json:"apartment"`
+	AdditionalData string         `json:"additional_data"`
+	Type           string         `json:"type"`
+	Latitude       *float64       `json:"latitude"`
+	Longitude      *float64       `json:"longitude"`
+	Attributes     map[string]any `json:"attributes"`
 }
 
 type updateAddressBody struct {
-	ID             uint64 `param:"id"`
-	StreetName     string `json:"street_name"`
-	StreenNo       string `json:"streen_no"`
-	City           string `json:"city"`
-	County         string `json:"county"`
-	Country        string `json:"country"`
-	ProfileID      uint64 `json:"profile_id"`
-	PostalCode     string `json:"postal_code"`
-	Building       string `json:"building"`
-	Apartment      string `json:"apartment"`
-	AdditionalData string `json:"additional_data"`
-	Type           string `json:"type"`
+	ID             uint64         `param:"id"`
+	StreetName     string         `json:"street_name"`
+	StreenNo       string         `json:"streen_no"`
+	City           string         `json:"city"`
+	County         string         `json:"county"`
+	Country        string         `json:"country"`
+	ProfileID      uint64         `json:"profile_id"`
+	CompanyID      uint64         `json:"company_id"`
+	PostalCode     string         `json:"postal_code"`
+	Building       string         `json:"building"`
+	Apartment      string         `json:"apartment"`
+	AdditionalData string         `json:"additional_data"`
+	Type           string         `json:"type"`
+	Latitude       *float64       `json:"latitude"`
+	Longitude      *float64       `json:"longitude"`
+	Attributes     map[string]any `json:"attributes"`
 }
 
 type addressPathParams struct {
 	ID uint64 `param:"id"`
 }
 
+type getAddressParams struct {
+	ID             uint64 `param:"id"`
+	IncludeDeleted bool   `query:"include_deleted"`
+}
+
 type listAddressesQuery struct {
-	ProfileID uint64 `query:"profile_id"`
-	Page      uint32 `query:"page"`
-	PageSize  uint32 `query:"page_size"`
-	Type      string `query:"type"`
+	ProfileID      uint64 `query:"profile_id"`
+	CompanyID      uint64 `query:"company_id"`
+	Page           uint32 `query:"page"`
+	PageSize       uint32 `query:"page_size"`
+	Type           string `query:"type"`
+	IncludeDeleted bool   `query:"include_deleted"`
+	PrimaryOnly    bool   `query:"primary_only"`
 }
 
 func NewCreateAddressRequestFromContext(ctx echo.Context) (*CreateAddressRequest, error) {
@@ -59,22 +79,39 @@ func NewCreateAddressRequestFromContext(ctx echo.Context) (*CreateAddressRequest
 		return nil, err
 	}
 
+	return body.request(), nil
+}
+
+func (b *createAddressBody) request() *CreateAddressRequest {
 	return &CreateAddressRequest{
-		StreetName:     body.StreetName,
-		StreenNo:       body.StreenNo,
-		City:           body.City,
-		County:         body.County,
-		Country:        body.Country,
-		ProfileId:      body.ProfileID,
-		PostalCode:     body.PostalCode,
-		Building:       body.Building,
-		Apartment:      body.Apartment,
-		AdditionalData: body.AdditionalData,
-		Type:           body.Type,
-	}, nil
+		StreetName:     b.StreetName,
+		StreenNo:       b.StreenNo,
+		City:           b.City,
+		County:         b.County,
+		Country:        b.Country,
+		ProfileId:      b.ProfileID,
+		CompanyId:      b.CompanyID,
+		PostalCode:     b.PostalCode,
+		Building:       b.Building,
+		Apartment:      b.Apartment,
+		AdditionalData: b.AdditionalData,
+		Type:           b.Type,
+		Latitude:       b.Latitude,
+		Longitude:      b.Longitude,
+		Attributes:     attribute.Struct(b.Attributes),
+	}
 }
 
 func (r *CreateAddressRequest) Validate() error {
+	if err := r.validateFields(); err != nil {
+		return err
+	}
+
+	return validateAddressOwner(r.ProfileId, r.CompanyId, r.Type)
+}
+
+// validateFields checks everything except the owner, which nested requests do not carry.
+func (r *CreateAddressRequest) validateFields() error {
 	if strings.TrimSpace(r.StreetName) == "" {
 		return errors.New("street_name is required")
 	}
@@ -90,23 +127,59 @@ func (r *CreateAddressRequest) Validate() error {
 	if strings.TrimSpace(r.Country) == "" {
 		return errors.New("country is required")
 	}
-	if r.ProfileId == 0 {
-		return errors.New("profile_id is required")
-	}
 	if len(r.AdditionalData) > 512 {
 		return errors.New("additional_data must be less than or equal to 512 characters")
 	}
+	if err := validateCoordinates(r.Latitude, r.Longitude); err != nil {
+		return err
+	}
+
+	normalized, err := country.NormalizeAddress(country.Address{Country: r.Country, County: r.County, PostalCode: r.PostalCode})
+	if err != nil {
+		return err
+	}
+	r.Country, r.County, r.PostalCode = normalized.Country, normalized.County, normalized.PostalCode
+
+	return nil
+}
+
+// validateAddressOwner checks that exactly one of profileID and companyID is set, and that a
+// company's address has one of the company address types.
+func validateAddressOwner(profileID, companyID uint64, addressType string) error {
+	if profileID == 0 && companyID == 0 {
+		return errors.New("profile_id is required")
+	}
+	if profileID != 0 && companyID != 0 {
+		return errors.New("exactly one of profile_id and company_id is required")
+	}
+	if companyID != 0 && !entity.IsCompanyAddressType(addressType) {
+		return &country.FieldError{Field: "type", Message: "type of a company address must be one of registered_office, billing, warehouse"}
+	}
 
 	return nil
 }
 
 func NewGetAddressRequestFromContext(ctx echo.Context) (*GetAddressRequest, error) {
-	params := &addressPathParams{}
+	params := &getAddressParams{}
 	if err := ctx.Bind(params); err != nil {
 		return nil, err
 	}
 
-	return &GetAddressRequest{Id: params.ID}, nil
+	return &GetAddressRequest{Id: params.ID, IncludeDeleted: params.IncludeDeleted}, nil
+}
+
+// AddressFormatLabel asks GET /addresses/:id for the postal label of the address as plain text.
+const AddressFormatLabel = "label"
+
+// AddressFormatFromContext reads the optional format query param of GET /addresses/:id, which
+// is empty for the JSON response.
+func AddressFormatFromContext(ctx echo.Context) (string, error) {
+	format := strings.TrimSpace(ctx.QueryParam("format"))
+	if format != "" && format != AddressFormatLabel {
+		return "", fmt.Errorf("format must be %q", AddressFormatLabel)
+	}
+
+	return format, nil
 }
 
 func (r *GetAddressRequest) Validate() error {
@@ -123,7 +196,7 @@ func NewUpdateAddressRequestFromContext(ctx echo.Context) (*UpdateAddressRequest
 		return nil, err
 	}
 
-	return &UpdateAddressRequest{
+	req := &UpdateAddressRequest{
 		Id:             body.ID,
 		StreetName:     body.StreetName,
 		StreenNo:       body.StreenNo,
@@ -131,12 +204,24 @@ func NewUpdateAddressRequestFromContext(ctx echo.Context) (*UpdateAddressRequest
 		County:         body.County,
 		Country:        body.Country,
 		ProfileId:      body.ProfileID,
+		CompanyId:      body.CompanyID,
 		PostalCode:     body.PostalCode,
 		Building:       body.Building,
 		Apartment:      body.Apartment,
 		AdditionalData: body.AdditionalData,
 		Type:           body.Type,
-	}, nil
+		Latitude:       body.Latitude,
+		Longitude:      body.Longitude,
+		Attributes:     attribute.Struct(body.Attributes),
+	}
+
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.ExpectedVersion = expectedVersion
+
+	return req, nil
 }
 
 func (r *UpdateAddressRequest) Validate() error {
@@ -158,12 +243,120 @@ func (r *UpdateAddressRequest) Validate() error {
 	if strings.TrimSpace(r.Country) == "" {
 		return errors.New("country is required")
 	}
-	if r.ProfileId == 0 {
-		return errors.New("profile_id is required")
+	if err := validateAddressOwner(r.ProfileId, r.CompanyId, r.Type); err != nil {
+		return err
 	}
 	if len(r.AdditionalData) > 512 {
 		return errors.New("additional_data must be less than or equal to 512 characters")
 	}
+	if err := validateCoordinates(r.Latitude, r.Longitude); err != nil {
+		return err
+	}
+
+	normalized, err := country.NormalizeAddress(country.Address{Country: r.Country, County: r.County, PostalCode: r.PostalCode})
+	if err != nil {
+		return err
+	}
+	r.Country, r.County, r.PostalCode = normalized.Country, normalized.County, normalized.PostalCode
+
+	return nil
+}
+
+var addressPatchPaths = map[string]struct{}{
+	"street_name":     {},
+	"streen_no":       {},
+	"city":            {},
+	"county":          {},
+	"country":         {},
+	"profile_id":      {},
+	"company_id":      {},
+	"postal_code":     {},
+	"building":        {},
+	"apartment":       {},
+	"additional_data": {},
+	"type":            {},
+	"latitude":        {},
+	"longitude":       {},
+	"attributes":      {},
+	"attributes.*":    {},
+}
+
+func NewPatchAddressRequestFromContext(ctx echo.Context) (*PatchAddressRequest, error) {
+	req := &PatchAddressRequest{}
+	id, mask, err := bindMergePatch(ctx, mergePatchFields{
+		"street_name":     &req.StreetName,
+		"streen_no":       &req.StreenNo,
+		"city":            &req.City,
+		"county":          &req.County,
+		"country":         &req.Country,
+		"profile_id":      &req.ProfileId,
+		"company_id":      &req.CompanyId,
+		"postal_code":     &req.PostalCode,
+		"building":        &req.Building,
+		"apartment":       &req.Apartment,
+		"additional_data": &req.AdditionalData,
+		"type":            &req.Type,
+		"latitude":        &req.Latitude,
+		"longitude":       &req.Longitude,
+		"attributes":      &req.Attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Id = id
+	req.UpdateMask = mask
+	if req.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Validate checks only the fields named in update_mask; mandatory fields cannot be cleared.
+func (r *PatchAddressRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+	paths, err := updateMaskPaths(r.UpdateMask, addressPatchPaths)
+	if err != nil {
+		return err
+	}
+	if hasPath(paths, "street_name") && strings.TrimSpace(r.StreetName) == "" {
+		return errors.New("street_name is required")
+	}
+	if hasPath(paths, "streen_no") && strings.TrimSpace(r.StreenNo) == "" {
+		return errors.New("streen_no is required")
+	}
+	if hasPath(paths, "city") && strings.TrimSpace(r.City) == "" {
+		return errors.New("city is required")
+	}
+	if hasPath(paths, "county") && strings.TrimSpace(r.County) == "" {
+		return errors.New("county is required")
+	}
+	if hasPath(paths, "country") && strings.TrimSpace(r.Country) == "" {
+		return errors.New("country is required")
+	}
+	// An address keeps its owner until the patch names another one.
+	if hasPath(paths, "profile_id") && r.ProfileId == 0 && (!hasPath(paths, "company_id") || r.CompanyId == 0) {
+		return errors.New("profile_id is required")
+	}
+	if hasPath(paths, "company_id") && r.CompanyId == 0 && (!hasPath(paths, "profile_id") || r.ProfileId == 0) {
+		return errors.New("company_id is required")
+	}
+	if hasPath(paths, "profile_id") && hasPath(paths, "company_id") && r.ProfileId != 0 && r.CompanyId != 0 {
+		return errors.New("exactly one of profile_id and company_id is required")
+	}
+	if hasPath(paths, "additional_data") && len(r.AdditionalData) > 512 {
+		return errors.New("additional_data must be less than or equal to 512 characters")
+	}
+	if hasPath(paths, "latitude") != hasPath(paths, "longitude") {
+		return errors.New("latitude and longitude must be patched together")
+	}
+	if hasPath(paths, "latitude") {
+		if err = validateCoordinates(r.Latitude, r.Longitude); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -174,7 +367,12 @@ func NewDeleteAddressRequestFromContext(ctx echo.Context) (*DeleteAddressRequest
 		return nil, err
 	}
 
-	return &DeleteAddressRequest{Id: params.ID}, nil
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteAddressRequest{Id: params.ID, ExpectedVersion: expectedVersion}, nil
 }
 
 func (r *DeleteAddressRequest) Validate() error {
@@ -185,6 +383,45 @@ func (r *DeleteAddressRequest) Validate() error {
 	return nil
 }
 
+func NewRestoreAddressRequestFromContext(ctx echo.Context) (*RestoreAddressRequest, error) {
+	params := &addressPathParams{}
+	if err := ctx.Bind(params); err != nil {
+		return nil, err
+	}
+
+	return &RestoreAddressRequest{Id: params.ID}, nil
+}
+
+func (r *RestoreAddressRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+func NewSetPrimaryAddressRequestFromContext(ctx echo.Context) (*SetPrimaryAddressRequest, error) {
+	params := &addressPathParams{}
+	if err := ctx.Bind(params); err != nil {
+		return nil, err
+	}
+
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetPrimaryAddressRequest{Id: params.ID, ExpectedVersion: expectedVersion}, nil
+}
+
+func (r *SetPrimaryAddressRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
 func NewListAddressesRequestFromContext(ctx echo.Context) (*ListAddressesRequest, error) {
 	query := &listAddressesQuery{
 		Page:     defaultAddressPage,
@@ -195,20 +432,24 @@ func NewListAddressesRequestFromContext(ctx echo.Context) (*ListAddressesRequest
 	}
 
 	return &ListAddressesRequest{
-		ProfileId: query.ProfileID,
-		Page:      query.Page,
-		PageSize:  query.PageSize,
-		Type:      strings.TrimSpace(query.Type),
+		ProfileId:      query.ProfileID,
+		CompanyId:      query.CompanyID,
+		Page:           query.Page,
+		PageSize:       query.PageSize,
+		Type:           strings.TrimSpace(query.Type),
+		IncludeDeleted: query.IncludeDeleted,
+		PrimaryOnly:    query.PrimaryOnly,
+		Attributes:     attributeFiltersFromQuery(ctx),
 	}, nil
 }
 
 func (r *ListAddressesRequest) Validate() error {
-	if r.ProfileId == 0 {
-		return errors.New("profile_id is required")
+	if (r.ProfileId == 0) == (r.CompanyId == 0) {
+		return errors.New("exactly one of profile_id and company_id is required")
 	}
 	if r.PageSize > maxAddressPageSize {
 		return errors.New("page_size must be less than or equal to 100")
 	}
 
-	return nil
+	return validateAttributeFilters(r.Attributes)
 }
@@ -1,11 +1,14 @@
 package types
 
 import (
+	"errors"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestCreateAddressRequestValidate(t *testing.T) {
@@ -13,8 +16,8 @@ func TestCreateAddressRequestValidate(t *testing.T) {
 		StreetName: "Street",
 		StreenNo:   "10",
 		City:       "City",
-		County:     "County",
-		Country:    "Country",
+		County:     "Cluj",
+		Country:    "RO",
 		ProfileId:  7,
 	}
 	if err := valid.Validate(); err != nil {
@@ -26,9 +29,42 @@ func TestCreateAddressRequestValidate(t *testing.T) {
 	}
 }
 
+func TestCreateAddressRequestValidateNormalizesCountryFields(t *testing.T) {
+	req := &CreateAddressRequest{
+		StreetName: "Street",
+		StreenNo:   "10",
+		City:       "Cluj-Napoca",
+		County:     " cluj",
+		Country:    "romania ",
+		PostalCode: "400001",
+		ProfileId:  7,
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if req.Country != "RO" || req.County != "cluj" || req.PostalCode != "400001" {
+		t.Fatalf("unexpected normalized request: %+v", req)
+	}
+
+	cases := []struct {
+		country, county, postalCode, field string
+	}{
+		{"Romanistan", "Cluj", "", "country"},
+		{"RO", "Bavaria", "", "county"},
+		{"Germany", "Bayern", "8033", "postal_code"},
+	}
+	for _, tc := range cases {
+		req = &CreateAddressRequest{StreetName: "Street", StreenNo: "10", City: "City", County: tc.county, Country: tc.country, PostalCode: tc.postalCode, ProfileId: 7}
+		var fieldErr *country.FieldError
+		if err := req.Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != tc.field {
+			t.Fatalf("expected %s error for %+v, got %v", tc.field, tc, err)
+		}
+	}
+}
+
 func TestNewCreateAddressRequestFromContext(t *testing.T) {
 	e := echo.New()
-	req := httptest.NewRequest("POST", "/addresses", strings.NewReader(`{"street_name":"Street","streen_no":"10","city":"City","county":"County","country":"Country","profile_id":7}`))
+	req := httptest.NewRequest("POST", "/addresses", strings.NewReader(`{"street_name":"Street","streen_no":"10","city":"City","county":"Cluj","country":"RO","profile_id":7}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
@@ -65,8 +101,8 @@ func TestUpdateAddressRequestValidate(t *testing.T) {
 		StreetName: "Street",
 		StreenNo:   "10",
 		City:       "City",
-		County:     "County",
-		Country:    "Country",
+		County:     "Cluj",
+		Country:    "RO",
 		ProfileId:  7,
 	}
 	if err := valid.Validate(); err != nil {
@@ -88,11 +124,34 @@ func TestListAddressesRequestValidate(t *testing.T) {
 	if err := (&ListAddressesRequest{ProfileId: 1, PageSize: 101}).Validate(); err == nil {
 		t.Fatal("expected validation error for page_size > 100")
 	}
+	if err := (&ListAddressesRequest{CompanyId: 2}).Validate(); err != nil {
+		t.Fatalf("expected valid company request, got %v", err)
+	}
+	if err := (&ListAddressesRequest{ProfileId: 1, CompanyId: 2}).Validate(); err == nil {
+		t.Fatal("expected validation error for both owners")
+	}
+}
+
+func TestCreateAddressRequestValidateOwner(t *testing.T) {
+	newReq := func(profileID, companyID uint64, addressType string) *CreateAddressRequest {
+		return &CreateAddressRequest{StreetName: "Street", StreenNo: "10", City: "City", County: "Cluj", Country: "RO", ProfileId: profileID, CompanyId: companyID, Type: addressType}
+	}
+
+	if err := newReq(0, 4, "registered_office").Validate(); err != nil {
+		t.Fatalf("expected valid company address, got %v", err)
+	}
+	if err := newReq(7, 4, "billing").Validate(); err == nil {
+		t.Fatal("expected validation error for both owners")
+	}
+	var fieldErr *country.FieldError
+	if err := newReq(0, 4, "shipping").Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != "type" {
+		t.Fatalf("expected type error, got %v", err)
+	}
 }
 
 func TestNewListAddressesRequestFromContext(t *testing.T) {
 	e := echo.New()
-	req := httptest.NewRequest("GET", "/addresses?profile_id=7&page=2&page_size=30&type=billing", nil)
+	req := httptest.NewRequest("GET", "/addresses?profile_id=7&page=2&page_size=30&type=billing&primary_only=true", nil)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
 
@@ -100,7 +159,151 @@ func TestNewListAddressesRequestFromContext(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected parse success, got %v", err)
 	}
-	if parsed.GetProfileId() != 7 || parsed.GetPage() != 2 || parsed.GetPageSize() != 30 || parsed.GetType() != "billing" {
+	if parsed.GetProfileId() != 7 || parsed.GetPage() != 2 || parsed.GetPageSize() != 30 || parsed.GetType() != "billing" || !parsed.GetPrimaryOnly() {
 		t.Fatalf("unexpected parsed values: %+v", parsed)
 	}
 }
+
+func TestPatchAddressRequestValidate(t *testing.T) {
+	valid := &PatchAddressRequest{Id: 9, City: "Cluj", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"city", "postal_code"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	clearMandatory := &PatchAddressRequest{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"street_name"}}}
+	if err := clearMandatory.Validate(); err == nil {
+		t.Fatal("expected validation error when clearing street_name")
+	}
+}
+
+func TestNewSetPrimaryAddressRequestFromContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("POST", "/addresses/10/primary", nil)
+	req.Header.Set(HeaderIfMatch, `"4"`)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("10")
+
+	parsed, err := NewSetPrimaryAddressRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if parsed.GetId() != 10 || parsed.GetExpectedVersion() != 4 {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+	if err = (&SetPrimaryAddressRequest{}).Validate(); err == nil {
+		t.Fatal("expected validation error for missing id")
+	}
+}
+
+func TestAddressRequestCoordinates(t *testing.T) {
+	latitude, longitude, outOfRange := 46.7712, 23.6236, 91.0
+	req := &CreateAddressRequest{StreetName: "Street", StreenNo: "10", City: "City", County: "Cluj", Country: "RO", ProfileId: 7, Latitude: &latitude, Longitude: &longitude}
+	if err := req.Validate(); err != nil || !req.HasCoordinates() {
+		t.Fatalf("expected valid coordinates, got %v", err)
+	}
+	req.Longitude = nil
+	if err := req.Validate(); err == nil {
+		t.Fatal("expected validation error for latitude without longitude")
+	}
+	req.Latitude, req.Longitude = &outOfRange, &longitude
+	if err := req.Validate(); err == nil {
+		t.Fatal("expected validation error for latitude out of range")
+	}
+
+	patch := &PatchAddressRequest{Id: 9, Latitude: &latitude, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"latitude"}}}
+	if err := patch.Validate(); err == nil {
+		t.Fatal("expected validation error when patching latitude alone")
+	}
+	patch = &PatchAddressRequest{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"latitude", "longitude"}}}
+	if err := patch.Validate(); err != nil || patch.HasCoordinates() {
+		t.Fatalf("expected clearing the coordinates to be valid, got %v", err)
+	}
+}
+
+func TestNewPatchAddressRequestFromContextReadsCoordinates(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("PATCH", "/addresses/10", strings.NewReader(`{"latitude":46.7712,"longitude":null}`))
+	req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("10")
+
+	parsed, err := NewPatchAddressRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if parsed.Latitude == nil || *parsed.Latitude != 46.7712 || parsed.Longitude != nil || len(parsed.GetUpdateMask().GetPaths()) != 2 {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+	if err = parsed.Validate(); err == nil {
+		t.Fatal("expected validation error for latitude without longitude")
+	}
+}
+
+func TestNewSearchAddressesNearRequestFromContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("GET", "/addresses/near?lat=46.7712&lng=23.6236&radius_m=2500&profile_id=7&type=billing&limit=5", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	parsed, err := NewSearchAddressesNearRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if center := parsed.CenterPoint(); center == nil || center.Latitude != 46.7712 || center.Longitude != 23.6236 || parsed.Box() != nil {
+		t.Fatalf("unexpected center: %+v", parsed)
+	}
+	if parsed.GetRadiusMeters() != 2500 || parsed.GetProfileId() != 7 || parsed.GetType() != "billing" || parsed.GetLimit() != 5 {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+
+	req = httptest.NewRequest("GET", "/addresses/near?bbox=23.5,46.7,23.7,46.8", nil)
+	if parsed, err = NewSearchAddressesNearRequestFromContext(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if box := parsed.Box(); box == nil || box.MinLongitude != 23.5 || box.MinLatitude != 46.7 || box.MaxLongitude != 23.7 || box.MaxLatitude != 46.8 || parsed.GetLimit() != 20 {
+		t.Fatalf("unexpected box: %+v", parsed)
+	}
+
+	req = httptest.NewRequest("GET", "/addresses/near?bbox=23.5,46.7,23.7", nil)
+	if _, err = NewSearchAddressesNearRequestFromContext(e.NewContext(req, httptest.NewRecorder())); err == nil {
+		t.Fatal("expected parse error for a bbox of three values")
+	}
+}
+
+func TestSearchAddressesNearRequestValidate(t *testing.T) {
+	center := &GeoPoint{Latitude: 46.7712, Longitude: 23.6236}
+	bbox := &BoundingBox{MinLatitude: 46.7, MinLongitude: 23.5, MaxLatitude: 46.8, MaxLongitude: 23.7}
+	cases := []*SearchAddressesNearRequest{
+		{},
+		{Center: center, Bbox: bbox, RadiusMeters: 100},
+		{Center: center},
+		{Center: &GeoPoint{Latitude: 91}, RadiusMeters: 100},
+		{Bbox: bbox, RadiusMeters: 100},
+		{Bbox: &BoundingBox{MinLatitude: 46.7, MinLongitude: 179, MaxLatitude: 46.8, MaxLongitude: -179}},
+		{Center: center, RadiusMeters: 100, Limit: 101},
+	}
+	for _, req := range cases {
+		if err := req.Validate(); err == nil {
+			t.Fatalf("expected validation error for %+v", req)
+		}
+	}
+}
+
+func TestAddressFormatFromContext(t *testing.T) {
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest("GET", "/addresses/10?format=label", nil), httptest.NewRecorder())
+	if format, err := AddressFormatFromContext(ctx); err != nil || format != AddressFormatLabel {
+		t.Fatalf("expected the label format, got %q, %v", format, err)
+	}
+
+	ctx = e.NewContext(httptest.NewRequest("GET", "/addresses/10?format=html", nil), httptest.NewRecorder())
+	if _, err := AddressFormatFromContext(ctx); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
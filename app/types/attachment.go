@@ -0,0 +1,238 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxAttachmentPageSize     = 100
+	defaultAttachmentPage     = 1
+	defaultAttachmentPerPage  = 20
+	maxAttachmentFilename     = 255
+	maxAttachmentContentType  = 127
+	attachmentFilePart        = "file"
+	maxAttachmentFormFieldLen = 256
+)
+
+var (
+	attachmentOwners = map[string]bool{
+		"profile": true,
+		"contact": true,
+		"address": true,
+		"company": true,
+	}
+	attachmentKindPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+// NewAttachmentUploadFromContext reads a multipart/form-data upload up to its "file" part. The
+// entity_type, entity_id and kind fields must come before it; the file part is returned to be
+// read by the caller and supplies the filename and declared content type.
+func NewAttachmentUploadFromContext(ctx echo.Context) (*AttachmentUpload, io.Reader, error) {
+	reader, err := ctx.Request().MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := &AttachmentUpload{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("multipart body has no %q part", attachmentFilePart)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FormName() == attachmentFilePart {
+			req.Filename = path.Base(strings.ReplaceAll(part.FileName(), `\`, "/"))
+			if req.Filename == "." || req.Filename == "/" {
+				req.Filename = ""
+			}
+			req.ContentType = part.Header.Get(echo.HeaderContentType)
+			return req, part, nil
+		}
+
+		if err = readAttachmentField(req, part); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func readAttachmentField(req *AttachmentUpload, part *multipart.Part) error {
+	raw, err := io.ReadAll(io.LimitReader(part, maxAttachmentFormFieldLen+1))
+	if err != nil {
+		return err
+	}
+	if len(raw) > maxAttachmentFormFieldLen {
+		return fmt.Errorf("%s must be at most %d bytes", part.FormName(), maxAttachmentFormFieldLen)
+	}
+	value := strings.TrimSpace(string(raw))
+
+	switch part.FormName() {
+	case "entity_type":
+		req.EntityType = value
+	case "entity_id":
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		req.EntityId = id
+	case "kind":
+		req.Kind = value
+	}
+
+	return nil
+}
+
+func (r *AttachmentUpload) Validate() error {
+	if err := validateAttachmentOwner(r.EntityType, r.EntityId); err != nil {
+		return err
+	}
+	if r.Kind != "" {
+		if err := validateAttachmentKind(r.Kind); err != nil {
+			return err
+		}
+	}
+	if len(r.Filename) > maxAttachmentFilename {
+		return fmt.Errorf("filename must be at most %d bytes", maxAttachmentFilename)
+	}
+	if strings.ContainsAny(r.Filename, `/\`) || strings.IndexFunc(r.Filename, unicode.IsControl) >= 0 {
+		return errors.New("filename must be a base name without control characters")
+	}
+	if len(r.ContentType) > maxAttachmentContentType {
+		return fmt.Errorf("content_type must be at most %d bytes", maxAttachmentContentType)
+	}
+
+	return nil
+}
+
+func NewGetAttachmentRequestFromContext(ctx echo.Context) (*GetAttachmentRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetAttachmentRequest{Id: id}, nil
+}
+
+func (r *GetAttachmentRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+func NewDownloadAttachmentRequestFromContext(ctx echo.Context) (*DownloadAttachmentRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DownloadAttachmentRequest{Id: id}, nil
+}
+
+func (r *DownloadAttachmentRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+func NewDeleteAttachmentRequestFromContext(ctx echo.Context) (*DeleteAttachmentRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteAttachmentRequest{Id: id}, nil
+}
+
+func (r *DeleteAttachmentRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+func NewListAttachmentsRequestFromContext(ctx echo.Context) (*ListAttachmentsRequest, error) {
+	req := &ListAttachmentsRequest{
+		EntityType: strings.TrimSpace(ctx.QueryParam("entity_type")),
+		Kind:       strings.TrimSpace(ctx.QueryParam("kind")),
+		Page:       defaultAttachmentPage,
+		PageSize:   defaultAttachmentPerPage,
+	}
+
+	if rawEntityID := strings.TrimSpace(ctx.QueryParam("entity_id")); rawEntityID != "" {
+		entityID, err := strconv.ParseUint(rawEntityID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		req.EntityId = entityID
+	}
+
+	if rawPage := strings.TrimSpace(ctx.QueryParam("page")); rawPage != "" {
+		page, err := strconv.ParseUint(rawPage, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		req.Page = uint32(page)
+	}
+
+	if rawPageSize := strings.TrimSpace(ctx.QueryParam("page_size")); rawPageSize != "" {
+		pageSize, err := strconv.ParseUint(rawPageSize, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		req.PageSize = uint32(pageSize)
+	}
+
+	return req, nil
+}
+
+func (r *ListAttachmentsRequest) Validate() error {
+	if err := validateAttachmentOwner(r.EntityType, r.EntityId); err != nil {
+		return err
+	}
+	if r.Kind != "" {
+		if err := validateAttachmentKind(r.Kind); err != nil {
+			return err
+		}
+	}
+	if r.PageSize > maxAttachmentPageSize {
+		return errors.New("page_size must be less than or equal to 100")
+	}
+
+	return nil
+}
+
+func validateAttachmentOwner(entityType string, entityID uint64) error {
+	if !attachmentOwners[entityType] {
+		return errors.New("entity_type must be one of profile, contact, address, company")
+	}
+	if entityID == 0 {
+		return errors.New("entity_id is required")
+	}
+
+	return nil
+}
+
+func validateAttachmentKind(kind string) error {
+	if !attachmentKindPattern.MatchString(kind) {
+		return errors.New("kind must be 1-32 lower case letters, digits or '_', starting with a letter")
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNewAttachmentUploadFromContext(t *testing.T) {
+	body := "--b\r\n" +
+		"Content-Disposition: form-data; name=\"entity_type\"\r\n\r\ncontact\r\n" +
+		"--b\r\n" +
+		"Content-Disposition: form-data; name=\"entity_id\"\r\n\r\n 4 \r\n" +
+		"--b\r\n" +
+		"Content-Disposition: form-data; name=\"kind\"\r\n\r\nid_card\r\n" +
+		"--b\r\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"C:\\\\scans\\\\card.pdf\"\r\n" +
+		"Content-Type: application/pdf\r\n\r\n%PDF-1.7\r\n" +
+		"--b--\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/attachments", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "multipart/form-data; boundary=b")
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+	parsed, content, err := NewAttachmentUploadFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if parsed.EntityType != "contact" || parsed.EntityId != 4 || parsed.Kind != "id_card" || parsed.Filename != "card.pdf" || parsed.ContentType != "application/pdf" {
+		t.Fatalf("unexpected request: %+v", parsed)
+	}
+	data, err := io.ReadAll(content)
+	if err != nil || string(data) != "%PDF-1.7" {
+		t.Fatalf("unexpected content %q, err %v", data, err)
+	}
+}
+
+func TestNewAttachmentUploadFromContextRequiresFilePart(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"entity_type\"\r\n\r\nprofile\r\n--b--\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/attachments", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "multipart/form-data; boundary=b")
+
+	if _, _, err := NewAttachmentUploadFromContext(echo.New().NewContext(req, httptest.NewRecorder())); err == nil {
+		t.Fatal("expected an error for a body without a file part")
+	}
+}
+
+func TestAttachmentRequestsValidate(t *testing.T) {
+	invalid := []interface{ Validate() error }{
+		&AttachmentUpload{EntityType: "preference", EntityId: 1},
+		&AttachmentUpload{EntityType: "profile"},
+		&AttachmentUpload{EntityType: "profile", EntityId: 1, Kind: "Avatar"},
+		&AttachmentUpload{EntityType: "profile", EntityId: 1, Filename: "a\nb.png"},
+		&AttachmentUpload{EntityType: "profile", EntityId: 1, Filename: "../b.png"},
+		&ListAttachmentsRequest{EntityType: "company", EntityId: 1, PageSize: 101},
+		&GetAttachmentRequest{},
+		&DownloadAttachmentRequest{},
+		&DeleteAttachmentRequest{},
+	}
+	for _, req := range invalid {
+		if err := req.Validate(); err == nil {
+			t.Fatalf("expected validation error for %+v", req)
+		}
+	}
+
+	if err := (&ListAttachmentsRequest{EntityType: "address", EntityId: 2, Kind: "floor_plan"}).Validate(); err != nil {
+		t.Fatalf("expected valid list request, got %v", err)
+	}
+}
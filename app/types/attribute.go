@@ -0,0 +1,101 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// attributesQueryPrefix starts the list query params that filter by attribute, as in
+// ?attributes.floor_code=2B.
+const attributesQueryPrefix = "attributes."
+
+// attributeFiltersFromQuery reads the attribute filters of a list request, or nil when there
+// are none.
+func attributeFiltersFromQuery(ctx echo.Context) map[string]string {
+	var filters map[string]string
+	for key, values := range ctx.QueryParams() {
+		name, ok := strings.CutPrefix(key, attributesQueryPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if filters == nil {
+			filters = make(map[string]string)
+		}
+		filters[name] = strings.TrimSpace(values[0])
+	}
+
+	return filters
+}
+
+func validateAttributeFilters(filters map[string]string) error {
+	for name := range filters {
+		if !attribute.ValidName(name) {
+			return fmt.Errorf("invalid attribute filter %q", name)
+		}
+	}
+
+	return nil
+}
+
+// bindStructPatch decodes the merge patch member name into values, one update mask path per
+// key (name.key), so that only the keys present in the patch change. A null member is the
+// single path name, which clears every key.
+func bindStructPatch(name string, raw json.RawMessage, values **structpb.Struct) ([]string, error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return []string{name}, nil
+	}
+
+	var members map[string]any
+	if err := json.Unmarshal(raw, &members); err != nil || members == nil {
+		return nil, fmt.Errorf("invalid value for %s: must be a JSON object", name)
+	}
+	decoded, err := structpb.NewStruct(members)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	*values = decoded
+
+	paths := make([]string, 0, len(members))
+	for key := range members {
+		if key == "" {
+			return nil, errors.New(name + " keys cannot be empty")
+		}
+		paths = append(paths, name+"."+key)
+	}
+
+	return paths, nil
+}
+
+// The responses below are rendered by encoding/json over HTTP, which would show a
+// google.protobuf.Struct as its Go fields; they write attributes as a plain JSON object.
+
+func (r *ContactResponse) MarshalJSON() ([]byte, error) {
+	type contactResponse ContactResponse
+	return json.Marshal(struct {
+		*contactResponse
+		Attributes map[string]any `json:"attributes,omitempty"`
+	}{(*contactResponse)(r), attribute.Values(r.GetAttributes())})
+}
+
+func (r *AddressResponse) MarshalJSON() ([]byte, error) {
+	type addressResponse AddressResponse
+	return json.Marshal(struct {
+		*addressResponse
+		Attributes map[string]any `json:"attributes,omitempty"`
+	}{(*addressResponse)(r), attribute.Values(r.GetAttributes())})
+}
+
+func (r *CompanyResponse) MarshalJSON() ([]byte, error) {
+	type companyResponse CompanyResponse
+	return json.Marshal(struct {
+		*companyResponse
+		Attributes map[string]any `json:"attributes,omitempty"`
+	}{(*companyResponse)(r), attribute.Values(r.GetAttributes())})
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestNewPatchRequestFromContextMasksEachAttribute(t *testing.T) {
+	ctx := newPatchContext(`{"attributes":{"dock":"B","gate":null},"city":"Cluj-Napoca"}`, "application/merge-patch+json")
+
+	parsed, err := NewPatchAddressRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.GetUpdateMask().GetPaths(), []string{"attributes.dock", "attributes.gate", "city"}) {
+		t.Fatalf("unexpected update mask: %v", parsed.GetUpdateMask().GetPaths())
+	}
+	if parsed.GetAttributes().GetFields()["dock"].GetStringValue() != "B" {
+		t.Fatalf("unexpected attributes: %v", parsed.GetAttributes())
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	cleared, err := NewPatchCompanyRequestFromContext(newPatchContext(`{"attributes":null}`, echo.MIMEApplicationJSON))
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if !reflect.DeepEqual(cleared.GetUpdateMask().GetPaths(), []string{"attributes"}) {
+		t.Fatalf("unexpected update mask: %v", cleared.GetUpdateMask().GetPaths())
+	}
+
+	for _, body := range []string{`{"attributes":"dock"}`, `{"attributes":{"":"B"}}`} {
+		if _, err = NewPatchContactRequestFromContext(newPatchContext(body, echo.MIMEApplicationJSON)); err == nil {
+			t.Fatalf("expected parse error for %s", body)
+		}
+	}
+}
+
+func TestNewListRequestsReadAttributeFilters(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("GET", "/addresses?profile_id=7&attributes.dock=%20B%20&attributes.floor_code=2", nil)
+
+	parsed, err := NewListAddressesRequestFromContext(e.NewContext(req, httptest.NewRecorder()))
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.GetAttributes(), map[string]string{"dock": "B", "floor_code": "2"}) {
+		t.Fatalf("unexpected attribute filters: %v", parsed.GetAttributes())
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	req = httptest.NewRequest("GET", "/contacts?profile_id=7&attributes.Job-Title=CTO", nil)
+	contacts, err := NewListContactsRequestFromContext(e.NewContext(req, httptest.NewRecorder()))
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if err = contacts.Validate(); err == nil || !strings.Contains(err.Error(), "Job-Title") {
+		t.Fatalf("expected invalid attribute filter error, got %v", err)
+	}
+}
+
+func TestNewCreateProfileRequestFromContextReadsChildAttributes(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("POST", "/profiles", strings.NewReader(`{"user_id":12,"email":"a@b.com","contact":{"first_name":"John","attributes":{"job_title":"CTO"}}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	parsed, err := NewCreateProfileRequestFromContext(e.NewContext(req, httptest.NewRecorder()))
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if parsed.GetContact().GetAttributes().GetFields()["job_title"].GetStringValue() != "CTO" || parsed.GetAddress() != nil {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+}
+
+func TestResponsesWriteAttributesAsAnObject(t *testing.T) {
+	attributes, _ := structpb.NewStruct(map[string]any{"dock": "B", "floor": float64(2)})
+	body, err := json.Marshal(&ListAddressesResponse{Addresses: []*AddressResponse{{Id: 3, Attributes: attributes}}})
+	if err != nil {
+		t.Fatalf("expected marshal success, got %v", err)
+	}
+	if !strings.Contains(string(body), `"attributes":{"dock":"B","floor":2}`) || !strings.Contains(string(body), `"id":3`) {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	body, err = json.Marshal(&ContactResponse{Id: 4})
+	if err != nil {
+		t.Fatalf("expected marshal success, got %v", err)
+	}
+	if strings.Contains(string(body), "attributes") {
+		t.Fatalf("expected no attributes member, got %s", body)
+	}
+}
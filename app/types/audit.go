@@ -0,0 +1,75 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxAuditPageSize    = 100
+	defaultAuditPage    = 1
+	defaultAuditPerPage = 20
+)
+
+var auditEntities = map[string]bool{
+	"profile": true,
+	"contact": true,
+	"address": true,
+	"company": true,
+	// company_member events are keyed by company id.
+	"company_member": true,
+	// preference events are keyed by profile id.
+	"preference": true,
+	"attachment": true,
+}
+
+func NewListAuditEventsRequestFromContext(ctx echo.Context) (*ListAuditEventsRequest, error) {
+	req := &ListAuditEventsRequest{
+		Entity:   strings.TrimSpace(ctx.QueryParam("entity")),
+		Page:     defaultAuditPage,
+		PageSize: defaultAuditPerPage,
+	}
+
+	if rawID := strings.TrimSpace(ctx.QueryParam("id")); rawID != "" {
+		id, err := strconv.ParseUint(rawID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		req.EntityId = id
+	}
+
+	if rawPage := strings.TrimSpace(ctx.QueryParam("page")); rawPage != "" {
+		page, err := strconv.ParseUint(rawPage, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		req.Page = uint32(page)
+	}
+
+	if rawPageSize := strings.TrimSpace(ctx.QueryParam("page_size")); rawPageSize != "" {
+		pageSize, err := strconv.ParseUint(rawPageSize, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		req.PageSize = uint32(pageSize)
+	}
+
+	return req, nil
+}
+
+func (r *ListAuditEventsRequest) Validate() error {
+	if !auditEntities[r.Entity] {
+		return errors.New("entity must be one of profile, contact, address, company, company_member, preference, attachment")
+	}
+	if r.EntityId == 0 {
+		return errors.New("id is required")
+	}
+	if r.PageSize > maxAuditPageSize {
+		return errors.New("page_size must be less than or equal to 100")
+	}
+
+	return nil
+}
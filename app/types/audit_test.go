@@ -0,0 +1,42 @@
+package types
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestListAuditEventsRequestValidate(t *testing.T) {
+	if err := (&ListAuditEventsRequest{Entity: "contact", EntityId: 3, PageSize: 100}).Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if err := (&ListAuditEventsRequest{Entity: "user", EntityId: 3}).Validate(); err == nil {
+		t.Fatal("expected validation error for unknown entity")
+	}
+	if err := (&ListAuditEventsRequest{Entity: "profile"}).Validate(); err == nil {
+		t.Fatal("expected validation error for missing id")
+	}
+	if err := (&ListAuditEventsRequest{Entity: "profile", EntityId: 3, PageSize: 101}).Validate(); err == nil {
+		t.Fatal("expected validation error for page_size > 100")
+	}
+}
+
+func TestNewListAuditEventsRequestFromContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("GET", "/audit?entity=address&id=12&page=2&page_size=5", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+
+	parsed, err := NewListAuditEventsRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if parsed.GetEntity() != "address" || parsed.GetEntityId() != 12 || parsed.GetPage() != 2 || parsed.GetPageSize() != 5 {
+		t.Fatalf("unexpected parsed values: %+v", parsed)
+	}
+
+	req = httptest.NewRequest("GET", "/audit?entity=address&id=abc", nil)
+	if _, err = NewListAuditEventsRequestFromContext(e.NewContext(req, httptest.NewRecorder())); err == nil {
+		t.Fatal("expected parse error for non-numeric id")
+	}
+}
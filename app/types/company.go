@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
 )
 
 const (
@@ -15,19 +16,21 @@ const (
 )
 
 type createCompanyBody struct {
-	Name           string `json:"name"`
-	RegistrationNo string `json:"registration_no"`
-	FiscalCode     string `json:"fiscal_code"`
-	ProfileID      uint64 `json:"profile_id"`
-	Type           string `json:"type"`
+	Name           string         `json:"name"`
+	RegistrationNo string         `json:"registration_no"`
+	FiscalCode     string         `json:"fiscal_code"`
+	ProfileID      uint64         `json:"profile_id"`
+	Type           string         `json:"type"`
+	Attributes     map[string]any `json:"attributes"`
 }
 
 type updateCompanyBody struct {
-	Name           string `json:"name"`
-	RegistrationNo string `json:"registration_no"`
-	FiscalCode     string `json:"fiscal_code"`
-	ProfileID      uint64 `json:"profile_id"`
-	Type           string `json:"type"`
+	Name           string         `json:"name"`
+	RegistrationNo string         `json:"registration_no"`
+	FiscalCode     string         `json:"fiscal_code"`
+	ProfileID      uint64         `json:"profile_id"`
+	Type           string         `json:"type"`
+	Attributes     map[string]any `json:"attributes"`
 }
 
 func NewCreateCompanyRequestFromContext(ctx echo.Context) (*CreateCompanyRequest, error) {
@@ -42,6 +45,7 @@ func NewCreateCompanyRequestFromContext(ctx echo.Context) (*CreateCompanyRequest
 		FiscalCode:     body.FiscalCode,
 		ProfileId:      body.ProfileID,
 		Type:           body.Type,
+		Attributes:     attribute.Struct(body.Attributes),
 	}, nil
 }
 
@@ -68,7 +72,19 @@ func NewGetCompanyRequestFromContext(ctx echo.Context) (*GetCompanyRequest, erro
 		return nil, err
 	}
 
-	return &GetCompanyRequest{Id: id}, nil
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &GetCompanyRequest{Id: id, IncludeDeleted: includeDeleted}
+	if raw := strings.TrimSpace(ctx.QueryParam("include_addresses")); raw != "" {
+		if req.IncludeAddresses, err = strconv.ParseBool(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
 }
 
 func (r *GetCompanyRequest) Validate() error {
@@ -90,14 +106,20 @@ func NewUpdateCompanyRequestFromContext(ctx echo.Context) (*UpdateCompanyRequest
 		return nil, err
 	}
 
-	return &UpdateCompanyRequest{
+	req := &UpdateCompanyRequest{
 		Id:             id,
 		Name:           body.Name,
 		RegistrationNo: body.RegistrationNo,
 		FiscalCode:     body.FiscalCode,
 		ProfileId:      body.ProfileID,
 		Type:           body.Type,
-	}, nil
+		Attributes:     attribute.Struct(body.Attributes),
+	}
+	if req.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
 }
 
 func (r *UpdateCompanyRequest) Validate() error {
@@ -120,13 +142,75 @@ func (r *UpdateCompanyRequest) Validate() error {
 	return nil
 }
 
+var companyPatchPaths = map[string]struct{}{
+	"name":            {},
+	"registration_no": {},
+	"fiscal_code":     {},
+	"profile_id":      {},
+	"type":            {},
+	"attributes":      {},
+	"attributes.*":    {},
+}
+
+func NewPatchCompanyRequestFromContext(ctx echo.Context) (*PatchCompanyRequest, error) {
+	req := &PatchCompanyRequest{}
+	id, mask, err := bindMergePatch(ctx, mergePatchFields{
+		"name":            &req.Name,
+		"registration_no": &req.RegistrationNo,
+		"fiscal_code":     &req.FiscalCode,
+		"profile_id":      &req.ProfileId,
+		"type":            &req.Type,
+		"attributes":      &req.Attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Id = id
+	req.UpdateMask = mask
+	if req.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Validate checks only the fields named in update_mask; mandatory fields cannot be cleared.
+func (r *PatchCompanyRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+	paths, err := updateMaskPaths(r.UpdateMask, companyPatchPaths)
+	if err != nil {
+		return err
+	}
+	if hasPath(paths, "name") && strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if hasPath(paths, "registration_no") && strings.TrimSpace(r.RegistrationNo) == "" {
+		return errors.New("registration_no is required")
+	}
+	if hasPath(paths, "fiscal_code") && strings.TrimSpace(r.FiscalCode) == "" {
+		return errors.New("fiscal_code is required")
+	}
+	if hasPath(paths, "profile_id") && r.ProfileId == 0 {
+		return errors.New("profile_id is required")
+	}
+
+	return nil
+}
+
 func NewDeleteCompanyRequestFromContext(ctx echo.Context) (*DeleteCompanyRequest, error) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	return &DeleteCompanyRequest{Id: id}, nil
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteCompanyRequest{Id: id, ExpectedVersion: expectedVersion}, nil
 }
 
 func (r *DeleteCompanyRequest) Validate() error {
@@ -137,6 +221,45 @@ func (r *DeleteCompanyRequest) Validate() error {
 	return nil
 }
 
+func NewRestoreCompanyRequestFromContext(ctx echo.Context) (*RestoreCompanyRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestoreCompanyRequest{Id: id}, nil
+}
+
+func (r *RestoreCompanyRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+func NewSetPrimaryCompanyRequestFromContext(ctx echo.Context) (*SetPrimaryCompanyRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetPrimaryCompanyRequest{Id: id, ExpectedVersion: expectedVersion}, nil
+}
+
+func (r *SetPrimaryCompanyRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
 func NewListCompaniesRequestFromContext(ctx echo.Context) (*ListCompaniesRequest, error) {
 	req := &ListCompaniesRequest{
 		Page:     defaultCompanyPage,
@@ -168,6 +291,19 @@ func NewListCompaniesRequestFromContext(ctx echo.Context) (*ListCompaniesRequest
 	}
 	req.Type = strings.TrimSpace(ctx.QueryParam("type"))
 
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.IncludeDeleted = includeDeleted
+
+	primaryOnly, err := primaryOnlyFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.PrimaryOnly = primaryOnly
+	req.Attributes = attributeFiltersFromQuery(ctx)
+
 	return req, nil
 }
 
@@ -179,5 +315,5 @@ func (r *ListCompaniesRequest) Validate() error {
 		return errors.New("page_size must be less than or equal to 100")
 	}
 
-	return nil
+	return validateAttributeFilters(r.Attributes)
 }
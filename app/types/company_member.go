@@ -0,0 +1,155 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var companyMemberRoles = map[string]bool{
+	"owner":      true,
+	"admin":      true,
+	"accountant": true,
+	"viewer":     true,
+}
+
+type addCompanyMemberBody struct {
+	Role string `json:"role"`
+}
+
+func NewAddCompanyMemberRequestFromContext(ctx echo.Context) (*AddCompanyMemberRequest, error) {
+	companyID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	profileID, err := strconv.ParseUint(ctx.Param("profile_id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var body addCompanyMemberBody
+	if err = ctx.Bind(&body); err != nil {
+		return nil, err
+	}
+
+	return &AddCompanyMemberRequest{
+		CompanyId: companyID,
+		ProfileId: profileID,
+		Role:      strings.TrimSpace(body.Role),
+	}, nil
+}
+
+func (r *AddCompanyMemberRequest) Validate() error {
+	if r.CompanyId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if r.ProfileId == 0 {
+		return errors.New("profile_id is required")
+	}
+	if !companyMemberRoles[r.Role] {
+		return errors.New("role must be one of owner, admin, accountant, viewer")
+	}
+
+	return nil
+}
+
+func NewRemoveCompanyMemberRequestFromContext(ctx echo.Context) (*RemoveCompanyMemberRequest, error) {
+	companyID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	profileID, err := strconv.ParseUint(ctx.Param("profile_id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoveCompanyMemberRequest{CompanyId: companyID, ProfileId: profileID}, nil
+}
+
+func (r *RemoveCompanyMemberRequest) Validate() error {
+	if r.CompanyId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if r.ProfileId == 0 {
+		return errors.New("profile_id is required")
+	}
+
+	return nil
+}
+
+func NewListCompanyMembersRequestFromContext(ctx echo.Context) (*ListCompanyMembersRequest, error) {
+	companyID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ListCompanyMembersRequest{CompanyId: companyID}
+	if req.Page, req.PageSize, err = companyPageFromQuery(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (r *ListCompanyMembersRequest) Validate() error {
+	if r.CompanyId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if r.PageSize > maxCompanyPageSize {
+		return errors.New("page_size must be less than or equal to 100")
+	}
+
+	return nil
+}
+
+func NewListProfileCompaniesRequestFromContext(ctx echo.Context) (*ListProfileCompaniesRequest, error) {
+	profileID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &ListProfileCompaniesRequest{ProfileId: profileID}
+	if req.Page, req.PageSize, err = companyPageFromQuery(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (r *ListProfileCompaniesRequest) Validate() error {
+	if r.ProfileId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if r.PageSize > maxCompanyPageSize {
+		return errors.New("page_size must be less than or equal to 100")
+	}
+
+	return nil
+}
+
+// companyPageFromQuery reads the page and page_size query params, defaulting them as the
+// company list does.
+func companyPageFromQuery(ctx echo.Context) (uint32, uint32, error) {
+	page, pageSize := uint32(defaultCompanyPage), uint32(defaultCompanyPerPage)
+	if rawPage := strings.TrimSpace(ctx.QueryParam("page")); rawPage != "" {
+		parsed, err := strconv.ParseUint(rawPage, 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		page = uint32(parsed)
+	}
+
+	if rawPageSize := strings.TrimSpace(ctx.QueryParam("page_size")); rawPageSize != "" {
+		parsed, err := strconv.ParseUint(rawPageSize, 10, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+		pageSize = uint32(parsed)
+	}
+
+	return page, pageSize, nil
+}
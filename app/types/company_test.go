@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestCreateCompanyRequestValidate(t *testing.T) {
@@ -100,3 +101,72 @@ func TestNewListCompaniesRequestFromContext(t *testing.T) {
 		t.Fatalf("unexpected parsed values: %+v", parsed)
 	}
 }
+
+func TestNewListCompaniesRequestFromContextPrimaryOnly(t *testing.T) {
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest("GET", "/companies?profile_id=7&primary_only=1", nil), httptest.NewRecorder())
+	parsed, err := NewListCompaniesRequestFromContext(ctx)
+	if err != nil || !parsed.GetPrimaryOnly() {
+		t.Fatalf("expected primary_only to be parsed, got %+v, %v", parsed, err)
+	}
+
+	ctx = e.NewContext(httptest.NewRequest("GET", "/companies?profile_id=7&primary_only=maybe", nil), httptest.NewRecorder())
+	if _, err = NewListCompaniesRequestFromContext(ctx); err == nil {
+		t.Fatal("expected parse error for invalid primary_only")
+	}
+}
+
+func TestPatchCompanyRequestValidate(t *testing.T) {
+	valid := &PatchCompanyRequest{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"type"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	clearMandatory := &PatchCompanyRequest{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"fiscal_code"}}}
+	if err := clearMandatory.Validate(); err == nil {
+		t.Fatal("expected validation error when clearing fiscal_code")
+	}
+}
+
+func TestNewAddCompanyMemberRequestFromContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("PUT", "/companies/5/members/8", strings.NewReader(`{"role":" accountant "}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	ctx.SetParamNames("id", "profile_id")
+	ctx.SetParamValues("5", "8")
+
+	parsed, err := NewAddCompanyMemberRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if parsed.GetCompanyId() != 5 || parsed.GetProfileId() != 8 || parsed.GetRole() != "accountant" {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	parsed.Role = "auditor"
+	if err = parsed.Validate(); err == nil {
+		t.Fatal("expected validation error for an unknown role")
+	}
+}
+
+func TestNewListCompanyMembersRequestFromContext(t *testing.T) {
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest("GET", "/companies/5/members?page=2&page_size=150", nil), httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+
+	parsed, err := NewListCompanyMembersRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if parsed.GetCompanyId() != 5 || parsed.GetPage() != 2 {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+	if err = parsed.Validate(); err == nil {
+		t.Fatal("expected validation error for page_size over 100")
+	}
+}
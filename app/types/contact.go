@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/nin"
+	"github.com/vibast-solutions/ms-go-profile/app/phone"
 )
 
 const (
@@ -17,23 +20,27 @@ const (
 )
 
 type createContactBody struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Nin       string `json:"nin"`
-	Dob       string `json:"dob"`
-	Phone     string `json:"phone"`
-	ProfileID uint64 `json:"profile_id"`
-	Type      string `json:"type"`
+	FirstName  string         `json:"first_name"`
+	LastName   string         `json:"last_name"`
+	Nin        string         `json:"nin"`
+	NinCountry string         `json:"nin_country"`
+	Dob        string         `json:"dob"`
+	Phone      string         `json:"phone"`
+	ProfileID  uint64         `json:"profile_id"`
+	Type       string         `json:"type"`
+	Attributes map[string]any `json:"attributes"`
 }
 
 type updateContactBody struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Nin       string `json:"nin"`
-	Dob       string `json:"dob"`
-	Phone     string `json:"phone"`
-	ProfileID uint64 `json:"profile_id"`
-	Type      string `json:"type"`
+	FirstName  string         `json:"first_name"`
+	LastName   string         `json:"last_name"`
+	Nin        string         `json:"nin"`
+	NinCountry string         `json:"nin_country"`
+	Dob        string         `json:"dob"`
+	Phone      string         `json:"phone"`
+	ProfileID  uint64         `json:"profile_id"`
+	Type       string         `json:"type"`
+	Attributes map[string]any `json:"attributes"`
 }
 
 func NewCreateContactRequestFromContext(ctx echo.Context) (*CreateContactRequest, error) {
@@ -43,23 +50,26 @@ func NewCreateContactRequestFromContext(ctx echo.Context) (*CreateContactRequest
 		return nil, err
 	}
 
+	return body.request(), nil
+}
+
+func (b *createContactBody) request() *CreateContactRequest {
 	return &CreateContactRequest{
-		FirstName: body.FirstName,
-		LastName:  body.LastName,
-		Nin:       body.Nin,
-		Dob:       body.Dob,
-		Phone:     body.Phone,
-		ProfileId: body.ProfileID,
-		Type:      body.Type,
-	}, nil
+		FirstName:  b.FirstName,
+		LastName:   b.LastName,
+		Nin:        b.Nin,
+		NinCountry: b.NinCountry,
+		Dob:        b.Dob,
+		Phone:      b.Phone,
+		ProfileId:  b.ProfileID,
+		Type:       b.Type,
+		Attributes: attribute.Struct(b.Attributes),
+	}
 }
 
 func (r *CreateContactRequest) Validate() error {
-	rawDOB := strings.TrimSpace(r.Dob)
-	if rawDOB != "" {
-		if _, err := time.Parse(contactDOBLayout, rawDOB); err != nil {
-			return errors.New("dob must be in YYYY-MM-DD format")
-		}
+	if err := r.validateFields(); err != nil {
+		return err
 	}
 	if r.ProfileId == 0 {
 		return errors.New("profile_id is required")
@@ -68,13 +78,64 @@ func (r *CreateContactRequest) Validate() error {
 	return nil
 }
 
+// validateFields checks everything except profile_id, which nested requests do not carry.
+func (r *CreateContactRequest) validateFields() error {
+	if err := normalizeContactNIN(&r.NinCountry, &r.Nin, &r.Dob); err != nil {
+		return err
+	}
+
+	return validateContactPhone(r.Phone)
+}
+
+// normalizeContactNIN checks the nin against the rules of its country and the dob against the
+// nin, filling an empty dob from the nin. The country is replaced by its alpha-2 code.
+func normalizeContactNIN(ninCountry, value, dob *string) error {
+	contact := nin.Contact{Country: *ninCountry, NIN: *value}
+	if rawDOB := strings.TrimSpace(*dob); rawDOB != "" {
+		parsed, err := time.Parse(contactDOBLayout, rawDOB)
+		if err != nil {
+			return errors.New("dob must be in YYYY-MM-DD format")
+		}
+		contact.DOB = &parsed
+	}
+
+	normalized, err := nin.NormalizeContact(contact)
+	if err != nil {
+		return err
+	}
+	*ninCountry, *value = normalized.Country, normalized.NIN
+	if contact.DOB == nil && normalized.DOB != nil {
+		*dob = normalized.DOB.Format(contactDOBLayout)
+	}
+
+	return nil
+}
+
+// validateContactPhone rejects phones that are not a number in any region. Whether a
+// national number is valid depends on the profile's region, which the service checks.
+func validateContactPhone(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	if err := phone.CheckSyntax(raw); err != nil {
+		return errors.New("invalid phone number")
+	}
+
+	return nil
+}
+
 func NewGetContactRequestFromContext(ctx echo.Context) (*GetContactRequest, error) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	return &GetContactRequest{Id: id}, nil
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetContactRequest{Id: id, IncludeDeleted: includeDeleted}, nil
 }
 
 func (r *GetContactRequest) Validate() error {
@@ -97,27 +158,34 @@ func NewUpdateContactRequestFromContext(ctx echo.Context) (*UpdateContactRequest
 		return nil, err
 	}
 
-	return &UpdateContactRequest{
-		Id:        id,
-		FirstName: body.FirstName,
-		LastName:  body.LastName,
-		Nin:       body.Nin,
-		Dob:       body.Dob,
-		Phone:     body.Phone,
-		ProfileId: body.ProfileID,
-		Type:      body.Type,
-	}, nil
+	req := &UpdateContactRequest{
+		Id:         id,
+		FirstName:  body.FirstName,
+		LastName:   body.LastName,
+		Nin:        body.Nin,
+		NinCountry: body.NinCountry,
+		Dob:        body.Dob,
+		Phone:      body.Phone,
+		ProfileId:  body.ProfileID,
+		Type:       body.Type,
+		Attributes: attribute.Struct(body.Attributes),
+	}
+	if req.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
 }
 
 func (r *UpdateContactRequest) Validate() error {
 	if r.Id == 0 {
 		return errors.New("invalid id provided")
 	}
-	rawDOB := strings.TrimSpace(r.Dob)
-	if rawDOB != "" {
-		if _, err := time.Parse(contactDOBLayout, rawDOB); err != nil {
-			return errors.New("dob must be in YYYY-MM-DD format")
-		}
+	if err := normalizeContactNIN(&r.NinCountry, &r.Nin, &r.Dob); err != nil {
+		return err
+	}
+	if err := validateContactPhone(r.Phone); err != nil {
+		return err
 	}
 	if r.ProfileId == 0 {
 		return errors.New("profile_id is required")
@@ -126,13 +194,84 @@ func (r *UpdateContactRequest) Validate() error {
 	return nil
 }
 
+var contactPatchPaths = map[string]struct{}{
+	"first_name":   {},
+	"last_name":    {},
+	"nin":          {},
+	"nin_country":  {},
+	"dob":          {},
+	"phone":        {},
+	"profile_id":   {},
+	"type":         {},
+	"attributes":   {},
+	"attributes.*": {},
+}
+
+func NewPatchContactRequestFromContext(ctx echo.Context) (*PatchContactRequest, error) {
+	req := &PatchContactRequest{}
+	id, mask, err := bindMergePatch(ctx, mergePatchFields{
+		"first_name":  &req.FirstName,
+		"last_name":   &req.LastName,
+		"nin":         &req.Nin,
+		"nin_country": &req.NinCountry,
+		"dob":         &req.Dob,
+		"phone":       &req.Phone,
+		"profile_id":  &req.ProfileId,
+		"type":        &req.Type,
+		"attributes":  &req.Attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Id = id
+	req.UpdateMask = mask
+	if req.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Validate checks only the fields named in update_mask.
+func (r *PatchContactRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+	paths, err := updateMaskPaths(r.UpdateMask, contactPatchPaths)
+	if err != nil {
+		return err
+	}
+	if hasPath(paths, "dob") {
+		if rawDOB := strings.TrimSpace(r.Dob); rawDOB != "" {
+			if _, err = time.Parse(contactDOBLayout, rawDOB); err != nil {
+				return errors.New("dob must be in YYYY-MM-DD format")
+			}
+		}
+	}
+	if hasPath(paths, "phone") {
+		if err = validateContactPhone(r.Phone); err != nil {
+			return err
+		}
+	}
+	if hasPath(paths, "profile_id") && r.ProfileId == 0 {
+		return errors.New("profile_id is required")
+	}
+
+	return nil
+}
+
 func NewDeleteContactRequestFromContext(ctx echo.Context) (*DeleteContactRequest, error) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
-	return &DeleteContactRequest{Id: id}, nil
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteContactRequest{Id: id, ExpectedVersion: expectedVersion}, nil
 }
 
 func (r *DeleteContactRequest) Validate() error {
@@ -143,6 +282,45 @@ func (r *DeleteContactRequest) Validate() error {
 	return nil
 }
 
+func NewRestoreContactRequestFromContext(ctx echo.Context) (*RestoreContactRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestoreContactRequest{Id: id}, nil
+}
+
+func (r *RestoreContactRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+func NewSetPrimaryContactRequestFromContext(ctx echo.Context) (*SetPrimaryContactRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetPrimaryContactRequest{Id: id, ExpectedVersion: expectedVersion}, nil
+}
+
+func (r *SetPrimaryContactRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
 func NewListContactsRequestFromContext(ctx echo.Context) (*ListContactsRequest, error) {
 	req := &ListContactsRequest{
 		Page:     defaultContactPage,
@@ -173,6 +351,20 @@ func NewListContactsRequestFromContext(ctx echo.Context) (*ListContactsRequest,
 		req.PageSize = uint32(pageSize)
 	}
 	req.Type = strings.TrimSpace(ctx.QueryParam("type"))
+	req.Nin = strings.TrimSpace(ctx.QueryParam("nin"))
+
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.IncludeDeleted = includeDeleted
+
+	primaryOnly, err := primaryOnlyFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.PrimaryOnly = primaryOnly
+	req.Attributes = attributeFiltersFromQuery(ctx)
 
 	return req, nil
 }
@@ -182,5 +374,5 @@ func (r *ListContactsRequest) Validate() error {
 		return errors.New("page_size must be less than or equal to 100")
 	}
 
-	return nil
+	return validateAttributeFilters(r.Attributes)
 }
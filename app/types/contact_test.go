@@ -1,11 +1,14 @@
 package types
 
 import (
+	"errors"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestCreateContactRequestValidate(t *testing.T) {
@@ -19,6 +22,47 @@ func TestCreateContactRequestValidate(t *testing.T) {
 	if err := (&CreateContactRequest{}).Validate(); err == nil {
 		t.Fatal("expected validation error")
 	}
+
+	for _, raw := range []string{"0722 123 456", "+40 (722) 123-456"} {
+		if err := (&CreateContactRequest{ProfileId: 5, Phone: raw}).Validate(); err != nil {
+			t.Fatalf("expected phone %q to be accepted, got %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"call me", "12", "+40 12"} {
+		if err := (&CreateContactRequest{ProfileId: 5, Phone: raw}).Validate(); err == nil {
+			t.Fatalf("expected phone %q to be rejected", raw)
+		}
+	}
+}
+
+func TestCreateContactRequestValidateChecksNIN(t *testing.T) {
+	req := &CreateContactRequest{ProfileId: 5, Nin: "1800101120017", NinCountry: "romania"}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if req.NinCountry != "RO" || req.Dob != "1980-01-01" {
+		t.Fatalf("expected country code and dob from the CNP, got %+v", req)
+	}
+
+	cases := []struct {
+		nin, ninCountry, dob, field string
+	}{
+		{"1800101120018", "RO", "", "nin"},
+		{"1800101120017", "RO", "1980-01-02", "dob"},
+		{"1800101120017", "Romanistan", "", "nin_country"},
+	}
+	for _, tc := range cases {
+		req = &CreateContactRequest{ProfileId: 5, Nin: tc.nin, NinCountry: tc.ninCountry, Dob: tc.dob}
+		var fieldErr *country.FieldError
+		if err := req.Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != tc.field {
+			t.Fatalf("expected %s error for %+v, got %v", tc.field, tc, err)
+		}
+	}
+
+	// The same number without a country is not checked.
+	if err := (&CreateContactRequest{ProfileId: 5, Nin: "1800101120018", Dob: "1980-01-02"}).Validate(); err != nil {
+		t.Fatalf("expected unchecked nin to be accepted, got %v", err)
+	}
 }
 
 func TestNewCreateContactRequestFromContext(t *testing.T) {
@@ -72,11 +116,16 @@ func TestUpdateContactRequestValidate(t *testing.T) {
 	if err := badDOB.Validate(); err == nil {
 		t.Fatal("expected validation error for bad dob")
 	}
+
+	badPhone := &UpdateContactRequest{Id: 9, Phone: "ext. 12", ProfileId: 5}
+	if err := badPhone.Validate(); err == nil {
+		t.Fatal("expected validation error for bad phone")
+	}
 }
 
 func TestNewListContactsRequestFromContext(t *testing.T) {
 	e := echo.New()
-	req := httptest.NewRequest("GET", "/contacts?profile_id=5&page=2&page_size=30&type=emergency", nil)
+	req := httptest.NewRequest("GET", "/contacts?profile_id=5&page=2&page_size=30&type=emergency&nin=1900517223344", nil)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
 
@@ -84,7 +133,8 @@ func TestNewListContactsRequestFromContext(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected parse success, got %v", err)
 	}
-	if parsed.GetProfileId() != 5 || parsed.GetPage() != 2 || parsed.GetPageSize() != 30 || parsed.GetType() != "emergency" {
+	if parsed.GetProfileId() != 5 || parsed.GetPage() != 2 || parsed.GetPageSize() != 30 || parsed.GetType() != "emergency" ||
+		parsed.GetNin() != "1900517223344" {
 		t.Fatalf("unexpected parsed values: %+v", parsed)
 	}
 }
@@ -97,3 +147,23 @@ func TestListContactsRequestValidate(t *testing.T) {
 		t.Fatal("expected validation error for page_size > 100")
 	}
 }
+
+func TestPatchContactRequestValidate(t *testing.T) {
+	valid := &PatchContactRequest{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"phone"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid request without profile_id, got %v", err)
+	}
+
+	cases := []*PatchContactRequest{
+		{Id: 9},
+		{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"nickname"}}},
+		{Id: 9, Dob: "02-01-1990", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"dob"}}},
+		{Id: 9, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"profile_id"}}},
+		{Id: 9, Phone: "n/a", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"phone"}}},
+	}
+	for _, req := range cases {
+		if err := req.Validate(); err == nil {
+			t.Fatalf("expected validation error for %+v", req)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"strconv"
+	"time"
+)
+
+// Headers announcing that v1 of the API is deprecated.
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderLink        = "Link"
+)
+
+// V1DeprecatedAt is when profile.v2 superseded v1 of the API.
+var V1DeprecatedAt = time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)
+
+// V1Deprecation renders V1DeprecatedAt as the value of a Deprecation header (RFC 9745).
+func V1Deprecation() string {
+	return "@" + strconv.FormatInt(V1DeprecatedAt.Unix(), 10)
+}
+
+// SuccessorLink renders a Link header value pointing at the replacement of a deprecated
+// resource or service.
+func SuccessorLink(target string) string {
+	return "<" + target + `>; rel="successor-version"`
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	maxEmailLength      = 254
+	maxEmailLocalLength = 64
+)
+
+var errInvalidEmail = errors.New("invalid email")
+
+// NormalizeEmail checks that raw is a single RFC 5322 addr-spec, without a display name or
+// comments, and returns it with the domain in lower-case Unicode form. Internationalized
+// domains may be given in Unicode or punycode; both normalize to the same value. The local
+// part is kept as sent, since only the receiving server may interpret its case.
+func NormalizeEmail(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	at := strings.LastIndex(raw, "@")
+	if at <= 0 || strings.ContainsAny(raw, "<>") {
+		return "", errInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(raw)
+	if err != nil || addr.Name != "" {
+		return "", errInvalidEmail
+	}
+
+	local, domain := raw[:at], raw[at+1:]
+	if len(local) > maxEmailLocalLength {
+		return "", errInvalidEmail
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(ascii, ".") || len(local)+1+len(ascii) > maxEmailLength {
+		return "", errInvalidEmail
+	}
+	unicode, err := idna.Lookup.ToUnicode(ascii)
+	if err != nil {
+		return "", errInvalidEmail
+	}
+
+	return local + "@" + unicode, nil
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	cases := map[string]string{
+		"john@example.com":                "john@example.com",
+		"  John.Doe@Example.COM ":         "John.Doe@example.com",
+		"a+tag@sub.example.co.uk":         "a+tag@sub.example.co.uk",
+		`"john doe"@example.com`:          `"john doe"@example.com`,
+		"jöhn@MÜLLER.de":                  "jöhn@müller.de",
+		"info@xn--mller-kva.de":           "info@müller.de",
+		"o'brien@example.org":             "o'brien@example.org",
+		strings.Repeat("a", 64) + "@x.io": strings.Repeat("a", 64) + "@x.io",
+	}
+	for raw, want := range cases {
+		got, err := NormalizeEmail(raw)
+		if err != nil {
+			t.Fatalf("NormalizeEmail(%q) returned error: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("NormalizeEmail(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestNormalizeEmailRejectsInvalid(t *testing.T) {
+	cases := []string{
+		"",
+		"john",
+		"@example.com",
+		"john@",
+		"john@localhost",
+		"john@@example.com",
+		"john doe@example.com",
+		"John <john@example.com>",
+		"<john@example.com>",
+		"john@example.com (John)",
+		"john@[192.168.0.1]",
+		"john@exa_mple.com",
+		"john@-example.com",
+		"a@b.com, c@d.com",
+		strings.Repeat("a", 65) + "@example.com",
+		"john@" + strings.Repeat("a", 63) + "." + strings.Repeat("b", 63) + "." + strings.Repeat("c", 63) + "." + strings.Repeat("d", 60) + ".com",
+	}
+	for _, raw := range cases {
+		if got, err := NormalizeEmail(raw); err == nil {
+			t.Fatalf("NormalizeEmail(%q): expected error, got %q", raw, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/geo"
+)
+
+const (
+	defaultNearbyLimit = 20
+	maxNearbyLimit     = 100
+)
+
+type searchAddressesNearQuery struct {
+	ProfileID uint64 `query:"profile_id"`
+	Type      string `query:"type"`
+	Limit     uint32 `query:"limit"`
+}
+
+// HasCoordinates reports whether the request sets latitude and longitude.
+func (r *CreateAddressRequest) HasCoordinates() bool {
+	return r.Latitude != nil && r.Longitude != nil
+}
+
+// HasCoordinates reports whether the request sets latitude and longitude.
+func (r *UpdateAddressRequest) HasCoordinates() bool {
+	return r.Latitude != nil && r.Longitude != nil
+}
+
+// HasCoordinates reports whether the request sets latitude and longitude; with both masked
+// and neither set, the patch clears them.
+func (r *PatchAddressRequest) HasCoordinates() bool {
+	return r.Latitude != nil && r.Longitude != nil
+}
+
+// validateCoordinates checks that latitude and longitude are given together and in range.
+func validateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude and longitude must be set together")
+	}
+	if latitude != nil && !(geo.Point{Latitude: *latitude, Longitude: *longitude}).Valid() {
+		return geo.ErrInvalidPoint
+	}
+
+	return nil
+}
+
+// NewSearchAddressesNearRequestFromContext reads a radius search from lat, lng and radius_m,
+// or a box search from bbox=min_lng,min_lat,max_lng,max_lat, the GeoJSON order.
+func NewSearchAddressesNearRequestFromContext(ctx echo.Context) (*SearchAddressesNearRequest, error) {
+	query := &searchAddressesNearQuery{Limit: defaultNearbyLimit}
+	if err := ctx.Bind(query); err != nil {
+		return nil, err
+	}
+	req := &SearchAddressesNearRequest{
+		ProfileId: query.ProfileID,
+		Type:      strings.TrimSpace(query.Type),
+		Limit:     query.Limit,
+	}
+
+	lat, lng := strings.TrimSpace(ctx.QueryParam("lat")), strings.TrimSpace(ctx.QueryParam("lng"))
+	if lat != "" || lng != "" {
+		values, err := parseFloats("lat,lng", lat, lng)
+		if err != nil {
+			return nil, err
+		}
+		req.Center = &GeoPoint{Latitude: values[0], Longitude: values[1]}
+	}
+	if radius := strings.TrimSpace(ctx.QueryParam("radius_m")); radius != "" {
+		values, err := parseFloats("radius_m", radius)
+		if err != nil {
+			return nil, err
+		}
+		req.RadiusMeters = values[0]
+	}
+	if bbox := strings.TrimSpace(ctx.QueryParam("bbox")); bbox != "" {
+		values, err := parseFloats("bbox", strings.Split(bbox, ",")...)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 4 {
+			return nil, errors.New("bbox must be min_lng,min_lat,max_lng,max_lat")
+		}
+		req.Bbox = &BoundingBox{MinLongitude: values[0], MinLatitude: values[1], MaxLongitude: values[2], MaxLatitude: values[3]}
+	}
+
+	return req, nil
+}
+
+func parseFloats(name string, raw ...string) ([]float64, error) {
+	values := make([]float64, len(raw))
+	for i, s := range raw {
+		value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func (r *SearchAddressesNearRequest) Validate() error {
+	if (r.Center == nil) == (r.Bbox == nil) {
+		return errors.New("exactly one of center and bbox is required")
+	}
+	if r.Center != nil {
+		if !r.CenterPoint().Valid() {
+			return geo.ErrInvalidPoint
+		}
+		if r.RadiusMeters <= 0 {
+			return errors.New("radius_meters must be greater than 0")
+		}
+	}
+	if r.Bbox != nil {
+		if r.RadiusMeters != 0 {
+			return errors.New("radius_meters only applies to a center search")
+		}
+		if !r.Box().Valid() {
+			return errors.New("bbox must hold valid coordinates with minimums not above maximums and may not cross the antimeridian")
+		}
+	}
+	if r.Limit > maxNearbyLimit {
+		return errors.New("limit must be less than or equal to 100")
+	}
+
+	return nil
+}
+
+// CenterPoint returns the center of a radius search, or nil for a box search.
+func (r *SearchAddressesNearRequest) CenterPoint() *geo.Point {
+	if r.Center == nil {
+		return nil
+	}
+
+	return &geo.Point{Latitude: r.Center.GetLatitude(), Longitude: r.Center.GetLongitude()}
+}
+
+// Box returns the area of a box search, or nil for a radius search.
+func (r *SearchAddressesNearRequest) Box() *geo.Box {
+	if r.Bbox == nil {
+		return nil
+	}
+
+	return &geo.Box{
+		MinLatitude:  r.Bbox.GetMinLatitude(),
+		MinLongitude: r.Bbox.GetMinLongitude(),
+		MaxLatitude:  r.Bbox.GetMaxLatitude(),
+		MaxLongitude: r.Bbox.GetMaxLongitude(),
+	}
+}
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+var (
+	ErrUnsupportedPatchContentType = errors.New("patch body must be application/merge-patch+json or application/json")
+)
+
+// mergePatchFields maps the JSON member names accepted by a PATCH body to the
+// request fields they are decoded into.
+type mergePatchFields map[string]any
+
+// bindMergePatch reads the `id` path param and a JSON Merge Patch (RFC 7386) body.
+// Every member present in the body is decoded into its field and reported as an
+// update mask path; a null member leaves the field at its zero value, which clears it.
+// A member decoded into a google.protobuf.Struct is merged key by key, as bindStructPatch
+// describes.
+func bindMergePatch(ctx echo.Context, fields mergePatchFields) (uint64, *fieldmaskpb.FieldMask, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if rawType := ctx.Request().Header.Get(echo.HeaderContentType); rawType != "" {
+		mediaType, _, err := mime.ParseMediaType(rawType)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != echo.MIMEApplicationJSON) {
+			return 0, nil, ErrUnsupportedPatchContentType
+		}
+	}
+
+	var patch map[string]json.RawMessage
+	if err = json.NewDecoder(ctx.Request().Body).Decode(&patch); err != nil {
+		return 0, nil, err
+	}
+	if patch == nil {
+		return 0, nil, errors.New("patch body must be a JSON object")
+	}
+
+	paths := make([]string, 0, len(patch))
+	for name, raw := range patch {
+		target, ok := fields[name]
+		if !ok {
+			return 0, nil, fmt.Errorf("unknown field %q", name)
+		}
+		if values, ok := target.(**structpb.Struct); ok {
+			structPaths, err := bindStructPatch(name, raw, values)
+			if err != nil {
+				return 0, nil, err
+			}
+			paths = append(paths, structPaths...)
+			continue
+		}
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if err = json.Unmarshal(raw, target); err != nil {
+				return 0, nil, fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+		}
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+
+	return id, &fieldmaskpb.FieldMask{Paths: paths}, nil
+}
+
+// updateMaskPaths checks that the mask is not empty and only names allowed fields,
+// and returns its paths as a set. An allowed "field.*" entry allows every field.key path.
+func updateMaskPaths(mask *fieldmaskpb.FieldMask, allowed map[string]struct{}) (map[string]struct{}, error) {
+	if len(mask.GetPaths()) == 0 {
+		return nil, errors.New("update_mask must contain at least one field")
+	}
+
+	paths := make(map[string]struct{}, len(mask.GetPaths()))
+	for _, path := range mask.GetPaths() {
+		if _, ok := allowed[path]; !ok {
+			field, key, nested := strings.Cut(path, ".")
+			if _, ok = allowed[field+".*"]; !nested || key == "" || !ok {
+				return nil, fmt.Errorf("update_mask contains unknown field %q", path)
+			}
+		}
+		paths[path] = struct{}{}
+	}
+
+	return paths, nil
+}
+
+func hasPath(paths map[string]struct{}, path string) bool {
+	_, ok := paths[path]
+	return ok
+}
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newPatchContext(body, contentType string) echo.Context {
+	e := echo.New()
+	req := httptest.NewRequest("PATCH", "/contacts/10", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("10")
+	return ctx
+}
+
+func TestNewPatchContactRequestFromContext(t *testing.T) {
+	ctx := newPatchContext(`{"phone":"+40 700 000 000","dob":null}`, "application/merge-patch+json")
+
+	parsed, err := NewPatchContactRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if parsed.GetId() != 10 || parsed.GetPhone() != "+40 700 000 000" || parsed.GetDob() != "" {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+	if !reflect.DeepEqual(parsed.GetUpdateMask().GetPaths(), []string{"dob", "phone"}) {
+		t.Fatalf("unexpected update mask: %v", parsed.GetUpdateMask().GetPaths())
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+}
+
+func TestNewPatchRequestFromContextRejectsBadBodies(t *testing.T) {
+	cases := map[string]string{
+		"unknown field": `{"nickname":"x"}`,
+		"wrong type":    `{"profile_id":"five"}`,
+		"not an object": `["phone"]`,
+		"null document": `null`,
+	}
+	for name, body := range cases {
+		if _, err := NewPatchContactRequestFromContext(newPatchContext(body, echo.MIMEApplicationJSON)); err == nil {
+			t.Fatalf("%s: expected parse error", name)
+		}
+	}
+}
+
+func TestNewPatchRequestFromContextRejectsContentType(t *testing.T) {
+	_, err := NewPatchCompanyRequestFromContext(newPatchContext(`{"name":"ACME"}`, echo.MIMETextPlain))
+	if !errors.Is(err, ErrUnsupportedPatchContentType) {
+		t.Fatalf("expected ErrUnsupportedPatchContentType, got %v", err)
+	}
+}
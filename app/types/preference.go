@@ -0,0 +1,146 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// maxPreferenceKeys bounds the keys one SetPreferences call may write.
+	maxPreferenceKeys = 100
+	// maxPreferenceValueBytes bounds the JSON encoding of a single value.
+	maxPreferenceValueBytes = 8 << 10
+)
+
+var (
+	// Namespaces double as schema file names, so they are kept to lower case.
+	preferenceNamespacePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+	preferenceKeyPattern       = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+)
+
+type setPreferencesBody struct {
+	Values map[string]any `json:"values"`
+}
+
+func NewGetPreferencesRequestFromContext(ctx echo.Context) (*GetPreferencesRequest, error) {
+	profileID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetPreferencesRequest{
+		ProfileId: profileID,
+		Namespace: strings.TrimSpace(ctx.QueryParam("namespace")),
+	}, nil
+}
+
+func (r *GetPreferencesRequest) Validate() error {
+	if r.ProfileId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if r.Namespace != "" {
+		return validatePreferenceNamespace(r.Namespace)
+	}
+
+	return nil
+}
+
+func NewSetPreferencesRequestFromContext(ctx echo.Context) (*SetPreferencesRequest, error) {
+	profileID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var body setPreferencesBody
+	if err = ctx.Bind(&body); err != nil {
+		return nil, err
+	}
+	values, err := structpb.NewStruct(body.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetPreferencesRequest{
+		ProfileId: profileID,
+		Namespace: ctx.Param("namespace"),
+		Values:    values,
+	}, nil
+}
+
+func (r *SetPreferencesRequest) Validate() error {
+	if r.ProfileId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if err := validatePreferenceNamespace(r.Namespace); err != nil {
+		return err
+	}
+
+	fields := r.GetValues().GetFields()
+	if len(fields) == 0 {
+		return errors.New("values must hold at least one key")
+	}
+	if len(fields) > maxPreferenceKeys {
+		return fmt.Errorf("values must hold at most %d keys", maxPreferenceKeys)
+	}
+	for key, value := range fields {
+		if err := validatePreferenceKey(key); err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(value.AsInterface())
+		if err != nil {
+			return err
+		}
+		if len(encoded) > maxPreferenceValueBytes {
+			return fmt.Errorf("value of %s must be at most %d bytes of JSON", key, maxPreferenceValueBytes)
+		}
+	}
+
+	return nil
+}
+
+func NewDeletePreferenceRequestFromContext(ctx echo.Context) (*DeletePreferenceRequest, error) {
+	profileID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeletePreferenceRequest{
+		ProfileId: profileID,
+		Namespace: ctx.Param("namespace"),
+		Key:       ctx.Param("key"),
+	}, nil
+}
+
+func (r *DeletePreferenceRequest) Validate() error {
+	if r.ProfileId == 0 {
+		return errors.New("invalid id provided")
+	}
+	if err := validatePreferenceNamespace(r.Namespace); err != nil {
+		return err
+	}
+
+	return validatePreferenceKey(r.Key)
+}
+
+func validatePreferenceNamespace(namespace string) error {
+	if !preferenceNamespacePattern.MatchString(namespace) {
+		return errors.New("namespace must be 1-64 lower case letters, digits, '_', '-' or '.', starting with a letter")
+	}
+
+	return nil
+}
+
+func validatePreferenceKey(key string) error {
+	if !preferenceKeyPattern.MatchString(key) {
+		return fmt.Errorf("key %q must be 1-128 letters, digits, '_', '-' or '.'", key)
+	}
+
+	return nil
+}
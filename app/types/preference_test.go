@@ -0,0 +1,68 @@
+package types
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestNewSetPreferencesRequestFromContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/profiles/7/preferences/locale", strings.NewReader(`{"values":{"language":"ro","digest_hour":7,"theme":null}}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	ctx.SetParamNames("id", "namespace")
+	ctx.SetParamValues("7", "locale")
+
+	parsed, err := NewSetPreferencesRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got %v", err)
+	}
+	if err = parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	fields := parsed.GetValues().GetFields()
+	if parsed.ProfileId != 7 || parsed.Namespace != "locale" || fields["language"].GetStringValue() != "ro" || fields["digest_hour"].GetNumberValue() != 7 {
+		t.Fatalf("unexpected request: %+v", parsed)
+	}
+	if _, isNull := fields["theme"].GetKind().(*structpb.Value_NullValue); !isNull {
+		t.Fatalf("expected theme to be null, got %v", fields["theme"])
+	}
+}
+
+func TestSetPreferencesRequestValidate(t *testing.T) {
+	values := func(m map[string]any) *structpb.Struct {
+		s, err := structpb.NewStruct(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	cases := []*SetPreferencesRequest{
+		{Namespace: "locale", Values: values(map[string]any{"language": "ro"})},
+		{ProfileId: 7, Namespace: "Locale", Values: values(map[string]any{"language": "ro"})},
+		{ProfileId: 7, Namespace: "locale/../x", Values: values(map[string]any{"language": "ro"})},
+		{ProfileId: 7, Namespace: "locale", Values: values(map[string]any{})},
+		{ProfileId: 7, Namespace: "locale", Values: values(map[string]any{"bad key": "ro"})},
+		{ProfileId: 7, Namespace: "locale", Values: values(map[string]any{"bio": strings.Repeat("x", maxPreferenceValueBytes)})},
+	}
+	for _, req := range cases {
+		if err := req.Validate(); err == nil {
+			t.Fatalf("expected validation error for %+v", req)
+		}
+	}
+}
+
+func TestDeletePreferenceRequestValidate(t *testing.T) {
+	if err := (&DeletePreferenceRequest{ProfileId: 7, Namespace: "billing", Key: "currency"}).Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+	if err := (&DeletePreferenceRequest{ProfileId: 7, Namespace: "billing"}).Validate(); err == nil {
+		t.Fatal("expected validation error for a missing key")
+	}
+}
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// primaryOnlyFromQuery reads the optional primary_only query flag of the list endpoints.
+func primaryOnlyFromQuery(ctx echo.Context) (bool, error) {
+	raw := strings.TrimSpace(ctx.QueryParam("primary_only"))
+	if raw == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(raw)
+}
@@ -2,19 +2,43 @@ package types
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
 )
 
+// createProfileBody decodes the nested contact and address through their own bodies, which
+// read custom attributes as plain JSON objects.
+type createProfileBody struct {
+	UserID  uint64             `json:"user_id"`
+	Email   string             `json:"email"`
+	Contact *createContactBody `json:"contact"`
+	Address *createAddressBody `json:"address"`
+}
+
 func NewCreateProfileRequestFromContext(ctx echo.Context) (*CreateProfileRequest, error) {
-	var body CreateProfileRequest
+	var body createProfileBody
 	err := ctx.Bind(&body)
 	if err != nil {
 		return nil, err
 	}
 
-	return &body, nil
+	req := &CreateProfileRequest{
+		UserId: body.UserID,
+		Email:  body.Email,
+	}
+	if body.Contact != nil {
+		req.Contact = body.Contact.request()
+	}
+	if body.Address != nil {
+		req.Address = body.Address.request()
+	}
+
+	return req, nil
 }
 
 func (r *CreateProfileRequest) Validate() error {
@@ -24,6 +48,32 @@ func (r *CreateProfileRequest) Validate() error {
 	if r.Email == "" {
 		return errors.New("email is required")
 	}
+	email, err := NormalizeEmail(r.Email)
+	if err != nil {
+		return err
+	}
+	r.Email = email
+	if r.Contact != nil {
+		if err := r.Contact.validateFields(); err != nil {
+			var fieldErr *country.FieldError
+			if errors.As(err, &fieldErr) {
+				return &country.FieldError{Field: "contact." + fieldErr.Field, Message: "contact: " + fieldErr.Message}
+			}
+			return fmt.Errorf("contact: %w", err)
+		}
+	}
+	if r.Address != nil {
+		if err := r.Address.validateFields(); err != nil {
+			var fieldErr *country.FieldError
+			if errors.As(err, &fieldErr) {
+				return &country.FieldError{Field: "address." + fieldErr.Field, Message: "address: " + fieldErr.Message}
+			}
+			return fmt.Errorf("address: %w", err)
+		}
+		if r.Address.CompanyId != 0 {
+			return errors.New("address: company_id cannot be set on the address of a new profile")
+		}
+	}
 
 	return nil
 }
@@ -34,7 +84,12 @@ func NewGetProfileRequestFromContext(ctx echo.Context) (*GetProfileRequest, erro
 		return nil, err
 	}
 
-	return &GetProfileRequest{Id: id}, nil
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetProfileRequest{Id: id, IncludeDeleted: includeDeleted}, nil
 }
 
 func (r *GetProfileRequest) Validate() error {
@@ -51,7 +106,12 @@ func NewGetProfileByUserIDRequestFromContext(ctx echo.Context) (*GetProfileByUse
 		return nil, err
 	}
 
-	return &GetProfileByUserIDRequest{UserId: userID}, nil
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetProfileByUserIDRequest{UserId: userID, IncludeDeleted: includeDeleted}, nil
 }
 
 func (r *GetProfileByUserIDRequest) Validate() error {
@@ -62,6 +122,35 @@ func (r *GetProfileByUserIDRequest) Validate() error {
 	return nil
 }
 
+func NewGetProfileByEmailRequestFromContext(ctx echo.Context) (*GetProfileByEmailRequest, error) {
+	// Echo routes on the raw path when it holds escaped reserved characters (an escaped '/'
+	// or '+' in the local part), leaving the parameter undecoded.
+	email := ctx.Param("email")
+	if ctx.Request().URL.RawPath != "" {
+		var err error
+		if email, err = url.PathUnescape(email); err != nil {
+			return nil, err
+		}
+	}
+
+	includeDeleted, err := includeDeletedFromQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetProfileByEmailRequest{Email: email, IncludeDeleted: includeDeleted}, nil
+}
+
+func (r *GetProfileByEmailRequest) Validate() error {
+	email, err := NormalizeEmail(r.Email)
+	if err != nil {
+		return err
+	}
+	r.Email = email
+
+	return nil
+}
+
 func NewUpdateProfileRequestFromContext(ctx echo.Context) (*UpdateProfileRequest, error) {
 	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
 	if err != nil {
@@ -75,6 +164,9 @@ func NewUpdateProfileRequestFromContext(ctx echo.Context) (*UpdateProfileRequest
 	}
 
 	body.Id = id
+	if body.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
 
 	return &body, nil
 }
@@ -84,11 +176,50 @@ func (r *UpdateProfileRequest) Validate() error {
 		return errors.New("invalid id provided")
 	}
 
-	if r.Email == "" {
-		return errors.New("invalid email")
+	email, err := NormalizeEmail(r.Email)
+	if err != nil {
+		return err
 	}
+	r.Email = email
+
+	return nil
+}
+
+var profilePatchPaths = map[string]struct{}{
+	"email": {},
+}
 
-	//TODO: properly validate email
+func NewPatchProfileRequestFromContext(ctx echo.Context) (*PatchProfileRequest, error) {
+	req := &PatchProfileRequest{}
+	id, mask, err := bindMergePatch(ctx, mergePatchFields{
+		"email": &req.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Id = id
+	req.UpdateMask = mask
+	if req.ExpectedVersion, err = ExpectedVersionFromIfMatch(ctx); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Validate checks only the fields named in update_mask.
+func (r *PatchProfileRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+	paths, err := updateMaskPaths(r.UpdateMask, profilePatchPaths)
+	if err != nil {
+		return err
+	}
+	if hasPath(paths, "email") {
+		if r.Email, err = NormalizeEmail(r.Email); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -99,7 +230,12 @@ func NewDeleteProfileRequestFromContext(ctx echo.Context) (*DeleteProfileRequest
 		return nil, err
 	}
 
-	return &DeleteProfileRequest{Id: id}, nil
+	expectedVersion, err := ExpectedVersionFromIfMatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteProfileRequest{Id: id, ExpectedVersion: expectedVersion}, nil
 }
 
 func (r *DeleteProfileRequest) Validate() error {
@@ -109,3 +245,58 @@ func (r *DeleteProfileRequest) Validate() error {
 
 	return nil
 }
+
+func NewRestoreProfileRequestFromContext(ctx echo.Context) (*RestoreProfileRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestoreProfileRequest{Id: id}, nil
+}
+
+func (r *RestoreProfileRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+
+	return nil
+}
+
+var profileBundleIncludes = map[string]struct{}{
+	"contacts":  {},
+	"addresses": {},
+	"companies": {},
+}
+
+// NewGetProfileBundleRequestFromContext reads `include` as repeated and/or comma-separated query values.
+func NewGetProfileBundleRequestFromContext(ctx echo.Context) (*GetProfileBundleRequest, error) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	include := make([]string, 0)
+	for _, raw := range ctx.QueryParams()["include"] {
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+				include = append(include, part)
+			}
+		}
+	}
+
+	return &GetProfileBundleRequest{Id: id, Include: include}, nil
+}
+
+func (r *GetProfileBundleRequest) Validate() error {
+	if r.Id == 0 {
+		return errors.New("invalid id provided")
+	}
+	for _, include := range r.Include {
+		if _, ok := profileBundleIncludes[include]; !ok {
+			return fmt.Errorf("invalid include %q: allowed values are contacts, addresses, companies", include)
+		}
+	}
+
+	return nil
+}
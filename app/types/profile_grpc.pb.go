@@ -19,26 +19,53 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	ProfileService_CreateProfile_FullMethodName      = "/profile.ProfileService/CreateProfile"
-	ProfileService_GetProfile_FullMethodName         = "/profile.ProfileService/GetProfile"
-	ProfileService_GetProfileByUserID_FullMethodName = "/profile.ProfileService/GetProfileByUserID"
-	ProfileService_UpdateProfile_FullMethodName      = "/profile.ProfileService/UpdateProfile"
-	ProfileService_DeleteProfile_FullMethodName      = "/profile.ProfileService/DeleteProfile"
-	ProfileService_CreateContact_FullMethodName      = "/profile.ProfileService/CreateContact"
-	ProfileService_GetContact_FullMethodName         = "/profile.ProfileService/GetContact"
-	ProfileService_UpdateContact_FullMethodName      = "/profile.ProfileService/UpdateContact"
-	ProfileService_DeleteContact_FullMethodName      = "/profile.ProfileService/DeleteContact"
-	ProfileService_ListContacts_FullMethodName       = "/profile.ProfileService/ListContacts"
-	ProfileService_CreateAddress_FullMethodName      = "/profile.ProfileService/CreateAddress"
-	ProfileService_GetAddress_FullMethodName         = "/profile.ProfileService/GetAddress"
-	ProfileService_UpdateAddress_FullMethodName      = "/profile.ProfileService/UpdateAddress"
-	ProfileService_DeleteAddress_FullMethodName      = "/profile.ProfileService/DeleteAddress"
-	ProfileService_ListAddresses_FullMethodName      = "/profile.ProfileService/ListAddresses"
-	ProfileService_CreateCompany_FullMethodName      = "/profile.ProfileService/CreateCompany"
-	ProfileService_GetCompany_FullMethodName         = "/profile.ProfileService/GetCompany"
-	ProfileService_UpdateCompany_FullMethodName      = "/profile.ProfileService/UpdateCompany"
-	ProfileService_DeleteCompany_FullMethodName      = "/profile.ProfileService/DeleteCompany"
-	ProfileService_ListCompanies_FullMethodName      = "/profile.ProfileService/ListCompanies"
+	ProfileService_CreateProfile_FullMethodName        = "/profile.ProfileService/CreateProfile"
+	ProfileService_GetProfile_FullMethodName           = "/profile.ProfileService/GetProfile"
+	ProfileService_GetProfileByUserID_FullMethodName   = "/profile.ProfileService/GetProfileByUserID"
+	ProfileService_GetProfileByEmail_FullMethodName    = "/profile.ProfileService/GetProfileByEmail"
+	ProfileService_UpdateProfile_FullMethodName        = "/profile.ProfileService/UpdateProfile"
+	ProfileService_PatchProfile_FullMethodName         = "/profile.ProfileService/PatchProfile"
+	ProfileService_DeleteProfile_FullMethodName        = "/profile.ProfileService/DeleteProfile"
+	ProfileService_RestoreProfile_FullMethodName       = "/profile.ProfileService/RestoreProfile"
+	ProfileService_GetProfileBundle_FullMethodName     = "/profile.ProfileService/GetProfileBundle"
+	ProfileService_CreateContact_FullMethodName        = "/profile.ProfileService/CreateContact"
+	ProfileService_GetContact_FullMethodName           = "/profile.ProfileService/GetContact"
+	ProfileService_UpdateContact_FullMethodName        = "/profile.ProfileService/UpdateContact"
+	ProfileService_PatchContact_FullMethodName         = "/profile.ProfileService/PatchContact"
+	ProfileService_DeleteContact_FullMethodName        = "/profile.ProfileService/DeleteContact"
+	ProfileService_RestoreContact_FullMethodName       = "/profile.ProfileService/RestoreContact"
+	ProfileService_SetPrimaryContact_FullMethodName    = "/profile.ProfileService/SetPrimaryContact"
+	ProfileService_ListContacts_FullMethodName         = "/profile.ProfileService/ListContacts"
+	ProfileService_CreateAddress_FullMethodName        = "/profile.ProfileService/CreateAddress"
+	ProfileService_GetAddress_FullMethodName           = "/profile.ProfileService/GetAddress"
+	ProfileService_UpdateAddress_FullMethodName        = "/profile.ProfileService/UpdateAddress"
+	ProfileService_PatchAddress_FullMethodName         = "/profile.ProfileService/PatchAddress"
+	ProfileService_DeleteAddress_FullMethodName        = "/profile.ProfileService/DeleteAddress"
+	ProfileService_RestoreAddress_FullMethodName       = "/profile.ProfileService/RestoreAddress"
+	ProfileService_SetPrimaryAddress_FullMethodName    = "/profile.ProfileService/SetPrimaryAddress"
+	ProfileService_ListAddresses_FullMethodName        = "/profile.ProfileService/ListAddresses"
+	ProfileService_SearchAddressesNear_FullMethodName  = "/profile.ProfileService/SearchAddressesNear"
+	ProfileService_CreateCompany_FullMethodName        = "/profile.ProfileService/CreateCompany"
+	ProfileService_GetCompany_FullMethodName           = "/profile.ProfileService/GetCompany"
+	ProfileService_UpdateCompany_FullMethodName        = "/profile.ProfileService/UpdateCompany"
+	ProfileService_PatchCompany_FullMethodName         = "/profile.ProfileService/PatchCompany"
+	ProfileService_DeleteCompany_FullMethodName        = "/profile.ProfileService/DeleteCompany"
+	ProfileService_RestoreCompany_FullMethodName       = "/profile.ProfileService/RestoreCompany"
+	ProfileService_SetPrimaryCompany_FullMethodName    = "/profile.ProfileService/SetPrimaryCompany"
+	ProfileService_ListCompanies_FullMethodName        = "/profile.ProfileService/ListCompanies"
+	ProfileService_AddCompanyMember_FullMethodName     = "/profile.ProfileService/AddCompanyMember"
+	ProfileService_RemoveCompanyMember_FullMethodName  = "/profile.ProfileService/RemoveCompanyMember"
+	ProfileService_ListCompanyMembers_FullMethodName   = "/profile.ProfileService/ListCompanyMembers"
+	ProfileService_ListProfileCompanies_FullMethodName = "/profile.ProfileService/ListProfileCompanies"
+	ProfileService_GetPreferences_FullMethodName       = "/profile.ProfileService/GetPreferences"
+	ProfileService_SetPreferences_FullMethodName       = "/profile.ProfileService/SetPreferences"
+	ProfileService_DeletePreference_FullMethodName     = "/profile.ProfileService/DeletePreference"
+	ProfileService_UploadAttachment_FullMethodName     = "/profile.ProfileService/UploadAttachment"
+	ProfileService_DownloadAttachment_FullMethodName   = "/profile.ProfileService/DownloadAttachment"
+	ProfileService_GetAttachment_FullMethodName        = "/profile.ProfileService/GetAttachment"
+	ProfileService_ListAttachments_FullMethodName      = "/profile.ProfileService/ListAttachments"
+	ProfileService_DeleteAttachment_FullMethodName     = "/profile.ProfileService/DeleteAttachment"
+	ProfileService_ListAuditEvents_FullMethodName      = "/profile.ProfileService/ListAuditEvents"
 )
 
 // ProfileServiceClient is the client API for ProfileService service.
@@ -48,23 +75,52 @@ type ProfileServiceClient interface {
 	CreateProfile(ctx context.Context, in *CreateProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
 	GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
 	GetProfileByUserID(ctx context.Context, in *GetProfileByUserIDRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
+	GetProfileByEmail(ctx context.Context, in *GetProfileByEmailRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
 	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
+	PatchProfile(ctx context.Context, in *PatchProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
 	DeleteProfile(ctx context.Context, in *DeleteProfileRequest, opts ...grpc.CallOption) (*DeleteProfileResponse, error)
+	RestoreProfile(ctx context.Context, in *RestoreProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
+	GetProfileBundle(ctx context.Context, in *GetProfileBundleRequest, opts ...grpc.CallOption) (*ProfileBundleResponse, error)
 	CreateContact(ctx context.Context, in *CreateContactRequest, opts ...grpc.CallOption) (*ContactResponse, error)
 	GetContact(ctx context.Context, in *GetContactRequest, opts ...grpc.CallOption) (*ContactResponse, error)
 	UpdateContact(ctx context.Context, in *UpdateContactRequest, opts ...grpc.CallOption) (*ContactResponse, error)
+	PatchContact(ctx context.Context, in *PatchContactRequest, opts ...grpc.CallOption) (*ContactResponse, error)
 	DeleteContact(ctx context.Context, in *DeleteContactRequest, opts ...grpc.CallOption) (*DeleteContactResponse, error)
+	RestoreContact(ctx context.Context, in *RestoreContactRequest, opts ...grpc.CallOption) (*ContactResponse, error)
+	SetPrimaryContact(ctx context.Context, in *SetPrimaryContactRequest, opts ...grpc.CallOption) (*ContactResponse, error)
 	ListContacts(ctx context.Context, in *ListContactsRequest, opts ...grpc.CallOption) (*ListContactsResponse, error)
 	CreateAddress(ctx context.Context, in *CreateAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error)
 	GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error)
 	UpdateAddress(ctx context.Context, in *UpdateAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error)
+	PatchAddress(ctx context.Context, in *PatchAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error)
 	DeleteAddress(ctx context.Context, in *DeleteAddressRequest, opts ...grpc.CallOption) (*DeleteAddressResponse, error)
+	RestoreAddress(ctx context.Context, in *RestoreAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error)
+	SetPrimaryAddress(ctx context.Context, in *SetPrimaryAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error)
 	ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*ListAddressesResponse, error)
+	SearchAddressesNear(ctx context.Context, in *SearchAddressesNearRequest, opts ...grpc.CallOption) (*SearchAddressesNearResponse, error)
 	CreateCompany(ctx context.Context, in *CreateCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error)
 	GetCompany(ctx context.Context, in *GetCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error)
 	UpdateCompany(ctx context.Context, in *UpdateCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error)
+	PatchCompany(ctx context.Context, in *PatchCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error)
 	DeleteCompany(ctx context.Context, in *DeleteCompanyRequest, opts ...grpc.CallOption) (*DeleteCompanyResponse, error)
+	RestoreCompany(ctx context.Context, in *RestoreCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error)
+	SetPrimaryCompany(ctx context.Context, in *SetPrimaryCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error)
 	ListCompanies(ctx context.Context, in *ListCompaniesRequest, opts ...grpc.CallOption) (*ListCompaniesResponse, error)
+	AddCompanyMember(ctx context.Context, in *AddCompanyMemberRequest, opts ...grpc.CallOption) (*CompanyMemberResponse, error)
+	RemoveCompanyMember(ctx context.Context, in *RemoveCompanyMemberRequest, opts ...grpc.CallOption) (*RemoveCompanyMemberResponse, error)
+	ListCompanyMembers(ctx context.Context, in *ListCompanyMembersRequest, opts ...grpc.CallOption) (*ListCompanyMembersResponse, error)
+	ListProfileCompanies(ctx context.Context, in *ListProfileCompaniesRequest, opts ...grpc.CallOption) (*ListProfileCompaniesResponse, error)
+	GetPreferences(ctx context.Context, in *GetPreferencesRequest, opts ...grpc.CallOption) (*PreferencesResponse, error)
+	SetPreferences(ctx context.Context, in *SetPreferencesRequest, opts ...grpc.CallOption) (*PreferencesResponse, error)
+	DeletePreference(ctx context.Context, in *DeletePreferenceRequest, opts ...grpc.CallOption) (*DeletePreferenceResponse, error)
+	// UploadAttachment streams the metadata of an attachment and then its content.
+	UploadAttachment(ctx context.Context, opts ...grpc.CallOption) (ProfileService_UploadAttachmentClient, error)
+	// DownloadAttachment streams the metadata of an attachment and then its content.
+	DownloadAttachment(ctx context.Context, in *DownloadAttachmentRequest, opts ...grpc.CallOption) (ProfileService_DownloadAttachmentClient, error)
+	GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (*AttachmentResponse, error)
+	ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsResponse, error)
+	DeleteAttachment(ctx context.Context, in *DeleteAttachmentRequest, opts ...grpc.CallOption) (*DeleteAttachmentResponse, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
 }
 
 type profileServiceClient struct {
@@ -102,6 +158,15 @@ func (c *profileServiceClient) GetProfileByUserID(ctx context.Context, in *GetPr
 	return out, nil
 }
 
+func (c *profileServiceClient) GetProfileByEmail(ctx context.Context, in *GetProfileByEmailRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
+	out := new(ProfileResponse)
+	err := c.cc.Invoke(ctx, ProfileService_GetProfileByEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
 	out := new(ProfileResponse)
 	err := c.cc.Invoke(ctx, ProfileService_UpdateProfile_FullMethodName, in, out, opts...)
@@ -111,6 +176,15 @@ func (c *profileServiceClient) UpdateProfile(ctx context.Context, in *UpdateProf
 	return out, nil
 }
 
+func (c *profileServiceClient) PatchProfile(ctx context.Context, in *PatchProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
+	out := new(ProfileResponse)
+	err := c.cc.Invoke(ctx, ProfileService_PatchProfile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) DeleteProfile(ctx context.Context, in *DeleteProfileRequest, opts ...grpc.CallOption) (*DeleteProfileResponse, error) {
 	out := new(DeleteProfileResponse)
 	err := c.cc.Invoke(ctx, ProfileService_DeleteProfile_FullMethodName, in, out, opts...)
@@ -120,6 +194,24 @@ func (c *profileServiceClient) DeleteProfile(ctx context.Context, in *DeleteProf
 	return out, nil
 }
 
+func (c *profileServiceClient) RestoreProfile(ctx context.Context, in *RestoreProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
+	out := new(ProfileResponse)
+	err := c.cc.Invoke(ctx, ProfileService_RestoreProfile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) GetProfileBundle(ctx context.Context, in *GetProfileBundleRequest, opts ...grpc.CallOption) (*ProfileBundleResponse, error) {
+	out := new(ProfileBundleResponse)
+	err := c.cc.Invoke(ctx, ProfileService_GetProfileBundle_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) CreateContact(ctx context.Context, in *CreateContactRequest, opts ...grpc.CallOption) (*ContactResponse, error) {
 	out := new(ContactResponse)
 	err := c.cc.Invoke(ctx, ProfileService_CreateContact_FullMethodName, in, out, opts...)
@@ -147,6 +239,15 @@ func (c *profileServiceClient) UpdateContact(ctx context.Context, in *UpdateCont
 	return out, nil
 }
 
+func (c *profileServiceClient) PatchContact(ctx context.Context, in *PatchContactRequest, opts ...grpc.CallOption) (*ContactResponse, error) {
+	out := new(ContactResponse)
+	err := c.cc.Invoke(ctx, ProfileService_PatchContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) DeleteContact(ctx context.Context, in *DeleteContactRequest, opts ...grpc.CallOption) (*DeleteContactResponse, error) {
 	out := new(DeleteContactResponse)
 	err := c.cc.Invoke(ctx, ProfileService_DeleteContact_FullMethodName, in, out, opts...)
@@ -156,6 +257,24 @@ func (c *profileServiceClient) DeleteContact(ctx context.Context, in *DeleteCont
 	return out, nil
 }
 
+func (c *profileServiceClient) RestoreContact(ctx context.Context, in *RestoreContactRequest, opts ...grpc.CallOption) (*ContactResponse, error) {
+	out := new(ContactResponse)
+	err := c.cc.Invoke(ctx, ProfileService_RestoreContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) SetPrimaryContact(ctx context.Context, in *SetPrimaryContactRequest, opts ...grpc.CallOption) (*ContactResponse, error) {
+	out := new(ContactResponse)
+	err := c.cc.Invoke(ctx, ProfileService_SetPrimaryContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) ListContacts(ctx context.Context, in *ListContactsRequest, opts ...grpc.CallOption) (*ListContactsResponse, error) {
 	out := new(ListContactsResponse)
 	err := c.cc.Invoke(ctx, ProfileService_ListContacts_FullMethodName, in, out, opts...)
@@ -192,6 +311,15 @@ func (c *profileServiceClient) UpdateAddress(ctx context.Context, in *UpdateAddr
 	return out, nil
 }
 
+func (c *profileServiceClient) PatchAddress(ctx context.Context, in *PatchAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error) {
+	out := new(AddressResponse)
+	err := c.cc.Invoke(ctx, ProfileService_PatchAddress_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) DeleteAddress(ctx context.Context, in *DeleteAddressRequest, opts ...grpc.CallOption) (*DeleteAddressResponse, error) {
 	out := new(DeleteAddressResponse)
 	err := c.cc.Invoke(ctx, ProfileService_DeleteAddress_FullMethodName, in, out, opts...)
@@ -201,6 +329,24 @@ func (c *profileServiceClient) DeleteAddress(ctx context.Context, in *DeleteAddr
 	return out, nil
 }
 
+func (c *profileServiceClient) RestoreAddress(ctx context.Context, in *RestoreAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error) {
+	out := new(AddressResponse)
+	err := c.cc.Invoke(ctx, ProfileService_RestoreAddress_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) SetPrimaryAddress(ctx context.Context, in *SetPrimaryAddressRequest, opts ...grpc.CallOption) (*AddressResponse, error) {
+	out := new(AddressResponse)
+	err := c.cc.Invoke(ctx, ProfileService_SetPrimaryAddress_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*ListAddressesResponse, error) {
 	out := new(ListAddressesResponse)
 	err := c.cc.Invoke(ctx, ProfileService_ListAddresses_FullMethodName, in, out, opts...)
@@ -210,6 +356,15 @@ func (c *profileServiceClient) ListAddresses(ctx context.Context, in *ListAddres
 	return out, nil
 }
 
+func (c *profileServiceClient) SearchAddressesNear(ctx context.Context, in *SearchAddressesNearRequest, opts ...grpc.CallOption) (*SearchAddressesNearResponse, error) {
+	out := new(SearchAddressesNearResponse)
+	err := c.cc.Invoke(ctx, ProfileService_SearchAddressesNear_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) CreateCompany(ctx context.Context, in *CreateCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error) {
 	out := new(CompanyResponse)
 	err := c.cc.Invoke(ctx, ProfileService_CreateCompany_FullMethodName, in, out, opts...)
@@ -237,6 +392,15 @@ func (c *profileServiceClient) UpdateCompany(ctx context.Context, in *UpdateComp
 	return out, nil
 }
 
+func (c *profileServiceClient) PatchCompany(ctx context.Context, in *PatchCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error) {
+	out := new(CompanyResponse)
+	err := c.cc.Invoke(ctx, ProfileService_PatchCompany_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) DeleteCompany(ctx context.Context, in *DeleteCompanyRequest, opts ...grpc.CallOption) (*DeleteCompanyResponse, error) {
 	out := new(DeleteCompanyResponse)
 	err := c.cc.Invoke(ctx, ProfileService_DeleteCompany_FullMethodName, in, out, opts...)
@@ -246,6 +410,24 @@ func (c *profileServiceClient) DeleteCompany(ctx context.Context, in *DeleteComp
 	return out, nil
 }
 
+func (c *profileServiceClient) RestoreCompany(ctx context.Context, in *RestoreCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error) {
+	out := new(CompanyResponse)
+	err := c.cc.Invoke(ctx, ProfileService_RestoreCompany_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) SetPrimaryCompany(ctx context.Context, in *SetPrimaryCompanyRequest, opts ...grpc.CallOption) (*CompanyResponse, error) {
+	out := new(CompanyResponse)
+	err := c.cc.Invoke(ctx, ProfileService_SetPrimaryCompany_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *profileServiceClient) ListCompanies(ctx context.Context, in *ListCompaniesRequest, opts ...grpc.CallOption) (*ListCompaniesResponse, error) {
 	out := new(ListCompaniesResponse)
 	err := c.cc.Invoke(ctx, ProfileService_ListCompanies_FullMethodName, in, out, opts...)
@@ -255,6 +437,171 @@ func (c *profileServiceClient) ListCompanies(ctx context.Context, in *ListCompan
 	return out, nil
 }
 
+func (c *profileServiceClient) AddCompanyMember(ctx context.Context, in *AddCompanyMemberRequest, opts ...grpc.CallOption) (*CompanyMemberResponse, error) {
+	out := new(CompanyMemberResponse)
+	err := c.cc.Invoke(ctx, ProfileService_AddCompanyMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) RemoveCompanyMember(ctx context.Context, in *RemoveCompanyMemberRequest, opts ...grpc.CallOption) (*RemoveCompanyMemberResponse, error) {
+	out := new(RemoveCompanyMemberResponse)
+	err := c.cc.Invoke(ctx, ProfileService_RemoveCompanyMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) ListCompanyMembers(ctx context.Context, in *ListCompanyMembersRequest, opts ...grpc.CallOption) (*ListCompanyMembersResponse, error) {
+	out := new(ListCompanyMembersResponse)
+	err := c.cc.Invoke(ctx, ProfileService_ListCompanyMembers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) ListProfileCompanies(ctx context.Context, in *ListProfileCompaniesRequest, opts ...grpc.CallOption) (*ListProfileCompaniesResponse, error) {
+	out := new(ListProfileCompaniesResponse)
+	err := c.cc.Invoke(ctx, ProfileService_ListProfileCompanies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) GetPreferences(ctx context.Context, in *GetPreferencesRequest, opts ...grpc.CallOption) (*PreferencesResponse, error) {
+	out := new(PreferencesResponse)
+	err := c.cc.Invoke(ctx, ProfileService_GetPreferences_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) SetPreferences(ctx context.Context, in *SetPreferencesRequest, opts ...grpc.CallOption) (*PreferencesResponse, error) {
+	out := new(PreferencesResponse)
+	err := c.cc.Invoke(ctx, ProfileService_SetPreferences_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) DeletePreference(ctx context.Context, in *DeletePreferenceRequest, opts ...grpc.CallOption) (*DeletePreferenceResponse, error) {
+	out := new(DeletePreferenceResponse)
+	err := c.cc.Invoke(ctx, ProfileService_DeletePreference_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) UploadAttachment(ctx context.Context, opts ...grpc.CallOption) (ProfileService_UploadAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProfileService_ServiceDesc.Streams[0], ProfileService_UploadAttachment_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &profileServiceUploadAttachmentClient{stream}
+	return x, nil
+}
+
+type ProfileService_UploadAttachmentClient interface {
+	Send(*UploadAttachmentRequest) error
+	CloseAndRecv() (*AttachmentResponse, error)
+	grpc.ClientStream
+}
+
+type profileServiceUploadAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *profileServiceUploadAttachmentClient) Send(m *UploadAttachmentRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *profileServiceUploadAttachmentClient) CloseAndRecv() (*AttachmentResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(AttachmentResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *profileServiceClient) DownloadAttachment(ctx context.Context, in *DownloadAttachmentRequest, opts ...grpc.CallOption) (ProfileService_DownloadAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProfileService_ServiceDesc.Streams[1], ProfileService_DownloadAttachment_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &profileServiceDownloadAttachmentClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProfileService_DownloadAttachmentClient interface {
+	Recv() (*DownloadAttachmentResponse, error)
+	grpc.ClientStream
+}
+
+type profileServiceDownloadAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *profileServiceDownloadAttachmentClient) Recv() (*DownloadAttachmentResponse, error) {
+	m := new(DownloadAttachmentResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *profileServiceClient) GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (*AttachmentResponse, error) {
+	out := new(AttachmentResponse)
+	err := c.cc.Invoke(ctx, ProfileService_GetAttachment_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsResponse, error) {
+	out := new(ListAttachmentsResponse)
+	err := c.cc.Invoke(ctx, ProfileService_ListAttachments_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) DeleteAttachment(ctx context.Context, in *DeleteAttachmentRequest, opts ...grpc.CallOption) (*DeleteAttachmentResponse, error) {
+	out := new(DeleteAttachmentResponse)
+	err := c.cc.Invoke(ctx, ProfileService_DeleteAttachment_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, ProfileService_ListAuditEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProfileServiceServer is the server API for ProfileService service.
 // All implementations must embed UnimplementedProfileServiceServer
 // for forward compatibility
@@ -262,23 +609,52 @@ type ProfileServiceServer interface {
 	CreateProfile(context.Context, *CreateProfileRequest) (*ProfileResponse, error)
 	GetProfile(context.Context, *GetProfileRequest) (*ProfileResponse, error)
 	GetProfileByUserID(context.Context, *GetProfileByUserIDRequest) (*ProfileResponse, error)
+	GetProfileByEmail(context.Context, *GetProfileByEmailRequest) (*ProfileResponse, error)
 	UpdateProfile(context.Context, *UpdateProfileRequest) (*ProfileResponse, error)
+	PatchProfile(context.Context, *PatchProfileRequest) (*ProfileResponse, error)
 	DeleteProfile(context.Context, *DeleteProfileRequest) (*DeleteProfileResponse, error)
+	RestoreProfile(context.Context, *RestoreProfileRequest) (*ProfileResponse, error)
+	GetProfileBundle(context.Context, *GetProfileBundleRequest) (*ProfileBundleResponse, error)
 	CreateContact(context.Context, *CreateContactRequest) (*ContactResponse, error)
 	GetContact(context.Context, *GetContactRequest) (*ContactResponse, error)
 	UpdateContact(context.Context, *UpdateContactRequest) (*ContactResponse, error)
+	PatchContact(context.Context, *PatchContactRequest) (*ContactResponse, error)
 	DeleteContact(context.Context, *DeleteContactRequest) (*DeleteContactResponse, error)
+	RestoreContact(context.Context, *RestoreContactRequest) (*ContactResponse, error)
+	SetPrimaryContact(context.Context, *SetPrimaryContactRequest) (*ContactResponse, error)
 	ListContacts(context.Context, *ListContactsRequest) (*ListContactsResponse, error)
 	CreateAddress(context.Context, *CreateAddressRequest) (*AddressResponse, error)
 	GetAddress(context.Context, *GetAddressRequest) (*AddressResponse, error)
 	UpdateAddress(context.Context, *UpdateAddressRequest) (*AddressResponse, error)
+	PatchAddress(context.Context, *PatchAddressRequest) (*AddressResponse, error)
 	DeleteAddress(context.Context, *DeleteAddressRequest) (*DeleteAddressResponse, error)
+	RestoreAddress(context.Context, *RestoreAddressRequest) (*AddressResponse, error)
+	SetPrimaryAddress(context.Context, *SetPrimaryAddressRequest) (*AddressResponse, error)
 	ListAddresses(context.Context, *ListAddressesRequest) (*ListAddressesResponse, error)
+	SearchAddressesNear(context.Context, *SearchAddressesNearRequest) (*SearchAddressesNearResponse, error)
 	CreateCompany(context.Context, *CreateCompanyRequest) (*CompanyResponse, error)
 	GetCompany(context.Context, *GetCompanyRequest) (*CompanyResponse, error)
 	UpdateCompany(context.Context, *UpdateCompanyRequest) (*CompanyResponse, error)
+	PatchCompany(context.Context, *PatchCompanyRequest) (*CompanyResponse, error)
 	DeleteCompany(context.Context, *DeleteCompanyRequest) (*DeleteCompanyResponse, error)
+	RestoreCompany(context.Context, *RestoreCompanyRequest) (*CompanyResponse, error)
+	SetPrimaryCompany(context.Context, *SetPrimaryCompanyRequest) (*CompanyResponse, error)
 	ListCompanies(context.Context, *ListCompaniesRequest) (*ListCompaniesResponse, error)
+	AddCompanyMember(context.Context, *AddCompanyMemberRequest) (*CompanyMemberResponse, error)
+	RemoveCompanyMember(context.Context, *RemoveCompanyMemberRequest) (*RemoveCompanyMemberResponse, error)
+	ListCompanyMembers(context.Context, *ListCompanyMembersRequest) (*ListCompanyMembersResponse, error)
+	ListProfileCompanies(context.Context, *ListProfileCompaniesRequest) (*ListProfileCompaniesResponse, error)
+	GetPreferences(context.Context, *GetPreferencesRequest) (*PreferencesResponse, error)
+	SetPreferences(context.Context, *SetPreferencesRequest) (*PreferencesResponse, error)
+	DeletePreference(context.Context, *DeletePreferenceRequest) (*DeletePreferenceResponse, error)
+	// UploadAttachment streams the metadata of an attachment and then its content.
+	UploadAttachment(ProfileService_UploadAttachmentServer) error
+	// DownloadAttachment streams the metadata of an attachment and then its content.
+	DownloadAttachment(*DownloadAttachmentRequest, ProfileService_DownloadAttachmentServer) error
+	GetAttachment(context.Context, *GetAttachmentRequest) (*AttachmentResponse, error)
+	ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsResponse, error)
+	DeleteAttachment(context.Context, *DeleteAttachmentRequest) (*DeleteAttachmentResponse, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
 	mustEmbedUnimplementedProfileServiceServer()
 }
 
@@ -295,12 +671,24 @@ func (UnimplementedProfileServiceServer) GetProfile(context.Context, *GetProfile
 func (UnimplementedProfileServiceServer) GetProfileByUserID(context.Context, *GetProfileByUserIDRequest) (*ProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProfileByUserID not implemented")
 }
+func (UnimplementedProfileServiceServer) GetProfileByEmail(context.Context, *GetProfileByEmailRequest) (*ProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfileByEmail not implemented")
+}
 func (UnimplementedProfileServiceServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*ProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
 }
+func (UnimplementedProfileServiceServer) PatchProfile(context.Context, *PatchProfileRequest) (*ProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchProfile not implemented")
+}
 func (UnimplementedProfileServiceServer) DeleteProfile(context.Context, *DeleteProfileRequest) (*DeleteProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteProfile not implemented")
 }
+func (UnimplementedProfileServiceServer) RestoreProfile(context.Context, *RestoreProfileRequest) (*ProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreProfile not implemented")
+}
+func (UnimplementedProfileServiceServer) GetProfileBundle(context.Context, *GetProfileBundleRequest) (*ProfileBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfileBundle not implemented")
+}
 func (UnimplementedProfileServiceServer) CreateContact(context.Context, *CreateContactRequest) (*ContactResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateContact not implemented")
 }
@@ -310,9 +698,18 @@ func (UnimplementedProfileServiceServer) GetContact(context.Context, *GetContact
 func (UnimplementedProfileServiceServer) UpdateContact(context.Context, *UpdateContactRequest) (*ContactResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateContact not implemented")
 }
+func (UnimplementedProfileServiceServer) PatchContact(context.Context, *PatchContactRequest) (*ContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchContact not implemented")
+}
 func (UnimplementedProfileServiceServer) DeleteContact(context.Context, *DeleteContactRequest) (*DeleteContactResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteContact not implemented")
 }
+func (UnimplementedProfileServiceServer) RestoreContact(context.Context, *RestoreContactRequest) (*ContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreContact not implemented")
+}
+func (UnimplementedProfileServiceServer) SetPrimaryContact(context.Context, *SetPrimaryContactRequest) (*ContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPrimaryContact not implemented")
+}
 func (UnimplementedProfileServiceServer) ListContacts(context.Context, *ListContactsRequest) (*ListContactsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListContacts not implemented")
 }
@@ -325,12 +722,24 @@ func (UnimplementedProfileServiceServer) GetAddress(context.Context, *GetAddress
 func (UnimplementedProfileServiceServer) UpdateAddress(context.Context, *UpdateAddressRequest) (*AddressResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateAddress not implemented")
 }
+func (UnimplementedProfileServiceServer) PatchAddress(context.Context, *PatchAddressRequest) (*AddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchAddress not implemented")
+}
 func (UnimplementedProfileServiceServer) DeleteAddress(context.Context, *DeleteAddressRequest) (*DeleteAddressResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteAddress not implemented")
 }
+func (UnimplementedProfileServiceServer) RestoreAddress(context.Context, *RestoreAddressRequest) (*AddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreAddress not implemented")
+}
+func (UnimplementedProfileServiceServer) SetPrimaryAddress(context.Context, *SetPrimaryAddressRequest) (*AddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPrimaryAddress not implemented")
+}
 func (UnimplementedProfileServiceServer) ListAddresses(context.Context, *ListAddressesRequest) (*ListAddressesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListAddresses not implemented")
 }
+func (UnimplementedProfileServiceServer) SearchAddressesNear(context.Context, *SearchAddressesNearRequest) (*SearchAddressesNearResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchAddressesNear not implemented")
+}
 func (UnimplementedProfileServiceServer) CreateCompany(context.Context, *CreateCompanyRequest) (*CompanyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateCompany not implemented")
 }
@@ -340,12 +749,60 @@ func (UnimplementedProfileServiceServer) GetCompany(context.Context, *GetCompany
 func (UnimplementedProfileServiceServer) UpdateCompany(context.Context, *UpdateCompanyRequest) (*CompanyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateCompany not implemented")
 }
+func (UnimplementedProfileServiceServer) PatchCompany(context.Context, *PatchCompanyRequest) (*CompanyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchCompany not implemented")
+}
 func (UnimplementedProfileServiceServer) DeleteCompany(context.Context, *DeleteCompanyRequest) (*DeleteCompanyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteCompany not implemented")
 }
+func (UnimplementedProfileServiceServer) RestoreCompany(context.Context, *RestoreCompanyRequest) (*CompanyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreCompany not implemented")
+}
+func (UnimplementedProfileServiceServer) SetPrimaryCompany(context.Context, *SetPrimaryCompanyRequest) (*CompanyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPrimaryCompany not implemented")
+}
 func (UnimplementedProfileServiceServer) ListCompanies(context.Context, *ListCompaniesRequest) (*ListCompaniesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListCompanies not implemented")
 }
+func (UnimplementedProfileServiceServer) AddCompanyMember(context.Context, *AddCompanyMemberRequest) (*CompanyMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddCompanyMember not implemented")
+}
+func (UnimplementedProfileServiceServer) RemoveCompanyMember(context.Context, *RemoveCompanyMemberRequest) (*RemoveCompanyMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveCompanyMember not implemented")
+}
+func (UnimplementedProfileServiceServer) ListCompanyMembers(context.Context, *ListCompanyMembersRequest) (*ListCompanyMembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCompanyMembers not implemented")
+}
+func (UnimplementedProfileServiceServer) ListProfileCompanies(context.Context, *ListProfileCompaniesRequest) (*ListProfileCompaniesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProfileCompanies not implemented")
+}
+func (UnimplementedProfileServiceServer) GetPreferences(context.Context, *GetPreferencesRequest) (*PreferencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPreferences not implemented")
+}
+func (UnimplementedProfileServiceServer) SetPreferences(context.Context, *SetPreferencesRequest) (*PreferencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPreferences not implemented")
+}
+func (UnimplementedProfileServiceServer) DeletePreference(context.Context, *DeletePreferenceRequest) (*DeletePreferenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePreference not implemented")
+}
+func (UnimplementedProfileServiceServer) UploadAttachment(ProfileService_UploadAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAttachment not implemented")
+}
+func (UnimplementedProfileServiceServer) DownloadAttachment(*DownloadAttachmentRequest, ProfileService_DownloadAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadAttachment not implemented")
+}
+func (UnimplementedProfileServiceServer) GetAttachment(context.Context, *GetAttachmentRequest) (*AttachmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAttachment not implemented")
+}
+func (UnimplementedProfileServiceServer) ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAttachments not implemented")
+}
+func (UnimplementedProfileServiceServer) DeleteAttachment(context.Context, *DeleteAttachmentRequest) (*DeleteAttachmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAttachment not implemented")
+}
+func (UnimplementedProfileServiceServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
 func (UnimplementedProfileServiceServer) mustEmbedUnimplementedProfileServiceServer() {}
 
 // UnsafeProfileServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -413,85 +870,157 @@ func _ProfileService_GetProfileByUserID_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ProfileService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateProfileRequest)
+func _ProfileService_GetProfileByEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProfileByEmailRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProfileServiceServer).UpdateProfile(ctx, in)
+		return srv.(ProfileServiceServer).GetProfileByEmail(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ProfileService_UpdateProfile_FullMethodName,
+		FullMethod: ProfileService_GetProfileByEmail_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProfileServiceServer).UpdateProfile(ctx, req.(*UpdateProfileRequest))
+		return srv.(ProfileServiceServer).GetProfileByEmail(ctx, req.(*GetProfileByEmailRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ProfileService_DeleteProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteProfileRequest)
+func _ProfileService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProfileServiceServer).DeleteProfile(ctx, in)
+		return srv.(ProfileServiceServer).UpdateProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ProfileService_DeleteProfile_FullMethodName,
+		FullMethod: ProfileService_UpdateProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProfileServiceServer).DeleteProfile(ctx, req.(*DeleteProfileRequest))
+		return srv.(ProfileServiceServer).UpdateProfile(ctx, req.(*UpdateProfileRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ProfileService_CreateContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateContactRequest)
+func _ProfileService_PatchProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProfileServiceServer).CreateContact(ctx, in)
+		return srv.(ProfileServiceServer).PatchProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ProfileService_CreateContact_FullMethodName,
+		FullMethod: ProfileService_PatchProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProfileServiceServer).CreateContact(ctx, req.(*CreateContactRequest))
+		return srv.(ProfileServiceServer).PatchProfile(ctx, req.(*PatchProfileRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ProfileService_GetContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetContactRequest)
+func _ProfileService_DeleteProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProfileServiceServer).GetContact(ctx, in)
+		return srv.(ProfileServiceServer).DeleteProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ProfileService_GetContact_FullMethodName,
+		FullMethod: ProfileService_DeleteProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ProfileServiceServer).GetContact(ctx, req.(*GetContactRequest))
+		return srv.(ProfileServiceServer).DeleteProfile(ctx, req.(*DeleteProfileRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ProfileService_UpdateContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateContactRequest)
+func _ProfileService_RestoreProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ProfileServiceServer).UpdateContact(ctx, in)
+		return srv.(ProfileServiceServer).RestoreProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_RestoreProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).RestoreProfile(ctx, req.(*RestoreProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_GetProfileBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProfileBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).GetProfileBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_GetProfileBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).GetProfileBundle(ctx, req.(*GetProfileBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_CreateContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).CreateContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_CreateContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).CreateContact(ctx, req.(*CreateContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_GetContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).GetContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_GetContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).GetContact(ctx, req.(*GetContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_UpdateContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).UpdateContact(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
@@ -503,6 +1032,24 @@ func _ProfileService_UpdateContact_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_PatchContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).PatchContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_PatchContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).PatchContact(ctx, req.(*PatchContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_DeleteContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteContactRequest)
 	if err := dec(in); err != nil {
@@ -521,6 +1068,42 @@ func _ProfileService_DeleteContact_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_RestoreContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).RestoreContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_RestoreContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).RestoreContact(ctx, req.(*RestoreContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_SetPrimaryContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPrimaryContactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).SetPrimaryContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_SetPrimaryContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).SetPrimaryContact(ctx, req.(*SetPrimaryContactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_ListContacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListContactsRequest)
 	if err := dec(in); err != nil {
@@ -593,6 +1176,24 @@ func _ProfileService_UpdateAddress_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_PatchAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).PatchAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_PatchAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).PatchAddress(ctx, req.(*PatchAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_DeleteAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteAddressRequest)
 	if err := dec(in); err != nil {
@@ -611,6 +1212,42 @@ func _ProfileService_DeleteAddress_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_RestoreAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).RestoreAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_RestoreAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).RestoreAddress(ctx, req.(*RestoreAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_SetPrimaryAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPrimaryAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).SetPrimaryAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_SetPrimaryAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).SetPrimaryAddress(ctx, req.(*SetPrimaryAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_ListAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListAddressesRequest)
 	if err := dec(in); err != nil {
@@ -629,6 +1266,24 @@ func _ProfileService_ListAddresses_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_SearchAddressesNear_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchAddressesNearRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).SearchAddressesNear(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_SearchAddressesNear_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).SearchAddressesNear(ctx, req.(*SearchAddressesNearRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_CreateCompany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateCompanyRequest)
 	if err := dec(in); err != nil {
@@ -683,6 +1338,24 @@ func _ProfileService_UpdateCompany_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_PatchCompany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchCompanyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).PatchCompany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_PatchCompany_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).PatchCompany(ctx, req.(*PatchCompanyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_DeleteCompany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteCompanyRequest)
 	if err := dec(in); err != nil {
@@ -701,6 +1374,42 @@ func _ProfileService_DeleteCompany_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_RestoreCompany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreCompanyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).RestoreCompany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_RestoreCompany_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).RestoreCompany(ctx, req.(*RestoreCompanyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_SetPrimaryCompany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPrimaryCompanyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).SetPrimaryCompany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_SetPrimaryCompany_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).SetPrimaryCompany(ctx, req.(*SetPrimaryCompanyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ProfileService_ListCompanies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListCompaniesRequest)
 	if err := dec(in); err != nil {
@@ -719,6 +1428,251 @@ func _ProfileService_ListCompanies_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_AddCompanyMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCompanyMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).AddCompanyMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_AddCompanyMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).AddCompanyMember(ctx, req.(*AddCompanyMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_RemoveCompanyMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveCompanyMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).RemoveCompanyMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_RemoveCompanyMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).RemoveCompanyMember(ctx, req.(*RemoveCompanyMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_ListCompanyMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCompanyMembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).ListCompanyMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_ListCompanyMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).ListCompanyMembers(ctx, req.(*ListCompanyMembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_ListProfileCompanies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProfileCompaniesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).ListProfileCompanies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_ListProfileCompanies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).ListProfileCompanies(ctx, req.(*ListProfileCompaniesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_GetPreferences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPreferencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).GetPreferences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_GetPreferences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).GetPreferences(ctx, req.(*GetPreferencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_SetPreferences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPreferencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).SetPreferences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_SetPreferences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).SetPreferences(ctx, req.(*SetPreferencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_DeletePreference_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePreferenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).DeletePreference(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_DeletePreference_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).DeletePreference(ctx, req.(*DeletePreferenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_UploadAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ProfileServiceServer).UploadAttachment(&profileServiceUploadAttachmentServer{stream})
+}
+
+type ProfileService_UploadAttachmentServer interface {
+	SendAndClose(*AttachmentResponse) error
+	Recv() (*UploadAttachmentRequest, error)
+	grpc.ServerStream
+}
+
+type profileServiceUploadAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *profileServiceUploadAttachmentServer) SendAndClose(m *AttachmentResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *profileServiceUploadAttachmentServer) Recv() (*UploadAttachmentRequest, error) {
+	m := new(UploadAttachmentRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ProfileService_DownloadAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadAttachmentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProfileServiceServer).DownloadAttachment(m, &profileServiceDownloadAttachmentServer{stream})
+}
+
+type ProfileService_DownloadAttachmentServer interface {
+	Send(*DownloadAttachmentResponse) error
+	grpc.ServerStream
+}
+
+type profileServiceDownloadAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *profileServiceDownloadAttachmentServer) Send(m *DownloadAttachmentResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ProfileService_GetAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).GetAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_GetAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).GetAttachment(ctx, req.(*GetAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_ListAttachments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAttachmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).ListAttachments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_ListAttachments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).ListAttachments(ctx, req.(*ListAttachmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_DeleteAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).DeleteAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_DeleteAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).DeleteAttachment(ctx, req.(*DeleteAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_ListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ProfileService_ServiceDesc is the grpc.ServiceDesc for ProfileService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -738,14 +1692,30 @@ var ProfileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProfileByUserID",
 			Handler:    _ProfileService_GetProfileByUserID_Handler,
 		},
+		{
+			MethodName: "GetProfileByEmail",
+			Handler:    _ProfileService_GetProfileByEmail_Handler,
+		},
 		{
 			MethodName: "UpdateProfile",
 			Handler:    _ProfileService_UpdateProfile_Handler,
 		},
+		{
+			MethodName: "PatchProfile",
+			Handler:    _ProfileService_PatchProfile_Handler,
+		},
 		{
 			MethodName: "DeleteProfile",
 			Handler:    _ProfileService_DeleteProfile_Handler,
 		},
+		{
+			MethodName: "RestoreProfile",
+			Handler:    _ProfileService_RestoreProfile_Handler,
+		},
+		{
+			MethodName: "GetProfileBundle",
+			Handler:    _ProfileService_GetProfileBundle_Handler,
+		},
 		{
 			MethodName: "CreateContact",
 			Handler:    _ProfileService_CreateContact_Handler,
@@ -758,10 +1728,22 @@ var ProfileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateContact",
 			Handler:    _ProfileService_UpdateContact_Handler,
 		},
+		{
+			MethodName: "PatchContact",
+			Handler:    _ProfileService_PatchContact_Handler,
+		},
 		{
 			MethodName: "DeleteContact",
 			Handler:    _ProfileService_DeleteContact_Handler,
 		},
+		{
+			MethodName: "RestoreContact",
+			Handler:    _ProfileService_RestoreContact_Handler,
+		},
+		{
+			MethodName: "SetPrimaryContact",
+			Handler:    _ProfileService_SetPrimaryContact_Handler,
+		},
 		{
 			MethodName: "ListContacts",
 			Handler:    _ProfileService_ListContacts_Handler,
@@ -778,14 +1760,30 @@ var ProfileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateAddress",
 			Handler:    _ProfileService_UpdateAddress_Handler,
 		},
+		{
+			MethodName: "PatchAddress",
+			Handler:    _ProfileService_PatchAddress_Handler,
+		},
 		{
 			MethodName: "DeleteAddress",
 			Handler:    _ProfileService_DeleteAddress_Handler,
 		},
+		{
+			MethodName: "RestoreAddress",
+			Handler:    _ProfileService_RestoreAddress_Handler,
+		},
+		{
+			MethodName: "SetPrimaryAddress",
+			Handler:    _ProfileService_SetPrimaryAddress_Handler,
+		},
 		{
 			MethodName: "ListAddresses",
 			Handler:    _ProfileService_ListAddresses_Handler,
 		},
+		{
+			MethodName: "SearchAddressesNear",
+			Handler:    _ProfileService_SearchAddressesNear_Handler,
+		},
 		{
 			MethodName: "CreateCompany",
 			Handler:    _ProfileService_CreateCompany_Handler,
@@ -798,15 +1796,82 @@ var ProfileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateCompany",
 			Handler:    _ProfileService_UpdateCompany_Handler,
 		},
+		{
+			MethodName: "PatchCompany",
+			Handler:    _ProfileService_PatchCompany_Handler,
+		},
 		{
 			MethodName: "DeleteCompany",
 			Handler:    _ProfileService_DeleteCompany_Handler,
 		},
+		{
+			MethodName: "RestoreCompany",
+			Handler:    _ProfileService_RestoreCompany_Handler,
+		},
+		{
+			MethodName: "SetPrimaryCompany",
+			Handler:    _ProfileService_SetPrimaryCompany_Handler,
+		},
 		{
 			MethodName: "ListCompanies",
 			Handler:    _ProfileService_ListCompanies_Handler,
 		},
+		{
+			MethodName: "AddCompanyMember",
+			Handler:    _ProfileService_AddCompanyMember_Handler,
+		},
+		{
+			MethodName: "RemoveCompanyMember",
+			Handler:    _ProfileService_RemoveCompanyMember_Handler,
+		},
+		{
+			MethodName: "ListCompanyMembers",
+			Handler:    _ProfileService_ListCompanyMembers_Handler,
+		},
+		{
+			MethodName: "ListProfileCompanies",
+			Handler:    _ProfileService_ListProfileCompanies_Handler,
+		},
+		{
+			MethodName: "GetPreferences",
+			Handler:    _ProfileService_GetPreferences_Handler,
+		},
+		{
+			MethodName: "SetPreferences",
+			Handler:    _ProfileService_SetPreferences_Handler,
+		},
+		{
+			MethodName: "DeletePreference",
+			Handler:    _ProfileService_DeletePreference_Handler,
+		},
+		{
+			MethodName: "GetAttachment",
+			Handler:    _ProfileService_GetAttachment_Handler,
+		},
+		{
+			MethodName: "ListAttachments",
+			Handler:    _ProfileService_ListAttachments_Handler,
+		},
+		{
+			MethodName: "DeleteAttachment",
+			Handler:    _ProfileService_DeleteAttachment_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _ProfileService_ListAuditEvents_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadAttachment",
+			Handler:       _ProfileService_UploadAttachment_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadAttachment",
+			Handler:       _ProfileService_DownloadAttachment_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "profile.proto",
 }
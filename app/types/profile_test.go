@@ -1,11 +1,14 @@
 package types
 
 import (
+	"errors"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/country"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestCreateProfileRequestValidate(t *testing.T) {
@@ -19,6 +22,37 @@ func TestCreateProfileRequestValidate(t *testing.T) {
 	}
 }
 
+func TestCreateProfileRequestValidateNestedChildren(t *testing.T) {
+	valid := &CreateProfileRequest{
+		UserId:  1,
+		Email:   "john@example.com",
+		Contact: &CreateContactRequest{FirstName: "John", Dob: "1990-01-02"},
+		Address: &CreateAddressRequest{StreetName: "Main", StreenNo: "1", City: "City", County: "Cluj", Country: "RO"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected nested children without profile_id to be valid, got: %v", err)
+	}
+
+	badContact := &CreateProfileRequest{UserId: 1, Email: "john@example.com", Contact: &CreateContactRequest{Dob: "02/01/1990"}}
+	if err := badContact.Validate(); err == nil || !strings.HasPrefix(err.Error(), "contact: ") {
+		t.Fatalf("expected contact validation error, got: %v", err)
+	}
+
+	badAddress := &CreateProfileRequest{UserId: 1, Email: "john@example.com", Address: &CreateAddressRequest{City: "City"}}
+	if err := badAddress.Validate(); err == nil || !strings.HasPrefix(err.Error(), "address: ") {
+		t.Fatalf("expected address validation error, got: %v", err)
+	}
+	badCounty := &CreateProfileRequest{UserId: 1, Email: "john@example.com", Address: &CreateAddressRequest{StreetName: "Main", StreenNo: "1", City: "City", County: "Bavaria", Country: "RO"}}
+	var fieldErr *country.FieldError
+	if err := badCounty.Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != "address.county" || !strings.HasPrefix(err.Error(), "address: ") {
+		t.Fatalf("expected address.county validation error, got: %v", err)
+	}
+	badNIN := &CreateProfileRequest{UserId: 1, Email: "john@example.com", Contact: &CreateContactRequest{Nin: "1800101120018", NinCountry: "RO"}}
+	if err := badNIN.Validate(); !errors.As(err, &fieldErr) || fieldErr.Field != "contact.nin" || !strings.HasPrefix(err.Error(), "contact: ") {
+		t.Fatalf("expected contact.nin validation error, got: %v", err)
+	}
+}
+
 func TestNewCreateProfileRequestFromContext(t *testing.T) {
 	e := echo.New()
 
@@ -35,6 +69,16 @@ func TestNewCreateProfileRequestFromContext(t *testing.T) {
 		t.Fatalf("unexpected parsed request: %+v", parsed)
 	}
 
+	nestedReq := httptest.NewRequest("POST", "/profiles", strings.NewReader(`{"user_id":12,"email":"a@b.com","contact":{"first_name":"John"},"address":{"street_name":"Main","type":"home"}}`))
+	nestedReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	nested, err := NewCreateProfileRequestFromContext(e.NewContext(nestedReq, httptest.NewRecorder()))
+	if err != nil {
+		t.Fatalf("expected nested parse success, got: %v", err)
+	}
+	if nested.GetContact().GetFirstName() != "John" || nested.GetAddress().GetStreetName() != "Main" || nested.GetAddress().GetType() != "home" {
+		t.Fatalf("unexpected nested request: %+v", nested)
+	}
+
 	badReq := httptest.NewRequest("POST", "/profiles", strings.NewReader("{invalid"))
 	badReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	badCtx := e.NewContext(badReq, httptest.NewRecorder())
@@ -130,6 +174,56 @@ func TestUpdateProfileRequestValidate(t *testing.T) {
 	if err := (&UpdateProfileRequest{Id: 1, Email: ""}).Validate(); err == nil {
 		t.Fatal("expected validation error for empty email")
 	}
+	if err := (&UpdateProfileRequest{Id: 1, Email: "John <john@example.com>"}).Validate(); err == nil {
+		t.Fatal("expected validation error for a display name")
+	}
+
+	req := &UpdateProfileRequest{Id: 1, Email: "John@Example.COM"}
+	if err := req.Validate(); err != nil || req.GetEmail() != "John@example.com" {
+		t.Fatalf("expected normalized email, got %q (%v)", req.GetEmail(), err)
+	}
+}
+
+func TestNewGetProfileByEmailRequestFromContext(t *testing.T) {
+	e := echo.New()
+	var parsed *GetProfileByEmailRequest
+	e.GET("/profiles/email/:email", func(ctx echo.Context) error {
+		var err error
+		parsed, err = NewGetProfileByEmailRequestFromContext(ctx)
+		return err
+	})
+
+	cases := []struct {
+		target         string
+		email          string
+		includeDeleted bool
+	}{
+		{"/profiles/email/john@example.com", "john@example.com", false},
+		{"/profiles/email/j%C3%B6hn@m%C3%BCller.de", "jöhn@müller.de", false},
+		{"/profiles/email/john%2Btag@example.com?include_deleted=true", "john+tag@example.com", true},
+		{"/profiles/email/a%2Fb%25c@example.com", "a/b%c@example.com", false},
+	}
+	for _, tc := range cases {
+		parsed = nil
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+		if rec.Code != 200 || parsed == nil {
+			t.Fatalf("%s: expected parse success, got status %d", tc.target, rec.Code)
+		}
+		if parsed.GetEmail() != tc.email || parsed.GetIncludeDeleted() != tc.includeDeleted {
+			t.Fatalf("%s: unexpected parsed request: %+v", tc.target, parsed)
+		}
+	}
+}
+
+func TestGetProfileByEmailValidate(t *testing.T) {
+	req := &GetProfileByEmailRequest{Email: "Jane@EXAMPLE.com"}
+	if err := req.Validate(); err != nil || req.GetEmail() != "Jane@example.com" {
+		t.Fatalf("expected normalized email, got %q (%v)", req.GetEmail(), err)
+	}
+	if err := (&GetProfileByEmailRequest{Email: "not-an-email"}).Validate(); err == nil {
+		t.Fatal("expected validation error for an invalid email")
+	}
 }
 
 func TestNewDeleteProfileRequestFromContextAndValidate(t *testing.T) {
@@ -152,3 +246,48 @@ func TestNewDeleteProfileRequestFromContextAndValidate(t *testing.T) {
 		t.Fatal("expected validation error for id=0")
 	}
 }
+
+func TestNewGetProfileBundleRequestFromContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest("GET", "/profiles/10/full?include=contacts,%20Addresses&include=companies", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("10")
+
+	parsed, err := NewGetProfileBundleRequestFromContext(ctx)
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if parsed.GetId() != 10 || strings.Join(parsed.GetInclude(), ",") != "contacts,addresses,companies" {
+		t.Fatalf("unexpected parsed request: %+v", parsed)
+	}
+	if err := parsed.Validate(); err != nil {
+		t.Fatalf("expected valid request, got: %v", err)
+	}
+}
+
+func TestGetProfileBundleRequestValidate(t *testing.T) {
+	if err := (&GetProfileBundleRequest{Id: 1}).Validate(); err != nil {
+		t.Fatalf("expected empty include to be valid, got: %v", err)
+	}
+	if err := (&GetProfileBundleRequest{}).Validate(); err == nil {
+		t.Fatal("expected validation error for missing id")
+	}
+	if err := (&GetProfileBundleRequest{Id: 1, Include: []string{"orders"}}).Validate(); err == nil {
+		t.Fatal("expected validation error for unknown include")
+	}
+}
+
+func TestPatchProfileRequestValidate(t *testing.T) {
+	valid := &PatchProfileRequest{Id: 1, Email: "a@b.com", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	if err := (&PatchProfileRequest{Id: 1, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}}}).Validate(); err == nil {
+		t.Fatal("expected validation error when clearing email")
+	}
+	if err := (&PatchProfileRequest{Id: 1, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"user_id"}}}).Validate(); err == nil {
+		t.Fatal("expected validation error for immutable user_id")
+	}
+}
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// includeDeletedFromQuery reads the optional include_deleted query flag.
+func includeDeletedFromQuery(ctx echo.Context) (bool, error) {
+	raw := strings.TrimSpace(ctx.QueryParam("include_deleted"))
+	if raw == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(raw)
+}
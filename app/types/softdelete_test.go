@@ -0,0 +1,70 @@
+package types
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIncludeDeletedQueryParsing(t *testing.T) {
+	e := echo.New()
+
+	for _, target := range []string{"/profiles/4?include_deleted=true", "/profiles/4?include_deleted=1"} {
+		ctx := e.NewContext(httptest.NewRequest("GET", target, nil), httptest.NewRecorder())
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("4")
+		parsed, err := NewGetProfileRequestFromContext(ctx)
+		if err != nil || !parsed.GetIncludeDeleted() {
+			t.Fatalf("%s: expected include_deleted, got %+v (err %v)", target, parsed, err)
+		}
+	}
+
+	ctx := e.NewContext(httptest.NewRequest("GET", "/contacts?profile_id=3&include_deleted=maybe", nil), httptest.NewRecorder())
+	if _, err := NewListContactsRequestFromContext(ctx); err == nil {
+		t.Fatal("expected parse error for invalid include_deleted")
+	}
+
+	ctx = e.NewContext(httptest.NewRequest("GET", "/addresses?profile_id=3&include_deleted=true", nil), httptest.NewRecorder())
+	parsed, err := NewListAddressesRequestFromContext(ctx)
+	if err != nil || !parsed.GetIncludeDeleted() {
+		t.Fatalf("expected include_deleted on address list, got %+v (err %v)", parsed, err)
+	}
+
+	ctx = e.NewContext(httptest.NewRequest("GET", "/companies/2", nil), httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("2")
+	company, err := NewGetCompanyRequestFromContext(ctx)
+	if err != nil || company.GetIncludeDeleted() {
+		t.Fatalf("expected include_deleted to default to false, got %+v (err %v)", company, err)
+	}
+}
+
+func TestNewRestoreRequestsFromContextAndValidate(t *testing.T) {
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest("POST", "/addresses/9/restore", nil), httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("9")
+
+	address, err := NewRestoreAddressRequestFromContext(ctx)
+	if err != nil || address.GetId() != 9 {
+		t.Fatalf("unexpected restore request: %+v (err %v)", address, err)
+	}
+	if err = address.Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	ctx = e.NewContext(httptest.NewRequest("POST", "/profiles/x/restore", nil), httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("x")
+	if _, err = NewRestoreProfileRequestFromContext(ctx); err == nil {
+		t.Fatal("expected parse error for non-numeric id")
+	}
+
+	if err = (&RestoreContactRequest{}).Validate(); err == nil {
+		t.Fatal("expected validation error for zero id")
+	}
+	if err = (&RestoreCompanyRequest{Id: 1}).Validate(); err != nil {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+}
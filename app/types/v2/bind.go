@@ -0,0 +1,218 @@
+package typesv2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var structName = (&structpb.Struct{}).ProtoReflect().Descriptor().FullName()
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	updateMaskField       = "update_mask"
+	expectedVersionField  = "expected_version"
+)
+
+// BindRequest fills req from an HTTP request the way the /v2 routes map onto the v2 service:
+// the JSON body holds the message in its protojson form, path params and query params name
+// request fields (nested ones with dots, e.g. center.latitude), and If-Match sets
+// expected_version. A PATCH body is a JSON Merge Patch; unless it sets update_mask itself,
+// every member it holds becomes an update mask path, and a google.protobuf.Struct member one
+// path per key it holds (attributes.<key>).
+func BindRequest(ctx echo.Context, req proto.Message) error {
+	msg := req.ProtoReflect()
+	method := ctx.Request().Method
+	if method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch {
+		if err := bindBody(ctx, msg, method == http.MethodPatch); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range ctx.ParamNames() {
+		value := ctx.Param(name)
+		// Echo leaves params undecoded when it routes on a raw path holding escaped reserved
+		// characters.
+		if ctx.Request().URL.RawPath != "" {
+			var err error
+			if value, err = url.PathUnescape(value); err != nil {
+				return err
+			}
+		}
+		if err := setField(msg, name, []string{value}); err != nil {
+			return err
+		}
+	}
+	for name, values := range ctx.QueryParams() {
+		if err := setField(msg, name, values); err != nil {
+			return err
+		}
+	}
+
+	if fd := msg.Descriptor().Fields().ByName(expectedVersionField); fd != nil {
+		version, err := types.ExpectedVersionFromIfMatch(ctx)
+		if err != nil {
+			return err
+		}
+		if version != 0 {
+			msg.Set(fd, protoreflect.ValueOfUint64(version))
+		}
+	}
+
+	return nil
+}
+
+func bindBody(ctx echo.Context, msg protoreflect.Message, patch bool) error {
+	if patch {
+		if rawType := ctx.Request().Header.Get(echo.HeaderContentType); rawType != "" {
+			mediaType, _, err := mime.ParseMediaType(rawType)
+			if err != nil || (mediaType != mergePatchContentType && mediaType != echo.MIMEApplicationJSON) {
+				return types.ErrUnsupportedPatchContentType
+			}
+		}
+	}
+
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		if patch {
+			return errors.New("patch body must be a JSON object")
+		}
+		return nil
+	}
+	if err = protojson.Unmarshal(body, msg.Interface()); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	maskField := msg.Descriptor().Fields().ByName(updateMaskField)
+	if !patch || maskField == nil || msg.Has(maskField) {
+		return nil
+	}
+
+	var members map[string]json.RawMessage
+	if err = json.Unmarshal(body, &members); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	mask := &fieldmaskpb.FieldMask{}
+	fields := msg.Descriptor().Fields()
+	for name := range members {
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil || fd.Name() == expectedVersionField {
+			continue
+		}
+		if fd.Message() != nil && fd.Message().FullName() == structName {
+			var keys map[string]json.RawMessage
+			if err = json.Unmarshal(members[name], &keys); err == nil && keys != nil {
+				for key := range keys {
+					mask.Paths = append(mask.Paths, string(fd.Name())+"."+key)
+				}
+				continue
+			}
+		}
+		mask.Paths = append(mask.Paths, string(fd.Name()))
+	}
+	mask.Normalize()
+	msg.Set(maskField, protoreflect.ValueOfMessage(mask.ProtoReflect()))
+
+	return nil
+}
+
+// setField sets the scalar field at the dotted path name from values; a repeated field takes
+// every value, split at commas, and a map field the entry keyed by the rest of the path.
+func setField(msg protoreflect.Message, name string, values []string) error {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			fd = msg.Descriptor().Fields().ByJSONName(part)
+		}
+		if fd == nil || (fd.IsMap() && i != len(parts)-2) {
+			return fmt.Errorf("unknown parameter %q", name)
+		}
+
+		if fd.IsMap() {
+			if fd.MapKey().Kind() != protoreflect.StringKind || parts[i+1] == "" {
+				return fmt.Errorf("unknown parameter %q", name)
+			}
+			parsed, err := parseScalar(fd.MapValue(), strings.TrimSpace(values[len(values)-1]))
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			msg.Mutable(fd).Map().Set(protoreflect.ValueOfString(parts[i+1]).MapKey(), parsed)
+			return nil
+		}
+
+		if i < len(parts)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() {
+				return fmt.Errorf("unknown parameter %q", name)
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, value := range values {
+				for _, item := range strings.Split(value, ",") {
+					parsed, err := parseScalar(fd, strings.TrimSpace(item))
+					if err != nil {
+						return fmt.Errorf("invalid %s: %w", name, err)
+					}
+					list.Append(parsed)
+				}
+			}
+			return nil
+		}
+
+		parsed, err := parseScalar(fd, strings.TrimSpace(values[len(values)-1]))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		msg.Set(fd, parsed)
+	}
+
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, raw string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(raw), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(raw)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Uint64Kind:
+		v, err := strconv.ParseUint(raw, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.Uint32Kind:
+		v, err := strconv.ParseUint(raw, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Int32Kind:
+		v, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(raw, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("%s cannot be set from a parameter", fd.Kind())
+}
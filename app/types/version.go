@@ -0,0 +1,37 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const HeaderIfMatch = "If-Match"
+
+// FormatETag renders a record version as the strong ETag returned over HTTP.
+func FormatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// ExpectedVersionFromIfMatch reads the version the client expects from an If-Match
+// header holding an ETag issued by FormatETag. A missing header or "*" returns 0,
+// which disables the version check.
+func ExpectedVersionFromIfMatch(ctx echo.Context) (uint64, error) {
+	raw := strings.TrimSpace(ctx.Request().Header.Get(HeaderIfMatch))
+	if raw == "" || raw == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(raw, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errors.New("If-Match must be a quoted ETag")
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, errors.New("If-Match does not hold a valid version")
+	}
+
+	return version, nil
+}
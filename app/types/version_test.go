@@ -0,0 +1,55 @@
+package types
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newIfMatchContext(ifMatch string) echo.Context {
+	e := echo.New()
+	req := httptest.NewRequest("DELETE", "/profiles/5", nil)
+	if ifMatch != "" {
+		req.Header.Set(HeaderIfMatch, ifMatch)
+	}
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("5")
+	return ctx
+}
+
+func TestExpectedVersionFromIfMatch(t *testing.T) {
+	cases := map[string]uint64{
+		"":            0,
+		"*":           0,
+		FormatETag(3): 3,
+		`W/"12"`:      12,
+		` "7" `:       7,
+	}
+	for header, expected := range cases {
+		version, err := ExpectedVersionFromIfMatch(newIfMatchContext(header))
+		if err != nil {
+			t.Fatalf("If-Match %q: unexpected error: %v", header, err)
+		}
+		if version != expected {
+			t.Fatalf("If-Match %q: expected %d, got %d", header, expected, version)
+		}
+	}
+
+	for _, header := range []string{"3", `"abc"`, `"0"`, `"1", "2"`} {
+		if _, err := ExpectedVersionFromIfMatch(newIfMatchContext(header)); err == nil {
+			t.Fatalf("If-Match %q: expected error", header)
+		}
+	}
+}
+
+func TestNewDeleteProfileRequestFromContextReadsIfMatch(t *testing.T) {
+	req, err := NewDeleteProfileRequestFromContext(newIfMatchContext(`"4"`))
+	if err != nil {
+		t.Fatalf("expected parse success, got: %v", err)
+	}
+	if req.GetId() != 5 || req.GetExpectedVersion() != 4 {
+		t.Fatalf("unexpected parsed request: %+v", req)
+	}
+}
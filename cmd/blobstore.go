@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/vibast-solutions/ms-go-profile/app/blobstore"
+	"github.com/vibast-solutions/ms-go-profile/config"
+)
+
+// newBlobStore builds the attachment store named by ATTACHMENTS_STORE.
+func newBlobStore(cfg config.AttachmentsConfig) (blobstore.Store, error) {
+	switch cfg.Store {
+	case "local":
+		return blobstore.NewLocal(cfg.LocalDir), nil
+	case "s3":
+		return blobstore.NewS3(blobstore.S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PathStyle:       cfg.S3.PathStyle,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown attachments store %q", cfg.Store)
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/vibast-solutions/ms-go-profile/config"
+)
+
+func TestNewBlobStore(t *testing.T) {
+	if _, err := newBlobStore(config.AttachmentsConfig{Store: "local", LocalDir: t.TempDir()}); err != nil {
+		t.Fatalf("expected local store, got %v", err)
+	}
+	s3 := config.AttachmentsConfig{Store: "s3", S3: config.S3Config{
+		Endpoint: "http://minio:9000", Region: "us-east-1", Bucket: "profiles", AccessKeyID: "key", SecretAccessKey: "secret", PathStyle: true,
+	}}
+	if _, err := newBlobStore(s3); err != nil {
+		t.Fatalf("expected s3 store, got %v", err)
+	}
+	s3.S3.Bucket = ""
+	if _, err := newBlobStore(s3); err == nil {
+		t.Fatal("expected an error for an s3 store without a bucket")
+	}
+	if _, err := newBlobStore(config.AttachmentsConfig{Store: "gcs"}); err == nil {
+		t.Fatal("expected an error for an unknown store")
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"database/sql"
+
+	"github.com/vibast-solutions/ms-go-profile/config"
+)
+
+// openDatabase opens and pings the MySQL pool described by config.
+func openDatabase(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("mysql", cfg.MySQL.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.MySQL.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MySQL.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.MySQL.ConnMaxLifetime)
+
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
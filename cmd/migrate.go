@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/migration"
+	"github.com/vibast-solutions/ms-go-profile/config"
+	"github.com/vibast-solutions/ms-go-profile/migrations"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema migrations",
+	Long:  "Apply, roll back and inspect the versioned schema migrations embedded in the binary.",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Run:   runMigrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the most recently applied migrations",
+	Run:   runMigrateDown,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations and whether they are applied",
+	Run:   runMigrateStatus,
+}
+
+var migrateVersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the current and expected schema versions",
+	Run:   runMigrateVersion,
+}
+
+// init registers the migrate command and its subcommands.
+func init() {
+	migrateDownCmd.Flags().Int("steps", 1, "number of migrations to roll back")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateVersionCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// runMigrateUp applies every pending migration.
+func runMigrateUp(_ *cobra.Command, _ []string) {
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to apply migrations")
+	}
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+}
+
+// runMigrateDown rolls back the requested number of migrations.
+func runMigrateDown(cmd *cobra.Command, _ []string) {
+	steps, err := cmd.Flags().GetInt("steps")
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid --steps flag")
+	}
+
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	rolledBack, err := migrator.Down(context.Background(), steps)
+	for _, m := range rolledBack {
+		fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+	}
+	if errors.Is(err, migration.ErrNothingToRollback) {
+		fmt.Println("no applied migrations to roll back")
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to roll back migrations")
+	}
+}
+
+// runMigrateStatus prints every known migration with its applied state.
+func runMigrateStatus(_ *cobra.Command, _ []string) {
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read migration status")
+	}
+
+	for _, st := range statuses {
+		state := "pending"
+		if st.Applied {
+			state = "applied " + st.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%04d_%s\t%s\n", st.Migration.Version, st.Migration.Name, state)
+	}
+}
+
+// runMigrateVersion prints the applied schema version next to the binary's expectation.
+func runMigrateVersion(_ *cobra.Command, _ []string) {
+	migrator, db := openMigrator()
+	defer db.Close()
+
+	current, err := migrator.Version(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read schema version")
+	}
+
+	fmt.Printf("current: %d\nexpected: %d\n", current, migrator.LatestVersion())
+}
+
+// openMigrator loads config, opens the database and builds a migrator for the CLI subcommands.
+func openMigrator() (*migration.Migrator, *sql.DB) {
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load configuration")
+	}
+	if err := configureLogging(cfg); err != nil {
+		logrus.WithError(err).Fatal("Failed to configure logging")
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to connect to database")
+	}
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		_ = db.Close()
+		logrus.WithError(err).Fatal("Failed to load migrations")
+	}
+
+	return migrator, db
+}
+
+// newMigrator builds a migrator over the migrations embedded in the binary.
+func newMigrator(db *sql.DB) (*migration.Migrator, error) {
+	loaded, err := migration.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	return migration.NewMigrator(db, loaded), nil
+}
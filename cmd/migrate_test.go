@@ -0,0 +1,27 @@
+package cmd
+
+import "testing"
+
+func TestMigrateCommandRegistersSubcommands(t *testing.T) {
+	expected := map[string]bool{"up": false, "down": false, "status": false, "version": false}
+	for _, sub := range migrateCmd.Commands() {
+		if _, ok := expected[sub.Name()]; ok {
+			expected[sub.Name()] = true
+		}
+	}
+	for name, found := range expected {
+		if !found {
+			t.Fatalf("expected migrate subcommand %q to be registered", name)
+		}
+	}
+}
+
+func TestNewMigratorLoadsEmbeddedMigrations(t *testing.T) {
+	migrator, err := newMigrator(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if migrator.LatestVersion() == 0 {
+		t.Fatal("expected embedded migrations to define a latest version")
+	}
+}
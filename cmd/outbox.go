@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/publisher"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newEventPublisher builds the publisher named by OUTBOX_PUBLISHER.
+func newEventPublisher(name string) (service.EventPublisher, error) {
+	switch name {
+	case "log":
+		return publisher.NewLogPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", name)
+	}
+}
+
+func newOutboxRelay(cfg *config.Config, db *sql.DB, eventPublisher service.EventPublisher) *service.OutboxRelay {
+	return service.NewOutboxRelay(
+		repository.NewOutboxRepository(db),
+		repository.NewOutboxRelayLock(db),
+		eventPublisher,
+		uint32(max(cfg.Outbox.BatchSize, 0)),
+		cfg.Outbox.MaxBackoff,
+	)
+}
+
+// runOutboxRelayLoop relays pending events every interval until ctx is cancelled. A full batch
+// is followed right away by the next one, so a backlog drains without waiting for the ticker.
+func runOutboxRelayLoop(ctx context.Context, relay *service.OutboxRelay, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				published, err := relay.Relay(ctx, time.Now())
+				if err != nil {
+					logrus.WithError(err).Error("Outbox relay failed")
+					break
+				}
+				if published > 0 {
+					logrus.WithField("published", published).Debug("Outbox events relayed")
+				}
+				if published < relay.BatchSize() {
+					break
+				}
+			}
+		}
+	}
+}
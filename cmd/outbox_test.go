@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/vibast-solutions/ms-go-profile/config"
+)
+
+func TestNewEventPublisher(t *testing.T) {
+	if _, err := newEventPublisher("log"); err != nil {
+		t.Fatalf("expected log publisher, got %v", err)
+	}
+	if _, err := newEventPublisher("kafka"); err == nil {
+		t.Fatal("expected an error for an unknown publisher")
+	}
+}
+
+func TestNewOutboxRelayDefaultsNonPositiveBatchSize(t *testing.T) {
+	for _, batchSize := range []int{0, -5} {
+		cfg := &config.Config{Outbox: config.OutboxConfig{BatchSize: batchSize}}
+		if got := newOutboxRelay(cfg, nil, nil).BatchSize(); got != 100 {
+			t.Fatalf("batch size %d: expected relay batch size 100, got %d", batchSize, got)
+		}
+	}
+}
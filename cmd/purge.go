@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/blobstore"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/config"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Hard-delete records soft-deleted before the retention window",
+	Long:  "Run the purge job once. Retention comes from PURGE_RETENTION_DAYS unless --retention-days is given.",
+	Run:   runPurge,
+}
+
+// init registers the purge command.
+func init() {
+	purgeCmd.Flags().Int("retention-days", 0, "override PURGE_RETENTION_DAYS for this run")
+	rootCmd.AddCommand(purgeCmd)
+}
+
+// runPurge purges once and prints the number of removed rows per entity.
+func runPurge(cmd *cobra.Command, _ []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load configuration")
+	}
+	if err := configureLogging(cfg); err != nil {
+		logrus.WithError(err).Fatal("Failed to configure logging")
+	}
+
+	days, err := cmd.Flags().GetInt("retention-days")
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid --retention-days flag")
+	}
+	if days > 0 {
+		cfg.Purge.Retention = time.Duration(days) * 24 * time.Hour
+	}
+	if cfg.Purge.Retention <= 0 {
+		logrus.Fatal("Purge retention is not set (use PURGE_RETENTION_DAYS or --retention-days)")
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to connect to database")
+	}
+	defer db.Close()
+
+	blobs, err := newBlobStore(cfg.Attachments)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid ATTACHMENTS_STORE")
+	}
+
+	result, err := newPurgeService(cfg, db, blobs).Purge(context.Background(), time.Now().UTC())
+	for _, name := range []string{"attachments", "contacts", "addresses", "companies", "profiles", "email_locks"} {
+		fmt.Printf("%s\t%d\n", name, result[name])
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("Purge failed")
+	}
+}
+
+func newPurgeService(cfg *config.Config, db *sql.DB, blobs blobstore.Store) *service.PurgeService {
+	return service.NewPurgeService(
+		repository.NewProfileRepository(db),
+		// Purging never reads contact columns, so it needs no cipher.
+		repository.NewContactRepository(db, nil),
+		repository.NewAddressRepository(db),
+		repository.NewCompanyRepository(db),
+		service.NewAttachmentPurger(repository.NewAttachmentRepository(db), blobs),
+		repository.NewProfileEmailLockRepository(db),
+		cfg.Purge.Retention,
+		uint32(max(cfg.Purge.BatchSize, 0)),
+	)
+}
+
+// runPurgeLoop purges every interval until ctx is cancelled. Failures are logged and retried on the next tick.
+func runPurgeLoop(ctx context.Context, purgeService *service.PurgeService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := purgeService.Purge(ctx, time.Now().UTC())
+			if err != nil {
+				logrus.WithError(err).Error("Purge run failed")
+				continue
+			}
+			logrus.WithFields(logrus.Fields{
+				"attachments": result["attachments"],
+				"contacts":    result["contacts"],
+				"addresses":   result["addresses"],
+				"companies":   result["companies"],
+				"profiles":    result["profiles"],
+				"email_locks": result["email_locks"],
+			}).Info("Purge run finished")
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vibast-solutions/ms-go-profile/app/fieldcrypt"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
+	"github.com/vibast-solutions/ms-go-profile/config"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rotateKeysCmd = &cobra.Command{
+	Use:   "rotate-keys",
+	Short: "Re-encrypt contact fields with the active encryption key",
+	Long: "Rewrite every contact whose encrypted fields use an older key, or whose encryption settings changed, " +
+		"and recompute the NIN blind index. Safe to run while the service is serving traffic and to re-run after a failure.",
+	Run: runRotateKeys,
+}
+
+// init registers the rotate-keys command.
+func init() {
+	rotateKeysCmd.Flags().Uint32("batch-size", 500, "number of contacts read per batch")
+	rootCmd.AddCommand(rotateKeysCmd)
+}
+
+// runRotateKeys walks the contacts table in id order and prints the number of rewritten rows.
+func runRotateKeys(cmd *cobra.Command, _ []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load configuration")
+	}
+	if err := configureLogging(cfg); err != nil {
+		logrus.WithError(err).Fatal("Failed to configure logging")
+	}
+
+	batchSize, err := cmd.Flags().GetUint32("batch-size")
+	if err != nil || batchSize == 0 {
+		logrus.Fatal("Invalid --batch-size flag")
+	}
+
+	cipher, err := newFieldCipher(cfg.Encryption)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid encryption configuration")
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to connect to database")
+	}
+	defer db.Close()
+
+	repo := repository.NewContactRepository(db, cipher)
+	ctx := context.Background()
+	var afterID uint64
+	total := 0
+	for {
+		lastID, rotated, err := repo.RotateKeys(ctx, afterID, batchSize)
+		total += rotated
+		if err != nil {
+			fmt.Printf("contacts\t%d\n", total)
+			logrus.WithError(err).WithField("after_id", afterID).Fatal("Key rotation failed")
+		}
+		if lastID == afterID {
+			break
+		}
+		afterID = lastID
+		logrus.WithFields(logrus.Fields{"last_id": lastID, "rotated": total}).Debug("Key rotation batch finished")
+	}
+
+	fmt.Printf("contacts\t%d\n", total)
+}
+
+// newFieldCipher builds the contact field cipher from the configured keys. It returns a nil
+// cipher, which stores every field in plaintext, when no key is configured.
+func newFieldCipher(cfg config.EncryptionConfig) (*fieldcrypt.Cipher, error) {
+	keys, err := fieldcrypt.ParseKeys(cfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("ENCRYPTION_KEYS: %w", err)
+	}
+	if cfg.KeysDir != "" {
+		dirKeys, err := fieldcrypt.LoadKeyDir(cfg.KeysDir)
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_KEYS_DIR: %w", err)
+		}
+		for id, key := range dirKeys {
+			if _, ok := keys[id]; ok {
+				return nil, fmt.Errorf("encryption key %q is configured twice", id)
+			}
+			keys[id] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	activeKeyID := cfg.ActiveKeyID
+	if activeKeyID == "" {
+		if len(keys) > 1 {
+			return nil, errors.New("ENCRYPTION_ACTIVE_KEY_ID is required when more than one key is configured")
+		}
+		for id := range keys {
+			activeKeyID = id
+		}
+	}
+
+	var indexKey []byte
+	switch {
+	case cfg.BlindIndexKey != "":
+		if indexKey, err = fieldcrypt.DecodeKey(cfg.BlindIndexKey); err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_BLIND_INDEX_KEY: %w", err)
+		}
+	case cfg.BlindIndexKeyFile != "":
+		if indexKey, err = fieldcrypt.ReadKeyFile(cfg.BlindIndexKeyFile); err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_BLIND_INDEX_KEY_FILE: %w", err)
+		}
+	}
+
+	return fieldcrypt.New(keys, activeKeyID, indexKey, cfg.Fields)
+}
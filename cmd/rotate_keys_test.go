@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vibast-solutions/ms-go-profile/config"
+)
+
+func TestNewFieldCipher(t *testing.T) {
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)) }
+
+	cipher, err := newFieldCipher(config.EncryptionConfig{Fields: []string{"nin"}})
+	if err != nil || cipher != nil {
+		t.Fatalf("expected encryption to be disabled without keys, got %v, %v", cipher, err)
+	}
+
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "k2"), []byte(key(2)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	indexFile := filepath.Join(t.TempDir(), "index")
+	if err = os.WriteFile(indexFile, []byte(key(9)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cipher, err = newFieldCipher(config.EncryptionConfig{
+		Keys:              "k1:" + key(1),
+		KeysDir:           dir,
+		ActiveKeyID:       "k2",
+		BlindIndexKeyFile: indexFile,
+		Fields:            []string{"nin", "phone"},
+	})
+	if err != nil || !cipher.Encrypts("phone") {
+		t.Fatalf("expected cipher encrypting phone, got %v, %v", cipher, err)
+	}
+
+	cases := []config.EncryptionConfig{
+		{Keys: "k1:" + key(1), Fields: []string{"nin"}},
+		{Keys: "k1:" + key(1) + ",k2:" + key(2), BlindIndexKey: key(9), Fields: []string{"nin"}},
+		{Keys: "k2:" + key(1), KeysDir: dir, ActiveKeyID: "k2", BlindIndexKey: key(9)},
+		{Keys: "k1:" + key(1), BlindIndexKey: key(9), Fields: []string{"email"}},
+	}
+	for i, tc := range cases {
+		if _, err = newFieldCipher(tc); err == nil {
+			t.Fatalf("case %d: expected an error", i)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -15,11 +16,19 @@ import (
 	authclient "github.com/vibast-solutions/lib-go-auth/client"
 	authmiddleware "github.com/vibast-solutions/lib-go-auth/middleware"
 	authlibservice "github.com/vibast-solutions/lib-go-auth/service"
+	"github.com/vibast-solutions/ms-go-profile/app/attribute"
+	"github.com/vibast-solutions/ms-go-profile/app/caller"
 	"github.com/vibast-solutions/ms-go-profile/app/controller"
+	"github.com/vibast-solutions/ms-go-profile/app/fieldpolicy"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
 	profilegrpc "github.com/vibast-solutions/ms-go-profile/app/grpc"
+	"github.com/vibast-solutions/ms-go-profile/app/jsonschema"
+	"github.com/vibast-solutions/ms-go-profile/app/migration"
+	"github.com/vibast-solutions/ms-go-profile/app/phone"
 	"github.com/vibast-solutions/ms-go-profile/app/repository"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
 	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
 	"github.com/vibast-solutions/ms-go-profile/config"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -52,32 +61,76 @@ func runServe(_ *cobra.Command, _ []string) {
 		logrus.WithError(err).Fatal("Failed to configure logging")
 	}
 
-	db, err := sql.Open("mysql", cfg.MySQL.DSN)
+	db, err := openDatabase(cfg)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to connect to database")
 	}
 	defer db.Close()
 
-	db.SetMaxOpenConns(cfg.MySQL.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.MySQL.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.MySQL.ConnMaxLifetime)
+	if cfg.Migrations.CheckOnStartup {
+		if err := checkSchemaVersion(db); err != nil {
+			logrus.WithError(err).Fatal("Database schema check failed")
+		}
+	}
 
-	if err := db.Ping(); err != nil {
-		logrus.WithError(err).Fatal("Failed to ping database")
+	txIsolation, err := repository.ParseIsolationLevel(cfg.MySQL.TxIsolation)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid MYSQL_TX_ISOLATION")
+	}
+	fieldCipher, err := newFieldCipher(cfg.Encryption)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid encryption configuration")
+	}
+	if region := cfg.Phone.DefaultRegion; region != "" && !phone.IsRegion(region) {
+		logrus.WithField("region", region).Fatal("Invalid PHONE_DEFAULT_REGION")
+	}
+	fiscalValidators := fiscal.NewValidators(cfg.Fiscal.DefaultCountry)
+	if country := cfg.Fiscal.DefaultCountry; country != "" && !fiscalValidators.Has(country) {
+		logrus.WithField("country", country).Fatal("Invalid FISCAL_DEFAULT_COUNTRY")
+	}
+	var preferenceSchemas map[string]*jsonschema.Schema
+	if dir := cfg.Preferences.SchemaDir; dir != "" {
+		if preferenceSchemas, err = jsonschema.LoadDir(dir); err != nil {
+			logrus.WithError(err).Fatal("Invalid PREFERENCES_SCHEMA_DIR")
+		}
+	}
+	var attributes *attribute.Registry
+	if path := cfg.Attributes.File; path != "" {
+		if attributes, err = attribute.Load(path); err != nil {
+			logrus.WithError(err).Fatal("Invalid ATTRIBUTES_FILE")
+		}
 	}
+	blobs, err := newBlobStore(cfg.Attachments)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid ATTACHMENTS_STORE")
+	}
+	unitOfWork := service.NewUnitOfWork(repository.NewTxManager(db, txIsolation, fieldCipher))
 
 	profileRepo := repository.NewProfileRepository(db)
-	profileService := service.NewProfileService(profileRepo)
+	profileService := service.NewProfileService(profileRepo, unitOfWork, cfg.Profile.UniqueEmail, cfg.Phone.DefaultRegion, attributes)
 	profileController := controller.NewProfileController(profileService)
-	contactRepo := repository.NewContactRepository(db)
-	contactService := service.NewContactService(contactRepo)
+	contactRepo := repository.NewContactRepository(db, fieldCipher)
+	contactService := service.NewContactService(contactRepo, unitOfWork, cfg.Phone.DefaultRegion, attributes)
 	contactController := controller.NewContactController(contactService)
 	addressRepo := repository.NewAddressRepository(db)
-	addressService := service.NewAddressService(addressRepo)
+	// No geocoding service is wired yet, so addresses only get the coordinates they are written with.
+	addressService := service.NewAddressService(addressRepo, unitOfWork, nil, attributes)
 	addressController := controller.NewAddressController(addressService)
 	companyRepo := repository.NewCompanyRepository(db)
-	companyService := service.NewCompanyService(companyRepo)
+	companyService := service.NewCompanyService(companyRepo, unitOfWork, fiscalValidators, attributes)
 	companyController := controller.NewCompanyController(companyService)
+	preferenceService := service.NewPreferenceService(unitOfWork, preferenceSchemas)
+	preferenceController := controller.NewPreferenceController(preferenceService)
+	attachmentService := service.NewAttachmentService(unitOfWork, blobs, service.AttachmentLimits{
+		MaxSize:      cfg.Attachments.MaxSize,
+		ContentTypes: cfg.Attachments.ContentTypes,
+	})
+	attachmentController := controller.NewAttachmentController(attachmentService)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	auditController := controller.NewAuditController(auditService)
+	profileServer := profilegrpc.NewProfileServer(profileService, contactService, addressService, companyService, preferenceService, attachmentService, auditService)
+	profileServerV2 := profilegrpc.NewProfileServerV2(profileServer)
+	v2Controller := controller.NewV2Controller(profileServerV2)
 
 	authGRPCClient, err := authclient.NewGRPCClientFromAddr(context.Background(), cfg.InternalEndpoints.AuthGRPCAddr)
 	if err != nil {
@@ -88,24 +141,58 @@ func runServe(_ *cobra.Command, _ []string) {
 	echoInternalAuthMiddleware := authmiddleware.NewEchoInternalAuthMiddleware(internalAuthService)
 	grpcInternalAuthMiddleware := authmiddleware.NewGRPCInternalAuthMiddleware(internalAuthService)
 
+	fieldPolicy, err := fieldpolicy.Parse(cfg.FieldPolicy.Default, cfg.FieldPolicy.Services)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid field policy configuration")
+	}
+
 	e := setupHTTPServer(
 		profileController,
 		contactController,
 		addressController,
 		companyController,
+		preferenceController,
+		attachmentController,
+		auditController,
+		v2Controller,
 		echoInternalAuthMiddleware,
 		cfg.App.ServiceName,
+		cfg.App.AdminAccess,
+		fieldPolicy,
 	)
 	grpcServer, lis := setupGRPCServer(
 		cfg,
-		profileService,
-		contactService,
-		addressService,
-		companyService,
+		profileServer,
+		profileServerV2,
 		grpcInternalAuthMiddleware,
 		cfg.App.ServiceName,
+		fieldPolicy,
 	)
 
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	if cfg.Purge.Retention > 0 {
+		logrus.WithFields(logrus.Fields{
+			"retention": cfg.Purge.Retention.String(),
+			"interval":  cfg.Purge.Interval.String(),
+		}).Info("Starting purge job")
+		go runPurgeLoop(purgeCtx, newPurgeService(cfg, db, blobs), cfg.Purge.Interval)
+	}
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if cfg.Outbox.RelayEnabled {
+		eventPublisher, err := newEventPublisher(cfg.Outbox.Publisher)
+		if err != nil {
+			logrus.WithError(err).Fatal("Invalid OUTBOX_PUBLISHER")
+		}
+		logrus.WithFields(logrus.Fields{
+			"publisher": cfg.Outbox.Publisher,
+			"interval":  cfg.Outbox.PollInterval.String(),
+		}).Info("Starting outbox relay")
+		go runOutboxRelayLoop(relayCtx, newOutboxRelay(cfg, db, eventPublisher), cfg.Outbox.PollInterval)
+	}
+
 	go func() {
 		httpAddr := net.JoinHostPort(cfg.HTTP.Host, cfg.HTTP.Port)
 		logrus.WithField("addr", httpAddr).Info("Starting HTTP server")
@@ -125,6 +212,8 @@ func runServe(_ *cobra.Command, _ []string) {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logrus.Info("Shutting down...")
+	stopPurge()
+	stopRelay()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -137,14 +226,40 @@ func runServe(_ *cobra.Command, _ []string) {
 	logrus.Info("Server stopped")
 }
 
+// checkSchemaVersion refuses to continue when the database is behind the embedded migrations.
+func checkSchemaVersion(db *sql.DB) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = migrator.CheckUpToDate(ctx); err != nil {
+		if errors.Is(err, migration.ErrSchemaOutdated) {
+			return fmt.Errorf("%w (run `profile migrate up`)", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
 // setupHTTPServer configures the Echo HTTP server and routes.
 func setupHTTPServer(
 	profileCtrl *controller.ProfileController,
 	contactCtrl *controller.ContactController,
 	addressCtrl *controller.AddressController,
 	companyCtrl *controller.CompanyController,
+	preferenceCtrl *controller.PreferenceController,
+	attachmentCtrl *controller.AttachmentController,
+	auditCtrl *controller.AuditController,
+	v2Ctrl *controller.V2Controller,
 	internalAuthMiddleware *authmiddleware.EchoInternalAuthMiddleware,
 	appServiceName string,
+	adminAccess string,
+	fieldPolicy *fieldpolicy.Policy,
 ) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
@@ -188,38 +303,126 @@ func setupHTTPServer(
 		},
 	}))
 	e.Use(internalAuthMiddleware.RequireInternalAccess(appServiceName))
+	e.Use(caller.EchoMiddleware(adminAccess))
+	e.Use(fieldpolicy.EchoMiddleware(fieldPolicy))
 
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{"status": "ok"})
 	})
 
-	profiles := e.Group("/profiles")
+	deprecated := controller.DeprecatedV1()
+
+	profiles := e.Group("/profiles", deprecated)
 	profiles.POST("", profileCtrl.Create)
 	profiles.GET("/:id", profileCtrl.GetByID)
+	profiles.GET("/:id/full", profileCtrl.GetBundle)
 	profiles.GET("/user/:user_id", profileCtrl.GetByUserID)
+	profiles.GET("/email/:email", profileCtrl.GetByEmail)
 	profiles.PUT("/:id", profileCtrl.Update)
+	profiles.PATCH("/:id", profileCtrl.Patch)
 	profiles.DELETE("/:id", profileCtrl.Delete)
+	profiles.POST("/:id/restore", profileCtrl.Restore)
+	profiles.GET("/:id/companies", companyCtrl.ListProfileCompanies)
+	profiles.GET("/:id/preferences", preferenceCtrl.Get)
+	profiles.PUT("/:id/preferences/:namespace", preferenceCtrl.Set)
+	profiles.DELETE("/:id/preferences/:namespace/:key", preferenceCtrl.Delete)
 
-	contacts := e.Group("/contacts")
+	contacts := e.Group("/contacts", deprecated)
 	contacts.POST("", contactCtrl.Create)
 	contacts.GET("/:id", contactCtrl.GetByID)
 	contacts.PUT("/:id", contactCtrl.Update)
+	contacts.PATCH("/:id", contactCtrl.Patch)
 	contacts.DELETE("/:id", contactCtrl.Delete)
+	contacts.POST("/:id/restore", contactCtrl.Restore)
+	contacts.POST("/:id/primary", contactCtrl.SetPrimary)
 	contacts.GET("", contactCtrl.List)
 
-	addresses := e.Group("/addresses")
+	addresses := e.Group("/addresses", deprecated)
 	addresses.POST("", addressCtrl.Create)
+	addresses.GET("/near", addressCtrl.SearchNear)
 	addresses.GET("/:id", addressCtrl.GetByID)
 	addresses.PUT("/:id", addressCtrl.Update)
+	addresses.PATCH("/:id", addressCtrl.Patch)
 	addresses.DELETE("/:id", addressCtrl.Delete)
+	addresses.POST("/:id/restore", addressCtrl.Restore)
+	addresses.POST("/:id/primary", addressCtrl.SetPrimary)
 	addresses.GET("", addressCtrl.List)
 
-	companies := e.Group("/companies")
+	companies := e.Group("/companies", deprecated)
 	companies.POST("", companyCtrl.Create)
 	companies.GET("/:id", companyCtrl.GetByID)
 	companies.PUT("/:id", companyCtrl.Update)
+	companies.PATCH("/:id", companyCtrl.Patch)
 	companies.DELETE("/:id", companyCtrl.Delete)
+	companies.POST("/:id/restore", companyCtrl.Restore)
+	companies.POST("/:id/primary", companyCtrl.SetPrimary)
 	companies.GET("", companyCtrl.List)
+	companies.GET("/:id/members", companyCtrl.ListMembers)
+	companies.PUT("/:id/members/:profile_id", companyCtrl.AddMember)
+	companies.DELETE("/:id/members/:profile_id", companyCtrl.RemoveMember)
+
+	attachments := e.Group("/attachments", deprecated)
+	attachments.POST("", attachmentCtrl.Upload)
+	attachments.GET("", attachmentCtrl.List)
+	attachments.GET("/:id", attachmentCtrl.GetByID)
+	attachments.GET("/:id/content", attachmentCtrl.Download)
+	attachments.DELETE("/:id", attachmentCtrl.Delete)
+
+	e.GET("/audit", auditCtrl.List, deprecated)
+
+	v2 := e.Group("/v2")
+	v2.POST("/profiles", v2Ctrl.CreateProfile)
+	v2.GET("/profiles/:id", v2Ctrl.GetProfile)
+	v2.GET("/profiles/:id/full", v2Ctrl.GetProfileBundle)
+	v2.GET("/profiles/user/:user_id", v2Ctrl.GetProfileByUserID)
+	v2.GET("/profiles/email/:email", v2Ctrl.GetProfileByEmail)
+	v2.PUT("/profiles/:id", v2Ctrl.UpdateProfile)
+	v2.PATCH("/profiles/:id", v2Ctrl.PatchProfile)
+	v2.DELETE("/profiles/:id", v2Ctrl.DeleteProfile)
+	v2.POST("/profiles/:id/restore", v2Ctrl.RestoreProfile)
+	v2.GET("/profiles/:profile_id/companies", v2Ctrl.ListProfileCompanies)
+	v2.GET("/profiles/:profile_id/preferences", v2Ctrl.GetPreferences)
+	v2.PUT("/profiles/:profile_id/preferences/:namespace", v2Ctrl.SetPreferences)
+	v2.DELETE("/profiles/:profile_id/preferences/:namespace/:key", v2Ctrl.DeletePreference)
+
+	v2.POST("/contacts", v2Ctrl.CreateContact)
+	v2.GET("/contacts/:id", v2Ctrl.GetContact)
+	v2.PUT("/contacts/:id", v2Ctrl.UpdateContact)
+	v2.PATCH("/contacts/:id", v2Ctrl.PatchContact)
+	v2.DELETE("/contacts/:id", v2Ctrl.DeleteContact)
+	v2.POST("/contacts/:id/restore", v2Ctrl.RestoreContact)
+	v2.POST("/contacts/:id/primary", v2Ctrl.SetPrimaryContact)
+	v2.GET("/contacts", v2Ctrl.ListContacts)
+
+	v2.POST("/addresses", v2Ctrl.CreateAddress)
+	v2.GET("/addresses/near", v2Ctrl.SearchAddressesNear)
+	v2.GET("/addresses/:id", v2Ctrl.GetAddress)
+	v2.PUT("/addresses/:id", v2Ctrl.UpdateAddress)
+	v2.PATCH("/addresses/:id", v2Ctrl.PatchAddress)
+	v2.DELETE("/addresses/:id", v2Ctrl.DeleteAddress)
+	v2.POST("/addresses/:id/restore", v2Ctrl.RestoreAddress)
+	v2.POST("/addresses/:id/primary", v2Ctrl.SetPrimaryAddress)
+	v2.GET("/addresses", v2Ctrl.ListAddresses)
+
+	v2.POST("/companies", v2Ctrl.CreateCompany)
+	v2.GET("/companies/:id", v2Ctrl.GetCompany)
+	v2.PUT("/companies/:id", v2Ctrl.UpdateCompany)
+	v2.PATCH("/companies/:id", v2Ctrl.PatchCompany)
+	v2.DELETE("/companies/:id", v2Ctrl.DeleteCompany)
+	v2.POST("/companies/:id/restore", v2Ctrl.RestoreCompany)
+	v2.POST("/companies/:id/primary", v2Ctrl.SetPrimaryCompany)
+	v2.GET("/companies", v2Ctrl.ListCompanies)
+	v2.GET("/companies/:company_id/members", v2Ctrl.ListCompanyMembers)
+	v2.PUT("/companies/:company_id/members/:profile_id", v2Ctrl.AddCompanyMember)
+	v2.DELETE("/companies/:company_id/members/:profile_id", v2Ctrl.RemoveCompanyMember)
+
+	v2.POST("/attachments", v2Ctrl.UploadAttachment)
+	v2.GET("/attachments", v2Ctrl.ListAttachments)
+	v2.GET("/attachments/:id", v2Ctrl.GetAttachment)
+	v2.GET("/attachments/:id/content", v2Ctrl.DownloadAttachment)
+	v2.DELETE("/attachments/:id", v2Ctrl.DeleteAttachment)
+
+	v2.GET("/audit", v2Ctrl.ListAuditEvents)
 
 	return e
 }
@@ -227,12 +430,11 @@ func setupHTTPServer(
 // setupGRPCServer builds the gRPC server and listener.
 func setupGRPCServer(
 	cfg *config.Config,
-	profileSvc *service.ProfileService,
-	contactSvc *service.ContactService,
-	addressSvc *service.AddressService,
-	companySvc *service.CompanyService,
+	profileServer *profilegrpc.ProfileServer,
+	profileServerV2 *profilegrpc.ProfileServerV2,
 	internalAuthMiddleware *authmiddleware.GRPCInternalAuthMiddleware,
 	appServiceName string,
+	fieldPolicy *fieldpolicy.Policy,
 ) (*grpc.Server, net.Listener) {
 	grpcAddr := net.JoinHostPort(cfg.GRPC.Host, cfg.GRPC.Port)
 	lis, err := net.Listen("tcp", grpcAddr)
@@ -246,10 +448,22 @@ func setupGRPCServer(
 			profilegrpc.RequestIDInterceptor(),
 			profilegrpc.LoggingInterceptor(),
 			internalAuthMiddleware.UnaryRequireInternalAccess(appServiceName),
+			profilegrpc.CallerInterceptor(cfg.App.AdminAccess),
+			profilegrpc.FieldPolicyInterceptor(fieldPolicy),
+			profilegrpc.DeprecationInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			profilegrpc.StreamInterceptor(profilegrpc.RecoveryInterceptor()),
+			profilegrpc.StreamInterceptor(profilegrpc.RequestIDInterceptor()),
+			profilegrpc.StreamInterceptor(profilegrpc.LoggingInterceptor()),
+			internalAuthMiddleware.StreamRequireInternalAccess(appServiceName),
+			profilegrpc.StreamInterceptor(profilegrpc.CallerInterceptor(cfg.App.AdminAccess)),
+			profilegrpc.StreamInterceptor(profilegrpc.FieldPolicyInterceptor(fieldPolicy)),
+			profilegrpc.StreamInterceptor(profilegrpc.DeprecationInterceptor()),
 		),
 	)
-	profileServer := profilegrpc.NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc)
 	types.RegisterProfileServiceServer(grpcServer, profileServer)
+	typesv2.RegisterProfileServiceServer(grpcServer, profileServerV2)
 
 	return grpcServer, lis
 }
@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"context"
+	"database/sql"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	authclient "github.com/vibast-solutions/lib-go-auth/client"
@@ -13,48 +15,152 @@ import (
 	authservice "github.com/vibast-solutions/lib-go-auth/service"
 	"github.com/vibast-solutions/ms-go-profile/app/controller"
 	"github.com/vibast-solutions/ms-go-profile/app/entity"
+	"github.com/vibast-solutions/ms-go-profile/app/fiscal"
+	profilegrpc "github.com/vibast-solutions/ms-go-profile/app/grpc"
+	"github.com/vibast-solutions/ms-go-profile/app/repository"
 	"github.com/vibast-solutions/ms-go-profile/app/service"
+	"github.com/vibast-solutions/ms-go-profile/app/types"
 )
 
 type cmdRepoStub struct{}
 
-func (cmdRepoStub) Create(context.Context, *entity.Profile) error             { return nil }
-func (cmdRepoStub) FindByID(context.Context, uint64) (*entity.Profile, error) { return nil, nil }
-func (cmdRepoStub) FindByUserID(context.Context, uint64) (*entity.Profile, error) {
+func (cmdRepoStub) Create(context.Context, *entity.Profile) error                   { return nil }
+func (cmdRepoStub) FindByID(context.Context, uint64, bool) (*entity.Profile, error) { return nil, nil }
+func (cmdRepoStub) FindByIDForUpdate(context.Context, uint64) (*entity.Profile, error) {
+	return nil, nil
+}
+func (cmdRepoStub) FindByUserID(context.Context, uint64, bool) (*entity.Profile, error) {
+	return nil, nil
+}
+func (cmdRepoStub) FindByEmail(context.Context, string, bool) (*entity.Profile, error) {
+	return nil, nil
+}
+func (cmdRepoStub) FindByEmailForUpdate(context.Context, string) (*entity.Profile, error) {
 	return nil, nil
 }
 func (cmdRepoStub) Update(context.Context, *entity.Profile) error { return nil }
-func (cmdRepoStub) Delete(context.Context, uint64) error          { return nil }
+func (cmdRepoStub) Delete(context.Context, uint64, uint64) error  { return nil }
+func (cmdRepoStub) Restore(context.Context, uint64) error         { return nil }
 
 type cmdContactRepoStub struct{}
 
-func (cmdContactRepoStub) Create(context.Context, *entity.Contact) error             { return nil }
-func (cmdContactRepoStub) FindByID(context.Context, uint64) (*entity.Contact, error) { return nil, nil }
-func (cmdContactRepoStub) Update(context.Context, *entity.Contact) error             { return nil }
-func (cmdContactRepoStub) Delete(context.Context, uint64) error                      { return nil }
-func (cmdContactRepoStub) List(context.Context, uint64, string, uint32, uint32) ([]*entity.Contact, uint64, error) {
+func (cmdContactRepoStub) Create(context.Context, *entity.Contact) error { return nil }
+func (cmdContactRepoStub) FindByID(context.Context, uint64, bool) (*entity.Contact, error) {
+	return nil, nil
+}
+func (cmdContactRepoStub) FindPrimary(context.Context, uint64, string) (*entity.Contact, error) {
+	return nil, nil
+}
+func (cmdContactRepoStub) Update(context.Context, *entity.Contact) error               { return nil }
+func (cmdContactRepoStub) Delete(context.Context, uint64, uint64) error                { return nil }
+func (cmdContactRepoStub) DeleteByProfileID(context.Context, uint64) error             { return nil }
+func (cmdContactRepoStub) Restore(context.Context, uint64) error                       { return nil }
+func (cmdContactRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error { return nil }
+func (cmdContactRepoStub) List(context.Context, uint64, string, string, map[string]string, bool, bool, uint32, uint32) ([]*entity.Contact, uint64, error) {
 	return nil, 0, nil
 }
+func (cmdContactRepoStub) ListByProfileID(context.Context, uint64) ([]*entity.Contact, error) {
+	return nil, nil
+}
+func (cmdContactRepoStub) Encrypts(string) bool { return false }
 
 type cmdAddressRepoStub struct{}
 
-func (cmdAddressRepoStub) Create(context.Context, *entity.Address) error             { return nil }
-func (cmdAddressRepoStub) FindByID(context.Context, uint64) (*entity.Address, error) { return nil, nil }
-func (cmdAddressRepoStub) Update(context.Context, *entity.Address) error             { return nil }
-func (cmdAddressRepoStub) Delete(context.Context, uint64) error                      { return nil }
-func (cmdAddressRepoStub) List(context.Context, uint64, string, uint32, uint32) ([]*entity.Address, uint64, error) {
+func (cmdAddressRepoStub) Create(context.Context, *entity.Address) error { return nil }
+func (cmdAddressRepoStub) FindByID(context.Context, uint64, bool) (*entity.Address, error) {
+	return nil, nil
+}
+func (cmdAddressRepoStub) FindPrimary(context.Context, uint64, uint64, string) (*entity.Address, error) {
+	return nil, nil
+}
+func (cmdAddressRepoStub) Update(context.Context, *entity.Address) error               { return nil }
+func (cmdAddressRepoStub) Delete(context.Context, uint64, uint64) error                { return nil }
+func (cmdAddressRepoStub) DeleteByProfileID(context.Context, uint64) error             { return nil }
+func (cmdAddressRepoStub) Restore(context.Context, uint64) error                       { return nil }
+func (cmdAddressRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error { return nil }
+func (cmdAddressRepoStub) DeleteByCompanyID(context.Context, uint64) error             { return nil }
+func (cmdAddressRepoStub) RestoreByCompanyID(context.Context, uint64, time.Time) error { return nil }
+func (cmdAddressRepoStub) List(context.Context, uint64, uint64, string, map[string]string, bool, bool, uint32, uint32) ([]*entity.Address, uint64, error) {
 	return nil, 0, nil
 }
+func (cmdAddressRepoStub) ListByProfileID(context.Context, uint64) ([]*entity.Address, error) {
+	return nil, nil
+}
+func (cmdAddressRepoStub) ListByCompanyID(context.Context, uint64) ([]*entity.Address, error) {
+	return nil, nil
+}
+func (cmdAddressRepoStub) SearchNear(context.Context, repository.AddressSearch) ([]*entity.NearbyAddress, error) {
+	return nil, nil
+}
 
 type cmdCompanyRepoStub struct{}
 
-func (cmdCompanyRepoStub) Create(context.Context, *entity.Company) error             { return nil }
-func (cmdCompanyRepoStub) FindByID(context.Context, uint64) (*entity.Company, error) { return nil, nil }
-func (cmdCompanyRepoStub) Update(context.Context, *entity.Company) error             { return nil }
-func (cmdCompanyRepoStub) Delete(context.Context, uint64) error                      { return nil }
-func (cmdCompanyRepoStub) List(context.Context, uint64, string, uint32, uint32) ([]*entity.Company, uint64, error) {
+func (cmdCompanyRepoStub) Create(context.Context, *entity.Company) error { return nil }
+func (cmdCompanyRepoStub) FindByID(context.Context, uint64, bool) (*entity.Company, error) {
+	return nil, nil
+}
+func (cmdCompanyRepoStub) FindPrimary(context.Context, uint64, string) (*entity.Company, error) {
+	return nil, nil
+}
+func (cmdCompanyRepoStub) FindByFiscalCode(context.Context, string) (*entity.Company, error) {
+	return nil, nil
+}
+func (cmdCompanyRepoStub) Update(context.Context, *entity.Company) error               { return nil }
+func (cmdCompanyRepoStub) Delete(context.Context, uint64, uint64) error                { return nil }
+func (cmdCompanyRepoStub) DeleteByProfileID(context.Context, uint64) error             { return nil }
+func (cmdCompanyRepoStub) Restore(context.Context, uint64) error                       { return nil }
+func (cmdCompanyRepoStub) RestoreByProfileID(context.Context, uint64, time.Time) error { return nil }
+func (cmdCompanyRepoStub) List(context.Context, uint64, string, map[string]string, bool, bool, uint32, uint32) ([]*entity.Company, uint64, error) {
 	return nil, 0, nil
 }
+func (cmdCompanyRepoStub) ListByProfileID(context.Context, uint64) ([]*entity.Company, error) {
+	return nil, nil
+}
+func (cmdCompanyRepoStub) ListForProfile(context.Context, uint64) ([]*entity.Company, error) {
+	return nil, nil
+}
+
+type cmdCompanyMemberRepoStub struct{}
+
+func (cmdCompanyMemberRepoStub) Save(context.Context, *entity.CompanyMember) error { return nil }
+func (cmdCompanyMemberRepoStub) Find(context.Context, uint64, uint64) (*entity.CompanyMember, error) {
+	return nil, nil
+}
+func (cmdCompanyMemberRepoStub) CountByRole(context.Context, uint64, string) (uint64, error) {
+	return 0, nil
+}
+func (cmdCompanyMemberRepoStub) Delete(context.Context, uint64, uint64) error { return nil }
+func (cmdCompanyMemberRepoStub) ListByCompanyID(context.Context, uint64, uint32, uint32) ([]*entity.CompanyMember, uint64, error) {
+	return nil, 0, nil
+}
+func (cmdCompanyMemberRepoStub) ListByProfileID(context.Context, uint64, uint32, uint32) ([]*entity.CompanyMembership, uint64, error) {
+	return nil, 0, nil
+}
+
+type cmdAuditRepoStub struct{}
+
+func (cmdAuditRepoStub) Create(context.Context, *entity.AuditEvent) error { return nil }
+func (cmdAuditRepoStub) List(context.Context, string, uint64, uint32, uint32) ([]*entity.AuditEvent, uint64, error) {
+	return nil, 0, nil
+}
+
+type cmdOutboxRepoStub struct{}
+
+func (cmdOutboxRepoStub) Create(context.Context, *entity.OutboxEvent) error { return nil }
+
+type cmdUnitOfWorkStub struct{}
+
+func (cmdUnitOfWorkStub) Do(ctx context.Context, _ *sql.TxOptions, fn func(context.Context, service.Repositories) error) error {
+	return fn(ctx, service.Repositories{
+		Profiles:       cmdRepoStub{},
+		Contacts:       cmdContactRepoStub{},
+		Addresses:      cmdAddressRepoStub{},
+		Companies:      cmdCompanyRepoStub{},
+		CompanyMembers: cmdCompanyMemberRepoStub{},
+		Audit:          cmdAuditRepoStub{},
+		Outbox:         cmdOutboxRepoStub{},
+	})
+}
 
 type internalAuthClientStub struct{}
 
@@ -81,16 +187,19 @@ func newInternalAuthMiddlewareStub() *authmiddleware.EchoInternalAuthMiddleware
 }
 
 func TestSetupHTTPServerHealthRoute(t *testing.T) {
-	profileSvc := service.NewProfileService(cmdRepoStub{})
+	profileSvc := service.NewProfileService(cmdRepoStub{}, cmdUnitOfWorkStub{}, false, "", nil)
 	profileCtrl := controller.NewProfileController(profileSvc)
-	contactSvc := service.NewContactService(cmdContactRepoStub{})
+	contactSvc := service.NewContactService(cmdContactRepoStub{}, cmdUnitOfWorkStub{}, "", nil)
 	contactCtrl := controller.NewContactController(contactSvc)
-	addressSvc := service.NewAddressService(cmdAddressRepoStub{})
+	addressSvc := service.NewAddressService(cmdAddressRepoStub{}, cmdUnitOfWorkStub{}, nil, nil)
 	addressCtrl := controller.NewAddressController(addressSvc)
-	companySvc := service.NewCompanyService(cmdCompanyRepoStub{})
+	companySvc := service.NewCompanyService(cmdCompanyRepoStub{}, cmdUnitOfWorkStub{}, fiscal.NewValidators(""), nil)
 	companyCtrl := controller.NewCompanyController(companySvc)
+	preferenceCtrl := controller.NewPreferenceController(service.NewPreferenceService(cmdUnitOfWorkStub{}, nil))
+	attachmentCtrl := controller.NewAttachmentController(service.NewAttachmentService(cmdUnitOfWorkStub{}, nil, service.AttachmentLimits{}))
+	auditCtrl := controller.NewAuditController(service.NewAuditService(cmdAuditRepoStub{}))
 	internalAuthMW := newInternalAuthMiddlewareStub()
-	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, internalAuthMW, "profile-service")
+	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, preferenceCtrl, attachmentCtrl, auditCtrl, newCmdV2Controller(profileSvc, contactSvc, addressSvc, companySvc), internalAuthMW, "profile-service", "profile-service:admin", nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rec := httptest.NewRecorder()
@@ -102,16 +211,19 @@ func TestSetupHTTPServerHealthRoute(t *testing.T) {
 }
 
 func TestSetupHTTPServerHealthRouteForbidden(t *testing.T) {
-	profileSvc := service.NewProfileService(cmdRepoStub{})
+	profileSvc := service.NewProfileService(cmdRepoStub{}, cmdUnitOfWorkStub{}, false, "", nil)
 	profileCtrl := controller.NewProfileController(profileSvc)
-	contactSvc := service.NewContactService(cmdContactRepoStub{})
+	contactSvc := service.NewContactService(cmdContactRepoStub{}, cmdUnitOfWorkStub{}, "", nil)
 	contactCtrl := controller.NewContactController(contactSvc)
-	addressSvc := service.NewAddressService(cmdAddressRepoStub{})
+	addressSvc := service.NewAddressService(cmdAddressRepoStub{}, cmdUnitOfWorkStub{}, nil, nil)
 	addressCtrl := controller.NewAddressController(addressSvc)
-	companySvc := service.NewCompanyService(cmdCompanyRepoStub{})
+	companySvc := service.NewCompanyService(cmdCompanyRepoStub{}, cmdUnitOfWorkStub{}, fiscal.NewValidators(""), nil)
 	companyCtrl := controller.NewCompanyController(companySvc)
+	preferenceCtrl := controller.NewPreferenceController(service.NewPreferenceService(cmdUnitOfWorkStub{}, nil))
+	attachmentCtrl := controller.NewAttachmentController(service.NewAttachmentService(cmdUnitOfWorkStub{}, nil, service.AttachmentLimits{}))
+	auditCtrl := controller.NewAuditController(service.NewAuditService(cmdAuditRepoStub{}))
 	internalAuthMW := newInternalAuthMiddlewareStub()
-	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, internalAuthMW, "profile-service")
+	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, preferenceCtrl, attachmentCtrl, auditCtrl, newCmdV2Controller(profileSvc, contactSvc, addressSvc, companySvc), internalAuthMW, "profile-service", "profile-service:admin", nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	req.Header.Set("X-API-Key", "no-access-key")
@@ -124,16 +236,19 @@ func TestSetupHTTPServerHealthRouteForbidden(t *testing.T) {
 }
 
 func TestSetupHTTPServerHealthRouteAuthorized(t *testing.T) {
-	profileSvc := service.NewProfileService(cmdRepoStub{})
+	profileSvc := service.NewProfileService(cmdRepoStub{}, cmdUnitOfWorkStub{}, false, "", nil)
 	profileCtrl := controller.NewProfileController(profileSvc)
-	contactSvc := service.NewContactService(cmdContactRepoStub{})
+	contactSvc := service.NewContactService(cmdContactRepoStub{}, cmdUnitOfWorkStub{}, "", nil)
 	contactCtrl := controller.NewContactController(contactSvc)
-	addressSvc := service.NewAddressService(cmdAddressRepoStub{})
+	addressSvc := service.NewAddressService(cmdAddressRepoStub{}, cmdUnitOfWorkStub{}, nil, nil)
 	addressCtrl := controller.NewAddressController(addressSvc)
-	companySvc := service.NewCompanyService(cmdCompanyRepoStub{})
+	companySvc := service.NewCompanyService(cmdCompanyRepoStub{}, cmdUnitOfWorkStub{}, fiscal.NewValidators(""), nil)
 	companyCtrl := controller.NewCompanyController(companySvc)
+	preferenceCtrl := controller.NewPreferenceController(service.NewPreferenceService(cmdUnitOfWorkStub{}, nil))
+	attachmentCtrl := controller.NewAttachmentController(service.NewAttachmentService(cmdUnitOfWorkStub{}, nil, service.AttachmentLimits{}))
+	auditCtrl := controller.NewAuditController(service.NewAuditService(cmdAuditRepoStub{}))
 	internalAuthMW := newInternalAuthMiddlewareStub()
-	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, internalAuthMW, "profile-service")
+	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, preferenceCtrl, attachmentCtrl, auditCtrl, newCmdV2Controller(profileSvc, contactSvc, addressSvc, companySvc), internalAuthMW, "profile-service", "profile-service:admin", nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	req.Header.Set("X-API-Key", "valid-key")
@@ -150,3 +265,56 @@ func TestSetupHTTPServerHealthRouteAuthorized(t *testing.T) {
 		t.Fatalf("expected %s header to be set", echo.HeaderXRequestID)
 	}
 }
+
+func TestSetupHTTPServerRestoreRequiresAdminAccess(t *testing.T) {
+	profileSvc := service.NewProfileService(cmdRepoStub{}, cmdUnitOfWorkStub{}, false, "", nil)
+	profileCtrl := controller.NewProfileController(profileSvc)
+	contactSvc := service.NewContactService(cmdContactRepoStub{}, cmdUnitOfWorkStub{}, "", nil)
+	contactCtrl := controller.NewContactController(contactSvc)
+	addressSvc := service.NewAddressService(cmdAddressRepoStub{}, cmdUnitOfWorkStub{}, nil, nil)
+	addressCtrl := controller.NewAddressController(addressSvc)
+	companySvc := service.NewCompanyService(cmdCompanyRepoStub{}, cmdUnitOfWorkStub{}, fiscal.NewValidators(""), nil)
+	companyCtrl := controller.NewCompanyController(companySvc)
+	preferenceCtrl := controller.NewPreferenceController(service.NewPreferenceService(cmdUnitOfWorkStub{}, nil))
+	attachmentCtrl := controller.NewAttachmentController(service.NewAttachmentService(cmdUnitOfWorkStub{}, nil, service.AttachmentLimits{}))
+	auditCtrl := controller.NewAuditController(service.NewAuditService(cmdAuditRepoStub{}))
+	internalAuthMW := newInternalAuthMiddlewareStub()
+	e := setupHTTPServer(profileCtrl, contactCtrl, addressCtrl, companyCtrl, preferenceCtrl, attachmentCtrl, auditCtrl, newCmdV2Controller(profileSvc, contactSvc, addressSvc, companySvc), internalAuthMW, "profile-service", "profile-service:admin", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/profiles/1/restore", nil)
+	req.Header.Set("X-API-Key", "valid-key")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "admin access required") {
+		t.Fatalf("unexpected payload: %s", rec.Body.String())
+	}
+	if got := rec.Header().Get(types.HeaderDeprecation); got != types.V1Deprecation() {
+		t.Fatalf("expected v1 deprecation header, got %q", got)
+	}
+	if got := rec.Header().Get(types.HeaderLink); got != `</v2/profiles/1/restore>; rel="successor-version"` {
+		t.Fatalf("unexpected link header: %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v2/profiles/1/restore", nil)
+	req.Header.Set("X-API-Key", "valid-key")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 from v2, got %d", rec.Code)
+	}
+	if rec.Header().Get(types.HeaderDeprecation) != "" {
+		t.Fatal("expected no deprecation header on v2")
+	}
+}
+
+func newCmdV2Controller(profileSvc *service.ProfileService, contactSvc *service.ContactService, addressSvc *service.AddressService, companySvc *service.CompanyService) *controller.V2Controller {
+	preferenceSvc := service.NewPreferenceService(cmdUnitOfWorkStub{}, nil)
+	auditSvc := service.NewAuditService(cmdAuditRepoStub{})
+	v1 := profilegrpc.NewProfileServer(profileSvc, contactSvc, addressSvc, companySvc, preferenceSvc, nil, auditSvc)
+	return controller.NewV2Controller(profilegrpc.NewProfileServerV2(v1))
+}
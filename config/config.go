@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -16,11 +17,24 @@ type Config struct {
 	MySQL             MySQLConfig
 	Log               LogConfig
 	InternalEndpoints InternalEndpointsConfig
+	Migrations        MigrationsConfig
+	Purge             PurgeConfig
+	Outbox            OutboxConfig
+	Encryption        EncryptionConfig
+	FieldPolicy       FieldPolicyConfig
+	Profile           ProfileConfig
+	Phone             PhoneConfig
+	Fiscal            FiscalConfig
+	Preferences       PreferencesConfig
+	Attributes        AttributesConfig
+	Attachments       AttachmentsConfig
 }
 
 type AppConfig struct {
 	ServiceName string
 	APIKey      string
+	// AdminAccess is the allowed-access grant that unlocks admin-only reads and restores.
+	AdminAccess string
 }
 
 type ServerConfig struct {
@@ -33,6 +47,7 @@ type MySQLConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	TxIsolation     string
 }
 
 type LogConfig struct {
@@ -43,6 +58,103 @@ type InternalEndpointsConfig struct {
 	AuthGRPCAddr string
 }
 
+type MigrationsConfig struct {
+	CheckOnStartup bool
+}
+
+// PurgeConfig controls the hard delete of soft-deleted rows. A zero Retention disables purging.
+type PurgeConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// OutboxConfig controls the relay that delivers outbox events to the configured publisher.
+type OutboxConfig struct {
+	RelayEnabled bool
+	Publisher    string
+	PollInterval time.Duration
+	BatchSize    int
+	MaxBackoff   time.Duration
+}
+
+// EncryptionConfig holds the keys for contact field encryption. Encryption is off while no
+// key is configured.
+type EncryptionConfig struct {
+	// Keys is a comma-separated list of id:base64key pairs.
+	Keys string
+	// KeysDir holds one base64 key per file, named after the key id.
+	KeysDir string
+	// ActiveKeyID encrypts new values; the other keys only decrypt.
+	ActiveKeyID       string
+	BlindIndexKey     string
+	BlindIndexKeyFile string
+	Fields            []string
+}
+
+// FieldPolicyConfig decides how much of the sensitive response fields each calling service
+// sees. See fieldpolicy.Parse for the rule syntax.
+type FieldPolicyConfig struct {
+	Default  string
+	Services string
+}
+
+// ProfileConfig holds the rules applied to profile records.
+type ProfileConfig struct {
+	// UniqueEmail rejects a profile whose email is already used by another live profile.
+	UniqueEmail bool
+}
+
+// PhoneConfig controls how contact phone numbers are parsed.
+type PhoneConfig struct {
+	// DefaultRegion is the ISO 3166-1 alpha-2 region of numbers written without a country
+	// calling code, used when the profile has no address to take the region from.
+	DefaultRegion string
+}
+
+// FiscalConfig controls how company fiscal codes are checked.
+type FiscalConfig struct {
+	// DefaultCountry is the EU VAT prefix of the country of fiscal codes written without one.
+	// Such codes are not checked when it is empty.
+	DefaultCountry string
+}
+
+// PreferencesConfig controls how profile preferences are checked.
+type PreferencesConfig struct {
+	// SchemaDir holds one JSON Schema per namespace, named <namespace>.json. Namespaces
+	// without a schema take any value.
+	SchemaDir string
+}
+
+// AttributesConfig controls how custom attributes are checked.
+type AttributesConfig struct {
+	// File is the JSON registry of attribute definitions. Without one, records take no
+	// attribute.
+	File string
+}
+
+// AttachmentsConfig controls where attachment content is kept and what may be uploaded.
+type AttachmentsConfig struct {
+	// Store is "local" or "s3".
+	Store    string
+	LocalDir string
+	S3       S3Config
+	// MaxSize is the largest attachment accepted, in bytes.
+	MaxSize      int64
+	ContentTypes []string
+}
+
+// S3Config addresses an S3-compatible bucket.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle puts the bucket in the URL path rather than the host name, as MinIO expects.
+	PathStyle bool
+}
+
 // Load reads configuration from environment variables (and .env when present).
 func Load() (*Config, error) {
 	_ = godotenv.Load()
@@ -56,6 +168,7 @@ func Load() (*Config, error) {
 		App: AppConfig{
 			ServiceName: getEnv("APP_SERVICE_NAME", "profile-service"),
 			APIKey:      getEnv("APP_API_KEY", ""),
+			AdminAccess: getEnv("APP_ADMIN_ACCESS", "profile-service:admin"),
 		},
 		HTTP: ServerConfig{
 			Host: getEnv("HTTP_HOST", "0.0.0.0"),
@@ -70,6 +183,7 @@ func Load() (*Config, error) {
 			MaxOpenConns:    getIntEnv("MYSQL_MAX_OPEN_CONNS", 10),
 			MaxIdleConns:    getIntEnv("MYSQL_MAX_IDLE_CONNS", 5),
 			ConnMaxLifetime: getDurationEnv("MYSQL_CONN_MAX_LIFETIME_MINUTES", 30*time.Minute),
+			TxIsolation:     getEnv("MYSQL_TX_ISOLATION", ""),
 		},
 		Log: LogConfig{
 			Level: getEnv("LOG_LEVEL", "info"),
@@ -77,6 +191,62 @@ func Load() (*Config, error) {
 		InternalEndpoints: InternalEndpointsConfig{
 			AuthGRPCAddr: getEnv("AUTH_SERVICE_GRPC_ADDR", "localhost:9090"),
 		},
+		Migrations: MigrationsConfig{
+			CheckOnStartup: getBoolEnv("MIGRATIONS_CHECK_ON_STARTUP", false),
+		},
+		Purge: PurgeConfig{
+			Retention: time.Duration(getIntEnv("PURGE_RETENTION_DAYS", 0)) * 24 * time.Hour,
+			Interval:  getDurationEnv("PURGE_INTERVAL_MINUTES", 60*time.Minute),
+			BatchSize: getIntEnv("PURGE_BATCH_SIZE", 500),
+		},
+		Outbox: OutboxConfig{
+			RelayEnabled: getBoolEnv("OUTBOX_RELAY_ENABLED", true),
+			Publisher:    getEnv("OUTBOX_PUBLISHER", "log"),
+			PollInterval: time.Duration(getIntEnv("OUTBOX_POLL_INTERVAL_SECONDS", 1)) * time.Second,
+			BatchSize:    getIntEnv("OUTBOX_BATCH_SIZE", 100),
+			MaxBackoff:   time.Duration(getIntEnv("OUTBOX_MAX_BACKOFF_SECONDS", 300)) * time.Second,
+		},
+		Encryption: EncryptionConfig{
+			Keys:              getEnv("ENCRYPTION_KEYS", ""),
+			KeysDir:           getEnv("ENCRYPTION_KEYS_DIR", ""),
+			ActiveKeyID:       getEnv("ENCRYPTION_ACTIVE_KEY_ID", ""),
+			BlindIndexKey:     getEnv("ENCRYPTION_BLIND_INDEX_KEY", ""),
+			BlindIndexKeyFile: getEnv("ENCRYPTION_BLIND_INDEX_KEY_FILE", ""),
+			Fields:            getListEnv("ENCRYPTION_FIELDS", []string{"nin"}),
+		},
+		FieldPolicy: FieldPolicyConfig{
+			Default:  getEnv("FIELD_POLICY_DEFAULT", "*:full"),
+			Services: getEnv("FIELD_POLICY_SERVICES", ""),
+		},
+		Profile: ProfileConfig{
+			UniqueEmail: getBoolEnv("PROFILE_UNIQUE_EMAIL", false),
+		},
+		Phone: PhoneConfig{
+			DefaultRegion: strings.ToUpper(getEnv("PHONE_DEFAULT_REGION", "")),
+		},
+		Fiscal: FiscalConfig{
+			DefaultCountry: strings.ToUpper(getEnv("FISCAL_DEFAULT_COUNTRY", "")),
+		},
+		Preferences: PreferencesConfig{
+			SchemaDir: getEnv("PREFERENCES_SCHEMA_DIR", ""),
+		},
+		Attributes: AttributesConfig{
+			File: getEnv("ATTRIBUTES_FILE", ""),
+		},
+		Attachments: AttachmentsConfig{
+			Store:    strings.ToLower(getEnv("ATTACHMENTS_STORE", "local")),
+			LocalDir: getEnv("ATTACHMENTS_LOCAL_DIR", "attachments"),
+			S3: S3Config{
+				Endpoint:        getEnv("ATTACHMENTS_S3_ENDPOINT", "https://s3.amazonaws.com"),
+				Region:          getEnv("ATTACHMENTS_S3_REGION", "us-east-1"),
+				Bucket:          getEnv("ATTACHMENTS_S3_BUCKET", ""),
+				AccessKeyID:     getEnv("ATTACHMENTS_S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("ATTACHMENTS_S3_SECRET_ACCESS_KEY", ""),
+				PathStyle:       getBoolEnv("ATTACHMENTS_S3_PATH_STYLE", false),
+			},
+			MaxSize:      int64(getIntEnv("ATTACHMENTS_MAX_SIZE_MB", 10)) << 20,
+			ContentTypes: getListEnv("ATTACHMENTS_CONTENT_TYPES", []string{"image/jpeg", "image/png", "image/webp", "application/pdf"}),
+		},
 	}, nil
 }
 
@@ -98,6 +268,32 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getListEnv returns the comma-separated env value or the default if empty.
+func getListEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getBoolEnv returns the bool env value or the default if empty/invalid.
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 // getDurationEnv returns a minutes-based duration from env or the default.
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
@@ -23,10 +23,32 @@ func TestLoadDefaults(t *testing.T) {
 	t.Setenv("MYSQL_MAX_OPEN_CONNS", "")
 	t.Setenv("MYSQL_MAX_IDLE_CONNS", "")
 	t.Setenv("MYSQL_CONN_MAX_LIFETIME_MINUTES", "")
+	t.Setenv("MYSQL_TX_ISOLATION", "")
 	t.Setenv("LOG_LEVEL", "")
 	t.Setenv("AUTH_SERVICE_GRPC_ADDR", "")
 	t.Setenv("APP_SERVICE_NAME", "")
 	t.Setenv("APP_API_KEY", "")
+	t.Setenv("MIGRATIONS_CHECK_ON_STARTUP", "")
+	t.Setenv("APP_ADMIN_ACCESS", "")
+	t.Setenv("PURGE_RETENTION_DAYS", "")
+	t.Setenv("PURGE_INTERVAL_MINUTES", "")
+	t.Setenv("PURGE_BATCH_SIZE", "")
+	t.Setenv("ENCRYPTION_KEYS", "")
+	t.Setenv("ENCRYPTION_FIELDS", "")
+	t.Setenv("FIELD_POLICY_DEFAULT", "")
+	t.Setenv("FIELD_POLICY_SERVICES", "")
+	t.Setenv("PROFILE_UNIQUE_EMAIL", "")
+	t.Setenv("PHONE_DEFAULT_REGION", "")
+	t.Setenv("FISCAL_DEFAULT_COUNTRY", "")
+	t.Setenv("PREFERENCES_SCHEMA_DIR", "")
+	t.Setenv("ATTRIBUTES_FILE", "")
+	t.Setenv("ATTACHMENTS_STORE", "")
+	t.Setenv("ATTACHMENTS_LOCAL_DIR", "")
+	t.Setenv("ATTACHMENTS_S3_ENDPOINT", "")
+	t.Setenv("ATTACHMENTS_S3_REGION", "")
+	t.Setenv("ATTACHMENTS_S3_PATH_STYLE", "")
+	t.Setenv("ATTACHMENTS_MAX_SIZE_MB", "")
+	t.Setenv("ATTACHMENTS_CONTENT_TYPES", "")
 
 	cfg, err := Load()
 	if err != nil {
@@ -45,6 +67,9 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.MySQL.ConnMaxLifetime != 30*time.Minute {
 		t.Fatalf("unexpected MySQL max life default: %v", cfg.MySQL.ConnMaxLifetime)
 	}
+	if cfg.MySQL.TxIsolation != "" {
+		t.Fatalf("expected MYSQL_TX_ISOLATION default empty, got %q", cfg.MySQL.TxIsolation)
+	}
 	if cfg.Log.Level != "info" {
 		t.Fatalf("expected LOG_LEVEL default 'info', got %q", cfg.Log.Level)
 	}
@@ -57,6 +82,49 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.App.APIKey != "" {
 		t.Fatalf("expected APP_API_KEY default empty, got %q", cfg.App.APIKey)
 	}
+	if cfg.Migrations.CheckOnStartup {
+		t.Fatal("expected MIGRATIONS_CHECK_ON_STARTUP default false")
+	}
+	if cfg.App.AdminAccess != "profile-service:admin" {
+		t.Fatalf("expected APP_ADMIN_ACCESS default, got %q", cfg.App.AdminAccess)
+	}
+	if cfg.Purge.Retention != 0 || cfg.Purge.Interval != time.Hour || cfg.Purge.BatchSize != 500 {
+		t.Fatalf("unexpected purge defaults: %+v", cfg.Purge)
+	}
+	if !cfg.Outbox.RelayEnabled || cfg.Outbox.Publisher != "log" || cfg.Outbox.PollInterval != time.Second ||
+		cfg.Outbox.BatchSize != 100 || cfg.Outbox.MaxBackoff != 5*time.Minute {
+		t.Fatalf("unexpected outbox defaults: %+v", cfg.Outbox)
+	}
+	if cfg.Encryption.Keys != "" || len(cfg.Encryption.Fields) != 1 || cfg.Encryption.Fields[0] != "nin" {
+		t.Fatalf("unexpected encryption defaults: %+v", cfg.Encryption)
+	}
+	if cfg.FieldPolicy.Default != "*:full" || cfg.FieldPolicy.Services != "" {
+		t.Fatalf("unexpected field policy defaults: %+v", cfg.FieldPolicy)
+	}
+	if cfg.Profile.UniqueEmail {
+		t.Fatal("expected PROFILE_UNIQUE_EMAIL default false")
+	}
+	if cfg.Phone.DefaultRegion != "" {
+		t.Fatalf("expected PHONE_DEFAULT_REGION default empty, got %q", cfg.Phone.DefaultRegion)
+	}
+	if cfg.Fiscal.DefaultCountry != "" {
+		t.Fatalf("expected FISCAL_DEFAULT_COUNTRY default empty, got %q", cfg.Fiscal.DefaultCountry)
+	}
+	if cfg.Preferences.SchemaDir != "" {
+		t.Fatalf("expected PREFERENCES_SCHEMA_DIR default empty, got %q", cfg.Preferences.SchemaDir)
+	}
+	if cfg.Attributes.File != "" {
+		t.Fatalf("expected ATTRIBUTES_FILE default empty, got %q", cfg.Attributes.File)
+	}
+	if cfg.Attachments.Store != "local" || cfg.Attachments.LocalDir != "attachments" || cfg.Attachments.MaxSize != 10<<20 {
+		t.Fatalf("unexpected attachments defaults: %+v", cfg.Attachments)
+	}
+	if cfg.Attachments.S3.Endpoint != "https://s3.amazonaws.com" || cfg.Attachments.S3.Region != "us-east-1" || cfg.Attachments.S3.PathStyle {
+		t.Fatalf("unexpected S3 defaults: %+v", cfg.Attachments.S3)
+	}
+	if len(cfg.Attachments.ContentTypes) != 4 || cfg.Attachments.ContentTypes[3] != "application/pdf" {
+		t.Fatalf("unexpected ATTACHMENTS_CONTENT_TYPES default: %v", cfg.Attachments.ContentTypes)
+	}
 }
 
 func TestLoadCustomValues(t *testing.T) {
@@ -68,10 +136,39 @@ func TestLoadCustomValues(t *testing.T) {
 	t.Setenv("MYSQL_MAX_OPEN_CONNS", "42")
 	t.Setenv("MYSQL_MAX_IDLE_CONNS", "12")
 	t.Setenv("MYSQL_CONN_MAX_LIFETIME_MINUTES", "17")
+	t.Setenv("MYSQL_TX_ISOLATION", "read-committed")
 	t.Setenv("LOG_LEVEL", "debug")
 	t.Setenv("AUTH_SERVICE_GRPC_ADDR", "auth:9090")
 	t.Setenv("APP_SERVICE_NAME", "profile-service")
 	t.Setenv("APP_API_KEY", "profile-key")
+	t.Setenv("MIGRATIONS_CHECK_ON_STARTUP", "true")
+	t.Setenv("APP_ADMIN_ACCESS", "profile-admin")
+	t.Setenv("PURGE_RETENTION_DAYS", "30")
+	t.Setenv("PURGE_INTERVAL_MINUTES", "15")
+	t.Setenv("PURGE_BATCH_SIZE", "100")
+	t.Setenv("OUTBOX_RELAY_ENABLED", "false")
+	t.Setenv("OUTBOX_POLL_INTERVAL_SECONDS", "5")
+	t.Setenv("OUTBOX_BATCH_SIZE", "50")
+	t.Setenv("OUTBOX_MAX_BACKOFF_SECONDS", "60")
+	t.Setenv("ENCRYPTION_KEYS", "k1:a2V5")
+	t.Setenv("ENCRYPTION_ACTIVE_KEY_ID", "k1")
+	t.Setenv("ENCRYPTION_FIELDS", "nin, phone,,dob")
+	t.Setenv("FIELD_POLICY_DEFAULT", "*:masked")
+	t.Setenv("FIELD_POLICY_SERVICES", "billing-service=contact.nin:full")
+	t.Setenv("PROFILE_UNIQUE_EMAIL", "true")
+	t.Setenv("PHONE_DEFAULT_REGION", "ro")
+	t.Setenv("FISCAL_DEFAULT_COUNTRY", "ro")
+	t.Setenv("PREFERENCES_SCHEMA_DIR", "/etc/profile/preferences")
+	t.Setenv("ATTRIBUTES_FILE", "/etc/profile/attributes.json")
+	t.Setenv("ATTACHMENTS_STORE", "S3")
+	t.Setenv("ATTACHMENTS_S3_ENDPOINT", "http://minio:9000")
+	t.Setenv("ATTACHMENTS_S3_REGION", "eu-central-1")
+	t.Setenv("ATTACHMENTS_S3_BUCKET", "profile-attachments")
+	t.Setenv("ATTACHMENTS_S3_ACCESS_KEY_ID", "access")
+	t.Setenv("ATTACHMENTS_S3_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("ATTACHMENTS_S3_PATH_STYLE", "true")
+	t.Setenv("ATTACHMENTS_MAX_SIZE_MB", "2")
+	t.Setenv("ATTACHMENTS_CONTENT_TYPES", "image/*, application/pdf")
 
 	cfg, err := Load()
 	if err != nil {
@@ -90,6 +187,9 @@ func TestLoadCustomValues(t *testing.T) {
 	if cfg.MySQL.ConnMaxLifetime != 17*time.Minute {
 		t.Fatalf("unexpected MySQL max life: %v", cfg.MySQL.ConnMaxLifetime)
 	}
+	if cfg.MySQL.TxIsolation != "read-committed" {
+		t.Fatalf("unexpected MYSQL_TX_ISOLATION: %q", cfg.MySQL.TxIsolation)
+	}
 	if cfg.Log.Level != "debug" {
 		t.Fatalf("unexpected LOG_LEVEL: %q", cfg.Log.Level)
 	}
@@ -102,6 +202,54 @@ func TestLoadCustomValues(t *testing.T) {
 	if cfg.App.APIKey != "profile-key" {
 		t.Fatalf("unexpected APP_API_KEY: %q", cfg.App.APIKey)
 	}
+	if !cfg.Migrations.CheckOnStartup {
+		t.Fatal("expected MIGRATIONS_CHECK_ON_STARTUP to be true")
+	}
+	if cfg.App.AdminAccess != "profile-admin" {
+		t.Fatalf("unexpected APP_ADMIN_ACCESS: %q", cfg.App.AdminAccess)
+	}
+	if cfg.Purge.Retention != 30*24*time.Hour || cfg.Purge.Interval != 15*time.Minute || cfg.Purge.BatchSize != 100 {
+		t.Fatalf("unexpected purge config: %+v", cfg.Purge)
+	}
+	if cfg.Outbox.RelayEnabled || cfg.Outbox.PollInterval != 5*time.Second || cfg.Outbox.BatchSize != 50 || cfg.Outbox.MaxBackoff != time.Minute {
+		t.Fatalf("unexpected outbox config: %+v", cfg.Outbox)
+	}
+	if cfg.Encryption.Keys != "k1:a2V5" || cfg.Encryption.ActiveKeyID != "k1" ||
+		len(cfg.Encryption.Fields) != 3 || cfg.Encryption.Fields[1] != "phone" || cfg.Encryption.Fields[2] != "dob" {
+		t.Fatalf("unexpected encryption config: %+v", cfg.Encryption)
+	}
+	if cfg.FieldPolicy.Default != "*:masked" || cfg.FieldPolicy.Services != "billing-service=contact.nin:full" {
+		t.Fatalf("unexpected field policy config: %+v", cfg.FieldPolicy)
+	}
+	if !cfg.Profile.UniqueEmail {
+		t.Fatal("expected PROFILE_UNIQUE_EMAIL true")
+	}
+	if cfg.Phone.DefaultRegion != "RO" {
+		t.Fatalf("expected PHONE_DEFAULT_REGION RO, got %q", cfg.Phone.DefaultRegion)
+	}
+	if cfg.Fiscal.DefaultCountry != "RO" {
+		t.Fatalf("expected FISCAL_DEFAULT_COUNTRY RO, got %q", cfg.Fiscal.DefaultCountry)
+	}
+	if cfg.Preferences.SchemaDir != "/etc/profile/preferences" {
+		t.Fatalf("expected PREFERENCES_SCHEMA_DIR /etc/profile/preferences, got %q", cfg.Preferences.SchemaDir)
+	}
+	if cfg.Attributes.File != "/etc/profile/attributes.json" {
+		t.Fatalf("expected ATTRIBUTES_FILE /etc/profile/attributes.json, got %q", cfg.Attributes.File)
+	}
+	if cfg.Attachments.Store != "s3" || cfg.Attachments.MaxSize != 2<<20 ||
+		len(cfg.Attachments.ContentTypes) != 2 || cfg.Attachments.ContentTypes[0] != "image/*" {
+		t.Fatalf("unexpected attachments config: %+v", cfg.Attachments)
+	}
+	if cfg.Attachments.S3 != (S3Config{
+		Endpoint:        "http://minio:9000",
+		Region:          "eu-central-1",
+		Bucket:          "profile-attachments",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	}) {
+		t.Fatalf("unexpected S3 config: %+v", cfg.Attachments.S3)
+	}
 }
 
 func TestGetIntAndDurationFallback(t *testing.T) {
@@ -114,4 +262,9 @@ func TestGetIntAndDurationFallback(t *testing.T) {
 	if got := getDurationEnv("BROKEN_MIN", 3*time.Minute); got != 3*time.Minute {
 		t.Fatalf("expected fallback duration 3m, got %v", got)
 	}
+
+	t.Setenv("BROKEN_BOOL", "maybe")
+	if got := getBoolEnv("BROKEN_BOOL", true); !got {
+		t.Fatal("expected fallback bool true")
+	}
 }
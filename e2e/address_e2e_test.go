@@ -111,8 +111,8 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 			"street_name":     "Street",
 			"streen_no":       "10",
 			"city":            "City",
-			"county":          "County",
-			"country":         "Country",
+			"county":          "Cluj",
+			"country":         "RO",
 			"profile_id":      1,
 			"additional_data": string(long),
 		})
@@ -142,9 +142,34 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 		if err := json.Unmarshal(body, &created); err != nil {
 			t.Fatalf("unmarshal create address failed: %v body=%s", err, string(body))
 		}
+		if created.GetCountry() != "GB" {
+			t.Fatalf("expected country normalized to GB, got %q", created.GetCountry())
+		}
 		state.addressHTTPID = created.GetId()
 	})
 
+	t.Run("HTTPCreateInvalidPostalCodeNamesField", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/addresses", map[string]any{
+			"street_name": "Main Street",
+			"streen_no":   "10",
+			"city":        "Cluj-Napoca",
+			"county":      "Cluj",
+			"country":     "Romania",
+			"postal_code": "40000",
+			"profile_id":  state.profileAID,
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("unmarshal error response failed: %v body=%s", err, string(body))
+		}
+		if payload["field"] != "postal_code" {
+			t.Fatalf("expected postal_code field error, got %s", string(body))
+		}
+	})
+
 	t.Run("GRPCCreateAddress", func(t *testing.T) {
 		created, err := grpcClient.CreateAddress(context.Background(), &types.CreateAddressRequest{
 			StreetName:     "Second Street",
@@ -162,6 +187,9 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 		if err != nil {
 			t.Fatalf("grpc create address failed: %v", err)
 		}
+		if created.GetCountry() != "FR" {
+			t.Fatalf("expected country normalized to FR, got %q", created.GetCountry())
+		}
 		state.addressGRPCID = created.GetId()
 	})
 
@@ -177,6 +205,21 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPGetLabel", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodGet,
+			"/addresses/"+strconv.FormatUint(state.addressGRPCID, 10)+"?format=label",
+			nil,
+		)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		if want := "20 Second Street\nBât. B, Appt 12\n75000 PARIS\nFRANCE\n"; string(body) != want {
+			t.Fatalf("expected label %q, got %q", want, string(body))
+		}
+	})
+
 	t.Run("GRPCGetByIDAfterHTTPCreate", func(t *testing.T) {
 		got, err := grpcClient.GetAddress(context.Background(), &types.GetAddressRequest{
 			Id: state.addressHTTPID,
@@ -187,6 +230,9 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 		if got.GetStreetName() != "Main Street" {
 			t.Fatalf("unexpected street_name: %+v", got)
 		}
+		if got.GetFormatted().GetSingleLine() != "Main Street 10, London, UNITED KINGDOM" {
+			t.Fatalf("unexpected formatted address: %+v", got.GetFormatted())
+		}
 	})
 
 	t.Run("HTTPListValidationMissingProfileID", func(t *testing.T) {
@@ -394,6 +440,68 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPCreateMissingProfileNotFound", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/addresses", map[string]any{
+			"street_name": "Nowhere Street",
+			"streen_no":   "1",
+			"city":        "Madrid",
+			"county":      "Madrid",
+			"country":     "Spain",
+			"profile_id":  missingProfileID,
+		})
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCCreateMissingProfileNotFound", func(t *testing.T) {
+		_, err := grpcClient.CreateAddress(context.Background(), &types.CreateAddressRequest{
+			StreetName: "Nowhere Street",
+			StreenNo:   "1",
+			City:       "Madrid",
+			County:     "Madrid",
+			Country:    "Spain",
+			ProfileId:  missingProfileID,
+		})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected NotFound, got %v", err)
+		}
+	})
+
+	t.Run("HTTPUpdateMissingTargetProfileUnprocessable", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodPut,
+			"/addresses/"+strconv.FormatUint(state.addressGRPCID, 10),
+			map[string]any{
+				"street_name": "Nowhere Street",
+				"streen_no":   "1",
+				"city":        "Madrid",
+				"county":      "Madrid",
+				"country":     "Spain",
+				"profile_id":  missingProfileID,
+			},
+		)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCUpdateMissingTargetProfileFailedPrecondition", func(t *testing.T) {
+		_, err := grpcClient.UpdateAddress(context.Background(), &types.UpdateAddressRequest{
+			Id:         state.addressGRPCID,
+			StreetName: "Nowhere Street",
+			StreenNo:   "1",
+			City:       "Madrid",
+			County:     "Madrid",
+			Country:    "Spain",
+			ProfileId:  missingProfileID,
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("expected FailedPrecondition, got %v", err)
+		}
+	})
+
 	t.Run("GRPCGetAfterHTTPUpdate", func(t *testing.T) {
 		got, err := grpcClient.GetAddress(context.Background(), &types.GetAddressRequest{
 			Id: state.addressGRPCID,
@@ -406,6 +514,41 @@ func TestAddressE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPPatchKeepsOmittedFields", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodPatch,
+			"/addresses/"+strconv.FormatUint(state.addressGRPCID, 10),
+			map[string]any{
+				"apartment":       "8",
+				"additional_data": nil,
+			},
+		)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		var patched types.AddressResponse
+		if err := json.Unmarshal(body, &patched); err != nil {
+			t.Fatalf("unmarshal patch address failed: %v body=%s", err, string(body))
+		}
+		if patched.GetApartment() != "8" || patched.GetAdditionalData() != "" || patched.GetStreetName() != "Updated Street" {
+			t.Fatalf("unexpected patched payload: %+v", &patched)
+		}
+	})
+
+	t.Run("HTTPPatchCannotClearMandatoryField", func(t *testing.T) {
+		resp, _ := httpClient.doJSON(
+			t,
+			http.MethodPatch,
+			"/addresses/"+strconv.FormatUint(state.addressGRPCID, 10),
+			map[string]any{"city": nil},
+		)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", resp.StatusCode)
+		}
+	})
+
 	t.Run("GRPCDelete", func(t *testing.T) {
 		_, err := grpcClient.DeleteAddress(context.Background(), &types.DeleteAddressRequest{
 			Id: state.addressGRPCID,
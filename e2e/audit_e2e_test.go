@@ -0,0 +1,120 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuditE2E_MutationsAreRecorded(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	rawConn := dialProfileGRPCRaw(t, grpcAddr)
+	defer rawConn.Close()
+	rawGRPCClient := types.NewProfileServiceClient(rawConn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 4_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("audit-%d@example.com", time.Now().UnixNano()),
+	})
+	if err != nil {
+		t.Fatalf("grpc create profile failed: %v", err)
+	}
+	profileID := profile.GetId()
+	auditPath := "/audit?entity=profile&id=" + strconv.FormatUint(profileID, 10)
+
+	resp, body := httpClient.doJSONWithHeaders(t, http.MethodPatch, "/profiles/"+strconv.FormatUint(profileID, 10), map[string]any{
+		"email": fmt.Sprintf("audit-updated-%d@example.com", time.Now().UnixNano()),
+	}, profileCallerAPIKey(), map[string]string{"X-Request-ID": "audit-e2e-patch"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 on patch, got %d body=%s", resp.StatusCode, string(body))
+	}
+
+	t.Run("HTTPListsEventsNewestFirst", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, auditPath, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got types.ListAuditEventsResponse
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal audit response failed: %v body=%s", err, string(body))
+		}
+		if got.GetTotal() != 2 || len(got.GetEvents()) != 2 {
+			t.Fatalf("expected create and update events, got %s", string(body))
+		}
+
+		update, create := got.GetEvents()[0], got.GetEvents()[1]
+		if update.GetAction() != "update" || update.GetRequestId() != "audit-e2e-patch" || update.GetCallerService() != "profile-gateway" {
+			t.Fatalf("unexpected update event: %+v", update)
+		}
+		if update.GetOldValues() == "" || update.GetNewValues() == "" {
+			t.Fatalf("expected old and new values on update: %+v", update)
+		}
+		if create.GetAction() != "create" || create.GetOldValues() != "" {
+			t.Fatalf("unexpected create event: %+v", create)
+		}
+	})
+
+	t.Run("HTTPRequiresAdmin", func(t *testing.T) {
+		resp, _ := httpClient.doJSONWithAPIKey(t, http.MethodGet, auditPath, nil, profileReaderAPIKey())
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for non-admin caller, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("GRPCListsDeleteEvent", func(t *testing.T) {
+		if _, err := grpcClient.DeleteProfile(context.Background(), &types.DeleteProfileRequest{Id: profileID}); err != nil {
+			t.Fatalf("grpc delete profile failed: %v", err)
+		}
+
+		got, err := grpcClient.ListAuditEvents(context.Background(), &types.ListAuditEventsRequest{Entity: "profile", EntityId: profileID, PageSize: 1})
+		if err != nil {
+			t.Fatalf("grpc list audit events failed: %v", err)
+		}
+		if got.GetTotal() != 3 || len(got.GetEvents()) != 1 {
+			t.Fatalf("unexpected audit page: %+v", got)
+		}
+		if event := got.GetEvents()[0]; event.GetAction() != "delete" || event.GetNewValues() != "" || event.GetRequestId() == "" {
+			t.Fatalf("unexpected delete event: %+v", event)
+		}
+	})
+
+	t.Run("GRPCRequiresAdmin", func(t *testing.T) {
+		_, err := rawGRPCClient.ListAuditEvents(grpcContextWithAPIKey(profileReaderAPIKey()), &types.ListAuditEventsRequest{Entity: "profile", EntityId: profileID})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected PermissionDenied, got %v", err)
+		}
+	})
+}
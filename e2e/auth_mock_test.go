@@ -21,6 +21,7 @@ import (
 const (
 	defaultProfileCallerAPIKey   = "profile-caller-key"
 	defaultProfileNoAccessAPIKey = "profile-no-access-key"
+	defaultProfileReaderAPIKey   = "profile-reader-key"
 	defaultProfileAppAPIKey      = "profile-app-api-key"
 	profileAuthMockAddr          = "0.0.0.0:38081"
 )
@@ -39,6 +40,14 @@ func profileNoAccessAPIKey() string {
 	return defaultProfileNoAccessAPIKey
 }
 
+// profileReaderAPIKey may call the service but lacks the admin grant.
+func profileReaderAPIKey() string {
+	if value := strings.TrimSpace(os.Getenv("PROFILE_READER_API_KEY")); value != "" {
+		return value
+	}
+	return defaultProfileReaderAPIKey
+}
+
 func profileAppAPIKey() string {
 	if value := strings.TrimSpace(os.Getenv("PROFILE_APP_API_KEY")); value != "" {
 		return value
@@ -60,7 +69,12 @@ func (s *profileAuthGRPCServer) ValidateInternalAccess(ctx context.Context, req
 	case profileCallerAPIKey():
 		return &authpb.ValidateInternalAccessResponse{
 			ServiceName:   "profile-gateway",
-			AllowedAccess: []string{"profile-service", "notifications-service"},
+			AllowedAccess: []string{"profile-service", "profile-service:admin", "notifications-service"},
+		}, nil
+	case profileReaderAPIKey():
+		return &authpb.ValidateInternalAccessResponse{
+			ServiceName:   "profile-reader",
+			AllowedAccess: []string{"profile-service"},
 		}, nil
 	case profileNoAccessAPIKey():
 		return &authpb.ValidateInternalAccessResponse{
@@ -16,6 +16,7 @@ import (
 	"github.com/vibast-solutions/ms-go-profile/app/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
@@ -110,8 +111,8 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 	t.Run("HTTPCreateCompany", func(t *testing.T) {
 		resp, body := httpClient.doJSON(t, http.MethodPost, "/companies", map[string]any{
 			"name":            "Acme SRL",
-			"registration_no": "REG-100",
-			"fiscal_code":     "FISC-100",
+			"registration_no": "J40/100/2020",
+			"fiscal_code":     "RO10000105",
 			"profile_id":      state.profileAID,
 		})
 		if resp.StatusCode != http.StatusCreated {
@@ -122,13 +123,35 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 			t.Fatalf("unmarshal create company failed: %v body=%s", err, string(body))
 		}
 		state.companyHTTPID = created.GetId()
+		if !created.GetFiscalCodeValid() || created.GetVatPayerPrefix() != "RO" {
+			t.Fatalf("expected a valid RO fiscal code, got %+v", &created)
+		}
+	})
+
+	t.Run("HTTPCreateInvalidFiscalCodeBadRequest", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/companies", map[string]any{
+			"name":            "Typo SRL",
+			"registration_no": "J40/100/2020",
+			"fiscal_code":     "RO10000106",
+			"profile_id":      state.profileAID,
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("unmarshal error failed: %v body=%s", err, string(body))
+		}
+		if payload["field"] != "fiscal_code" {
+			t.Fatalf("expected fiscal_code field error, got %s", string(body))
+		}
 	})
 
 	t.Run("GRPCCreateCompany", func(t *testing.T) {
 		created, err := grpcClient.CreateCompany(context.Background(), &types.CreateCompanyRequest{
 			Name:           "Globex LLC",
-			RegistrationNo: "REG-200",
-			FiscalCode:     "FISC-200",
+			RegistrationNo: "J12/200/2019",
+			FiscalCode:     "RO10000202",
 			ProfileId:      state.profileAID,
 			Type:           "vendor",
 		})
@@ -138,6 +161,18 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 		state.companyGRPCID = created.GetId()
 	})
 
+	t.Run("GRPCCreateInvalidRegistrationNo", func(t *testing.T) {
+		_, err := grpcClient.CreateCompany(context.Background(), &types.CreateCompanyRequest{
+			Name:           "Typo LLC",
+			RegistrationNo: "J77/200/2019",
+			FiscalCode:     "RO10000202",
+			ProfileId:      state.profileAID,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected InvalidArgument, got %v", err)
+		}
+	})
+
 	t.Run("HTTPGetByIDAfterGRPCCreate", func(t *testing.T) {
 		resp, body := httpClient.doJSON(
 			t,
@@ -243,8 +278,8 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 	t.Run("HTTPCreateCompanySecondProfile", func(t *testing.T) {
 		resp, body := httpClient.doJSON(t, http.MethodPost, "/companies", map[string]any{
 			"name":            "Secondary SA",
-			"registration_no": "REG-B",
-			"fiscal_code":     "FISC-B",
+			"registration_no": "J40/300/2021",
+			"fiscal_code":     "RO10000300",
 			"profile_id":      state.profileBID,
 		})
 		if resp.StatusCode != http.StatusCreated {
@@ -288,8 +323,8 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 			"/companies/"+strconv.FormatUint(state.companyGRPCID, 10),
 			map[string]any{
 				"name":            "Globex Updated",
-				"registration_no": "REG-200-U",
-				"fiscal_code":     "FISC-200-U",
+				"registration_no": "J12/201/2019",
+				"fiscal_code":     "RO10000407",
 				"profile_id":      state.profileAID,
 				"type":            "partner",
 			},
@@ -299,6 +334,60 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPCreateMissingProfileNotFound", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/companies", map[string]any{
+			"name":            "Ghost SRL",
+			"registration_no": "J40/404/2020",
+			"fiscal_code":     "RO10000504",
+			"profile_id":      missingProfileID,
+		})
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCCreateMissingProfileNotFound", func(t *testing.T) {
+		_, err := grpcClient.CreateCompany(context.Background(), &types.CreateCompanyRequest{
+			Name:           "Ghost SRL",
+			RegistrationNo: "J40/404/2020",
+			FiscalCode:     "RO10000504",
+			ProfileId:      missingProfileID,
+		})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected NotFound, got %v", err)
+		}
+	})
+
+	t.Run("HTTPUpdateMissingTargetProfileUnprocessable", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodPut,
+			"/companies/"+strconv.FormatUint(state.companyGRPCID, 10),
+			map[string]any{
+				"name":            "Ghost SRL",
+				"registration_no": "J40/404/2020",
+				"fiscal_code":     "RO10000504",
+				"profile_id":      missingProfileID,
+			},
+		)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCUpdateMissingTargetProfileFailedPrecondition", func(t *testing.T) {
+		_, err := grpcClient.UpdateCompany(context.Background(), &types.UpdateCompanyRequest{
+			Id:             state.companyGRPCID,
+			Name:           "Ghost SRL",
+			RegistrationNo: "J40/404/2020",
+			FiscalCode:     "RO10000504",
+			ProfileId:      missingProfileID,
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("expected FailedPrecondition, got %v", err)
+		}
+	})
+
 	t.Run("GRPCGetAfterHTTPUpdate", func(t *testing.T) {
 		got, err := grpcClient.GetCompany(context.Background(), &types.GetCompanyRequest{
 			Id: state.companyGRPCID,
@@ -311,6 +400,20 @@ func TestCompanyE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("GRPCPatchWithFieldMask", func(t *testing.T) {
+		patched, err := grpcClient.PatchCompany(context.Background(), &types.PatchCompanyRequest{
+			Id:         state.companyGRPCID,
+			Type:       "supplier",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"type"}},
+		})
+		if err != nil {
+			t.Fatalf("grpc patch company failed: %v", err)
+		}
+		if patched.GetType() != "supplier" || patched.GetName() != "Globex Updated" || patched.GetFiscalCode() != "RO10000407" {
+			t.Fatalf("unexpected grpc patched company: %+v", patched)
+		}
+	})
+
 	t.Run("GRPCDeleteThenHTTPNotFound", func(t *testing.T) {
 		_, err := grpcClient.DeleteCompany(context.Background(), &types.DeleteCompanyRequest{
 			Id: state.companyHTTPID,
@@ -0,0 +1,167 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestCompanyMemberE2E_SharedCompany(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+	v2Client := typesv2.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 12_000_000
+	profiles := make([]*types.ProfileResponse, 0, 2)
+	for i := uint64(0); i < 2; i++ {
+		profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+			UserId: userID + i,
+			Email:  fmt.Sprintf("member-e2e-%d@example.com", userID+i),
+		})
+		if err != nil {
+			t.Fatalf("create profile failed: %v", err)
+		}
+		profiles = append(profiles, profile)
+	}
+	owner, accountant := profiles[0].GetId(), profiles[1].GetId()
+
+	company, err := grpcClient.CreateCompany(context.Background(), &types.CreateCompanyRequest{
+		Name:           "Shared SRL",
+		RegistrationNo: "J40/100/2020",
+		FiscalCode:     "RO10000105",
+		ProfileId:      owner,
+	})
+	if err != nil {
+		t.Fatalf("create company failed: %v", err)
+	}
+	membersPath := fmt.Sprintf("/companies/%d/members", company.GetId())
+
+	t.Run("HTTPAddMember", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPut, fmt.Sprintf("%s/%d", membersPath, accountant), map[string]any{"role": "accountant"})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		resp, body = httpClient.doJSON(t, http.MethodPut, fmt.Sprintf("%s/%d", membersPath, accountant), map[string]any{"role": "auditor"})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for an unknown role, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("HTTPListMembers", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, membersPath, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var list types.ListCompanyMembersResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			t.Fatalf("unmarshal members failed: %v body=%s", err, string(body))
+		}
+		roles := map[uint64]string{}
+		for _, member := range list.GetMembers() {
+			roles[member.GetProfileId()] = member.GetRole()
+		}
+		if list.GetTotal() != 2 || roles[owner] != "owner" || roles[accountant] != "accountant" {
+			t.Fatalf("unexpected members %s", string(body))
+		}
+	})
+
+	t.Run("GRPCListProfileCompanies", func(t *testing.T) {
+		resp, err := grpcClient.ListProfileCompanies(context.Background(), &types.ListProfileCompaniesRequest{ProfileId: accountant})
+		if err != nil {
+			t.Fatalf("list profile companies failed: %v", err)
+		}
+		if len(resp.GetCompanies()) != 1 || resp.GetCompanies()[0].GetCompany().GetId() != company.GetId() || resp.GetCompanies()[0].GetRole() != "accountant" {
+			t.Fatalf("unexpected companies %+v", resp)
+		}
+	})
+
+	t.Run("MemberSeesCompanyInBundleAndList", func(t *testing.T) {
+		bundle, err := grpcClient.GetProfileBundle(context.Background(), &types.GetProfileBundleRequest{Id: accountant, Include: []string{"companies"}})
+		if err != nil {
+			t.Fatalf("get bundle failed: %v", err)
+		}
+		if len(bundle.GetCompanies()) != 1 || bundle.GetCompanies()[0].GetId() != company.GetId() {
+			t.Fatalf("expected the shared company in the member's bundle, got %+v", bundle.GetCompanies())
+		}
+
+		list, err := grpcClient.ListCompanies(context.Background(), &types.ListCompaniesRequest{ProfileId: accountant})
+		if err != nil {
+			t.Fatalf("list companies failed: %v", err)
+		}
+		if list.GetTotal() != 1 || len(list.GetCompanies()) != 1 || list.GetCompanies()[0].GetId() != company.GetId() {
+			t.Fatalf("expected the shared company in the member's companies, got %+v", list)
+		}
+	})
+
+	t.Run("LastOwnerIsKept", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodDelete, fmt.Sprintf("%s/%d", membersPath, owner), nil)
+		if resp.StatusCode != http.StatusConflict {
+			t.Fatalf("expected 409, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		_, err := grpcClient.AddCompanyMember(context.Background(), &types.AddCompanyMemberRequest{CompanyId: company.GetId(), ProfileId: owner, Role: "viewer"})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("expected FAILED_PRECONDITION, got %v", err)
+		}
+	})
+
+	t.Run("HandOverOwnership", func(t *testing.T) {
+		if _, err := grpcClient.AddCompanyMember(context.Background(), &types.AddCompanyMemberRequest{CompanyId: company.GetId(), ProfileId: accountant, Role: "owner"}); err != nil {
+			t.Fatalf("promote member failed: %v", err)
+		}
+		resp, body := httpClient.doJSON(t, http.MethodDelete, fmt.Sprintf("%s/%d", membersPath, owner), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("V2ListProfileCompanies", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, fmt.Sprintf("/v2/profiles/%d/companies", accountant), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var list typesv2.ListProfileCompaniesResponse
+		if err := protojson.Unmarshal(body, &list); err != nil {
+			t.Fatalf("unmarshal companies failed: %v body=%s", err, string(body))
+		}
+		if len(list.GetCompanies()) != 1 || list.GetCompanies()[0].GetRole() != "owner" || list.GetCompanies()[0].GetCompany().GetCreatedAt() == nil {
+			t.Fatalf("unexpected companies %s", string(body))
+		}
+
+		if _, err := v2Client.RemoveCompanyMember(context.Background(), &typesv2.RemoveCompanyMemberRequest{CompanyId: company.GetId(), ProfileId: owner}); status.Code(err) != codes.NotFound {
+			t.Fatalf("expected NOT_FOUND for a removed member, got %v", err)
+		}
+	})
+}
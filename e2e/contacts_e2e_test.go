@@ -16,6 +16,7 @@ import (
 	"github.com/vibast-solutions/ms-go-profile/app/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestContactsE2E_CrossTransportCRUDAndList(t *testing.T) {
@@ -179,7 +180,7 @@ func TestContactsE2E_CrossTransportCRUDAndList(t *testing.T) {
 			LastName:  "Doe",
 			Nin:       "NIN-A-123",
 			Dob:       "1990-01-02",
-			Phone:     "+1-555-0101",
+			Phone:     "+1-202-555-0101",
 			Type:      "emergency",
 		})
 		if err != nil {
@@ -210,6 +211,9 @@ func TestContactsE2E_CrossTransportCRUDAndList(t *testing.T) {
 		if got.GetFirstName() != "John" || got.GetDob() != "1990-01-02" || got.GetType() != "emergency" {
 			t.Fatalf("unexpected full contact payload: %+v", got)
 		}
+		if got.GetPhone() != "+1-202-555-0101" || got.GetPhoneE164() != "+12025550101" {
+			t.Fatalf("expected raw and E.164 phone, got %+v", &got)
+		}
 	})
 
 	t.Run("GRPCGetByIDAfterHTTPCreate", func(t *testing.T) {
@@ -520,7 +524,7 @@ func TestContactsE2E_CrossTransportCRUDAndList(t *testing.T) {
 				"last_name":  "Smith",
 				"nin":        "NIN-A-999",
 				"dob":        "1988-05-20",
-				"phone":      "+1-555-9999",
+				"phone":      "+1-202-555-9999",
 				"type":       "other",
 			},
 		)
@@ -537,6 +541,81 @@ func TestContactsE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPListByNIN", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, "/contacts?nin=NIN-A-999", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		var list types.ListContactsResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			t.Fatalf("unmarshal list contacts failed: %v body=%s", err, string(body))
+		}
+		if !hasContactID(list.GetContacts(), state.contactFullID) {
+			t.Fatalf("expected updated contact in nin lookup: %+v", list.GetContacts())
+		}
+		for _, contact := range list.GetContacts() {
+			if contact.GetNin() != "NIN-A-999" {
+				t.Fatalf("expected only exact nin matches, got %+v", contact)
+			}
+		}
+	})
+
+	t.Run("GRPCListByNIN", func(t *testing.T) {
+		list, err := grpcClient.ListContacts(context.Background(), &types.ListContactsRequest{
+			ProfileId: state.profileAID,
+			Nin:       "NIN-A-999",
+		})
+		if err != nil {
+			t.Fatalf("grpc list contacts by nin failed: %v", err)
+		}
+		if !hasContactID(list.GetContacts(), state.contactFullID) {
+			t.Fatalf("expected updated contact in nin lookup: %+v", list.GetContacts())
+		}
+	})
+
+	t.Run("HTTPCreateMissingProfileNotFound", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/contacts", map[string]any{
+			"profile_id": missingProfileID,
+		})
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCCreateMissingProfileNotFound", func(t *testing.T) {
+		_, err := grpcClient.CreateContact(context.Background(), &types.CreateContactRequest{
+			ProfileId: missingProfileID,
+		})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected NotFound, got %v", err)
+		}
+	})
+
+	t.Run("HTTPUpdateMissingTargetProfileUnprocessable", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodPut,
+			"/contacts/"+strconv.FormatUint(state.contactFullID, 10),
+			map[string]any{
+				"profile_id": missingProfileID,
+			},
+		)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("expected 422, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCUpdateMissingTargetProfileFailedPrecondition", func(t *testing.T) {
+		_, err := grpcClient.UpdateContact(context.Background(), &types.UpdateContactRequest{
+			Id:        state.contactFullID,
+			ProfileId: missingProfileID,
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("expected FailedPrecondition, got %v", err)
+		}
+	})
+
 	t.Run("GRPCGetAfterHTTPUpdate", func(t *testing.T) {
 		got, err := grpcClient.GetContact(context.Background(), &types.GetContactRequest{
 			Id: state.contactFullID,
@@ -549,6 +628,56 @@ func TestContactsE2E_CrossTransportCRUDAndList(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPPatchKeepsOmittedFields", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodPatch,
+			"/contacts/"+strconv.FormatUint(state.contactFullID, 10),
+			map[string]any{
+				"phone": "+1-202-555-0000",
+				"dob":   nil,
+			},
+		)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		var patched types.ContactResponse
+		if err := json.Unmarshal(body, &patched); err != nil {
+			t.Fatalf("unmarshal patch contact failed: %v body=%s", err, string(body))
+		}
+		if patched.GetPhone() != "+1-202-555-0000" || patched.GetDob() != "" || patched.GetFirstName() != "Jane" || patched.GetNin() != "NIN-A-999" {
+			t.Fatalf("unexpected patched payload: %+v", &patched)
+		}
+	})
+
+	t.Run("HTTPPatchValidationOnlyPresentFields", func(t *testing.T) {
+		resp, _ := httpClient.doJSON(
+			t,
+			http.MethodPatch,
+			"/contacts/"+strconv.FormatUint(state.contactFullID, 10),
+			map[string]any{"dob": "20-05-1988"},
+		)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("GRPCPatchWithFieldMask", func(t *testing.T) {
+		patched, err := grpcClient.PatchContact(context.Background(), &types.PatchContactRequest{
+			Id:         state.contactFullID,
+			FirstName:  "Ignored",
+			LastName:   "Patched",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"last_name"}},
+		})
+		if err != nil {
+			t.Fatalf("grpc patch contact failed: %v", err)
+		}
+		if patched.GetLastName() != "Patched" || patched.GetFirstName() != "Jane" || patched.GetPhone() != "+1-202-555-0000" {
+			t.Fatalf("unexpected grpc patched contact: %+v", patched)
+		}
+	})
+
 	t.Run("GRPCDelete", func(t *testing.T) {
 		_, err := grpcClient.DeleteContact(context.Background(), &types.DeleteContactRequest{
 			Id: state.contactFullID,
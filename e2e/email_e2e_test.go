@@ -0,0 +1,110 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEmailE2E_NormalizationAndLookup(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	local := fmt.Sprintf("Email-E2E+%d", time.Now().UnixNano())
+	normalized := local + "@müller.de"
+
+	var created types.ProfileResponse
+	t.Run("HTTPCreateNormalizesDomain", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/profiles", map[string]any{
+			"user_id": uint64(time.Now().UnixNano()%1_000_000) + 6_000_000,
+			"email":   local + "@XN--MLLER-KVA.DE",
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", resp.StatusCode, string(body))
+		}
+		if err := json.Unmarshal(body, &created); err != nil {
+			t.Fatalf("unmarshal profile failed: %v body=%s", err, string(body))
+		}
+		if created.GetEmail() != normalized {
+			t.Fatalf("expected email %q, got %q", normalized, created.GetEmail())
+		}
+	})
+
+	t.Run("HTTPCreateRejectsInvalidEmail", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/profiles", map[string]any{
+			"user_id": uint64(time.Now().UnixNano()%1_000_000) + 7_000_000,
+			"email":   "John <john@example.com>",
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("HTTPGetByEmail", func(t *testing.T) {
+		path := "/profiles/email/" + url.PathEscape(strings.Replace(normalized, "müller", "MÜLLER", 1))
+		resp, body := httpClient.doJSON(t, http.MethodGet, path, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got types.ProfileResponse
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal profile failed: %v body=%s", err, string(body))
+		}
+		if got.GetId() != created.GetId() {
+			t.Fatalf("expected profile %d, got %d", created.GetId(), got.GetId())
+		}
+	})
+
+	t.Run("HTTPGetByEmailInvalid", func(t *testing.T) {
+		resp, _ := httpClient.doJSON(t, http.MethodGet, "/profiles/email/not-an-email", nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("GRPCGetProfileByEmail", func(t *testing.T) {
+		got, err := grpcClient.GetProfileByEmail(context.Background(), &types.GetProfileByEmailRequest{Email: local + "@xn--mller-kva.de"})
+		if err != nil {
+			t.Fatalf("grpc get profile by email failed: %v", err)
+		}
+		if got.GetId() != created.GetId() {
+			t.Fatalf("expected profile %d, got %d", created.GetId(), got.GetId())
+		}
+
+		_, err = grpcClient.GetProfileByEmail(context.Background(), &types.GetProfileByEmailRequest{Email: "missing-" + normalized})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected NotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+)
+
+// The e2e compose file sets FIELD_POLICY_SERVICES=profile-reader=contact.nin:masked,company.fiscal_code:hidden.
+func TestFieldPolicyE2E_SensitiveFieldsPerCaller(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	rawConn := dialProfileGRPCRaw(t, grpcAddr)
+	defer rawConn.Close()
+	rawGRPCClient := types.NewProfileServiceClient(rawConn)
+
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: uint64(time.Now().UnixNano()%1_000_000) + 5_000_000,
+		Email:  fmt.Sprintf("fieldpolicy-%d@example.com", time.Now().UnixNano()),
+	})
+	if err != nil {
+		t.Fatalf("grpc create profile failed: %v", err)
+	}
+	contact, err := grpcClient.CreateContact(context.Background(), &types.CreateContactRequest{
+		ProfileId: profile.GetId(),
+		FirstName: "Ana",
+		Nin:       "2900102223344",
+		Phone:     "+40700000000",
+	})
+	if err != nil {
+		t.Fatalf("grpc create contact failed: %v", err)
+	}
+	company, err := grpcClient.CreateCompany(context.Background(), &types.CreateCompanyRequest{
+		ProfileId:      profile.GetId(),
+		Name:           "ACME",
+		RegistrationNo: "J40/1234/2020",
+		FiscalCode:     "RO12345674",
+	})
+	if err != nil {
+		t.Fatalf("grpc create company failed: %v", err)
+	}
+	contactPath := "/contacts/" + strconv.FormatUint(contact.GetId(), 10)
+
+	t.Run("HTTPFullForUnrestrictedCaller", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, contactPath, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got types.ContactResponse
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal contact failed: %v body=%s", err, string(body))
+		}
+		if got.GetNin() != "2900102223344" {
+			t.Fatalf("expected full nin, got %q", got.GetNin())
+		}
+	})
+
+	t.Run("HTTPMaskedForReader", func(t *testing.T) {
+		resp, body := httpClient.doJSONWithAPIKey(t, http.MethodGet, contactPath, nil, profileReaderAPIKey())
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got types.ContactResponse
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal contact failed: %v body=%s", err, string(body))
+		}
+		if got.GetNin() != "*********3344" || got.GetPhone() != "+40700000000" {
+			t.Fatalf("expected masked nin and full phone, got %+v", &got)
+		}
+	})
+
+	t.Run("GRPCHiddenForReader", func(t *testing.T) {
+		got, err := rawGRPCClient.GetCompany(grpcContextWithAPIKey(profileReaderAPIKey()), &types.GetCompanyRequest{Id: company.GetId()})
+		if err != nil {
+			t.Fatalf("grpc get company failed: %v", err)
+		}
+		if got.GetFiscalCode() != "" || got.GetName() != "ACME" {
+			t.Fatalf("expected hidden fiscal code, got %+v", got)
+		}
+	})
+}
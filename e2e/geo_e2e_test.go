@@ -0,0 +1,143 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGeoE2E_SearchAddressesNear(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 10_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("geo-e2e-%d@example.com", userID),
+	})
+	if err != nil {
+		t.Fatalf("create profile failed: %v", err)
+	}
+
+	// The city center, an address about 2.7 km west of it, one in Turda about 30 km away and
+	// one without coordinates.
+	points := []struct{ lat, lng *float64 }{
+		{proto.Float64(46.7712), proto.Float64(23.6236)},
+		{proto.Float64(46.7694), proto.Float64(23.5899)},
+		{proto.Float64(46.5667), proto.Float64(23.7833)},
+		{nil, nil},
+	}
+	ids := make([]uint64, 0, len(points))
+	for _, point := range points {
+		address, err := grpcClient.CreateAddress(context.Background(), &types.CreateAddressRequest{
+			StreetName: "Main Street",
+			StreenNo:   "1",
+			City:       "Cluj-Napoca",
+			County:     "Cluj",
+			Country:    "RO",
+			ProfileId:  profile.GetId(),
+			Type:       "billing",
+			Latitude:   point.lat,
+			Longitude:  point.lng,
+		})
+		if err != nil {
+			t.Fatalf("create address failed: %v", err)
+		}
+		if address.Latitude == nil != (point.lat == nil) {
+			t.Fatalf("unexpected coordinates in %+v", address)
+		}
+		ids = append(ids, address.GetId())
+	}
+
+	t.Run("RadiusOverGRPC", func(t *testing.T) {
+		resp, err := grpcClient.SearchAddressesNear(context.Background(), &types.SearchAddressesNearRequest{
+			Center:       &types.GeoPoint{Latitude: 46.7712, Longitude: 23.6236},
+			RadiusMeters: 5000,
+			ProfileId:    profile.GetId(),
+		})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		found := resp.GetAddresses()
+		if len(found) != 2 || found[0].GetAddress().GetId() != ids[0] || found[1].GetAddress().GetId() != ids[1] {
+			t.Fatalf("expected the two nearby addresses nearest first, got %+v", found)
+		}
+		if d := found[1].GetDistanceMeters(); d < 2000 || d > 3500 {
+			t.Fatalf("unexpected distance %f", d)
+		}
+	})
+
+	t.Run("BoundingBoxOverHTTP", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, fmt.Sprintf("/addresses/near?bbox=23.7,46.5,23.9,46.6&profile_id=%d", profile.GetId()), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var result types.SearchAddressesNearResponse
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Fatalf("unmarshal result failed: %v body=%s", err, string(body))
+		}
+		if len(result.GetAddresses()) != 1 || result.GetAddresses()[0].GetAddress().GetId() != ids[2] {
+			t.Fatalf("expected only the Turda address, got %s", string(body))
+		}
+	})
+
+	t.Run("PatchClearsCoordinates", func(t *testing.T) {
+		resp, body := httpClient.doJSONWithHeaders(t, http.MethodPatch, fmt.Sprintf("/addresses/%d", ids[1]),
+			map[string]any{"latitude": nil, "longitude": nil}, profileCallerAPIKey(), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		search, err := grpcClient.SearchAddressesNear(context.Background(), &types.SearchAddressesNearRequest{
+			Center:       &types.GeoPoint{Latitude: 46.7712, Longitude: 23.6236},
+			RadiusMeters: 5000,
+			ProfileId:    profile.GetId(),
+		})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		if len(search.GetAddresses()) != 1 {
+			t.Fatalf("expected the cleared address to drop out, got %+v", search.GetAddresses())
+		}
+	})
+
+	t.Run("InvalidSearchRejected", func(t *testing.T) {
+		_, err := grpcClient.SearchAddressesNear(context.Background(), &types.SearchAddressesNearRequest{
+			Center:    &types.GeoPoint{Latitude: 46.7712, Longitude: 23.6236},
+			ProfileId: profile.GetId(),
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected codes.InvalidArgument, got %v", err)
+		}
+	})
+}
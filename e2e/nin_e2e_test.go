@@ -0,0 +1,101 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestNINE2E_RomanianCNP(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 8_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("nin-e2e-%d@example.com", userID),
+	})
+	if err != nil {
+		t.Fatalf("create profile failed: %v", err)
+	}
+
+	var contactID uint64
+	t.Run("DOBFilledFromCNP", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/contacts", map[string]any{
+			"profile_id":  profile.GetId(),
+			"first_name":  "Ana",
+			"nin":         "2901231040024",
+			"nin_country": "Romania",
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var created types.ContactResponse
+		if err := json.Unmarshal(body, &created); err != nil {
+			t.Fatalf("unmarshal contact failed: %v body=%s", err, string(body))
+		}
+		if created.GetDob() != "1990-12-31" || created.GetNinCountry() != "RO" {
+			t.Fatalf("expected dob and nin_country from the CNP, got %s", string(body))
+		}
+		contactID = created.GetId()
+	})
+
+	t.Run("InvalidControlDigitRejected", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/contacts", map[string]any{
+			"profile_id":  profile.GetId(),
+			"nin":         "2901231040025",
+			"nin_country": "RO",
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("unmarshal error failed: %v body=%s", err, string(body))
+		}
+		if payload["field"] != "nin" {
+			t.Fatalf("expected nin field error, got %s", string(body))
+		}
+	})
+
+	t.Run("PatchedDOBMustMatchCNP", func(t *testing.T) {
+		_, err := grpcClient.PatchContact(context.Background(), &types.PatchContactRequest{
+			Id:         contactID,
+			Dob:        "1991-01-01",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"dob"}},
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected codes.InvalidArgument, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPhoneE2E_RegionFromProfileAddress(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 7_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("phone-e2e-%d@example.com", userID),
+	})
+	if err != nil {
+		t.Fatalf("create profile failed: %v", err)
+	}
+
+	t.Run("NationalNumberWithoutRegionRejected", func(t *testing.T) {
+		_, err := grpcClient.CreateContact(context.Background(), &types.CreateContactRequest{
+			ProfileId: profile.GetId(),
+			Phone:     "0722 123 456",
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected codes.InvalidArgument, got %v", err)
+		}
+	})
+
+	t.Run("NationalNumberUsesAddressCountry", func(t *testing.T) {
+		_, err := grpcClient.CreateAddress(context.Background(), &types.CreateAddressRequest{
+			ProfileId:  profile.GetId(),
+			StreetName: "Strada Lipscani",
+			StreenNo:   "10",
+			City:       "Bucuresti",
+			County:     "Bucuresti",
+			Country:    "RO",
+		})
+		if err != nil {
+			t.Fatalf("create address failed: %v", err)
+		}
+
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/contacts", map[string]any{
+			"profile_id": profile.GetId(),
+			"first_name": "Ion",
+			"phone":      "0722 123 456",
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		var got types.ContactResponse
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal contact failed: %v body=%s", err, string(body))
+		}
+		if got.GetPhone() != "0722 123 456" || got.GetPhoneE164() != "+40722123456" || got.GetPhoneType() != "mobile" {
+			t.Fatalf("unexpected phone fields: %+v", &got)
+		}
+	})
+
+	t.Run("MalformedPhoneRejected", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/contacts", map[string]any{
+			"profile_id": profile.GetId(),
+			"phone":      "call me",
+		})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+}
@@ -0,0 +1,127 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPreferenceE2E_Namespaces(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+	v2Client := typesv2.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 13_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("preference-e2e-%d@example.com", userID),
+	})
+	if err != nil {
+		t.Fatalf("create profile failed: %v", err)
+	}
+	preferencesPath := fmt.Sprintf("/profiles/%d/preferences", profile.GetId())
+
+	t.Run("HTTPSetMergesKeys", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPut, preferencesPath+"/notifications", map[string]any{
+			"values": map[string]any{"channels": []string{"email"}, "digest_hour": 7},
+		})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		resp, body = httpClient.doJSON(t, http.MethodPut, preferencesPath+"/notifications", map[string]any{
+			"values": map[string]any{"digest_hour": nil, "muted": true},
+		})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got struct {
+			Namespaces []struct {
+				Namespace string         `json:"namespace"`
+				Values    map[string]any `json:"values"`
+			} `json:"namespaces"`
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal preferences failed: %v body=%s", err, string(body))
+		}
+		if len(got.Namespaces) != 1 || len(got.Namespaces[0].Values) != 2 || got.Namespaces[0].Values["muted"] != true {
+			t.Fatalf("unexpected preferences %s", string(body))
+		}
+	})
+
+	t.Run("GRPCSetAndGet", func(t *testing.T) {
+		values, err := structpb.NewStruct(map[string]any{"currency": "RON"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = grpcClient.SetPreferences(context.Background(), &types.SetPreferencesRequest{ProfileId: profile.GetId(), Namespace: "billing", Values: values}); err != nil {
+			t.Fatalf("set preferences failed: %v", err)
+		}
+
+		resp, err := grpcClient.GetPreferences(context.Background(), &types.GetPreferencesRequest{ProfileId: profile.GetId()})
+		if err != nil {
+			t.Fatalf("get preferences failed: %v", err)
+		}
+		if len(resp.GetNamespaces()) != 2 || resp.GetNamespaces()[0].GetNamespace() != "billing" {
+			t.Fatalf("unexpected preferences %+v", resp)
+		}
+	})
+
+	t.Run("V2GetOneNamespace", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, fmt.Sprintf("/v2%s?namespace=billing", preferencesPath), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got typesv2.PreferencesResponse
+		if err := protojson.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal preferences failed: %v body=%s", err, string(body))
+		}
+		if len(got.GetNamespaces()) != 1 || got.GetNamespaces()[0].GetValues().GetFields()["currency"].GetStringValue() != "RON" || got.GetNamespaces()[0].GetUpdatedAt() == nil {
+			t.Fatalf("unexpected preferences %s", string(body))
+		}
+	})
+
+	t.Run("DeleteKey", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodDelete, preferencesPath+"/billing/currency", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		_, err := v2Client.DeletePreference(context.Background(), &typesv2.DeletePreferenceRequest{ProfileId: profile.GetId(), Namespace: "billing", Key: "currency"})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected NOT_FOUND for a deleted key, got %v", err)
+		}
+	})
+}
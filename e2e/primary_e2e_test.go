@@ -0,0 +1,141 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPrimaryE2E_OnePrimaryPerType(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 9_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("primary-e2e-%d@example.com", userID),
+	})
+	if err != nil {
+		t.Fatalf("create profile failed: %v", err)
+	}
+
+	addresses := make([]*types.AddressResponse, 0, 3)
+	for _, kind := range []string{"billing", "billing", "shipping"} {
+		address, err := grpcClient.CreateAddress(context.Background(), &types.CreateAddressRequest{
+			StreetName: "Main Street",
+			StreenNo:   "1",
+			City:       "Cluj-Napoca",
+			County:     "Cluj",
+			Country:    "RO",
+			ProfileId:  profile.GetId(),
+			Type:       kind,
+		})
+		if err != nil {
+			t.Fatalf("create address failed: %v", err)
+		}
+		if address.GetIsPrimary() {
+			t.Fatalf("expected a new address not to be primary, got %+v", address)
+		}
+		addresses = append(addresses, address)
+	}
+	first, second, shipping := addresses[0], addresses[1], addresses[2]
+
+	t.Run("SetPrimaryOverHTTP", func(t *testing.T) {
+		resp, body := httpClient.doJSONWithHeaders(t, http.MethodPost, fmt.Sprintf("/addresses/%d/primary", first.GetId()), nil, profileCallerAPIKey(),
+			map[string]string{"If-Match": types.FormatETag(first.GetVersion())})
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var updated types.AddressResponse
+		if err := json.Unmarshal(body, &updated); err != nil {
+			t.Fatalf("unmarshal address failed: %v body=%s", err, string(body))
+		}
+		if !updated.GetIsPrimary() || resp.Header.Get("ETag") != types.FormatETag(updated.GetVersion()) {
+			t.Fatalf("expected a primary address with its ETag, got %s", string(body))
+		}
+	})
+
+	t.Run("SetPrimaryOverGRPCMovesFlag", func(t *testing.T) {
+		if _, err := grpcClient.SetPrimaryAddress(context.Background(), &types.SetPrimaryAddressRequest{Id: second.GetId()}); err != nil {
+			t.Fatalf("set primary failed: %v", err)
+		}
+		if _, err := grpcClient.SetPrimaryAddress(context.Background(), &types.SetPrimaryAddressRequest{Id: shipping.GetId()}); err != nil {
+			t.Fatalf("set primary failed: %v", err)
+		}
+
+		previous, err := grpcClient.GetAddress(context.Background(), &types.GetAddressRequest{Id: first.GetId()})
+		if err != nil {
+			t.Fatalf("get address failed: %v", err)
+		}
+		if previous.GetIsPrimary() {
+			t.Fatalf("expected the previous billing primary to be cleared, got %+v", previous)
+		}
+
+		list, err := grpcClient.ListAddresses(context.Background(), &types.ListAddressesRequest{ProfileId: profile.GetId(), PrimaryOnly: true})
+		if err != nil {
+			t.Fatalf("list addresses failed: %v", err)
+		}
+		if list.GetTotal() != 2 || !hasAddressID(list.GetAddresses(), second.GetId()) || !hasAddressID(list.GetAddresses(), shipping.GetId()) {
+			t.Fatalf("expected one primary address per type, got %+v", list.GetAddresses())
+		}
+	})
+
+	t.Run("StaleVersionRejected", func(t *testing.T) {
+		_, err := grpcClient.SetPrimaryAddress(context.Background(), &types.SetPrimaryAddressRequest{Id: first.GetId(), ExpectedVersion: first.GetVersion()})
+		if status.Code(err) != codes.Aborted {
+			t.Fatalf("expected codes.Aborted, got %v", err)
+		}
+	})
+
+	t.Run("DeletedPrimaryFreesType", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodDelete, fmt.Sprintf("/addresses/%d", second.GetId()), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		resp, body = httpClient.doJSON(t, http.MethodGet, fmt.Sprintf("/addresses?profile_id=%d&type=billing&primary_only=true", profile.GetId()), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var list types.ListAddressesResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			t.Fatalf("unmarshal list failed: %v body=%s", err, string(body))
+		}
+		if list.GetTotal() != 0 {
+			t.Fatalf("expected no primary billing address, got %s", string(body))
+		}
+
+		if _, err := grpcClient.SetPrimaryAddress(context.Background(), &types.SetPrimaryAddressRequest{Id: first.GetId()}); err != nil {
+			t.Fatalf("set primary after delete failed: %v", err)
+		}
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,6 +27,9 @@ import (
 const (
 	defaultHTTPBase = "http://localhost:28080"
 	defaultGRPCAddr = "localhost:29090"
+
+	// missingProfileID is never allocated by the e2e database.
+	missingProfileID = uint64(1) << 62
 )
 
 type httpClient struct {
@@ -48,6 +52,11 @@ func (c *httpClient) doJSON(t *testing.T, method, path string, body any) (*http.
 
 func (c *httpClient) doJSONWithAPIKey(t *testing.T, method, path string, body any, apiKey string) (*http.Response, []byte) {
 	t.Helper()
+	return c.doJSONWithHeaders(t, method, path, body, apiKey, nil)
+}
+
+func (c *httpClient) doJSONWithHeaders(t *testing.T, method, path string, body any, apiKey string, headers map[string]string) (*http.Response, []byte) {
+	t.Helper()
 
 	var reqBody *bytes.Reader
 	if body != nil {
@@ -70,6 +79,9 @@ func (c *httpClient) doJSONWithAPIKey(t *testing.T, method, path string, body an
 	if apiKey != "" {
 		req.Header.Set("X-API-Key", apiKey)
 	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -311,6 +323,46 @@ func TestProfileE2E_CrossTransportCRUD(t *testing.T) {
 		}
 	})
 
+	t.Run("HTTPGetReturnsETag", func(t *testing.T) {
+		resp, body := httpClient.doJSON(
+			t,
+			http.MethodGet,
+			"/profiles/"+strconv.FormatUint(state.profile.GetId(), 10),
+			nil,
+		)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"2"` {
+			t.Fatalf("expected ETag \"2\" after one update, got %q", etag)
+		}
+	})
+
+	t.Run("HTTPUpdateStaleIfMatchPreconditionFailed", func(t *testing.T) {
+		resp, body := httpClient.doJSONWithHeaders(
+			t,
+			http.MethodPut,
+			"/profiles/"+strconv.FormatUint(state.profile.GetId(), 10),
+			map[string]any{"email": "stale-" + state.emailV1},
+			profileCallerAPIKey(),
+			map[string]string{"If-Match": `"1"`},
+		)
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatalf("expected 412, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("GRPCUpdateStaleExpectedVersionAborted", func(t *testing.T) {
+		_, err := grpcClient.UpdateProfile(context.Background(), &types.UpdateProfileRequest{
+			Id:              state.profile.GetId(),
+			Email:           "stale-" + state.emailV1,
+			ExpectedVersion: 1,
+		})
+		if status.Code(err) != codes.Aborted {
+			t.Fatalf("expected Aborted, got %v", err)
+		}
+	})
+
 	t.Run("HTTPGetByUserID", func(t *testing.T) {
 		resp, body := httpClient.doJSON(
 			t,
@@ -362,6 +414,95 @@ func TestProfileE2E_CrossTransportCRUD(t *testing.T) {
 			t.Fatalf("expected NotFound after delete, got %v", err)
 		}
 	})
+
+	t.Run("GRPCCreateWithChildren", func(t *testing.T) {
+		userID := state.userID + 1
+		created, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+			UserId:  userID,
+			Email:   "children-" + state.emailV1,
+			Contact: &types.CreateContactRequest{FirstName: "John", LastName: "Doe", Type: "primary"},
+			Address: &types.CreateAddressRequest{StreetName: "Main", StreenNo: "1", City: "City", County: "Cluj", Country: "RO"},
+		})
+		if err != nil {
+			t.Fatalf("grpc create with children failed: %v", err)
+		}
+		defer grpcClient.DeleteProfile(context.Background(), &types.DeleteProfileRequest{Id: created.GetId()})
+
+		contacts, err := grpcClient.ListContacts(context.Background(), &types.ListContactsRequest{ProfileId: created.GetId()})
+		if err != nil {
+			t.Fatalf("grpc list contacts failed: %v", err)
+		}
+		if contacts.GetTotal() != 1 || contacts.GetContacts()[0].GetFirstName() != "John" {
+			t.Fatalf("expected the initial contact, got: %+v", contacts)
+		}
+
+		resp, body := httpClient.doJSON(t, http.MethodGet, "/addresses?profile_id="+strconv.FormatUint(created.GetId(), 10), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var addresses types.ListAddressesResponse
+		if err := json.Unmarshal(body, &addresses); err != nil {
+			t.Fatalf("unmarshal list addresses response failed: %v body=%s", err, string(body))
+		}
+		if addresses.GetTotal() != 1 {
+			t.Fatalf("expected the initial address, got: %s", string(body))
+		}
+
+		resp, body = httpClient.doJSON(t, http.MethodGet, "/profiles/"+strconv.FormatUint(created.GetId(), 10)+"/full", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var bundle types.ProfileBundleResponse
+		if err := json.Unmarshal(body, &bundle); err != nil {
+			t.Fatalf("unmarshal bundle response failed: %v body=%s", err, string(body))
+		}
+		if bundle.GetProfile().GetId() != created.GetId() || len(bundle.GetContacts()) != 1 || len(bundle.GetAddresses()) != 1 || len(bundle.GetCompanies()) != 0 {
+			t.Fatalf("unexpected http bundle: %s", string(body))
+		}
+
+		partial, err := grpcClient.GetProfileBundle(context.Background(), &types.GetProfileBundleRequest{
+			Id:      created.GetId(),
+			Include: []string{"addresses"},
+		})
+		if err != nil {
+			t.Fatalf("grpc get profile bundle failed: %v", err)
+		}
+		if len(partial.GetContacts()) != 0 || len(partial.GetAddresses()) != 1 {
+			t.Fatalf("expected only addresses in bundle, got: %+v", partial)
+		}
+	})
+
+	t.Run("HTTPBundleNotFound", func(t *testing.T) {
+		resp, _ := httpClient.doJSON(t, http.MethodGet, "/profiles/"+strconv.FormatUint(state.deleteID, 10)+"/full", nil)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 for deleted profile bundle, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("HTTPCreateWithChildrenRollsBack", func(t *testing.T) {
+		userID := state.userID + 2
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/profiles", map[string]any{
+			"user_id": userID,
+			"email":   "rollback-" + state.emailV1,
+			"contact": map[string]any{"first_name": "John"},
+			"address": map[string]any{
+				"street_name": "Main",
+				"streen_no":   "1",
+				"city":        "City",
+				"county":      "Cluj",
+				"country":     "RO",
+				"type":        strings.Repeat("x", 300),
+			},
+		})
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("expected 500 for address rejected by the database, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		_, err := grpcClient.GetProfileByUserID(context.Background(), &types.GetProfileByUserIDRequest{UserId: userID})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected profile insert to be rolled back, got %v", err)
+		}
+	})
 }
 
 func ioReadAll(resp *http.Response) ([]byte, error) {
@@ -0,0 +1,143 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSoftDeleteE2E_DeleteRestoreCascade(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	grpcClient := types.NewProfileServiceClient(conn)
+
+	rawConn := dialProfileGRPCRaw(t, grpcAddr)
+	defer rawConn.Close()
+	rawGRPCClient := types.NewProfileServiceClient(rawConn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 3_000_000
+	profile, err := grpcClient.CreateProfile(context.Background(), &types.CreateProfileRequest{
+		UserId:  userID,
+		Email:   fmt.Sprintf("softdelete-%d@example.com", time.Now().UnixNano()),
+		Contact: &types.CreateContactRequest{FirstName: "Ana", LastName: "Pop"},
+	})
+	if err != nil {
+		t.Fatalf("grpc create profile failed: %v", err)
+	}
+	profilePath := "/profiles/" + strconv.FormatUint(profile.GetId(), 10)
+
+	contacts, err := grpcClient.ListContacts(context.Background(), &types.ListContactsRequest{ProfileId: profile.GetId()})
+	if err != nil || contacts.GetTotal() != 1 {
+		t.Fatalf("expected one contact, got %+v (err %v)", contacts, err)
+	}
+	contactID := contacts.GetContacts()[0].GetId()
+
+	t.Run("HTTPDeleteHidesProfileAndChildren", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodDelete, profilePath, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+
+		resp, _ = httpClient.doJSON(t, http.MethodGet, profilePath, nil)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 after delete, got %d", resp.StatusCode)
+		}
+		_, err := grpcClient.GetContact(context.Background(), &types.GetContactRequest{Id: contactID})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("expected contact to be deleted with its profile, got %v", err)
+		}
+	})
+
+	t.Run("HTTPIncludeDeletedRequiresAdmin", func(t *testing.T) {
+		resp, _ := httpClient.doJSONWithAPIKey(t, http.MethodGet, profilePath+"?include_deleted=true", nil, profileReaderAPIKey())
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for non-admin include_deleted, got %d", resp.StatusCode)
+		}
+		resp, _ = httpClient.doJSONWithAPIKey(t, http.MethodPost, profilePath+"/restore", nil, profileReaderAPIKey())
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for non-admin restore, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("HTTPIncludeDeletedReturnsDeletedAt", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodGet, profilePath+"?include_deleted=true", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		var got types.ProfileResponse
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("unmarshal profile response failed: %v body=%s", err, string(body))
+		}
+		if got.GetDeletedAt() == "" {
+			t.Fatalf("expected deleted_at to be set: %s", string(body))
+		}
+	})
+
+	t.Run("GRPCRestoreContactWhileProfileDeleted", func(t *testing.T) {
+		_, err := grpcClient.RestoreContact(context.Background(), &types.RestoreContactRequest{Id: contactID})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("expected FailedPrecondition, got %v", err)
+		}
+	})
+
+	t.Run("HTTPRestoreBringsBackChildren", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, profilePath+"/restore", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		if resp.Header.Get("ETag") == "" {
+			t.Fatal("expected ETag on restore response")
+		}
+
+		resp, _ = httpClient.doJSON(t, http.MethodPost, profilePath+"/restore", nil)
+		if resp.StatusCode != http.StatusConflict {
+			t.Fatalf("expected 409 restoring a live profile, got %d", resp.StatusCode)
+		}
+
+		contact, err := grpcClient.GetContact(context.Background(), &types.GetContactRequest{Id: contactID})
+		if err != nil {
+			t.Fatalf("expected contact to be restored with its profile, got %v", err)
+		}
+		if contact.GetDeletedAt() != "" {
+			t.Fatalf("expected live contact, got %+v", contact)
+		}
+	})
+
+	t.Run("GRPCRestoreRequiresAdmin", func(t *testing.T) {
+		_, err := rawGRPCClient.RestoreProfile(grpcContextWithAPIKey(profileReaderAPIKey()), &types.RestoreProfileRequest{Id: profile.GetId()})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected PermissionDenied, got %v", err)
+		}
+	})
+
+	_, _ = grpcClient.DeleteProfile(context.Background(), &types.DeleteProfileRequest{Id: profile.GetId()})
+}
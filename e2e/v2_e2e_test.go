@@ -0,0 +1,137 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vibast-solutions/ms-go-profile/app/types"
+	typesv2 "github.com/vibast-solutions/ms-go-profile/app/types/v2"
+	"google.golang.org/genproto/googleapis/type/date"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestV2E2E(t *testing.T) {
+	httpBase := os.Getenv("PROFILE_HTTP_URL")
+	if httpBase == "" {
+		httpBase = defaultHTTPBase
+	}
+	grpcAddr := os.Getenv("PROFILE_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = defaultGRPCAddr
+	}
+
+	if err := waitForHTTP(httpBase, 30*time.Second); err != nil {
+		t.Fatalf("http not ready: %v", err)
+	}
+	if err := waitForGRPC(grpcAddr, 30*time.Second); err != nil {
+		t.Fatalf("grpc not ready: %v", err)
+	}
+
+	httpClient := newHTTPClient(httpBase)
+	conn := dialProfileGRPC(t, grpcAddr)
+	defer conn.Close()
+	v1Client := types.NewProfileServiceClient(conn)
+	v2Client := typesv2.NewProfileServiceClient(conn)
+
+	userID := uint64(time.Now().UnixNano()%1_000_000) + 11_000_000
+	profile, err := v2Client.CreateProfile(context.Background(), &typesv2.CreateProfileRequest{
+		UserId: userID,
+		Email:  fmt.Sprintf("v2-e2e-%d@example.com", userID),
+	})
+	if err != nil {
+		t.Fatalf("create profile failed: %v", err)
+	}
+	if profile.GetCreatedAt() == nil || profile.GetDeletedAt() != nil {
+		t.Fatalf("unexpected timestamps in %+v", profile)
+	}
+
+	t.Run("ContactDOBOverGRPC", func(t *testing.T) {
+		contact, err := v2Client.CreateContact(context.Background(), &typesv2.CreateContactRequest{
+			FirstName: "Ana",
+			LastName:  "Pop",
+			Dob:       &date.Date{Year: 1990, Month: 5, Day: 15},
+			ProfileId: profile.GetId(),
+		})
+		if err != nil {
+			t.Fatalf("create contact failed: %v", err)
+		}
+		if dob := contact.GetDob(); dob.GetYear() != 1990 || dob.GetMonth() != 5 || dob.GetDay() != 15 {
+			t.Fatalf("unexpected dob %+v", dob)
+		}
+
+		v1Contact, err := v1Client.GetContact(context.Background(), &types.GetContactRequest{Id: contact.GetId()})
+		if err != nil {
+			t.Fatalf("get contact over v1 failed: %v", err)
+		}
+		if v1Contact.GetDob() != "1990-05-15" {
+			t.Fatalf("expected v1 to read the same dob, got %q", v1Contact.GetDob())
+		}
+	})
+
+	t.Run("AddressOverHTTP", func(t *testing.T) {
+		resp, body := httpClient.doJSON(t, http.MethodPost, "/v2/addresses", map[string]any{
+			"street_name": "Strada Memorandumului",
+			"street_no":   "28",
+			"city":        "Cluj-Napoca",
+			"county":      "Cluj",
+			"country":     "RO",
+			"profile_id":  fmt.Sprint(profile.GetId()),
+		})
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", resp.StatusCode, string(body))
+		}
+		if resp.Header.Get("Deprecation") != "" {
+			t.Fatal("expected no deprecation header on v2")
+		}
+		var address typesv2.AddressResponse
+		if err := protojson.Unmarshal(body, &address); err != nil {
+			t.Fatalf("unmarshal address failed: %v body=%s", err, string(body))
+		}
+		if address.GetStreetNo() != "28" || address.GetCreatedAt() == nil {
+			t.Fatalf("unexpected address %s", string(body))
+		}
+
+		resp, body = httpClient.doJSON(t, http.MethodGet, fmt.Sprintf("/addresses/%d", address.GetId()), nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", resp.StatusCode, string(body))
+		}
+		if resp.Header.Get("Deprecation") != types.V1Deprecation() {
+			t.Fatalf("expected a v1 deprecation header, got %q", resp.Header.Get("Deprecation"))
+		}
+		if want := fmt.Sprintf(`</v2/addresses/%d>; rel="successor-version"`, address.GetId()); resp.Header.Get("Link") != want {
+			t.Fatalf("unexpected link header %q", resp.Header.Get("Link"))
+		}
+
+		resp, body = httpClient.doJSON(t, http.MethodDelete, fmt.Sprintf("/v2/addresses/%d", address.GetId()), nil)
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d body=%s", resp.StatusCode, string(body))
+		}
+	})
+
+	t.Run("V1GRPCHeaders", func(t *testing.T) {
+		var header metadata.MD
+		if _, err := v1Client.GetProfile(context.Background(), &types.GetProfileRequest{Id: profile.GetId()}, grpc.Header(&header)); err != nil {
+			t.Fatalf("get profile failed: %v", err)
+		}
+		if got := header.Get("deprecation"); len(got) != 1 || got[0] != types.V1Deprecation() {
+			t.Fatalf("unexpected deprecation header %q", got)
+		}
+
+		header = nil
+		if _, err := v2Client.DeleteProfile(context.Background(), &typesv2.DeleteProfileRequest{Id: profile.GetId()}, grpc.Header(&header)); err != nil {
+			t.Fatalf("delete profile failed: %v", err)
+		}
+		if len(header.Get("deprecation")) != 0 {
+			t.Fatalf("expected no deprecation header on v2, got %q", header.Get("deprecation"))
+		}
+	})
+}
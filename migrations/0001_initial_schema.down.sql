@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS companies;
+DROP TABLE IF EXISTS addresses;
+DROP TABLE IF EXISTS contacts;
+DROP TABLE IF EXISTS profile;
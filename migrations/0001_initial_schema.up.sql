@@ -1,7 +1,4 @@
-CREATE DATABASE IF NOT EXISTS profile;
-USE profile;
-
-CREATE TABLE profile (
+CREATE TABLE IF NOT EXISTS profile (
     id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
     user_id BIGINT UNSIGNED NOT NULL,
     email VARCHAR(255) NOT NULL,
@@ -11,7 +8,7 @@ CREATE TABLE profile (
     INDEX idx_profile_email (email)
 );
 
-CREATE TABLE contacts (
+CREATE TABLE IF NOT EXISTS contacts (
     id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
     first_name VARCHAR(255) NOT NULL,
     last_name VARCHAR(255) NOT NULL,
@@ -26,7 +23,7 @@ CREATE TABLE contacts (
     CONSTRAINT fk_contacts_profile_id FOREIGN KEY (profile_id) REFERENCES profile(id) ON DELETE CASCADE
 );
 
-CREATE TABLE addresses (
+CREATE TABLE IF NOT EXISTS addresses (
     id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
     street_name VARCHAR(255) NOT NULL,
     streen_no VARCHAR(128) NOT NULL,
@@ -45,7 +42,7 @@ CREATE TABLE addresses (
     CONSTRAINT fk_addresses_profile_id FOREIGN KEY (profile_id) REFERENCES profile(id) ON DELETE CASCADE
 );
 
-CREATE TABLE companies (
+CREATE TABLE IF NOT EXISTS companies (
     id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     registration_no VARCHAR(255) NOT NULL,
@@ -0,0 +1,4 @@
+ALTER TABLE companies DROP COLUMN version;
+ALTER TABLE addresses DROP COLUMN version;
+ALTER TABLE contacts DROP COLUMN version;
+ALTER TABLE profile DROP COLUMN version;
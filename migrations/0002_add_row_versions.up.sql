@@ -0,0 +1,4 @@
+ALTER TABLE profile ADD COLUMN version BIGINT UNSIGNED NOT NULL DEFAULT 1;
+ALTER TABLE contacts ADD COLUMN version BIGINT UNSIGNED NOT NULL DEFAULT 1;
+ALTER TABLE addresses ADD COLUMN version BIGINT UNSIGNED NOT NULL DEFAULT 1;
+ALTER TABLE companies ADD COLUMN version BIGINT UNSIGNED NOT NULL DEFAULT 1;
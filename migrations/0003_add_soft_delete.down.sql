@@ -0,0 +1,4 @@
+ALTER TABLE companies DROP INDEX idx_companies_deleted_at, DROP COLUMN deleted_at;
+ALTER TABLE addresses DROP INDEX idx_addresses_deleted_at, DROP COLUMN deleted_at;
+ALTER TABLE contacts DROP INDEX idx_contacts_deleted_at, DROP COLUMN deleted_at;
+ALTER TABLE profile DROP INDEX idx_profile_deleted_at, DROP COLUMN deleted_at;
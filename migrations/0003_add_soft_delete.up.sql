@@ -0,0 +1,4 @@
+ALTER TABLE profile ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL, ADD INDEX idx_profile_deleted_at (deleted_at);
+ALTER TABLE contacts ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL, ADD INDEX idx_contacts_deleted_at (deleted_at);
+ALTER TABLE addresses ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL, ADD INDEX idx_addresses_deleted_at (deleted_at);
+ALTER TABLE companies ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL, ADD INDEX idx_companies_deleted_at (deleted_at);
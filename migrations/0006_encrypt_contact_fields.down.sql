@@ -0,0 +1,12 @@
+-- The narrower columns cannot hold sealed values, so the rollback refuses to run while any
+-- contact field is still encrypted. Decrypt them first by running rotate-keys with
+-- ENCRYPTION_FIELDS=, so that no field is encrypted.
+DROP TEMPORARY TABLE IF EXISTS contacts_encryption_guard;
+CREATE TEMPORARY TABLE contacts_encryption_guard (
+    sealed_contacts BIGINT UNSIGNED NOT NULL,
+    CONSTRAINT chk_contacts_must_be_decrypted_before_rollback CHECK (sealed_contacts = 0)
+);
+INSERT INTO contacts_encryption_guard (sealed_contacts)
+SELECT COUNT(*) FROM contacts WHERE nin LIKE 'enc:v1:%' OR phone LIKE 'enc:v1:%' OR dob LIKE 'enc:v1:%';
+DROP TEMPORARY TABLE contacts_encryption_guard;
+ALTER TABLE contacts DROP INDEX idx_contacts_nin_index, DROP COLUMN nin_index, MODIFY COLUMN dob DATE NULL, MODIFY COLUMN phone VARCHAR(64) NOT NULL, MODIFY COLUMN nin VARCHAR(128) NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE contacts MODIFY COLUMN nin VARCHAR(512) NOT NULL, MODIFY COLUMN phone VARCHAR(512) NOT NULL, MODIFY COLUMN dob VARCHAR(512) NULL, ADD COLUMN nin_index CHAR(64) NOT NULL DEFAULT '', ADD INDEX idx_contacts_nin_index (nin_index);
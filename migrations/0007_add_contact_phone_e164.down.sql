@@ -0,0 +1 @@
+ALTER TABLE contacts DROP COLUMN phone_type, DROP COLUMN phone_e164;
@@ -0,0 +1 @@
+ALTER TABLE contacts ADD COLUMN phone_e164 VARCHAR(512) NOT NULL DEFAULT '' AFTER phone, ADD COLUMN phone_type VARCHAR(16) NOT NULL DEFAULT '' AFTER phone_e164;
@@ -0,0 +1 @@
+ALTER TABLE companies DROP COLUMN vat_payer_prefix, DROP COLUMN fiscal_code_valid;
@@ -0,0 +1 @@
+ALTER TABLE companies ADD COLUMN fiscal_code_valid TINYINT(1) NOT NULL DEFAULT 0 AFTER fiscal_code, ADD COLUMN vat_payer_prefix VARCHAR(2) NOT NULL DEFAULT '' AFTER fiscal_code_valid;
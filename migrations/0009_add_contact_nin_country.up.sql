@@ -0,0 +1 @@
+ALTER TABLE contacts ADD COLUMN nin_country VARCHAR(2) NOT NULL DEFAULT '' AFTER nin;
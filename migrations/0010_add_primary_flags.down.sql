@@ -0,0 +1,3 @@
+ALTER TABLE companies DROP INDEX uq_companies_primary, DROP COLUMN primary_type, DROP COLUMN is_primary;
+ALTER TABLE addresses DROP INDEX uq_addresses_primary, DROP COLUMN primary_type, DROP COLUMN is_primary;
+ALTER TABLE contacts DROP INDEX uq_contacts_primary, DROP COLUMN primary_type, DROP COLUMN is_primary;
@@ -0,0 +1,3 @@
+ALTER TABLE contacts ADD COLUMN is_primary TINYINT(1) NOT NULL DEFAULT 0 AFTER `type`, ADD COLUMN primary_type VARCHAR(255) AS (IF(is_primary = 1, `type`, NULL)) VIRTUAL, ADD UNIQUE INDEX uq_contacts_primary (profile_id, primary_type);
+ALTER TABLE addresses ADD COLUMN is_primary TINYINT(1) NOT NULL DEFAULT 0 AFTER `type`, ADD COLUMN primary_type VARCHAR(255) AS (IF(is_primary = 1, `type`, NULL)) VIRTUAL, ADD UNIQUE INDEX uq_addresses_primary (profile_id, primary_type);
+ALTER TABLE companies ADD COLUMN is_primary TINYINT(1) NOT NULL DEFAULT 0 AFTER `type`, ADD COLUMN primary_type VARCHAR(255) AS (IF(is_primary = 1, `type`, NULL)) VIRTUAL, ADD UNIQUE INDEX uq_companies_primary (profile_id, primary_type);
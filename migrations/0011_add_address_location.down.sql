@@ -0,0 +1,2 @@
+ALTER TABLE addresses DROP INDEX sp_addresses_location;
+ALTER TABLE addresses DROP COLUMN location, DROP COLUMN longitude, DROP COLUMN latitude;
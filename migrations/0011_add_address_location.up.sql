@@ -0,0 +1,2 @@
+ALTER TABLE addresses ADD COLUMN latitude DOUBLE NULL AFTER `type`, ADD COLUMN longitude DOUBLE NULL AFTER latitude, ADD COLUMN location POINT AS (POINT(COALESCE(longitude, 0), COALESCE(latitude, 0))) STORED NOT NULL SRID 0;
+ALTER TABLE addresses ADD SPATIAL INDEX sp_addresses_location (location);
@@ -0,0 +1,2 @@
+-- Copies folded into another company by the up migration stay soft-deleted.
+DROP TABLE IF EXISTS company_members;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS company_members (
+    company_id BIGINT UNSIGNED NOT NULL,
+    profile_id BIGINT UNSIGNED NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (company_id, profile_id),
+    INDEX idx_company_members_profile_id (profile_id),
+    CONSTRAINT fk_company_members_company_id FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE,
+    CONSTRAINT fk_company_members_profile_id FOREIGN KEY (profile_id) REFERENCES profile(id) ON DELETE CASCADE
+);
+INSERT INTO company_members (company_id, profile_id, role, created_at, updated_at)
+SELECT id, profile_id, 'owner', created_at, updated_at FROM companies;
+-- Live companies sharing a fiscal code are folded into the oldest of them: the profiles of the
+-- copies become its owners and the copies are soft-deleted.
+INSERT IGNORE INTO company_members (company_id, profile_id, role, created_at, updated_at)
+SELECT keeper.id, dup.profile_id, 'owner', dup.created_at, dup.updated_at
+FROM companies dup
+JOIN (SELECT fiscal_code, MIN(id) AS id FROM companies WHERE deleted_at IS NULL AND fiscal_code <> '' GROUP BY fiscal_code) keeper
+    ON keeper.fiscal_code = dup.fiscal_code AND keeper.id <> dup.id
+WHERE dup.deleted_at IS NULL;
+UPDATE companies dup
+JOIN (SELECT fiscal_code, MIN(id) AS id FROM companies WHERE deleted_at IS NULL AND fiscal_code <> '' GROUP BY fiscal_code) keeper
+    ON keeper.fiscal_code = dup.fiscal_code AND keeper.id <> dup.id
+SET dup.deleted_at = NOW(), dup.is_primary = 0, dup.version = dup.version + 1
+WHERE dup.deleted_at IS NULL;
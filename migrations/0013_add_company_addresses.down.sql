@@ -0,0 +1,5 @@
+-- Company addresses have no profile to fall back to and are dropped.
+DELETE FROM addresses WHERE company_id IS NOT NULL;
+ALTER TABLE addresses DROP INDEX uq_addresses_company_primary;
+ALTER TABLE addresses DROP FOREIGN KEY fk_addresses_company_id;
+ALTER TABLE addresses DROP INDEX idx_addresses_company_id_type, DROP COLUMN company_id, MODIFY profile_id BIGINT UNSIGNED NOT NULL;
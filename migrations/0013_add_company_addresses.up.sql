@@ -0,0 +1,3 @@
+-- An address belongs to either a profile or a company; the service keeps exactly one of the two set.
+ALTER TABLE addresses MODIFY profile_id BIGINT UNSIGNED NULL, ADD COLUMN company_id BIGINT UNSIGNED NULL AFTER profile_id, ADD INDEX idx_addresses_company_id_type (company_id, `type`), ADD CONSTRAINT fk_addresses_company_id FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+ALTER TABLE addresses ADD UNIQUE INDEX uq_addresses_company_primary (company_id, primary_type);
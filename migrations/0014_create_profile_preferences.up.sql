@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS profile_preferences (
+    profile_id BIGINT UNSIGNED NOT NULL,
+    namespace VARCHAR(64) NOT NULL,
+    `key` VARCHAR(128) NOT NULL,
+    value JSON NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (profile_id, namespace, `key`),
+    CONSTRAINT fk_profile_preferences_profile_id FOREIGN KEY (profile_id) REFERENCES profile(id) ON DELETE CASCADE
+);
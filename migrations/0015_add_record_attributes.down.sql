@@ -0,0 +1,3 @@
+ALTER TABLE companies DROP COLUMN attributes;
+ALTER TABLE addresses DROP COLUMN attributes;
+ALTER TABLE contacts DROP COLUMN attributes;
@@ -0,0 +1,4 @@
+-- Custom attributes of contacts, addresses and companies, checked by the service against the attribute registry.
+ALTER TABLE contacts ADD COLUMN attributes JSON NULL AFTER `type`;
+ALTER TABLE addresses ADD COLUMN attributes JSON NULL AFTER `type`;
+ALTER TABLE companies ADD COLUMN attributes JSON NULL AFTER `type`;
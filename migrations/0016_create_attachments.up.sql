@@ -0,0 +1,17 @@
+-- Files attached to profiles, contacts, addresses and companies. Only their metadata lives here;
+-- the content is kept by the blob store under storage_key.
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id BIGINT UNSIGNED NOT NULL,
+    kind VARCHAR(32) NOT NULL DEFAULT '',
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    content_type VARCHAR(127) NOT NULL,
+    size BIGINT UNSIGNED NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uq_attachments_storage_key (storage_key),
+    INDEX idx_attachments_entity (entity_type, entity_id, id)
+);
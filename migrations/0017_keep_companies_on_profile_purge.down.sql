@@ -0,0 +1,2 @@
+ALTER TABLE companies DROP FOREIGN KEY fk_companies_profile_id;
+ALTER TABLE companies ADD CONSTRAINT fk_companies_profile_id FOREIGN KEY (profile_id) REFERENCES profile(id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+-- A company outlives the profile it is filed under while it has other owners, so purging a
+-- profile must not take its companies with it. Profiles with companies are kept by the purge.
+ALTER TABLE companies DROP FOREIGN KEY fk_companies_profile_id;
+ALTER TABLE companies ADD CONSTRAINT fk_companies_profile_id FOREIGN KEY (profile_id) REFERENCES profile(id);
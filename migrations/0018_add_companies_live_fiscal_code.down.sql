@@ -0,0 +1,2 @@
+-- Copies folded into another company by the up migration stay soft-deleted.
+ALTER TABLE companies DROP INDEX uq_companies_live_fiscal_code, DROP COLUMN live_fiscal_code;
@@ -0,0 +1,20 @@
+-- Live companies that share a fiscal code again since 0012 are folded into the oldest of them
+-- the same way: the members of the copies become members of the oldest company, keeping their
+-- role unless they already are one, and the copies are soft-deleted.
+INSERT IGNORE INTO company_members (company_id, profile_id, role, created_at, updated_at)
+SELECT keeper.id, m.profile_id, m.role, m.created_at, m.updated_at
+FROM companies dup
+JOIN (SELECT fiscal_code, MIN(id) AS id FROM companies WHERE deleted_at IS NULL AND fiscal_code <> '' GROUP BY fiscal_code) keeper
+    ON keeper.fiscal_code = dup.fiscal_code AND keeper.id <> dup.id
+JOIN company_members m ON m.company_id = dup.id
+WHERE dup.deleted_at IS NULL;
+UPDATE companies dup
+JOIN (SELECT fiscal_code, MIN(id) AS id FROM companies WHERE deleted_at IS NULL AND fiscal_code <> '' GROUP BY fiscal_code) keeper
+    ON keeper.fiscal_code = dup.fiscal_code AND keeper.id <> dup.id
+SET dup.deleted_at = NOW(), dup.is_primary = 0, dup.version = dup.version + 1
+WHERE dup.deleted_at IS NULL;
+-- live_fiscal_code is NULL for deleted companies and companies without a code, which the unique
+-- index does not compare, so only one live company can hold a fiscal code.
+ALTER TABLE companies
+    ADD COLUMN live_fiscal_code VARCHAR(255) GENERATED ALWAYS AS (IF(deleted_at IS NULL AND fiscal_code <> '', fiscal_code, NULL)) STORED,
+    ADD UNIQUE INDEX uq_companies_live_fiscal_code (live_fiscal_code);
@@ -0,0 +1 @@
+-- Redacted NINs cannot be recovered, so there is nothing to roll back.
@@ -0,0 +1,7 @@
+-- Audit events and domain events written before NINs were redacted hold them in plaintext.
+UPDATE audit_events SET old_values = JSON_SET(old_values, '$.nin', '[redacted]')
+WHERE entity_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(old_values, '$.nin')) NOT IN ('', '[redacted]');
+UPDATE audit_events SET new_values = JSON_SET(new_values, '$.nin', '[redacted]')
+WHERE entity_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(new_values, '$.nin')) NOT IN ('', '[redacted]');
+UPDATE outbox_events SET payload = JSON_SET(payload, '$.data.nin', '[redacted]')
+WHERE aggregate_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(payload, '$.data.nin')) NOT IN ('', '[redacted]');
@@ -0,0 +1 @@
+ALTER TABLE outbox_events DROP INDEX idx_outbox_events_aggregate;
@@ -0,0 +1,2 @@
+-- Lets the relay find the older unpublished events of an aggregate without scanning the table.
+ALTER TABLE outbox_events ADD INDEX idx_outbox_events_aggregate (aggregate_type, aggregate_id, published_at, id);
@@ -0,0 +1,6 @@
+-- One row per email a profile has been written with. With PROFILE_UNIQUE_EMAIL set, a write
+-- locks its email's row before checking that no other live profile has the email, so two
+-- concurrent writes of the same email are serialized under any isolation level.
+CREATE TABLE IF NOT EXISTS profile_email_locks (
+    email VARCHAR(255) NOT NULL PRIMARY KEY
+);
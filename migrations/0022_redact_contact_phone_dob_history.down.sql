@@ -0,0 +1 @@
+-- Redacted phones and birth dates cannot be recovered, so there is nothing to roll back.
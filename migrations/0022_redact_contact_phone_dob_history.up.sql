@@ -0,0 +1,15 @@
+-- Audit events and domain events written before phones and birth dates were redacted hold them
+-- in plaintext. A migration cannot tell whether ENCRYPTION_FIELDS seals them, so the history of
+-- both is redacted either way; events written from now on redact them only while encrypted.
+UPDATE audit_events SET old_values = JSON_SET(old_values, '$.phone', '[redacted]')
+WHERE entity_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(old_values, '$.phone')) NOT IN ('', '[redacted]');
+UPDATE audit_events SET new_values = JSON_SET(new_values, '$.phone', '[redacted]')
+WHERE entity_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(new_values, '$.phone')) NOT IN ('', '[redacted]');
+UPDATE audit_events SET old_values = JSON_SET(old_values, '$.dob', '[redacted]')
+WHERE entity_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(old_values, '$.dob')) NOT IN ('', '[redacted]');
+UPDATE audit_events SET new_values = JSON_SET(new_values, '$.dob', '[redacted]')
+WHERE entity_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(new_values, '$.dob')) NOT IN ('', '[redacted]');
+UPDATE outbox_events SET payload = JSON_SET(payload, '$.data.phone', '[redacted]')
+WHERE aggregate_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(payload, '$.data.phone')) NOT IN ('', '[redacted]');
+UPDATE outbox_events SET payload = JSON_SET(payload, '$.data.dob', '[redacted]')
+WHERE aggregate_type = 'contact' AND JSON_UNQUOTE(JSON_EXTRACT(payload, '$.data.dob')) NOT IN ('', '[redacted]');
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// FS holds the versioned SQL migrations embedded in the binary.
+//
+//go:embed *.sql
+var FS embed.FS
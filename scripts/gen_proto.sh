@@ -4,7 +4,6 @@ set -euo pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 ROOT_DIR="$(cd "$SCRIPT_DIR/.." && pwd)"
 
-PROTO_FILE="$ROOT_DIR/proto/profile.proto"
 OUT_DIR="$ROOT_DIR/app/types"
 
 if ! command -v protoc >/dev/null 2>&1; then
@@ -31,6 +30,7 @@ protoc \
   -I "$ROOT_DIR/proto" \
   --go_out="$OUT_DIR" --go_opt=paths=source_relative \
   --go-grpc_out="$OUT_DIR" --go-grpc_opt=paths=source_relative \
-  "profile.proto"
+  "profile.proto" \
+  "v2/profile.proto"
 
 echo "Generated Go protobuf/grpc files in $OUT_DIR"